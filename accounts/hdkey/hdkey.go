@@ -0,0 +1,211 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package hdkey derives private keys from a mnemonic along BIP-32 paths, so
+// that the keys of a test network can be regenerated from a single seed.
+//
+// The mnemonic is turned into a seed as BIP-39 specifies, but its words are
+// not checked against the BIP-39 word list: any phrase works as a seed.
+package hdkey
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/portto/go-tangerine/accounts"
+	"github.com/portto/go-tangerine/common/math"
+	"github.com/portto/go-tangerine/crypto"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// HardenedKeyStart is the index of the first hardened child key.
+const HardenedKeyStart = 0x80000000
+
+var (
+	// NodeKeyPath is the base derivation path of the node keys of a test
+	// network, the i'th node key being derived at NodeKeyPath/i.
+	NodeKeyPath = accounts.DerivationPath{HardenedKeyStart + 44, HardenedKeyStart + 60, HardenedKeyStart + 1, 0}
+
+	// OwnerKeyPath is the base derivation path of the accounts operating a
+	// test network: the governance owner at OwnerKeyPath/0 and the monkey
+	// funding the zoo at OwnerKeyPath/1.
+	OwnerKeyPath = accounts.DerivationPath{HardenedKeyStart + 44, HardenedKeyStart + 60, HardenedKeyStart + 0, 0}
+
+	// ZooKeyPath is the base derivation path of the random accounts of the
+	// zoo monkeys.
+	ZooKeyPath = accounts.DerivationPath{HardenedKeyStart + 44, HardenedKeyStart + 60, HardenedKeyStart + 2, 0}
+)
+
+// Indexes of the operating accounts under OwnerKeyPath.
+const (
+	OwnerIndex  = 0
+	MonkeyIndex = 1
+)
+
+var errInvalidChild = errors.New("invalid child key, use the next index")
+
+// masterKeySalt is the HMAC key deriving the master key from a seed.
+var masterKeySalt = []byte("Bitcoin seed")
+
+// ExtendedKey is a private key along with the chain code its children are
+// derived with.
+type ExtendedKey struct {
+	key       []byte // 32 bytes private key
+	chainCode []byte // 32 bytes chain code
+}
+
+// NewSeed returns the seed of mnemonic protected by passphrase, as BIP-39
+// derives it.
+func NewSeed(mnemonic, passphrase string) []byte {
+	mnemonic = norm.NFKD.String(strings.Join(strings.Fields(mnemonic), " "))
+	salt := norm.NFKD.String("mnemonic" + passphrase)
+	return pbkdf2.Key([]byte(mnemonic), []byte(salt), 2048, 64, sha512.New)
+}
+
+// NewMaster returns the master key of seed.
+func NewMaster(seed []byte) (*ExtendedKey, error) {
+	mac := hmac.New(sha512.New, masterKeySalt)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	k := new(big.Int).SetBytes(sum[:32])
+	if k.Sign() == 0 || k.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, errors.New("invalid seed, use another one")
+	}
+	return &ExtendedKey{key: sum[:32], chainCode: sum[32:]}, nil
+}
+
+// Child returns the child key of k at index, hardened from HardenedKeyStart.
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	var data []byte
+	if index >= HardenedKeyStart {
+		data = append([]byte{0}, k.key...)
+	} else {
+		priv, err := crypto.ToECDSA(k.key)
+		if err != nil {
+			return nil, err
+		}
+		data = crypto.CompressPubkey(&priv.PublicKey)
+	}
+	var enc [4]byte
+	binary.BigEndian.PutUint32(enc[:], index)
+	data = append(data, enc[:]...)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := crypto.S256().Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, errInvalidChild
+	}
+	child := il.Add(il, new(big.Int).SetBytes(k.key))
+	child.Mod(child, n)
+	if child.Sign() == 0 {
+		return nil, errInvalidChild
+	}
+	return &ExtendedKey{key: math.PaddedBigBytes(child, 32), chainCode: sum[32:]}, nil
+}
+
+// Derive returns the key of k at path, relative to k.
+func (k *ExtendedKey) Derive(path accounts.DerivationPath) (*ExtendedKey, error) {
+	var err error
+	for _, index := range path {
+		if k, err = k.Child(index); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// ECDSA returns the private key of k.
+func (k *ExtendedKey) ECDSA() (*ecdsa.PrivateKey, error) {
+	return crypto.ToECDSA(k.key)
+}
+
+// Wallet derives the keys of a test network from a mnemonic.
+type Wallet struct {
+	master *ExtendedKey
+}
+
+// NewWallet returns a wallet deriving keys from mnemonic and passphrase.
+func NewWallet(mnemonic, passphrase string) (*Wallet, error) {
+	if strings.TrimSpace(mnemonic) == "" {
+		return nil, errors.New("empty mnemonic")
+	}
+	master, err := NewMaster(NewSeed(mnemonic, passphrase))
+	if err != nil {
+		return nil, err
+	}
+	return &Wallet{master: master}, nil
+}
+
+// Key returns the private key at path.
+func (w *Wallet) Key(path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	k, err := w.master.Derive(path)
+	if err != nil {
+		return nil, err
+	}
+	return k.ECDSA()
+}
+
+// Keys returns the private keys at the indexes below n under base.
+func (w *Wallet) Keys(base accounts.DerivationPath, n int) ([]*ecdsa.PrivateKey, error) {
+	parent, err := w.master.Derive(base)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		child, err := parent.Child(uint32(i))
+		if err != nil {
+			return nil, err
+		}
+		if keys[i], err = child.ECDSA(); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// NodeKey returns the i'th node key.
+func (w *Wallet) NodeKey(i int) (*ecdsa.PrivateKey, error) {
+	return w.Key(childPath(NodeKeyPath, uint32(i)))
+}
+
+// OwnerKey returns the key of the governance owner.
+func (w *Wallet) OwnerKey() (*ecdsa.PrivateKey, error) {
+	return w.Key(childPath(OwnerKeyPath, OwnerIndex))
+}
+
+// MonkeyKey returns the key of the monkey funding the zoo.
+func (w *Wallet) MonkeyKey() (*ecdsa.PrivateKey, error) {
+	return w.Key(childPath(OwnerKeyPath, MonkeyIndex))
+}
+
+// childPath returns the path of the child at index under base.
+func childPath(base accounts.DerivationPath, index uint32) accounts.DerivationPath {
+	path := make(accounts.DerivationPath, len(base), len(base)+1)
+	copy(path, base)
+	return append(path, index)
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package hdkey derives private keys from a mnemonic along BIP-32 paths, so
+package hdkey
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/portto/go-tangerine/accounts"
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/crypto"
+)
+
+// Tests the derivation of private keys against the BIP-32 test vector 1.
+func TestDerive(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMaster(seed)
+	if err != nil {
+		t.Fatalf("failed to create master key: %v", err)
+	}
+	tests := []struct {
+		path string
+		key  string
+	}{
+		{"m", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{"m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{"m/0'/1/2'", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+		{"m/0'/1/2'/2", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4"},
+		{"m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+	for _, tt := range tests {
+		var path accounts.DerivationPath
+		if tt.path != "m" {
+			if path, err = accounts.ParseDerivationPath(tt.path); err != nil {
+				t.Fatalf("%s: failed to parse path: %v", tt.path, err)
+			}
+		}
+		key, err := master.Derive(path)
+		if err != nil {
+			t.Fatalf("%s: failed to derive key: %v", tt.path, err)
+		}
+		if have := hex.EncodeToString(key.key); have != tt.key {
+			t.Errorf("%s: key mismatch: have %s, want %s", tt.path, have, tt.key)
+		}
+	}
+}
+
+// Tests that mnemonics turn into the seeds and accounts other BIP-39 wallets
+// derive.
+func TestNewWallet(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+	want, _ := hex.DecodeString("c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04")
+	if seed := NewSeed(mnemonic, "TREZOR"); !bytes.Equal(seed, want) {
+		t.Errorf("seed mismatch: have %x, want %x", seed, want)
+	}
+	// Extra whitespace does not change the seed.
+	if !bytes.Equal(NewSeed(" "+mnemonic+"\n", ""), NewSeed(mnemonic, "")) {
+		t.Errorf("seed depends on whitespace")
+	}
+
+	w, err := NewWallet(mnemonic, "")
+	if err != nil {
+		t.Fatalf("failed to create wallet: %v", err)
+	}
+	owner, err := w.OwnerKey()
+	if err != nil {
+		t.Fatalf("failed to derive owner key: %v", err)
+	}
+	if addr := crypto.PubkeyToAddress(owner.PublicKey); addr != common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94") {
+		t.Errorf("owner address mismatch: have %s", addr.Hex())
+	}
+
+	// The node keys are distinct and regenerated identically.
+	keys, err := w.Keys(NodeKeyPath, 4)
+	if err != nil {
+		t.Fatalf("failed to derive node keys: %v", err)
+	}
+	seen := map[common.Address]bool{crypto.PubkeyToAddress(owner.PublicKey): true}
+	for i, key := range keys {
+		again, err := w.NodeKey(i)
+		if err != nil {
+			t.Fatalf("failed to derive node key %d: %v", i, err)
+		}
+		if !bytes.Equal(crypto.FromECDSA(again), crypto.FromECDSA(key)) {
+			t.Errorf("node key %d mismatch", i)
+		}
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		if seen[addr] {
+			t.Errorf("node key %d reused", i)
+		}
+		seen[addr] = true
+	}
+
+	if _, err := NewWallet(" ", ""); err == nil {
+		t.Errorf("empty mnemonic accepted")
+	}
+}
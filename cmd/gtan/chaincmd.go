@@ -17,6 +17,7 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -27,15 +28,22 @@ import (
 
 	"github.com/portto/go-tangerine/cmd/utils"
 	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/common/hexutil"
 	"github.com/portto/go-tangerine/console"
 	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/rawdb"
 	"github.com/portto/go-tangerine/core/state"
 	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/dex"
+	dexDB "github.com/portto/go-tangerine/dex/db"
 	"github.com/portto/go-tangerine/eth/downloader"
 	"github.com/portto/go-tangerine/ethdb"
 	"github.com/portto/go-tangerine/event"
+	"github.com/portto/go-tangerine/internal/ethapi"
 	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/rlp"
 	"github.com/portto/go-tangerine/trie"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -75,6 +83,10 @@ It expects the genesis file as argument.`,
 The import command imports blocks from an RLP-encoded form. The form can be one file
 with several RLP-encoded blocks, or several files can be used.
 
+Besides executing the blocks, the import verifies that the DexconMeta of every block
+is the core block of its position, with randomness signed by the DKG group of its
+round as reconstructed from the governance state of the imported chain.
+
 If only one file is used, import error will result in failure. If several files are used,
 processing will proceed even if an individual RLP-file import failure occurs.`,
 	}
@@ -135,6 +147,7 @@ The export-preimages command export hash preimages to an RLP encoded stream`,
 			utils.SyncModeFlag,
 			utils.FakePoWFlag,
 			utils.TestnetFlag,
+			utils.NetworkFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -167,6 +180,172 @@ Remove blockchain and state databases`,
 The arguments are interpreted as block numbers or hashes.
 Use "ethereum dump 0" to dump the genesis block.`,
 	}
+	dumpBlocksCommand = cli.Command{
+		Action:    utils.MigrateFlags(dumpBlocks),
+		Name:      "dump-blocks",
+		Usage:     "Export a range of blocks with consensus metadata as JSON",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+			dumpFromFlag,
+			dumpToFlag,
+			dumpFormatFlag,
+			dumpOutputFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The dump-blocks command exports the blocks in the range [--from, --to] with
+their transactions, receipts and decoded consensus metadata (position,
+randomness and witness), one JSON object per line.`,
+	}
+	decodeMetaCommand = cli.Command{
+		Action:    utils.MigrateFlags(decodeMeta),
+		Name:      "decode-meta",
+		Usage:     "Decode the consensus metadata of a block header",
+		ArgsUsage: "<hex | blockNum>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The decode-meta command prints the core block embedded in the DexconMeta of a
+header (position, proposer, witness, randomness and signatures) as JSON.
+
+The argument is either the number of a block in the local database, or a hex
+encoded RLP blob of a header or of a DexconMeta field. DexconMeta in the compact
+encoding can only be decoded along with its header.`,
+	}
+	dumpFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block number to export",
+	}
+	dumpToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block number to export (default = current head)",
+	}
+	dumpFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "Output format (jsonl)",
+		Value: "jsonl",
+	}
+	dumpOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "File to write to (default = stdout)",
+	}
+	backfillTxIndexCommand = cli.Command{
+		Action:    utils.MigrateFlags(backfillTxIndex),
+		Name:      "backfill-txindex",
+		Usage:     "Rebuild the transaction lookup index of pruned blocks",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+			backfillFromFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The backfill-txindex command indexes the transactions of the blocks from --from
+up to the oldest block still indexed, after they were removed by running with
+--txlookuplimit. Run the node with a limit covering the backfilled blocks, or
+they are unindexed again.`,
+	}
+	backfillFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block number to index",
+	}
+	migrateCoreDBCommand = cli.Command{
+		Action:    utils.MigrateFlags(migrateCoreDB),
+		Name:      "migrate-coredb",
+		Usage:     "Import a legacy JSON consensus core database",
+		ArgsUsage: "<jsonfile>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The migrate-coredb command copies the core blocks persisted as JSON by older
+nodes into the consensus core database of the chain data directory. The hash
+of every block is verified before it is written. Blocks already present are
+skipped, so the command can be rerun after an interruption.`,
+	}
+	importCoreDBCommand = cli.Command{
+		Action:    utils.MigrateFlags(importCoreDB),
+		Name:      "import-coredb",
+		Usage:     "Import the consensus core data from an RLP stream",
+		ArgsUsage: "<datafile>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The import-coredb command imports the core blocks, the position index of the
+finalized ones, the compaction chain tip and the DKG protocol info exported by
+export-coredb, seeding a node without resyncing the compaction chain. The hash
+of every block is verified before it is written. Finalized block positions are
+only imported if the node has none indexed there, the compaction chain tip only
+if it is higher than the local one, and the DKG protocol info only if the node
+has none.`,
+	}
+	exportCoreDBCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportCoreDB),
+		Name:      "export-coredb",
+		Usage:     "Export the consensus core data into an RLP stream",
+		ArgsUsage: "<dumpfile>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The export-coredb command exports the core blocks, the position index of the
+finalized ones, the compaction chain tip and the DKG protocol info of the
+consensus core to an RLP encoded stream. If the
+file ends with .gz, the output will be gzipped. The DKG protocol info holds
+the secret shares of the node and is exported as stored, encrypted if the node
+has a DKG passphrase, so the file must be handled like a key file.`,
+	}
+	repairCoreTipCommand = cli.Command{
+		Action:    utils.MigrateFlags(repairCoreTip),
+		Name:      "repair-core-tip",
+		Usage:     "Rebuild the consensus compaction chain tip from the chain head",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The repair-core-tip command resets the compaction chain tip of the consensus
+core to the core block of the chain head, as recorded in its DexconMeta. It
+repairs a node refusing to start because the tip diverged from the chain, e.g.
+after an unclean shutdown. A tip on the chain is left untouched. A tip ahead of
+the chain head is reset as well, dropping the core blocks delivered but not
+executed, which are synced again.`,
+	}
+	backfillCoreDBCommand = cli.Command{
+		Action:    utils.MigrateFlags(backfillCoreDB),
+		Name:      "backfill-coredb",
+		Usage:     "Rebuild the consensus core database from the chain",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The backfill-coredb command rebuilds the core blocks of the consensus core from
+the DexconMeta of the executed chain and resets the compaction chain tip to the
+chain head. It recovers a node whose consensus core database was lost while its
+chain data is intact, without resyncing from the network. Blocks already present
+are skipped, so the command can be rerun after an interruption.`,
+	}
 )
 
 // initGenesis will initialise the given JSON format genesis file and writes it as
@@ -464,6 +643,256 @@ func dump(ctx *cli.Context) error {
 	return nil
 }
 
+// dumpConsensusMeta is the decoded consensus metadata of an exported block.
+type dumpConsensusMeta struct {
+	CoreHash     common.Hash        `json:"coreHash"`
+	ProposerID   coreTypes.NodeID   `json:"proposerID"`
+	Position     coreTypes.Position `json:"position"`
+	Timestamp    time.Time          `json:"timestamp"`
+	Randomness   hexutil.Bytes      `json:"randomness"`
+	Witness      dumpWitness        `json:"witness"`
+	Signature    hexutil.Bytes      `json:"signature"`
+	CRSSignature hexutil.Bytes      `json:"crsSignature"`
+}
+
+type dumpWitness struct {
+	Height hexutil.Uint64 `json:"height"`
+	Data   hexutil.Bytes  `json:"data"`
+}
+
+func newDumpConsensusMeta(block *coreTypes.Block) *dumpConsensusMeta {
+	return &dumpConsensusMeta{
+		CoreHash:   common.Hash(block.Hash),
+		ProposerID: block.ProposerID,
+		Position:   block.Position,
+		Timestamp:  block.Timestamp,
+		Randomness: block.Randomness,
+		Witness: dumpWitness{
+			Height: hexutil.Uint64(block.Witness.Height),
+			Data:   block.Witness.Data,
+		},
+		Signature:    block.Signature.Signature,
+		CRSSignature: block.CRSSignature.Signature,
+	}
+}
+
+func decodeMeta(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	arg := ctx.Args().First()
+
+	var header *types.Header
+	if number, err := strconv.ParseUint(arg, 10, 64); err == nil {
+		stack := makeFullNode(ctx)
+		chain, chainDb := utils.MakeChain(ctx, stack)
+		header = chain.GetHeaderByNumber(number)
+		chainDb.Close()
+		if header == nil {
+			utils.Fatalf("Block %d not found", number)
+		}
+	} else {
+		blob, err := hexutil.Decode(arg)
+		if err != nil {
+			if blob, err = hexutil.Decode("0x" + arg); err != nil {
+				utils.Fatalf("Invalid argument %q: neither a block number nor hex", arg)
+			}
+		}
+		header = new(types.Header)
+		if err := rlp.DecodeBytes(blob, header); err != nil {
+			// Not a header, take the blob as the DexconMeta field itself.
+			if types.IsCompactDexconMeta(blob) {
+				utils.Fatalf("Compact dexcon meta can only be decoded along with its header")
+			}
+			header = &types.Header{DexconMeta: blob}
+		}
+	}
+	if len(header.DexconMeta) == 0 {
+		utils.Fatalf("Header has no dexcon meta")
+	}
+	block, err := header.CoreBlock()
+	if err != nil {
+		utils.Fatalf("Invalid dexcon meta: %v", err)
+	}
+	out, err := json.MarshalIndent(newDumpConsensusMeta(block), "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode core block: %v", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+func backfillTxIndex(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	from, tail := ctx.Uint64(backfillFromFlag.Name), chain.TxIndexTail()
+	if from >= tail {
+		fmt.Printf("Transactions from block %d are already indexed\n", from)
+		return nil
+	}
+	start := time.Now()
+	if err := core.IndexTransactions(chainDb, from, tail, nil); err != nil {
+		utils.Fatalf("Failed to index transactions: %v", err)
+	}
+	fmt.Printf("Indexed transactions of blocks %d to %d in %v\n", from, tail-1, time.Since(start))
+	return nil
+}
+
+func migrateCoreDB(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	start := time.Now()
+	stats, err := dexDB.MigrateJSON(ctx.Args().First(), dexDB.NewDatabase(chainDb))
+	if err != nil {
+		utils.Fatalf("Migration failed after %d blocks: %v", stats.Migrated, err)
+	}
+	fmt.Printf("Migrated %d core blocks, skipped %d already present, in %v\n",
+		stats.Migrated, stats.Skipped, time.Since(start))
+	return nil
+}
+
+// importCoreDB imports the consensus core data from the specified file.
+func importCoreDB(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	start := time.Now()
+	stats, err := utils.ImportCoreDB(chainDb, ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Import error after %d blocks: %v", stats.Blocks, err)
+	}
+	fmt.Printf("Imported %d core blocks, %d finalized positions (chain tip: %t, DKG protocol: %t), skipped %d entries, in %v\n",
+		stats.Blocks, stats.Finalized, stats.ChainTip, stats.DKGProtocol, stats.Skipped, time.Since(start))
+	return nil
+}
+
+// exportCoreDB dumps the consensus core data to the specified file.
+func exportCoreDB(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	start := time.Now()
+	stats, err := utils.ExportCoreDB(chainDb, ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Export error: %v", err)
+	}
+	fmt.Printf("Exported %d core blocks, %d finalized positions (chain tip: %t, DKG protocol: %t) in %v\n",
+		stats.Blocks, stats.Finalized, stats.ChainTip, stats.DKGProtocol, time.Since(start))
+	return nil
+}
+
+// repairCoreTip resets the compaction chain tip to the chain head.
+func repairCoreTip(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	db := dexDB.NewDatabase(chainDb)
+	oldHash, oldHeight := db.GetCompactionChainTipInfo()
+	if err := dex.CheckCoreTip(chain, db); err == nil && oldHeight <= chain.CurrentBlock().NumberU64() {
+		fmt.Printf("Compaction chain tip %s at %d is on the chain\n", oldHash.String(), oldHeight)
+		return nil
+	}
+	hash, height, err := dex.RepairCoreTip(chain, db)
+	if err != nil {
+		utils.Fatalf("Failed to repair compaction chain tip: %v", err)
+	}
+	fmt.Printf("Reset compaction chain tip from %s at %d to %s at %d\n",
+		oldHash.String(), oldHeight, hash.String(), height)
+	return nil
+}
+
+// backfillCoreDB rebuilds the consensus core database from the chain.
+func backfillCoreDB(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	start := time.Now()
+	stats, err := dex.BackfillCoreDB(chain, dexDB.NewDatabase(chainDb))
+	if err != nil {
+		utils.Fatalf("Backfill failed after %d blocks: %v", stats.Blocks, err)
+	}
+	fmt.Printf("Backfilled %d core blocks (chain tip: %t), skipped %d already present, in %v\n",
+		stats.Blocks, stats.ChainTip, stats.Skipped, time.Since(start))
+	return nil
+}
+
+func dumpBlocks(ctx *cli.Context) error {
+	if format := ctx.String(dumpFormatFlag.Name); format != "jsonl" {
+		utils.Fatalf("Unsupported format: %s", format)
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	from := ctx.Uint64(dumpFromFlag.Name)
+	to := chain.CurrentBlock().NumberU64()
+	if ctx.IsSet(dumpToFlag.Name) && ctx.Uint64(dumpToFlag.Name) < to {
+		to = ctx.Uint64(dumpToFlag.Name)
+	}
+	if from > to {
+		utils.Fatalf("Invalid block range: %d > %d", from, to)
+	}
+
+	out := os.Stdout
+	if file := ctx.String(dumpOutputFlag.Name); file != "" {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			utils.Fatalf("Failed to open output file: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	writer := bufio.NewWriter(out)
+	defer writer.Flush()
+	encoder := json.NewEncoder(writer)
+
+	start := time.Now()
+	for number := from; number <= to; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			utils.Fatalf("Block %d not found", number)
+		}
+		fields, err := ethapi.RPCMarshalBlock(block, true, true)
+		if err != nil {
+			utils.Fatalf("Failed to marshal block %d: %v", number, err)
+		}
+		if len(block.Header().DexconMeta) > 0 {
+			coreBlock, err := block.Header().CoreBlock()
+			if err != nil {
+				utils.Fatalf("Invalid dexcon meta of block %d: %v", number, err)
+			}
+			fields["consensus"] = newDumpConsensusMeta(coreBlock)
+		}
+		receipts := rawdb.ReadReceipts(chainDb, block.Hash(), number)
+		if receipts == nil {
+			receipts = types.Receipts{}
+		}
+		fields["receipts"] = receipts
+		if err := encoder.Encode(fields); err != nil {
+			utils.Fatalf("Failed to write block %d: %v", number, err)
+		}
+	}
+	log.Info("Exported blocks", "from", from, "to", to, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
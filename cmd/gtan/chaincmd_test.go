@@ -0,0 +1,80 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/common/hexutil"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/rlp"
+)
+
+// Tests that decode-meta prints the core block of a header given as hex, in
+// either encoding of its dexcon meta, and of a full dexcon meta on its own.
+func TestDecodeMeta(t *testing.T) {
+	block := &coreTypes.Block{
+		Hash:       coreCommon.NewRandomHash(),
+		Position:   coreTypes.Position{Round: 3, Height: 7},
+		Timestamp:  time.Unix(1560000000, 0).UTC(),
+		Randomness: bytes.Repeat([]byte{0x07}, 4),
+	}
+	full, err := types.EncodeDexconMeta(block, false)
+	if err != nil {
+		t.Fatalf("failed to encode full dexcon meta: %v", err)
+	}
+	compact, err := types.EncodeDexconMeta(block, true)
+	if err != nil {
+		t.Fatalf("failed to encode compact dexcon meta: %v", err)
+	}
+	header, err := rlp.EncodeToBytes(&types.Header{
+		Number:     big.NewInt(7),
+		Round:      3,
+		Time:       uint64(block.Timestamp.UnixNano() / 1000000),
+		DexconMeta: compact,
+		Randomness: block.Randomness,
+	})
+	if err != nil {
+		t.Fatalf("failed to encode header: %v", err)
+	}
+
+	want := fmt.Sprintf(`(?s)"coreHash": "%s".*"round": 3,\s*"height": 7.*"randomness": "0x07070707"`,
+		common.Hash(block.Hash).Hex())
+	for _, arg := range []string{hexutil.Encode(full), hexutil.Encode(header)} {
+		gtan := runGeth(t, "decode-meta", arg)
+		gtan.ExpectRegexp(want)
+		gtan.ExpectExit()
+	}
+
+	gtan := runGeth(t, "decode-meta", hexutil.Encode(compact))
+	gtan.WaitExit()
+	if status := gtan.ExitStatus(); status == 0 {
+		t.Errorf("compact dexcon meta decoded without its header")
+	}
+	if stderr := gtan.StderrText(); !strings.Contains(stderr, "along with its header") {
+		t.Errorf("missing failure message in stderr: %s", stderr)
+	}
+}
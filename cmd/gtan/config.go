@@ -18,15 +18,21 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"unicode"
 
 	cli "gopkg.in/urfave/cli.v1"
 
 	"github.com/naoina/toml"
+	"github.com/naoina/toml/ast"
 	"github.com/portto/go-tangerine/cmd/utils"
 	"github.com/portto/go-tangerine/dashboard"
 	"github.com/portto/go-tangerine/dex"
@@ -37,18 +43,22 @@ import (
 
 var (
 	dumpConfigCommand = cli.Command{
-		Action:      utils.MigrateFlags(dumpConfig),
-		Name:        "dumpconfig",
-		Usage:       "Show configuration values",
-		ArgsUsage:   "",
-		Flags:       append(append(nodeFlags, rpcFlags...), whisperFlags...),
-		Category:    "MISCELLANEOUS COMMANDS",
-		Description: `The dumpconfig command shows configuration values.`,
+		Action:    utils.MigrateFlags(dumpConfig),
+		Name:      "dumpconfig",
+		Usage:     "Show configuration values",
+		ArgsUsage: "",
+		Flags:     append(append(nodeFlags, rpcFlags...), whisperFlags...),
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `The dumpconfig command shows configuration values.
+
+The effective configuration, the config file merged with the flags, is written
+to the file given as argument or to stdout. The file is written as JSON if its
+name ends with .json and as TOML otherwise.`,
 	}
 
 	configFileFlag = cli.StringFlag{
 		Name:  "config",
-		Usage: "TOML configuration file",
+		Usage: "TOML or JSON (.json) configuration file",
 	}
 )
 
@@ -82,6 +92,9 @@ type gethConfig struct {
 }
 
 func loadConfig(file string, cfg *gethConfig) error {
+	if isJSONConfig(file) {
+		return loadJSONConfig(file, cfg)
+	}
 	f, err := os.Open(file)
 	if err != nil {
 		return err
@@ -96,6 +109,193 @@ func loadConfig(file string, cfg *gethConfig) error {
 	return err
 }
 
+// isJSONConfig reports whether the config file is in JSON instead of TOML.
+func isJSONConfig(file string) bool {
+	return strings.EqualFold(filepath.Ext(file), ".json")
+}
+
+// loadJSONConfig loads a JSON config file. The JSON document is converted to
+// a TOML table before being decoded, so keys and values follow the same rules
+// as in TOML config files.
+func loadJSONConfig(file string, cfg *gethConfig) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	table, err := jsonToTable(data)
+	if err != nil {
+		return errors.New(file + ", " + err.Error())
+	}
+	err = tomlSettings.UnmarshalTable(table, cfg)
+	// Line numbers are meaningless as the table has no source.
+	if lerr, ok := err.(*toml.LineError); ok {
+		err = lerr.Err
+		if lerr.StructField != "" {
+			err = fmt.Errorf("(%s) %v", lerr.StructField, lerr.Err)
+		}
+	}
+	if err != nil {
+		return errors.New(file + ", " + err.Error())
+	}
+	return nil
+}
+
+// jsonToTable converts a JSON object to a TOML table.
+func jsonToTable(data []byte) (*ast.Table, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return jsonObjectTable("", obj)
+}
+
+func jsonObjectTable(name string, obj map[string]interface{}) (*ast.Table, error) {
+	table := &ast.Table{Name: name, Fields: make(map[string]interface{})}
+	for key, v := range obj {
+		path := key
+		if name != "" {
+			path = name + "." + key
+		}
+		switch v := v.(type) {
+		case nil:
+			// Null leaves the default value.
+		case map[string]interface{}:
+			sub, err := jsonObjectTable(path, v)
+			if err != nil {
+				return nil, err
+			}
+			table.Fields[key] = sub
+		case []interface{}:
+			tables, err := jsonArrayTables(path, v)
+			if err != nil {
+				return nil, err
+			}
+			if tables != nil {
+				table.Fields[key] = tables
+				continue
+			}
+			array, err := jsonValue(path, v)
+			if err != nil {
+				return nil, err
+			}
+			table.Fields[key] = &ast.KeyValue{Key: key, Value: array}
+		default:
+			value, err := jsonValue(path, v)
+			if err != nil {
+				return nil, err
+			}
+			table.Fields[key] = &ast.KeyValue{Key: key, Value: value}
+		}
+	}
+	return table, nil
+}
+
+// jsonArrayTables converts a JSON array of objects to an array of TOML
+// tables, returning nil if the array is empty or holds other values.
+func jsonArrayTables(name string, array []interface{}) ([]*ast.Table, error) {
+	if len(array) == 0 {
+		return nil, nil
+	}
+	tables := make([]*ast.Table, 0, len(array))
+	for _, elem := range array {
+		obj, ok := elem.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		table, err := jsonObjectTable(name, obj)
+		if err != nil {
+			return nil, err
+		}
+		table.Type = ast.TableTypeArray
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func jsonValue(name string, v interface{}) (ast.Value, error) {
+	switch v := v.(type) {
+	case string:
+		return &ast.String{Value: v, Data: []rune(v)}, nil
+	case bool:
+		s := fmt.Sprint(v)
+		return &ast.Boolean{Value: s, Data: []rune(s)}, nil
+	case json.Number:
+		s := v.String()
+		if _, err := v.Int64(); err == nil {
+			return &ast.Integer{Value: s, Data: []rune(s)}, nil
+		}
+		return &ast.Float{Value: s, Data: []rune(s)}, nil
+	case []interface{}:
+		array := &ast.Array{Value: make([]ast.Value, 0, len(v))}
+		for _, elem := range v {
+			value, err := jsonValue(name, elem)
+			if err != nil {
+				return nil, err
+			}
+			array.Value = append(array.Value, value)
+		}
+		return array, nil
+	default:
+		return nil, fmt.Errorf("unsupported value of %s: %v", name, v)
+	}
+}
+
+// tomlToJSON converts a TOML document to indented JSON with the same keys.
+// Numbers are copied verbatim, keeping the precision of big integers.
+func tomlToJSON(data []byte) ([]byte, error) {
+	table, err := toml.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(tableToJSON(table), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func tableToJSON(table *ast.Table) map[string]interface{} {
+	obj := make(map[string]interface{}, len(table.Fields))
+	for key, field := range table.Fields {
+		switch field := field.(type) {
+		case *ast.KeyValue:
+			obj[key] = valueToJSON(field.Value)
+		case *ast.Table:
+			obj[key] = tableToJSON(field)
+		case []*ast.Table:
+			array := make([]interface{}, len(field))
+			for i, elem := range field {
+				array[i] = tableToJSON(elem)
+			}
+			obj[key] = array
+		}
+	}
+	return obj
+}
+
+func valueToJSON(v ast.Value) interface{} {
+	switch v := v.(type) {
+	case *ast.String:
+		return v.Value
+	case *ast.Integer:
+		return json.Number(v.Value)
+	case *ast.Float:
+		return json.Number(v.Value)
+	case *ast.Boolean:
+		return v.Value == "true"
+	case *ast.Array:
+		array := make([]interface{}, len(v.Value))
+		for i, elem := range v.Value {
+			array[i] = valueToJSON(elem)
+		}
+		return array
+	default:
+		return v.Source()
+	}
+}
+
 func defaultNodeConfig() node.Config {
 	cfg := node.DefaultConfig
 	cfg.Name = clientIdentifier
@@ -194,6 +394,13 @@ func dumpConfig(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	// JSON has no comments, the genesis block is left out all the same.
+	if ctx.NArg() > 0 && isJSONConfig(ctx.Args().Get(0)) {
+		if out, err = tomlToJSON(out); err != nil {
+			return err
+		}
+		comment = ""
+	}
 
 	dump := os.Stdout
 	if ctx.NArg() > 0 {
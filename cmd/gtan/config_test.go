@@ -0,0 +1,79 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/portto/go-tangerine/dex"
+)
+
+func TestJSONConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gtan-config-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := gethConfig{Dex: dex.DefaultConfig, Node: defaultNodeConfig()}
+	want.Dex.Indexer.Enable = true
+	want.Dex.Indexer.Plugin = "indexer.so"
+	want.Dex.RecoveryNetworkRPC = "http://localhost:8545"
+	want.Dex.TxPool.Locals = nil
+	want.Dex.DefaultGasPrice = new(big.Int).Exp(big.NewInt(10), big.NewInt(30), nil)
+	want.Dex.Alerts.Interval = time.Minute
+	want.Node.HTTPModules = []string{"eth"}
+
+	out, err := tomlSettings.Marshal(&want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := tomlToJSON(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := gethConfig{Dex: dex.DefaultConfig, Node: defaultNodeConfig()}
+	if err := loadConfig(file, &got); err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	gotOut, err := tomlSettings.Marshal(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotOut, out) {
+		t.Errorf("config mismatch:\ngot:\n%s\nwant:\n%s", gotOut, out)
+	}
+
+	if err := ioutil.WriteFile(file, []byte(`{"Dex": {"Unknown": 1}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(file, &got); err == nil || !strings.Contains(err.Error(), "Unknown") {
+		t.Errorf("unknown field error mismatch: %v", err)
+	}
+}
@@ -122,8 +122,8 @@ func remoteConsole(ctx *cli.Context) error {
 			path = ctx.GlobalString(utils.DataDirFlag.Name)
 		}
 		if path != "" {
-			if ctx.GlobalBool(utils.TestnetFlag.Name) {
-				path = filepath.Join(path, "testnet")
+			if network := utils.SelectedNetwork(ctx); network != nil {
+				path = filepath.Join(path, network.DataDir)
 			}
 		}
 		endpoint = fmt.Sprintf("%s/gtan.ipc", path)
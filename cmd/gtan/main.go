@@ -20,6 +20,8 @@ package main
 import (
 	"fmt"
 	"math"
+	"net"
+	"net/http"
 	"os"
 	godebug "runtime/debug"
 	"sort"
@@ -84,7 +86,12 @@ var (
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
 		utils.SyncModeFlag,
+		utils.CheckpointFlag,
 		utils.GCModeFlag,
+		utils.TxLookupLimitFlag,
+		utils.ExtendedReceiptsFlag,
+		utils.ScrubIntervalFlag,
+		utils.PeerHistoryRetentionFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
 		utils.LightKDFFlag,
@@ -96,8 +103,13 @@ var (
 		utils.TrieCacheGenFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
+		utils.PeerScalingMarginFlag,
 		utils.MaxPendingPeersFlag,
 		utils.BlockProposerEnabledFlag,
+		utils.ExternalSignerFlag,
+		utils.DKGPasswordFileFlag,
+		utils.TxOrderingFlag,
+		utils.SafeModeFlag,
 		utils.MiningEnabledFlag,
 		utils.MinerThreadsFlag,
 		utils.MinerLegacyThreadsFlag,
@@ -122,13 +134,19 @@ var (
 		utils.DeveloperFlag,
 		utils.DeveloperPeriodFlag,
 		utils.TestnetFlag,
+		utils.NetworkFlag,
 		utils.VMEnableDebugFlag,
 		utils.NetworkIdFlag,
 		utils.ConstantinopleOverrideFlag,
 		utils.RPCCORSDomainFlag,
 		utils.RPCVirtualHostsFlag,
+		utils.RPCBatchRequestLimitFlag,
+		utils.RPCBatchResponseMaxSizeFlag,
+		utils.AuditProxyUserFlag,
 		utils.EthStatsURLFlag,
 		utils.MetricsEnabledFlag,
+		utils.MetricsHTTPFlag,
+		utils.MetricsPortFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
@@ -139,6 +157,15 @@ var (
 		utils.IndexerPluginFlag,
 		utils.IndexerPluginFlagsFlag,
 		utils.RecoveryNetworkRPCFlag,
+		utils.MsgProfilingLabelsFlag,
+		utils.AlertWebhookFlag,
+		utils.AlertIntervalFlag,
+		utils.StateRootGossipFlag,
+		utils.AdaptiveLambdaFlag,
+		utils.AdaptiveLambdaMinFlag,
+		utils.AdaptiveLambdaMaxFlag,
+		utils.CoreMsgFutureToleranceFlag,
+		utils.FeaturesFlag,
 		configFileFlag,
 	}
 
@@ -155,6 +182,8 @@ var (
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 		utils.RPCGlobalGasCap,
+		utils.RPCGovernanceGasCap,
+		utils.RPCFilterTimeoutFlag,
 	}
 
 	whisperFlags = []cli.Flag{
@@ -164,6 +193,18 @@ var (
 		utils.WhisperRestrictConnectionBetweenLightClientsFlag,
 	}
 
+	healthFlags = []cli.Flag{
+		utils.HealthEnabledFlag,
+		utils.HealthListenAddrFlag,
+		utils.HealthPortFlag,
+	}
+
+	statusPageFlags = []cli.Flag{
+		utils.StatusPageEnabledFlag,
+		utils.StatusPageListenAddrFlag,
+		utils.StatusPagePortFlag,
+	}
+
 	metricsFlags = []cli.Flag{
 		utils.MetricsEnableInfluxDBFlag,
 		utils.MetricsInfluxDBEndpointFlag,
@@ -189,6 +230,14 @@ func init() {
 		copydbCommand,
 		removedbCommand,
 		dumpCommand,
+		dumpBlocksCommand,
+		decodeMetaCommand,
+		backfillTxIndexCommand,
+		migrateCoreDBCommand,
+		importCoreDBCommand,
+		exportCoreDBCommand,
+		repairCoreTipCommand,
+		backfillCoreDBCommand,
 		// See monitorcmd.go:
 		monitorCommand,
 		// See accountcmd.go:
@@ -204,6 +253,8 @@ func init() {
 		versionCommand,
 		bugCommand,
 		licenseCommand,
+		healthcheckCommand,
+		nettestCommand,
 		// See config.go
 		dumpConfigCommand,
 	}
@@ -214,6 +265,8 @@ func init() {
 	app.Flags = append(app.Flags, consoleFlags...)
 	app.Flags = append(app.Flags, debug.Flags...)
 	app.Flags = append(app.Flags, whisperFlags...)
+	app.Flags = append(app.Flags, healthFlags...)
+	app.Flags = append(app.Flags, statusPageFlags...)
 	app.Flags = append(app.Flags, metricsFlags...)
 
 	app.Before = func(ctx *cli.Context) error {
@@ -224,6 +277,9 @@ func init() {
 		if err := debug.Setup(ctx, logdir); err != nil {
 			return err
 		}
+		if err := utils.SetFeatures(ctx); err != nil {
+			return err
+		}
 		// Cap the cache allowance and tune the garbage collector
 		var mem gosigar.Mem
 		if err := mem.Get(); err == nil {
@@ -371,4 +427,41 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 			utils.Fatalf("Tangerine service not running: %v", err)
 		}
 	}
+
+	if ctx.GlobalBool(utils.HealthEnabledFlag.Name) {
+		var dexon *dex.Tangerine
+		if err := stack.Service(&dexon); err != nil {
+			utils.Fatalf("Tangerine service not running: %v", err)
+		}
+		address := healthAddress(ctx)
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			utils.Fatalf("Failed to start health probe server: %v", err)
+		}
+		log.Info("Health probe server started", "addr", fmt.Sprintf("http://%s", address))
+		go http.Serve(listener, dex.NewHealthHandler(dexon))
+	}
+	if ctx.GlobalBool(utils.StatusPageEnabledFlag.Name) {
+		var dexon *dex.Tangerine
+		if err := stack.Service(&dexon); err != nil {
+			utils.Fatalf("Tangerine service not running: %v", err)
+		}
+		address := net.JoinHostPort(ctx.GlobalString(utils.StatusPageListenAddrFlag.Name),
+			strconv.Itoa(ctx.GlobalInt(utils.StatusPagePortFlag.Name)))
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			utils.Fatalf("Failed to start status page server: %v", err)
+		}
+		log.Info("Status page server started", "addr", fmt.Sprintf("http://%s", address))
+		go http.Serve(listener, dex.NewStatusHandler(dexon))
+	}
+	if err := utils.SdNotify(utils.SdNotifyReady); err != nil {
+		log.Warn("Failed to notify service manager", "err", err)
+	}
+}
+
+// healthAddress returns the listening address of the health probe server.
+func healthAddress(ctx *cli.Context) string {
+	return net.JoinHostPort(ctx.GlobalString(utils.HealthListenAddrFlag.Name),
+		strconv.Itoa(ctx.GlobalInt(utils.HealthPortFlag.Name)))
 }
@@ -18,10 +18,13 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/portto/go-tangerine/cmd/utils"
 	"github.com/portto/go-tangerine/consensus/ethash"
@@ -74,6 +77,27 @@ The output of this command is supposed to be machine-readable.
 		ArgsUsage: " ",
 		Category:  "MISCELLANEOUS COMMANDS",
 	}
+	healthcheckCommand = cli.Command{
+		Action:    utils.MigrateFlags(healthcheck),
+		Name:      "healthcheck",
+		Usage:     "Query the health probes of a running node",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.HealthListenAddrFlag,
+			utils.HealthPortFlag,
+			healthLiveFlag,
+		},
+		Category: "MISCELLANEOUS COMMANDS",
+		Description: `
+The healthcheck command queries the readiness probe of a node started with
+--health, or its liveness probe with --live, and exits with a non-zero status
+if the probe fails. It is meant to be used as a container health check.
+`,
+	}
+	healthLiveFlag = cli.BoolFlag{
+		Name:  "live",
+		Usage: "Query the liveness instead of the readiness probe",
+	}
 )
 
 // makecache generates an ethash verification cache into the provided folder.
@@ -137,3 +161,22 @@ You should have received a copy of the GNU General Public License
 along with gtan. If not, see <http://www.gnu.org/licenses/>.`)
 	return nil
 }
+
+func healthcheck(ctx *cli.Context) error {
+	path := dex.HealthReadyPath
+	if ctx.Bool(healthLiveFlag.Name) {
+		path = dex.HealthLivePath
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://%s%s", healthAddress(ctx), path))
+	if err != nil {
+		utils.Fatalf("Health probe failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	io.Copy(os.Stdout, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		utils.Fatalf("Health probe failed: %s", resp.Status)
+	}
+	return nil
+}
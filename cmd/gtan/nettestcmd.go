@@ -0,0 +1,260 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/portto/go-tangerine/cmd/utils"
+	"github.com/portto/go-tangerine/dex"
+	"github.com/portto/go-tangerine/p2p"
+	"github.com/portto/go-tangerine/p2p/enode"
+	"github.com/portto/go-tangerine/p2p/netutil"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	nettestDurationFlag = cli.DurationFlag{
+		Name:  "duration",
+		Usage: "How long to wait for inbound connections",
+		Value: 2 * time.Minute,
+	}
+	nettestReflectorFlag = cli.StringFlag{
+		Name:  "reflector",
+		Usage: "URL of a service replying with the public IP of the caller (e.g. https://api.ipify.org)",
+	}
+	nettestCommand = cli.Command{
+		Action:    utils.MigrateFlags(nettest),
+		Name:      "nettest",
+		Usage:     "Check the p2p connectivity of the node",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.TestnetFlag,
+			utils.NetworkFlag,
+			utils.NodeKeyFileFlag,
+			utils.NodeKeyHexFlag,
+			utils.ListenPortFlag,
+			utils.NATFlag,
+			utils.BootnodesFlag,
+			utils.BootnodesV4Flag,
+			utils.MaxPeersFlag,
+			nettestDurationFlag,
+			nettestReflectorFlag,
+		},
+		Category: "MISCELLANEOUS COMMANDS",
+		Description: `
+The nettest command runs a connectivity self-test using the p2p settings of the
+node. It reports the NAT mapping status and the available IPv6 addresses, then
+joins the network for a while and counts the peers dialing in. The command exits
+with a non-zero status if any check fails.
+
+A node never receiving inbound connections is effectively unreachable, which is
+a common cause of missed proposals. The node must not be running while the test
+is executed, as both need the same p2p port.`,
+	}
+)
+
+// nettest runs the connectivity self-test.
+func nettest(ctx *cli.Context) error {
+	cfg := defaultNodeConfig()
+	utils.SetNodeConfig(ctx, &cfg)
+
+	srvCfg := cfg.P2P
+	srvCfg.PrivateKey = cfg.NodeKey()
+	srvCfg.Name = cfg.NodeName()
+	srvCfg.NodeDatabase = "" // don't touch the node database of the real node
+	srvCfg.Protocols = nettestProtocols()
+
+	var warnings []string
+	warn := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+	fmt.Println("Listen address:", srvCfg.ListenAddr)
+	_, port, err := net.SplitHostPort(srvCfg.ListenAddr)
+	if err != nil {
+		utils.Fatalf("Invalid listen address %q: %v", srvCfg.ListenAddr, err)
+	}
+
+	// Report the IPv6 setup of the host.
+	var v6 []string
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if ok && ipnet.IP.To4() == nil && ipnet.IP.IsGlobalUnicast() && !netutil.IsLAN(ipnet.IP) {
+				v6 = append(v6, ipnet.IP.String())
+			}
+		}
+	}
+	if len(v6) == 0 {
+		fmt.Println("IPv6: no global addresses")
+	} else {
+		fmt.Println("IPv6:", strings.Join(v6, ", "))
+	}
+
+	// Report the NAT mapping status.
+	var externalIP net.IP
+	if srvCfg.NAT == nil {
+		fmt.Println("NAT: traversal disabled")
+	} else {
+		fmt.Println("NAT:", srvCfg.NAT)
+		if externalIP, err = srvCfg.NAT.ExternalIP(); err != nil {
+			warn("NAT external address unavailable: %v", err)
+		} else {
+			fmt.Println("NAT external address:", externalIP)
+		}
+		p, _ := strconv.Atoi(port)
+		if err := srvCfg.NAT.AddMapping("tcp", p, p, "gtan nettest", time.Minute); err != nil {
+			warn("NAT port mapping failed: %v", err)
+		} else {
+			fmt.Printf("NAT port mapping: tcp %d ok\n", p)
+			srvCfg.NAT.DeleteMapping("tcp", p, p)
+		}
+	}
+
+	// Ask the reflector for the address the outside world sees.
+	if url := ctx.String(nettestReflectorFlag.Name); url != "" {
+		public, err := reflectIP(url)
+		if err != nil {
+			warn("Reflector query failed: %v", err)
+		} else {
+			fmt.Println("Public address:", public)
+			if externalIP != nil && !externalIP.Equal(public) {
+				warn("NAT external address %v differs from public address %v", externalIP, public)
+			}
+		}
+	}
+
+	// Join the network and wait for other nodes to dial in.
+	srv := &p2p.Server{Config: srvCfg}
+	events := make(chan *p2p.PeerEvent, 16)
+	sub := srv.SubscribeEvents(events)
+	defer sub.Unsubscribe()
+	if err := srv.Start(); err != nil {
+		utils.Fatalf("Failed to start p2p server: %v", err)
+	}
+	defer srv.Stop()
+
+	self := srv.Self()
+	fmt.Println("Advertised node:", self.String())
+	if srvCfg.NAT == nil && netutil.IsLAN(self.IP()) {
+		warn("Advertised address %v is not publicly routable, consider --%s", self.IP(), utils.NATFlag.Name)
+	}
+
+	duration := ctx.Duration(nettestDurationFlag.Name)
+	fmt.Printf("Waiting %v for inbound connections...\n", duration)
+	inbound, outbound := countPeers(srv, events, time.After(duration))
+
+	fmt.Printf("Peers: %d inbound, %d outbound\n", inbound, outbound)
+
+	switch {
+	case inbound == 0 && outbound == 0:
+		warn("No peers found, check the bootnodes and outbound firewall rules")
+	case inbound == 0:
+		warn("No inbound connections, the node is likely unreachable on port %s", port)
+	}
+	if len(warnings) == 0 {
+		fmt.Println("Connectivity OK")
+		return nil
+	}
+	for _, w := range warnings {
+		fmt.Println("WARNING:", w)
+	}
+	return fmt.Errorf("connectivity check failed with %d warnings", len(warnings))
+}
+
+// nettestProtocols returns stand-ins for the dex protocol versions. Without a
+// protocol in common, peers are dropped as useless before they are counted.
+// The stand-ins discard all messages until the peer disconnects.
+func nettestProtocols() []p2p.Protocol {
+	protocols := make([]p2p.Protocol, len(dex.ProtocolVersions))
+	for i, version := range dex.ProtocolVersions {
+		protocols[i] = p2p.Protocol{
+			Name:    dex.ProtocolName,
+			Version: version,
+			Length:  dex.ProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				for {
+					msg, err := rw.ReadMsg()
+					if err != nil {
+						return err
+					}
+					msg.Discard()
+				}
+			},
+		}
+	}
+	return protocols
+}
+
+// countPeers counts the distinct peers added to srv, as reported by the peer
+// events, by direction until done fires.
+func countPeers(srv *p2p.Server, events <-chan *p2p.PeerEvent, done <-chan time.Time) (inbound, outbound int) {
+	seen := make(map[enode.ID]bool) // Peer ID -> inbound
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type != p2p.PeerEventTypeAdd {
+				continue
+			}
+			for _, p := range srv.Peers() {
+				if p.ID() == ev.Peer {
+					seen[p.ID()] = p.Inbound()
+				}
+			}
+		case <-done:
+			for _, in := range seen {
+				if in {
+					inbound++
+				} else {
+					outbound++
+				}
+			}
+			return inbound, outbound
+		}
+	}
+}
+
+// reflectIP queries a reflector service for the public IP of the caller.
+func reflectIP(url string) (net.IP, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reflector replied %s", resp.Status)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return nil, fmt.Errorf("invalid reflector reply %q", body)
+	}
+	return ip, nil
+}
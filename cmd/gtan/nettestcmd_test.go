@@ -0,0 +1,97 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/p2p"
+	"github.com/portto/go-tangerine/p2p/enode"
+)
+
+func newNettestServer(t *testing.T) (*p2p.Server, chan *p2p.PeerEvent) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &p2p.Server{Config: p2p.Config{
+		PrivateKey:  key,
+		MaxPeers:    10,
+		ListenAddr:  "127.0.0.1:0",
+		NoDiscovery: true,
+		Protocols:   nettestProtocols(),
+	}}
+	events := make(chan *p2p.PeerEvent, 16)
+	srv.SubscribeEvents(events)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	return srv, events
+}
+
+// Tests that the peers connecting during the self-test are counted by
+// direction.
+func TestNettestCountPeers(t *testing.T) {
+	listener, listenerEvents := newNettestServer(t)
+	defer listener.Stop()
+	dialer, dialerEvents := newNettestServer(t)
+	defer dialer.Stop()
+
+	dialer.AddPeer(listener.Self())
+
+	done := make(chan time.Time)
+	go func() {
+		time.Sleep(2 * time.Second)
+		close(done)
+	}()
+	type counts struct{ inbound, outbound int }
+	results := make(chan counts, 2)
+	for _, s := range []struct {
+		srv    *p2p.Server
+		events chan *p2p.PeerEvent
+	}{{listener, listenerEvents}, {dialer, dialerEvents}} {
+		go func(srv *p2p.Server, events chan *p2p.PeerEvent) {
+			in, out := countPeers(srv, events, done)
+			results <- counts{in, out}
+		}(s.srv, s.events)
+	}
+	have := map[counts]int{<-results: 1}
+	have[<-results]++
+	if have[counts{1, 0}] != 1 || have[counts{0, 1}] != 1 {
+		t.Errorf("peer counts mismatch: have %v, want one inbound and one outbound", have)
+	}
+}
+
+// Tests that the self-test fails when no peers connect.
+func TestNettestNoPeers(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	bootnode := enode.NewV4(&key.PublicKey, []byte{127, 0, 0, 1}, 1, 1)
+
+	gtan := runGeth(t, "nettest", "--port", "0", "--nat", "none",
+		"--bootnodes", bootnode.String(), "--duration", "1s")
+	gtan.ExpectRegexp(`(?s)Peers: 0 inbound, 0 outbound.*WARNING: No peers found`)
+	gtan.WaitExit()
+	if status := gtan.ExitStatus(); status == 0 {
+		t.Errorf("nettest succeeded without peers")
+	}
+	if stderr := gtan.StderrText(); !strings.Contains(stderr, "connectivity check failed") {
+		t.Errorf("missing failure message in stderr: %s", stderr)
+	}
+}
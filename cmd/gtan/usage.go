@@ -73,10 +73,17 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.NoUSBFlag,
 			utils.NetworkIdFlag,
 			utils.TestnetFlag,
+			utils.NetworkFlag,
 			utils.SyncModeFlag,
+			utils.CheckpointFlag,
 			utils.GCModeFlag,
+			utils.TxLookupLimitFlag,
+			utils.ExtendedReceiptsFlag,
+			utils.ScrubIntervalFlag,
+			utils.PeerHistoryRetentionFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
+			utils.FeaturesFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
 			utils.LightKDFFlag,
@@ -152,6 +159,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCPortFlag,
 			utils.RPCApiFlag,
 			utils.RPCGlobalGasCap,
+			utils.RPCGovernanceGasCap,
+			utils.RPCFilterTimeoutFlag,
 			utils.WSEnabledFlag,
 			utils.WSListenAddrFlag,
 			utils.WSPortFlag,
@@ -161,6 +170,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
+			utils.RPCBatchRequestLimitFlag,
+			utils.RPCBatchResponseMaxSizeFlag,
+			utils.AuditProxyUserFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -174,6 +186,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.BootnodesV5Flag,
 			utils.ListenPortFlag,
 			utils.MaxPeersFlag,
+			utils.PeerScalingMarginFlag,
 			utils.MaxPendingPeersFlag,
 			utils.NATFlag,
 			utils.NoDiscoverFlag,
@@ -187,6 +200,14 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "BLOCK PROPOSER",
 		Flags: []cli.Flag{
 			utils.BlockProposerEnabledFlag,
+			utils.ExternalSignerFlag,
+			utils.DKGPasswordFileFlag,
+			utils.TxOrderingFlag,
+			utils.SafeModeFlag,
+			utils.AdaptiveLambdaFlag,
+			utils.AdaptiveLambdaMinFlag,
+			utils.AdaptiveLambdaMaxFlag,
+			utils.CoreMsgFutureToleranceFlag,
 		},
 	},
 	{
@@ -224,12 +245,39 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: append([]cli.Flag{
 			utils.FakePoWFlag,
 			utils.NoCompactionFlag,
+			utils.MsgProfilingLabelsFlag,
 		}, debug.Flags...),
 	},
+	{
+		Name: "ALERTS",
+		Flags: []cli.Flag{
+			utils.AlertWebhookFlag,
+			utils.AlertIntervalFlag,
+			utils.StateRootGossipFlag,
+		},
+	},
+	{
+		Name: "HEALTH PROBES",
+		Flags: []cli.Flag{
+			utils.HealthEnabledFlag,
+			utils.HealthListenAddrFlag,
+			utils.HealthPortFlag,
+		},
+	},
+	{
+		Name: "STATUS PAGE",
+		Flags: []cli.Flag{
+			utils.StatusPageEnabledFlag,
+			utils.StatusPageListenAddrFlag,
+			utils.StatusPagePortFlag,
+		},
+	},
 	{
 		Name: "METRICS AND STATS",
 		Flags: []cli.Flag{
 			utils.MetricsEnabledFlag,
+			utils.MetricsHTTPFlag,
+			utils.MetricsPortFlag,
 			utils.MetricsEnableInfluxDBFlag,
 			utils.MetricsInfluxDBEndpointFlag,
 			utils.MetricsInfluxDBDatabaseFlag,
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"crypto/ecdsa"
 	"encoding/hex"
 	"fmt"
 	"os"
 
+	"github.com/portto/go-tangerine/accounts/hdkey"
 	"github.com/portto/go-tangerine/cmd/utils"
 	"github.com/portto/go-tangerine/crypto"
 
@@ -24,6 +26,7 @@ func init() {
 	app = utils.NewApp(gitCommit, "DEXON node key manager")
 	app.Commands = []cli.Command{
 		commandGenerate,
+		commandDerive,
 		commandInspect,
 		commandPK2Addr,
 	}
@@ -64,6 +67,83 @@ var commandGenerate = cli.Command{
 	},
 }
 
+var (
+	mnemonicFlag = cli.StringFlag{
+		Name:  "mnemonic",
+		Usage: "mnemonic the keys of the test network are derived from",
+	}
+	passphraseFlag = cli.StringFlag{
+		Name:  "passphrase",
+		Usage: "passphrase protecting the mnemonic",
+	}
+	roleFlag = cli.StringFlag{
+		Name:  "role",
+		Usage: "role of the key: node, owner or monkey",
+		Value: "node",
+	}
+	indexFlag = cli.IntFlag{
+		Name:  "index",
+		Usage: "index of the node key",
+	}
+)
+
+var commandDerive = cli.Command{
+	Name:      "derive",
+	Usage:     "derive a test network keyfile from a mnemonic",
+	ArgsUsage: "[ <keyfile> ]",
+	Flags: []cli.Flag{
+		mnemonicFlag,
+		passphraseFlag,
+		roleFlag,
+		indexFlag,
+	},
+	Description: `
+Derive a key of a test network from a mnemonic, so that the same mnemonic
+regenerates the keys of the whole network. The node keys are derived at
+m/44'/60'/1'/0/<index>, the governance owner key at m/44'/60'/0'/0/0 and the
+zoo monkey key at m/44'/60'/0'/0/1.`,
+	Action: func(ctx *cli.Context) error {
+		keyfilepath := ctx.Args().First()
+		if keyfilepath == "" {
+			keyfilepath = defaultKeyfileName
+		}
+		if _, err := os.Stat(keyfilepath); err == nil {
+			utils.Fatalf("Keyfile already exists at %s.", keyfilepath)
+		} else if !os.IsNotExist(err) {
+			utils.Fatalf("Error checking if keyfile exists: %v", err)
+		}
+
+		wallet, err := hdkey.NewWallet(ctx.String(mnemonicFlag.Name), ctx.String(passphraseFlag.Name))
+		if err != nil {
+			utils.Fatalf("Failed to open mnemonic: %v", err)
+		}
+		var privKey *ecdsa.PrivateKey
+		switch role := ctx.String(roleFlag.Name); role {
+		case "node":
+			privKey, err = wallet.NodeKey(ctx.Int(indexFlag.Name))
+		case "owner":
+			privKey, err = wallet.OwnerKey()
+		case "monkey":
+			privKey, err = wallet.MonkeyKey()
+		default:
+			utils.Fatalf("Unknown key role %q.", role)
+		}
+		if err != nil {
+			utils.Fatalf("Failed to derive private key: %v", err)
+		}
+
+		address := crypto.PubkeyToAddress(privKey.PublicKey)
+		if err := crypto.SaveECDSA(keyfilepath, privKey); err != nil {
+			utils.Fatalf("Failed to save keyfile: %v", err)
+		}
+
+		fmt.Printf("Node Address: %s\n", address.String())
+		fmt.Printf("Public Key: 0x%s\n",
+			hex.EncodeToString(crypto.FromECDSAPub(&privKey.PublicKey)))
+		return nil
+	},
+}
+
 var commandInspect = cli.Command{
 	Name:        "inspect",
 	Usage:       "inspect a keyfile",
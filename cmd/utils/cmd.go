@@ -32,6 +32,7 @@ import (
 	"github.com/portto/go-tangerine/core/rawdb"
 	"github.com/portto/go-tangerine/core/types"
 	"github.com/portto/go-tangerine/crypto"
+	dexDB "github.com/portto/go-tangerine/dex/db"
 	"github.com/portto/go-tangerine/ethdb"
 	"github.com/portto/go-tangerine/internal/debug"
 	"github.com/portto/go-tangerine/log"
@@ -73,6 +74,9 @@ func StartNode(stack *node.Node) {
 		defer signal.Stop(sigc)
 		<-sigc
 		log.Info("Got interrupt, shutting down...")
+		if err := SdNotify(SdNotifyStopping); err != nil {
+			log.Warn("Failed to notify service manager", "err", err)
+		}
 		go stack.Stop()
 		for i := 10; i > 0; i-- {
 			<-sigc
@@ -161,9 +165,32 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 			log.Info("Skipping batch as all blocks present", "batch", batch, "first", blocks[0].Hash(), "last", blocks[i-1].Hash())
 			continue
 		}
-		if _, err := chain.InsertChain(missing); err != nil {
-			return fmt.Errorf("invalid block %d: %v", n, err)
+		if err := importBlocks(chain, missing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importBlocks verifies the DexconMeta of blocks and inserts them into chain,
+// one round at a time. The DKG group public key of a round is reconstructed
+// from the governance state of the chain, which has it once the previous
+// round is executed.
+func importBlocks(chain *core.BlockChain, blocks []*types.Block) error {
+	for len(blocks) > 0 {
+		end := 1
+		for end < len(blocks) && blocks[end].Round() == blocks[0].Round() {
+			end++
+		}
+		for _, block := range blocks[:end] {
+			if err := chain.VerifyDexconMeta(block.Header()); err != nil {
+				return fmt.Errorf("invalid block %d: %v", block.NumberU64(), err)
+			}
+		}
+		if i, err := chain.InsertChain(blocks[:end]); err != nil {
+			return fmt.Errorf("invalid block %d: %v", blocks[i].NumberU64(), err)
 		}
+		blocks = blocks[end:]
 	}
 	return nil
 }
@@ -310,3 +337,52 @@ func ExportPreimages(db *ethdb.LDBDatabase, fn string) error {
 	log.Info("Exported preimages", "file", fn)
 	return nil
 }
+
+// ImportCoreDB imports the consensus core data exported by ExportCoreDB from
+// the specified file.
+func ImportCoreDB(db ethdb.Database, fn string) (dexDB.ExportStats, error) {
+	log.Info("Importing consensus core data", "file", fn)
+
+	// Open the file handle and potentially unwrap the gzip stream
+	fh, err := os.Open(fn)
+	if err != nil {
+		return dexDB.ExportStats{}, err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return dexDB.ExportStats{}, err
+		}
+	}
+	return dexDB.NewDatabase(db).Import(reader)
+}
+
+// ExportCoreDB exports the core blocks, the position index of the finalized
+// ones, the compaction chain tip and the DKG protocol info into the specified
+// file, truncating any data already present in the file. The file is only
+// readable by the owner as the DKG protocol info holds the node's secret
+// shares.
+func ExportCoreDB(db ethdb.Database, fn string) (dexDB.ExportStats, error) {
+	log.Info("Exporting consensus core data", "file", fn)
+
+	// Open the file handle and potentially wrap with a gzip stream
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return dexDB.ExportStats{}, err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	stats, err := dexDB.NewDatabase(db).Export(writer)
+	if err != nil {
+		return stats, err
+	}
+	log.Info("Exported consensus core data", "file", fn)
+	return stats, nil
+}
@@ -46,9 +46,11 @@ import (
 	"github.com/portto/go-tangerine/eth/gasprice"
 	"github.com/portto/go-tangerine/ethdb"
 	"github.com/portto/go-tangerine/ethstats"
+	"github.com/portto/go-tangerine/internal/features"
 	"github.com/portto/go-tangerine/les"
 	"github.com/portto/go-tangerine/log"
 	"github.com/portto/go-tangerine/metrics"
+	"github.com/portto/go-tangerine/metrics/exp"
 	"github.com/portto/go-tangerine/metrics/influxdb"
 	"github.com/portto/go-tangerine/node"
 	"github.com/portto/go-tangerine/p2p"
@@ -137,6 +139,10 @@ var (
 		Name:  "testnet",
 		Usage: "Taiwan network: default public testnet",
 	}
+	NetworkFlag = cli.StringFlag{
+		Name:  "network",
+		Usage: "Named network preset to join (" + strings.Join(networkNames(), ", ") + ")",
+	}
 	ConstantinopleOverrideFlag = cli.Uint64Flag{
 		Name:  "override.constantinople",
 		Usage: "Manually specify constantinople fork-block, overriding the bundled setting",
@@ -161,14 +167,37 @@ var (
 	defaultSyncMode = dex.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "light" or "checkpoint")`,
 		Value: &defaultSyncMode,
 	}
+	CheckpointFlag = cli.StringFlag{
+		Name:  "checkpoint",
+		Usage: "Trusted finalized block checkpoint sync starts from (<number>=<hash>)",
+	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	TxLookupLimitFlag = cli.Uint64Flag{
+		Name:  "txlookuplimit",
+		Usage: "Number of recent blocks to maintain transactions index by hash for (default = index all blocks)",
+		Value: 0,
+	}
+	ExtendedReceiptsFlag = cli.BoolFlag{
+		Name:  "extendedreceipts",
+		Usage: "Store the touched accounts and gas refund of transactions for tan_getExtendedReceipt",
+	}
+	ScrubIntervalFlag = cli.DurationFlag{
+		Name:  "scrub.interval",
+		Usage: "Interval between integrity checks of randomly sampled stored blocks (0 = disabled)",
+		Value: 0,
+	}
+	PeerHistoryRetentionFlag = cli.DurationFlag{
+		Name:  "peerhistory.retention",
+		Usage: "How long peer connection events are kept for debug_peerHistory (0 = disabled)",
+		Value: dex.DefaultConfig.PeerHistoryRetention,
+	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (0-90)",
@@ -190,7 +219,7 @@ var (
 	// Dashboard settings
 	DashboardEnabledFlag = cli.BoolFlag{
 		Name:  metrics.DashboardEnabledFlag,
-		Usage: "Enable the dashboard",
+		Usage: "Enable the dashboard (deprecated, use --statuspage)",
 	}
 	DashboardAddrFlag = cli.StringFlag{
 		Name:  "dashboard.addr",
@@ -322,6 +351,23 @@ var (
 		Name:  "bp",
 		Usage: "Enable block proposer mode (node set)",
 	}
+	ExternalSignerFlag = cli.StringFlag{
+		Name:  "bp.signer",
+		Usage: "External signer endpoint signing consensus messages and governance transactions for the node key",
+	}
+	DKGPasswordFileFlag = cli.StringFlag{
+		Name:  "bp.dkgpassword",
+		Usage: "Password file encrypting the DKG secrets stored in the database",
+	}
+	TxOrderingFlag = cli.StringFlag{
+		Name:  "bp.txorder",
+		Usage: `Ordering of proposed transactions ("price", "fifo" or "fair")`,
+		Value: dex.DefaultConfig.TxOrdering,
+	}
+	SafeModeFlag = cli.BoolFlag{
+		Name:  "safe-mode",
+		Usage: "Start without networking and consensus, serving read-only RPC from the local database",
+	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -413,6 +459,16 @@ var (
 		Name:  "rpc.gascap",
 		Usage: "Sets a cap on gas that can be used in eth_call/estimateGas",
 	}
+	RPCGovernanceGasCap = cli.Uint64Flag{
+		Name:  "rpc.govgascap",
+		Usage: "Sets a cap on gas that can be used in eth_call on read-only governance methods",
+		Value: dex.DefaultConfig.RPCGovernanceGasCap.Uint64(),
+	}
+	RPCFilterTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.filtertimeout",
+		Usage: "Time after which filters that have not been polled are removed",
+		Value: dex.DefaultConfig.FilterTimeout,
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -451,6 +507,16 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.HTTPVirtualHosts, ","),
 	}
+	RPCBatchRequestLimitFlag = cli.IntFlag{
+		Name:  "rpc.batch-request-limit",
+		Usage: "Maximum number of requests in an HTTP or WebSocket RPC batch (0 = unlimited)",
+		Value: node.DefaultConfig.BatchRequestLimit,
+	}
+	RPCBatchResponseMaxSizeFlag = cli.IntFlag{
+		Name:  "rpc.batch-response-max-size",
+		Usage: "Maximum number of bytes returned by an HTTP or WebSocket RPC batch (0 = unlimited)",
+		Value: node.DefaultConfig.BatchResponseMaxSize,
+	}
 	RPCApiFlag = cli.StringFlag{
 		Name:  "rpcapi",
 		Usage: "API's offered over the HTTP-RPC interface",
@@ -503,6 +569,10 @@ var (
 		Usage: "Maximum number of network peers (network disabled if set to 0)",
 		Value: 100,
 	}
+	PeerScalingMarginFlag = cli.IntFlag{
+		Name:  "peerscaling.margin",
+		Usage: "Scale the peer limits to the notary set size plus this many peers (0 = use maxpeers)",
+	}
 	MaxPendingPeersFlag = cli.IntFlag{
 		Name:  "maxpendpeers",
 		Usage: "Maximum number of pending connection attempts (defaults used if set to 0)",
@@ -591,11 +661,53 @@ var (
 		Usage: "Restrict connection between two whisper light clients",
 	}
 
+	// Health probe flags
+	HealthEnabledFlag = cli.BoolFlag{
+		Name:  "health",
+		Usage: "Enable the HTTP liveness and readiness probe server",
+	}
+	HealthListenAddrFlag = cli.StringFlag{
+		Name:  "health.addr",
+		Usage: "Health probe server listening interface",
+		Value: "127.0.0.1",
+	}
+	HealthPortFlag = cli.IntFlag{
+		Name:  "health.port",
+		Usage: "Health probe server listening port",
+		Value: 6062,
+	}
+
+	// Status page flags
+	StatusPageEnabledFlag = cli.BoolFlag{
+		Name:  "statuspage",
+		Usage: "Enable the HTTP server of the node status page",
+	}
+	StatusPageListenAddrFlag = cli.StringFlag{
+		Name:  "statuspage.addr",
+		Usage: "Status page server listening interface",
+		Value: "127.0.0.1",
+	}
+	StatusPagePortFlag = cli.IntFlag{
+		Name:  "statuspage.port",
+		Usage: "Status page server listening port",
+		Value: 6063,
+	}
+
 	// Metrics flags
 	MetricsEnabledFlag = cli.BoolFlag{
 		Name:  metrics.MetricsEnabledFlag,
 		Usage: "Enable metrics collection and reporting",
 	}
+	MetricsHTTPFlag = cli.StringFlag{
+		Name:  "metrics.addr",
+		Usage: "Enable the Prometheus metrics server on /metrics, listening on the given interface",
+		Value: "",
+	}
+	MetricsPortFlag = cli.IntFlag{
+		Name:  "metrics.port",
+		Usage: "Metrics server listening port",
+		Value: 6061,
+	}
 	MetricsEnableInfluxDBFlag = cli.BoolFlag{
 		Name:  "metrics.influxdb",
 		Usage: "Enable metrics export/push to an external InfluxDB database",
@@ -632,7 +744,7 @@ var (
 
 	EWASMInterpreterFlag = cli.StringFlag{
 		Name:  "vm.ewasm",
-		Usage: "External ewasm configuration (default = built-in interpreter)",
+		Usage: "Registered ewasm engine, activated by the genesis ewasmBlock/ewasmRound (default = built-in engine)",
 		Value: "",
 	}
 	EVMInterpreterFlag = cli.StringFlag{
@@ -658,6 +770,50 @@ var (
 	}
 
 	// Dexcon settings.
+	FeaturesFlag = cli.StringFlag{
+		Name:  "features",
+		Usage: "Comma separated feature flags to enable, prefixed with - to disable",
+	}
+	MsgProfilingLabelsFlag = cli.BoolFlag{
+		Name:  "pprof.msglabels",
+		Usage: "Label protocol message handlers with the message type in profiles",
+	}
+	AlertWebhookFlag = cli.StringFlag{
+		Name:  "alert.webhook",
+		Usage: "Webhook URL consensus alerts are posted to, or comma separated event=URL pairs (events: notaryDropped, dkgMissed, disqualified, watchCat, proposingStopped, stateDivergence, * for the others)",
+	}
+	AlertIntervalFlag = cli.DurationFlag{
+		Name:  "alert.interval",
+		Usage: "Minimum time between two posts of the same consensus alert",
+		Value: dex.DefaultConfig.Alerts.Interval,
+	}
+	StateRootGossipFlag = cli.BoolFlag{
+		Name:  "stateroot.gossip",
+		Usage: "Gossip validator state roots at round boundaries and alert on divergence",
+	}
+	AdaptiveLambdaFlag = cli.BoolFlag{
+		Name:  "ba.adaptive",
+		Usage: "Let the BA interval follow the observed confirmation times",
+	}
+	AdaptiveLambdaMinFlag = cli.Float64Flag{
+		Name:  "ba.adaptive.min",
+		Usage: "Lowest adaptive BA interval, as a fraction of the governance lambda (at least 0.25)",
+		Value: dex.DefaultConfig.AdaptiveLambda.Min,
+	}
+	AdaptiveLambdaMaxFlag = cli.Float64Flag{
+		Name:  "ba.adaptive.max",
+		Usage: "Highest adaptive BA interval, as a fraction of the governance lambda (at most 1)",
+		Value: dex.DefaultConfig.AdaptiveLambda.Max,
+	}
+	CoreMsgFutureToleranceFlag = cli.DurationFlag{
+		Name:  "coremsg.futuretolerance",
+		Usage: "How far ahead of the local clock core blocks may be timestamped (0 = unlimited)",
+		Value: dex.DefaultConfig.CoreMsgFutureTolerance,
+	}
+	AuditProxyUserFlag = cli.BoolFlag{
+		Name:  "audit.proxyuser",
+		Usage: "Record the basic-auth user of RPC requests in the audit log (only behind an authenticating proxy)",
+	}
 	RecoveryNetworkRPCFlag = cli.StringFlag{
 		Name:  "recovery.network-rpc",
 		Usage: "RPC URL of the recovery network",
@@ -670,8 +826,8 @@ var (
 // the a subdirectory of the specified datadir will be used.
 func MakeDataDir(ctx *cli.Context) string {
 	if path := ctx.GlobalString(DataDirFlag.Name); path != "" {
-		if ctx.GlobalBool(TestnetFlag.Name) {
-			return filepath.Join(path, "testnet")
+		if network := SelectedNetwork(ctx); network != nil {
+			return filepath.Join(path, network.DataDir)
 		}
 		return path
 	}
@@ -716,6 +872,7 @@ func setNodeUserIdent(ctx *cli.Context, cfg *node.Config) {
 // flags, reverting to pre-configured ones if none have been specified.
 func setBootstrapNodes(ctx *cli.Context, cfg *p2p.Config) {
 	urls := params.MainnetBootnodes
+	network := SelectedNetwork(ctx)
 	switch {
 	case ctx.GlobalIsSet(BootnodesFlag.Name) || ctx.GlobalIsSet(BootnodesV4Flag.Name):
 		if ctx.GlobalIsSet(BootnodesV4Flag.Name) {
@@ -723,8 +880,8 @@ func setBootstrapNodes(ctx *cli.Context, cfg *p2p.Config) {
 		} else {
 			urls = strings.Split(ctx.GlobalString(BootnodesFlag.Name), ",")
 		}
-	case ctx.GlobalBool(TestnetFlag.Name):
-		urls = params.TestnetBootnodes
+	case network != nil:
+		urls = network.Bootnodes
 	case cfg.BootstrapNodes != nil:
 		return // already set, don't apply defaults.
 	}
@@ -818,6 +975,16 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setBatchLimits applies the RPC batch limits of the command line flags.
+func setBatchLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBatchRequestLimitFlag.Name) {
+		cfg.BatchRequestLimit = ctx.GlobalInt(RPCBatchRequestLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchResponseMaxSizeFlag.Name) {
+		cfg.BatchResponseMaxSize = ctx.GlobalInt(RPCBatchResponseMaxSizeFlag.Name)
+	}
+}
+
 // setWS creates the WebSocket RPC listener interface string from the set
 // command line flags, returning empty if the HTTP endpoint is disabled.
 func setWS(ctx *cli.Context, cfg *node.Config) {
@@ -994,6 +1161,17 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		cfg.NoDiscovery = true
 		cfg.DiscoveryV5 = false
 	}
+	if ctx.GlobalBool(SafeModeFlag.Name) {
+		// --safe-mode neither listens nor dials, not even the static nodes
+		// the node would load if left nil.
+		cfg.MaxPeers = 0
+		cfg.ListenAddr = ""
+		cfg.NoDial = true
+		cfg.NoDiscovery = true
+		cfg.DiscoveryV5 = false
+		cfg.StaticNodes = []*enode.Node{}
+		cfg.TrustedNodes = []*enode.Node{}
+	}
 }
 
 // SetNodeConfig applies node-related command line flags to the config.
@@ -1002,6 +1180,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setBatchLimits(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
 
@@ -1022,8 +1201,10 @@ func setDataDir(ctx *cli.Context, cfg *node.Config) {
 		cfg.DataDir = ctx.GlobalString(DataDirFlag.Name)
 	case ctx.GlobalBool(DeveloperFlag.Name):
 		cfg.DataDir = "" // unless explicitly requested, use memory databases
-	case ctx.GlobalBool(TestnetFlag.Name):
-		cfg.DataDir = filepath.Join(node.DefaultDataDir(), "testnet")
+	default:
+		if network := SelectedNetwork(ctx); network != nil {
+			cfg.DataDir = filepath.Join(node.DefaultDataDir(), network.DataDir)
+		}
 	}
 }
 
@@ -1079,6 +1260,29 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	}
 }
 
+func setAlerts(ctx *cli.Context, cfg *dex.AlertConfig) {
+	if ctx.GlobalIsSet(AlertWebhookFlag.Name) {
+		webhooks := make(map[string]string)
+		for _, entry := range strings.Split(ctx.GlobalString(AlertWebhookFlag.Name), ",") {
+			entry = strings.TrimSpace(entry)
+			event, url := "*", entry
+			// URLs may hold "=" in their queries, entries are only split on it
+			// when the part before holds no path.
+			if parts := strings.SplitN(entry, "=", 2); len(parts) == 2 && !strings.Contains(parts[0], "/") {
+				event, url = parts[0], parts[1]
+			}
+			if url == "" {
+				Fatalf("Invalid webhook in --%s: %s", AlertWebhookFlag.Name, entry)
+			}
+			webhooks[event] = url
+		}
+		cfg.Webhooks = webhooks
+	}
+	if ctx.GlobalIsSet(AlertIntervalFlag.Name) {
+		cfg.Interval = ctx.GlobalDuration(AlertIntervalFlag.Name)
+	}
+}
+
 func setEthash(ctx *cli.Context, cfg *eth.Config) {
 	if ctx.GlobalIsSet(EthashCacheDirFlag.Name) {
 		cfg.Ethash.CacheDir = ctx.GlobalString(EthashCacheDirFlag.Name)
@@ -1123,6 +1327,24 @@ func setWhitelist(ctx *cli.Context, cfg *dex.Config) {
 	}
 }
 
+// setCheckpoint parses the trusted checkpoint of checkpoint syncs.
+func setCheckpoint(ctx *cli.Context, cfg *dex.Config) {
+	checkpoint := ctx.GlobalString(CheckpointFlag.Name)
+	parts := strings.Split(checkpoint, "=")
+	if len(parts) != 2 {
+		Fatalf("Invalid checkpoint: %s", checkpoint)
+	}
+	number, err := strconv.ParseUint(parts[0], 0, 64)
+	if err != nil {
+		Fatalf("Invalid checkpoint block number %s: %v", parts[0], err)
+	}
+	var hash common.Hash
+	if err = hash.UnmarshalText([]byte(parts[1])); err != nil {
+		Fatalf("Invalid checkpoint hash %s: %v", parts[1], err)
+	}
+	cfg.Checkpoint = &downloader.Checkpoint{Number: number, Hash: hash}
+}
+
 // checkExclusive verifies that only a single instance of the provided flags was
 // set by the user. Each flag might optionally be followed by a string type to
 // specialize it further.
@@ -1181,6 +1403,8 @@ func SetShhConfig(ctx *cli.Context, stack *node.Node, cfg *whisper.Config) {
 func SetDexConfig(ctx *cli.Context, stack *node.Node, cfg *dex.Config) {
 	// Avoid conflicting network flags
 	checkExclusive(ctx, DeveloperFlag, TestnetFlag)
+	checkExclusive(ctx, DeveloperFlag, NetworkFlag)
+	checkExclusive(ctx, SafeModeFlag, BlockProposerEnabledFlag)
 	checkExclusive(ctx, LightServFlag, SyncModeFlag, "light")
 
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
@@ -1191,6 +1415,12 @@ func SetDexConfig(ctx *cli.Context, stack *node.Node, cfg *dex.Config) {
 	if ctx.GlobalIsSet(SyncModeFlag.Name) {
 		cfg.SyncMode = *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
 	}
+	if ctx.GlobalIsSet(CheckpointFlag.Name) {
+		setCheckpoint(ctx, cfg)
+	}
+	if cfg.SyncMode == downloader.CheckpointSync && cfg.Checkpoint == nil {
+		Fatalf("Checkpoint sync requires a trusted --%s", CheckpointFlag.Name)
+	}
 	if ctx.GlobalIsSet(LightServFlag.Name) {
 		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
 	}
@@ -1203,6 +1433,30 @@ func SetDexConfig(ctx *cli.Context, stack *node.Node, cfg *dex.Config) {
 	if ctx.GlobalIsSet(BlockProposerEnabledFlag.Name) {
 		cfg.BlockProposerEnabled = ctx.GlobalBool(BlockProposerEnabledFlag.Name)
 	}
+	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
+	}
+	if ctx.GlobalIsSet(DKGPasswordFileFlag.Name) {
+		text, err := ioutil.ReadFile(ctx.GlobalString(DKGPasswordFileFlag.Name))
+		if err != nil {
+			Fatalf("Failed to read DKG password file: %v", err)
+		}
+		cfg.DKGPassphrase = strings.TrimRight(strings.SplitN(string(text), "\n", 2)[0], "\r")
+		if cfg.DKGPassphrase == "" {
+			Fatalf("Empty DKG password in --%s", DKGPasswordFileFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(TxOrderingFlag.Name) {
+		switch order := ctx.GlobalString(TxOrderingFlag.Name); order {
+		case dex.TxOrderPrice, dex.TxOrderFIFO, dex.TxOrderFair:
+			cfg.TxOrdering = order
+		default:
+			Fatalf("Invalid --%s: %s", TxOrderingFlag.Name, order)
+		}
+	}
+	if ctx.GlobalIsSet(SafeModeFlag.Name) {
+		cfg.SafeMode = ctx.GlobalBool(SafeModeFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheDatabaseFlag.Name) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
@@ -1214,6 +1468,18 @@ func SetDexConfig(ctx *cli.Context, stack *node.Node, cfg *dex.Config) {
 	}
 	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
 
+	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
+		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(ExtendedReceiptsFlag.Name) {
+		cfg.ExtendedReceipts = ctx.GlobalBool(ExtendedReceiptsFlag.Name)
+	}
+	if ctx.GlobalIsSet(ScrubIntervalFlag.Name) {
+		cfg.ScrubInterval = ctx.GlobalDuration(ScrubIntervalFlag.Name)
+	}
+	if ctx.GlobalIsSet(PeerHistoryRetentionFlag.Name) {
+		cfg.PeerHistoryRetention = ctx.GlobalDuration(PeerHistoryRetentionFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1238,25 +1504,59 @@ func SetDexConfig(ctx *cli.Context, stack *node.Node, cfg *dex.Config) {
 	if ctx.GlobalIsSet(RPCGlobalGasCap.Name) {
 		cfg.RPCGasCap = new(big.Int).SetUint64(ctx.GlobalUint64(RPCGlobalGasCap.Name))
 	}
+	if ctx.GlobalIsSet(RPCGovernanceGasCap.Name) {
+		cfg.RPCGovernanceGasCap = new(big.Int).SetUint64(ctx.GlobalUint64(RPCGovernanceGasCap.Name))
+	}
+	if ctx.GlobalIsSet(RPCFilterTimeoutFlag.Name) {
+		cfg.FilterTimeout = ctx.GlobalDuration(RPCFilterTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(MsgProfilingLabelsFlag.Name) {
+		cfg.MsgProfilingLabels = ctx.GlobalBool(MsgProfilingLabelsFlag.Name)
+	}
+	if ctx.GlobalIsSet(PeerScalingMarginFlag.Name) {
+		cfg.PeerScalingMargin = ctx.GlobalInt(PeerScalingMarginFlag.Name)
+	}
+	if ctx.GlobalIsSet(StateRootGossipFlag.Name) {
+		cfg.StateRootGossip = ctx.GlobalBool(StateRootGossipFlag.Name)
+	}
+	setAlerts(ctx, &cfg.Alerts)
+	if ctx.GlobalIsSet(AdaptiveLambdaFlag.Name) {
+		cfg.AdaptiveLambda.Enabled = ctx.GlobalBool(AdaptiveLambdaFlag.Name)
+	}
+	if ctx.GlobalIsSet(AdaptiveLambdaMinFlag.Name) {
+		cfg.AdaptiveLambda.Min = ctx.GlobalFloat64(AdaptiveLambdaMinFlag.Name)
+	}
+	if ctx.GlobalIsSet(AdaptiveLambdaMaxFlag.Name) {
+		cfg.AdaptiveLambda.Max = ctx.GlobalFloat64(AdaptiveLambdaMaxFlag.Name)
+	}
+	if ctx.GlobalIsSet(AuditProxyUserFlag.Name) {
+		cfg.AuditProxyUser = ctx.GlobalBool(AuditProxyUserFlag.Name)
+	}
+	if ctx.GlobalIsSet(CoreMsgFutureToleranceFlag.Name) {
+		cfg.CoreMsgFutureTolerance = ctx.GlobalDuration(CoreMsgFutureToleranceFlag.Name)
+	}
 
-	cfg.RecoveryNetworkRPC = ctx.GlobalString(RecoveryNetworkRPCFlag.Name)
+	if ctx.GlobalIsSet(RecoveryNetworkRPCFlag.Name) {
+		cfg.RecoveryNetworkRPC = ctx.GlobalString(RecoveryNetworkRPCFlag.Name)
+	}
 	defaultRecoveryNetworkRPC := "https://rinkeby.infura.io"
 
 	// Override any default configs for hard coded networks.
+	network := SelectedNetwork(ctx)
 	switch {
-	case ctx.GlobalBool(TestnetFlag.Name):
+	case network != nil:
 		if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
-			cfg.NetworkId = 374
+			cfg.NetworkId = network.NetworkId
 		}
-		if !ctx.GlobalIsSet(RecoveryNetworkRPCFlag.Name) {
-			cfg.RecoveryNetworkRPC = defaultRecoveryNetworkRPC
+		if cfg.RecoveryNetworkRPC == "" {
+			cfg.RecoveryNetworkRPC = network.RecoveryNetworkRPC
 		}
-		cfg.Genesis = core.DefaultTestnetGenesisBlock()
+		cfg.Genesis = network.Genesis()
 	case ctx.GlobalBool(DeveloperFlag.Name):
 		if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
 			cfg.NetworkId = 1337
 		}
-		if !ctx.GlobalIsSet(RecoveryNetworkRPCFlag.Name) {
+		if cfg.RecoveryNetworkRPC == "" {
 			cfg.RecoveryNetworkRPC = defaultRecoveryNetworkRPC
 		}
 		// Create new developer account or reuse existing one
@@ -1279,6 +1579,9 @@ func SetDexConfig(ctx *cli.Context, stack *node.Node, cfg *dex.Config) {
 
 		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), developer.Address)
 	}
+	if cfg.RecoveryNetworkRPC == "" {
+		cfg.RecoveryNetworkRPC = RecoveryNetworkRPCFlag.Value
+	}
 	// TODO(fjl): move trie cache generations into config
 	if gen := ctx.GlobalInt(TrieCacheGenFlag.Name); gen > 0 {
 		state.MaxTrieCacheGen = uint16(gen)
@@ -1289,17 +1592,24 @@ func SetDexConfig(ctx *cli.Context, stack *node.Node, cfg *dex.Config) {
 }
 
 func setIndexerConfig(ctx *cli.Context, cfg *dex.Config) {
-	cfg.Indexer.Enable = ctx.GlobalBool(IndexerEnableFlag.Name)
+	if ctx.GlobalIsSet(IndexerEnableFlag.Name) {
+		cfg.Indexer.Enable = ctx.GlobalBool(IndexerEnableFlag.Name)
+	}
 	if !cfg.Indexer.Enable {
 		return
 	}
 
-	cfg.Indexer.Plugin = ctx.GlobalString(IndexerPluginFlag.Name)
-	cfg.Indexer.PluginFlags = ctx.GlobalString(IndexerPluginFlagsFlag.Name)
+	if ctx.GlobalIsSet(IndexerPluginFlag.Name) {
+		cfg.Indexer.Plugin = ctx.GlobalString(IndexerPluginFlag.Name)
+	}
+	if ctx.GlobalIsSet(IndexerPluginFlagsFlag.Name) {
+		cfg.Indexer.PluginFlags = ctx.GlobalString(IndexerPluginFlagsFlag.Name)
+	}
 	// copy required dex configs
 	cfg.Indexer.Genesis = cfg.Genesis
 	cfg.Indexer.NetworkID = cfg.NetworkId
 	cfg.Indexer.SyncMode = cfg.SyncMode
+	cfg.Indexer.ExtendedReceipts = cfg.ExtendedReceipts
 }
 
 // SetDashboardConfig applies dashboard related command line flags to the config.
@@ -1313,17 +1623,13 @@ func SetDashboardConfig(ctx *cli.Context, cfg *dashboard.Config) {
 func RegisterDexService(stack *node.Node, cfg *dex.Config) {
 	var err error
 	if cfg.SyncMode == downloader.LightSync {
-		//err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-		//	return les.New(ctx, cfg)
-		//})
+		err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+			return dex.NewLight(ctx, cfg)
+		})
 	} else {
 		err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 			cfg.PrivateKey = ctx.ServerConfig.PrivateKey
 			fullNode, err := dex.New(ctx, cfg)
-			//if fullNode != nil && cfg.LightServ > 0 {
-			//	ls, _ := les.NewLesServer(fullNode, cfg)
-			//	fullNode.AddLesServer(ls)
-			//}
 			return fullNode, err
 		})
 	}
@@ -1365,6 +1671,19 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 	}
 }
 
+// SetFeatures applies the feature flags requested on the command line.
+func SetFeatures(ctx *cli.Context) error {
+	if err := features.Apply(ctx.GlobalString(FeaturesFlag.Name)); err != nil {
+		return err
+	}
+	for _, status := range features.List() {
+		if status.Enabled != status.Default {
+			log.Info("Feature flag overridden", "name", status.Name, "enabled", status.Enabled, "source", status.Source)
+		}
+	}
+	return nil
+}
+
 func SetupMetrics(ctx *cli.Context) {
 	if metrics.Enabled {
 		log.Info("Enabling metrics collection")
@@ -1383,6 +1702,12 @@ func SetupMetrics(ctx *cli.Context) {
 
 			go influxdb.InfluxDBWithTags(metrics.DefaultRegistry, 10*time.Second, endpoint, database, username, password, "gtan.", tagsMap)
 		}
+
+		if ctx.GlobalIsSet(MetricsHTTPFlag.Name) {
+			address := fmt.Sprintf("%s:%d", ctx.GlobalString(MetricsHTTPFlag.Name), ctx.GlobalInt(MetricsPortFlag.Name))
+			log.Info("Enabling stand-alone metrics HTTP endpoint", "address", address)
+			exp.Setup(address)
+		}
 	}
 }
 
@@ -1422,9 +1747,9 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 
 func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	var genesis *core.Genesis
-	switch {
-	case ctx.GlobalBool(TestnetFlag.Name):
-		genesis = core.DefaultTestnetGenesisBlock()
+	switch network := SelectedNetwork(ctx); {
+	case network != nil:
+		genesis = network.Genesis()
 	case ctx.GlobalBool(DeveloperFlag.Name):
 		Fatalf("Developer chains are ephemeral")
 	}
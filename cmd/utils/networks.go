@@ -0,0 +1,92 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/params"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+// Network is a named preset bundling everything needed to join a well known
+// network. The DMoment of the network is part of the genesis chain config.
+type Network struct {
+	Name      string
+	NetworkId uint64
+	Bootnodes []string
+	Genesis   func() *core.Genesis
+
+	// RecoveryNetworkRPC is the RPC URL of the recovery network.
+	RecoveryNetworkRPC string
+
+	// DataDir is the subdirectory of the data directory used for the
+	// network, empty for the data directory itself.
+	DataDir string
+}
+
+// networks are the presets selectable with --network.
+var networks = map[string]*Network{
+	"mainnet": {
+		Name:      "mainnet",
+		NetworkId: 411,
+		Bootnodes: params.MainnetBootnodes,
+		Genesis:   core.DefaultGenesisBlock,
+
+		RecoveryNetworkRPC: "https://mainnet.infura.io",
+	},
+	"testnet": {
+		Name:      "testnet",
+		NetworkId: 374,
+		Bootnodes: params.TestnetBootnodes,
+		Genesis:   core.DefaultTestnetGenesisBlock,
+		DataDir:   "testnet",
+
+		RecoveryNetworkRPC: "https://rinkeby.infura.io",
+	},
+}
+
+// networkNames returns the sorted names of all network presets.
+func networkNames() []string {
+	names := make([]string, 0, len(networks))
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SelectedNetwork returns the network preset requested on the command line,
+// or nil if none was. The --testnet flag is a shorthand for --network testnet.
+func SelectedNetwork(ctx *cli.Context) *Network {
+	checkExclusive(ctx, NetworkFlag, TestnetFlag)
+
+	if ctx.GlobalBool(TestnetFlag.Name) {
+		return networks["testnet"]
+	}
+	if !ctx.GlobalIsSet(NetworkFlag.Name) {
+		return nil
+	}
+	name := ctx.GlobalString(NetworkFlag.Name)
+	network, ok := networks[strings.ToLower(name)]
+	if !ok {
+		Fatalf("Unknown network %q (available: %s)", name, strings.Join(networkNames(), ", "))
+	}
+	return network
+}
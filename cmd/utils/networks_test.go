@@ -0,0 +1,47 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/params"
+)
+
+func TestNetworkPresets(t *testing.T) {
+	tests := []struct {
+		name    string
+		genesis common.Hash
+	}{
+		{"mainnet", params.MainnetGenesisHash},
+		{"testnet", params.TestnetGenesisHash},
+	}
+	for _, test := range tests {
+		network, ok := networks[test.name]
+		if !ok {
+			t.Errorf("%s: preset missing", test.name)
+			continue
+		}
+		if hash := network.Genesis().ToBlock(nil).Hash(); hash != test.genesis {
+			t.Errorf("%s: genesis mismatch: have %x, want %x", test.name, hash, test.genesis)
+		}
+		if len(network.Bootnodes) == 0 {
+			t.Errorf("%s: no bootnodes", test.name)
+		}
+	}
+}
@@ -68,12 +68,22 @@ type TransferContext struct {
 }
 
 func (c *Client) PrepareTx(ctx *TransferContext) *types.Transaction {
+	tx, err := c.SignTransfer(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return tx
+}
+
+// SignTransfer builds and signs the transaction of ctx, filling in the nonce
+// and gas if unset.
+func (c *Client) SignTransfer(ctx *TransferContext) (*types.Transaction, error) {
 	if ctx.Nonce == math.MaxUint64 {
 		var err error
 		address := crypto.PubkeyToAddress(ctx.Key.PublicKey)
 		ctx.Nonce, err = c.PendingNonceAt(context.Background(), address)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 	}
 
@@ -83,13 +93,13 @@ func (c *Client) PrepareTx(ctx *TransferContext) *types.Transaction {
 			Data: ctx.Data,
 		})
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 	}
 
 	gasPrice, err := c.SuggestGasPrice(context.Background())
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	tx := types.NewTransaction(
@@ -101,12 +111,7 @@ func (c *Client) PrepareTx(ctx *TransferContext) *types.Transaction {
 		ctx.Data)
 
 	signer := types.NewEIP155Signer(c.networkID)
-	tx, err = types.SignTx(tx, signer, ctx.Key)
-	if err != nil {
-		panic(err)
-	}
-
-	return tx
+	return types.SignTx(tx, signer, ctx.Key)
 }
 
 func (c *Client) Transfer(ctx *TransferContext) {
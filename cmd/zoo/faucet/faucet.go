@@ -0,0 +1,261 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+// A testnet faucet dripping coins to the addresses asking for them.
+
+package faucet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/portto/go-tangerine/cmd/zoo/client"
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/params"
+)
+
+type FaucetConfig struct {
+	Key      string
+	Endpoint string
+	Listen   string
+	Amount   int           // DXN dripped per request
+	Interval time.Duration // Minimum time between drips to an address or IP
+
+	// Captcha is the URL of a reCAPTCHA compatible verification endpoint,
+	// captcha checking is disabled if empty.
+	Captcha       string
+	CaptchaSecret string
+}
+
+// Verifier checks the captcha response sent along a drip request.
+type Verifier interface {
+	Verify(response, remoteIP string) error
+}
+
+// captchaVerifier verifies captcha responses against a reCAPTCHA compatible
+// verification endpoint.
+type captchaVerifier struct {
+	url    string
+	secret string
+}
+
+func (v *captchaVerifier) Verify(response, remoteIP string) error {
+	res, err := http.PostForm(v.url, url.Values{
+		"secret":   {v.secret},
+		"response": {response},
+		"remoteip": {remoteIP},
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var result struct {
+		Success bool     `json:"success"`
+		Errors  []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("captcha rejected: %v", result.Errors)
+	}
+	return nil
+}
+
+// limiter remembers the last drip time of addresses and IPs.
+type limiter struct {
+	interval time.Duration
+
+	lock sync.Mutex
+	last map[string]time.Time
+}
+
+func newLimiter(interval time.Duration) *limiter {
+	return &limiter{
+		interval: interval,
+		last:     make(map[string]time.Time),
+	}
+}
+
+// allow records a drip for all keys at now, returning the time to wait
+// instead if any of them got a drip within the interval.
+func (l *limiter) allow(now time.Time, keys ...string) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for key, last := range l.last {
+		if now.Sub(last) >= l.interval {
+			delete(l.last, key)
+		}
+	}
+	for _, key := range keys {
+		if last, ok := l.last[key]; ok {
+			return l.interval - now.Sub(last)
+		}
+	}
+	for _, key := range keys {
+		l.last[key] = now
+	}
+	return 0
+}
+
+// forget removes the drip records of keys, for drips that failed.
+func (l *limiter) forget(keys ...string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, key := range keys {
+		delete(l.last, key)
+	}
+}
+
+type Faucet struct {
+	client.Client
+
+	source   *ecdsa.PrivateKey
+	amount   *big.Int
+	limiter  *limiter
+	verifier Verifier
+
+	lock  sync.Mutex
+	nonce uint64
+}
+
+func New(ep string, source *ecdsa.PrivateKey, amount *big.Int,
+	interval time.Duration, verifier Verifier) *Faucet {
+	client, err := client.New(ep)
+	if err != nil {
+		panic(err)
+	}
+	return &Faucet{
+		Client:   *client,
+		source:   source,
+		amount:   amount,
+		limiter:  newLimiter(interval),
+		verifier: verifier,
+		nonce:    math.MaxUint64,
+	}
+}
+
+// Drip sends the drip amount to address.
+func (f *Faucet) Drip(address common.Address) (common.Hash, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	ctx := &client.TransferContext{
+		Key:       f.source,
+		ToAddress: address,
+		Amount:    f.amount,
+		Nonce:     f.nonce,
+		Gas:       params.TxGas,
+	}
+	tx, err := f.SignTransfer(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := f.SendTransaction(context.Background(), tx); err != nil {
+		// Fetch the nonce again on the next drip in case it went out of
+		// sync with the pool.
+		f.nonce = math.MaxUint64
+		return common.Hash{}, err
+	}
+	f.nonce = ctx.Nonce + 1
+	return tx.Hash(), nil
+}
+
+type dripResult struct {
+	Hash  *common.Hash `json:"hash,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+// ServeHTTP serves drip requests posted with the address and the captcha
+// response as form values.
+func (f *Faucet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reply := func(status int, result *dripResult) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(result)
+	}
+	if r.Method != http.MethodPost {
+		reply(http.StatusMethodNotAllowed, &dripResult{Error: "POST required"})
+		return
+	}
+	if !common.IsHexAddress(r.FormValue("address")) {
+		reply(http.StatusBadRequest, &dripResult{Error: "invalid address"})
+		return
+	}
+	address := common.HexToAddress(r.FormValue("address"))
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if f.verifier != nil {
+		if err := f.verifier.Verify(r.FormValue("captcha"), ip); err != nil {
+			reply(http.StatusForbidden, &dripResult{Error: err.Error()})
+			return
+		}
+	}
+
+	keys := []string{address.Hex(), ip}
+	if wait := f.limiter.allow(time.Now(), keys...); wait > 0 {
+		reply(http.StatusTooManyRequests, &dripResult{
+			Error: fmt.Sprintf("retry in %v", wait.Round(time.Second)),
+		})
+		return
+	}
+	hash, err := f.Drip(address)
+	if err != nil {
+		f.limiter.forget(keys...)
+		fmt.Println("Failed to drip", address.Hex(), err)
+		reply(http.StatusInternalServerError, &dripResult{Error: "failed to send transaction"})
+		return
+	}
+	fmt.Println("Dripped", address.Hex(), "ip", ip, "tx", hash.Hex())
+	reply(http.StatusOK, &dripResult{Hash: &hash})
+}
+
+func Serve(config *FaucetConfig) {
+	privKey, err := crypto.LoadECDSA(config.Key)
+	if err != nil {
+		panic(err)
+	}
+
+	var verifier Verifier
+	if config.Captcha != "" {
+		verifier = &captchaVerifier{url: config.Captcha, secret: config.CaptchaSecret}
+	}
+	amount := new(big.Int).Mul(big.NewInt(int64(config.Amount)), big.NewInt(params.Ether))
+	faucet := New(config.Endpoint, privKey, amount, config.Interval, verifier)
+
+	fmt.Printf("Faucet of %s dripping %d DXN listening on %s\n",
+		crypto.PubkeyToAddress(privKey.PublicKey).Hex(), config.Amount, config.Listen)
+	if err := http.ListenAndServe(config.Listen, faucet); err != nil {
+		panic(err)
+	}
+}
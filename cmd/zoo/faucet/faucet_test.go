@@ -0,0 +1,184 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package faucet
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/common/hexutil"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/rlp"
+	"github.com/portto/go-tangerine/rpc"
+)
+
+// TestNode answers the calls the faucet makes to a node. It is exported to be
+// registered as an RPC service.
+type TestNode struct {
+	lock  sync.Mutex
+	nonce uint64
+	fail  bool
+	txs   []*types.Transaction
+}
+
+func (n *TestNode) Version() string { return "237" }
+
+func (n *TestNode) GetTransactionCount(address common.Address, block string) hexutil.Uint64 {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return hexutil.Uint64(n.nonce)
+}
+
+func (n *TestNode) GasPrice() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(1))
+}
+
+func (n *TestNode) SendRawTransaction(data hexutil.Bytes) (common.Hash, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.fail {
+		return common.Hash{}, errors.New("pool full")
+	}
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(data, tx); err != nil {
+		return common.Hash{}, err
+	}
+	n.txs = append(n.txs, tx)
+	return tx.Hash(), nil
+}
+
+type rejectVerifier struct{}
+
+func (rejectVerifier) Verify(response, remoteIP string) error {
+	if response != "ok" {
+		return errors.New("captcha rejected")
+	}
+	return nil
+}
+
+// Tests that drips are limited per address and per IP, that failed drips
+// don't count against the limit, and that invalid requests are rejected.
+func TestFaucet(t *testing.T) {
+	node := &TestNode{nonce: 5}
+	server := rpc.NewServer()
+	if err := server.RegisterName("net", node); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("eth", node); err != nil {
+		t.Fatal(err)
+	}
+	endpoint := httptest.NewServer(server)
+	defer endpoint.Close()
+
+	key, _ := crypto.GenerateKey()
+	faucet := New(endpoint.URL, key, big.NewInt(100), time.Hour, rejectVerifier{})
+
+	drip := func(method, address, ip, captcha string) (int, *dripResult) {
+		form := url.Values{"address": {address}, "captcha": {captcha}}
+		req := httptest.NewRequest(method, "/", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		faucet.ServeHTTP(rec, req)
+
+		result := new(dripResult)
+		if err := json.NewDecoder(rec.Body).Decode(result); err != nil {
+			t.Fatalf("failed to decode reply: %v", err)
+		}
+		return rec.Code, result
+	}
+	addresses := []string{
+		common.Address{1}.Hex(),
+		common.Address{2}.Hex(),
+		common.Address{3}.Hex(),
+	}
+
+	if code, _ := drip(http.MethodGet, addresses[0], "10.0.0.1", "ok"); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status mismatch: have %d, want %d", code, http.StatusMethodNotAllowed)
+	}
+	if code, _ := drip(http.MethodPost, "0x1234", "10.0.0.1", "ok"); code != http.StatusBadRequest {
+		t.Errorf("invalid address status mismatch: have %d, want %d", code, http.StatusBadRequest)
+	}
+	if code, _ := drip(http.MethodPost, addresses[0], "10.0.0.1", "bad"); code != http.StatusForbidden {
+		t.Errorf("bad captcha status mismatch: have %d, want %d", code, http.StatusForbidden)
+	}
+
+	code, result := drip(http.MethodPost, addresses[0], "10.0.0.1", "ok")
+	if code != http.StatusOK || result.Hash == nil {
+		t.Fatalf("drip failed: %d %+v", code, result)
+	}
+	if len(node.txs) != 1 {
+		t.Fatalf("sent transaction count mismatch: have %d, want 1", len(node.txs))
+	}
+	tx := node.txs[0]
+	if tx.Hash() != *result.Hash || tx.Nonce() != 5 || *tx.To() != (common.Address{1}) || tx.Value().Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("dripped transaction mismatch: %v", tx)
+	}
+
+	// The address and the IP wait for the interval.
+	if code, _ := drip(http.MethodPost, addresses[0], "10.0.0.2", "ok"); code != http.StatusTooManyRequests {
+		t.Errorf("repeated address status mismatch: have %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code, _ := drip(http.MethodPost, addresses[1], "10.0.0.1", "ok"); code != http.StatusTooManyRequests {
+		t.Errorf("repeated IP status mismatch: have %d, want %d", code, http.StatusTooManyRequests)
+	}
+
+	// A failed drip can be retried right away, with the nonce fetched again.
+	node.lock.Lock()
+	node.fail = true
+	node.lock.Unlock()
+	if code, _ := drip(http.MethodPost, addresses[2], "10.0.0.3", "ok"); code != http.StatusInternalServerError {
+		t.Errorf("failed drip status mismatch: have %d, want %d", code, http.StatusInternalServerError)
+	}
+	node.lock.Lock()
+	node.fail, node.nonce = false, 9
+	node.lock.Unlock()
+	if code, result := drip(http.MethodPost, addresses[2], "10.0.0.3", "ok"); code != http.StatusOK {
+		t.Fatalf("retried drip failed: %d %+v", code, result)
+	}
+	if nonce := node.txs[1].Nonce(); nonce != 9 {
+		t.Errorf("retried drip nonce mismatch: have %d, want 9", nonce)
+	}
+}
+
+// Tests that drip records expire after the interval.
+func TestLimiter(t *testing.T) {
+	l := newLimiter(time.Minute)
+	now := time.Now()
+	if wait := l.allow(now, "a", "ip"); wait != 0 {
+		t.Fatalf("first drip refused, wait %v", wait)
+	}
+	if wait := l.allow(now.Add(20*time.Second), "b", "ip"); wait != 40*time.Second {
+		t.Errorf("wait mismatch: have %v, want 40s", wait)
+	}
+	// The refused drip recorded nothing.
+	if wait := l.allow(now.Add(time.Minute), "b", "ip"); wait != 0 {
+		t.Errorf("drip refused after the interval, wait %v", wait)
+	}
+}
@@ -2,12 +2,15 @@ package main
 
 import (
 	"flag"
+	"time"
 
+	"github.com/portto/go-tangerine/cmd/zoo/faucet"
 	"github.com/portto/go-tangerine/cmd/zoo/monkey"
 	"github.com/portto/go-tangerine/cmd/zoo/utils"
 )
 
 var key = flag.String("key", "", "private key path")
+var mnemonic = flag.String("mnemonic", "", "derive the private key, unless -key is set, and the random accounts from this mnemonic")
 var endpoint = flag.String("endpoint", "http://127.0.0.1:8545", "JSON RPC endpoint")
 var n = flag.Int("n", 100, "number of random accounts")
 var gambler = flag.Bool("gambler", false, "make this monkey a gambler")
@@ -16,6 +19,11 @@ var sleep = flag.Int("sleep", 500, "time in millisecond that monkeys sleep betwe
 var feeder = flag.Bool("feeder", false, "make this monkey a feeder")
 var timeout = flag.Int("timeout", 0, "execution time limit after start")
 var shutdown = flag.String("shutdown", "", "shutdown the previously opened zoo")
+var faucetListen = flag.String("faucet", "", "serve a faucet on this address instead of running monkeys")
+var faucetAmount = flag.Int("faucet.amount", 10, "DXN dripped per faucet request")
+var faucetInterval = flag.Duration("faucet.interval", 24*time.Hour, "minimum time between drips to an address or IP")
+var faucetCaptcha = flag.String("faucet.captcha", "", "reCAPTCHA compatible verification URL (disabled if empty)")
+var faucetSecret = flag.String("faucet.secret", "", "captcha verification secret")
 
 func main() {
 	flag.Parse()
@@ -30,8 +38,22 @@ func main() {
 		return
 	}
 
+	if *faucetListen != "" {
+		faucet.Serve(&faucet.FaucetConfig{
+			Key:           *key,
+			Endpoint:      *endpoint,
+			Listen:        *faucetListen,
+			Amount:        *faucetAmount,
+			Interval:      *faucetInterval,
+			Captcha:       *faucetCaptcha,
+			CaptchaSecret: *faucetSecret,
+		})
+		return
+	}
+
 	monkey.Init(&monkey.MonkeyConfig{
 		Key:      *key,
+		Mnemonic: *mnemonic,
 		Endpoint: *endpoint,
 		N:        *n,
 		Gambler:  *gambler,
@@ -29,6 +29,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/portto/go-tangerine/accounts/hdkey"
 	"github.com/portto/go-tangerine/cmd/zoo/client"
 	"github.com/portto/go-tangerine/crypto"
 )
@@ -37,6 +38,7 @@ var config *MonkeyConfig
 
 type MonkeyConfig struct {
 	Key      string
+	Mnemonic string // Derives the key, if not set, and the accounts
 	Endpoint string
 	N        int
 	Gambler  bool
@@ -58,7 +60,9 @@ type Monkey struct {
 	timer  <-chan time.Time
 }
 
-func New(ep string, source *ecdsa.PrivateKey, num int, timeout time.Duration) *Monkey {
+// New creates a monkey with num random accounts, or the first num zoo
+// accounts of wallet if not nil.
+func New(ep string, source *ecdsa.PrivateKey, wallet *hdkey.Wallet, num int, timeout time.Duration) *Monkey {
 	client, err := client.New(ep)
 	if err != nil {
 		panic(err)
@@ -83,17 +87,23 @@ func New(ep string, source *ecdsa.PrivateKey, num int, timeout time.Duration) *M
 	defer file.Close()
 
 	var keys []*ecdsa.PrivateKey
-
-	for i := 0; i < num; i++ {
-		key, err := crypto.GenerateKey()
-		if err != nil {
+	if wallet != nil {
+		if keys, err = wallet.Keys(hdkey.ZooKeyPath, num); err != nil {
 			panic(err)
 		}
-		_, err = file.Write([]byte(hex.EncodeToString(crypto.FromECDSA(key)) + "\n"))
+	}
+	for i := 0; i < num; i++ {
+		if wallet == nil {
+			key, err := crypto.GenerateKey()
+			if err != nil {
+				panic(err)
+			}
+			keys = append(keys, key)
+		}
+		_, err = file.Write([]byte(hex.EncodeToString(crypto.FromECDSA(keys[i])) + "\n"))
 		if err != nil {
 			panic(err)
 		}
-		keys = append(keys, key)
 	}
 
 	monkey := &Monkey{
@@ -183,12 +193,26 @@ func (m *Monkey) Keys() []*ecdsa.PrivateKey {
 }
 
 func Exec() (*Monkey, uint64) {
-	privKey, err := crypto.LoadECDSA(config.Key)
+	var (
+		wallet  *hdkey.Wallet
+		privKey *ecdsa.PrivateKey
+		err     error
+	)
+	if config.Mnemonic != "" {
+		if wallet, err = hdkey.NewWallet(config.Mnemonic, ""); err != nil {
+			panic(err)
+		}
+	}
+	if config.Key != "" || wallet == nil {
+		privKey, err = crypto.LoadECDSA(config.Key)
+	} else {
+		privKey, err = wallet.MonkeyKey()
+	}
 	if err != nil {
 		panic(err)
 	}
 
-	m := New(config.Endpoint, privKey, config.N, time.Duration(config.Timeout))
+	m := New(config.Endpoint, privKey, wallet, config.N, time.Duration(config.Timeout))
 	m.Distribute()
 	var finalNonce uint64
 	if config.Gambler {
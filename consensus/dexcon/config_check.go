@@ -0,0 +1,77 @@
+// Copyright 2019 The DEXON Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dexcon
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/portto/go-tangerine/params"
+)
+
+const (
+	// millisecondsPerYear is the length of the year the block reward is
+	// derived from.
+	millisecondsPerYear = 86400 * 1000 * 365
+
+	// maxRoundLength is the longest round the block reward formula can
+	// divide by without overflowing.
+	maxRoundLength = math.MaxInt64 / millisecondsPerYear
+
+	// dkgPhases is the number of DKG phases run by the consensus core, each
+	// lasting LambdaDKG.
+	dkgPhases = 7
+)
+
+var (
+	errZeroRoundLength      = errors.New("zero round length")
+	errZeroMinBlockInterval = errors.New("zero minimum block interval")
+	errZeroLambdaBA         = errors.New("zero BA lambda")
+	errRoundLengthTooLong   = fmt.Errorf("round length exceeds %d blocks", uint64(maxRoundLength))
+	errDKGPhaseTooShort     = errors.New("DKG phase shorter than the minimum block interval")
+)
+
+// VerifyConfiguration checks that the invariants the consensus core and the
+// block reward derive from the governance configuration hold: the reward
+// formula has non-zero denominators, and all DKG phases fit between the DKG
+// preparation and reset heights of a round.
+func VerifyConfiguration(config *params.DexconConfig) error {
+	switch {
+	case config.RoundLength == 0:
+		return errZeroRoundLength
+	case config.MinBlockInterval == 0:
+		return errZeroMinBlockInterval
+	case config.LambdaBA == 0:
+		return errZeroLambdaBA
+	case config.RoundLength > maxRoundLength:
+		return errRoundLengthTooLong
+	case config.LambdaDKG < config.MinBlockInterval:
+		return errDKGPhaseTooShort
+	}
+	// Mirrors the heights of utils.RoundEventParam in the consensus core.
+	var (
+		phaseHeight = config.LambdaDKG / config.MinBlockInterval
+		begin       = config.RoundLength * 2 / 3
+		reset       = config.RoundLength * 85 / 100
+	)
+	if reset < begin || phaseHeight > (reset-begin)/dkgPhases {
+		return fmt.Errorf("%d DKG phases of %d blocks exceed the %d blocks available in a round of %d blocks",
+			dkgPhases, phaseHeight, reset-begin, config.RoundLength)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 The DEXON Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dexcon
+
+import (
+	"testing"
+
+	"github.com/portto/go-tangerine/params"
+)
+
+func TestVerifyConfiguration(t *testing.T) {
+	for _, config := range []*params.ChainConfig{params.MainnetChainConfig, params.TestnetChainConfig} {
+		if err := VerifyConfiguration(config.Dexcon); err != nil {
+			t.Errorf("chain %v: configuration refused: %v", config.ChainID, err)
+		}
+	}
+
+	base := *params.MainnetChainConfig.Dexcon
+	tests := []struct {
+		modify func(*params.DexconConfig)
+		valid  bool
+	}{
+		{func(c *params.DexconConfig) {}, true},
+		{func(c *params.DexconConfig) { c.RoundLength = 0 }, false},
+		{func(c *params.DexconConfig) { c.MinBlockInterval = 0 }, false},
+		{func(c *params.DexconConfig) { c.LambdaBA = 0 }, false},
+		{func(c *params.DexconConfig) { c.RoundLength = maxRoundLength + 1 }, false},
+		{func(c *params.DexconConfig) { c.LambdaDKG = c.MinBlockInterval - 1 }, false},
+		// Seven phases of 20 blocks need 140 of the blocks between 2/3 and
+		// 85% of the round.
+		{func(c *params.DexconConfig) { c.RoundLength = 770 }, true},
+		{func(c *params.DexconConfig) { c.RoundLength = 700 }, false},
+		{func(c *params.DexconConfig) { c.MinBlockInterval = 10 }, false},
+	}
+	for i, tt := range tests {
+		config := base
+		tt.modify(&config)
+		if err := VerifyConfiguration(&config); (err == nil) != tt.valid {
+			t.Errorf("test %d: valid mismatch: have %v, want %v", i, err, tt.valid)
+		}
+	}
+}
@@ -27,15 +27,20 @@ import (
 	"github.com/portto/go-tangerine/core/types"
 	"github.com/portto/go-tangerine/core/vm"
 	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/metrics"
+	"github.com/portto/go-tangerine/params"
 	"github.com/portto/go-tangerine/rpc"
 	dexCore "github.com/portto/tangerine-consensus/core"
 )
 
 type GovernanceStateFetcher interface {
 	GetConfigState(round uint64) (*vm.GovernanceState, error)
+	GetConfigSnapshot(round uint64) (*vm.ConfigSnapshot, error)
 	DKGSetNodeKeyAddresses(round uint64) (map[common.Address]struct{}, error)
 }
 
+var dangerousConfigMeter = metrics.NewRegisteredMeter("dexcon/config/dangerous", nil)
+
 // Dexcon is a delegated proof-of-stake consensus engine.
 type Dexcon struct {
 	govStateFetcer GovernanceStateFetcher
@@ -114,12 +119,12 @@ func (d *Dexcon) Prepare(chain consensus.ChainReader, header *types.Header) erro
 
 func (d *Dexcon) inExtendedRound(header *types.Header, state *state.StateDB) bool {
 	gs := vm.GovernanceState{state}
-	rgs, err := d.govStateFetcer.GetConfigState(header.Round)
+	config, err := d.govStateFetcer.GetConfigSnapshot(header.Round)
 	if err != nil {
 		panic(err)
 	}
 
-	roundEnd := gs.RoundHeight(new(big.Int).SetUint64(header.Round)).Uint64() + rgs.RoundLength().Uint64()
+	roundEnd := gs.RoundHeight(new(big.Int).SetUint64(header.Round)).Uint64() + config.Config.RoundLength
 
 	// Round 0 starts and height 0 instead of height 1.
 	if header.Round == 0 {
@@ -129,12 +134,16 @@ func (d *Dexcon) inExtendedRound(header *types.Header, state *state.StateDB) boo
 }
 
 func (d *Dexcon) calculateBlockReward(round uint64) *big.Int {
-	gs, err := d.govStateFetcer.GetConfigState(round)
+	config, err := d.govStateFetcer.GetConfigSnapshot(round)
 	if err != nil {
 		panic(err)
 	}
-	config := gs.Configuration()
+	return BlockReward(config.Config, config.TotalStaked)
+}
 
+// BlockReward returns the reward of a block proposed under config with
+// totalStaked staked in total.
+func BlockReward(config *params.DexconConfig, totalStaked *big.Int) *big.Int {
 	blocksPerRound := config.RoundLength
 	roundInterval := new(big.Float).Mul(
 		big.NewFloat(float64(blocksPerRound)),
@@ -144,12 +153,12 @@ func (d *Dexcon) calculateBlockReward(round uint64) *big.Int {
 	numerator, _ := new(big.Float).Mul(
 		new(big.Float).Mul(
 			big.NewFloat(float64(config.MiningVelocity)),
-			new(big.Float).SetInt(gs.TotalStaked())),
+			new(big.Float).SetInt(totalStaked)),
 		roundInterval).Int(nil)
 
 	reward := new(big.Int).Div(numerator,
 		new(big.Int).Mul(
-			big.NewInt(86400*1000*365),
+			big.NewInt(millisecondsPerYear),
 			big.NewInt(int64(blocksPerRound))))
 
 	return reward
@@ -160,6 +169,11 @@ func (d *Dexcon) calculateBlockReward(round uint64) *big.Int {
 func (d *Dexcon) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
 	gs := vm.GovernanceState{state}
 
+	// Mark the governance storage layout when the marker fork activates.
+	if fork := chain.Config().GovernanceLayoutBlock; fork != nil && fork.Cmp(header.Number) == 0 {
+		gs.MigrateLayout()
+	}
+
 	height := gs.RoundHeight(new(big.Int).SetUint64(header.Round))
 
 	// The first block of a round is found.
@@ -201,6 +215,16 @@ func (d *Dexcon) Finalize(chain consensus.ChainReader, header *types.Header, sta
 		}
 	}
 
+	// Flag the configuration changes breaking the consensus core before they
+	// take effect.
+	if configurationChanged(receipts) {
+		if err := VerifyConfiguration(gs.Configuration()); err != nil {
+			dangerousConfigMeter.Mark(1)
+			log.Error("Dangerous governance configuration", "number", header.Number,
+				"round", header.Round+dexCore.ConfigRoundShift, "err", err)
+		}
+	}
+
 	// Distribute block reward and halving condition.
 	reward := new(big.Int)
 
@@ -211,7 +235,11 @@ func (d *Dexcon) Finalize(chain consensus.ChainReader, header *types.Header, sta
 	}
 
 	header.Reward = reward
-	state.AddBalance(header.Coinbase, reward)
+	rewardAddr := header.Coinbase
+	if chain.Config().IsRewardAddress(header.Number) {
+		rewardAddr = gs.BlockRewardAddress(header.Coinbase)
+	}
+	state.AddBalance(rewardAddr, reward)
 	gs.IncTotalSupply(reward)
 
 	// Check if halving checkpoint reached.
@@ -229,6 +257,20 @@ func (d *Dexcon) Finalize(chain consensus.ChainReader, header *types.Header, sta
 	return types.NewBlock(header, txs, uncles, receipts), nil
 }
 
+// configurationChanged reports whether the governance configuration was
+// updated by the transactions of the receipts.
+func configurationChanged(receipts []*types.Receipt) bool {
+	topic := vm.GovernanceABI.Events["ConfigurationChanged"].Id()
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			if l.Address == vm.GovernanceContractAddress && len(l.Topics) > 0 && l.Topics[0] == topic {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Seal implements consensus.Engine, attempting to create a sealed block using
 // the local signing credentials.
 func (d *Dexcon) Seal(chain consensus.ChainReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
@@ -24,7 +24,9 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/consensus"
 	"github.com/portto/go-tangerine/core/state"
+	"github.com/portto/go-tangerine/core/types"
 	"github.com/portto/go-tangerine/core/vm"
 	"github.com/portto/go-tangerine/crypto"
 	"github.com/portto/go-tangerine/ethdb"
@@ -36,13 +38,32 @@ type govStateFetcher struct {
 }
 
 func (g *govStateFetcher) GetConfigState(_ uint64) (*vm.GovernanceState, error) {
-	return &vm.GovernanceState{g.statedb}, nil
+	return &vm.GovernanceState{StateDB: g.statedb}, nil
+}
+
+func (g *govStateFetcher) GetConfigSnapshot(round uint64) (*vm.ConfigSnapshot, error) {
+	return vm.NewConfigSnapshot(&vm.GovernanceState{StateDB: g.statedb}, round, 0), nil
 }
 
 func (g *govStateFetcher) DKGSetNodeKeyAddresses(round uint64) (map[common.Address]struct{}, error) {
 	return make(map[common.Address]struct{}), nil
 }
 
+type chainReader struct {
+	consensus.ChainReader
+	config *params.ChainConfig
+}
+
+func (c *chainReader) Config() *params.ChainConfig {
+	return c.config
+}
+
+func newChainReader(rewardAddressBlock int64) *chainReader {
+	config := *params.TestChainConfig
+	config.RewardAddressBlock = big.NewInt(rewardAddressBlock)
+	return &chainReader{config: &config}
+}
+
 type DexconTestSuite struct {
 	suite.Suite
 
@@ -60,7 +81,7 @@ func (d *DexconTestSuite) SetupTest() {
 	}
 	d.memDB = memDB
 	d.stateDB = stateDB
-	d.s = &vm.GovernanceState{stateDB}
+	d.s = &vm.GovernanceState{StateDB: stateDB}
 
 	config := params.TestnetChainConfig.Dexcon
 	config.LockupPeriod = 1000
@@ -99,6 +120,55 @@ func (d *DexconTestSuite) TestBlockRewardCalculation() {
 	d.Require().Equal(big.NewInt(5945585996), consensus.calculateBlockReward(0))
 }
 
+func (d *DexconTestSuite) TestFinalizeRewardAddress() {
+	consensus := New()
+	consensus.SetGovStateFetcher(&govStateFetcher{d.stateDB})
+	d.s.IncTotalStaked(big.NewInt(1e18))
+
+	owner, cold := common.Address{1}, common.Address{2}
+	d.s.SetRewardAddress(owner, cold)
+
+	// Before the fork rewards are credited to the coinbase.
+	header := &types.Header{Number: big.NewInt(1), Coinbase: owner}
+	_, err := consensus.Finalize(newChainReader(2), header, d.stateDB, nil, nil, nil)
+	d.Require().NoError(err)
+	d.Require().NotZero(header.Reward.Sign())
+	d.Require().Equal(header.Reward, d.stateDB.GetBalance(owner))
+	d.Require().Zero(d.stateDB.GetBalance(cold).Sign())
+
+	ownerBalance := d.stateDB.GetBalance(owner)
+
+	header = &types.Header{Number: big.NewInt(2), Coinbase: owner}
+	_, err = consensus.Finalize(newChainReader(2), header, d.stateDB, nil, nil, nil)
+	d.Require().NoError(err)
+	d.Require().NotZero(header.Reward.Sign())
+	d.Require().Equal(header.Reward, d.stateDB.GetBalance(cold))
+	d.Require().Equal(ownerBalance, d.stateDB.GetBalance(owner))
+
+	// Proposing is still accounted to the owner.
+	d.Require().Equal(uint64(2), d.s.LastProposedHeight(owner).Uint64())
+}
+
+func (d *DexconTestSuite) TestFinalizeGovernanceLayout() {
+	consensus := New()
+	consensus.SetGovStateFetcher(&govStateFetcher{d.stateDB})
+
+	chain := newChainReader(0)
+	chain.config.GovernanceLayoutBlock = big.NewInt(2)
+	marker := crypto.Keccak256Hash([]byte("governance.layoutVersion"))
+
+	header := &types.Header{Number: big.NewInt(1)}
+	_, err := consensus.Finalize(chain, header, d.stateDB, nil, nil, nil)
+	d.Require().NoError(err)
+	d.Require().Equal(common.Hash{}, d.stateDB.GetState(vm.GovernanceContractAddress, marker))
+
+	header = &types.Header{Number: big.NewInt(2)}
+	_, err = consensus.Finalize(chain, header, d.stateDB, nil, nil, nil)
+	d.Require().NoError(err)
+	d.Require().Equal(common.BigToHash(big.NewInt(vm.GovernanceLayoutVersion)),
+		d.stateDB.GetState(vm.GovernanceContractAddress, marker))
+}
+
 func TestDexcon(t *testing.T) {
 	suite.Run(t, new(DexconTestSuite))
 }
@@ -18,7 +18,6 @@ import (
 	"github.com/portto/go-tangerine/core/types"
 	"github.com/portto/go-tangerine/core/vm"
 	"github.com/portto/go-tangerine/crypto"
-	"github.com/portto/go-tangerine/rlp"
 )
 
 type FakeDexcon struct {
@@ -34,29 +33,29 @@ func NewFaker(nodes *NodeSet) *FakeDexcon {
 }
 
 func (f *FakeDexcon) Prepare(chain consensus.ChainReader, header *types.Header) error {
-	var coreBlock coreTypes.Block
-	if err := rlp.DecodeBytes(header.DexconMeta, &coreBlock); err != nil {
+	coreBlock, err := header.CoreBlock()
+	if err != nil {
 		return err
 	}
 
-	blockHash, err := coreUtils.HashBlock(&coreBlock)
+	blockHash, err := coreUtils.HashBlock(coreBlock)
 	if err != nil {
 		return err
 	}
 
 	parentHeader := chain.GetHeaderByNumber(header.Number.Uint64() - 1)
-	var parentCoreBlock coreTypes.Block
 	if parentHeader.Number.Uint64() != 0 {
-		if err := rlp.DecodeBytes(
-			parentHeader.DexconMeta, &parentCoreBlock); err != nil {
+		if _, err := parentHeader.CoreBlock(); err != nil {
 			return err
 		}
 	}
 
 	randomness := f.nodes.Randomness(header.Round, common.Hash(blockHash))
+	coreBlock.Hash = blockHash
 	coreBlock.Randomness = randomness
+	header.Randomness = randomness
 
-	dexconMeta, err := rlp.EncodeToBytes(&coreBlock)
+	dexconMeta, err := types.EncodeDexconMeta(coreBlock, false)
 	if err != nil {
 		return err
 	}
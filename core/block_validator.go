@@ -153,3 +153,13 @@ func CalcGasLimit(parent *types.Block, gasFloor, gasCeil uint64) uint64 {
 	}
 	return limit
 }
+
+// VerifyBlockInterval checks that a block timestamped at time follows its
+// parent timestamped at parentTime, both in milliseconds, by at least the
+// minimum block interval of config.
+func VerifyBlockInterval(parentTime, time uint64, config *params.DexconConfig) error {
+	if time < parentTime+config.MinBlockInterval {
+		return ErrBlockIntervalTooShort
+	}
+	return nil
+}
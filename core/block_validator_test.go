@@ -17,6 +17,7 @@
 package core
 
 import (
+	"math/big"
 	"runtime"
 	"testing"
 	"time"
@@ -197,3 +198,33 @@ func testHeaderConcurrentAbortion(t *testing.T, threads int) {
 		t.Errorf("verification count too large: have %d, want below %d", verified, 2*threads)
 	}
 }
+
+// Tests that the minimum block interval is only enforced from the block
+// interval fork on.
+func TestTangerineBlockIntervalFork(t *testing.T) {
+	config := *params.TestChainConfig
+	config.BlockIntervalBlock = big.NewInt(3)
+	hc := &HeaderChain{config: &config}
+
+	cache := newHeaderVerifierCache(nil, nil)
+	cache.configCache.Add(uint64(0), &params.DexconConfig{MinBlockInterval: 1000})
+
+	genesis := &types.Header{Number: big.NewInt(0), Time: 0}
+	parent := &types.Header{Number: big.NewInt(2), Time: 5000}
+	tests := []struct {
+		header *types.Header
+		parent *types.Header
+		valid  bool
+	}{
+		{&types.Header{Number: big.NewInt(1), Time: 1}, genesis, true},
+		{&types.Header{Number: big.NewInt(2), Time: 5500}, &types.Header{Number: big.NewInt(1), Time: 5000}, true},
+		{&types.Header{Number: big.NewInt(3), Time: 5500}, parent, false},
+		{&types.Header{Number: big.NewInt(3), Time: 6000}, parent, true},
+	}
+	for i, tt := range tests {
+		err := hc.verifyTangerineBlockInterval(tt.header, tt.parent, cache)
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("test %d: valid mismatch: have %v, want %v (err %v)", i, valid, tt.valid, err)
+		}
+	}
+}
@@ -30,7 +30,9 @@ import (
 
 	lru "github.com/hashicorp/golang-lru"
 	dexCore "github.com/portto/tangerine-consensus/core"
+	coreCrypto "github.com/portto/tangerine-consensus/core/crypto"
 	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	coreUtils "github.com/portto/tangerine-consensus/core/utils"
 
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/common/math"
@@ -58,6 +60,8 @@ var (
 	blockExecutionTimer  = metrics.NewRegisteredTimer("chain/execution", nil)
 	blockWriteTimer      = metrics.NewRegisteredTimer("chain/write", nil)
 
+	headBlockGauge = metrics.NewRegisteredGauge("chain/head/block", nil)
+
 	ErrNoGenesis = errors.New("Genesis not found in chain")
 )
 
@@ -136,6 +140,9 @@ type BlockChain struct {
 	procInterrupt int32          // interrupt signaler for block processing
 	wg            sync.WaitGroup // chain processing wait group for shutting down
 
+	txLookupLimit    uint64 // Number of recent blocks with indexed transactions (atomic access)
+	extendedReceipts int32  // Whether extended receipts are stored (atomic access)
+
 	engine    consensus.Engine
 	processor Processor // block processor interface
 	validator Validator // block and state validator interface
@@ -243,10 +250,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		if curh == uint64(0) {
 			// Linear search the first block of current round
 			// from previous round height.
-			h := prevh
-			for h <= curblock.NumberU64() {
-				b := bc.GetBlockByNumber(h)
-				if b.Round() == r {
+			// Headers are used as a checkpoint synced chain has no
+			// bodies before the checkpoint.
+			for h := prevh; h <= curblock.NumberU64(); h++ {
+				if header := bc.GetHeaderByNumber(h); header != nil && header.Round == r {
 					curh = h
 					break
 				}
@@ -264,6 +271,9 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 
 	// Take ownership of this particular state
 	go bc.update()
+	headCh := make(chan ChainHeadEvent, txIndexChainHeadChanSize)
+	bc.wg.Add(1)
+	go bc.maintainTxIndex(headCh, bc.SubscribeChainHeadEvent(headCh))
 	return bc, nil
 }
 
@@ -303,6 +313,7 @@ func (bc *BlockChain) loadLastState() error {
 	}
 	// Everything seems to be fine, set as the head block
 	bc.currentBlock.Store(currentBlock)
+	headBlockGauge.Update(int64(currentBlock.NumberU64()))
 
 	// Restore the last known head header
 	currentHeader := currentBlock.Header()
@@ -403,6 +414,7 @@ func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
 	// If all checks out, manually set the head block
 	bc.mu.Lock()
 	bc.currentBlock.Store(block)
+	headBlockGauge.Update(int64(block.NumberU64()))
 	bc.mu.Unlock()
 
 	log.Info("Committed new head block", "number", block.Number(), "hash", hash)
@@ -570,6 +582,7 @@ func (bc *BlockChain) insert(block *types.Block) {
 	rawdb.WriteHeadBlockHash(bc.db, block.Hash())
 
 	bc.currentBlock.Store(block)
+	headBlockGauge.Update(int64(block.NumberU64()))
 
 	// If the block is better than our head or is on a different chain, force update heads
 	if updateHeads {
@@ -835,6 +848,7 @@ func (bc *BlockChain) Rollback(chain []common.Hash) {
 		if currentBlock := bc.CurrentBlock(); currentBlock.Hash() == hash {
 			newBlock := bc.GetBlock(currentBlock.ParentHash(), currentBlock.NumberU64()-1)
 			bc.currentBlock.Store(newBlock)
+			headBlockGauge.Update(int64(newBlock.NumberU64()))
 			rawdb.WriteHeadBlockHash(bc.db, newBlock.Hash())
 		}
 	}
@@ -923,6 +937,8 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		rawdb.WriteBody(batch, block.Hash(), block.NumberU64(), block.Body())
 		rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
 		rawdb.WriteTxLookupEntries(batch, block)
+		rawdb.WriteProposedBlock(batch, block)
+		writeStakeEvents(batch, block, receipts)
 
 		stats.processed++
 
@@ -1101,6 +1117,9 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	// Write other block data using a batch.
 	batch := bc.db.NewBatch()
 	rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
+	if atomic.LoadInt32(&bc.extendedReceipts) == 1 {
+		rawdb.WriteExtendedReceipts(batch, block.Hash(), block.NumberU64(), receipts)
+	}
 
 	// If the total difficulty is higher than our known, add it to the canonical chain
 	// Second clause in the if statement reduces the vulnerability to selfish mining.
@@ -1129,6 +1148,8 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 		}
 		// Write the positional metadata for transaction/receipt lookups and preimages
 		rawdb.WriteTxLookupEntries(batch, block)
+		rawdb.WriteProposedBlock(batch, block)
+		writeStakeEvents(batch, block, receipts)
 		rawdb.WritePreimages(batch, statedb.Preimages())
 
 		status = CanonStatTy
@@ -1296,7 +1317,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		}
 		// Process block using the parent state as reference point.
 		t0 := time.Now()
-		receipts, logs, usedGas, err := bc.processor.Process(block, state, bc.vmConfig)
+		receipts, logs, usedGas, err := bc.processor.Process(block, state, bc.processConfig())
 		t1 := time.Now()
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
@@ -1630,7 +1651,7 @@ func (bc *BlockChain) insertTangerineChain(chain types.Blocks) (int, []interface
 			return i, events, coalescedLogs, err
 		}
 		// Process block using the parent state as reference point.
-		receipts, logs, usedGas, err := bc.processor.Process(block, state, bc.vmConfig)
+		receipts, logs, usedGas, err := bc.processor.Process(block, state, bc.processConfig())
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
 			return i, events, coalescedLogs, err
@@ -1701,6 +1722,57 @@ func (bc *BlockChain) VerifyTangerineHeader(header *types.Header) error {
 	return bc.hc.VerifyTangerineHeader(header, bc.gov, bc.verifierCache, bc.Validator())
 }
 
+// VerifyDexconMeta checks that the core block in the DexconMeta of header is
+// the block of header and that its randomness is the threshold signature of
+// the block by the DKG group of its round. The group public key is
+// reconstructed from the governance state of the chain, so the blocks of a
+// round can only be verified once the chain reaches the round.
+func (bc *BlockChain) VerifyDexconMeta(header *types.Header) error {
+	return VerifyDexconMeta(header, bc.verifierCache)
+}
+
+// VerifyDexconMeta checks the DexconMeta of header like the method of
+// BlockChain, with the DKG groups of verifierCache.
+func VerifyDexconMeta(header *types.Header, verifierCache *dexCore.TSigVerifierCache) error {
+	block, err := header.CoreBlock()
+	if err != nil {
+		return fmt.Errorf("%v: %v", ErrInvalidDexconMeta, err)
+	}
+	if block.Position.Height != header.Number.Uint64() || block.Position.Round != header.Round ||
+		!bytes.Equal(block.Randomness, header.Randomness) {
+		return fmt.Errorf("%v: core block mismatch", ErrInvalidDexconMeta)
+	}
+	if header.Time != uint64(block.Timestamp.UnixNano()/1000000) {
+		return fmt.Errorf("%v: timestamp mismatch", ErrInvalidDexconMeta)
+	}
+	hash, err := coreUtils.HashBlock(block)
+	if err != nil {
+		return fmt.Errorf("%v: %v", ErrInvalidDexconMeta, err)
+	}
+	if hash != block.Hash {
+		return fmt.Errorf("%v: core block hash mismatch", ErrInvalidDexconMeta)
+	}
+
+	// There is no DKG group to sign the blocks of the first rounds.
+	if block.Position.Round < dexCore.DKGDelayRound {
+		return nil
+	}
+	v, ok, err := verifierCache.UpdateAndGet(block.Position.Round)
+	if err != nil {
+		return fmt.Errorf("%v: %v", ErrInvalidDexconMeta, err)
+	}
+	if !ok {
+		return fmt.Errorf("%v: DKG of round %d not finished", ErrInvalidDexconMeta, block.Position.Round)
+	}
+	if !v.VerifySignature(block.Hash, coreCrypto.Signature{
+		Type:      "bls",
+		Signature: block.Randomness,
+	}) {
+		return fmt.Errorf("%v: invalid randomness", ErrInvalidDexconMeta)
+	}
+	return nil
+}
+
 func (bc *BlockChain) ProcessBlock(block *types.Block, witness *coreTypes.Witness) (*common.Hash, error) {
 	root, events, logs, err := bc.processBlock(block, witness)
 	bc.PostChainEvents(events, logs)
@@ -1760,7 +1832,7 @@ func (bc *BlockChain) processBlock(
 	// Iterate over and process the individual transactions.
 	for i, tx := range block.Transactions() {
 		currentState.Prepare(tx.Hash(), block.Hash(), i)
-		receipt, _, err := ApplyTransaction(bc.chainConfig, bc, nil, gp, currentState, header, tx, usedGas, bc.vmConfig)
+		receipt, _, err := ApplyTransaction(bc.chainConfig, bc, nil, gp, currentState, header, tx, usedGas, bc.processConfig())
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("apply transaction error: %v %d", err, tx.Nonce())
 		}
@@ -2060,6 +2132,8 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		}
 		// Write lookup entries for hash based transaction/receipt searches
 		rawdb.WriteTxLookupEntries(bc.db, newChain[i])
+		rawdb.WriteProposedBlock(bc.db, newChain[i])
+		writeStakeEvents(bc.db, newChain[i], rawdb.ReadReceipts(bc.db, newChain[i].Hash(), newChain[i].NumberU64()))
 		addedTxs = append(addedTxs, newChain[i].Transactions()...)
 	}
 	// When transactions get deleted from the database, the receipts that were
@@ -2228,6 +2302,31 @@ func (bc *BlockChain) InsertTangerineHeaderChain(chain []*types.HeaderWithGovSta
 	return bc.hc.InsertTangerineHeaderChain(chain, whFunc, start)
 }
 
+// InsertCheckpoint writes a verified, contiguous segment of headers ending at
+// a checkpoint into an empty chain and makes the checkpoint the head header,
+// so that syncing continues from the checkpoint instead of the genesis.
+func (bc *BlockChain) InsertCheckpoint(chain []*types.HeaderWithGovState) error {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	bc.wg.Add(1)
+	defer bc.wg.Done()
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if bc.CurrentBlock().NumberU64() != 0 {
+		return errors.New("blockchain not empty")
+	}
+	if err := bc.hc.InsertCheckpoint(chain); err != nil {
+		return err
+	}
+	head := chain[len(chain)-1]
+	log.Info("Inserted checkpoint headers", "count", len(chain),
+		"number", head.Number, "hash", head.Hash())
+	return nil
+}
+
 // writeHeader writes a header into the local chain, given that its parent is
 // already known. If the total difficulty of the newly inserted header becomes
 // greater than the current known TD, the canonical chain is re-routed.
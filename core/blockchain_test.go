@@ -25,7 +25,9 @@ import (
 	"testing"
 	"time"
 
+	coreCommon "github.com/portto/tangerine-consensus/common"
 	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	coreUtils "github.com/portto/tangerine-consensus/core/utils"
 
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/consensus"
@@ -1965,3 +1967,62 @@ func TestLowDiffLongChain(t *testing.T) {
 		header = chain.GetHeader(header.ParentHash, number-1)
 	}
 }
+
+// Tests that the DexconMeta of a header is only accepted if it is the core
+// block of the header.
+func TestVerifyDexconMeta(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	gspec := &Genesis{Config: params.TestnetChainConfig}
+	chainConfig, _, err := SetupGenesisBlock(db, gspec)
+	if err != nil {
+		t.Fatalf("set up genesis block error: %v", err)
+	}
+	chain, err := NewBlockChain(db, nil, chainConfig, &dexconTest{blockReward: big.NewInt(1e18)}, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	newHeader := func(round uint64, tamper func(*coreTypes.Block)) *types.Header {
+		block := &coreTypes.Block{
+			Position:   coreTypes.Position{Round: round, Height: 1},
+			Timestamp:  time.Unix(1, 2000000).UTC(),
+			Randomness: []byte{1, 2, 3},
+		}
+		hash, err := coreUtils.HashBlock(block)
+		if err != nil {
+			t.Fatalf("failed to hash block: %v", err)
+		}
+		block.Hash = hash
+		header := &types.Header{
+			Number:     big.NewInt(1),
+			Round:      round,
+			Time:       1002,
+			Randomness: block.Randomness,
+		}
+		if tamper != nil {
+			tamper(block)
+		}
+		if header.DexconMeta, err = types.EncodeDexconMeta(block, false); err != nil {
+			t.Fatalf("failed to encode dexcon meta: %v", err)
+		}
+		return header
+	}
+
+	if err := chain.VerifyDexconMeta(newHeader(0, nil)); err != nil {
+		t.Errorf("valid dexcon meta rejected: %v", err)
+	}
+	tests := map[string]*types.Header{
+		"height":     newHeader(0, func(b *coreTypes.Block) { b.Position.Height = 2 }),
+		"randomness": newHeader(0, func(b *coreTypes.Block) { b.Randomness = []byte{4} }),
+		"timestamp":  newHeader(0, func(b *coreTypes.Block) { b.Timestamp = b.Timestamp.Add(time.Second) }),
+		"hash":       newHeader(0, func(b *coreTypes.Block) { b.Hash = coreCommon.Hash{1} }),
+		"payload":    newHeader(0, func(b *coreTypes.Block) { b.PayloadHash = coreCommon.Hash{1} }),
+		"no DKG":     newHeader(1, nil),
+	}
+	for name, header := range tests {
+		if err := chain.VerifyDexconMeta(header); err == nil || !strings.HasPrefix(err.Error(), ErrInvalidDexconMeta.Error()) {
+			t.Errorf("%s: error mismatch: have %v, want %v", name, err, ErrInvalidDexconMeta)
+		}
+	}
+}
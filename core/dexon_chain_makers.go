@@ -255,7 +255,8 @@ func makeDexconMeta(b *TangerineBlockGen, parent *types.Block) []byte {
 	}
 
 	block := coreTypes.Block{
-		Position: b.position,
+		Position:  b.position,
+		Timestamp: time.Unix(0, int64(b.header.Time)*int64(time.Millisecond)).UTC(),
 		Witness: coreTypes.Witness{
 			Height: witnessedBlock.NumberU64(),
 			Data:   data,
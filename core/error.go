@@ -32,4 +32,12 @@ var (
 	// ErrNonceTooHigh is returned if the nonce of a transaction is higher than the
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
+
+	// ErrBlockIntervalTooShort is returned if a block is timestamped earlier
+	// than the minimum block interval after its parent.
+	ErrBlockIntervalTooShort = errors.New("block interval too short")
+
+	// ErrInvalidDexconMeta is returned if the core block in the DexconMeta of
+	// a block isn't the finalized block of its position.
+	ErrInvalidDexconMeta = errors.New("invalid dexcon meta")
 )
@@ -0,0 +1,59 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync/atomic"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/core/vm"
+)
+
+// SetExtendedReceipts sets whether the extended receipts of the transactions
+// of the blocks written from now on are stored.
+func (bc *BlockChain) SetExtendedReceipts(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&bc.extendedReceipts, v)
+}
+
+// ExtendedReceipts returns whether extended receipts are stored.
+func (bc *BlockChain) ExtendedReceipts() bool {
+	return atomic.LoadInt32(&bc.extendedReceipts) == 1
+}
+
+// processConfig returns the EVM configuration blocks are processed with,
+// recording the touched accounts only if extended receipts are stored.
+func (bc *BlockChain) processConfig() vm.Config {
+	cfg := bc.vmConfig
+	cfg.RecordTouchedAccounts = bc.ExtendedReceipts()
+	return cfg
+}
+
+// GetExtendedReceiptsByHash retrieves the extended receipts of the
+// transactions of a block, nil if they were not stored.
+func (bc *BlockChain) GetExtendedReceiptsByHash(hash common.Hash) []*types.ExtendedReceipt {
+	number := rawdb.ReadHeaderNumber(bc.db, hash)
+	if number == nil {
+		return nil
+	}
+	return rawdb.ReadExtendedReceipts(bc.db, hash, *number)
+}
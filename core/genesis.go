@@ -219,6 +219,15 @@ func SetupGenesisBlockWithOverride(db ethdb.Database, genesis *Genesis, constant
 		return newcfg, stored, fmt.Errorf("missing block number for head header hash")
 	}
 	compatErr := storedcfg.CheckCompatible(newcfg, *height)
+	if head := rawdb.ReadHeader(db, rawdb.ReadHeadHeaderHash(db), *height); head != nil {
+		roundHeight := func(round uint64) uint64 {
+			return firstHeightOfRound(db, round, *height)
+		}
+		roundErr := storedcfg.CheckRoundCompatible(newcfg, head.Round, roundHeight)
+		if roundErr != nil && (compatErr == nil || roundErr.RewindTo < compatErr.RewindTo) {
+			compatErr = roundErr
+		}
+	}
 	if compatErr != nil && *height != 0 && compatErr.RewindTo != 0 {
 		return newcfg, stored, compatErr
 	}
@@ -226,6 +235,15 @@ func SetupGenesisBlockWithOverride(db ethdb.Database, genesis *Genesis, constant
 	return newcfg, stored, nil
 }
 
+// firstHeightOfRound returns the height of the first canonical block whose
+// round is not before the given round, searching heights up to head.
+func firstHeightOfRound(db rawdb.DatabaseReader, round, head uint64) uint64 {
+	return uint64(sort.Search(int(head+1), func(i int) bool {
+		header := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, uint64(i)), uint64(i))
+		return header == nil || header.Round >= round
+	}))
+}
+
 func (g *Genesis) configOrDefault(ghash common.Hash) *params.ChainConfig {
 	switch {
 	case g != nil:
@@ -307,6 +325,12 @@ func (g *Genesis) ToBlock(db ethdb.Database) *types.Block {
 
 		// Initialize governance.
 		govStateHelper.Initialize(g.Config.Dexcon, totalSupply)
+
+		// Chains starting with the layout marker fork carry the marker from
+		// genesis, the others get it when the fork activates.
+		if g.Config.IsGovernanceLayout(new(big.Int).SetUint64(g.Number)) {
+			govStateHelper.MigrateLayout()
+		}
 	}
 
 	// Set oracle contract.
@@ -25,7 +25,10 @@ import (
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/consensus/ethash"
 	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/state"
+	"github.com/portto/go-tangerine/core/types"
 	"github.com/portto/go-tangerine/core/vm"
+	"github.com/portto/go-tangerine/crypto"
 	"github.com/portto/go-tangerine/ethdb"
 	"github.com/portto/go-tangerine/params"
 )
@@ -41,6 +44,32 @@ func TestDefaultGenesisBlock(t *testing.T) {
 	}
 }
 
+// Tests that the governance storage layout marker is written at genesis only
+// by chains starting with the marker fork.
+func TestGenesisGovernanceLayout(t *testing.T) {
+	marker := crypto.Keccak256Hash([]byte("governance.layoutVersion"))
+	for _, fork := range []*big.Int{nil, big.NewInt(1), big.NewInt(0)} {
+		genesis := DefaultTestnetGenesisBlock()
+		config := *genesis.Config
+		config.GovernanceLayoutBlock = fork
+		genesis.Config = &config
+
+		db := ethdb.NewMemDatabase()
+		block := genesis.MustCommit(db)
+		statedb, err := state.New(block.Root(), state.NewDatabase(db))
+		if err != nil {
+			t.Fatalf("failed to open genesis state: %v", err)
+		}
+		want := common.Hash{}
+		if fork != nil && fork.Sign() == 0 {
+			want = common.BigToHash(big.NewInt(vm.GovernanceLayoutVersion))
+		}
+		if have := statedb.GetState(vm.GovernanceContractAddress, marker); have != want {
+			t.Errorf("fork %v: marker mismatch: have %x, want %x", fork, have, want)
+		}
+	}
+}
+
 func TestSetupGenesis(t *testing.T) {
 	var (
 		customghash = common.HexToHash("0x5434fb7e64d951dd3934beb7566e7c8f5e71fab0ed3f3c7fffe2253f68f5596b")
@@ -162,3 +191,21 @@ func TestSetupGenesis(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the first block of a round is found among canonical headers.
+func TestFirstHeightOfRound(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	// Blocks 0-4 are in round 0, 5-9 in round 1 and 10-14 in round 3.
+	rounds := []uint64{0, 0, 0, 0, 0, 1, 1, 1, 1, 1, 3, 3, 3, 3, 3}
+	for i, round := range rounds {
+		header := &types.Header{Number: big.NewInt(int64(i)), Round: round}
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), uint64(i))
+	}
+	head := uint64(len(rounds) - 1)
+	for round, want := range []uint64{0, 5, 10, 10} {
+		if have := firstHeightOfRound(db, uint64(round), head); have != want {
+			t.Errorf("round %d: first height mismatch: have %d, want %d", round, have, want)
+		}
+	}
+}
@@ -20,9 +20,38 @@ import (
 	"github.com/portto/go-tangerine/core/state"
 	"github.com/portto/go-tangerine/core/vm"
 	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/metrics"
 )
 
-const dkgCacheSize = 5
+const (
+	dkgCacheSize = 5
+
+	// configCacheSize is the number of rounds whose configuration snapshot
+	// is kept, enough for the rounds around the head the callers look at.
+	configCacheSize = 8
+)
+
+var (
+	dkgStatusCacheHitCounter  = metrics.NewRegisteredCounter("governance/dkgstatus/hit", nil)
+	dkgStatusCacheMissCounter = metrics.NewRegisteredCounter("governance/dkgstatus/miss", nil)
+
+	configCacheHitCounter  = metrics.NewRegisteredCounter("governance/config/hit", nil)
+	configCacheMissCounter = metrics.NewRegisteredCounter("governance/config/miss", nil)
+)
+
+// DKG status bits of a round.
+const (
+	dkgStatusFinal uint8 = 1 << iota
+	dkgStatusSuccess
+)
+
+// dkgStatusItem is the cached DKG status bitmap of a round. The status only
+// changes with governance transactions, so it stays valid as long as the
+// storage of the governance contract is unchanged.
+type dkgStatusItem struct {
+	version common.Hash
+	status  uint8
+}
 
 type GovernanceStateDB interface {
 	State() (*state.StateDB, error)
@@ -64,6 +93,12 @@ type Governance struct {
 	dkgCache     *simplelru.LRU
 	dkgCacheMu   sync.RWMutex
 	util         vm.GovUtil
+
+	dkgStatusCache   map[uint64]dkgStatusItem
+	dkgStatusCacheMu sync.Mutex
+
+	configCache   *simplelru.LRU // round -> *vm.ConfigSnapshot
+	configCacheMu sync.Mutex
 }
 
 func NewGovernance(db GovernanceStateDB) *Governance {
@@ -72,9 +107,16 @@ func NewGovernance(db GovernanceStateDB) *Governance {
 		log.Error("Failed to initialize DKG cache", "error", err)
 		return nil
 	}
+	configCache, err := simplelru.NewLRU(configCacheSize, nil)
+	if err != nil {
+		log.Error("Failed to initialize config cache", "error", err)
+		return nil
+	}
 	g := &Governance{
-		db:       db,
-		dkgCache: cache,
+		db:             db,
+		dkgCache:       cache,
+		dkgStatusCache: make(map[uint64]dkgStatusItem),
+		configCache:    configCache,
 	}
 	g.nodeSetCache = dexCore.NewNodeSetCache(g)
 	g.util = vm.GovUtil{g}
@@ -98,6 +140,33 @@ func (g *Governance) GetConfigState(round uint64) (*vm.GovernanceState, error) {
 	return g.util.GetConfigState(round)
 }
 
+// GetConfigSnapshot returns the decoded configuration in effect at round.
+// The configuration of a round is read from the state at the first block of
+// an earlier round, so a snapshot is taken once that round begins and stays
+// valid unless the round is found to begin at another height, as after a
+// rewind of the chain.
+func (g *Governance) GetConfigSnapshot(round uint64) (*vm.ConfigSnapshot, error) {
+	height := g.util.GetRoundHeight(vm.ConfigRound(round))
+
+	g.configCacheMu.Lock()
+	defer g.configCacheMu.Unlock()
+	if v, exist := g.configCache.Get(round); exist {
+		if snapshot := v.(*vm.ConfigSnapshot); snapshot.Height == height {
+			configCacheHitCounter.Inc(1)
+			return snapshot, nil
+		}
+	}
+	configCacheMissCounter.Inc(1)
+
+	s, err := g.util.GetConfigState(round)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := vm.NewConfigSnapshot(s, round, height)
+	g.configCache.Add(round, snapshot)
+	return snapshot, nil
+}
+
 func (g *Governance) GetStateForDKGAtRound(round uint64) (*vm.GovernanceState, error) {
 	gs, err := g.GetHeadGovState()
 	if err != nil {
@@ -132,15 +201,15 @@ func (g *Governance) GetRoundHeight(round uint64) uint64 {
 }
 
 func (g *Governance) Configuration(round uint64) *coreTypes.Config {
-	s, err := g.util.GetConfigState(round)
+	s, err := g.GetConfigSnapshot(round)
 	if err != nil {
 		panic(err)
 	}
-	c := s.Configuration()
+	c := s.Config
 	return &coreTypes.Config{
 		LambdaBA:         time.Duration(c.LambdaBA) * time.Millisecond,
 		LambdaDKG:        time.Duration(c.LambdaDKG) * time.Millisecond,
-		NotarySetSize:    uint32(s.NotarySetSize().Uint64()),
+		NotarySetSize:    uint32(s.NotarySetSize),
 		RoundLength:      c.RoundLength,
 		MinBlockInterval: time.Duration(c.MinBlockInterval) * time.Millisecond,
 	}
@@ -272,25 +341,56 @@ func (g *Governance) IsDKGMPKReady(round uint64) bool {
 }
 
 func (g *Governance) IsDKGFinal(round uint64) bool {
-	s, err := g.GetStateForDKGAtRound(round)
-	if err != nil {
-		log.Error("Failed to get state for DKG", "round", round, "err", err)
-		return false
-	}
-	config := g.Configuration(round)
-	threshold := 2*uint64(config.NotarySetSize)/3 + 1
-	count := s.DKGFinalizedsCount().Uint64()
-	return count >= threshold
+	return g.dkgStatus(round)&dkgStatusFinal != 0
 }
 
 func (g *Governance) IsDKGSuccess(round uint64) bool {
+	return g.dkgStatus(round)&dkgStatusSuccess != 0
+}
+
+// dkgStatus returns the DKG status bitmap of round, served from the cache
+// unless the governance state changed since it was computed.
+func (g *Governance) dkgStatus(round uint64) uint8 {
+	headState, err := g.db.State()
+	if err != nil {
+		log.Error("Failed to get head state", "err", err)
+		return 0
+	}
+	var version common.Hash
+	if trie := headState.StorageTrie(vm.GovernanceContractAddress); trie != nil {
+		version = trie.Hash()
+	}
+
+	g.dkgStatusCacheMu.Lock()
+	defer g.dkgStatusCacheMu.Unlock()
+	if item, exist := g.dkgStatusCache[round]; exist && item.version == version {
+		dkgStatusCacheHitCounter.Inc(1)
+		return item.status
+	}
+	dkgStatusCacheMissCounter.Inc(1)
+
 	s, err := g.GetStateForDKGAtRound(round)
 	if err != nil {
 		log.Error("Failed to get state for DKG", "round", round, "err", err)
-		return false
+		return 0
+	}
+	config := g.Configuration(round)
+	var status uint8
+	if s.DKGFinalizedsCount().Uint64() >= 2*uint64(config.NotarySetSize)/3+1 {
+		status |= dkgStatusFinal
+	}
+	if s.DKGSuccessesCount().Uint64() >=
+		uint64(coreUtils.GetDKGValidThreshold(config)) {
+		status |= dkgStatusSuccess
+	}
+	// Entries of other rounds are outdated by the new version anyway.
+	for r, item := range g.dkgStatusCache {
+		if item.version != version {
+			delete(g.dkgStatusCache, r)
+		}
 	}
-	return s.DKGSuccessesCount().Uint64() >=
-		uint64(coreUtils.GetDKGValidThreshold(g.Configuration(round)))
+	g.dkgStatusCache[round] = dkgStatusItem{version: version, status: status}
+	return status
 }
 
 func (g *Governance) DKGResetCount(round uint64) uint64 {
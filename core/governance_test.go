@@ -0,0 +1,77 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/portto/go-tangerine/core/state"
+	"github.com/portto/go-tangerine/core/vm"
+	"github.com/portto/go-tangerine/ethdb"
+	coreUtils "github.com/portto/tangerine-consensus/core/utils"
+)
+
+// testGovernanceStateDB serves a single state as the state of every height.
+type testGovernanceStateDB struct {
+	state *state.StateDB
+}
+
+func (db *testGovernanceStateDB) State() (*state.StateDB, error) { return db.state, nil }
+
+func (db *testGovernanceStateDB) StateAt(height uint64) (*state.StateDB, error) {
+	return db.state, nil
+}
+
+// Tests that the cached DKG status of a round is recomputed once the
+// governance state changes.
+func TestDKGStatusCache(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	genesis := DefaultTestnetGenesisBlock().MustCommit(db)
+	statedb, err := state.New(genesis.Root(), state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open genesis state: %v", err)
+	}
+	g := NewGovernance(&testGovernanceStateDB{state: statedb})
+	gs := &vm.GovernanceState{StateDB: statedb}
+
+	if g.IsDKGFinal(0) || g.IsDKGSuccess(0) {
+		t.Fatalf("DKG of round 0 final or successful before any finalization")
+	}
+	if item, exist := g.dkgStatusCache[0]; !exist || item.status != 0 {
+		t.Fatalf("DKG status not cached: %+v", item)
+	}
+
+	config := g.Configuration(0)
+	for i := uint32(0); i < 2*config.NotarySetSize/3+1; i++ {
+		gs.IncDKGFinalizedsCount()
+	}
+	if !g.IsDKGFinal(0) {
+		t.Errorf("stale DKG final status served")
+	}
+	if g.IsDKGSuccess(0) {
+		t.Errorf("DKG successful without successes")
+	}
+	for i := 0; i < coreUtils.GetDKGValidThreshold(config); i++ {
+		gs.IncDKGSuccessesCount()
+	}
+	if !g.IsDKGSuccess(0) {
+		t.Errorf("stale DKG success status served")
+	}
+	if item := g.dkgStatusCache[0]; item.status != dkgStatusFinal|dkgStatusSuccess {
+		t.Errorf("cached status mismatch: have %b, want %b", item.status, dkgStatusFinal|dkgStatusSuccess)
+	}
+}
@@ -372,28 +372,81 @@ func (hc *HeaderChain) WriteTangerineHeader(header *types.HeaderWithGovState) (s
 
 	// Store the govState
 	if govState := header.GovState; govState != nil {
-		rawdb.WriteGovState(hc.chainDb, header.Hash(), header.GovState)
-		batch := hc.chainDb.NewBatch()
-		for _, node := range govState.Proof {
-			batch.Put(crypto.Keccak256(node), node)
+		hc.writeGovState(header.Hash(), govState)
+	}
+	return
+}
+
+// writeGovState stores the governance state of the header with the given
+// hash, along with its account proof and storage trie, so the governance
+// contract can be read at the header's state root.
+func (hc *HeaderChain) writeGovState(hash common.Hash, govState *types.GovState) {
+	rawdb.WriteGovState(hc.chainDb, hash, govState)
+	batch := hc.chainDb.NewBatch()
+	for _, node := range govState.Proof {
+		batch.Put(crypto.Keccak256(node), node)
+	}
+	if err := batch.Write(); err != nil {
+		panic(fmt.Errorf("DB write error: %v", err))
+	}
+
+	triedb := trie.NewDatabase(hc.chainDb)
+	t, err := trie.New(common.Hash{}, triedb)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, kv := range govState.Storage {
+		t.TryUpdate(kv[0], kv[1])
+	}
+	t.Commit(nil)
+	triedb.Commit(t.Hash(), false)
+}
+
+// InsertCheckpoint writes a contiguous segment of headers ending at a
+// checkpoint into an empty header chain and makes the checkpoint its head,
+// leaving out the headers before the segment. The segment must have been
+// verified by the caller, only its linkage is checked here.
+func (hc *HeaderChain) InsertCheckpoint(chain []*types.HeaderWithGovState) error {
+	if len(chain) == 0 {
+		return errors.New("empty checkpoint")
+	}
+	if hc.CurrentHeader().Number.Uint64() != 0 {
+		return errors.New("header chain not empty")
+	}
+	if chain[0].Number.Uint64() == 0 {
+		return errors.New("checkpoint segment includes genesis")
+	}
+	for i := 1; i < len(chain); i++ {
+		if chain[i].Number.Uint64() != chain[i-1].Number.Uint64()+1 || chain[i].ParentHash != chain[i-1].Hash() {
+			return fmt.Errorf("non contiguous checkpoint segment at #%d", chain[i].Number)
 		}
-		if err := batch.Write(); err != nil {
-			panic(fmt.Errorf("DB write error: %v", err))
+	}
+	// Every block has a difficulty of one, so the total difficulty of the
+	// skipped ancestors follows from the number.
+	genesisTd := hc.GetTd(hc.genesisHeader.Hash(), 0)
+	for _, header := range chain {
+		if header.Difficulty.Cmp(big.NewInt(1)) != 0 {
+			return fmt.Errorf("difficulty should be 1, number=%d", header.Number)
 		}
-
-		triedb := trie.NewDatabase(hc.chainDb)
-		t, err := trie.New(common.Hash{}, triedb)
-		if err != nil {
-			panic(err)
+		var (
+			hash   = header.Hash()
+			number = header.Number.Uint64()
+		)
+		td := new(big.Int).Add(genesisTd, header.Number)
+		if err := hc.WriteTd(hash, number, td); err != nil {
+			log.Crit("Failed to write header total difficulty", "err", err)
 		}
-
-		for _, kv := range govState.Storage {
-			t.TryUpdate(kv[0], kv[1])
+		rawdb.WriteHeader(hc.chainDb, header.Header)
+		rawdb.WriteCanonicalHash(hc.chainDb, hash, number)
+		if header.GovState != nil {
+			hc.writeGovState(hash, header.GovState)
 		}
-		t.Commit(nil)
-		triedb.Commit(t.Hash(), false)
+		hc.headerCache.Add(hash, header.Header)
+		hc.numberCache.Add(hash, number)
 	}
-	return
+	hc.SetCurrentHeader(types.CopyHeader(chain[len(chain)-1].Header))
+	return nil
 }
 
 type Wh2Callback func(*types.HeaderWithGovState) error
@@ -495,20 +548,26 @@ func (hc *HeaderChain) ValidateTangerineHeaderChain(chain []*types.HeaderWithGov
 			return 0, errors.New("aborted")
 		}
 
+		var parent *types.Header
 		if i == 0 {
 			log.Debug("validate header chain", "parent", header.ParentHash.String(), "number", header.Number.Uint64()-1)
-			if parent := hc.GetHeader(header.ParentHash, header.Number.Uint64()-1); parent == nil {
+			if parent = hc.GetHeader(header.ParentHash, header.Number.Uint64()-1); parent == nil {
 				return 0, consensus.ErrUnknownAncestor
 			}
+		} else {
+			parent = chain[i-1].Header
 		}
 
 		if err := hc.verifyTangerineHeader(header.Header, gov, cache, verifyTSig); err != nil {
 			return i, err
 		}
+		if err := hc.verifyTangerineBlockInterval(header.Header, parent, cache); err != nil {
+			return i, err
+		}
 
 		// Verify witness
-		var coreBlock coreTypes.Block
-		if err := rlp.DecodeBytes(header.DexconMeta, &coreBlock); err != nil {
+		coreBlock, err := header.CoreBlock()
+		if err != nil {
 			return i, err
 		}
 
@@ -539,17 +598,21 @@ func (hc *HeaderChain) VerifyTangerineHeader(header *types.Header,
 	gov dexcon.GovernanceStateFetcher,
 	verifierCache *dexCore.TSigVerifierCache, validator Validator) error {
 
-	if parent := hc.GetHeader(header.ParentHash, header.Number.Uint64()-1); parent == nil {
+	parent := hc.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
 		return consensus.ErrUnknownAncestor
 	}
 	cache := newHeaderVerifierCache(verifierCache, gov)
 	if err := hc.verifyTangerineHeader(header, gov, cache, true); err != nil {
 		return err
 	}
+	if err := hc.verifyTangerineBlockInterval(header, parent, cache); err != nil {
+		return err
+	}
 
 	// Verify witness
-	var coreBlock coreTypes.Block
-	if err := rlp.DecodeBytes(header.DexconMeta, &coreBlock); err != nil {
+	coreBlock, err := header.CoreBlock()
+	if err != nil {
 		return err
 	}
 
@@ -583,14 +646,18 @@ func (hc *HeaderChain) verifyTangerineHeader(header *types.Header,
 	}
 
 	// Verify fields that should be same as dexcon meta.
-	var coreBlock coreTypes.Block
-	if err := rlp.DecodeBytes(header.DexconMeta, &coreBlock); err != nil {
+	if hc.config.IsCompactDexconMeta(header.Number) != types.IsCompactDexconMeta(header.DexconMeta) {
+		return fmt.Errorf("dexcon meta encoding mismatch, number=%d",
+			header.Number.Uint64())
+	}
+	coreBlock, err := header.CoreBlock()
+	if err != nil {
 		return fmt.Errorf("decode dexcon meta fail, number=%d, err=%v",
 			header.Number.Uint64(), err)
 	}
 
 	if verifyTSig {
-		if err := hc.verifyTSig(&coreBlock, cache.verifierCache); err != nil {
+		if err := hc.verifyTSig(coreBlock, cache.verifierCache); err != nil {
 			log.Debug("verify header sig fail, number=%d, err=%v",
 				header.Number.Uint64(), err)
 		}
@@ -634,6 +701,23 @@ func (hc *HeaderChain) verifyTangerineHeader(header *types.Header,
 	return nil
 }
 
+// verifyTangerineBlockInterval checks that header is timestamped at least the
+// minimum block interval of the round of its parent after the parent. The
+// genesis block is not timestamped by the consensus core and is exempted, as
+// are the blocks before the block interval fork.
+func (hc *HeaderChain) verifyTangerineBlockInterval(header, parent *types.Header,
+	cache *headerVerifierCache) error {
+	if parent.Number.Uint64() == 0 || !hc.config.IsBlockInterval(header.Number) {
+		return nil
+	}
+	err := VerifyBlockInterval(parent.Time, header.Time, cache.configuration(parent.Round))
+	if err != nil {
+		return fmt.Errorf("%v, number=%d, time=%d, parent time=%d", err,
+			header.Number.Uint64(), header.Time, parent.Time)
+	}
+	return nil
+}
+
 func (hc *HeaderChain) verifyTSig(coreBlock *coreTypes.Block,
 	verifierCache *dexCore.TSigVerifierCache) error {
 
@@ -0,0 +1,58 @@
+package rawdb
+
+import (
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/rlp"
+)
+
+// ReadAppConfirmedBlocks retrieves the core blocks confirmed to the
+// application and not delivered yet, in hash order.
+func ReadAppConfirmedBlocks(db DatabaseReader) []*coreTypes.Block {
+	var blocks []*coreTypes.Block
+	iterateWithPrefix(db, appConfirmedPrefix, common.Hash{}.Bytes(), func(key, value []byte) bool {
+		block := new(coreTypes.Block)
+		if err := rlp.DecodeBytes(value, block); err != nil {
+			log.Error("Invalid confirmed block RLP", "key", key, "err", err)
+			return true
+		}
+		blocks = append(blocks, block)
+		return true
+	})
+	return blocks
+}
+
+// ReadAppConfirmedBlock retrieves a core block confirmed to the application,
+// nil if not stored.
+func ReadAppConfirmedBlock(db DatabaseReader, hash common.Hash) *coreTypes.Block {
+	data, _ := db.Get(appConfirmedBlockKey(hash))
+	if len(data) == 0 {
+		return nil
+	}
+	block := new(coreTypes.Block)
+	if err := rlp.DecodeBytes(data, block); err != nil {
+		log.Error("Invalid confirmed block RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return block
+}
+
+// WriteAppConfirmedBlock stores a core block confirmed to the application.
+func WriteAppConfirmedBlock(db DatabaseWriter, block *coreTypes.Block) {
+	data, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		log.Crit("Failed to RLP encode confirmed block", "err", err)
+	}
+	if err := db.Put(appConfirmedBlockKey(common.Hash(block.Hash)), data); err != nil {
+		log.Crit("Failed to store confirmed block", "err", err)
+	}
+}
+
+// DeleteAppConfirmedBlock removes a core block confirmed to the application.
+func DeleteAppConfirmedBlock(db DatabaseDeleter, hash common.Hash) {
+	if err := db.Delete(appConfirmedBlockKey(hash)); err != nil {
+		log.Crit("Failed to delete confirmed block", "err", err)
+	}
+}
@@ -0,0 +1,50 @@
+package rawdb
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/log"
+)
+
+// ReadAuditEntry retrieves the audit log entry of the given sequence number.
+func ReadAuditEntry(db DatabaseReader, seq uint64) *types.AuditEntry {
+	data, _ := db.Get(auditEntryKey(seq))
+	if len(data) == 0 {
+		return nil
+	}
+	entry := new(types.AuditEntry)
+	if err := json.Unmarshal(data, entry); err != nil {
+		log.Error("Invalid audit entry JSON", "seq", seq, "err", err)
+		return nil
+	}
+	return entry
+}
+
+// WriteAuditEntry stores an audit log entry under its sequence number.
+func WriteAuditEntry(db DatabaseWriter, entry *types.AuditEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Crit("Failed to JSON encode audit entry", "err", err)
+	}
+	if err := db.Put(auditEntryKey(entry.Seq), data); err != nil {
+		log.Crit("Failed to store audit entry", "err", err)
+	}
+}
+
+// ReadAuditLogLength retrieves the number of entries of the audit log.
+func ReadAuditLogLength(db DatabaseReader) uint64 {
+	data, _ := db.Get(auditLogLengthKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteAuditLogLength stores the number of entries of the audit log.
+func WriteAuditLogLength(db DatabaseWriter, length uint64) {
+	if err := db.Put(auditLogLengthKey, encodeBlockNumber(length)); err != nil {
+		log.Crit("Failed to store audit log length", "err", err)
+	}
+}
@@ -127,6 +127,46 @@ func WriteFastTrieProgress(db DatabaseWriter, count uint64) {
 	}
 }
 
+// FastSyncProgress is the progress of an incomplete fast sync, persisted to
+// resume it where it left off after a restart.
+type FastSyncProgress struct {
+	Pivot   uint64 // Number of the block whose state is synced
+	Headers uint64 // Number of the last header imported
+}
+
+// ReadFastSyncProgress retrieves the progress of an incomplete fast sync, or
+// nil if there is none.
+func ReadFastSyncProgress(db DatabaseReader) *FastSyncProgress {
+	data, _ := db.Get(fastSyncProgressKey)
+	if len(data) == 0 {
+		return nil
+	}
+	progress := new(FastSyncProgress)
+	if err := rlp.DecodeBytes(data, progress); err != nil {
+		log.Error("Invalid fast sync progress RLP", "err", err)
+		return nil
+	}
+	return progress
+}
+
+// WriteFastSyncProgress stores the progress of an incomplete fast sync.
+func WriteFastSyncProgress(db DatabaseWriter, progress *FastSyncProgress) {
+	data, err := rlp.EncodeToBytes(progress)
+	if err != nil {
+		log.Crit("Failed to RLP encode fast sync progress", "err", err)
+	}
+	if err := db.Put(fastSyncProgressKey, data); err != nil {
+		log.Crit("Failed to store fast sync progress", "err", err)
+	}
+}
+
+// DeleteFastSyncProgress removes the progress of a fast sync once complete.
+func DeleteFastSyncProgress(db DatabaseDeleter) {
+	if err := db.Delete(fastSyncProgressKey); err != nil {
+		log.Crit("Failed to delete fast sync progress", "err", err)
+	}
+}
+
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(headerKey(number, hash))
@@ -352,6 +392,7 @@ func WriteBlock(db DatabaseWriter, block *types.Block) {
 // DeleteBlock removes all block data associated with a hash.
 func DeleteBlock(db DatabaseDeleter, hash common.Hash, number uint64) {
 	DeleteReceipts(db, hash, number)
+	DeleteExtendedReceipts(db, hash, number)
 	DeleteHeader(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
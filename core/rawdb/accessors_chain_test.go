@@ -264,6 +264,24 @@ func TestHeadStorage(t *testing.T) {
 	}
 }
 
+// Tests that the progress of a fast sync can be stored, retrieved and deleted.
+func TestFastSyncProgressStorage(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+
+	if progress := ReadFastSyncProgress(db); progress != nil {
+		t.Fatalf("Non fast sync progress returned: %v", progress)
+	}
+	want := &FastSyncProgress{Pivot: 100, Headers: 200}
+	WriteFastSyncProgress(db, want)
+	if progress := ReadFastSyncProgress(db); progress == nil || *progress != *want {
+		t.Fatalf("Fast sync progress mismatch: have %v, want %v", progress, want)
+	}
+	DeleteFastSyncProgress(db)
+	if progress := ReadFastSyncProgress(db); progress != nil {
+		t.Fatalf("Deleted fast sync progress returned: %v", progress)
+	}
+}
+
 // Tests that receipts associated with a single block can be stored and retrieved.
 func TestBlockReceiptStorage(t *testing.T) {
 	db := ethdb.NewMemDatabase()
@@ -317,3 +335,57 @@ func TestBlockReceiptStorage(t *testing.T) {
 		t.Fatalf("deleted receipts returned: %v", rs)
 	}
 }
+
+// Tests extended receipt storage and retrieval operations.
+func TestBlockExtendedReceiptStorage(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+
+	receipts := []*types.Receipt{
+		{
+			TxHash:          common.BytesToHash([]byte{0x11, 0x11}),
+			GasUsed:         111111,
+			GasRefund:       15000,
+			TouchedAccounts: []common.Address{{0x01}, {0x11}},
+		},
+		{
+			TxHash:  common.BytesToHash([]byte{0x22, 0x22}),
+			GasUsed: 222222,
+		},
+	}
+	hash := common.BytesToHash([]byte{0x03, 0x14})
+	if rs := ReadExtendedReceipts(db, hash, 0); rs != nil {
+		t.Fatalf("non existent extended receipts returned: %v", rs)
+	}
+	WriteExtendedReceipts(db, hash, 0, receipts)
+	rs := ReadExtendedReceipts(db, hash, 0)
+	if len(rs) != len(receipts) {
+		t.Fatalf("extended receipts length mismatch: have %d, want %d", len(rs), len(receipts))
+	}
+	for i, receipt := range receipts {
+		if rs[i].TxHash != receipt.TxHash || rs[i].GasRefund != receipt.GasRefund ||
+			len(rs[i].TouchedAccounts) != len(receipt.TouchedAccounts) {
+			t.Fatalf("extended receipt #%d mismatch: have %+v, want %+v", i, rs[i], receipt.Extended())
+		}
+		for j, addr := range receipt.TouchedAccounts {
+			if rs[i].TouchedAccounts[j] != addr {
+				t.Fatalf("extended receipt #%d: touched account %d mismatch: have %x, want %x",
+					i, j, rs[i].TouchedAccounts[j], addr)
+			}
+		}
+	}
+	// The consensus receipts are not affected by the extended fields.
+	WriteReceipts(db, hash, 0, receipts)
+	if r := ReadReceipts(db, hash, 0)[0]; r.GasRefund != 0 || r.TouchedAccounts != nil {
+		t.Fatalf("extended fields stored with the receipt: %+v", r)
+	}
+	DeleteExtendedReceipts(db, hash, 0)
+	if rs := ReadExtendedReceipts(db, hash, 0); rs != nil {
+		t.Fatalf("deleted extended receipts returned: %v", rs)
+	}
+	// Deleting the block deletes its extended receipts too.
+	WriteExtendedReceipts(db, hash, 0, receipts)
+	DeleteBlock(db, hash, 0)
+	if rs := ReadExtendedReceipts(db, hash, 0); rs != nil {
+		t.Fatalf("extended receipts of deleted block returned: %v", rs)
+	}
+}
@@ -2,12 +2,16 @@ package rawdb
 
 import (
 	"bytes"
+	"fmt"
+	"sort"
 
 	coreTypes "github.com/portto/tangerine-consensus/core/types"
 
 	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/ethdb"
 	"github.com/portto/go-tangerine/log"
 	"github.com/portto/go-tangerine/rlp"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 )
 
 func ReadCoreBlockRLP(db DatabaseReader, hash common.Hash) rlp.RawValue {
@@ -49,3 +53,130 @@ func WriteCoreBlock(db DatabaseWriter, hash common.Hash, block *coreTypes.Block)
 	}
 	WriteCoreBlockRLP(db, hash, data)
 }
+
+// ReadCoreFinalizedHash retrieves the hash of the finalized core block at the
+// position of round and height, nil if not stored.
+func ReadCoreFinalizedHash(db DatabaseReader, round, height uint64) *common.Hash {
+	data, _ := db.Get(coreFinalizedHashKey(round, height))
+	if len(data) != common.HashLength {
+		return nil
+	}
+	hash := common.BytesToHash(data)
+	return &hash
+}
+
+// WriteCoreFinalizedHash stores the hash of the finalized core block at the
+// position of round and height. The block itself is stored by hash by the
+// consensus core once delivered.
+func WriteCoreFinalizedHash(db DatabaseWriter, round, height uint64, hash common.Hash) {
+	if err := db.Put(coreFinalizedHashKey(round, height), hash.Bytes()); err != nil {
+		log.Crit("Failed to store finalized core block hash", "err", err)
+	}
+}
+
+// IterateCoreBlockHashes calls fn with the hash of every core block stored in
+// db. It returns false if db does not support iteration.
+func IterateCoreBlockHashes(db DatabaseReader, fn func(common.Hash)) bool {
+	it := NewCoreBlockIterator(db)
+	if it == nil {
+		return false
+	}
+	defer it.Release()
+	for it.Next() {
+		fn(it.Hash())
+	}
+	return it.Error() == nil
+}
+
+// CoreBlockIterator iterates the core blocks stored in a database in hash
+// order, one block at a time.
+type CoreBlockIterator struct {
+	it   iterator.Iterator // Iterator of a LevelDB database
+	db   DatabaseReader    // Memory database, if not a LevelDB one
+	keys [][]byte          // Keys of the memory database left to visit
+
+	hash  common.Hash
+	value []byte
+}
+
+// NewCoreBlockIterator returns an iterator over the core blocks stored in db,
+// nil if db does not support iteration. A LevelDB iterator holds a snapshot
+// of the database until released.
+func NewCoreBlockIterator(db DatabaseReader) *CoreBlockIterator {
+	switch db := db.(type) {
+	case *ethdb.LDBDatabase:
+		return &CoreBlockIterator{it: db.NewIteratorWithPrefix(coreBlockPrefix)}
+	case *ethdb.MemDatabase:
+		keys := db.Keys()
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+		return &CoreBlockIterator{db: db, keys: keys}
+	}
+	return nil
+}
+
+// Next moves the iterator to the next core block, returning false when there
+// are no more blocks or an error occurred.
+func (it *CoreBlockIterator) Next() bool {
+	if it.it != nil {
+		for it.it.Next() {
+			if hash, ok := coreBlockHashFromKey(it.it.Key()); ok {
+				it.hash, it.value = hash, it.it.Value()
+				return true
+			}
+		}
+		return false
+	}
+	for len(it.keys) > 0 {
+		key := it.keys[0]
+		it.keys = it.keys[1:]
+		hash, ok := coreBlockHashFromKey(key)
+		if !ok {
+			continue
+		}
+		// Skip the blocks deleted since the iterator was created.
+		if value, err := it.db.Get(key); err == nil {
+			it.hash, it.value = hash, value
+			return true
+		}
+	}
+	return false
+}
+
+// Hash returns the hash of the current core block.
+func (it *CoreBlockIterator) Hash() common.Hash {
+	return it.hash
+}
+
+// Block decodes the current core block.
+func (it *CoreBlockIterator) Block() (*coreTypes.Block, error) {
+	block := new(coreTypes.Block)
+	if err := rlp.DecodeBytes(it.value, block); err != nil {
+		return nil, fmt.Errorf("invalid core block RLP %x: %v", it.hash, err)
+	}
+	return block, nil
+}
+
+// Error returns the error the iteration stopped on, if any.
+func (it *CoreBlockIterator) Error() error {
+	if it.it != nil {
+		return it.it.Error()
+	}
+	return nil
+}
+
+// Release releases the resources held by the iterator.
+func (it *CoreBlockIterator) Release() {
+	if it.it != nil {
+		it.it.Release()
+	}
+	it.keys, it.value = nil, nil
+}
+
+// coreBlockHashFromKey returns the hash of the core block stored under key,
+// which may belong to another data type sharing the prefix.
+func coreBlockHashFromKey(key []byte) (common.Hash, bool) {
+	if len(key) != len(coreBlockPrefix)+common.HashLength || !bytes.HasPrefix(key, coreBlockPrefix) {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(key[len(coreBlockPrefix):]), true
+}
@@ -31,6 +31,12 @@ func ReadCoreDKGPrivateKey(db DatabaseReader, round, reset uint64) *coreDKG.Priv
 	if len(data) == 0 {
 		return nil
 	}
+	return DecodeCoreDKGPrivateKey(data, round, reset)
+}
+
+// DecodeCoreDKGPrivateKey decodes the DKG private key of round stored as
+// data, returning nil if it is not the one of reset.
+func DecodeCoreDKGPrivateKey(data rlp.RawValue, round, reset uint64) *coreDKG.PrivateKey {
 	key := &dkgPrivateKey{
 		PK: new(coreDKG.PrivateKey),
 	}
@@ -45,6 +51,16 @@ func ReadCoreDKGPrivateKey(db DatabaseReader, round, reset uint64) *coreDKG.Priv
 }
 
 func WriteCoreDKGPrivateKey(db DatabaseWriter, round, reset uint64, pk *coreDKG.PrivateKey) error {
+	data, err := EncodeCoreDKGPrivateKey(round, reset, pk)
+	if err != nil {
+		return err
+	}
+	return WriteCoreDKGPrivateKeyRLP(db, round, data)
+}
+
+// EncodeCoreDKGPrivateKey encodes the DKG private key of round and reset as
+// stored by WriteCoreDKGPrivateKey.
+func EncodeCoreDKGPrivateKey(round, reset uint64, pk *coreDKG.PrivateKey) (rlp.RawValue, error) {
 	key := &dkgPrivateKey{
 		PK:    pk,
 		Reset: reset,
@@ -52,7 +68,7 @@ func WriteCoreDKGPrivateKey(db DatabaseWriter, round, reset uint64, pk *coreDKG.
 	data, err := rlp.EncodeToBytes(key)
 	if err != nil {
 		log.Crit("Failed to RLP encode core DKG private key", "round", round, "err", err)
-		return err
+		return nil, err
 	}
-	return WriteCoreDKGPrivateKeyRLP(db, round, data)
+	return data, nil
 }
@@ -26,6 +26,11 @@ func ReadCoreDKGProtocol(db DatabaseReader) *coreDb.DKGProtocolInfo {
 	if len(data) == 0 {
 		return nil
 	}
+	return DecodeCoreDKGProtocol(data)
+}
+
+// DecodeCoreDKGProtocol decodes the DKG protocol info stored as data.
+func DecodeCoreDKGProtocol(data rlp.RawValue) *coreDb.DKGProtocolInfo {
 	protocol := new(coreDb.DKGProtocolInfo)
 	if err := rlp.Decode(bytes.NewReader(data), protocol); err != nil {
 		log.Error("Invalid core DKG protocol RLP", "err", err)
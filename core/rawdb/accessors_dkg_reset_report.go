@@ -0,0 +1,33 @@
+package rawdb
+
+import (
+	"encoding/json"
+
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/log"
+)
+
+// ReadDKGResetReport retrieves the report of the given DKG reset.
+func ReadDKGResetReport(db DatabaseReader, round, reset uint64) *types.DKGResetReport {
+	data, _ := db.Get(dkgResetReportKey(round, reset))
+	if len(data) == 0 {
+		return nil
+	}
+	report := new(types.DKGResetReport)
+	if err := json.Unmarshal(data, report); err != nil {
+		log.Error("Invalid DKG reset report JSON", "round", round, "reset", reset, "err", err)
+		return nil
+	}
+	return report
+}
+
+// WriteDKGResetReport stores the report of a DKG reset.
+func WriteDKGResetReport(db DatabaseWriter, report *types.DKGResetReport) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		log.Crit("Failed to JSON encode DKG reset report", "err", err)
+	}
+	if err := db.Put(dkgResetReportKey(report.Round, report.Reset), data); err != nil {
+		log.Crit("Failed to store DKG reset report", "err", err)
+	}
+}
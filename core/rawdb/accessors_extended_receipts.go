@@ -0,0 +1,46 @@
+package rawdb
+
+import (
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/rlp"
+)
+
+// ReadExtendedReceipts retrieves the extended receipts of the transactions of
+// a block, nil if they were not stored.
+func ReadExtendedReceipts(db DatabaseReader, hash common.Hash, number uint64) []*types.ExtendedReceipt {
+	data, _ := db.Get(extendedReceiptsKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	var receipts []*types.ExtendedReceipt
+	if err := rlp.DecodeBytes(data, &receipts); err != nil {
+		log.Error("Invalid extended receipt array RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return receipts
+}
+
+// WriteExtendedReceipts stores the extended receipts of the transaction
+// receipts of a block.
+func WriteExtendedReceipts(db DatabaseWriter, hash common.Hash, number uint64, receipts types.Receipts) {
+	extended := make([]*types.ExtendedReceipt, len(receipts))
+	for i, receipt := range receipts {
+		extended[i] = receipt.Extended()
+	}
+	data, err := rlp.EncodeToBytes(extended)
+	if err != nil {
+		log.Crit("Failed to encode block extended receipts", "err", err)
+	}
+	if err := db.Put(extendedReceiptsKey(number, hash), data); err != nil {
+		log.Crit("Failed to store block extended receipts", "err", err)
+	}
+}
+
+// DeleteExtendedReceipts removes the extended receipts of a block.
+func DeleteExtendedReceipts(db DatabaseDeleter, hash common.Hash, number uint64) {
+	if err := db.Delete(extendedReceiptsKey(number, hash)); err != nil {
+		log.Crit("Failed to delete block extended receipts", "err", err)
+	}
+}
@@ -0,0 +1,35 @@
+package rawdb
+
+import (
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/rlp"
+)
+
+// ReadGovPendingTxs retrieves the governance transactions sent by address
+// which were not yet known to be included in the chain.
+func ReadGovPendingTxs(db DatabaseReader, address common.Address) []*types.Transaction {
+	data, _ := db.Get(govPendingTxsKey(address))
+	if len(data) == 0 {
+		return nil
+	}
+	var txs []*types.Transaction
+	if err := rlp.DecodeBytes(data, &txs); err != nil {
+		log.Error("Invalid pending governance transactions RLP", "address", address, "err", err)
+		return nil
+	}
+	return txs
+}
+
+// WriteGovPendingTxs stores the pending governance transactions sent by
+// address, replacing the previously stored ones.
+func WriteGovPendingTxs(db DatabaseWriter, address common.Address, txs []*types.Transaction) {
+	data, err := rlp.EncodeToBytes(txs)
+	if err != nil {
+		log.Crit("Failed to RLP encode pending governance transactions", "err", err)
+	}
+	if err := db.Put(govPendingTxsKey(address), data); err != nil {
+		log.Crit("Failed to store pending governance transactions", "err", err)
+	}
+}
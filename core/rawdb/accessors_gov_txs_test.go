@@ -0,0 +1,43 @@
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/ethdb"
+)
+
+// Tests that pending governance transactions can be stored and retrieved.
+func TestGovPendingTxsStorage(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	address := common.HexToAddress("0x01")
+
+	if txs := ReadGovPendingTxs(db, address); txs != nil {
+		t.Fatalf("non existent pending transactions returned: %v", txs)
+	}
+	txs := []*types.Transaction{
+		types.NewTransaction(1, common.HexToAddress("0x02"), big.NewInt(0), 21000, big.NewInt(1), []byte{0x11}),
+		types.NewTransaction(2, common.HexToAddress("0x02"), big.NewInt(0), 21000, big.NewInt(1), []byte{0x22}),
+	}
+	WriteGovPendingTxs(db, address, txs)
+
+	stored := ReadGovPendingTxs(db, address)
+	if len(stored) != len(txs) {
+		t.Fatalf("pending transaction count mismatch: have %d, want %d", len(stored), len(txs))
+	}
+	for i, tx := range stored {
+		if tx.Hash() != txs[i].Hash() {
+			t.Errorf("pending transaction %d mismatch: have %x, want %x", i, tx.Hash(), txs[i].Hash())
+		}
+	}
+	if txs := ReadGovPendingTxs(db, common.HexToAddress("0x03")); txs != nil {
+		t.Errorf("pending transactions of other address returned: %v", txs)
+	}
+
+	WriteGovPendingTxs(db, address, nil)
+	if txs := ReadGovPendingTxs(db, address); len(txs) != 0 {
+		t.Errorf("pending transactions not cleared: %v", txs)
+	}
+}
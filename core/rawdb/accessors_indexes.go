@@ -17,6 +17,8 @@
 package rawdb
 
 import (
+	"encoding/binary"
+
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/core/types"
 	"github.com/portto/go-tangerine/log"
@@ -62,6 +64,33 @@ func DeleteTxLookupEntry(db DatabaseDeleter, hash common.Hash) {
 	db.Delete(txLookupKey(hash))
 }
 
+// DeleteTxLookupEntries removes the positional metadata of every transaction
+// from a block.
+func DeleteTxLookupEntries(db DatabaseDeleter, block *types.Block) {
+	for _, tx := range block.Transactions() {
+		DeleteTxLookupEntry(db, tx.Hash())
+	}
+}
+
+// ReadTxIndexTail retrieves the number of the oldest block whose transactions
+// are indexed, or nil if the transactions of all blocks are indexed.
+func ReadTxIndexTail(db DatabaseReader) *uint64 {
+	data, _ := db.Get(txIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteTxIndexTail stores the number of the oldest block whose transactions
+// are indexed.
+func WriteTxIndexTail(db DatabaseWriter, number uint64) {
+	if err := db.Put(txIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store transaction index tail", "err", err)
+	}
+}
+
 // ReadTransaction retrieves a specific transaction from the database, along with
 // its added positional metadata.
 func ReadTransaction(db DatabaseReader, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
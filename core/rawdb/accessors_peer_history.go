@@ -0,0 +1,61 @@
+package rawdb
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/log"
+)
+
+// ReadPeerEvents retrieves the peer events recorded within the given bucket
+// of the peer history.
+func ReadPeerEvents(db DatabaseReader, bucket uint64) []*types.PeerEvent {
+	data, _ := db.Get(peerHistoryKey(bucket))
+	if len(data) == 0 {
+		return nil
+	}
+	var events []*types.PeerEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		log.Error("Invalid peer events JSON", "bucket", bucket, "err", err)
+		return nil
+	}
+	return events
+}
+
+// WritePeerEvents stores the peer events recorded within the given bucket of
+// the peer history.
+func WritePeerEvents(db DatabaseWriter, bucket uint64, events []*types.PeerEvent) {
+	data, err := json.Marshal(events)
+	if err != nil {
+		log.Crit("Failed to JSON encode peer events", "err", err)
+	}
+	if err := db.Put(peerHistoryKey(bucket), data); err != nil {
+		log.Crit("Failed to store peer events", "err", err)
+	}
+}
+
+// DeletePeerEvents removes the peer events of the given bucket.
+func DeletePeerEvents(db DatabaseDeleter, bucket uint64) {
+	if err := db.Delete(peerHistoryKey(bucket)); err != nil {
+		log.Crit("Failed to delete peer events", "err", err)
+	}
+}
+
+// ReadPeerHistoryTail retrieves the oldest bucket of the peer history, or nil
+// if nothing was recorded yet.
+func ReadPeerHistoryTail(db DatabaseReader) *uint64 {
+	data, _ := db.Get(peerHistoryTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	bucket := binary.BigEndian.Uint64(data)
+	return &bucket
+}
+
+// WritePeerHistoryTail stores the oldest bucket of the peer history.
+func WritePeerHistoryTail(db DatabaseWriter, bucket uint64) {
+	if err := db.Put(peerHistoryTailKey, encodeBlockNumber(bucket)); err != nil {
+		log.Crit("Failed to store peer history tail", "err", err)
+	}
+}
@@ -0,0 +1,73 @@
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/log"
+)
+
+// ReadProposedBlocks retrieves the sorted numbers of the blocks in [from, to]
+// proposed by address. It returns nil if db does not support iteration.
+func ReadProposedBlocks(db DatabaseReader, address common.Address, from, to uint64) []uint64 {
+	var numbers []uint64
+	prefix := append(proposedBlockPrefix, address.Bytes()...)
+	iterateWithPrefix(db, prefix, encodeBlockNumber(from), func(key, value []byte) bool {
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number > to {
+			return false
+		}
+		numbers = append(numbers, number)
+		return true
+	})
+	return numbers
+}
+
+// WriteProposedBlock adds the block to the proposer index under its coinbase.
+func WriteProposedBlock(db DatabaseWriter, block *types.Block) {
+	if err := db.Put(proposedBlockKey(block.Coinbase(), block.NumberU64()), []byte{}); err != nil {
+		log.Crit("Failed to store proposed block", "err", err)
+	}
+}
+
+// iterateWithPrefix calls fn with the entries of db whose keys have the given
+// prefix, in key order from prefix+start, until fn returns false. Only keys as
+// long as prefix+start are visited. Databases other than LevelDB and memory
+// ones are not iterated.
+func iterateWithPrefix(db DatabaseReader, prefix, start []byte, fn func(key, value []byte) bool) {
+	switch db := db.(type) {
+	case *ethdb.LDBDatabase:
+		it := db.NewIteratorWithPrefix(prefix)
+		defer it.Release()
+		for ok := it.Seek(append(common.CopyBytes(prefix), start...)); ok; ok = it.Next() {
+			if len(it.Key()) != len(prefix)+len(start) {
+				continue
+			}
+			if !fn(it.Key(), it.Value()) {
+				return
+			}
+		}
+	case *ethdb.MemDatabase:
+		var keys [][]byte
+		first := append(common.CopyBytes(prefix), start...)
+		for _, key := range db.Keys() {
+			if bytes.HasPrefix(key, prefix) && len(key) == len(first) && bytes.Compare(key, first) >= 0 {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+		for _, key := range keys {
+			value, err := db.Get(key)
+			if err != nil {
+				continue
+			}
+			if !fn(key, value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package rawdb
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/ethdb"
+)
+
+// Tests that the proposer index returns the sorted and deduplicated numbers
+// of the blocks proposed in a range, on both memory and LevelDB databases.
+func TestProposedBlocksStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proposer-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ldb, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer ldb.Close()
+
+	for _, db := range []ethdb.Database{ethdb.NewMemDatabase(), ldb} {
+		proposer := common.HexToAddress("0x01")
+		other := common.HexToAddress("0x02")
+
+		for _, n := range []uint64{5, 1, 3, 3, 256, 1 << 40} {
+			WriteProposedBlock(db, types.NewBlockWithHeader(&types.Header{
+				Number:   new(big.Int).SetUint64(n),
+				Coinbase: proposer,
+			}))
+		}
+		WriteProposedBlock(db, types.NewBlockWithHeader(&types.Header{
+			Number:   big.NewInt(2),
+			Coinbase: other,
+		}))
+
+		if numbers := ReadProposedBlocks(db, proposer, 0, 1<<40); !reflect.DeepEqual(numbers, []uint64{1, 3, 5, 256, 1 << 40}) {
+			t.Errorf("%T: wrong proposed blocks: %v", db, numbers)
+		}
+		if numbers := ReadProposedBlocks(db, proposer, 2, 256); !reflect.DeepEqual(numbers, []uint64{3, 5, 256}) {
+			t.Errorf("%T: wrong proposed blocks in range: %v", db, numbers)
+		}
+		if numbers := ReadProposedBlocks(db, other, 0, 10); !reflect.DeepEqual(numbers, []uint64{2}) {
+			t.Errorf("%T: wrong proposed blocks of other proposer: %v", db, numbers)
+		}
+	}
+}
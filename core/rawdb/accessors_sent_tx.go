@@ -0,0 +1,24 @@
+package rawdb
+
+import (
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/log"
+)
+
+// ReadSentTxHash retrieves the hash of the transaction sent with the
+// idempotency key of the given hash, the zero hash if none was.
+func ReadSentTxHash(db DatabaseReader, keyHash common.Hash) common.Hash {
+	data, _ := db.Get(sentTxKey(keyHash))
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteSentTxHash stores the hash of the transaction sent with the
+// idempotency key of the given hash.
+func WriteSentTxHash(db DatabaseWriter, keyHash, txHash common.Hash) {
+	if err := db.Put(sentTxKey(keyHash), txHash.Bytes()); err != nil {
+		log.Crit("Failed to store sent transaction hash", "err", err)
+	}
+}
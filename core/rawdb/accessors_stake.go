@@ -0,0 +1,37 @@
+package rawdb
+
+import (
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/rlp"
+)
+
+// ReadStakeEvents retrieves the stake events of the node owner address,
+// ordered by block. It returns nil if db does not support iteration.
+func ReadStakeEvents(db DatabaseReader, address common.Address) []*types.StakeEvent {
+	var all []*types.StakeEvent
+	prefix := append(stakeEventsPrefix, address.Bytes()...)
+	iterateWithPrefix(db, prefix, encodeBlockNumber(0), func(key, value []byte) bool {
+		var events []*types.StakeEvent
+		if err := rlp.DecodeBytes(value, &events); err != nil {
+			log.Error("Invalid stake events RLP", "address", address, "err", err)
+			return true
+		}
+		all = append(all, events...)
+		return true
+	})
+	return all
+}
+
+// WriteStakeEvents stores the stake events of the node owner address in the
+// block number, replacing the ones of any other block with that number.
+func WriteStakeEvents(db DatabaseWriter, address common.Address, number uint64, events []*types.StakeEvent) {
+	data, err := rlp.EncodeToBytes(events)
+	if err != nil {
+		log.Crit("Failed to RLP encode stake events", "err", err)
+	}
+	if err := db.Put(stakeEventsKey(address, number), data); err != nil {
+		log.Crit("Failed to store stake events", "err", err)
+	}
+}
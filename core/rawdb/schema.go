@@ -41,6 +41,18 @@ var (
 	// fastTrieProgressKey tracks the number of trie entries imported during fast sync.
 	fastTrieProgressKey = []byte("TrieSync")
 
+	// fastSyncProgressKey tracks the pivot and the headers of an incomplete fast sync.
+	fastSyncProgressKey = []byte("FastSyncProgress")
+
+	// txIndexTailKey tracks the oldest block whose transactions are indexed.
+	txIndexTailKey = []byte("TransactionIndexTail")
+
+	// peerHistoryTailKey tracks the oldest bucket of the peer history.
+	peerHistoryTailKey = []byte("PeerHistoryTail")
+
+	// auditLogLengthKey tracks the number of entries of the audit log.
+	auditLogLengthKey = []byte("AuditLogLength")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -50,6 +62,8 @@ var (
 	blockBodyPrefix     = []byte("b") // blockBodyPrefix + num (uint64 big endian) + hash -> block body
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 
+	extendedReceiptsPrefix = []byte("extended-receipts-") // extendedReceiptsPrefix + num (uint64 big endian) + hash -> block extended receipts
+
 	govStatePrefix = []byte("g")
 
 	txLookupPrefix  = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
@@ -59,6 +73,16 @@ var (
 	coreDKGPrivateKeyPrefix   = []byte("DPK")
 	coreCompactionChainTipKey = []byte("CoreChainTip")
 	coreDKGProtocolKey        = []byte("CoreDKGProtocol")
+	coreFinalizedHashPrefix   = []byte("core-finalized-hash-") // coreFinalizedHashPrefix + round (uint64 big endian) + height (uint64 big endian) -> finalized core block hash
+
+	dkgResetReportPrefix = []byte("dkg-reset-report-") // dkgResetReportPrefix + round (uint64 big endian) + reset (uint64 big endian) -> report
+	proposedBlockPrefix  = []byte("proposed-block-")   // proposedBlockPrefix + address + num (uint64 big endian) -> empty
+	govPendingTxsPrefix  = []byte("gov-pending-txs-")  // govPendingTxsPrefix + address -> pending governance transactions
+	stakeEventsPrefix    = []byte("stake-events-")     // stakeEventsPrefix + address + num (uint64 big endian) -> stake events
+	peerHistoryPrefix    = []byte("peer-history-")     // peerHistoryPrefix + bucket (uint64 big endian) -> peer events
+	appConfirmedPrefix   = []byte("app-confirmed-")    // appConfirmedPrefix + hash -> core block confirmed to the application
+	auditEntryPrefix     = []byte("audit-")            // auditEntryPrefix + seq (uint64 big endian) -> audit log entry
+	sentTxPrefix         = []byte("sent-tx-")          // sentTxPrefix + idempotency key hash -> transaction hash
 
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
@@ -115,6 +139,11 @@ func blockReceiptsKey(number uint64, hash common.Hash) []byte {
 	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+// extendedReceiptsKey = extendedReceiptsPrefix + num (uint64 big endian) + hash
+func extendedReceiptsKey(number uint64, hash common.Hash) []byte {
+	return append(append(extendedReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // txLookupKey = txLookupPrefix + hash
 func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
@@ -129,6 +158,11 @@ func coreBlockKey(hash common.Hash) []byte {
 	return append(coreBlockPrefix, hash.Bytes()...)
 }
 
+// coreFinalizedHashKey = coreFinalizedHashPrefix + round (uint64 big endian) + height (uint64 big endian)
+func coreFinalizedHashKey(round, height uint64) []byte {
+	return append(append(coreFinalizedHashPrefix, encodeBlockNumber(round)...), encodeBlockNumber(height)...)
+}
+
 // coreDKGPrivateKeyKey = coreDKGPrivateKeyPrefix + round
 func coreDKGPrivateKeyKey(round uint64) []byte {
 	ret := make([]byte, len(coreDKGPrivateKeyPrefix)+8)
@@ -137,6 +171,46 @@ func coreDKGPrivateKeyKey(round uint64) []byte {
 	return ret
 }
 
+// dkgResetReportKey = dkgResetReportPrefix + round (uint64 big endian) + reset (uint64 big endian)
+func dkgResetReportKey(round, reset uint64) []byte {
+	return append(append(dkgResetReportPrefix, encodeBlockNumber(round)...), encodeBlockNumber(reset)...)
+}
+
+// proposedBlockKey = proposedBlockPrefix + address + num (uint64 big endian)
+func proposedBlockKey(address common.Address, number uint64) []byte {
+	return append(append(proposedBlockPrefix, address.Bytes()...), encodeBlockNumber(number)...)
+}
+
+// govPendingTxsKey = govPendingTxsPrefix + address
+func govPendingTxsKey(address common.Address) []byte {
+	return append(govPendingTxsPrefix, address.Bytes()...)
+}
+
+// appConfirmedBlockKey = appConfirmedPrefix + hash
+func appConfirmedBlockKey(hash common.Hash) []byte {
+	return append(appConfirmedPrefix, hash.Bytes()...)
+}
+
+// auditEntryKey = auditEntryPrefix + seq (uint64 big endian)
+func auditEntryKey(seq uint64) []byte {
+	return append(auditEntryPrefix, encodeBlockNumber(seq)...)
+}
+
+// stakeEventsKey = stakeEventsPrefix + address + num (uint64 big endian)
+func stakeEventsKey(address common.Address, number uint64) []byte {
+	return append(append(stakeEventsPrefix, address.Bytes()...), encodeBlockNumber(number)...)
+}
+
+// peerHistoryKey = peerHistoryPrefix + bucket (uint64 big endian)
+func peerHistoryKey(bucket uint64) []byte {
+	return append(peerHistoryPrefix, encodeBlockNumber(bucket)...)
+}
+
+// sentTxKey = sentTxPrefix + idempotency key hash
+func sentTxKey(keyHash common.Hash) []byte {
+	return append(sentTxPrefix, keyHash.Bytes()...)
+}
+
 // bloomBitsKey = bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash
 func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	key := append(append(bloomBitsPrefix, make([]byte, 10)...), hash.Bytes()...)
@@ -0,0 +1,78 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/core/vm"
+	"github.com/portto/go-tangerine/ethdb"
+)
+
+// stakeEventKinds maps the topics of the governance contract events changing
+// the stake of a node to their kinds.
+var stakeEventKinds = map[common.Hash]types.StakeEventKind{
+	vm.GovernanceABI.Events["Staked"].Id():    types.StakeEventStaked,
+	vm.GovernanceABI.Events["Unstaked"].Id():  types.StakeEventUnstaked,
+	vm.GovernanceABI.Events["Withdrawn"].Id(): types.StakeEventWithdrawn,
+}
+
+// stakeEvents extracts the stake events in the receipts of block, grouped by
+// node owner.
+func stakeEvents(block *types.Block, receipts types.Receipts) map[common.Address][]*types.StakeEvent {
+	var (
+		events   map[common.Address][]*types.StakeEvent
+		logIndex uint
+	)
+	for i, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			index := logIndex
+			logIndex++
+			if l.Address != vm.GovernanceContractAddress || len(l.Topics) != 2 {
+				continue
+			}
+			kind, ok := stakeEventKinds[l.Topics[0]]
+			if !ok {
+				continue
+			}
+			if events == nil {
+				events = make(map[common.Address][]*types.StakeEvent)
+			}
+			owner := common.BytesToAddress(l.Topics[1].Bytes())
+			events[owner] = append(events[owner], &types.StakeEvent{
+				Kind:        kind,
+				Amount:      new(big.Int).SetBytes(l.Data),
+				BlockNumber: block.NumberU64(),
+				BlockHash:   block.Hash(),
+				TxHash:      block.Transactions()[i].Hash(),
+				LogIndex:    index,
+			})
+		}
+	}
+	return events
+}
+
+// writeStakeEvents adds the stake events in the receipts of block to the
+// stake event index.
+func writeStakeEvents(db ethdb.Putter, block *types.Block, receipts types.Receipts) {
+	for owner, events := range stakeEvents(block, receipts) {
+		rawdb.WriteStakeEvents(db, owner, block.NumberU64(), events)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/core/vm"
+	"github.com/portto/go-tangerine/ethdb"
+)
+
+// Tests that the stake events in the receipts of a block are indexed under
+// their node owners exactly once.
+func TestStakeEventIndex(t *testing.T) {
+	var (
+		db     = ethdb.NewMemDatabase()
+		owner1 = common.Address{0x01}
+		owner2 = common.Address{0x02}
+	)
+	stakeLog := func(event string, owner common.Address, amount int64) *types.Log {
+		return &types.Log{
+			Address: vm.GovernanceContractAddress,
+			Topics:  []common.Hash{vm.GovernanceABI.Events[event].Id(), owner.Hash()},
+			Data:    common.BigToHash(big.NewInt(amount)).Bytes(),
+		}
+	}
+	txs := []*types.Transaction{
+		types.NewTransaction(0, vm.GovernanceContractAddress, big.NewInt(0), 0, big.NewInt(0), nil),
+		types.NewTransaction(1, vm.GovernanceContractAddress, big.NewInt(0), 0, big.NewInt(0), nil),
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(7)}, txs, nil, nil)
+	receipts := types.Receipts{
+		{Logs: []*types.Log{
+			stakeLog("Staked", owner1, 100),
+			{Address: common.Address{0xff}, Topics: []common.Hash{vm.GovernanceABI.Events["Staked"].Id(), owner1.Hash()}},
+		}},
+		{Logs: []*types.Log{
+			stakeLog("NodeAdded", owner2, 0),
+			stakeLog("Unstaked", owner2, 50),
+			stakeLog("Withdrawn", owner1, 30),
+		}},
+	}
+	writeStakeEvents(db, block, receipts)
+	writeStakeEvents(db, block, receipts)
+
+	tests := []struct {
+		owner  common.Address
+		events []types.StakeEvent
+	}{
+		{owner1, []types.StakeEvent{
+			{Kind: types.StakeEventStaked, Amount: big.NewInt(100), TxHash: txs[0].Hash(), LogIndex: 0},
+			{Kind: types.StakeEventWithdrawn, Amount: big.NewInt(30), TxHash: txs[1].Hash(), LogIndex: 4},
+		}},
+		{owner2, []types.StakeEvent{
+			{Kind: types.StakeEventUnstaked, Amount: big.NewInt(50), TxHash: txs[1].Hash(), LogIndex: 3},
+		}},
+	}
+	for _, tt := range tests {
+		have := rawdb.ReadStakeEvents(db, tt.owner)
+		if len(have) != len(tt.events) {
+			t.Fatalf("owner %x: event count mismatch: have %d, want %d", tt.owner, len(have), len(tt.events))
+		}
+		for i, want := range tt.events {
+			event := have[i]
+			if event.Kind != want.Kind || event.Amount.Cmp(want.Amount) != 0 ||
+				event.TxHash != want.TxHash || event.LogIndex != want.LogIndex ||
+				event.BlockNumber != 7 || event.BlockHash != block.Hash() {
+				t.Errorf("owner %x event %d mismatch: have %+v, want %+v", tt.owner, i, event, want)
+			}
+		}
+	}
+}
@@ -18,6 +18,7 @@
 package state
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math/big"
@@ -568,6 +569,19 @@ func (self *StateDB) GetRefund() uint64 {
 	return self.refund
 }
 
+// TouchedAccounts returns the sorted addresses of the accounts touched since
+// the state was last finalised.
+func (s *StateDB) TouchedAccounts() []common.Address {
+	addrs := make([]common.Address, 0, len(s.journal.dirties))
+	for addr := range s.journal.dirties {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
+
 // Finalise finalises the state by removing the self destructed objects
 // and clears the journal as well as the refunds.
 func (s *StateDB) Finalise(deleteEmptyObjects bool) {
@@ -17,7 +17,6 @@
 package core
 
 import (
-	"errors"
 	"flag"
 	"math"
 	"math/big"
@@ -33,10 +32,6 @@ import (
 var legacyEvm = flag.Bool("legacy-evm", false, "make evm run origin logic")
 var TestingMode = false
 
-var (
-	errInsufficientBalanceForGas = errors.New("insufficient balance to pay for gas")
-)
-
 var lastInExtendedRoundResultCache atomic.Value
 
 type lastInExtendedRoundResultType struct {
@@ -71,6 +66,8 @@ type StateTransition struct {
 	data       []byte
 	state      vm.StateDB
 	evm        *vm.EVM
+	refunded   uint64 // Gas refunded by the refund counter
+	vmerr      error  // Error the EVM execution aborted with, if any
 }
 
 // Message represents a message sent to a contract.
@@ -145,6 +142,16 @@ func ApplyMessage(evm *vm.EVM, msg Message, gp *GasPool) ([]byte, uint64, bool,
 	return NewStateTransition(evm, msg, gp).TransitionDb()
 }
 
+// ApplyCall is ApplyMessage for messages that are only simulated, like calls
+// and gas estimations, additionally returning the error the EVM execution
+// aborted with. The bytes returned are the revert data if that error is
+// vm.ErrExecutionReverted.
+func ApplyCall(evm *vm.EVM, msg Message, gp *GasPool) ([]byte, uint64, error, error) {
+	st := NewStateTransition(evm, msg, gp)
+	ret, gas, _, err := st.TransitionDb()
+	return ret, gas, st.vmerr, err
+}
+
 // to returns the recipient of the message.
 func (st *StateTransition) to() common.Address {
 	if st.msg == nil || st.msg.To() == nil /* contract creation */ {
@@ -165,7 +172,7 @@ func (st *StateTransition) useGas(amount uint64) error {
 func (st *StateTransition) buyGas() error {
 	mgval := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.gasPrice)
 	if st.state.GetBalance(st.msg.From()).Cmp(mgval) < 0 {
-		return errInsufficientBalanceForGas
+		return ErrInsufficientFunds
 	}
 	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
 		return err
@@ -273,6 +280,7 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 		st.state.SetNonce(msg.From(), st.state.GetNonce(sender.Address())+1)
 		ret, st.gas, vmerr = evm.Call(sender, st.to(), st.data, st.gas, st.value)
 	}
+	st.vmerr = vmerr
 	if vmerr != nil {
 		log.Debug("VM returned with error", "err", vmerr)
 		// The only possible consensus-error would be if there wasn't
@@ -325,6 +333,7 @@ func (st *StateTransition) refundGas() {
 		refund = st.state.GetRefund()
 	}
 	st.gas += refund
+	st.refunded = refund
 
 	// Return ETH for remaining gas, exchanged at the original rate.
 	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
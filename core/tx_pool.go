@@ -99,6 +99,10 @@ var (
 	// General tx metrics
 	invalidTxCounter     = metrics.NewRegisteredCounter("txpool/invalid", nil)
 	underpricedTxCounter = metrics.NewRegisteredCounter("txpool/underpriced", nil)
+
+	// Sizes of the pool, updated at every status report
+	pendingGauge = metrics.NewRegisteredGauge("txpool/pending", nil)
+	queuedGauge  = metrics.NewRegisteredGauge("txpool/queued", nil)
 )
 
 // TxStatus is the current status of a transaction as seen by the pool.
@@ -329,6 +333,9 @@ func (pool *TxPool) loop() {
 			stales := pool.priced.stales
 			pool.mu.RUnlock()
 
+			pendingGauge.Update(int64(pending))
+			queuedGauge.Update(int64(queued))
+
 			if pending != prevPending || queued != prevQueued || stales != prevStales {
 				log.Debug("Transaction pool status report", "executable", pending, "queued", queued, "stales", stales)
 				prevPending, prevQueued, prevStales = pending, queued, stales
@@ -0,0 +1,172 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/event"
+	"github.com/portto/go-tangerine/log"
+)
+
+// txIndexChainHeadChanSize is the size of channel listening to ChainHeadEvent
+// in the transaction index maintenance loop.
+const txIndexChainHeadChanSize = 10
+
+// errTxIndexInterrupted is returned if (un)indexing transactions is aborted.
+var errTxIndexInterrupted = errors.New("transaction indexing interrupted")
+
+// IndexTransactions writes the lookup entries of the transactions in the
+// canonical blocks in [from, to) and moves the index tail down to from. Blocks
+// are indexed from the newest, so the index stays contiguous if interrupted.
+func IndexTransactions(db ethdb.Database, from, to uint64, interrupt <-chan struct{}) error {
+	var (
+		batch  = db.NewBatch()
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for number := to; number > from; number-- {
+		select {
+		case <-interrupt:
+			return errTxIndexInterrupted
+		default:
+		}
+		if block := rawdb.ReadBlock(db, rawdb.ReadCanonicalHash(db, number-1), number-1); block != nil {
+			rawdb.WriteTxLookupEntries(batch, block)
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			rawdb.WriteTxIndexTail(batch, number-1)
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Indexing transactions", "block", number-1, "remaining", number-1-from,
+				"elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	rawdb.WriteTxIndexTail(batch, from)
+	return batch.Write()
+}
+
+// UnindexTransactions removes the lookup entries of the transactions in the
+// canonical blocks in [from, to) and moves the index tail up to to.
+func UnindexTransactions(db ethdb.Database, from, to uint64, interrupt <-chan struct{}) error {
+	var (
+		batch  = db.NewBatch()
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for number := from; number < to; number++ {
+		select {
+		case <-interrupt:
+			return errTxIndexInterrupted
+		default:
+		}
+		if block := rawdb.ReadBlock(db, rawdb.ReadCanonicalHash(db, number), number); block != nil {
+			rawdb.DeleteTxLookupEntries(batch, block)
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			rawdb.WriteTxIndexTail(batch, number+1)
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Unindexing transactions", "block", number, "remaining", to-number,
+				"elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	rawdb.WriteTxIndexTail(batch, to)
+	return batch.Write()
+}
+
+// SetTxLookupLimit sets the number of recent blocks whose transactions are
+// indexed for lookups by hash. The lookup entries of older blocks are removed
+// in the background as the chain grows. Zero keeps the entries of all blocks.
+func (bc *BlockChain) SetTxLookupLimit(limit uint64) {
+	atomic.StoreUint64(&bc.txLookupLimit, limit)
+}
+
+// TxIndexTail returns the number of the oldest block whose transactions are
+// indexed.
+func (bc *BlockChain) TxIndexTail() uint64 {
+	if tail := rawdb.ReadTxIndexTail(bc.db); tail != nil {
+		return *tail
+	}
+	return 0
+}
+
+// maintainTxIndex removes the lookup entries of the transactions in blocks
+// falling out of the lookup limit as new heads arrive.
+func (bc *BlockChain) maintainTxIndex(headCh <-chan ChainHeadEvent, sub event.Subscription) {
+	defer bc.wg.Done()
+	defer sub.Unsubscribe()
+
+	// done is non-nil while unindexing runs in the background, so heads keep
+	// being consumed and never block the chain head feed.
+	var done chan struct{}
+	for {
+		select {
+		case head := <-headCh:
+			if done == nil {
+				done = make(chan struct{})
+				go func(head uint64) {
+					bc.unindexTransactions(head)
+					close(done)
+				}(head.Block.NumberU64())
+			}
+		case <-done:
+			done = nil
+		case <-sub.Err():
+			if done != nil {
+				<-done
+			}
+			return
+		case <-bc.quit:
+			if done != nil {
+				<-done
+			}
+			return
+		}
+	}
+}
+
+// unindexTransactions removes the lookup entries of the transactions in blocks
+// older than the lookup limit from head.
+func (bc *BlockChain) unindexTransactions(head uint64) {
+	limit := atomic.LoadUint64(&bc.txLookupLimit)
+	if limit == 0 || head < limit {
+		return
+	}
+	tail, newTail := bc.TxIndexTail(), head-limit+1
+	if tail >= newTail {
+		return
+	}
+	if err := UnindexTransactions(bc.db, tail, newTail, bc.quit); err != nil && err != errTxIndexInterrupted {
+		log.Error("Failed to unindex transactions", "from", tail, "to", newTail, "err", err)
+	}
+}
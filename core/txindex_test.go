@@ -0,0 +1,72 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/ethdb"
+)
+
+// Tests that transaction lookup entries are removed and restored by block
+// ranges, moving the index tail along.
+func TestTxIndexRange(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+
+	var blocks []*types.Block
+	for i := uint64(0); i < 10; i++ {
+		tx := types.NewTransaction(i, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil)
+		block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(i)},
+			[]*types.Transaction{tx}, nil, nil)
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), i)
+		rawdb.WriteTxLookupEntries(db, block)
+		blocks = append(blocks, block)
+	}
+	check := func(tail uint64) {
+		if have := rawdb.ReadTxIndexTail(db); have == nil || *have != tail {
+			t.Fatalf("index tail mismatch: have %v, want %d", have, tail)
+		}
+		for i, block := range blocks {
+			hash := block.Transactions()[0].Hash()
+			blockHash, _, _ := rawdb.ReadTxLookupEntry(db, hash)
+			indexed := blockHash != (common.Hash{})
+			if want := uint64(i) >= tail; indexed != want {
+				t.Errorf("block %d: indexed %v, want %v", i, indexed, want)
+			}
+		}
+	}
+	if err := UnindexTransactions(db, 0, 6, nil); err != nil {
+		t.Fatalf("failed to unindex transactions: %v", err)
+	}
+	check(6)
+	if err := IndexTransactions(db, 3, 6, nil); err != nil {
+		t.Fatalf("failed to index transactions: %v", err)
+	}
+	check(3)
+
+	interrupt := make(chan struct{})
+	close(interrupt)
+	if err := IndexTransactions(db, 0, 3, interrupt); err != errTxIndexInterrupted {
+		t.Fatalf("interrupted indexing error mismatch: have %v, want %v", err, errTxIndexInterrupted)
+	}
+	check(3)
+}
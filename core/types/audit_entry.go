@@ -0,0 +1,16 @@
+package types
+
+// AuditNode is the requester of the audited operations the node performs on
+// its own.
+const AuditNode = "node"
+
+// AuditEntry records a sensitive operation performed on the node, who
+// requested it and when.
+type AuditEntry struct {
+	Seq       uint64 `json:"seq"`
+	Time      int64  `json:"time"` // Local unix time in milliseconds
+	Operation string `json:"operation"`
+	Requester string `json:"requester"`
+	Details   string `json:"details,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"errors"
+	"time"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreCrypto "github.com/portto/tangerine-consensus/core/crypto"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/rlp"
+)
+
+// compactDexconMetaPrefix prefixes the compact encoding of DexconMeta. The
+// full encoding is an RLP list, which never starts with this byte.
+const compactDexconMetaPrefix = 0x01
+
+var errInvalidCompactDexconMeta = errors.New("invalid compact dexcon meta")
+
+// compactDexconMeta is the compact encoding of DexconMeta. It leaves out the
+// fields of the core block the header already carries: the position, the
+// randomness and the millisecond part of the timestamp. The payload is never
+// stored in DexconMeta as it duplicates the block body.
+type compactDexconMeta struct {
+	ProposerID   coreTypes.NodeID
+	ParentHash   coreCommon.Hash
+	Hash         coreCommon.Hash
+	SubMillis    uint64 // Sub-millisecond part of the timestamp in nanoseconds
+	PayloadHash  coreCommon.Hash
+	Witness      coreTypes.Witness
+	Signature    coreCrypto.Signature
+	CRSSignature coreCrypto.Signature
+}
+
+// EncodeDexconMeta encodes the core block delivered as the header, in the
+// compact form if compact is set. The compact form can only be decoded along
+// with a header whose number, round, time and randomness match the block.
+func EncodeDexconMeta(block *coreTypes.Block, compact bool) ([]byte, error) {
+	if !compact {
+		cpy := *block
+		cpy.Payload = nil
+		return rlp.EncodeToBytes(&cpy)
+	}
+	meta, err := rlp.EncodeToBytes(&compactDexconMeta{
+		ProposerID:   block.ProposerID,
+		ParentHash:   block.ParentHash,
+		Hash:         block.Hash,
+		SubMillis:    uint64(block.Timestamp.UnixNano() % int64(time.Millisecond)),
+		PayloadHash:  block.PayloadHash,
+		Witness:      block.Witness,
+		Signature:    block.Signature,
+		CRSSignature: block.CRSSignature,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{compactDexconMetaPrefix}, meta...), nil
+}
+
+// IsCompactDexconMeta reports whether meta is in the compact encoding.
+func IsCompactDexconMeta(meta []byte) bool {
+	return len(meta) > 0 && meta[0] == compactDexconMetaPrefix
+}
+
+// CoreBlock decodes the core block delivered as the header from DexconMeta,
+// which may be in either encoding.
+func (h *Header) CoreBlock() (*coreTypes.Block, error) {
+	block := new(coreTypes.Block)
+	if !IsCompactDexconMeta(h.DexconMeta) {
+		if err := rlp.DecodeBytes(h.DexconMeta, block); err != nil {
+			return nil, err
+		}
+		return block, nil
+	}
+	var meta compactDexconMeta
+	if err := rlp.DecodeBytes(h.DexconMeta[1:], &meta); err != nil {
+		return nil, err
+	}
+	if meta.SubMillis >= uint64(time.Millisecond) {
+		return nil, errInvalidCompactDexconMeta
+	}
+	*block = coreTypes.Block{
+		ProposerID: meta.ProposerID,
+		ParentHash: meta.ParentHash,
+		Hash:       meta.Hash,
+		Position: coreTypes.Position{
+			Round:  h.Round,
+			Height: h.Number.Uint64(),
+		},
+		Timestamp: time.Unix(0,
+			int64(h.Time)*int64(time.Millisecond)+int64(meta.SubMillis)).UTC(),
+		PayloadHash:  meta.PayloadHash,
+		Witness:      meta.Witness,
+		Randomness:   common.CopyBytes(h.Randomness),
+		Signature:    meta.Signature,
+		CRSSignature: meta.CRSSignature,
+	}
+	return block, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreCrypto "github.com/portto/tangerine-consensus/core/crypto"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+)
+
+// Tests that both DexconMeta encodings decode to the core block delivered.
+func TestDexconMetaEncoding(t *testing.T) {
+	block := &coreTypes.Block{
+		ProposerID:  coreTypes.NodeID{Hash: coreCommon.Hash{0x01}},
+		ParentHash:  coreCommon.Hash{0x02},
+		Hash:        coreCommon.Hash{0x03},
+		Position:    coreTypes.Position{Round: 2, Height: 100},
+		Timestamp:   time.Unix(1550000000, 123456789).UTC(),
+		Payload:     []byte{0x04},
+		PayloadHash: coreCommon.Hash{0x05},
+		Witness:     coreTypes.Witness{Height: 99, Data: []byte{0x06}},
+		Randomness:  bytes.Repeat([]byte{0x07}, 96),
+		Signature: coreCrypto.Signature{
+			Type: "ecdsa", Signature: bytes.Repeat([]byte{0x08}, 65)},
+		CRSSignature: coreCrypto.Signature{
+			Type: "bls", Signature: bytes.Repeat([]byte{0x09}, 96)},
+	}
+	header := &Header{
+		Number:     new(big.Int).SetUint64(block.Position.Height),
+		Round:      block.Position.Round,
+		Time:       uint64(block.Timestamp.UnixNano() / 1000000),
+		Randomness: block.Randomness,
+	}
+	full, err := EncodeDexconMeta(block, false)
+	if err != nil {
+		t.Fatalf("failed to encode full dexcon meta: %v", err)
+	}
+	compact, err := EncodeDexconMeta(block, true)
+	if err != nil {
+		t.Fatalf("failed to encode compact dexcon meta: %v", err)
+	}
+	if IsCompactDexconMeta(full) || !IsCompactDexconMeta(compact) {
+		t.Fatalf("encodings not told apart")
+	}
+	if len(compact) >= len(full) {
+		t.Errorf("compact encoding not smaller: %d >= %d", len(compact), len(full))
+	}
+	for _, meta := range [][]byte{full, compact} {
+		header.DexconMeta = meta
+		have, err := header.CoreBlock()
+		if err != nil {
+			t.Fatalf("failed to decode dexcon meta: %v", err)
+		}
+		enc, err := EncodeDexconMeta(have, false)
+		if err != nil {
+			t.Fatalf("failed to encode decoded core block: %v", err)
+		}
+		if !bytes.Equal(enc, full) {
+			t.Errorf("core block mismatch: have %+v, want %+v", have, block)
+		}
+	}
+
+	header.DexconMeta = append([]byte{compactDexconMetaPrefix}, 0xff)
+	if _, err := header.CoreBlock(); err == nil {
+		t.Errorf("invalid compact dexcon meta decoded")
+	}
+}
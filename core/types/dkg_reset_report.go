@@ -0,0 +1,34 @@
+package types
+
+import (
+	"github.com/portto/go-tangerine/common"
+)
+
+// DKGResetReport collects the evidence of a DKG attempt that ended with a
+// reset, as observed from the governance state before the reset.
+type DKGResetReport struct {
+	Round uint64 `json:"round"` // DKG round being reset
+	Reset uint64 `json:"reset"` // Reset count of the failed attempt
+
+	BlockNumber uint64      `json:"blockNumber"` // Block including the reset
+	BlockHash   common.Hash `json:"blockHash"`
+	BlockTime   uint64      `json:"blockTime"`
+	GeneratedAt int64       `json:"generatedAt"` // Local unix time of the report
+
+	NotarySetSize uint64 `json:"notarySetSize"`
+	Threshold     uint64 `json:"threshold"`
+
+	MasterPublicKeys uint64   `json:"masterPublicKeys"`
+	MissingMPKs      []string `json:"missingMPKs"` // Notary nodes without a master public key
+
+	MPKReadys         uint64   `json:"mpkReadys"`
+	MissingMPKReadys  []string `json:"missingMPKReadys"`
+	Finalizeds        uint64   `json:"finalizeds"`
+	MissingFinalizeds []string `json:"missingFinalizeds"`
+	Successes         uint64   `json:"successes"`
+	MissingSuccesses  []string `json:"missingSuccesses"`
+
+	Complaints     uint64            `json:"complaints"`
+	NackComplaints map[string]uint64 `json:"nackComplaints"` // Nack complaints received by each node
+	Disqualified   []string          `json:"disqualified"`   // Nodes disqualified by complaints
+}
@@ -0,0 +1,22 @@
+package types
+
+import (
+	"github.com/portto/go-tangerine/common"
+)
+
+// ExtendedReceipt is the execution report of a transaction beyond its
+// receipt, stored apart when extended receipts are enabled.
+type ExtendedReceipt struct {
+	TxHash          common.Hash
+	GasRefund       uint64           // Gas refunded by the refund counter
+	TouchedAccounts []common.Address // Accounts touched, sorted
+}
+
+// Extended returns the extended receipt of the transaction of r.
+func (r *Receipt) Extended() *ExtendedReceipt {
+	return &ExtendedReceipt{
+		TxHash:          r.TxHash,
+		GasRefund:       r.GasRefund,
+		TouchedAccounts: r.TouchedAccounts,
+	}
+}
@@ -0,0 +1,22 @@
+package types
+
+// Peer events recorded in the peer history.
+const (
+	PeerConnected    = "connect"
+	PeerDisconnected = "disconnect"
+)
+
+// PeerEvent records a peer connecting or disconnecting, along with the
+// state of the peer at that time.
+type PeerEvent struct {
+	Time       int64  `json:"time"` // Local unix time in milliseconds
+	Event      string `json:"event"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	RemoteAddr string `json:"remoteAddr"`
+	Inbound    bool   `json:"inbound"`
+	Version    int    `json:"version"` // Negotiated protocol version
+	Notary     bool   `json:"notary"`  // Whether the peer was in a known notary set
+	Round      uint64 `json:"round"`   // Round of the local chain head
+	Reason     string `json:"reason,omitempty"`
+}
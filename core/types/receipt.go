@@ -55,6 +55,10 @@ type Receipt struct {
 	TxHash          common.Hash    `json:"transactionHash" gencodec:"required"`
 	ContractAddress common.Address `json:"contractAddress"`
 	GasUsed         uint64         `json:"gasUsed" gencodec:"required"`
+
+	// Extended fields, stored apart from the receipt if at all
+	GasRefund       uint64           `json:"-"`
+	TouchedAccounts []common.Address `json:"-"`
 }
 
 type receiptMarshaling struct {
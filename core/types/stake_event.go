@@ -0,0 +1,41 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/portto/go-tangerine/common"
+)
+
+// StakeEventKind is the kind of change to the stake of a node.
+type StakeEventKind uint8
+
+const (
+	StakeEventStaked    StakeEventKind = iota // Stake deposited
+	StakeEventUnstaked                        // Stake unlocked for withdrawal
+	StakeEventWithdrawn                       // Unlocked stake withdrawn
+)
+
+// String implements fmt.Stringer.
+func (k StakeEventKind) String() string {
+	switch k {
+	case StakeEventStaked:
+		return "staked"
+	case StakeEventUnstaked:
+		return "unstaked"
+	case StakeEventWithdrawn:
+		return "withdrawn"
+	default:
+		return "unknown"
+	}
+}
+
+// StakeEvent is a change to the stake of a node owner emitted by the
+// governance contract.
+type StakeEvent struct {
+	Kind        StakeEventKind
+	Amount      *big.Int
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxHash      common.Hash
+	LogIndex    uint
+}
@@ -27,4 +27,7 @@ var (
 	ErrInsufficientBalance      = errors.New("insufficient balance for transfer")
 	ErrContractAddressCollision = errors.New("contract address collision")
 	ErrNoCompatibleInterpreter  = errors.New("no compatible interpreter")
+	ErrEWASMEngineUnavailable   = errors.New("no ewasm engine available")
+	ErrInvalidEWASMDeployment   = errors.New("deployed code does not match init code VM")
+	ErrExecutionReverted        = errors.New("execution reverted")
 )
@@ -0,0 +1,95 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"sync"
+)
+
+// ewasmMagic is the preamble of every WebAssembly binary module.
+var ewasmMagic = []byte{0x00, 0x61, 0x73, 0x6d}
+
+// EWASMEngine creates an interpreter capable of executing ewasm modules.
+type EWASMEngine func(evm *EVM, cfg Config) Interpreter
+
+var (
+	ewasmEnginesMu sync.RWMutex
+	ewasmEngines   = make(map[string]EWASMEngine)
+)
+
+// RegisterEWASMEngine makes an ewasm engine available under the given name.
+// The engine is selected with Config.EWASMInterpreter, the empty name being
+// the default engine.
+func RegisterEWASMEngine(name string, engine EWASMEngine) {
+	ewasmEnginesMu.Lock()
+	defer ewasmEnginesMu.Unlock()
+	ewasmEngines[name] = engine
+}
+
+// HasEWASMEngine returns whether an ewasm engine is registered under name.
+func HasEWASMEngine(name string) bool {
+	return lookupEWASMEngine(name) != nil
+}
+
+func lookupEWASMEngine(name string) EWASMEngine {
+	ewasmEnginesMu.RLock()
+	defer ewasmEnginesMu.RUnlock()
+	return ewasmEngines[name]
+}
+
+// isEWASMCode returns whether code is an ewasm module.
+func isEWASMCode(code []byte) bool {
+	return bytes.HasPrefix(code, ewasmMagic)
+}
+
+// EWASMInterpreter dispatches ewasm modules to the configured engine. It is
+// placed in front of the EVM interpreter once ewasm is activated, so calls
+// between EVM and ewasm contracts are routed by the callee's code.
+type EWASMInterpreter struct {
+	engine Interpreter
+}
+
+// NewEWASMInterpreter returns a new instance of the ewasm interpreter using
+// the engine named by cfg.EWASMInterpreter. Without a registered engine,
+// every ewasm execution fails with ErrEWASMEngineUnavailable.
+func NewEWASMInterpreter(evm *EVM, cfg Config) *EWASMInterpreter {
+	in := &EWASMInterpreter{}
+	if engine := lookupEWASMEngine(cfg.EWASMInterpreter); engine != nil {
+		in.engine = engine(evm, cfg)
+	}
+	return in
+}
+
+// Run executes the ewasm module of the contract with the configured engine.
+func (in *EWASMInterpreter) Run(contract *Contract, input []byte, readOnly bool) ([]byte, error) {
+	if in.engine == nil {
+		return nil, ErrEWASMEngineUnavailable
+	}
+	return in.engine.Run(contract, input, readOnly)
+}
+
+// CanRun tells if the contract, passed as an argument, is an ewasm module.
+func (in *EWASMInterpreter) CanRun(code []byte) bool {
+	return isEWASMCode(code)
+}
+
+// isEWASMActive returns whether ewasm execution is enabled for the current
+// block, either by the block-based or the round-based fork.
+func (evm *EVM) isEWASMActive() bool {
+	return evm.chainConfig.IsEWASM(evm.BlockNumber) || evm.chainConfig.IsEWASMRound(evm.Round)
+}
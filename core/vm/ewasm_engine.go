@@ -0,0 +1,1105 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+	"math/bits"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/params"
+)
+
+const (
+	wasmPageSize      = 65536
+	wasmMaxPages      = 256  // Maximum memory of a contract, 16 MiB
+	wasmMaxLocals     = 1024 // Maximum locals of a function
+	wasmMaxBlockDepth = 1024 // Maximum nesting of the blocks of a function
+	wasmMaxFrames     = 1024 // Maximum depth of function calls within a contract
+	wasmMaxStack      = 1 << 16
+
+	// Gas charged by the built-in engine on top of the host functions: per
+	// instruction executed, and per memory page, as much as EVM memory of the
+	// same size before the quadratic term.
+	wasmInstructionGas = 1
+	wasmPageGas        = wasmPageSize / 32 * params.MemoryGas
+)
+
+var (
+	errWasmUnreachable    = errors.New("ewasm: unreachable executed")
+	errWasmOpcode         = errors.New("ewasm: unsupported opcode")
+	errWasmStack          = errors.New("ewasm: stack overflow or underflow")
+	errWasmFrames         = errors.New("ewasm: call stack exhausted")
+	errWasmMemory         = errors.New("ewasm: out of bounds memory access")
+	errWasmDivideByZero   = errors.New("ewasm: integer divide by zero")
+	errWasmDivideOverflow = errors.New("ewasm: integer overflow")
+	errWasmValue          = errors.New("ewasm: value does not fit 128 bits")
+
+	// errWasmFinish stops the execution of a contract successfully. It is
+	// returned by the finish host function and never leaves the engine.
+	errWasmFinish = errors.New("ewasm: finished")
+)
+
+func init() {
+	RegisterEWASMEngine("", newWasmInterpreter)
+}
+
+// wasmInterpreter is the built-in ewasm engine, selected by the empty engine
+// name. It executes the integer subset of the WebAssembly MVP, and provides
+// contracts the ethereum environment interface of the host functions below.
+//
+// Gas is charged by the engine per instruction rather than by metering code
+// injected at deployment.
+type wasmInterpreter struct {
+	evm      *EVM
+	cfg      Config
+	readOnly bool // Whether to throw on state modifying host functions
+}
+
+func newWasmInterpreter(evm *EVM, cfg Config) Interpreter {
+	return &wasmInterpreter{evm: evm, cfg: cfg}
+}
+
+// CanRun tells if the code is an ewasm module.
+func (in *wasmInterpreter) CanRun(code []byte) bool {
+	return isEWASMCode(code)
+}
+
+// Run decodes the ewasm module of the contract and executes its main
+// function. A module failing to decode fails like an invalid opcode.
+func (in *wasmInterpreter) Run(contract *Contract, input []byte, readOnly bool) (ret []byte, err error) {
+	in.evm.depth++
+	defer func() { in.evm.depth-- }()
+
+	// Like the EVM interpreter, the flag stays set for the child calls.
+	if readOnly && !in.readOnly {
+		in.readOnly = true
+		defer func() { in.readOnly = false }()
+	}
+	mod, err := decodeWasmModule(contract.Code)
+	if err != nil {
+		return nil, err
+	}
+	inst := &wasmInstance{
+		in:       in,
+		evm:      in.evm,
+		contract: contract,
+		input:    input,
+		mod:      mod,
+		globals:  make([]uint64, len(mod.globals)),
+	}
+	if _, err := inst.grow(mod.memMin); err != nil {
+		return nil, err
+	}
+	for _, d := range mod.data {
+		copy(inst.mem[d.offset:], d.data)
+	}
+	for i, g := range mod.globals {
+		inst.globals[i] = g.init
+	}
+	switch err := inst.call(mod.main, 0); err {
+	case nil:
+		return nil, nil
+	case errWasmFinish:
+		return inst.output, nil
+	default:
+		return inst.output, err
+	}
+}
+
+// static returns whether the state must not be modified, either because of
+// a static call of an ewasm contract or of an EVM contract.
+func (in *wasmInterpreter) static() bool {
+	if in.readOnly {
+		return true
+	}
+	for _, other := range in.evm.interpreters {
+		if evm, ok := other.(*EVMInterpreter); ok && evm.readOnly {
+			return true
+		}
+	}
+	return false
+}
+
+// wasmLabel is the target of the branches out of a block.
+type wasmLabel struct {
+	pc     int  // Continuation of the branches
+	height int  // Operand stack height at the start of the block
+	arity  int  // Number of values the block leaves on the stack
+	loop   bool // Whether branches restart the block
+}
+
+// wasmInstance is an execution of an ewasm module.
+type wasmInstance struct {
+	in       *wasmInterpreter
+	evm      *EVM
+	contract *Contract
+	input    []byte
+	mod      *wasmModule
+
+	mem        []byte
+	globals    []uint64
+	stack      []uint64
+	base       int    // Stack height at the start of the function executed
+	returnData []byte // Output of the last call to another contract
+	output     []byte // Output set by finish or revert
+}
+
+func (inst *wasmInstance) useGas(gas uint64) error {
+	if !inst.contract.UseGas(gas) {
+		return ErrOutOfGas
+	}
+	return nil
+}
+
+func (inst *wasmInstance) push(v uint64) error {
+	if len(inst.stack) >= wasmMaxStack {
+		return errWasmStack
+	}
+	inst.stack = append(inst.stack, v)
+	return nil
+}
+
+func (inst *wasmInstance) pop() (uint64, error) {
+	if len(inst.stack) <= inst.base {
+		return 0, errWasmStack
+	}
+	v := inst.stack[len(inst.stack)-1]
+	inst.stack = inst.stack[:len(inst.stack)-1]
+	return v, nil
+}
+
+// popN pops n values, returned in the order they were pushed.
+func (inst *wasmInstance) popN(n int) ([]uint64, error) {
+	if len(inst.stack)-inst.base < n {
+		return nil, errWasmStack
+	}
+	vs := make([]uint64, n)
+	copy(vs, inst.stack[len(inst.stack)-n:])
+	inst.stack = inst.stack[:len(inst.stack)-n]
+	return vs, nil
+}
+
+// grow adds pages to the memory, returning the previous size in pages or -1
+// if the memory cannot grow that much.
+func (inst *wasmInstance) grow(pages uint32) (int64, error) {
+	size := uint32(len(inst.mem) / wasmPageSize)
+	if uint64(size)+uint64(pages) > uint64(inst.mod.memMax) {
+		return -1, nil
+	}
+	if err := inst.useGas(uint64(pages) * wasmPageGas); err != nil {
+		return 0, err
+	}
+	inst.mem = append(inst.mem, make([]byte, int(pages)*wasmPageSize)...)
+	return int64(size), nil
+}
+
+// memory returns size bytes of memory at offset.
+func (inst *wasmInstance) memory(offset, size uint64) ([]byte, error) {
+	if offset > uint64(len(inst.mem)) || size > uint64(len(inst.mem))-offset {
+		return nil, errWasmMemory
+	}
+	return inst.mem[offset : offset+size], nil
+}
+
+// unwind leaves the arity values on top of the stack at height.
+func (inst *wasmInstance) unwind(height, arity int) error {
+	if len(inst.stack) < height+arity {
+		return errWasmStack
+	}
+	copy(inst.stack[height:], inst.stack[len(inst.stack)-arity:])
+	inst.stack = inst.stack[:height+arity]
+	return nil
+}
+
+// call calls a host or module function with the arguments on the stack.
+func (inst *wasmInstance) call(index uint32, depth int) error {
+	if index < uint32(len(inst.mod.imports)) {
+		host := inst.mod.imports[index]
+		args, err := inst.popN(len(host.typ.params))
+		if err != nil {
+			return err
+		}
+		result, err := host.fn(inst, args)
+		if err != nil {
+			return err
+		}
+		if len(host.typ.results) > 0 {
+			return inst.push(result)
+		}
+		return nil
+	}
+	if depth >= wasmMaxFrames {
+		return errWasmFrames
+	}
+	f := inst.mod.funcs[index-uint32(len(inst.mod.imports))]
+	params, err := inst.popN(len(f.typ.params))
+	if err != nil {
+		return err
+	}
+	locals := make([]uint64, len(f.typ.params)+len(f.locals))
+	copy(locals, params)
+
+	// The function cannot pop the operands of its caller.
+	base := inst.base
+	inst.base = len(inst.stack)
+	err = inst.exec(f, locals, depth)
+	inst.base = base
+	return err
+}
+
+// exec executes the code of a function.
+func (inst *wasmInstance) exec(f *wasmFunc, locals []uint64, depth int) error {
+	var (
+		code   = f.code
+		r      = &wasmReader{buf: code}
+		labels = []wasmLabel{{pc: len(code), height: len(inst.stack), arity: len(f.typ.results)}}
+	)
+	// branch continues after the nth enclosing block, or at the start of
+	// the nth enclosing loop.
+	branch := func(n uint32) error {
+		if n >= uint32(len(labels)) {
+			return errWasmStack
+		}
+		l := labels[len(labels)-1-int(n)]
+		if l.loop {
+			if err := inst.unwind(l.height, 0); err != nil {
+				return err
+			}
+			labels = labels[:len(labels)-int(n)]
+		} else {
+			if err := inst.unwind(l.height, l.arity); err != nil {
+				return err
+			}
+			labels = labels[:len(labels)-1-int(n)]
+		}
+		r.pos = l.pc
+		return nil
+	}
+	// Immediates were checked when decoding the module.
+	u32 := func() uint32 {
+		v, _ := r.u32()
+		return v
+	}
+	for len(labels) > 0 {
+		if err := inst.useGas(wasmInstructionGas); err != nil {
+			return err
+		}
+		var (
+			pc     = r.pos
+			op, _  = r.byte()
+			result uint64
+			push   bool // Whether the instruction pushes result
+			err    error
+		)
+		switch op {
+		case wasmUnreachable:
+			return errWasmUnreachable
+		case wasmNop:
+
+		case wasmBlock, wasmLoop, wasmIf:
+			bt, _ := r.byte()
+			l := wasmLabel{pc: f.ends[pc] + 1, height: len(inst.stack)}
+			if bt != wasmBlockEmpty {
+				l.arity = 1
+			}
+			if op == wasmLoop {
+				l.pc, l.loop = r.pos, true
+			}
+			if op == wasmIf {
+				var c uint64
+				if c, err = inst.pop(); err != nil {
+					return err
+				}
+				l.height--
+				if c == 0 {
+					if els, ok := f.elses[pc]; ok {
+						r.pos = els + 1
+					} else {
+						r.pos = f.ends[pc]
+					}
+				}
+			}
+			labels = append(labels, l)
+		case wasmElse:
+			// The then branch is done.
+			r.pos = f.ends[pc]
+		case wasmEnd:
+			l := labels[len(labels)-1]
+			err = inst.unwind(l.height, l.arity)
+			labels = labels[:len(labels)-1]
+		case wasmBr:
+			err = branch(u32())
+		case wasmBrIf:
+			var (
+				n = u32()
+				c uint64
+			)
+			if c, err = inst.pop(); err == nil && c != 0 {
+				err = branch(n)
+			}
+		case wasmBrTable:
+			var (
+				targets = f.brTables[pc]
+				i       uint64
+			)
+			if i, err = inst.pop(); err == nil {
+				if i >= uint64(len(targets)-1) {
+					i = uint64(len(targets) - 1)
+				}
+				err = branch(targets[i])
+			}
+		case wasmReturn:
+			err = branch(uint32(len(labels) - 1))
+		case wasmCall:
+			err = inst.call(u32(), depth+1)
+
+		case wasmDrop:
+			_, err = inst.pop()
+		case wasmSelect:
+			var vs []uint64
+			if vs, err = inst.popN(3); err == nil {
+				result, push = vs[1], true
+				if vs[2] != 0 {
+					result = vs[0]
+				}
+			}
+
+		case wasmLocalGet:
+			result, push = locals[u32()], true
+		case wasmLocalSet:
+			n := u32()
+			locals[n], err = inst.pop()
+		case wasmLocalTee:
+			n := u32()
+			if locals[n], err = inst.pop(); err == nil {
+				result, push = locals[n], true
+			}
+		case wasmGlobalGet:
+			result, push = inst.globals[u32()], true
+		case wasmGlobalSet:
+			n := u32()
+			inst.globals[n], err = inst.pop()
+
+		case wasmMemorySize:
+			r.byte()
+			result, push = uint64(len(inst.mem)/wasmPageSize), true
+		case wasmMemoryGrow:
+			r.byte()
+			var (
+				pages uint64
+				size  int64
+			)
+			if pages, err = inst.pop(); err == nil {
+				if size, err = inst.grow(uint32(pages)); err == nil {
+					result, push = uint64(uint32(size)), true
+				}
+			}
+
+		case wasmI32Const:
+			v, _ := r.sleb(32)
+			result, push = uint64(uint32(v)), true
+		case wasmI64Const:
+			v, _ := r.sleb(64)
+			result, push = uint64(v), true
+
+		case wasmI32WrapI64, wasmI64ExtendI32U:
+			var a uint64
+			if a, err = inst.pop(); err == nil {
+				result, push = uint64(uint32(a)), true
+			}
+		case wasmI64ExtendI32S:
+			var a uint64
+			if a, err = inst.pop(); err == nil {
+				result, push = uint64(int64(int32(a))), true
+			}
+
+		default:
+			switch {
+			case isWasmMemoryOp(op):
+				u32() // Alignment hint
+				offset := uint64(u32())
+				err = inst.memoryOp(op, offset)
+			case op == wasmI32Eqz, op == wasmI64Eqz:
+				var a uint64
+				if a, err = inst.pop(); err == nil {
+					if op == wasmI32Eqz {
+						a = uint64(uint32(a))
+					}
+					result, push = wasmBool(a == 0), true
+				}
+			case op > wasmI32Eqz && op <= wasmI32GeU:
+				var vs []uint64
+				if vs, err = inst.popN(2); err == nil {
+					result, push = wasmBool(compare32(op, uint32(vs[0]), uint32(vs[1]))), true
+				}
+			case op > wasmI64Eqz && op <= wasmI64GeU:
+				var vs []uint64
+				if vs, err = inst.popN(2); err == nil {
+					result, push = wasmBool(compare64(op, vs[0], vs[1])), true
+				}
+			case op >= wasmI32Clz && op <= wasmI32Popcnt:
+				var a uint64
+				if a, err = inst.pop(); err == nil {
+					result, push = uint64(unary32(op, uint32(a))), true
+				}
+			case op > wasmI32Popcnt && op <= wasmI32Rotr:
+				var (
+					vs []uint64
+					c  uint32
+				)
+				if vs, err = inst.popN(2); err == nil {
+					if c, err = arith32(op, uint32(vs[0]), uint32(vs[1])); err == nil {
+						result, push = uint64(c), true
+					}
+				}
+			case op >= wasmI64Clz && op <= wasmI64Popcnt:
+				var a uint64
+				if a, err = inst.pop(); err == nil {
+					result, push = unary64(op, a), true
+				}
+			case op > wasmI64Popcnt && op <= wasmI64Rotr:
+				var vs []uint64
+				if vs, err = inst.popN(2); err == nil {
+					result, err = arith64(op, vs[0], vs[1])
+					push = err == nil
+				}
+			default:
+				// Modules with other opcodes fail to decode, but the engine
+				// must never skip an instruction it does not know.
+				return errWasmOpcode
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if push {
+			if err := inst.push(result); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// memoryOp executes a load or a store at offset from the address on the
+// stack.
+func (inst *wasmInstance) memoryOp(op byte, offset uint64) error {
+	var value uint64
+	if op >= wasmI32Store {
+		v, err := inst.pop()
+		if err != nil {
+			return err
+		}
+		value = v
+	}
+	base, err := inst.pop()
+	if err != nil {
+		return err
+	}
+	addr := uint64(uint32(base)) + offset
+
+	var size uint64
+	switch op {
+	case wasmI32Load8S, wasmI32Load8U, wasmI64Load8S, wasmI64Load8U, wasmI32Store8, wasmI64Store8:
+		size = 1
+	case wasmI32Load16S, wasmI32Load16U, wasmI64Load16S, wasmI64Load16U, wasmI32Store16, wasmI64Store16:
+		size = 2
+	case wasmI32Load, wasmI64Load32S, wasmI64Load32U, wasmI32Store, wasmI64Store32:
+		size = 4
+	default:
+		size = 8
+	}
+	mem, err := inst.memory(addr, size)
+	if err != nil {
+		return err
+	}
+	switch op {
+	case wasmI32Load, wasmI64Load32U:
+		return inst.push(uint64(binary.LittleEndian.Uint32(mem)))
+	case wasmI64Load:
+		return inst.push(binary.LittleEndian.Uint64(mem))
+	case wasmI32Load8S:
+		return inst.push(uint64(uint32(int8(mem[0]))))
+	case wasmI32Load8U, wasmI64Load8U:
+		return inst.push(uint64(mem[0]))
+	case wasmI32Load16S:
+		return inst.push(uint64(uint32(int16(binary.LittleEndian.Uint16(mem)))))
+	case wasmI32Load16U, wasmI64Load16U:
+		return inst.push(uint64(binary.LittleEndian.Uint16(mem)))
+	case wasmI64Load8S:
+		return inst.push(uint64(int8(mem[0])))
+	case wasmI64Load16S:
+		return inst.push(uint64(int16(binary.LittleEndian.Uint16(mem))))
+	case wasmI64Load32S:
+		return inst.push(uint64(int32(binary.LittleEndian.Uint32(mem))))
+	case wasmI32Store, wasmI64Store32:
+		binary.LittleEndian.PutUint32(mem, uint32(value))
+	case wasmI64Store:
+		binary.LittleEndian.PutUint64(mem, value)
+	case wasmI32Store8, wasmI64Store8:
+		mem[0] = byte(value)
+	case wasmI32Store16, wasmI64Store16:
+		binary.LittleEndian.PutUint16(mem, uint16(value))
+	}
+	return nil
+}
+
+func wasmBool(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// compare32 executes the i32 comparison a op b, ordered as eq, ne, lt_s,
+// lt_u, gt_s, gt_u, le_s, le_u, ge_s and ge_u.
+func compare32(op byte, a, b uint32) bool {
+	switch op - wasmI32Eqz {
+	case 1:
+		return a == b
+	case 2:
+		return a != b
+	case 3:
+		return int32(a) < int32(b)
+	case 4:
+		return a < b
+	case 5:
+		return int32(a) > int32(b)
+	case 6:
+		return a > b
+	case 7:
+		return int32(a) <= int32(b)
+	case 8:
+		return a <= b
+	case 9:
+		return int32(a) >= int32(b)
+	default:
+		return a >= b
+	}
+}
+
+// compare64 executes the i64 comparison a op b, ordered like the i32 ones.
+func compare64(op byte, a, b uint64) bool {
+	switch op - wasmI64Eqz {
+	case 1:
+		return a == b
+	case 2:
+		return a != b
+	case 3:
+		return int64(a) < int64(b)
+	case 4:
+		return a < b
+	case 5:
+		return int64(a) > int64(b)
+	case 6:
+		return a > b
+	case 7:
+		return int64(a) <= int64(b)
+	case 8:
+		return a <= b
+	case 9:
+		return int64(a) >= int64(b)
+	default:
+		return a >= b
+	}
+}
+
+// unary32 executes the i32 clz, ctz or popcnt instruction.
+func unary32(op byte, a uint32) uint32 {
+	switch op {
+	case wasmI32Clz:
+		return uint32(bits.LeadingZeros32(a))
+	case wasmI32Ctz:
+		return uint32(bits.TrailingZeros32(a))
+	default:
+		return uint32(bits.OnesCount32(a))
+	}
+}
+
+// unary64 executes the i64 clz, ctz or popcnt instruction.
+func unary64(op byte, a uint64) uint64 {
+	switch op {
+	case wasmI64Clz:
+		return uint64(bits.LeadingZeros64(a))
+	case wasmI64Ctz:
+		return uint64(bits.TrailingZeros64(a))
+	default:
+		return uint64(bits.OnesCount64(a))
+	}
+}
+
+// arith32 executes the i32 arithmetic or bitwise instruction a op b, ordered
+// as add, sub, mul, div_s, div_u, rem_s, rem_u, and, or, xor, shl, shr_s,
+// shr_u, rotl and rotr.
+func arith32(op byte, a, b uint32) (uint32, error) {
+	switch op - wasmI32Popcnt {
+	case 1:
+		return a + b, nil
+	case 2:
+		return a - b, nil
+	case 3:
+		return a * b, nil
+	case 4:
+		if b == 0 {
+			return 0, errWasmDivideByZero
+		}
+		if int32(a) == math.MinInt32 && int32(b) == -1 {
+			return 0, errWasmDivideOverflow
+		}
+		return uint32(int32(a) / int32(b)), nil
+	case 5:
+		if b == 0 {
+			return 0, errWasmDivideByZero
+		}
+		return a / b, nil
+	case 6:
+		if b == 0 {
+			return 0, errWasmDivideByZero
+		}
+		if int32(b) == -1 {
+			return 0, nil
+		}
+		return uint32(int32(a) % int32(b)), nil
+	case 7:
+		if b == 0 {
+			return 0, errWasmDivideByZero
+		}
+		return a % b, nil
+	case 8:
+		return a & b, nil
+	case 9:
+		return a | b, nil
+	case 10:
+		return a ^ b, nil
+	case 11:
+		return a << (b & 31), nil
+	case 12:
+		return uint32(int32(a) >> (b & 31)), nil
+	case 13:
+		return a >> (b & 31), nil
+	case 14:
+		return bits.RotateLeft32(a, int(b&31)), nil
+	default:
+		return bits.RotateLeft32(a, -int(b&31)), nil
+	}
+}
+
+// arith64 executes the i64 arithmetic or bitwise instruction a op b, ordered
+// like the i32 ones.
+func arith64(op byte, a, b uint64) (uint64, error) {
+	switch op - wasmI64Popcnt {
+	case 1:
+		return a + b, nil
+	case 2:
+		return a - b, nil
+	case 3:
+		return a * b, nil
+	case 4:
+		if b == 0 {
+			return 0, errWasmDivideByZero
+		}
+		if int64(a) == math.MinInt64 && int64(b) == -1 {
+			return 0, errWasmDivideOverflow
+		}
+		return uint64(int64(a) / int64(b)), nil
+	case 5:
+		if b == 0 {
+			return 0, errWasmDivideByZero
+		}
+		return a / b, nil
+	case 6:
+		if b == 0 {
+			return 0, errWasmDivideByZero
+		}
+		if int64(b) == -1 {
+			return 0, nil
+		}
+		return uint64(int64(a) % int64(b)), nil
+	case 7:
+		if b == 0 {
+			return 0, errWasmDivideByZero
+		}
+		return a % b, nil
+	case 8:
+		return a & b, nil
+	case 9:
+		return a | b, nil
+	case 10:
+		return a ^ b, nil
+	case 11:
+		return a << (b & 63), nil
+	case 12:
+		return uint64(int64(a) >> (b & 63)), nil
+	case 13:
+		return a >> (b & 63), nil
+	case 14:
+		return bits.RotateLeft64(a, int(b&63)), nil
+	default:
+		return bits.RotateLeft64(a, -int(b&63)), nil
+	}
+}
+
+// ewasmHostFunc is a function of the ethereum environment interface.
+type ewasmHostFunc struct {
+	typ wasmFuncType
+	fn  func(inst *wasmInstance, args []uint64) (uint64, error)
+}
+
+func hostFunc(params, results string, fn func(inst *wasmInstance, args []uint64) (uint64, error)) *ewasmHostFunc {
+	types := func(s string) []byte {
+		t := make([]byte, len(s))
+		for i := range s {
+			if s[i] == 'i' {
+				t[i] = wasmI32
+			} else {
+				t[i] = wasmI64
+			}
+		}
+		return t
+	}
+	return &ewasmHostFunc{typ: wasmFuncType{params: types(params), results: types(results)}, fn: fn}
+}
+
+// ewasmHostFuncs are the host functions contracts import from the ethereum
+// namespace, with their i32 and i64 parameters and results. Memory offsets
+// and lengths are i32, amounts of wei are 128 bits little endian integers in
+// memory, and the host functions are charged like the equivalent EVM
+// instructions.
+var ewasmHostFuncs map[string]*ewasmHostFunc
+
+func init() {
+	ewasmHostFuncs = map[string]*ewasmHostFunc{
+		"useGas": hostFunc("I", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			return 0, inst.useGas(args[0])
+		}),
+		"getGasLeft": hostFunc("", "I", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			if err := inst.useGas(GasQuickStep); err != nil {
+				return 0, err
+			}
+			return inst.contract.Gas, nil
+		}),
+		"getAddress": hostFunc("i", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			return 0, inst.storeBytes(GasQuickStep, args[0], inst.contract.Address().Bytes())
+		}),
+		"getExternalBalance": hostFunc("ii", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			if err := inst.useGas(inst.evm.ChainConfig().GasTable(inst.evm.BlockNumber).Balance); err != nil {
+				return 0, err
+			}
+			mem, err := inst.memory(args[0], common.AddressLength)
+			if err != nil {
+				return 0, err
+			}
+			return 0, inst.storeU128(args[1], inst.evm.StateDB.GetBalance(common.BytesToAddress(mem)))
+		}),
+		"getBlockHash": hostFunc("Ii", "i", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			if err := inst.useGas(GasExtStep); err != nil {
+				return 0, err
+			}
+			current := inst.evm.BlockNumber.Uint64()
+			if args[0] >= current || current-args[0] > 256 {
+				return 1, nil
+			}
+			return 0, inst.storeBytes(0, args[1], inst.evm.GetHash(args[0]).Bytes())
+		}),
+		"getCallDataSize": hostFunc("", "i", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			return uint64(len(inst.input)), inst.useGas(GasQuickStep)
+		}),
+		"callDataCopy": hostFunc("iii", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			return 0, inst.copyData(args[0], inst.input, args[1], args[2])
+		}),
+		"getCaller": hostFunc("i", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			return 0, inst.storeBytes(GasQuickStep, args[0], inst.contract.Caller().Bytes())
+		}),
+		"getCallValue": hostFunc("i", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			if err := inst.useGas(GasQuickStep); err != nil {
+				return 0, err
+			}
+			return 0, inst.storeU128(args[0], inst.contract.Value())
+		}),
+		"getCodeSize": hostFunc("", "i", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			return uint64(len(inst.contract.Code)), inst.useGas(GasQuickStep)
+		}),
+		"codeCopy": hostFunc("iii", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			return 0, inst.copyData(args[0], inst.contract.Code, args[1], args[2])
+		}),
+		"getExternalCodeSize": hostFunc("i", "i", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			if err := inst.useGas(inst.evm.ChainConfig().GasTable(inst.evm.BlockNumber).ExtcodeSize); err != nil {
+				return 0, err
+			}
+			mem, err := inst.memory(args[0], common.AddressLength)
+			if err != nil {
+				return 0, err
+			}
+			return uint64(inst.evm.StateDB.GetCodeSize(common.BytesToAddress(mem))), nil
+		}),
+		"getBlockCoinbase": hostFunc("i", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			return 0, inst.storeBytes(GasQuickStep, args[0], inst.evm.Coinbase.Bytes())
+		}),
+		"getBlockGasLimit": hostFunc("", "I", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			return inst.evm.GasLimit, inst.useGas(GasQuickStep)
+		}),
+		"getBlockNumber": hostFunc("", "I", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			return inst.evm.BlockNumber.Uint64(), inst.useGas(GasQuickStep)
+		}),
+		"getBlockTimestamp": hostFunc("", "I", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			return inst.evm.Time.Uint64(), inst.useGas(GasQuickStep)
+		}),
+		"getTxGasPrice": hostFunc("i", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			if err := inst.useGas(GasQuickStep); err != nil {
+				return 0, err
+			}
+			return 0, inst.storeU128(args[0], inst.evm.GasPrice)
+		}),
+		"getTxOrigin": hostFunc("i", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			return 0, inst.storeBytes(GasQuickStep, args[0], inst.evm.Origin.Bytes())
+		}),
+		"storageStore": hostFunc("ii", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			if inst.in.static() {
+				return 0, errWriteProtection
+			}
+			keyMem, err := inst.memory(args[0], common.HashLength)
+			if err != nil {
+				return 0, err
+			}
+			valueMem, err := inst.memory(args[1], common.HashLength)
+			if err != nil {
+				return 0, err
+			}
+			var (
+				key     = common.BytesToHash(keyMem)
+				value   = common.BytesToHash(valueMem)
+				address = inst.contract.Address()
+				current = inst.evm.StateDB.GetState(address, key)
+			)
+			switch {
+			case current == (common.Hash{}) && value != (common.Hash{}):
+				err = inst.useGas(params.SstoreSetGas)
+			case current != (common.Hash{}) && value == (common.Hash{}):
+				if err = inst.useGas(params.SstoreClearGas); err == nil {
+					inst.evm.StateDB.AddRefund(params.SstoreRefundGas)
+				}
+			default:
+				err = inst.useGas(params.SstoreResetGas)
+			}
+			if err != nil {
+				return 0, err
+			}
+			inst.evm.StateDB.SetState(address, key, value)
+			return 0, nil
+		}),
+		"storageLoad": hostFunc("ii", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			if err := inst.useGas(inst.evm.ChainConfig().GasTable(inst.evm.BlockNumber).SLoad); err != nil {
+				return 0, err
+			}
+			mem, err := inst.memory(args[0], common.HashLength)
+			if err != nil {
+				return 0, err
+			}
+			value := inst.evm.StateDB.GetState(inst.contract.Address(), common.BytesToHash(mem))
+			return 0, inst.storeBytes(0, args[1], value.Bytes())
+		}),
+		"log": hostFunc("iiiiiii", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			if inst.in.static() {
+				return 0, errWriteProtection
+			}
+			count := args[2]
+			if count > 4 {
+				return 0, errWasmMemory
+			}
+			if err := inst.useGas(params.LogGas + count*params.LogTopicGas + args[1]*params.LogDataGas); err != nil {
+				return 0, err
+			}
+			topics := make([]common.Hash, count)
+			for i := range topics {
+				mem, err := inst.memory(args[3+i], common.HashLength)
+				if err != nil {
+					return 0, err
+				}
+				topics[i] = common.BytesToHash(mem)
+			}
+			data, err := inst.memory(args[0], args[1])
+			if err != nil {
+				return 0, err
+			}
+			inst.evm.StateDB.AddLog(&types.Log{
+				Address:     inst.contract.Address(),
+				Topics:      topics,
+				Data:        common.CopyBytes(data),
+				BlockNumber: inst.evm.BlockNumber.Uint64(),
+			})
+			return 0, nil
+		}),
+		"finish": hostFunc("ii", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			mem, err := inst.memory(args[0], args[1])
+			if err != nil {
+				return 0, err
+			}
+			inst.output = common.CopyBytes(mem)
+			return 0, errWasmFinish
+		}),
+		"revert": hostFunc("ii", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			mem, err := inst.memory(args[0], args[1])
+			if err != nil {
+				return 0, err
+			}
+			inst.output = common.CopyBytes(mem)
+			return 0, ErrExecutionReverted
+		}),
+		"getReturnDataSize": hostFunc("", "i", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			return uint64(len(inst.returnData)), inst.useGas(GasQuickStep)
+		}),
+		"returnDataCopy": hostFunc("iii", "", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			if args[1]+args[2] > uint64(len(inst.returnData)) {
+				return 0, errReturnDataOutOfBounds
+			}
+			return 0, inst.copyData(args[0], inst.returnData, args[1], args[2])
+		}),
+		"call": hostFunc("Iiiii", "i", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			value, err := inst.loadU128(args[2])
+			if err != nil {
+				return 0, err
+			}
+			return inst.callContract(args[0], args[1], value, args[3], args[4])
+		}),
+		"callStatic": hostFunc("Iiii", "i", func(inst *wasmInstance, args []uint64) (uint64, error) {
+			return inst.callContract(args[0], args[1], nil, args[2], args[3])
+		}),
+	}
+}
+
+// storeBytes charges gas and copies b to memory at offset.
+func (inst *wasmInstance) storeBytes(gas, offset uint64, b []byte) error {
+	if err := inst.useGas(gas); err != nil {
+		return err
+	}
+	mem, err := inst.memory(offset, uint64(len(b)))
+	if err != nil {
+		return err
+	}
+	copy(mem, b)
+	return nil
+}
+
+// copyData copies length bytes of data at offset to memory at dest. The
+// data is padded with zeros.
+func (inst *wasmInstance) copyData(dest uint64, data []byte, offset, length uint64) error {
+	if err := inst.useGas(GasFastestStep + toWordSize(length)*params.CopyGas); err != nil {
+		return err
+	}
+	mem, err := inst.memory(dest, length)
+	if err != nil {
+		return err
+	}
+	copy(mem, getData(data, offset, length))
+	return nil
+}
+
+// storeU128 stores value in memory at offset as a 128 bits integer.
+func (inst *wasmInstance) storeU128(offset uint64, value *big.Int) error {
+	if value.BitLen() > 128 {
+		return errWasmValue
+	}
+	mem, err := inst.memory(offset, 16)
+	if err != nil {
+		return err
+	}
+	b := common.LeftPadBytes(value.Bytes(), 16)
+	for i := range mem {
+		mem[i] = b[15-i]
+	}
+	return nil
+}
+
+// loadU128 loads the 128 bits integer in memory at offset.
+func (inst *wasmInstance) loadU128(offset uint64) (*big.Int, error) {
+	mem, err := inst.memory(offset, 16)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 16)
+	for i := range b {
+		b[i] = mem[15-i]
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// callContract calls the contract at the address in memory, statically if
+// value is nil, with at most gas of the gas left minus a 64th. It returns
+// 0 on success, 1 on failure and 2 on revert.
+func (inst *wasmInstance) callContract(gas, addrOffset uint64, value *big.Int, dataOffset, dataLength uint64) (uint64, error) {
+	addrMem, err := inst.memory(addrOffset, common.AddressLength)
+	if err != nil {
+		return 0, err
+	}
+	dataMem, err := inst.memory(dataOffset, dataLength)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		addr   = common.BytesToAddress(addrMem)
+		data   = common.CopyBytes(dataMem)
+		static = value == nil || inst.in.static()
+	)
+	cost := inst.evm.ChainConfig().GasTable(inst.evm.BlockNumber).Calls
+	if value != nil && value.Sign() > 0 {
+		if static {
+			return 0, errWriteProtection
+		}
+		cost += params.CallValueTransferGas
+		if inst.evm.StateDB.Empty(addr) {
+			cost += params.CallNewAccountGas
+		}
+	}
+	if err := inst.useGas(cost); err != nil {
+		return 0, err
+	}
+	if available := inst.contract.Gas - inst.contract.Gas/64; gas > available {
+		gas = available
+	}
+	if err := inst.useGas(gas); err != nil {
+		return 0, err
+	}
+
+	var (
+		ret      []byte
+		returned uint64
+	)
+	if static {
+		ret, returned, err = inst.evm.StaticCall(inst.contract, addr, data, gas)
+	} else {
+		if value.Sign() > 0 {
+			gas += params.CallStipend
+		}
+		ret, returned, err = inst.evm.Call(inst.contract, addr, data, gas, value)
+	}
+	inst.contract.Gas += returned
+	inst.returnData = ret
+
+	switch err {
+	case nil:
+		return 0, nil
+	case ErrExecutionReverted:
+		return 2, nil
+	default:
+		return 1, nil
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/portto/go-tangerine/common"
+)
+
+// testWasmFunc is a function of a test module, its types written like the
+// ones of the host functions.
+type testWasmFunc struct {
+	params, results, locals string
+	body                    [][]byte
+}
+
+func testWasmUleb(n uint64) []byte {
+	var b []byte
+	for {
+		c := byte(n & 0x7f)
+		n >>= 7
+		if n != 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func testWasmSleb(n int64) []byte {
+	var b []byte
+	for {
+		c := byte(n & 0x7f)
+		n >>= 7
+		if (n == 0 && c&0x40 == 0) || (n == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func testWasmVec(items [][]byte) []byte {
+	b := testWasmUleb(uint64(len(items)))
+	for _, item := range items {
+		b = append(b, item...)
+	}
+	return b
+}
+
+// testWasmBytes encodes a byte vector, like names and value types.
+func testWasmBytes(b []byte) []byte {
+	return append(testWasmUleb(uint64(len(b))), b...)
+}
+
+func testWasmTypes(s string) []byte {
+	return hostFunc(s, "", nil).typ.params
+}
+
+// buildWasmModule assembles a module importing the named host functions,
+// whose main function is the first of funcs, with a memory page starting
+// with data.
+func buildWasmModule(imports []string, funcs []testWasmFunc, data []byte) []byte {
+	var types, imps, decls, codes [][]byte
+	for i, name := range imports {
+		typ := ewasmHostFuncs[name].typ
+		types = append(types, append(append([]byte{0x60}, testWasmBytes(typ.params)...), testWasmBytes(typ.results)...))
+		imp := append(testWasmBytes([]byte("ethereum")), testWasmBytes([]byte(name))...)
+		imps = append(imps, append(imp, append([]byte{0x00}, testWasmUleb(uint64(i))...)...))
+	}
+	for i, f := range funcs {
+		types = append(types, append(append([]byte{0x60},
+			testWasmBytes(testWasmTypes(f.params))...), testWasmBytes(testWasmTypes(f.results))...))
+		decls = append(decls, testWasmUleb(uint64(len(imports)+i)))
+
+		var locals [][]byte
+		for _, t := range testWasmTypes(f.locals) {
+			locals = append(locals, []byte{1, t})
+		}
+		body := testWasmVec(locals)
+		for _, ins := range f.body {
+			body = append(body, ins...)
+		}
+		body = append(body, wasmEnd)
+		codes = append(codes, append(testWasmUleb(uint64(len(body))), body...))
+	}
+	section := func(id byte, items [][]byte) []byte {
+		content := testWasmVec(items)
+		return append(append([]byte{id}, testWasmUleb(uint64(len(content)))...), content...)
+	}
+	exports := [][]byte{
+		append(testWasmBytes([]byte("main")), append([]byte{0x00}, testWasmUleb(uint64(len(imports)))...)...),
+		append(testWasmBytes([]byte("memory")), 0x02, 0x00),
+	}
+	module := append(common.CopyBytes(ewasmMagic), 0x01, 0x00, 0x00, 0x00)
+	module = append(module, section(wasmSectionType, types)...)
+	module = append(module, section(wasmSectionImport, imps)...)
+	module = append(module, section(wasmSectionFunction, decls)...)
+	module = append(module, section(wasmSectionMemory, [][]byte{{0x00, 0x01}})...)
+	module = append(module, section(wasmSectionExport, exports)...)
+	module = append(module, section(wasmSectionCode, codes)...)
+	if data != nil {
+		segment := append([]byte{0x00, wasmI32Const, 0x00, wasmEnd}, testWasmBytes(data)...)
+		module = append(module, section(wasmSectionData, [][]byte{segment})...)
+	}
+	return module
+}
+
+func i32c(v int64) []byte {
+	return append([]byte{wasmI32Const}, testWasmSleb(v)...)
+}
+
+func i64c(v int64) []byte {
+	return append([]byte{wasmI64Const}, testWasmSleb(v)...)
+}
+
+func op(code byte, immediates ...byte) []byte {
+	return append([]byte{code}, immediates...)
+}
+
+// sumWasmModule returns the sum of 1 to 10, computed by a loop of a second
+// function, as a 32 bits little endian integer.
+func sumWasmModule() []byte {
+	return buildWasmModule([]string{"finish"}, []testWasmFunc{
+		{body: [][]byte{
+			i32c(0),
+			i32c(1), op(wasmIf, wasmI32), i32c(10), op(wasmElse), i32c(20), op(wasmEnd),
+			op(wasmCall, 2),
+			op(wasmI32Store, 2, 0),
+			i32c(0), i32c(4), op(wasmCall, 0),
+		}},
+		{params: "i", results: "i", locals: "i", body: [][]byte{
+			op(wasmBlock, wasmBlockEmpty), op(wasmLoop, wasmBlockEmpty),
+			op(wasmLocalGet, 0), op(wasmI32Eqz), op(wasmBrIf, 1),
+			op(wasmLocalGet, 1), op(wasmLocalGet, 0), op(0x6a), op(wasmLocalSet, 1), // i32.add
+			op(wasmLocalGet, 0), i32c(1), op(0x6b), op(wasmLocalSet, 0), // i32.sub
+			op(wasmBr, 0),
+			op(wasmEnd), op(wasmEnd),
+			op(wasmLocalGet, 1),
+		}},
+	}, nil)
+}
+
+func TestEWASMEngineExecution(t *testing.T) {
+	var (
+		caller = AccountRef(common.HexToAddress("0x1"))
+		wasm   = common.HexToAddress("0x1000")
+	)
+	evm := newEWASMTestEVM(t, 10, "")
+	evm.StateDB.SetCode(wasm, sumWasmModule())
+
+	ret, _, err := evm.Call(caller, wasm, nil, 100000, new(big.Int))
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if len(ret) != 4 || binary.LittleEndian.Uint32(ret) != 55 {
+		t.Errorf("output mismatch: have %x, want 55", ret)
+	}
+}
+
+func TestEWASMEngineStorage(t *testing.T) {
+	var (
+		caller = AccountRef(common.HexToAddress("0x1"))
+		wasm   = common.HexToAddress("0x1000")
+		key    = common.HexToHash("0x01")
+		value  = common.HexToHash("0x2a")
+	)
+	evm := newEWASMTestEVM(t, 10, "")
+	evm.StateDB.SetCode(wasm, buildWasmModule([]string{"storageStore", "storageLoad", "finish"}, []testWasmFunc{
+		{body: [][]byte{
+			i32c(0), i32c(32), op(wasmCall, 0),
+			i32c(0), i32c(64), op(wasmCall, 1),
+			i32c(64), i32c(32), op(wasmCall, 2),
+		}},
+	}, append(key.Bytes(), value.Bytes()...)))
+
+	ret, _, err := evm.Call(caller, wasm, nil, 100000, new(big.Int))
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if !bytes.Equal(ret, value.Bytes()) {
+		t.Errorf("loaded value mismatch: have %x, want %x", ret, value)
+	}
+	if have := evm.StateDB.GetState(wasm, key); have != value {
+		t.Errorf("stored value mismatch: have %x, want %x", have, value)
+	}
+
+	// Static calls cannot store.
+	evm.StateDB.SetState(wasm, key, common.Hash{})
+	if _, _, err := evm.StaticCall(caller, wasm, nil, 100000); err != errWriteProtection {
+		t.Errorf("error mismatch: have %v, want %v", err, errWriteProtection)
+	}
+	if have := evm.StateDB.GetState(wasm, key); have != (common.Hash{}) {
+		t.Errorf("value stored by static call: %x", have)
+	}
+}
+
+func TestEWASMEngineFailures(t *testing.T) {
+	var (
+		caller = AccountRef(common.HexToAddress("0x1"))
+		wasm   = common.HexToAddress("0x1000")
+	)
+	tests := []struct {
+		name    string
+		imports []string
+		body    [][]byte
+		data    []byte
+		err     error
+		ret     []byte
+	}{
+		{"endless loop", nil, [][]byte{op(wasmLoop, wasmBlockEmpty), op(wasmBr, 0), op(wasmEnd)}, nil, ErrOutOfGas, nil},
+		{"revert", []string{"revert"}, [][]byte{i32c(0), i32c(4), op(wasmCall, 0)}, []byte("fail"), ErrExecutionReverted, []byte("fail")},
+		{"unreachable", nil, [][]byte{op(wasmUnreachable)}, nil, errWasmUnreachable, nil},
+		{"division by zero", nil, [][]byte{i32c(1), i32c(0), op(0x6e), op(wasmDrop)}, nil, errWasmDivideByZero, nil},
+		{"out of bounds", nil, [][]byte{i32c(wasmPageSize - 2), op(wasmI32Load, 2, 0), op(wasmDrop)}, nil, errWasmMemory, nil},
+		{"stack underflow", nil, [][]byte{op(wasmDrop)}, nil, errWasmStack, nil},
+	}
+	for _, tt := range tests {
+		evm := newEWASMTestEVM(t, 10, "")
+		evm.StateDB.SetCode(wasm, buildWasmModule(tt.imports, []testWasmFunc{{body: tt.body}}, tt.data))
+
+		ret, gas, err := evm.Call(caller, wasm, nil, 100000, new(big.Int))
+		if err != tt.err {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.err)
+		}
+		if !bytes.Equal(ret, tt.ret) {
+			t.Errorf("%s: output mismatch: have %q, want %q", tt.name, ret, tt.ret)
+		}
+		if (gas > 0) != (tt.err == ErrExecutionReverted) {
+			t.Errorf("%s: gas left mismatch: %d", tt.name, gas)
+		}
+	}
+}
+
+// Tests that ewasm contracts call EVM contracts, and that ewasm contracts are
+// deployed by ewasm init code.
+func TestEWASMEngineCrossVM(t *testing.T) {
+	var (
+		caller = AccountRef(common.HexToAddress("0x1"))
+		wasm   = common.HexToAddress("0x1000")
+		evmc   = common.HexToAddress("0x2000")
+	)
+	evm := newEWASMTestEVM(t, 10, "")
+	// PUSH1 0x2a PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	evm.StateDB.SetCode(evmc, common.Hex2Bytes("602a60005260206000f3"))
+	evm.StateDB.SetCode(wasm, buildWasmModule([]string{"call", "returnDataCopy", "finish"}, []testWasmFunc{
+		{body: [][]byte{
+			i64c(50000), i32c(0), i32c(32), i32c(0), i32c(0), op(wasmCall, 0), op(wasmDrop),
+			i32c(64), i32c(0), i32c(32), op(wasmCall, 1),
+			i32c(64), i32c(32), op(wasmCall, 2),
+		}},
+	}, evmc.Bytes()))
+
+	ret, _, err := evm.Call(caller, wasm, nil, 100000, new(big.Int))
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if want := common.LeftPadBytes([]byte{0x2a}, 32); !bytes.Equal(ret, want) {
+		t.Errorf("output mismatch: have %x, want %x", ret, want)
+	}
+
+	runtime := sumWasmModule()
+	initCode := buildWasmModule([]string{"finish"}, []testWasmFunc{
+		{body: [][]byte{i32c(0), i32c(int64(len(runtime))), op(wasmCall, 0)}},
+	}, runtime)
+	_, addr, _, err := evm.Create(caller, initCode, 10000000, new(big.Int))
+	if err != nil {
+		t.Fatalf("deployment failed: %v", err)
+	}
+	if ret, _, err = evm.Call(caller, addr, nil, 100000, new(big.Int)); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if len(ret) != 4 || binary.LittleEndian.Uint32(ret) != 55 {
+		t.Errorf("output mismatch: have %x, want 55", ret)
+	}
+}
+
+func TestDecodeWasmModule(t *testing.T) {
+	if _, err := decodeWasmModule(sumWasmModule()); err != nil {
+		t.Fatalf("failed to decode module: %v", err)
+	}
+	invalid := map[string][]byte{
+		"floating point": buildWasmModule(nil, []testWasmFunc{{body: [][]byte{{0x43, 0, 0, 0, 0}, op(wasmDrop)}}}, nil),
+		"unknown import": buildWasmModule(nil, []testWasmFunc{{body: [][]byte{op(wasmCall, 5)}}}, nil),
+		"main signature": buildWasmModule(nil, []testWasmFunc{{params: "i"}}, nil),
+		"unclosed block": buildWasmModule(nil, []testWasmFunc{{body: [][]byte{op(wasmBlock, wasmBlockEmpty)}}}, nil),
+		"unknown local":  buildWasmModule(nil, []testWasmFunc{{body: [][]byte{op(wasmLocalGet, 0), op(wasmDrop)}}}, nil),
+		"truncated":      sumWasmModule()[:40],
+	}
+	for name, code := range invalid {
+		if _, err := decodeWasmModule(code); err == nil {
+			t.Errorf("%s: module decoded", name)
+		}
+	}
+	// Imports are limited to the host functions.
+	code := buildWasmModule([]string{"finish"}, []testWasmFunc{{}}, nil)
+	code = bytes.Replace(code, []byte("finish"), []byte("finisk"), 1)
+	if _, err := decodeWasmModule(code); err == nil {
+		t.Errorf("unknown host function imported")
+	}
+}
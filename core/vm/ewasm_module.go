@@ -0,0 +1,755 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Value types of ewasm modules. Floating point types are not allowed, as
+// their results are not deterministic across platforms.
+const (
+	wasmI32 byte = 0x7f
+	wasmI64 byte = 0x7e
+
+	wasmBlockEmpty byte = 0x40
+)
+
+// WebAssembly opcodes supported by the built-in ewasm engine: the MVP
+// instruction set without floating point and indirect calls.
+const (
+	wasmUnreachable = 0x00
+	wasmNop         = 0x01
+	wasmBlock       = 0x02
+	wasmLoop        = 0x03
+	wasmIf          = 0x04
+	wasmElse        = 0x05
+	wasmEnd         = 0x0b
+	wasmBr          = 0x0c
+	wasmBrIf        = 0x0d
+	wasmBrTable     = 0x0e
+	wasmReturn      = 0x0f
+	wasmCall        = 0x10
+
+	wasmDrop   = 0x1a
+	wasmSelect = 0x1b
+
+	wasmLocalGet  = 0x20
+	wasmLocalSet  = 0x21
+	wasmLocalTee  = 0x22
+	wasmGlobalGet = 0x23
+	wasmGlobalSet = 0x24
+
+	wasmI32Load    = 0x28
+	wasmI64Load    = 0x29
+	wasmI32Load8S  = 0x2c
+	wasmI32Load8U  = 0x2d
+	wasmI32Load16S = 0x2e
+	wasmI32Load16U = 0x2f
+	wasmI64Load8S  = 0x30
+	wasmI64Load8U  = 0x31
+	wasmI64Load16S = 0x32
+	wasmI64Load16U = 0x33
+	wasmI64Load32S = 0x34
+	wasmI64Load32U = 0x35
+	wasmI32Store   = 0x36
+	wasmI64Store   = 0x37
+	wasmI32Store8  = 0x3a
+	wasmI32Store16 = 0x3b
+	wasmI64Store8  = 0x3c
+	wasmI64Store16 = 0x3d
+	wasmI64Store32 = 0x3e
+	wasmMemorySize = 0x3f
+	wasmMemoryGrow = 0x40
+
+	wasmI32Const = 0x41
+	wasmI64Const = 0x42
+
+	wasmI32Eqz    = 0x45
+	wasmI32GeU    = 0x4f
+	wasmI64Eqz    = 0x50
+	wasmI64GeU    = 0x5a
+	wasmI32Clz    = 0x67
+	wasmI32Ctz    = 0x68
+	wasmI32Popcnt = 0x69
+	wasmI32Rotr   = 0x78
+	wasmI64Clz    = 0x79
+	wasmI64Ctz    = 0x7a
+	wasmI64Popcnt = 0x7b
+	wasmI64Rotr   = 0x8a
+
+	wasmI32WrapI64    = 0xa7
+	wasmI64ExtendI32S = 0xac
+	wasmI64ExtendI32U = 0xad
+)
+
+// Section identifiers of WebAssembly binary modules.
+const (
+	wasmSectionCustom   = 0
+	wasmSectionType     = 1
+	wasmSectionImport   = 2
+	wasmSectionFunction = 3
+	wasmSectionMemory   = 5
+	wasmSectionGlobal   = 6
+	wasmSectionExport   = 7
+	wasmSectionCode     = 10
+	wasmSectionData     = 11
+)
+
+var errWasmTruncated = errors.New("ewasm: truncated module")
+
+// wasmFuncType is the signature of a function.
+type wasmFuncType struct {
+	params  []byte
+	results []byte
+}
+
+func (t *wasmFuncType) equal(other *wasmFuncType) bool {
+	return string(t.params) == string(other.params) && string(t.results) == string(other.results)
+}
+
+// wasmFunc is a function defined by a module.
+type wasmFunc struct {
+	typ    *wasmFuncType
+	locals []byte // Types of the locals following the parameters
+	code   []byte
+
+	// Positions of the end of each block, loop, if and else, and of the else
+	// of each if, and the targets of each br_table, by the position of their
+	// opcode.
+	ends     map[int]int
+	elses    map[int]int
+	brTables map[int][]uint32
+}
+
+// wasmGlobal is a global variable of a module and its initial value.
+type wasmGlobal struct {
+	typ     byte
+	mutable bool
+	init    uint64
+}
+
+// wasmData is a data segment initializing the memory.
+type wasmData struct {
+	offset uint32
+	data   []byte
+}
+
+// wasmModule is a decoded ewasm contract. Its functions are indexed after
+// the host functions it imports.
+type wasmModule struct {
+	imports []*ewasmHostFunc
+	funcs   []*wasmFunc
+	globals []wasmGlobal
+	data    []wasmData
+	main    uint32
+
+	hasMemory bool
+	memMin    uint32 // Initial memory size in pages
+	memMax    uint32 // Maximum memory size in pages
+}
+
+// wasmReader decodes the primitive values of a binary module.
+type wasmReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *wasmReader) done() bool {
+	return r.pos >= len(r.buf)
+}
+
+func (r *wasmReader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, errWasmTruncated
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *wasmReader) bytes(n uint32) ([]byte, error) {
+	if uint64(n) > uint64(len(r.buf)-r.pos) {
+		return nil, errWasmTruncated
+	}
+	b := r.buf[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+// uleb reads an unsigned LEB128 integer of at most bits bits.
+func (r *wasmReader) uleb(bits uint) (uint64, error) {
+	var (
+		result uint64
+		shift  uint
+	)
+	for {
+		if shift >= bits {
+			return 0, errors.New("ewasm: integer representation too long")
+		}
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		if shift == 63 && b&0x7e != 0 {
+			return 0, errors.New("ewasm: integer too large")
+		}
+		result |= uint64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if bits < 64 && result>>bits != 0 {
+		return 0, errors.New("ewasm: integer too large")
+	}
+	return result, nil
+}
+
+// sleb reads a signed LEB128 integer of at most bits bits.
+func (r *wasmReader) sleb(bits uint) (int64, error) {
+	var (
+		result int64
+		shift  uint
+		b      byte
+		err    error
+	)
+	for {
+		if shift >= bits {
+			return 0, errors.New("ewasm: integer representation too long")
+		}
+		if b, err = r.byte(); err != nil {
+			return 0, err
+		}
+		result |= int64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if shift < 64 && b&0x40 != 0 {
+		result |= -1 << shift
+	}
+	if bits == 32 && (result < math.MinInt32 || result > math.MaxInt32) {
+		return 0, errors.New("ewasm: integer too large")
+	}
+	return result, nil
+}
+
+func (r *wasmReader) u32() (uint32, error) {
+	n, err := r.uleb(32)
+	return uint32(n), err
+}
+
+func (r *wasmReader) name() (string, error) {
+	n, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(n)
+	return string(b), err
+}
+
+// valueTypes reads a vector of value types.
+func (r *wasmReader) valueTypes() ([]byte, error) {
+	n, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	types, err := r.bytes(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		if t != wasmI32 && t != wasmI64 {
+			return nil, fmt.Errorf("ewasm: unsupported value type %#x", t)
+		}
+	}
+	return types, nil
+}
+
+// constExpr reads the constant initializer of a global or a data segment.
+func (r *wasmReader) constExpr(typ byte) (uint64, error) {
+	op, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	var value int64
+	switch {
+	case op == wasmI32Const && typ == wasmI32:
+		value, err = r.sleb(32)
+		value = int64(uint32(value))
+	case op == wasmI64Const && typ == wasmI64:
+		value, err = r.sleb(64)
+	default:
+		return 0, fmt.Errorf("ewasm: unsupported initializer %#x", op)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if end, err := r.byte(); err != nil || end != wasmEnd {
+		return 0, errors.New("ewasm: unterminated initializer")
+	}
+	return uint64(value), nil
+}
+
+// decodeWasmModule decodes and checks an ewasm contract. Its only export
+// besides the memory must be the main function, taking and returning
+// nothing, and it may import only the host functions of the ethereum
+// namespace. Tables, start functions and floating point are rejected.
+func decodeWasmModule(code []byte) (*wasmModule, error) {
+	if len(code) < 8 || !isEWASMCode(code) || code[4] != 1 || code[5] != 0 || code[6] != 0 || code[7] != 0 {
+		return nil, errors.New("ewasm: invalid module header")
+	}
+	var (
+		r       = &wasmReader{buf: code, pos: 8}
+		mod     = new(wasmModule)
+		types   []*wasmFuncType
+		funcs   []uint32
+		hasMain bool
+		last    byte
+	)
+	for !r.done() {
+		id, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		body, err := r.bytes(size)
+		if err != nil {
+			return nil, err
+		}
+		if id == wasmSectionCustom {
+			continue
+		}
+		if id <= last {
+			return nil, fmt.Errorf("ewasm: misplaced section %d", id)
+		}
+		last = id
+
+		s := &wasmReader{buf: body}
+		n, err := s.u32()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint32(0); i < n; i++ {
+			switch id {
+			case wasmSectionType:
+				if form, err := s.byte(); err != nil || form != 0x60 {
+					return nil, errors.New("ewasm: invalid function type")
+				}
+				params, err := s.valueTypes()
+				if err != nil {
+					return nil, err
+				}
+				results, err := s.valueTypes()
+				if err != nil {
+					return nil, err
+				}
+				if len(results) > 1 {
+					return nil, errors.New("ewasm: multiple results")
+				}
+				types = append(types, &wasmFuncType{params: params, results: results})
+
+			case wasmSectionImport:
+				module, err := s.name()
+				if err != nil {
+					return nil, err
+				}
+				field, err := s.name()
+				if err != nil {
+					return nil, err
+				}
+				if kind, err := s.byte(); err != nil || kind != 0 {
+					return nil, fmt.Errorf("ewasm: import %s.%s is not a function", module, field)
+				}
+				typ, err := s.u32()
+				if err != nil {
+					return nil, err
+				}
+				host := ewasmHostFuncs[field]
+				if module != "ethereum" || host == nil {
+					return nil, fmt.Errorf("ewasm: unknown import %s.%s", module, field)
+				}
+				if typ >= uint32(len(types)) || !types[typ].equal(&host.typ) {
+					return nil, fmt.Errorf("ewasm: import %s.%s signature mismatch", module, field)
+				}
+				mod.imports = append(mod.imports, host)
+
+			case wasmSectionFunction:
+				typ, err := s.u32()
+				if err != nil {
+					return nil, err
+				}
+				if typ >= uint32(len(types)) {
+					return nil, errors.New("ewasm: unknown function type")
+				}
+				funcs = append(funcs, typ)
+
+			case wasmSectionMemory:
+				if i > 0 {
+					return nil, errors.New("ewasm: multiple memories")
+				}
+				flags, err := s.byte()
+				if err != nil {
+					return nil, err
+				}
+				if mod.memMin, err = s.u32(); err != nil {
+					return nil, err
+				}
+				mod.memMax = wasmMaxPages
+				switch flags {
+				case 0:
+				case 1:
+					max, err := s.u32()
+					if err != nil {
+						return nil, err
+					}
+					if max < mod.memMax {
+						mod.memMax = max
+					}
+				default:
+					return nil, errors.New("ewasm: invalid memory limits")
+				}
+				if mod.memMin > mod.memMax {
+					return nil, errors.New("ewasm: memory too large")
+				}
+				mod.hasMemory = true
+
+			case wasmSectionGlobal:
+				typ, err := s.byte()
+				if err != nil {
+					return nil, err
+				}
+				if typ != wasmI32 && typ != wasmI64 {
+					return nil, fmt.Errorf("ewasm: unsupported value type %#x", typ)
+				}
+				mutable, err := s.byte()
+				if err != nil || mutable > 1 {
+					return nil, errors.New("ewasm: invalid global")
+				}
+				init, err := s.constExpr(typ)
+				if err != nil {
+					return nil, err
+				}
+				mod.globals = append(mod.globals, wasmGlobal{typ: typ, mutable: mutable == 1, init: init})
+
+			case wasmSectionExport:
+				name, err := s.name()
+				if err != nil {
+					return nil, err
+				}
+				kind, err := s.byte()
+				if err != nil {
+					return nil, err
+				}
+				index, err := s.u32()
+				if err != nil {
+					return nil, err
+				}
+				switch {
+				case name == "main" && kind == 0:
+					mod.main, hasMain = index, true
+				case name == "memory" && kind == 2:
+				default:
+					return nil, fmt.Errorf("ewasm: unexpected export %s", name)
+				}
+
+			case wasmSectionCode:
+				if n != uint32(len(funcs)) {
+					return nil, errors.New("ewasm: function and code sections mismatch")
+				}
+				size, err := s.u32()
+				if err != nil {
+					return nil, err
+				}
+				body, err := s.bytes(size)
+				if err != nil {
+					return nil, err
+				}
+				f, err := decodeWasmFunc(body, types[funcs[i]])
+				if err != nil {
+					return nil, err
+				}
+				mod.funcs = append(mod.funcs, f)
+
+			case wasmSectionData:
+				if memory, err := s.u32(); err != nil || memory != 0 || !mod.hasMemory {
+					return nil, errors.New("ewasm: data segment without memory")
+				}
+				offset, err := s.constExpr(wasmI32)
+				if err != nil {
+					return nil, err
+				}
+				size, err := s.u32()
+				if err != nil {
+					return nil, err
+				}
+				data, err := s.bytes(size)
+				if err != nil {
+					return nil, err
+				}
+				if uint64(offset)+uint64(size) > uint64(mod.memMin)*wasmPageSize {
+					return nil, errors.New("ewasm: data segment out of memory")
+				}
+				mod.data = append(mod.data, wasmData{offset: uint32(offset), data: data})
+
+			default:
+				return nil, fmt.Errorf("ewasm: unsupported section %d", id)
+			}
+		}
+		if !s.done() {
+			return nil, fmt.Errorf("ewasm: malformed section %d", id)
+		}
+	}
+	if len(mod.funcs) != len(funcs) {
+		return nil, errors.New("ewasm: function and code sections mismatch")
+	}
+	if !hasMain || mod.main < uint32(len(mod.imports)) || mod.main >= uint32(len(mod.imports)+len(mod.funcs)) {
+		return nil, errors.New("ewasm: main function not exported")
+	}
+	if typ := mod.funcs[mod.main-uint32(len(mod.imports))].typ; len(typ.params) != 0 || len(typ.results) != 0 {
+		return nil, errors.New("ewasm: main function signature mismatch")
+	}
+	// Check the indexes the code refers to now that all are known.
+	for _, f := range mod.funcs {
+		if err := mod.checkFunc(f); err != nil {
+			return nil, err
+		}
+	}
+	return mod, nil
+}
+
+// decodeWasmFunc decodes the locals of a function body and matches the
+// blocks of its code.
+func decodeWasmFunc(body []byte, typ *wasmFuncType) (*wasmFunc, error) {
+	r := &wasmReader{buf: body}
+	n, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	f := &wasmFunc{
+		typ:      typ,
+		ends:     make(map[int]int),
+		elses:    make(map[int]int),
+		brTables: make(map[int][]uint32),
+	}
+	for i := uint32(0); i < n; i++ {
+		count, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		t, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if t != wasmI32 && t != wasmI64 {
+			return nil, fmt.Errorf("ewasm: unsupported value type %#x", t)
+		}
+		if uint64(len(f.locals))+uint64(count) > wasmMaxLocals {
+			return nil, errors.New("ewasm: too many locals")
+		}
+		for j := uint32(0); j < count; j++ {
+			f.locals = append(f.locals, t)
+		}
+	}
+	f.code = body[r.pos:]
+
+	// Match the blocks with their ends, the function body itself being the
+	// outermost block.
+	type block struct {
+		start int // Position of the block, loop or if
+		els   int // Position of the else of an if, -1 if none
+	}
+	var (
+		c      = &wasmReader{buf: f.code}
+		blocks []block
+	)
+	for !c.done() {
+		pc := c.pos
+		op, _ := c.byte()
+		switch op {
+		case wasmBlock, wasmLoop, wasmIf:
+			bt, err := c.byte()
+			if err != nil {
+				return nil, err
+			}
+			if bt != wasmBlockEmpty && bt != wasmI32 && bt != wasmI64 {
+				return nil, fmt.Errorf("ewasm: unsupported block type %#x", bt)
+			}
+			if len(blocks) == wasmMaxBlockDepth {
+				return nil, errors.New("ewasm: blocks nested too deep")
+			}
+			blocks = append(blocks, block{start: pc, els: -1})
+		case wasmElse:
+			if len(blocks) == 0 {
+				return nil, errors.New("ewasm: else outside of if")
+			}
+			b := &blocks[len(blocks)-1]
+			if f.code[b.start] != wasmIf || b.els >= 0 {
+				return nil, errors.New("ewasm: else outside of if")
+			}
+			b.els = pc
+			f.elses[b.start] = pc
+		case wasmEnd:
+			if len(blocks) == 0 {
+				if !c.done() {
+					return nil, errors.New("ewasm: code after function end")
+				}
+				return f, nil
+			}
+			b := blocks[len(blocks)-1]
+			f.ends[b.start] = pc
+			if b.els >= 0 {
+				f.ends[b.els] = pc
+			}
+			blocks = blocks[:len(blocks)-1]
+		case wasmBr, wasmBrIf, wasmCall, wasmLocalGet, wasmLocalSet, wasmLocalTee,
+			wasmGlobalGet, wasmGlobalSet:
+			if _, err := c.u32(); err != nil {
+				return nil, err
+			}
+		case wasmBrTable:
+			n, err := c.u32()
+			if err != nil {
+				return nil, err
+			}
+			// Every target takes at least a byte.
+			if uint64(n) >= uint64(len(f.code)) {
+				return nil, errWasmTruncated
+			}
+			targets := make([]uint32, n+1)
+			for i := range targets {
+				if targets[i], err = c.u32(); err != nil {
+					return nil, err
+				}
+			}
+			f.brTables[pc] = targets
+		case wasmMemorySize, wasmMemoryGrow:
+			if b, err := c.byte(); err != nil || b != 0 {
+				return nil, errors.New("ewasm: invalid memory index")
+			}
+		case wasmI32Const:
+			if _, err := c.sleb(32); err != nil {
+				return nil, err
+			}
+		case wasmI64Const:
+			if _, err := c.sleb(64); err != nil {
+				return nil, err
+			}
+		default:
+			switch {
+			case isWasmMemoryOp(op):
+				if _, err := c.u32(); err != nil {
+					return nil, err
+				}
+				if _, err := c.u32(); err != nil {
+					return nil, err
+				}
+			case isWasmPlainOp(op):
+			default:
+				return nil, fmt.Errorf("ewasm: unsupported opcode %#x", op)
+			}
+		}
+	}
+	return nil, errors.New("ewasm: unterminated function")
+}
+
+// checkFunc checks the function, local and global indexes of the code of f,
+// and that it accesses memory only if the module has some.
+func (mod *wasmModule) checkFunc(f *wasmFunc) error {
+	var (
+		c      = &wasmReader{buf: f.code}
+		locals = uint32(len(f.typ.params) + len(f.locals))
+		funcs  = uint32(len(mod.imports) + len(mod.funcs))
+	)
+	for !c.done() {
+		op, _ := c.byte()
+		switch op {
+		case wasmBlock, wasmLoop, wasmIf:
+			c.byte()
+		case wasmBr, wasmBrIf:
+			c.u32()
+		case wasmBrTable:
+			n, _ := c.u32()
+			for i := uint64(0); i <= uint64(n); i++ {
+				c.u32()
+			}
+		case wasmCall:
+			if index, _ := c.u32(); index >= funcs {
+				return errors.New("ewasm: unknown function")
+			}
+		case wasmLocalGet, wasmLocalSet, wasmLocalTee:
+			if index, _ := c.u32(); index >= locals {
+				return errors.New("ewasm: unknown local")
+			}
+		case wasmGlobalGet:
+			if index, _ := c.u32(); index >= uint32(len(mod.globals)) {
+				return errors.New("ewasm: unknown global")
+			}
+		case wasmGlobalSet:
+			if index, _ := c.u32(); index >= uint32(len(mod.globals)) || !mod.globals[index].mutable {
+				return errors.New("ewasm: unknown or immutable global")
+			}
+		case wasmMemorySize, wasmMemoryGrow:
+			c.byte()
+			if !mod.hasMemory {
+				return errors.New("ewasm: memory access without memory")
+			}
+		case wasmI32Const:
+			c.sleb(32)
+		case wasmI64Const:
+			c.sleb(64)
+		default:
+			if isWasmMemoryOp(op) {
+				c.u32()
+				c.u32()
+				if !mod.hasMemory {
+					return errors.New("ewasm: memory access without memory")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isWasmMemoryOp returns whether op is a supported load or store.
+func isWasmMemoryOp(op byte) bool {
+	return (op >= wasmI32Load && op <= wasmI64Load) || (op >= wasmI32Load8S && op <= wasmI64Store) ||
+		(op >= wasmI32Store8 && op <= wasmI64Store32)
+}
+
+// isWasmPlainOp returns whether op is a supported instruction without
+// immediates.
+func isWasmPlainOp(op byte) bool {
+	switch {
+	case op == wasmUnreachable, op == wasmNop, op == wasmReturn, op == wasmDrop, op == wasmSelect:
+		return true
+	case op >= wasmI32Eqz && op <= wasmI32GeU, op >= wasmI64Eqz && op <= wasmI64GeU:
+		return true
+	case op >= wasmI32Clz && op <= wasmI32Rotr, op >= wasmI64Clz && op <= wasmI64Rotr:
+		return true
+	case op == wasmI32WrapI64, op == wasmI64ExtendI32S, op == wasmI64ExtendI32U:
+		return true
+	}
+	return false
+}
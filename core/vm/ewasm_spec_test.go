@@ -0,0 +1,728 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/portto/go-tangerine/common"
+)
+
+// The tests below check the built-in ewasm engine against the assertions of
+// the WebAssembly specification test suite (i32.wast, i64.wast,
+// conversions.wast, memory.wast, br_table.wast and friends) for the integer
+// subset of the MVP the engine supports. Values are written like in the
+// .wast files.
+
+// wasmSpecOps are the opcodes of the numeric instructions, by name.
+var wasmSpecOps = map[string]byte{}
+
+func init() {
+	names := func(first byte, prefix string, ops ...string) {
+		for i, name := range ops {
+			wasmSpecOps[prefix+name] = first + byte(i)
+		}
+	}
+	compare := []string{"eqz", "eq", "ne", "lt_s", "lt_u", "gt_s", "gt_u", "le_s", "le_u", "ge_s", "ge_u"}
+	arith := []string{"clz", "ctz", "popcnt", "add", "sub", "mul", "div_s", "div_u", "rem_s", "rem_u",
+		"and", "or", "xor", "shl", "shr_s", "shr_u", "rotl", "rotr"}
+	names(wasmI32Eqz, "i32.", compare...)
+	names(wasmI64Eqz, "i64.", compare...)
+	names(wasmI32Clz, "i32.", arith...)
+	names(wasmI64Clz, "i64.", arith...)
+	wasmSpecOps["i32.wrap_i64"] = wasmI32WrapI64
+	wasmSpecOps["i64.extend_i32_s"] = wasmI64ExtendI32S
+	wasmSpecOps["i64.extend_i32_u"] = wasmI64ExtendI32U
+}
+
+// wasmSpecValue parses a value of a .wast assertion.
+func wasmSpecValue(t *testing.T, s string) uint64 {
+	if strings.HasPrefix(s, "-") {
+		v, err := strconv.ParseInt(s, 0, 64)
+		if err != nil {
+			t.Fatalf("invalid value %q: %v", s, err)
+		}
+		return uint64(v)
+	}
+	v, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		t.Fatalf("invalid value %q: %v", s, err)
+	}
+	return v
+}
+
+// wasmSpecTypes returns whether the operands and the result of the named
+// instruction are i64.
+func wasmSpecTypes(name string) (operands64, result64 bool) {
+	operands64 = strings.HasPrefix(name, "i64.")
+	result64 = operands64
+	switch {
+	case name == "i32.wrap_i64":
+		operands64 = true
+	case strings.HasPrefix(name, "i64.extend_i32"):
+		operands64 = false
+	case strings.HasPrefix(name, "i64.") && wasmSpecOps[name] <= wasmI64GeU:
+		result64 = false
+	}
+	return operands64, result64
+}
+
+// runWasmSpecBody runs a main function and returns the i32 or i64 it
+// leaves in the first bytes of memory.
+func runWasmSpecBody(t *testing.T, body [][]byte, result64 bool) (uint64, error) {
+	var (
+		caller = AccountRef(common.HexToAddress("0x1"))
+		wasm   = common.HexToAddress("0x1000")
+		size   = int64(4)
+	)
+	if result64 {
+		size = 8
+	}
+	body = append(body, i32c(0), i32c(size), op(wasmCall, 0))
+
+	evm := newEWASMTestEVM(t, 10, "")
+	evm.StateDB.SetCode(wasm, buildWasmModule([]string{"finish"}, []testWasmFunc{{body: body}}, nil))
+	ret, _, err := evm.Call(caller, wasm, nil, 1000000, new(big.Int))
+	if err != nil {
+		return 0, err
+	}
+	if int64(len(ret)) != size {
+		t.Fatalf("output size mismatch: have %d, want %d", len(ret), size)
+	}
+	if result64 {
+		return binary.LittleEndian.Uint64(ret), nil
+	}
+	return uint64(binary.LittleEndian.Uint32(ret)), nil
+}
+
+// storeResult stores the result of an instruction at address 0, which must
+// have been pushed first.
+func storeResult(result64 bool) []byte {
+	if result64 {
+		return op(wasmI64Store, 3, 0)
+	}
+	return op(wasmI32Store, 2, 0)
+}
+
+func TestEWASMSpecNumeric(t *testing.T) {
+	tests := []struct {
+		op   string
+		args string
+		want string
+		err  error
+	}{
+		// i32.wast
+		{"i32.add", "1 1", "2", nil},
+		{"i32.add", "1 0", "1", nil},
+		{"i32.add", "-1 -1", "-2", nil},
+		{"i32.add", "-1 1", "0", nil},
+		{"i32.add", "0x7fffffff 1", "0x80000000", nil},
+		{"i32.add", "0x80000000 -1", "0x7fffffff", nil},
+		{"i32.add", "0x80000000 0x80000000", "0", nil},
+		{"i32.add", "0x3fffffff 1", "0x40000000", nil},
+		{"i32.sub", "1 1", "0", nil},
+		{"i32.sub", "-1 -1", "0", nil},
+		{"i32.sub", "0x7fffffff -1", "0x80000000", nil},
+		{"i32.sub", "0x80000000 1", "0x7fffffff", nil},
+		{"i32.sub", "0x80000000 0x80000000", "0", nil},
+		{"i32.sub", "0x3fffffff -1", "0x40000000", nil},
+		{"i32.mul", "1 1", "1", nil},
+		{"i32.mul", "-1 -1", "1", nil},
+		{"i32.mul", "0x10000000 4096", "0", nil},
+		{"i32.mul", "0x80000000 0", "0", nil},
+		{"i32.mul", "0x80000000 -1", "0x80000000", nil},
+		{"i32.mul", "0x7fffffff -1", "0x80000001", nil},
+		{"i32.mul", "0x01234567 0x76543210", "0x358e7470", nil},
+		{"i32.mul", "0x7fffffff 0x7fffffff", "1", nil},
+		{"i32.div_s", "1 0", "", errWasmDivideByZero},
+		{"i32.div_s", "0 0", "", errWasmDivideByZero},
+		{"i32.div_s", "0x80000000 -1", "", errWasmDivideOverflow},
+		{"i32.div_s", "0x80000000 0", "", errWasmDivideByZero},
+		{"i32.div_s", "1 1", "1", nil},
+		{"i32.div_s", "0 -1", "0", nil},
+		{"i32.div_s", "-1 -1", "1", nil},
+		{"i32.div_s", "0x80000000 2", "0xc0000000", nil},
+		{"i32.div_s", "0x80000001 1000", "0xffdf3b65", nil},
+		{"i32.div_s", "5 2", "2", nil},
+		{"i32.div_s", "-5 2", "-2", nil},
+		{"i32.div_s", "5 -2", "-2", nil},
+		{"i32.div_s", "-5 -2", "2", nil},
+		{"i32.div_s", "7 3", "2", nil},
+		{"i32.div_s", "-7 3", "-2", nil},
+		{"i32.div_s", "7 -3", "-2", nil},
+		{"i32.div_s", "-7 -3", "2", nil},
+		{"i32.div_s", "11 5", "2", nil},
+		{"i32.div_s", "17 7", "2", nil},
+		{"i32.div_u", "1 0", "", errWasmDivideByZero},
+		{"i32.div_u", "0 0", "", errWasmDivideByZero},
+		{"i32.div_u", "1 1", "1", nil},
+		{"i32.div_u", "-1 -1", "1", nil},
+		{"i32.div_u", "0x80000000 -1", "0", nil},
+		{"i32.div_u", "0x80000000 2", "0x40000000", nil},
+		{"i32.div_u", "0x8ff00ff0 0x10001", "0x8fef", nil},
+		{"i32.div_u", "0x80000001 1000", "0x20c49b", nil},
+		{"i32.div_u", "5 2", "2", nil},
+		{"i32.div_u", "-5 2", "0x7ffffffd", nil},
+		{"i32.div_u", "5 -2", "0", nil},
+		{"i32.div_u", "-5 -2", "0", nil},
+		{"i32.div_u", "7 3", "2", nil},
+		{"i32.rem_s", "1 0", "", errWasmDivideByZero},
+		{"i32.rem_s", "0 0", "", errWasmDivideByZero},
+		{"i32.rem_s", "0x7fffffff -1", "0", nil},
+		{"i32.rem_s", "1 1", "0", nil},
+		{"i32.rem_s", "-1 -1", "0", nil},
+		{"i32.rem_s", "0x80000000 -1", "0", nil},
+		{"i32.rem_s", "0x80000000 2", "0", nil},
+		{"i32.rem_s", "0x80000001 1000", "-647", nil},
+		{"i32.rem_s", "5 2", "1", nil},
+		{"i32.rem_s", "-5 2", "-1", nil},
+		{"i32.rem_s", "5 -2", "1", nil},
+		{"i32.rem_s", "-5 -2", "-1", nil},
+		{"i32.rem_s", "7 3", "1", nil},
+		{"i32.rem_s", "-7 3", "-1", nil},
+		{"i32.rem_s", "7 -3", "1", nil},
+		{"i32.rem_s", "-7 -3", "-1", nil},
+		{"i32.rem_s", "17 7", "3", nil},
+		{"i32.rem_u", "1 0", "", errWasmDivideByZero},
+		{"i32.rem_u", "0 0", "", errWasmDivideByZero},
+		{"i32.rem_u", "-1 -1", "0", nil},
+		{"i32.rem_u", "0x80000000 -1", "0x80000000", nil},
+		{"i32.rem_u", "0x80000000 2", "0", nil},
+		{"i32.rem_u", "0x8ff00ff0 0x10001", "0x8001", nil},
+		{"i32.rem_u", "0x80000001 1000", "649", nil},
+		{"i32.rem_u", "-5 2", "1", nil},
+		{"i32.rem_u", "5 -2", "5", nil},
+		{"i32.rem_u", "-5 -2", "-5", nil},
+		{"i32.rem_u", "17 7", "3", nil},
+		{"i32.and", "1 0", "0", nil},
+		{"i32.and", "1 1", "1", nil},
+		{"i32.and", "0x7fffffff 0x80000000", "0", nil},
+		{"i32.and", "0x7fffffff -1", "0x7fffffff", nil},
+		{"i32.and", "0xf0f0ffff 0xfffff0f0", "0xf0f0f0f0", nil},
+		{"i32.and", "0xffffffff 0xffffffff", "0xffffffff", nil},
+		{"i32.or", "1 0", "1", nil},
+		{"i32.or", "0x7fffffff 0x80000000", "-1", nil},
+		{"i32.or", "0x80000000 0", "0x80000000", nil},
+		{"i32.or", "0xf0f0ffff 0xfffff0f0", "0xffffffff", nil},
+		{"i32.xor", "1 1", "0", nil},
+		{"i32.xor", "0x7fffffff 0x80000000", "-1", nil},
+		{"i32.xor", "-1 0x80000000", "0x7fffffff", nil},
+		{"i32.xor", "-1 0x7fffffff", "0x80000000", nil},
+		{"i32.xor", "0xf0f0ffff 0xfffff0f0", "0x0f0f0f0f", nil},
+		{"i32.shl", "1 1", "2", nil},
+		{"i32.shl", "0x7fffffff 1", "0xfffffffe", nil},
+		{"i32.shl", "0x80000000 1", "0", nil},
+		{"i32.shl", "0x40000000 1", "0x80000000", nil},
+		{"i32.shl", "1 31", "0x80000000", nil},
+		{"i32.shl", "1 32", "1", nil},
+		{"i32.shl", "1 33", "2", nil},
+		{"i32.shl", "1 -1", "0x80000000", nil},
+		{"i32.shl", "1 0x7fffffff", "0x80000000", nil},
+		{"i32.shr_s", "1 1", "0", nil},
+		{"i32.shr_s", "-1 1", "-1", nil},
+		{"i32.shr_s", "0x7fffffff 1", "0x3fffffff", nil},
+		{"i32.shr_s", "0x80000000 1", "0xc0000000", nil},
+		{"i32.shr_s", "1 32", "1", nil},
+		{"i32.shr_s", "1 33", "0", nil},
+		{"i32.shr_s", "1 -1", "0", nil},
+		{"i32.shr_s", "1 0x80000000", "1", nil},
+		{"i32.shr_s", "0x80000000 31", "-1", nil},
+		{"i32.shr_s", "-1 32", "-1", nil},
+		{"i32.shr_s", "-1 0x7fffffff", "-1", nil},
+		{"i32.shr_u", "-1 1", "0x7fffffff", nil},
+		{"i32.shr_u", "0x80000000 1", "0x40000000", nil},
+		{"i32.shr_u", "1 32", "1", nil},
+		{"i32.shr_u", "1 33", "0", nil},
+		{"i32.shr_u", "0x80000000 31", "1", nil},
+		{"i32.shr_u", "-1 32", "-1", nil},
+		{"i32.shr_u", "-1 33", "0x7fffffff", nil},
+		{"i32.shr_u", "-1 -1", "1", nil},
+		{"i32.shr_u", "-1 0x80000000", "-1", nil},
+		{"i32.rotl", "1 1", "2", nil},
+		{"i32.rotl", "-1 1", "-1", nil},
+		{"i32.rotl", "1 32", "1", nil},
+		{"i32.rotl", "0xabcd9876 1", "0x579b30ed", nil},
+		{"i32.rotl", "0xfe00dc00 4", "0xe00dc00f", nil},
+		{"i32.rotl", "0xb0c1d2e3 5", "0x183a5c76", nil},
+		{"i32.rotl", "0x00008000 37", "0x00100000", nil},
+		{"i32.rotl", "0xb0c1d2e3 0xff05", "0x183a5c76", nil},
+		{"i32.rotl", "0x769abcdf 0xffffffed", "0x579beed3", nil},
+		{"i32.rotl", "0x769abcdf 0x8000000d", "0x579beed3", nil},
+		{"i32.rotl", "1 31", "0x80000000", nil},
+		{"i32.rotl", "0x80000000 1", "1", nil},
+		{"i32.rotr", "1 1", "0x80000000", nil},
+		{"i32.rotr", "1 32", "1", nil},
+		{"i32.rotr", "0xff00cc00 1", "0x7f806600", nil},
+		{"i32.rotr", "0x00080000 4", "0x00008000", nil},
+		{"i32.rotr", "0xb0c1d2e3 5", "0x1d860e97", nil},
+		{"i32.rotr", "0x00008000 37", "0x00000400", nil},
+		{"i32.rotr", "0xb0c1d2e3 0xff05", "0x1d860e97", nil},
+		{"i32.rotr", "0x769abcdf 0xffffffed", "0xe6fbb4d5", nil},
+		{"i32.rotr", "0x769abcdf 0x8000000d", "0xe6fbb4d5", nil},
+		{"i32.rotr", "1 31", "2", nil},
+		{"i32.rotr", "0x80000000 31", "1", nil},
+		{"i32.clz", "0xffffffff", "0", nil},
+		{"i32.clz", "0", "32", nil},
+		{"i32.clz", "0x00008000", "16", nil},
+		{"i32.clz", "0xff", "24", nil},
+		{"i32.clz", "0x80000000", "0", nil},
+		{"i32.clz", "1", "31", nil},
+		{"i32.clz", "2", "30", nil},
+		{"i32.clz", "0x7fffffff", "1", nil},
+		{"i32.ctz", "-1", "0", nil},
+		{"i32.ctz", "0", "32", nil},
+		{"i32.ctz", "0x00008000", "15", nil},
+		{"i32.ctz", "0x00010000", "16", nil},
+		{"i32.ctz", "0x80000000", "31", nil},
+		{"i32.ctz", "0x7fffffff", "0", nil},
+		{"i32.popcnt", "-1", "32", nil},
+		{"i32.popcnt", "0", "0", nil},
+		{"i32.popcnt", "0x00008000", "1", nil},
+		{"i32.popcnt", "0x80008000", "2", nil},
+		{"i32.popcnt", "0x7fffffff", "31", nil},
+		{"i32.popcnt", "0xaaaaaaaa", "16", nil},
+		{"i32.popcnt", "0x55555555", "16", nil},
+		{"i32.popcnt", "0xdeadbeef", "24", nil},
+		{"i32.eqz", "0", "1", nil},
+		{"i32.eqz", "1", "0", nil},
+		{"i32.eqz", "0x80000000", "0", nil},
+		{"i32.eqz", "0xffffffff", "0", nil},
+		{"i32.eq", "0 0", "1", nil},
+		{"i32.eq", "-1 1", "0", nil},
+		{"i32.eq", "0x80000000 0x80000000", "1", nil},
+		{"i32.eq", "0x80000000 -1", "0", nil},
+		{"i32.ne", "0 0", "0", nil},
+		{"i32.ne", "-1 1", "1", nil},
+		{"i32.ne", "0x80000000 0x7fffffff", "1", nil},
+		{"i32.lt_s", "-1 1", "1", nil},
+		{"i32.lt_s", "0x80000000 0", "1", nil},
+		{"i32.lt_s", "0 0x80000000", "0", nil},
+		{"i32.lt_s", "0x80000000 0x7fffffff", "1", nil},
+		{"i32.lt_s", "0x7fffffff 0x80000000", "0", nil},
+		{"i32.lt_u", "-1 1", "0", nil},
+		{"i32.lt_u", "0 0x80000000", "1", nil},
+		{"i32.lt_u", "0x80000000 0x7fffffff", "0", nil},
+		{"i32.lt_u", "0x7fffffff 0x80000000", "1", nil},
+		{"i32.gt_s", "-1 1", "0", nil},
+		{"i32.gt_s", "0x7fffffff 0x80000000", "1", nil},
+		{"i32.gt_u", "-1 1", "1", nil},
+		{"i32.gt_u", "0x7fffffff 0x80000000", "0", nil},
+		{"i32.le_s", "0 0", "1", nil},
+		{"i32.le_s", "-1 1", "1", nil},
+		{"i32.le_s", "0x7fffffff 0x80000000", "0", nil},
+		{"i32.le_u", "-1 1", "0", nil},
+		{"i32.le_u", "1 1", "1", nil},
+		{"i32.le_u", "0x7fffffff 0x80000000", "1", nil},
+		{"i32.ge_s", "0 0", "1", nil},
+		{"i32.ge_s", "-1 1", "0", nil},
+		{"i32.ge_s", "0x7fffffff 0x80000000", "1", nil},
+		{"i32.ge_u", "-1 1", "1", nil},
+		{"i32.ge_u", "0x7fffffff 0x80000000", "0", nil},
+
+		// i64.wast
+		{"i64.add", "-1 -1", "-2", nil},
+		{"i64.add", "0x7fffffffffffffff 1", "0x8000000000000000", nil},
+		{"i64.add", "0x8000000000000000 -1", "0x7fffffffffffffff", nil},
+		{"i64.add", "0x8000000000000000 0x8000000000000000", "0", nil},
+		{"i64.add", "0x3fffffff 1", "0x40000000", nil},
+		{"i64.sub", "0x7fffffffffffffff -1", "0x8000000000000000", nil},
+		{"i64.sub", "0x8000000000000000 1", "0x7fffffffffffffff", nil},
+		{"i64.sub", "0x3fffffff -1", "0x40000000", nil},
+		{"i64.mul", "0x1000000000000000 4096", "0", nil},
+		{"i64.mul", "0x8000000000000000 -1", "0x8000000000000000", nil},
+		{"i64.mul", "0x7fffffffffffffff -1", "0x8000000000000001", nil},
+		{"i64.mul", "0x0123456789abcdef 0xfedcba9876543210", "0x2236d88fe5618cf0", nil},
+		{"i64.mul", "0x7fffffffffffffff 0x7fffffffffffffff", "1", nil},
+		{"i64.div_s", "1 0", "", errWasmDivideByZero},
+		{"i64.div_s", "0x8000000000000000 -1", "", errWasmDivideOverflow},
+		{"i64.div_s", "-1 -1", "1", nil},
+		{"i64.div_s", "0x8000000000000000 2", "0xc000000000000000", nil},
+		{"i64.div_s", "0x8000000000000001 1000", "0xffdf3b645a1cac09", nil},
+		{"i64.div_s", "-5 2", "-2", nil},
+		{"i64.div_s", "5 -2", "-2", nil},
+		{"i64.div_s", "-7 -3", "2", nil},
+		{"i64.div_u", "1 0", "", errWasmDivideByZero},
+		{"i64.div_u", "-1 -1", "1", nil},
+		{"i64.div_u", "0x8000000000000000 -1", "0", nil},
+		{"i64.div_u", "0x8000000000000000 2", "0x4000000000000000", nil},
+		{"i64.div_u", "0x8ff00ff00ff00ff0 0x100000001", "0x8ff00fef", nil},
+		{"i64.div_u", "0x8000000000000001 1000", "0x20c49ba5e353f7", nil},
+		{"i64.div_u", "-5 2", "0x7ffffffffffffffd", nil},
+		{"i64.div_u", "5 -2", "0", nil},
+		{"i64.rem_s", "1 0", "", errWasmDivideByZero},
+		{"i64.rem_s", "0x7fffffffffffffff -1", "0", nil},
+		{"i64.rem_s", "0x8000000000000000 -1", "0", nil},
+		{"i64.rem_s", "0x8000000000000000 2", "0", nil},
+		{"i64.rem_s", "0x8000000000000001 1000", "-807", nil},
+		{"i64.rem_s", "-5 2", "-1", nil},
+		{"i64.rem_s", "7 -3", "1", nil},
+		{"i64.rem_s", "-7 -3", "-1", nil},
+		{"i64.rem_u", "1 0", "", errWasmDivideByZero},
+		{"i64.rem_u", "0x8000000000000000 -1", "0x8000000000000000", nil},
+		{"i64.rem_u", "0x8ff00ff00ff00ff0 0x100000001", "0x80000001", nil},
+		{"i64.rem_u", "0x8000000000000001 1000", "809", nil},
+		{"i64.rem_u", "5 -2", "5", nil},
+		{"i64.rem_u", "-5 -2", "-5", nil},
+		{"i64.and", "0x7fffffffffffffff 0x8000000000000000", "0", nil},
+		{"i64.and", "0xf0f0ffff 0xfffff0f0", "0xf0f0f0f0", nil},
+		{"i64.or", "0x7fffffffffffffff 0x8000000000000000", "-1", nil},
+		{"i64.or", "0xf0f0ffff 0xfffff0f0", "0xffffffff", nil},
+		{"i64.xor", "-1 0x8000000000000000", "0x7fffffffffffffff", nil},
+		{"i64.xor", "0xf0f0ffff 0xfffff0f0", "0x0f0f0f0f", nil},
+		{"i64.shl", "0x7fffffffffffffff 1", "0xfffffffffffffffe", nil},
+		{"i64.shl", "1 63", "0x8000000000000000", nil},
+		{"i64.shl", "1 64", "1", nil},
+		{"i64.shl", "1 65", "2", nil},
+		{"i64.shl", "1 -1", "0x8000000000000000", nil},
+		{"i64.shr_s", "0x8000000000000000 1", "0xc000000000000000", nil},
+		{"i64.shr_s", "0x8000000000000000 63", "-1", nil},
+		{"i64.shr_s", "1 64", "1", nil},
+		{"i64.shr_s", "1 65", "0", nil},
+		{"i64.shr_s", "-1 -1", "-1", nil},
+		{"i64.shr_u", "-1 1", "0x7fffffffffffffff", nil},
+		{"i64.shr_u", "0x8000000000000000 63", "1", nil},
+		{"i64.shr_u", "-1 64", "-1", nil},
+		{"i64.shr_u", "-1 65", "0x7fffffffffffffff", nil},
+		{"i64.shr_u", "-1 -1", "1", nil},
+		{"i64.rotl", "1 64", "1", nil},
+		{"i64.rotl", "0xabcd987602468ace 1", "0x579b30ec048d159d", nil},
+		{"i64.rotl", "0xfe000000dc000000 4", "0xe000000dc000000f", nil},
+		{"i64.rotl", "0xabcd1234ef567809 53", "0x013579a2469deacf", nil},
+		{"i64.rotl", "0xabd1234ef567809c 63", "0x55e891a77ab3c04e", nil},
+		{"i64.rotl", "0x8000000000000000 1", "1", nil},
+		{"i64.rotr", "1 1", "0x8000000000000000", nil},
+		{"i64.rotr", "0xabcd987602468ace 1", "0x55e6cc3b01234567", nil},
+		{"i64.rotr", "0xfe000000dc000000 4", "0x0fe000000dc00000", nil},
+		{"i64.rotr", "0xabcd1234ef567809 53", "0x6891a77ab3c04d5e", nil},
+		{"i64.rotr", "0xabd1234ef567809c 63", "0x57a2469deacf0139", nil},
+		{"i64.rotr", "0x8000000000000000 63", "1", nil},
+		{"i64.clz", "-1", "0", nil},
+		{"i64.clz", "0", "64", nil},
+		{"i64.clz", "0x00008000", "48", nil},
+		{"i64.clz", "0xff", "56", nil},
+		{"i64.clz", "1", "63", nil},
+		{"i64.clz", "0x7fffffffffffffff", "1", nil},
+		{"i64.ctz", "0", "64", nil},
+		{"i64.ctz", "0x00008000", "15", nil},
+		{"i64.ctz", "0x00010000", "16", nil},
+		{"i64.ctz", "0x8000000000000000", "63", nil},
+		{"i64.popcnt", "-1", "64", nil},
+		{"i64.popcnt", "0x8000800080008000", "4", nil},
+		{"i64.popcnt", "0xaaaaaaaa55555555", "32", nil},
+		{"i64.popcnt", "0x99999999aaaaaaaa", "32", nil},
+		{"i64.popcnt", "0xdeadbeefdeadbeef", "48", nil},
+		{"i64.eqz", "0", "1", nil},
+		{"i64.eqz", "0x8000000000000000", "0", nil},
+		{"i64.eqz", "0x100000000", "0", nil},
+		{"i64.eq", "0x8000000000000000 0x8000000000000000", "1", nil},
+		{"i64.eq", "0x100000000 0", "0", nil},
+		{"i64.ne", "0x100000000 0", "1", nil},
+		{"i64.lt_s", "0x8000000000000000 0x7fffffffffffffff", "1", nil},
+		{"i64.lt_u", "0x8000000000000000 0x7fffffffffffffff", "0", nil},
+		{"i64.gt_s", "0x7fffffffffffffff 0x8000000000000000", "1", nil},
+		{"i64.gt_u", "0x7fffffffffffffff 0x8000000000000000", "0", nil},
+		{"i64.le_s", "0x8000000000000000 -1", "1", nil},
+		{"i64.le_u", "0x8000000000000000 -1", "1", nil},
+		{"i64.ge_s", "-1 0x8000000000000000", "1", nil},
+		{"i64.ge_u", "-1 0x8000000000000000", "1", nil},
+
+		// conversions.wast
+		{"i64.extend_i32_s", "0", "0", nil},
+		{"i64.extend_i32_s", "10000", "10000", nil},
+		{"i64.extend_i32_s", "-10000", "-10000", nil},
+		{"i64.extend_i32_s", "-1", "-1", nil},
+		{"i64.extend_i32_s", "0x7fffffff", "0x000000007fffffff", nil},
+		{"i64.extend_i32_s", "0x80000000", "0xffffffff80000000", nil},
+		{"i64.extend_i32_u", "-10000", "0x00000000ffffd8f0", nil},
+		{"i64.extend_i32_u", "-1", "0xffffffff", nil},
+		{"i64.extend_i32_u", "0x80000000", "0x0000000080000000", nil},
+		{"i32.wrap_i64", "-1", "-1", nil},
+		{"i32.wrap_i64", "-100000", "-100000", nil},
+		{"i32.wrap_i64", "0x80000000", "0x80000000", nil},
+		{"i32.wrap_i64", "0xffffffff7fffffff", "0x7fffffff", nil},
+		{"i32.wrap_i64", "0xffffffff00000000", "0", nil},
+		{"i32.wrap_i64", "0x0000000100000000", "0", nil},
+		{"i32.wrap_i64", "0x00000000ffffffff", "-1", nil},
+		{"i32.wrap_i64", "0xfedcba9876543210", "0x76543210", nil},
+	}
+	for _, tt := range tests {
+		code, ok := wasmSpecOps[tt.op]
+		if !ok {
+			t.Fatalf("unknown instruction %s", tt.op)
+		}
+		operands64, result64 := wasmSpecTypes(tt.op)
+		body := [][]byte{i32c(0)}
+		for _, arg := range strings.Fields(tt.args) {
+			v := wasmSpecValue(t, arg)
+			if operands64 {
+				body = append(body, i64c(int64(v)))
+			} else {
+				body = append(body, i32c(int64(int32(uint32(v)))))
+			}
+		}
+		body = append(body, op(code), storeResult(result64))
+
+		have, err := runWasmSpecBody(t, body, result64)
+		if err != tt.err {
+			t.Errorf("%s %s: error mismatch: have %v, want %v", tt.op, tt.args, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		want := wasmSpecValue(t, tt.want)
+		if !result64 {
+			want = uint64(uint32(want))
+		}
+		if have != want {
+			t.Errorf("%s %s: result mismatch: have %#x, want %#x", tt.op, tt.args, have, want)
+		}
+	}
+}
+
+func TestEWASMSpecMemory(t *testing.T) {
+	// The data segment starts memory with ff 80 00 80 ff ff ff ff.
+	data := []byte{0xff, 0x80, 0x00, 0x80, 0xff, 0xff, 0xff, 0xff}
+	tests := []struct {
+		name     string
+		body     [][]byte
+		result64 bool
+		want     uint64
+		err      error
+	}{
+		// memory.wast and address.wast
+		{"i32.load8_s", [][]byte{i32c(0), i32c(0), op(wasmI32Load8S, 0, 0)}, false, 0xffffffff, nil},
+		{"i32.load8_u", [][]byte{i32c(0), i32c(0), op(wasmI32Load8U, 0, 0)}, false, 0xff, nil},
+		{"i32.load16_s", [][]byte{i32c(0), i32c(0), op(wasmI32Load16S, 1, 0)}, false, 0xffff80ff, nil},
+		{"i32.load16_u", [][]byte{i32c(0), i32c(0), op(wasmI32Load16U, 1, 0)}, false, 0x80ff, nil},
+		{"i32.load offset", [][]byte{i32c(0), i32c(0), op(wasmI32Load, 2, 4)}, false, 0xffffffff, nil},
+		{"i64.load8_s", [][]byte{i32c(0), i32c(1), op(wasmI64Load8S, 0, 0)}, true, 0xffffffffffffff80, nil},
+		{"i64.load16_u", [][]byte{i32c(0), i32c(2), op(wasmI64Load16U, 1, 0)}, true, 0x8000, nil},
+		{"i64.load32_s", [][]byte{i32c(0), i32c(0), op(wasmI64Load32S, 2, 0)}, true, 0xffffffff800080ff, nil},
+		{"i64.load32_u", [][]byte{i32c(0), i32c(0), op(wasmI64Load32U, 2, 0)}, true, 0x800080ff, nil},
+		{"i64.load", [][]byte{i32c(0), i32c(0), op(wasmI64Load, 3, 0)}, true, 0xffffffff800080ff, nil},
+		{"i32.store8 wraps", [][]byte{
+			i32c(16), i32c(0x1234), op(wasmI32Store8, 0, 0),
+			i32c(0), i32c(16), op(wasmI32Load, 2, 0),
+		}, false, 0x34, nil},
+		{"i64.store16 wraps", [][]byte{
+			i32c(16), i64c(-1), op(wasmI64Store16, 1, 0),
+			i32c(0), i32c(16), op(wasmI64Load, 3, 0),
+		}, true, 0xffff, nil},
+		{"i64.store32 wraps", [][]byte{
+			i32c(16), i64c(-2), op(wasmI64Store32, 2, 0),
+			i32c(0), i32c(16), op(wasmI64Load, 3, 0),
+		}, true, 0xfffffffe, nil},
+		{"load at memory end", [][]byte{i32c(0), i32c(wasmPageSize - 4), op(wasmI32Load, 2, 0)}, false, 0, nil},
+		{"load past memory end", [][]byte{i32c(0), i32c(wasmPageSize - 3), op(wasmI32Load, 2, 0)}, false, 0, errWasmMemory},
+		{"offset past memory end", [][]byte{i32c(0), i32c(0), op(wasmI32Load8U, 0, 0x80, 0x80, 0x04)}, false, 0, errWasmMemory},
+		{"address wraps not", [][]byte{i32c(0), i32c(-1), op(wasmI32Load8U, 0, 1)}, false, 0, errWasmMemory},
+		{"store past memory end", [][]byte{i32c(wasmPageSize - 7), i64c(0), op(wasmI64Store, 3, 0), i32c(0), i32c(0)}, false, 0, errWasmMemory},
+
+		// memory_size.wast and memory_grow.wast
+		{"memory.size", [][]byte{i32c(0), op(wasmMemorySize, 0)}, false, 1, nil},
+		{"memory.grow", [][]byte{i32c(0), i32c(2), op(wasmMemoryGrow, 0)}, false, 1, nil},
+		{"memory.grow zero", [][]byte{i32c(0), i32c(0), op(wasmMemoryGrow, 0)}, false, 1, nil},
+		{"memory.grow size", [][]byte{
+			i32c(3), op(wasmMemoryGrow, 0), op(wasmDrop),
+			i32c(0), op(wasmMemorySize, 0),
+		}, false, 4, nil},
+		{"memory.grow past maximum", [][]byte{i32c(0), i32c(wasmMaxPages), op(wasmMemoryGrow, 0)}, false, 0xffffffff, nil},
+		{"memory.grow huge", [][]byte{i32c(0), i32c(-1), op(wasmMemoryGrow, 0)}, false, 0xffffffff, nil},
+		{"grown memory access", [][]byte{
+			i32c(1), op(wasmMemoryGrow, 0), op(wasmDrop),
+			i32c(2*wasmPageSize - 4), i32c(42), op(wasmI32Store, 2, 0),
+			i32c(0), i32c(2*wasmPageSize - 4), op(wasmI32Load, 2, 0),
+		}, false, 42, nil},
+	}
+	for _, tt := range tests {
+		var (
+			caller = AccountRef(common.HexToAddress("0x1"))
+			wasm   = common.HexToAddress("0x1000")
+			size   = int64(4)
+		)
+		if tt.result64 {
+			size = 8
+		}
+		body := append(tt.body, storeResult(tt.result64), i32c(0), i32c(size), op(wasmCall, 0))
+		evm := newEWASMTestEVM(t, 10, "")
+		evm.StateDB.SetCode(wasm, buildWasmModule([]string{"finish"}, []testWasmFunc{{body: body}}, data))
+
+		ret, _, err := evm.Call(caller, wasm, nil, 10000000, new(big.Int))
+		if err != tt.err {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		var have uint64
+		if tt.result64 {
+			have = binary.LittleEndian.Uint64(ret)
+		} else {
+			have = uint64(binary.LittleEndian.Uint32(ret))
+		}
+		if have != tt.want {
+			t.Errorf("%s: result mismatch: have %#x, want %#x", tt.name, have, tt.want)
+		}
+	}
+}
+
+func TestEWASMSpecControl(t *testing.T) {
+	// brTable returns a module function branching with br_table on its
+	// parameter to blocks returning 10, 11 and 12, the last being the default.
+	brTable := testWasmFunc{params: "i", results: "i", body: [][]byte{
+		op(wasmBlock, wasmBlockEmpty),
+		op(wasmBlock, wasmBlockEmpty),
+		op(wasmBlock, wasmBlockEmpty),
+		op(wasmLocalGet, 0), op(wasmBrTable, 2, 0, 1, 2),
+		op(wasmEnd), i32c(10), op(wasmReturn),
+		op(wasmEnd), i32c(11), op(wasmReturn),
+		op(wasmEnd), i32c(12),
+	}}
+	tests := []struct {
+		name  string
+		funcs []testWasmFunc
+		want  uint32
+		err   error
+	}{
+		// br_table.wast
+		{"br_table first", []testWasmFunc{{body: [][]byte{i32c(0), i32c(0), op(wasmCall, 1)}}, brTable}, 10, nil},
+		{"br_table second", []testWasmFunc{{body: [][]byte{i32c(0), i32c(1), op(wasmCall, 1)}}, brTable}, 11, nil},
+		{"br_table default", []testWasmFunc{{body: [][]byte{i32c(0), i32c(2), op(wasmCall, 1)}}, brTable}, 12, nil},
+		{"br_table large index", []testWasmFunc{{body: [][]byte{i32c(0), i32c(-1), op(wasmCall, 1)}}, brTable}, 12, nil},
+
+		// block.wast, loop.wast, if.wast and br.wast
+		{"block result", []testWasmFunc{{body: [][]byte{
+			i32c(0), op(wasmBlock, wasmI32), i32c(1), i32c(7), op(wasmBr, 0), op(wasmEnd),
+		}}}, 7, nil},
+		{"br_if value", []testWasmFunc{{body: [][]byte{
+			i32c(0), op(wasmBlock, wasmI32), i32c(8), i32c(1), op(wasmBrIf, 0), op(wasmDrop), i32c(9), op(wasmEnd),
+		}}}, 8, nil},
+		{"br_if not taken", []testWasmFunc{{body: [][]byte{
+			i32c(0), op(wasmBlock, wasmI32), i32c(8), i32c(0), op(wasmBrIf, 0), op(wasmDrop), i32c(9), op(wasmEnd),
+		}}}, 9, nil},
+		{"nested br", []testWasmFunc{{body: [][]byte{
+			i32c(0), op(wasmBlock, wasmI32), op(wasmBlock, wasmBlockEmpty), i32c(3), op(wasmBr, 1), op(wasmEnd), i32c(4), op(wasmEnd),
+		}}}, 3, nil},
+		{"if then", []testWasmFunc{{body: [][]byte{
+			i32c(0), i32c(1), op(wasmIf, wasmI32), i32c(5), op(wasmElse), i32c(6), op(wasmEnd),
+		}}}, 5, nil},
+		{"if else", []testWasmFunc{{body: [][]byte{
+			i32c(0), i32c(0), op(wasmIf, wasmI32), i32c(5), op(wasmElse), i32c(6), op(wasmEnd),
+		}}}, 6, nil},
+		{"if without else", []testWasmFunc{{body: [][]byte{
+			i32c(0), i32c(1), i32c(0), op(wasmIf, wasmBlockEmpty), op(wasmDrop), op(wasmEnd),
+		}}}, 1, nil},
+		{"loop continue", []testWasmFunc{{locals: "i", body: [][]byte{
+			i32c(0),
+			op(wasmLoop, wasmBlockEmpty),
+			op(wasmLocalGet, 0), i32c(1), op(wasmSpecOps["i32.add"]), op(wasmLocalTee, 0),
+			i32c(5), op(wasmSpecOps["i32.lt_u"]), op(wasmBrIf, 0),
+			op(wasmEnd),
+			op(wasmLocalGet, 0),
+		}}}, 5, nil},
+		{"return from nested block", []testWasmFunc{{body: [][]byte{
+			i32c(0), op(wasmCall, 1),
+		}}, {results: "i", body: [][]byte{
+			op(wasmBlock, wasmBlockEmpty), op(wasmLoop, wasmBlockEmpty), i32c(13), op(wasmReturn), op(wasmEnd), op(wasmEnd),
+			i32c(14),
+		}}}, 13, nil},
+		{"select first", []testWasmFunc{{body: [][]byte{i32c(0), i32c(1), i32c(2), i32c(1), op(wasmSelect)}}}, 1, nil},
+		{"select second", []testWasmFunc{{body: [][]byte{i32c(0), i32c(1), i32c(2), i32c(0), op(wasmSelect)}}}, 2, nil},
+
+		// call.wast
+		{"call arguments", []testWasmFunc{{body: [][]byte{
+			i32c(0), i32c(10), i32c(3), op(wasmCall, 2),
+		}}, {}, {params: "ii", results: "i", body: [][]byte{
+			op(wasmLocalGet, 0), op(wasmLocalGet, 1), op(wasmSpecOps["i32.sub"]),
+		}}}, 7, nil},
+		{"call stack exhaustion", []testWasmFunc{{body: [][]byte{
+			i32c(0), op(wasmCall, 1),
+		}}, {results: "i", body: [][]byte{op(wasmCall, 1)}}}, 0, errWasmFrames},
+
+		// unreachable.wast
+		{"unreachable", []testWasmFunc{{body: [][]byte{i32c(0), op(wasmUnreachable)}}}, 0, errWasmUnreachable},
+		{"unreachable in callee", []testWasmFunc{{body: [][]byte{
+			i32c(0), op(wasmCall, 1),
+		}}, {results: "i", body: [][]byte{op(wasmUnreachable)}}}, 0, errWasmUnreachable},
+	}
+	for _, tt := range tests {
+		var (
+			caller = AccountRef(common.HexToAddress("0x1"))
+			wasm   = common.HexToAddress("0x1000")
+		)
+		// Calls to the functions shift by the finish import.
+		funcs := make([]testWasmFunc, len(tt.funcs))
+		copy(funcs, tt.funcs)
+		funcs[0].body = append(shiftWasmCalls(funcs[0].body), storeResult(false), i32c(0), i32c(4), op(wasmCall, 0))
+		for i := 1; i < len(funcs); i++ {
+			funcs[i].body = shiftWasmCalls(funcs[i].body)
+		}
+		evm := newEWASMTestEVM(t, 10, "")
+		evm.StateDB.SetCode(wasm, buildWasmModule([]string{"finish"}, funcs, nil))
+
+		ret, _, err := evm.Call(caller, wasm, nil, 10000000, new(big.Int))
+		if err != tt.err {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if have := binary.LittleEndian.Uint32(ret); have != tt.want {
+			t.Errorf("%s: result mismatch: have %d, want %d", tt.name, have, tt.want)
+		}
+	}
+}
+
+// shiftWasmCalls renumbers the calls of the instructions of a test function
+// written without imports to a module importing finish.
+func shiftWasmCalls(body [][]byte) [][]byte {
+	shifted := make([][]byte, len(body))
+	for i, ins := range body {
+		if len(ins) == 2 && ins[0] == wasmCall {
+			ins = op(wasmCall, ins[1]+1)
+		}
+		shifted[i] = ins
+	}
+	return shifted
+}
+
+// Tests that the engine traps on opcodes it does not know instead of
+// skipping them, even if they slipped through module decoding.
+func TestEWASMEngineUnknownOpcode(t *testing.T) {
+	mod, err := decodeWasmModule(buildWasmModule(nil, []testWasmFunc{{body: [][]byte{op(wasmNop)}}}, nil))
+	if err != nil {
+		t.Fatalf("failed to decode module: %v", err)
+	}
+	for _, code := range []byte{0x43, 0x92, 0xc0, 0xfc} {
+		f := *mod.funcs[0]
+		f.code = bytes.Replace(common.CopyBytes(f.code), []byte{wasmNop}, []byte{code}, 1)
+
+		contract := NewContract(AccountRef(common.Address{}), AccountRef(common.Address{}), new(big.Int), 100000)
+		inst := &wasmInstance{contract: contract, mod: mod}
+		if err := inst.exec(&f, nil, 0); err != errWasmOpcode {
+			t.Errorf("opcode %#x: error mismatch: have %v, want %v", code, err, errWasmOpcode)
+		}
+	}
+}
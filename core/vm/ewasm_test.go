@@ -0,0 +1,114 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/state"
+	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/params"
+)
+
+var ewasmTestOutput = []byte("ewasm")
+
+type echoEWASMEngine struct{}
+
+func (e *echoEWASMEngine) Run(contract *Contract, input []byte, readOnly bool) ([]byte, error) {
+	return ewasmTestOutput, nil
+}
+
+func (e *echoEWASMEngine) CanRun(code []byte) bool {
+	return isEWASMCode(code)
+}
+
+func init() {
+	RegisterEWASMEngine("echo", func(*EVM, Config) Interpreter {
+		return &echoEWASMEngine{}
+	})
+}
+
+func newEWASMTestEVM(t *testing.T, round uint64, engine string) *EVM {
+	stateDB, err := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	config := *params.TestChainConfig
+	config.EWASMRound = big.NewInt(10)
+
+	context := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: big.NewInt(0),
+		Round:       new(big.Int).SetUint64(round),
+	}
+	return NewEVM(context, stateDB, &config, Config{EWASMInterpreter: engine})
+}
+
+func TestEWASMRoundGating(t *testing.T) {
+	if evm := newEWASMTestEVM(t, 9, "echo"); len(evm.interpreters) != 1 {
+		t.Errorf("ewasm enabled before fork round: %d interpreters", len(evm.interpreters))
+	}
+	evm := newEWASMTestEVM(t, 10, "echo")
+	if len(evm.interpreters) != 2 {
+		t.Fatalf("ewasm not enabled at fork round: %d interpreters", len(evm.interpreters))
+	}
+	if _, ok := evm.Interpreter().(*EVMInterpreter); !ok {
+		t.Errorf("default interpreter is not the EVM interpreter")
+	}
+}
+
+func TestEWASMCallRouting(t *testing.T) {
+	var (
+		caller = AccountRef(common.HexToAddress("0x1"))
+		wasm   = common.HexToAddress("0x1000")
+	)
+	evm := newEWASMTestEVM(t, 10, "echo")
+	evm.StateDB.SetCode(wasm, append(common.CopyBytes(ewasmMagic), 0x01, 0x00, 0x00, 0x00))
+
+	ret, _, err := evm.Call(caller, wasm, nil, 100000, new(big.Int))
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if !bytes.Equal(ret, ewasmTestOutput) {
+		t.Errorf("call not routed to ewasm engine: got %x", ret)
+	}
+
+	evm = newEWASMTestEVM(t, 10, "missing")
+	evm.StateDB.SetCode(wasm, append(common.CopyBytes(ewasmMagic), 0x01, 0x00, 0x00, 0x00))
+	if _, _, err := evm.Call(caller, wasm, nil, 100000, new(big.Int)); err != ErrEWASMEngineUnavailable {
+		t.Errorf("unexpected error without engine: %v", err)
+	}
+}
+
+func TestEWASMDeploymentRules(t *testing.T) {
+	caller := AccountRef(common.HexToAddress("0x1"))
+	// PUSH4 0x0061736d PUSH1 0 MSTORE PUSH1 4 PUSH1 28 RETURN
+	initCode := common.Hex2Bytes("630061736d6000526004601cf3")
+
+	evm := newEWASMTestEVM(t, 9, "echo")
+	if _, _, _, err := evm.Create(caller, initCode, 100000, new(big.Int)); err != nil {
+		t.Errorf("deployment before fork round failed: %v", err)
+	}
+	evm = newEWASMTestEVM(t, 10, "echo")
+	if _, _, _, err := evm.Create(caller, initCode, 100000, new(big.Int)); err != ErrInvalidEWASMDeployment {
+		t.Errorf("EVM init code deployed ewasm code: %v", err)
+	}
+}
@@ -49,6 +49,9 @@ type Config struct {
 
 	// Whether or not we are a block proposer.
 	IsBlockProposer bool
+
+	// Whether receipts record the accounts touched by their transaction.
+	RecordTouchedAccounts bool
 }
 
 // Interpreter is used to run Ethereum based contracts and will utilise the
@@ -142,7 +145,7 @@ func (in *EVMInterpreter) enforceRestrictions(op OpCode, operation operation, st
 //
 // It's important to note that any errors returned by the interpreter should be
 // considered a revert-and-consume-all-gas operation except for
-// errExecutionReverted which means revert-and-keep-gas-left.
+// ErrExecutionReverted which means revert-and-keep-gas-left.
 func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (ret []byte, err error) {
 	if in.intPool == nil {
 		in.intPool = poolOfIntPools.get()
@@ -276,7 +279,7 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		case err != nil:
 			return nil, err
 		case operation.reverts:
-			return res, errExecutionReverted
+			return res, ErrExecutionReverted
 		case operation.halts:
 			return res, nil
 		case !operation.jumps:
@@ -0,0 +1,194 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build none
+
+/*
+
+   The mklayout tool documents the storage layout of the governance contract
+   as read by the GovernanceState accessors in oracle_contracts.go. Each
+   storage location is listed with its slot and the Solidity declaration
+   found in the doc comment of the accessors using it.
+
+       go run mklayout.go -in oracle_contracts.go -out governance_layout.md
+
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"strings"
+)
+
+var (
+	inFlag  = flag.String("in", "oracle_contracts.go", "Go source declaring the GovernanceState accessors")
+	outFlag = flag.String("out", "governance_layout.md", "Markdown file to write the layout to")
+
+	// solidityDecl matches the Solidity state variable declarations the
+	// accessors are documented with.
+	solidityDecl = regexp.MustCompile(`^(u?int[0-9]*|bytes[0-9]*|address|bool|string|mapping\(.*\)|Node)(\[\])? .*`)
+)
+
+func main() {
+	flag.Parse()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, *inFlag, nil, parser.ParseComments)
+	if err != nil {
+		log.Fatal(err)
+	}
+	locs, version := storageLocations(file)
+	if len(locs) == 0 {
+		log.Fatalf("no storage locations found in %s", *inFlag)
+	}
+	decls := accessorDeclarations(file, locs)
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "<!-- Code generated by mklayout.go. DO NOT EDIT. -->\n\n")
+	fmt.Fprintf(buf, "# Governance contract storage layout\n\n")
+	fmt.Fprintf(buf, "Layout version: %s\n\n", version)
+	fmt.Fprintf(buf, "| Slot | Location | Declaration |\n")
+	fmt.Fprintf(buf, "|-----:|----------|-------------|\n")
+	for i, name := range locs {
+		decl := decls[name]
+		if decl == "" {
+			decl = "-"
+		}
+		fmt.Fprintf(buf, "| %d | `%s` | `%s` |\n", i, name, decl)
+	}
+	if err := ioutil.WriteFile(*outFlag, buf.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// storageLocations returns the names of the sequential storage locations in
+// slot order and the declared layout version.
+func storageLocations(file *ast.File) ([]string, string) {
+	var (
+		locs    []string
+		version = "unknown"
+	)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for i, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for _, name := range vs.Names {
+				if name.Name == "GovernanceLayoutVersion" && len(vs.Values) > 0 {
+					if lit, ok := vs.Values[0].(*ast.BasicLit); ok {
+						version = lit.Value
+					}
+				}
+			}
+			if i == 0 && (len(vs.Values) != 1 || !isIota(vs.Values[0]) ||
+				!strings.HasSuffix(vs.Names[0].Name, "Loc")) {
+				break
+			}
+			for _, name := range vs.Names {
+				if strings.HasSuffix(name.Name, "Loc") {
+					locs = append(locs, name.Name)
+				}
+			}
+		}
+	}
+	return locs, version
+}
+
+func isIota(expr ast.Expr) bool {
+	id, ok := expr.(*ast.Ident)
+	return ok && id.Name == "iota"
+}
+
+// accessorDeclarations maps the storage locations to the Solidity declaration
+// documenting the first accessor reading or writing them. A declaration
+// documenting a type, like the one of the nodes array, applies to the
+// undocumented accessors following it.
+func accessorDeclarations(file *ast.File, locs []string) map[string]string {
+	known := make(map[string]bool)
+	for _, name := range locs {
+		known[name] = true
+	}
+	var (
+		decls   = make(map[string]string)
+		pending string
+	)
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			if decl.Tok == token.TYPE {
+				if sol := declaration(decl.Doc); sol != "" {
+					pending = sol
+				}
+			}
+		case *ast.FuncDecl:
+			if decl.Body == nil || !isStateAccessor(decl) {
+				continue
+			}
+			sol := declaration(decl.Doc)
+			if sol != "" {
+				pending = ""
+			} else if sol = pending; sol == "" {
+				continue
+			}
+			var loc string
+			ast.Inspect(decl.Body, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && loc == "" && known[id.Name] {
+					loc = id.Name
+				}
+				return loc == ""
+			})
+			if loc != "" && decls[loc] == "" {
+				decls[loc] = sol
+			}
+		}
+	}
+	return decls
+}
+
+// declaration returns the Solidity declaration ending the doc comment, if any.
+func declaration(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSpace(doc.Text()), "\n")
+	sol := strings.TrimSuffix(strings.TrimSpace(lines[len(lines)-1]), ";")
+	if !solidityDecl.MatchString(sol) {
+		return ""
+	}
+	return sol
+}
+
+func isStateAccessor(fn *ast.FuncDecl) bool {
+	if fn.Recv == nil || len(fn.Recv.List) != 1 {
+		return false
+	}
+	star, ok := fn.Recv.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	id, ok := star.X.(*ast.Ident)
+	return ok && id.Name == "GovernanceState"
+}
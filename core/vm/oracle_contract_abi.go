@@ -1137,6 +1137,67 @@ const GovernanceABIJSON = `
     "stateMutability": "view",
     "type": "function"
   },
+  {
+    "constant": true,
+    "inputs": [],
+    "name": "idleBlockInterval",
+    "outputs": [
+      {
+        "name": "",
+        "type": "uint256"
+      }
+    ],
+    "payable": false,
+    "stateMutability": "view",
+    "type": "function"
+  },
+  {
+    "constant": false,
+    "inputs": [
+      {
+        "name": "IdleBlockInterval",
+        "type": "uint256"
+      }
+    ],
+    "name": "updateIdleBlockInterval",
+    "outputs": [],
+    "payable": false,
+    "stateMutability": "nonpayable",
+    "type": "function"
+  },
+  {
+    "constant": true,
+    "inputs": [
+      {
+        "name": "",
+        "type": "address"
+      }
+    ],
+    "name": "rewardAddress",
+    "outputs": [
+      {
+        "name": "",
+        "type": "address"
+      }
+    ],
+    "payable": false,
+    "stateMutability": "view",
+    "type": "function"
+  },
+  {
+    "constant": false,
+    "inputs": [
+      {
+        "name": "RewardAddress",
+        "type": "address"
+      }
+    ],
+    "name": "updateRewardAddress",
+    "outputs": [],
+    "payable": false,
+    "stateMutability": "nonpayable",
+    "type": "function"
+  },
   {
     "anonymous": false,
     "inputs": [],
@@ -1177,6 +1238,23 @@ const GovernanceABIJSON = `
     "name": "NodeOwnershipTransfered",
     "type": "event"
   },
+  {
+    "anonymous": false,
+    "inputs": [
+      {
+        "indexed": true,
+        "name": "NodeAddress",
+        "type": "address"
+      },
+      {
+        "indexed": true,
+        "name": "RewardAddress",
+        "type": "address"
+      }
+    ],
+    "name": "RewardAddressUpdated",
+    "type": "event"
+  },
   {
     "anonymous": false,
     "inputs": [
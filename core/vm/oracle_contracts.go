@@ -102,8 +102,22 @@ const (
 	isConsortiumLoc
 	addressWhitelistLoc
 	whitelistOffsetByAddressLoc
+	idleBlockIntervalLoc
+	rewardAddressLoc
 )
 
+//go:generate go run mklayout.go -in oracle_contracts.go -out governance_layout.md
+
+// GovernanceLayoutVersion is the version of the storage layout the
+// GovernanceState accessors are written against. Bump it and regenerate
+// governance_layout.md whenever a storage location changes meaning.
+const GovernanceLayoutVersion = 1
+
+// layoutVersionLoc stores the layout version marker of the deployed contract.
+// It is hashed away from the sequential locations, so appending variables
+// never moves it.
+var layoutVersionLoc = crypto.Keccak256Hash([]byte("governance.layoutVersion"))
+
 func publicKeyToNodeKeyAddress(pkBytes []byte) (common.Address, error) {
 	pk, err := crypto.UnmarshalPubkey(pkBytes)
 	if err != nil {
@@ -347,7 +361,6 @@ func (s *GovernanceState) DecTotalStaked(amount *big.Int) {
 // }
 //
 // Node[] nodes;
-
 type nodeInfo struct {
 	Owner      common.Address
 	PublicKey  []byte
@@ -1061,6 +1074,71 @@ func (s *GovernanceState) DeleteWhitelistOffsetByAddress(addr common.Address) {
 	s.setStateBigInt(loc, big.NewInt(0))
 }
 
+// uint256 public idleBlockInterval;
+func (s *GovernanceState) IdleBlockInterval() *big.Int {
+	return s.getStateBigInt(big.NewInt(idleBlockIntervalLoc))
+}
+func (s *GovernanceState) SetIdleBlockInterval(interval *big.Int) {
+	s.setStateBigInt(big.NewInt(idleBlockIntervalLoc), interval)
+}
+
+// mapping(address => address) public rewardAddress;
+func (s *GovernanceState) RewardAddress(owner common.Address) common.Address {
+	loc := s.getMapLoc(big.NewInt(rewardAddressLoc), owner.Bytes())
+	return common.BytesToAddress(s.getState(common.BigToHash(loc)).Bytes())
+}
+func (s *GovernanceState) SetRewardAddress(owner, addr common.Address) {
+	loc := s.getMapLoc(big.NewInt(rewardAddressLoc), owner.Bytes())
+	s.setState(common.BigToHash(loc), addr.Hash())
+}
+
+// BlockRewardAddress returns the address the rewards of the blocks proposed
+// by the node of owner are credited to, owner itself unless configured.
+func (s *GovernanceState) BlockRewardAddress(owner common.Address) common.Address {
+	if addr := s.RewardAddress(owner); addr != (common.Address{}) {
+		return addr
+	}
+	return owner
+}
+
+// LayoutVersion returns the storage layout version marker of the contract.
+// Contracts deployed before the marker was introduced carry none and are laid
+// out as version 1.
+func (s *GovernanceState) LayoutVersion() uint64 {
+	version := s.getState(layoutVersionLoc).Big()
+	switch {
+	case version.Sign() == 0:
+		return 1
+	case !version.IsUint64():
+		return math.MaxUint64
+	}
+	return version.Uint64()
+}
+func (s *GovernanceState) SetLayoutVersion(version uint64) {
+	s.setState(layoutVersionLoc, common.BigToHash(new(big.Int).SetUint64(version)))
+}
+
+// MigrateLayout migrates the storage of the contract to the layout version
+// the accessors read and writes the version marker. Contracts without a
+// marker are laid out as version 1, which needs no migration.
+func (s *GovernanceState) MigrateLayout() {
+	if s.getState(layoutVersionLoc) != (common.Hash{}) && s.LayoutVersion() >= GovernanceLayoutVersion {
+		return
+	}
+	s.SetLayoutVersion(GovernanceLayoutVersion)
+}
+
+// CheckLayoutVersion returns an error if the contract is laid out in a
+// different version than the accessors read, e.g. after an upgrade done
+// without updating the node.
+func (s *GovernanceState) CheckLayoutVersion() error {
+	if version := s.LayoutVersion(); version != GovernanceLayoutVersion {
+		return fmt.Errorf("governance storage layout version mismatch (%d / %d)",
+			version, GovernanceLayoutVersion)
+	}
+	return nil
+}
+
 // Initialize initializes governance contract state.
 func (s *GovernanceState) Initialize(config *params.DexconConfig, totalSupply *big.Int) {
 	if config.NextHalvingSupply.Cmp(totalSupply) <= 0 {
@@ -1174,6 +1252,7 @@ func (s *GovernanceState) Configuration() *params.DexconConfig {
 		FineValues:        s.FineValues(),
 		AddressWhitelist:  s.AddressWhitelists(),
 		IsConsortium:      s.getStateBigInt(big.NewInt(isConsortiumLoc)).Uint64() != 0,
+		IdleBlockInterval: s.getStateBigInt(big.NewInt(idleBlockIntervalLoc)).Uint64(),
 	}
 }
 
@@ -1192,6 +1271,7 @@ func (s *GovernanceState) UpdateConfiguration(cfg *params.DexconConfig) {
 	s.setStateBigInt(big.NewInt(notaryParamBetaLoc), big.NewInt(int64(cfg.NotaryParamBeta*decimalMultiplier)))
 	s.setStateBigInt(big.NewInt(roundLengthLoc), big.NewInt(int64(cfg.RoundLength)))
 	s.setStateBigInt(big.NewInt(minBlockIntervalLoc), big.NewInt(int64(cfg.MinBlockInterval)))
+	s.setStateBigInt(big.NewInt(idleBlockIntervalLoc), new(big.Int).SetUint64(cfg.IdleBlockInterval))
 	s.SetFineValues(cfg.FineValues)
 	if cfg.IsConsortium {
 		for _, addr := range cfg.AddressWhitelist {
@@ -1262,6 +1342,16 @@ func (s *GovernanceState) emitNodeOwnershipTransfered(nodeAddr, newNodeAddr comm
 	})
 }
 
+// event RewardAddressUpdated(address indexed NodeAddress, address indexed RewardAddress);
+func (s *GovernanceState) emitRewardAddressUpdated(nodeAddr, rewardAddr common.Address) {
+	s.StateDB.AddLog(&types.Log{
+		Address: GovernanceContractAddress,
+		Topics: []common.Hash{GovernanceABI.Events["RewardAddressUpdated"].Id(),
+			nodeAddr.Hash(), rewardAddr.Hash()},
+		Data: []byte{},
+	})
+}
+
 // event NodePublicKeyReplaced(address indexed NodeAddress, bytes PublicKey);
 func (s *GovernanceState) emitNodePublicKeyReplaced(nodeAddr common.Address, pk []byte) {
 	s.StateDB.AddLog(&types.Log{
@@ -1563,12 +1653,12 @@ func (g *GovernanceContract) addDKGComplaint(comp []byte) ([]byte, error) {
 
 	// Can not add complaint if caller does not exists.
 	if offset.Cmp(big.NewInt(0)) < 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Finalized caller is not allowed to propose complaint.
 	if g.state.DKGFinalized(caller) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Calculate 2f + 1
@@ -1577,34 +1667,34 @@ func (g *GovernanceContract) addDKGComplaint(comp []byte) ([]byte, error) {
 
 	// If 2f + 1 of DKG set is finalized, one can not propose complaint anymore.
 	if g.state.DKGFinalizedsCount().Uint64() >= uint64(threshold) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	var dkgComplaint dkgTypes.Complaint
 	if err := rlp.DecodeBytes(comp, &dkgComplaint); err != nil {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	if g.state.DKGComplaintProposed(getDKGComplaintID(&dkgComplaint)) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	round := big.NewInt(int64(dkgComplaint.Round))
 	if round.Uint64() != g.evm.Round.Uint64()+1 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	if dkgComplaint.Reset != g.state.DKGResetCount(round).Uint64() {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// DKGComplaint must belongs to someone in DKG set.
 	if !g.inNotarySet(round, dkgComplaint.ProposerID) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	verified, _ := coreUtils.VerifyDKGComplaintSignature(&dkgComplaint)
 	if !verified {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	mpkOffset := g.state.DKGMasterPublicKeyOffset(Bytes32(dkgComplaint.PrivateShare.ProposerID.Hash))
@@ -1613,22 +1703,22 @@ func (g *GovernanceContract) addDKGComplaint(comp []byte) ([]byte, error) {
 	// Verify DKG complaint is correct.
 	ok, err := coreUtils.VerifyDKGComplaint(&dkgComplaint, mpk)
 	if !ok || err != nil {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Fine the attacker.
 	need, err := coreUtils.NeedPenaltyDKGPrivateShare(&dkgComplaint, mpk)
 	if err != nil {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	if need {
 		node, err := g.state.GetNodeByID(dkgComplaint.PrivateShare.ProposerID)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		fineValue := g.state.FineValue(big.NewInt(FineTypeInvalidDKG))
 		if err := g.fine(node.Owner, fineValue, comp, nil); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 	}
 
@@ -1641,11 +1731,11 @@ func (g *GovernanceContract) addDKGComplaint(comp []byte) ([]byte, error) {
 func (g *GovernanceContract) addDKGMasterPublicKey(mpk []byte) ([]byte, error) {
 	var dkgMasterPK dkgTypes.MasterPublicKey
 	if err := rlp.DecodeBytes(mpk, &dkgMasterPK); err != nil {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	round := big.NewInt(int64(dkgMasterPK.Round))
 	if round.Uint64() != g.evm.Round.Uint64()+1 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	if g.state.DKGRound().Cmp(g.evm.Round) == 0 {
@@ -1656,7 +1746,7 @@ func (g *GovernanceContract) addDKGMasterPublicKey(mpk []byte) ([]byte, error) {
 
 	mpkOffset := g.state.DKGMasterPublicKeyOffset(getDKGMasterPublicKeyID(&dkgMasterPK))
 	if mpkOffset.Cmp(big.NewInt(0)) >= 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	caller := g.contract.Caller()
@@ -1664,12 +1754,12 @@ func (g *GovernanceContract) addDKGMasterPublicKey(mpk []byte) ([]byte, error) {
 
 	// Can not add dkg mpk if not staked.
 	if offset.Cmp(big.NewInt(0)) < 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// MPKReady caller is not allowed to propose mpk.
 	if g.state.DKGMPKReady(caller) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Calculate 2f + 1
@@ -1678,21 +1768,21 @@ func (g *GovernanceContract) addDKGMasterPublicKey(mpk []byte) ([]byte, error) {
 
 	// If 2f + 1 of DKG set is mpk ready, one can not propose mpk anymore.
 	if g.state.DKGMPKReadysCount().Uint64() >= uint64(threshold) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	if dkgMasterPK.Reset != g.state.DKGResetCount(round).Uint64() {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// DKGMasterPublicKey must belongs to someone in DKG set.
 	if !g.inNotarySet(round, dkgMasterPK.ProposerID) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	verified, _ := coreUtils.VerifyDKGMasterPublicKeySignature(&dkgMasterPK)
 	if !verified {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	mpkOffset = g.state.LenDKGMasterPublicKeys()
@@ -1707,25 +1797,25 @@ func (g *GovernanceContract) addDKGMPKReady(ready []byte) ([]byte, error) {
 
 	var dkgReady dkgTypes.MPKReady
 	if err := rlp.DecodeBytes(ready, &dkgReady); err != nil {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	round := big.NewInt(int64(dkgReady.Round))
 	if round.Uint64() != g.evm.Round.Uint64()+1 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	if dkgReady.Reset != g.state.DKGResetCount(round).Uint64() {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// DKGFInalize must belongs to someone in DKG set.
 	if !g.inNotarySet(round, dkgReady.ProposerID) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	verified, _ := coreUtils.VerifyDKGMPKReadySignature(&dkgReady)
 	if !verified {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	if !g.state.DKGMPKReady(caller) {
@@ -1741,25 +1831,25 @@ func (g *GovernanceContract) addDKGFinalize(finalize []byte) ([]byte, error) {
 
 	var dkgFinalize dkgTypes.Finalize
 	if err := rlp.DecodeBytes(finalize, &dkgFinalize); err != nil {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	round := big.NewInt(int64(dkgFinalize.Round))
 	if round.Uint64() != g.evm.Round.Uint64()+1 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	if dkgFinalize.Reset != g.state.DKGResetCount(round).Uint64() {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// DKGFInalize must belongs to someone in DKG set.
 	if !g.inNotarySet(round, dkgFinalize.ProposerID) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	verified, _ := coreUtils.VerifyDKGFinalizeSignature(&dkgFinalize)
 	if !verified {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	if !g.state.DKGFinalized(caller) {
@@ -1784,25 +1874,25 @@ func (g *GovernanceContract) addDKGSuccess(success []byte) ([]byte, error) {
 
 	var dkgSuccess dkgTypes.Success
 	if err := rlp.DecodeBytes(success, &dkgSuccess); err != nil {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	round := big.NewInt(int64(dkgSuccess.Round))
 	if round.Uint64() != g.evm.Round.Uint64()+1 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	if dkgSuccess.Reset != g.state.DKGResetCount(round).Uint64() {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// DKGFInalize must belongs to someone in DKG set.
 	if !g.inNotarySet(round, dkgSuccess.ProposerID) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	verified, _ := coreUtils.VerifyDKGSuccessSignature(&dkgSuccess)
 	if !verified {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	if !g.state.DKGSuccess(caller) {
@@ -1816,7 +1906,7 @@ func (g *GovernanceContract) addDKGSuccess(success []byte) ([]byte, error) {
 func (g *GovernanceContract) updateConfiguration(cfg *rawConfigStruct) ([]byte, error) {
 	// Only owner can update configuration.
 	if g.contract.Caller() != g.state.Owner() {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Sanity checks.
@@ -1828,7 +1918,7 @@ func (g *GovernanceContract) updateConfiguration(cfg *rawConfigStruct) ([]byte,
 		cfg.LambdaDKG.Cmp(big.NewInt(0)) <= 0 ||
 		cfg.RoundLength.Cmp(big.NewInt(0)) <= 0 ||
 		cfg.MinBlockInterval.Cmp(big.NewInt(0)) <= 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	g.state.UpdateConfigurationRaw(cfg)
@@ -1837,12 +1927,35 @@ func (g *GovernanceContract) updateConfiguration(cfg *rawConfigStruct) ([]byte,
 	return nil, nil
 }
 
+func (g *GovernanceContract) updateIdleBlockInterval(interval *big.Int) ([]byte, error) {
+	if g.contract.Value().Cmp(big.NewInt(0)) > 0 {
+		return nil, ErrExecutionReverted
+	}
+
+	// Only owner can update configuration.
+	if g.contract.Caller() != g.state.Owner() {
+		return nil, ErrExecutionReverted
+	}
+
+	// Zero disables idle block suppression. Otherwise idle blocks can not be
+	// proposed faster than the regular ones.
+	if !interval.IsUint64() ||
+		(interval.Sign() > 0 && interval.Cmp(g.state.MinBlockInterval()) < 0) {
+		return nil, ErrExecutionReverted
+	}
+
+	g.state.SetIdleBlockInterval(interval)
+	g.state.emitConfigurationChangedEvent()
+
+	return nil, nil
+}
+
 func (g *GovernanceContract) register(
 	publicKey []byte, name, email, location, url string) ([]byte, error) {
 
 	// Reject invalid inputs.
 	if len(name) >= 64 || len(email) >= 128 || len(location) >= 64 || len(url) >= 128 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	caller := g.contract.Caller()
@@ -1851,19 +1964,19 @@ func (g *GovernanceContract) register(
 
 	// Can not register if already registered.
 	if offset.Cmp(big.NewInt(0)) >= 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	nodeKeyAddr, err := publicKeyToNodeKeyAddress(publicKey)
 	if err != nil {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	offset = g.state.NodesOffsetByNodeKeyAddress(nodeKeyAddr)
 
 	// Can not register if node key is duplicate.
 	if offset.Cmp(big.NewInt(0)) >= 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	offset = g.state.LenNodes()
@@ -1895,17 +2008,17 @@ func (g *GovernanceContract) stake() ([]byte, error) {
 	value := g.contract.Value()
 
 	if big.NewInt(0).Cmp(value) == 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	offset := g.state.NodesOffsetByAddress(caller)
 	if offset.Cmp(big.NewInt(0)) < 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	node := g.state.Node(offset)
 	if node.Fined.Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	node.Staked = new(big.Int).Add(node.Staked, value)
@@ -1919,29 +2032,29 @@ func (g *GovernanceContract) stake() ([]byte, error) {
 
 func (g *GovernanceContract) unstake(amount *big.Int) ([]byte, error) {
 	if g.contract.Value().Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	caller := g.contract.Caller()
 
 	offset := g.state.NodesOffsetByAddress(caller)
 	if offset.Cmp(big.NewInt(0)) < 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	node := g.state.Node(offset)
 
 	// Can not unstake if there are unpaied fine.
 	if node.Fined.Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Can not unstake if there are unwithdrawn stake.
 	if node.Unstaked.Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	if node.Staked.Cmp(amount) < 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	node.Staked = new(big.Int).Sub(node.Staked, amount)
@@ -1959,19 +2072,19 @@ func (g *GovernanceContract) updateNodeInfo(
 	name, email, location, url string) ([]byte, error) {
 
 	if g.contract.Value().Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	caller := g.contract.Caller()
 
 	offset := g.state.NodesOffsetByAddress(caller)
 	if offset.Cmp(big.NewInt(0)) < 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Reject invalid inputs.
 	if len(name) >= 64 || len(email) >= 128 || len(location) >= 64 || len(url) >= 128 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	g.state.UpdateNodeInfo(offset, &nodeInfo{
@@ -1986,17 +2099,17 @@ func (g *GovernanceContract) updateNodeInfo(
 
 func (g *GovernanceContract) withdraw() ([]byte, error) {
 	if g.contract.Value().Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	if !g.withdrawable() {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	caller := g.contract.Caller()
 
 	offset := g.state.NodesOffsetByAddress(caller)
 	if offset.Cmp(big.NewInt(0)) < 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	node := g.state.Node(offset)
@@ -2023,7 +2136,7 @@ func (g *GovernanceContract) withdraw() ([]byte, error) {
 
 	// Return the staked fund.
 	if !g.transfer(GovernanceContractAddress, node.Owner, amount) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	g.state.emitWithdrawn(caller, amount)
 
@@ -2057,12 +2170,12 @@ func (g *GovernanceContract) withdrawable() bool {
 func (g *GovernanceContract) payFine(nodeAddr common.Address) ([]byte, error) {
 	nodeOffset := g.state.NodesOffsetByAddress(nodeAddr)
 	if nodeOffset.Cmp(big.NewInt(0)) < 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	node := g.state.Node(nodeOffset)
 	if node.Fined.Cmp(big.NewInt(0)) <= 0 || node.Fined.Cmp(g.contract.Value()) < 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	node.Fined = new(big.Int).Sub(node.Fined, g.contract.Value())
@@ -2070,7 +2183,7 @@ func (g *GovernanceContract) payFine(nodeAddr common.Address) ([]byte, error) {
 
 	// Pay the fine to governance owner.
 	if !g.transfer(GovernanceContractAddress, g.state.Owner(), g.contract.Value()) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	g.state.emitFinePaid(nodeAddr, g.contract.Value())
@@ -2080,12 +2193,12 @@ func (g *GovernanceContract) payFine(nodeAddr common.Address) ([]byte, error) {
 
 func (g *GovernanceContract) proposeCRS(nextRound *big.Int, signedCRS []byte) ([]byte, error) {
 	if g.contract.Value().Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	if nextRound.Uint64() != g.evm.Round.Uint64()+1 ||
 		g.state.CRSRound().Uint64() == nextRound.Uint64() {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	prevCRS := g.state.CRS()
@@ -2101,14 +2214,14 @@ func (g *GovernanceContract) proposeCRS(nextRound *big.Int, signedCRS []byte) ([
 		NotarySetSize: uint32(g.configNotarySetSize(nextRound).Uint64())})
 	dkgGPK, err := g.coreDKGUtil.NewGroupPublicKey(&g.state, nextRound, threshold)
 	if err != nil {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	signature := coreCrypto.Signature{
 		Type:      "bls",
 		Signature: signedCRS,
 	}
 	if !dkgGPK.VerifySignature(coreCommon.Hash(prevCRS), signature) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Save new CRS into state and increase round.
@@ -2146,7 +2259,7 @@ func (g *GovernanceContract) fine(nodeAddr common.Address, amount *big.Int, payl
 
 	nodeOffset := g.state.NodesOffsetByAddress(nodeAddr)
 	if nodeOffset.Cmp(big.NewInt(0)) < 0 {
-		return errExecutionReverted
+		return ErrExecutionReverted
 	}
 
 	// Set fined value.
@@ -2161,7 +2274,7 @@ func (g *GovernanceContract) fine(nodeAddr common.Address, amount *big.Int, payl
 
 func (g *GovernanceContract) report(reportType *big.Int, arg1, arg2 []byte) ([]byte, error) {
 	if g.contract.Value().Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	typeEnum := FineType(reportType.Uint64())
@@ -2171,52 +2284,52 @@ func (g *GovernanceContract) report(reportType *big.Int, arg1, arg2 []byte) ([]b
 	case FineTypeForkVote:
 		vote1 := new(coreTypes.Vote)
 		if err := rlp.DecodeBytes(arg1, vote1); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		vote2 := new(coreTypes.Vote)
 		if err := rlp.DecodeBytes(arg2, vote2); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		need, err := coreUtils.NeedPenaltyForkVote(vote1, vote2)
 		if !need || err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		reportedNodeID = vote1.ProposerID
 	case FineTypeForkBlock:
 		block1 := new(coreTypes.Block)
 		if err := rlp.DecodeBytes(arg1, block1); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		block2 := new(coreTypes.Block)
 		if err := rlp.DecodeBytes(arg2, block2); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		need, err := coreUtils.NeedPenaltyForkBlock(block1, block2)
 		if !need || err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		reportedNodeID = block1.ProposerID
 	default:
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	node, err := g.state.GetNodeByID(reportedNodeID)
 	if err != nil {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	g.state.emitReported(node.Owner, reportType, arg1, arg2)
 
 	fineValue := g.state.FineValue(reportType)
 	if err := g.fine(node.Owner, fineValue, arg1, arg2); err != nil {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	return nil, nil
 }
 
 func (g *GovernanceContract) resetDKG(newSignedCRS []byte) ([]byte, error) {
 	if g.contract.Value().Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	round := g.evm.Round
@@ -2234,7 +2347,7 @@ func (g *GovernanceContract) resetDKG(newSignedCRS []byte) ([]byte, error) {
 
 	// Just restart DEXON if failed at round 0.
 	if round.Cmp(big.NewInt(0)) == 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Extend the the current round.
@@ -2258,7 +2371,7 @@ func (g *GovernanceContract) resetDKG(newSignedCRS []byte) ([]byte, error) {
 	// Check if current block over 85%of current round.
 	blockHeight := g.evm.Context.BlockNumber
 	if blockHeight.Cmp(targetBlockNum) < 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Check if next DKG has not enough of success.
@@ -2283,12 +2396,12 @@ func (g *GovernanceContract) resetDKG(newSignedCRS []byte) ([]byte, error) {
 
 			// DKG success.
 			if err == nil {
-				return nil, errExecutionReverted
+				return nil, ErrExecutionReverted
 			}
 			switch err {
 			case dkgTypes.ErrNotReachThreshold, dkgTypes.ErrInvalidThreshold:
 			default:
-				return nil, errExecutionReverted
+				return nil, ErrExecutionReverted
 			}
 		}
 	}
@@ -2301,7 +2414,7 @@ func (g *GovernanceContract) resetDKG(newSignedCRS []byte) ([]byte, error) {
 	// Update CRS.
 	state, err := g.util.GetStateAtRound(round.Uint64())
 	if err != nil {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	prevCRS := state.CRS()
 
@@ -2320,14 +2433,14 @@ func (g *GovernanceContract) resetDKG(newSignedCRS []byte) ([]byte, error) {
 		coreUtils.GetDKGThreshold(&coreTypes.Config{
 			NotarySetSize: uint32(g.configNotarySetSize(round).Uint64())}))
 	if err != nil {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	signature := coreCrypto.Signature{
 		Type:      "bls",
 		Signature: newSignedCRS,
 	}
 	if !dkgGPK.VerifySignature(coreCommon.Hash(prevCRS), signature) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Clear DKG states for next round.
@@ -2349,10 +2462,23 @@ func (g *GovernanceContract) resetDKG(newSignedCRS []byte) ([]byte, error) {
 	return nil, nil
 }
 
+// methodActivated returns whether the fork introducing the method is active
+// at the current block.
+func (g *GovernanceContract) methodActivated(name string) bool {
+	config, number := g.evm.ChainConfig(), g.evm.BlockNumber
+	switch name {
+	case "updateRewardAddress", "rewardAddress":
+		return config.IsRewardAddress(number)
+	case "updateIdleBlockInterval", "idleBlockInterval":
+		return config.IsIdleBlockInterval(number)
+	}
+	return true
+}
+
 // Run executes governance contract.
 func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (ret []byte, err error) {
 	if len(input) < 4 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Initialize contract state.
@@ -2364,7 +2490,12 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 	// Parse input.
 	method, exists := GovernanceABI.Sig2Method[string(input[:4])]
 	if !exists {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
+	}
+
+	// Methods introduced by a fork do not exist before it activates.
+	if !g.methodActivated(method.Name) {
+		return nil, ErrExecutionReverted
 	}
 
 	arguments := input[4:]
@@ -2374,57 +2505,57 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 	case "addDKGComplaint":
 		var Complaint []byte
 		if err := method.Inputs.Unpack(&Complaint, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.addDKGComplaint(Complaint)
 	case "addDKGMasterPublicKey":
 		var PublicKey []byte
 		if err := method.Inputs.Unpack(&PublicKey, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.addDKGMasterPublicKey(PublicKey)
 	case "addDKGMPKReady":
 		var MPKReady []byte
 		if err := method.Inputs.Unpack(&MPKReady, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.addDKGMPKReady(MPKReady)
 	case "addDKGFinalize":
 		var Finalize []byte
 		if err := method.Inputs.Unpack(&Finalize, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.addDKGFinalize(Finalize)
 	case "addDKGSuccess":
 		var Success []byte
 		if err := method.Inputs.Unpack(&Success, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.addDKGSuccess(Success)
 	case "addToWhitelist":
 		var address common.Address
 		if err := method.Inputs.Unpack(&address, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		offset, err := g.addToWhitelist(address)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		res, err := method.Outputs.Pack(offset)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "nodesLength":
 		res, err := method.Outputs.Pack(g.state.LenNodes())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "payFine":
 		address := common.Address{}
 		if err := method.Inputs.Unpack(&address, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.payFine(address)
 	case "proposeCRS":
@@ -2433,21 +2564,21 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 			SignedCRS []byte
 		}{}
 		if err := method.Inputs.Unpack(&args, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.proposeCRS(args.Round, args.SignedCRS)
 	case "removeFromWhitelist":
 		var address common.Address
 		if err := method.Inputs.Unpack(&address, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		offset, err := g.removeFromWhitelist(address)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		res, err := method.Outputs.Pack(offset)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "report":
@@ -2457,7 +2588,7 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 			Arg2 []byte
 		}{}
 		if err := method.Inputs.Unpack(&args, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.report(args.Type, args.Arg1, args.Arg2)
 	case "resetDKG":
@@ -2465,7 +2596,7 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 			NewSignedCRS []byte
 		}{}
 		if err := method.Inputs.Unpack(&args, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.resetDKG(args.NewSignedCRS)
 	case "register":
@@ -2477,7 +2608,7 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 			Url       string
 		}{}
 		if err := method.Inputs.Unpack(&args, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.register(args.PublicKey, args.Name, args.Email, args.Location, args.Url)
 	case "stake":
@@ -2485,13 +2616,19 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 	case "transferOwnership":
 		var newOwner common.Address
 		if err := method.Inputs.Unpack(&newOwner, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.transferOwnership(newOwner)
+	case "updateRewardAddress":
+		var addr common.Address
+		if err := method.Inputs.Unpack(&addr, arguments); err != nil {
+			return nil, ErrExecutionReverted
+		}
+		return g.updateRewardAddress(addr)
 	case "transferNodeOwnership":
 		var newOwner common.Address
 		if err := method.Inputs.Unpack(&newOwner, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.transferNodeOwnership(newOwner)
 	case "transferNodeOwnershipByFoundation":
@@ -2500,21 +2637,27 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 			NewOwner common.Address
 		}{}
 		if err := method.Inputs.Unpack(&args, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.transferNodeOwnershipByFoundation(args.OldOwner, args.NewOwner)
 	case "unstake":
 		amount := new(big.Int)
 		if err := method.Inputs.Unpack(&amount, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.unstake(amount)
 	case "updateConfiguration":
 		var cfg rawConfigStruct
 		if err := method.Inputs.Unpack(&cfg, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.updateConfiguration(&cfg)
+	case "updateIdleBlockInterval":
+		interval := new(big.Int)
+		if err := method.Inputs.Unpack(&interval, arguments); err != nil {
+			return nil, ErrExecutionReverted
+		}
+		return g.updateIdleBlockInterval(interval)
 	case "updateNodeInfo":
 		args := struct {
 			Name     string
@@ -2523,13 +2666,13 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 			Url      string
 		}{}
 		if err := method.Inputs.Unpack(&args, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.updateNodeInfo(args.Name, args.Email, args.Location, args.Url)
 	case "whitelistLength":
 		res, err := method.Outputs.Pack(g.state.LenWhitelist())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "withdraw":
@@ -2537,7 +2680,7 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 	case "withdrawable":
 		res, err := method.Outputs.Pack(g.withdrawable())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	// --------------------------------
@@ -2547,242 +2690,258 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 	case "addressWhitelist":
 		offset := new(big.Int)
 		if err := method.Inputs.Unpack(&offset, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		address := g.state.AddressWhitelist(offset)
 		res, err := method.Outputs.Pack(address)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "blockGasLimit":
 		res, err := method.Outputs.Pack(g.state.BlockGasLimit())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "crs":
 		res, err := method.Outputs.Pack(g.state.CRS())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "crsRound":
 		res, err := method.Outputs.Pack(g.state.CRSRound())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "dkgComplaints":
 		offset := new(big.Int)
 		if err := method.Inputs.Unpack(&offset, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		complaint := g.state.DKGComplaint(offset)
 		res, err := method.Outputs.Pack(complaint)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "dkgComplaintsProposed":
 		id := Bytes32{}
 		if err := method.Inputs.Unpack(&id, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		proposed := g.state.DKGComplaintProposed(id)
 		res, err := method.Outputs.Pack(proposed)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "dkgFinalizeds":
 		addr := common.Address{}
 		if err := method.Inputs.Unpack(&addr, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		finalized := g.state.DKGFinalized(addr)
 		res, err := method.Outputs.Pack(finalized)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "dkgFinalizedsCount":
 		count := g.state.DKGFinalizedsCount()
 		res, err := method.Outputs.Pack(count)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "dkgSuccesses":
 		addr := common.Address{}
 		if err := method.Inputs.Unpack(&addr, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		finalized := g.state.DKGSuccess(addr)
 		res, err := method.Outputs.Pack(finalized)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "dkgSuccessesCount":
 		count := g.state.DKGSuccessesCount()
 		res, err := method.Outputs.Pack(count)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "dkgMasterPublicKeys":
 		offset := new(big.Int)
 		if err := method.Inputs.Unpack(&offset, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		mpk := g.state.DKGMasterPublicKey(offset)
 		res, err := method.Outputs.Pack(mpk)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "dkgMasterPublicKeyOffset":
 		id := Bytes32{}
 		if err := method.Inputs.Unpack(&id, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		offset := g.state.DKGMasterPublicKeyOffset(id)
 		res, err := method.Outputs.Pack(offset)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "dkgMPKReadys":
 		addr := common.Address{}
 		if err := method.Inputs.Unpack(&addr, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		ready := g.state.DKGMPKReady(addr)
 		res, err := method.Outputs.Pack(ready)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "dkgMPKReadysCount":
 		count := g.state.DKGMPKReadysCount()
 		res, err := method.Outputs.Pack(count)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "dkgResetCount":
 		round := new(big.Int)
 		if err := method.Inputs.Unpack(&round, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		res, err := method.Outputs.Pack(g.state.DKGResetCount(round))
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "dkgRound":
 		res, err := method.Outputs.Pack(g.state.DKGRound())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "finedRecords":
 		record := Bytes32{}
 		if err := method.Inputs.Unpack(&record, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		value := g.state.FineRecords(record)
 		res, err := method.Outputs.Pack(value)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "fineValues":
 		index := new(big.Int)
 		if err := method.Inputs.Unpack(&index, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		value := g.state.FineValue(index)
 		res, err := method.Outputs.Pack(value)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
+		}
+		return res, nil
+	case "idleBlockInterval":
+		res, err := method.Outputs.Pack(g.state.IdleBlockInterval())
+		if err != nil {
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "isConsortium":
 		res, err := method.Outputs.Pack(g.state.IsConsortium())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "lambdaBA":
 		res, err := method.Outputs.Pack(g.state.LambdaBA())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "lambdaDKG":
 		res, err := method.Outputs.Pack(g.state.LambdaDKG())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "lastHalvedAmount":
 		res, err := method.Outputs.Pack(g.state.LastHalvedAmount())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "lastProposedHeight":
 		address := common.Address{}
 		if err := method.Inputs.Unpack(&address, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		res, err := method.Outputs.Pack(g.state.LastProposedHeight(address))
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
+		}
+		return res, nil
+	case "rewardAddress":
+		address := common.Address{}
+		if err := method.Inputs.Unpack(&address, arguments); err != nil {
+			return nil, ErrExecutionReverted
+		}
+		res, err := method.Outputs.Pack(g.state.RewardAddress(address))
+		if err != nil {
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "lockupPeriod":
 		res, err := method.Outputs.Pack(g.state.LockupPeriod())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "minBlockInterval":
 		res, err := method.Outputs.Pack(g.state.MinBlockInterval())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "minGasPrice":
 		res, err := method.Outputs.Pack(g.state.MinGasPrice())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "miningVelocity":
 		res, err := method.Outputs.Pack(g.state.MiningVelocity())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "minStake":
 		res, err := method.Outputs.Pack(g.state.MinStake())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "nextHalvingSupply":
 		res, err := method.Outputs.Pack(g.state.NextHalvingSupply())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "nodes":
 		index := new(big.Int)
 		if err := method.Inputs.Unpack(&index, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		info := g.state.Node(index)
 		res, err := method.Outputs.Pack(
@@ -2790,113 +2949,113 @@ func (g *GovernanceContract) Run(evm *EVM, input []byte, contract *Contract) (re
 			info.Name, info.Email, info.Location, info.Url,
 			info.Unstaked, info.UnstakedAt)
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "nodesOffsetByAddress":
 		address := common.Address{}
 		if err := method.Inputs.Unpack(&address, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		res, err := method.Outputs.Pack(g.state.NodesOffsetByAddress(address))
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "nodesOffsetByNodeKeyAddress":
 		address := common.Address{}
 		if err := method.Inputs.Unpack(&address, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		res, err := method.Outputs.Pack(g.state.NodesOffsetByNodeKeyAddress(address))
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "notarySetSize":
 		res, err := method.Outputs.Pack(g.state.NotarySetSize())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "notaryParamAlpha":
 		res, err := method.Outputs.Pack(g.state.NotaryParamAlpha())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "notaryParamBeta":
 		res, err := method.Outputs.Pack(g.state.NotaryParamBeta())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "owner":
 		res, err := method.Outputs.Pack(g.state.Owner())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "replaceNodePublicKey":
 		var pk []byte
 		if err := method.Inputs.Unpack(&pk, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return g.replaceNodePublicKey(pk)
 	case "roundHeight":
 		round := new(big.Int)
 		if err := method.Inputs.Unpack(&round, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		res, err := method.Outputs.Pack(g.state.RoundHeight(round))
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "roundLength":
 		res, err := method.Outputs.Pack(g.state.RoundLength())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "totalStaked":
 		res, err := method.Outputs.Pack(g.state.TotalStaked())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "totalSupply":
 		res, err := method.Outputs.Pack(g.state.TotalSupply())
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	case "whitelistOffsetByAddress":
 		address := common.Address{}
 		if err := method.Inputs.Unpack(&address, arguments); err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		res, err := method.Outputs.Pack(g.state.WhitelistOffsetByAddress(address))
 		if err != nil {
-			return nil, errExecutionReverted
+			return nil, ErrExecutionReverted
 		}
 		return res, nil
 	}
 
-	return nil, errExecutionReverted
+	return nil, ErrExecutionReverted
 }
 
 func (g *GovernanceContract) transferOwnership(newOwner common.Address) ([]byte, error) {
 	if g.contract.Value().Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Only owner can update configuration.
 	if g.contract.Caller() != g.state.Owner() {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	if newOwner == (common.Address{}) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	g.state.SetOwner(newOwner)
 	return nil, nil
@@ -2904,22 +3063,22 @@ func (g *GovernanceContract) transferOwnership(newOwner common.Address) ([]byte,
 
 func (g *GovernanceContract) transferNodeOwnership(newOwner common.Address) ([]byte, error) {
 	if g.contract.Value().Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	if newOwner == (common.Address{}) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	caller := g.contract.Caller()
 
 	offset := g.state.NodesOffsetByAddress(caller)
 	if offset.Cmp(big.NewInt(0)) < 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	newOffset := g.state.NodesOffsetByAddress(newOwner)
 	if newOffset.Cmp(big.NewInt(0)) >= 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	node := g.state.Node(offset)
@@ -2929,6 +3088,11 @@ func (g *GovernanceContract) transferNodeOwnership(newOwner common.Address) ([]b
 	g.state.PutNodeOffsets(node, offset)
 	g.state.UpdateNode(offset, node)
 
+	// Rewards go to the new owner until it configures an address.
+	if g.evm.ChainConfig().IsRewardAddress(g.evm.BlockNumber) {
+		g.state.SetRewardAddress(caller, common.Address{})
+	}
+
 	g.state.emitNodeOwnershipTransfered(caller, newOwner)
 
 	return nil, nil
@@ -2936,26 +3100,26 @@ func (g *GovernanceContract) transferNodeOwnership(newOwner common.Address) ([]b
 
 func (g *GovernanceContract) transferNodeOwnershipByFoundation(oldOwner, newOwner common.Address) ([]byte, error) {
 	if g.contract.Value().Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Only owner can update configuration.
 	if g.contract.Caller() != g.state.Owner() {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	if newOwner == (common.Address{}) {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	offset := g.state.NodesOffsetByAddress(oldOwner)
 	if offset.Cmp(big.NewInt(0)) < 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	newOffset := g.state.NodesOffsetByAddress(newOwner)
 	if newOffset.Cmp(big.NewInt(0)) >= 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	node := g.state.Node(offset)
@@ -2965,31 +3129,57 @@ func (g *GovernanceContract) transferNodeOwnershipByFoundation(oldOwner, newOwne
 	g.state.PutNodeOffsets(node, offset)
 	g.state.UpdateNode(offset, node)
 
+	// Rewards go to the new owner until it configures an address.
+	if g.evm.ChainConfig().IsRewardAddress(g.evm.BlockNumber) {
+		g.state.SetRewardAddress(oldOwner, common.Address{})
+	}
+
 	g.state.emitNodeOwnershipTransfered(oldOwner, newOwner)
 
 	return nil, nil
 }
 
+// updateRewardAddress sets the address the block rewards of the caller's node
+// are credited to, letting them go to cold storage instead of the operated
+// node owner account. The zero address restores crediting the owner.
+func (g *GovernanceContract) updateRewardAddress(addr common.Address) ([]byte, error) {
+	if g.contract.Value().Cmp(big.NewInt(0)) > 0 {
+		return nil, ErrExecutionReverted
+	}
+
+	caller := g.contract.Caller()
+
+	offset := g.state.NodesOffsetByAddress(caller)
+	if offset.Cmp(big.NewInt(0)) < 0 {
+		return nil, ErrExecutionReverted
+	}
+
+	g.state.SetRewardAddress(caller, addr)
+	g.state.emitRewardAddressUpdated(caller, addr)
+
+	return nil, nil
+}
+
 func (g *GovernanceContract) replaceNodePublicKey(newPublicKey []byte) ([]byte, error) {
 	if g.contract.Value().Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	caller := g.contract.Caller()
 
 	offset := g.state.NodesOffsetByAddress(caller)
 	if offset.Cmp(big.NewInt(0)) < 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	newNodeKeyAddr, err := publicKeyToNodeKeyAddress(newPublicKey)
 	if err != nil {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	newNodeKeyOffset := g.state.NodesOffsetByNodeKeyAddress(newNodeKeyAddr)
 	if newNodeKeyOffset.Cmp(big.NewInt(0)) >= 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	node := g.state.Node(offset)
@@ -3007,24 +3197,24 @@ func (g *GovernanceContract) replaceNodePublicKey(newPublicKey []byte) ([]byte,
 
 func (g *GovernanceContract) addToWhitelist(addr common.Address) (*big.Int, error) {
 	if g.contract.Value().Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Only owner can update whitelist.
 	if g.contract.Caller() != g.state.Owner() {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	return g.state.AddToWhitelist(addr), nil
 }
 
 func (g *GovernanceContract) removeFromWhitelist(addr common.Address) (*big.Int, error) {
 	if g.contract.Value().Cmp(big.NewInt(0)) > 0 {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 
 	// Only owner can update whitelist.
 	if g.contract.Caller() != g.state.Owner() {
-		return nil, errExecutionReverted
+		return nil, ErrExecutionReverted
 	}
 	return g.state.DeleteAddressWhitelist(addr), nil
 }
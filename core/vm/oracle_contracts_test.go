@@ -157,6 +157,30 @@ func (g *GovernanceStateTestSuite) TestDisqualify() {
 	g.Require().Error(g.s.Disqualify(node))
 }
 
+func (g *GovernanceStateTestSuite) TestLayoutVersion() {
+	// Contracts without a marker use the first layout.
+	g.Require().Equal(uint64(1), g.s.LayoutVersion())
+	g.Require().NoError(g.s.CheckLayoutVersion())
+
+	g.s.SetLayoutVersion(GovernanceLayoutVersion)
+	g.Require().NoError(g.s.CheckLayoutVersion())
+
+	g.s.SetLayoutVersion(GovernanceLayoutVersion + 1)
+	g.Require().Error(g.s.CheckLayoutVersion())
+
+	// Migrating never downgrades the marker.
+	g.s.MigrateLayout()
+	g.Require().Equal(uint64(GovernanceLayoutVersion+1), g.s.LayoutVersion())
+}
+
+func (g *GovernanceStateTestSuite) TestMigrateLayout() {
+	g.Require().Equal(common.Hash{}, g.s.getState(layoutVersionLoc))
+
+	g.s.MigrateLayout()
+	g.Require().Equal(common.BigToHash(big.NewInt(GovernanceLayoutVersion)), g.s.getState(layoutVersionLoc))
+	g.Require().NoError(g.s.CheckLayoutVersion())
+}
+
 func TestGovernanceState(t *testing.T) {
 	suite.Run(t, new(GovernanceStateTestSuite))
 }
@@ -164,11 +188,12 @@ func TestGovernanceState(t *testing.T) {
 type GovernanceContractTestSuite struct {
 	suite.Suite
 
-	context Context
-	config  *params.DexconConfig
-	memDB   *ethdb.MemDatabase
-	stateDB *state.StateDB
-	s       *GovernanceState
+	context     Context
+	config      *params.DexconConfig
+	chainConfig *params.ChainConfig
+	memDB       *ethdb.MemDatabase
+	stateDB     *state.StateDB
+	s           *GovernanceState
 }
 
 func (g *GovernanceContractTestSuite) SetupTest() {
@@ -189,6 +214,11 @@ func (g *GovernanceContractTestSuite) SetupTest() {
 
 	g.config = config
 
+	chainConfig := *params.TestChainConfig
+	chainConfig.RewardAddressBlock = big.NewInt(0)
+	chainConfig.IdleBlockIntervalBlock = big.NewInt(0)
+	g.chainConfig = &chainConfig
+
 	// Give governance contract balance so it will not be deleted because of being an empty state object.
 	stateDB.AddBalance(GovernanceContractAddress, big.NewInt(1))
 
@@ -247,7 +277,7 @@ func (g *GovernanceContractTestSuite) call(
 
 	g.context.Time = big.NewInt(time.Now().UnixNano() / 1000000)
 
-	evm := NewEVM(g.context, g.stateDB, params.TestChainConfig, Config{IsBlockProposer: true})
+	evm := NewEVM(g.context, g.stateDB, g.chainConfig, Config{IsBlockProposer: true})
 	ret, _, err := evm.Call(AccountRef(caller), contractAddr, input, 10000000, value)
 	return ret, err
 }
@@ -274,6 +304,75 @@ func (g *GovernanceContractTestSuite) TestTransferOwnership() {
 	g.Require().Equal(addr, g.s.Owner())
 }
 
+func (g *GovernanceContractTestSuite) TestUpdateRewardAddress() {
+	privKey, addr := newPrefundAccount(g.stateDB)
+	pk := crypto.FromECDSAPub(&privKey.PublicKey)
+	_, cold := newPrefundAccount(g.stateDB)
+
+	input, err := GovernanceABI.ABI.Pack("updateRewardAddress", cold)
+	g.Require().NoError(err)
+
+	// Call with non-node.
+	_, err = g.call(GovernanceContractAddress, addr, input, big.NewInt(0))
+	g.Require().Error(err)
+
+	amount := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e6))
+	registerInput, err := GovernanceABI.ABI.Pack("register", pk, "Test1", "test1@dexon.org", "Taipei", "https://dexon.org")
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, registerInput, amount)
+	g.Require().NoError(err)
+	g.Require().Equal(addr, g.s.BlockRewardAddress(addr))
+
+	// Call with node owner.
+	_, err = g.call(GovernanceContractAddress, addr, input, big.NewInt(0))
+	g.Require().NoError(err)
+	g.Require().Equal(cold, g.s.RewardAddress(addr))
+	g.Require().Equal(cold, g.s.BlockRewardAddress(addr))
+
+	// The reward address does not follow the node to a new owner.
+	_, newAddr := newPrefundAccount(g.stateDB)
+	input, err = GovernanceABI.ABI.Pack("transferNodeOwnership", newAddr)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, big.NewInt(0))
+	g.Require().NoError(err)
+	g.Require().Equal(common.Address{}, g.s.RewardAddress(addr))
+	g.Require().Equal(newAddr, g.s.BlockRewardAddress(newAddr))
+}
+
+func (g *GovernanceContractTestSuite) TestUpdateRewardAddressBeforeFork() {
+	g.chainConfig.RewardAddressBlock = big.NewInt(1)
+
+	privKey, addr := newPrefundAccount(g.stateDB)
+	pk := crypto.FromECDSAPub(&privKey.PublicKey)
+	_, cold := newPrefundAccount(g.stateDB)
+
+	amount := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e6))
+	input, err := GovernanceABI.ABI.Pack("register", pk, "Test1", "test1@dexon.org", "Taipei", "https://dexon.org")
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, amount)
+	g.Require().NoError(err)
+
+	// Both methods are unknown before the fork.
+	input, err = GovernanceABI.ABI.Pack("updateRewardAddress", cold)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, big.NewInt(0))
+	g.Require().Error(err)
+	g.Require().Equal(common.Address{}, g.s.RewardAddress(addr))
+
+	input, err = GovernanceABI.ABI.Pack("rewardAddress", addr)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, big.NewInt(0))
+	g.Require().Error(err)
+
+	// And become available once it activates.
+	g.context.BlockNumber = big.NewInt(1)
+	input, err = GovernanceABI.ABI.Pack("updateRewardAddress", cold)
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, addr, input, big.NewInt(0))
+	g.Require().NoError(err)
+	g.Require().Equal(cold, g.s.RewardAddress(addr))
+}
+
 func (g *GovernanceContractTestSuite) TestTransferNodeOwnership() {
 	privKey, addr := newPrefundAccount(g.stateDB)
 	pk := crypto.FromECDSAPub(&privKey.PublicKey)
@@ -695,6 +794,58 @@ func (g *GovernanceContractTestSuite) TestUpdateConfiguration() {
 	g.Require().NoError(err)
 }
 
+func (g *GovernanceContractTestSuite) TestUpdateIdleBlockInterval() {
+	_, addr := newPrefundAccount(g.stateDB)
+
+	input, err := GovernanceABI.ABI.Pack("updateIdleBlockInterval", big.NewInt(5000))
+	g.Require().NoError(err)
+
+	// Call with non-owner.
+	_, err = g.call(GovernanceContractAddress, addr, input, big.NewInt(0))
+	g.Require().NotNil(err)
+
+	// Call with owner.
+	_, err = g.call(GovernanceContractAddress, g.config.Owner, input, big.NewInt(0))
+	g.Require().NoError(err)
+	g.Require().Equal(uint64(5000), g.s.Configuration().IdleBlockInterval)
+
+	// Interval shorter than the minimum block interval.
+	input, err = GovernanceABI.ABI.Pack("updateIdleBlockInterval",
+		new(big.Int).Sub(g.s.MinBlockInterval(), big.NewInt(1)))
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, g.config.Owner, input, big.NewInt(0))
+	g.Require().NotNil(err)
+
+	// Disable.
+	input, err = GovernanceABI.ABI.Pack("updateIdleBlockInterval", big.NewInt(0))
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, g.config.Owner, input, big.NewInt(0))
+	g.Require().NoError(err)
+	g.Require().Equal(uint64(0), g.s.IdleBlockInterval().Uint64())
+}
+
+func (g *GovernanceContractTestSuite) TestUpdateIdleBlockIntervalBeforeFork() {
+	g.chainConfig.IdleBlockIntervalBlock = big.NewInt(1)
+
+	input, err := GovernanceABI.ABI.Pack("updateIdleBlockInterval", big.NewInt(5000))
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, g.config.Owner, input, big.NewInt(0))
+	g.Require().NotNil(err)
+	g.Require().Equal(uint64(0), g.s.IdleBlockInterval().Uint64())
+
+	input, err = GovernanceABI.ABI.Pack("idleBlockInterval")
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, g.config.Owner, input, big.NewInt(0))
+	g.Require().NotNil(err)
+
+	g.context.BlockNumber = big.NewInt(1)
+	input, err = GovernanceABI.ABI.Pack("updateIdleBlockInterval", big.NewInt(5000))
+	g.Require().NoError(err)
+	_, err = g.call(GovernanceContractAddress, g.config.Owner, input, big.NewInt(0))
+	g.Require().NoError(err)
+	g.Require().Equal(uint64(5000), g.s.IdleBlockInterval().Uint64())
+}
+
 func (g *GovernanceContractTestSuite) TestConfigurationReading() {
 	_, addr := newPrefundAccount(g.stateDB)
 
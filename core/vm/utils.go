@@ -8,6 +8,7 @@ import (
 	"github.com/portto/go-tangerine/core/state"
 	"github.com/portto/go-tangerine/crypto"
 	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/params"
 	dexCore "github.com/portto/tangerine-consensus/core"
 )
 
@@ -43,13 +44,39 @@ func (g GovUtil) GetStateAtRound(round uint64) (*GovernanceState, error) {
 	return &GovernanceState{StateDB: s}, nil
 }
 
-func (g GovUtil) GetConfigState(round uint64) (*GovernanceState, error) {
+// ConfigRound returns the round whose starting state holds the configuration
+// in effect at round.
+func ConfigRound(round uint64) uint64 {
 	if round < dexCore.ConfigRoundShift {
-		round = 0
-	} else {
-		round -= dexCore.ConfigRoundShift
+		return 0
+	}
+	return round - dexCore.ConfigRoundShift
+}
+
+func (g GovUtil) GetConfigState(round uint64) (*GovernanceState, error) {
+	return g.GetStateAtRound(ConfigRound(round))
+}
+
+// ConfigSnapshot is the decoded configuration in effect at a round. Snapshots
+// are shared by their readers and must not be modified.
+type ConfigSnapshot struct {
+	Round         uint64
+	Height        uint64 // Height of the block the configuration is read at
+	Config        *params.DexconConfig
+	TotalStaked   *big.Int
+	NotarySetSize uint64
+}
+
+// NewConfigSnapshot decodes the configuration of round from s, the state at
+// height.
+func NewConfigSnapshot(s *GovernanceState, round, height uint64) *ConfigSnapshot {
+	return &ConfigSnapshot{
+		Round:         round,
+		Height:        height,
+		Config:        s.Configuration(),
+		TotalStaked:   s.TotalStaked(),
+		NotarySetSize: s.NotarySetSize().Uint64(),
 	}
-	return g.GetStateAtRound(round)
 }
 
 func (g *GovUtil) CRSRound() uint64 {
@@ -0,0 +1,107 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"sort"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	dexCore "github.com/portto/tangerine-consensus/core"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+)
+
+// voteTypeNames are the names of the core vote types, by type.
+var voteTypeNames = []string{
+	coreTypes.VoteInit:    "init",
+	coreTypes.VotePreCom:  "preCom",
+	coreTypes.VoteCom:     "com",
+	coreTypes.VoteFast:    "fast",
+	coreTypes.VoteFastCom: "fastCom",
+}
+
+// AgreementState is the state of the agreement module of the running
+// consensus core.
+type AgreementState struct {
+	Position        coreTypes.Position `json:"position"`
+	Stopped         bool               `json:"stopped"`
+	Leader          coreTypes.NodeID   `json:"leader"`
+	LeaderBlockHash coreCommon.Hash    `json:"leaderBlockHash"`
+	State           string             `json:"state"`
+	Period          uint64             `json:"period"`
+	LockValue       coreCommon.Hash    `json:"lockValue"`
+	LockPeriod      uint64             `json:"lockPeriod"` // Period the value was locked in
+	Confirmed       bool               `json:"confirmed"`
+	PendingBlocks   int                `json:"pendingBlocks"`
+	PendingVotes    int                `json:"pendingVotes"`
+	Votes           []*AgreementVotes  `json:"votes"` // By period and type
+}
+
+// AgreementVotes are the votes of a type collected in a period.
+type AgreementVotes struct {
+	Period uint64                                 `json:"period"`
+	Type   string                                 `json:"type"`
+	Votes  map[coreCommon.Hash][]coreTypes.NodeID `json:"votes"` // Proposers by voted hash
+}
+
+// newAgreementState converts a snapshot of the agreement module, ordering
+// the votes so that equal states dump identically.
+func newAgreementState(s *dexCore.AgreementSnapshot) *AgreementState {
+	state := &AgreementState{
+		Position:        s.Position,
+		Stopped:         s.Stopped,
+		Leader:          s.Leader,
+		LeaderBlockHash: s.LeaderBlockHash,
+		State:           s.State,
+		Period:          s.Period,
+		LockValue:       s.LockValue,
+		LockPeriod:      s.LockIter,
+		Confirmed:       s.Confirmed,
+		PendingBlocks:   s.PendingBlocks,
+		PendingVotes:    s.PendingVotes,
+		Votes:           []*AgreementVotes{},
+	}
+	periods := make([]uint64, 0, len(s.Votes))
+	for period := range s.Votes {
+		periods = append(periods, period)
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i] < periods[j] })
+
+	for _, period := range periods {
+		for t, byProposer := range s.Votes[period] {
+			if len(byProposer) == 0 {
+				continue
+			}
+			votes := &AgreementVotes{
+				Period: period,
+				Type:   voteTypeNames[t],
+				Votes:  make(map[coreCommon.Hash][]coreTypes.NodeID),
+			}
+			for id, hash := range byProposer {
+				votes.Votes[hash] = append(votes.Votes[hash], id)
+			}
+			for _, ids := range votes.Votes {
+				sort.Slice(ids, func(i, j int) bool {
+					return bytes.Compare(ids[i].Hash[:], ids[j].Hash[:]) < 0
+				})
+			}
+			state.Votes = append(state.Votes, votes)
+		}
+	}
+	return state
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"encoding/json"
+	"testing"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	dexCore "github.com/portto/tangerine-consensus/core"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+)
+
+// Tests that the agreement votes are grouped by period, type and hash, and
+// dumped in a deterministic order.
+func TestNewAgreementState(t *testing.T) {
+	var (
+		ids   = make([]coreTypes.NodeID, 3)
+		value = coreCommon.NewRandomHash()
+	)
+	for i := range ids {
+		ids[i] = coreTypes.NodeID{Hash: coreCommon.Hash{byte(3 - i)}}
+	}
+	newVotes := func() []map[coreTypes.NodeID]coreCommon.Hash {
+		votes := make([]map[coreTypes.NodeID]coreCommon.Hash, coreTypes.MaxVoteType)
+		for i := range votes {
+			votes[i] = make(map[coreTypes.NodeID]coreCommon.Hash)
+		}
+		return votes
+	}
+	snapshot := &dexCore.AgreementSnapshot{
+		Position:  coreTypes.Position{Round: 1, Height: 10},
+		State:     "preCommit",
+		Period:    2,
+		LockValue: value,
+		LockIter:  1,
+		Votes:     map[uint64][]map[coreTypes.NodeID]coreCommon.Hash{},
+	}
+	snapshot.Votes[2] = newVotes()
+	snapshot.Votes[2][coreTypes.VoteInit][ids[0]] = value
+	snapshot.Votes[1] = newVotes()
+	snapshot.Votes[1][coreTypes.VoteCom][ids[2]] = coreTypes.SkipBlockHash
+	for _, id := range ids {
+		snapshot.Votes[1][coreTypes.VotePreCom][id] = value
+	}
+
+	state := newAgreementState(snapshot)
+	if state.LockValue != value || state.LockPeriod != 1 || state.State != "preCommit" {
+		t.Fatalf("agreement state mismatch: %+v", state)
+	}
+	want := []struct {
+		period uint64
+		typ    string
+		votes  int
+	}{{1, "preCom", 3}, {1, "com", 1}, {2, "init", 1}}
+	if len(state.Votes) != len(want) {
+		t.Fatalf("votes length mismatch: have %d, want %d", len(state.Votes), len(want))
+	}
+	for i, w := range want {
+		votes := state.Votes[i]
+		n := 0
+		for _, ids := range votes.Votes {
+			n += len(ids)
+		}
+		if votes.Period != w.period || votes.Type != w.typ || n != w.votes {
+			t.Errorf("votes %d mismatch: have %d %s %d, want %d %s %d",
+				i, votes.Period, votes.Type, n, w.period, w.typ, w.votes)
+		}
+	}
+	if preCom := state.Votes[0].Votes[value]; preCom[0] != ids[2] || preCom[2] != ids[0] {
+		t.Errorf("proposers not sorted: %v", preCom)
+	}
+
+	// Equal states dump identically.
+	first, _ := json.Marshal(state)
+	for i := 0; i < 10; i++ {
+		again, _ := json.Marshal(newAgreementState(snapshot))
+		if string(again) != string(first) {
+			t.Fatalf("dump mismatch: have %s, want %s", again, first)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	dexCore "github.com/portto/tangerine-consensus/core"
+	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/vm"
+	"github.com/portto/go-tangerine/event"
+	"github.com/portto/go-tangerine/log"
+)
+
+// Consensus critical events alerts can be posted for.
+const (
+	AlertNotaryDropped    = "notaryDropped"    // Node left the notary set
+	AlertDKGMissed        = "dkgMissed"        // Node did not submit its DKG master public key
+	AlertDisqualified     = "disqualified"     // Node was disqualified from the DKG
+	AlertWatchCat         = "watchCat"         // WatchCat triggered a force sync
+	AlertProposingStopped = "proposingStopped" // Block proposer stopped or failed to start
+	AlertStateDivergence  = "stateDivergence"  // A validator announced a different state root
+)
+
+const (
+	// alertWildcard is the webhook entry of the events without their own.
+	alertWildcard = "*"
+
+	alertRoundChanSize = 16
+	alertPostTimeout   = 5 * time.Second
+)
+
+// AlertConfig configures the webhooks consensus critical events are posted
+// to.
+type AlertConfig struct {
+	// Webhooks maps events to the URL they are posted to, the "*" entry
+	// receiving the events without one of their own.
+	Webhooks map[string]string `toml:",omitempty"`
+
+	// Interval is the minimum time between two posts of the same event.
+	Interval time.Duration
+}
+
+// alertMessage is the JSON body posted to webhooks, its text field making it
+// displayable by Slack compatible receivers.
+type alertMessage struct {
+	Text  string         `json:"text"`
+	Event string         `json:"event"`
+	Node  common.Address `json:"node"`
+	Time  int64          `json:"time"`
+}
+
+// alertGovernance is the governance data needed to check the DKG outcome of
+// the node.
+type alertGovernance interface {
+	DKGMasterPublicKeys(round uint64) []*dkgTypes.MasterPublicKey
+	IsDKGFinal(round uint64) bool
+	DKGSetNodeKeyAddresses(round uint64) (map[common.Address]struct{}, error)
+}
+
+// alerter posts consensus critical events concerning the node to operator
+// webhooks.
+type alerter struct {
+	config AlertConfig
+	gov    alertGovernance
+	client *http.Client
+
+	lock    sync.Mutex
+	node    common.Address
+	nodeKey string // Hex encoded public key, as found in notary sets
+	last    map[string]time.Time
+	wg      sync.WaitGroup
+
+	roundCh  chan RoundChangeEvent
+	roundSub event.Subscription
+}
+
+func newAlerter(config AlertConfig, node common.Address, nodeKey string,
+	gov alertGovernance) *alerter {
+	return &alerter{
+		config:  config,
+		node:    node,
+		nodeKey: nodeKey,
+		gov:     gov,
+		client:  &http.Client{Timeout: alertPostTimeout},
+		last:    make(map[string]time.Time),
+	}
+}
+
+// identity returns the node key address and public key alerts are about.
+func (a *alerter) identity() (common.Address, string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.node, a.nodeKey
+}
+
+// setIdentity makes the alerts about a new node key, after a rotation.
+func (a *alerter) setIdentity(node common.Address, nodeKey string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.node, a.nodeKey = node, nodeKey
+}
+
+func (a *alerter) Start(rounds *roundNotifier) {
+	a.roundCh = make(chan RoundChangeEvent, alertRoundChanSize)
+	a.roundSub = rounds.Subscribe(a.roundCh)
+	go a.loop()
+}
+
+// Stop stops watching round changes and waits for the pending posts.
+func (a *alerter) Stop() {
+	if a.roundSub != nil {
+		a.roundSub.Unsubscribe()
+	}
+	a.wg.Wait()
+}
+
+func (a *alerter) loop() {
+	for {
+		select {
+		case ev := <-a.roundCh:
+			a.checkRound(ev)
+		case <-a.roundSub.Err():
+			return
+		}
+	}
+}
+
+// checkRound alerts about the notary set membership and the DKG outcome of
+// the node in the new round.
+func (a *alerter) checkRound(ev RoundChangeEvent) {
+	node, nodeKey := a.identity()
+	_, wasNotary := ev.OldNotarySet[nodeKey]
+	_, isNotary := ev.NewNotarySet[nodeKey]
+	if wasNotary && !isNotary {
+		a.alert(AlertNotaryDropped, fmt.Sprintf(
+			"node left the notary set in round %d", ev.NewRound))
+	}
+	if !isNotary || ev.NewRound < dexCore.DKGDelayRound {
+		return
+	}
+	submitted := false
+	for _, mpk := range a.gov.DKGMasterPublicKeys(ev.NewRound) {
+		if vm.IdToAddress(mpk.ProposerID) == node {
+			submitted = true
+			break
+		}
+	}
+	if !submitted {
+		a.alert(AlertDKGMissed, fmt.Sprintf(
+			"node did not submit its DKG master public key for round %d", ev.NewRound))
+		return
+	}
+	if !a.gov.IsDKGFinal(ev.NewRound) {
+		return
+	}
+	qualified, err := a.gov.DKGSetNodeKeyAddresses(ev.NewRound)
+	if err != nil {
+		log.Warn("Failed to get DKG qualified nodes", "round", ev.NewRound, "err", err)
+		return
+	}
+	if _, ok := qualified[node]; !ok {
+		a.alert(AlertDisqualified, fmt.Sprintf(
+			"node was disqualified from the DKG of round %d", ev.NewRound))
+	}
+}
+
+// alert posts text to the webhook of event, unless the event was posted
+// within the configured interval.
+func (a *alerter) alert(event, text string) {
+	log.Warn("Consensus alert", "event", event, "text", text)
+
+	url, ok := a.config.Webhooks[event]
+	if !ok {
+		url, ok = a.config.Webhooks[alertWildcard]
+	}
+	if !ok || url == "" {
+		return
+	}
+	now := time.Now()
+	a.lock.Lock()
+	if last, ok := a.last[event]; ok && now.Sub(last) < a.config.Interval {
+		a.lock.Unlock()
+		log.Debug("Alert rate limited", "event", event)
+		return
+	}
+	a.last[event] = now
+	node := a.node
+	a.lock.Unlock()
+
+	body, err := json.Marshal(&alertMessage{
+		Text:  fmt.Sprintf("[%s] %s", node.Hex(), text),
+		Event: event,
+		Node:  node,
+		Time:  now.Unix(),
+	})
+	if err != nil {
+		log.Error("Failed to encode alert", "err", err)
+		return
+	}
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		res, err := a.client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Warn("Failed to post alert", "event", event, "err", err)
+			return
+		}
+		res.Body.Close()
+		if res.StatusCode/100 != 2 {
+			log.Warn("Alert webhook refused alert", "event", event, "status", res.Status)
+		}
+	}()
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	dexCore "github.com/portto/tangerine-consensus/core"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+
+	"github.com/portto/go-tangerine/common"
+)
+
+type testAlertGovernance struct {
+	mpks      []*dkgTypes.MasterPublicKey
+	final     bool
+	qualified map[common.Address]struct{}
+}
+
+func (g *testAlertGovernance) DKGMasterPublicKeys(round uint64) []*dkgTypes.MasterPublicKey {
+	return g.mpks
+}
+
+func (g *testAlertGovernance) IsDKGFinal(round uint64) bool {
+	return g.final
+}
+
+func (g *testAlertGovernance) DKGSetNodeKeyAddresses(round uint64) (map[common.Address]struct{}, error) {
+	return g.qualified, nil
+}
+
+// testAlertSink records the alerts posted to it.
+type testAlertSink struct {
+	*httptest.Server
+
+	lock   sync.Mutex
+	events []string
+}
+
+func newTestAlertSink() *testAlertSink {
+	s := new(testAlertSink)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg alertMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.lock.Lock()
+		s.events = append(s.events, msg.Event)
+		s.lock.Unlock()
+	}))
+	return s
+}
+
+func (s *testAlertSink) posted() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string{}, s.events...)
+}
+
+// Tests that round changes raise the alerts matching the notary set
+// membership and the DKG outcome of the node.
+func TestAlerterCheckRound(t *testing.T) {
+	var (
+		node    = common.Address{1}
+		nodeKey = "self"
+		nodeID  coreTypes.NodeID
+		round   = dexCore.DKGDelayRound + 1
+		notary  = map[string]struct{}{nodeKey: {}}
+	)
+	copy(nodeID.Hash[12:], node[:])
+
+	tests := []struct {
+		gov    *testAlertGovernance
+		old    map[string]struct{}
+		new    map[string]struct{}
+		alerts []string
+	}{
+		// Dropped from the notary set.
+		{&testAlertGovernance{}, notary, nil, []string{AlertNotaryDropped}},
+		// Not a notary, DKG is none of the node's business.
+		{&testAlertGovernance{}, nil, nil, nil},
+		// Master public key not submitted.
+		{&testAlertGovernance{}, nil, notary, []string{AlertDKGMissed}},
+		// Submitted, DKG not final yet.
+		{&testAlertGovernance{
+			mpks: []*dkgTypes.MasterPublicKey{{ProposerID: nodeID}},
+		}, notary, notary, nil},
+		// Submitted and disqualified.
+		{&testAlertGovernance{
+			mpks:  []*dkgTypes.MasterPublicKey{{ProposerID: nodeID}},
+			final: true,
+		}, notary, notary, []string{AlertDisqualified}},
+		// Submitted and qualified.
+		{&testAlertGovernance{
+			mpks:      []*dkgTypes.MasterPublicKey{{ProposerID: nodeID}},
+			final:     true,
+			qualified: map[common.Address]struct{}{node: {}},
+		}, notary, notary, nil},
+	}
+	for i, tt := range tests {
+		sink := newTestAlertSink()
+		a := newAlerter(AlertConfig{Webhooks: map[string]string{"*": sink.URL}},
+			node, nodeKey, tt.gov)
+		a.checkRound(RoundChangeEvent{
+			OldRound:     round - 1,
+			OldNotarySet: tt.old,
+			NewRound:     round,
+			NewNotarySet: tt.new,
+		})
+		a.Stop()
+		sink.Close()
+
+		posted := sink.posted()
+		if len(posted) != len(tt.alerts) {
+			t.Errorf("test %d: alerts mismatch: have %v, want %v", i, posted, tt.alerts)
+			continue
+		}
+		for j := range posted {
+			if posted[j] != tt.alerts[j] {
+				t.Errorf("test %d: alerts mismatch: have %v, want %v", i, posted, tt.alerts)
+				break
+			}
+		}
+	}
+}
+
+// Tests that alerts are routed to their event's webhook and rate limited
+// per event.
+func TestAlerterRateLimit(t *testing.T) {
+	all, watchCat := newTestAlertSink(), newTestAlertSink()
+	defer all.Close()
+	defer watchCat.Close()
+
+	a := newAlerter(AlertConfig{
+		Webhooks: map[string]string{"*": all.URL, AlertWatchCat: watchCat.URL},
+		Interval: time.Hour,
+	}, common.Address{}, "", &testAlertGovernance{})
+	a.alert(AlertWatchCat, "meow")
+	a.alert(AlertWatchCat, "meow")
+	a.alert(AlertProposingStopped, "stopped")
+	a.alert(AlertProposingStopped, "stopped")
+	a.Stop()
+
+	if posted := watchCat.posted(); len(posted) != 1 || posted[0] != AlertWatchCat {
+		t.Errorf("watchCat webhook mismatch: %v", posted)
+	}
+	if posted := all.posted(); len(posted) != 1 || posted[0] != AlertProposingStopped {
+		t.Errorf("wildcard webhook mismatch: %v", posted)
+	}
+}
@@ -24,7 +24,12 @@ import (
 	"io"
 	"math/big"
 	"os"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
 
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/common/hexutil"
@@ -32,11 +37,20 @@ import (
 	"github.com/portto/go-tangerine/core/rawdb"
 	"github.com/portto/go-tangerine/core/state"
 	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/core/vm"
+	"github.com/portto/go-tangerine/crypto"
 	"github.com/portto/go-tangerine/internal/ethapi"
+	"github.com/portto/go-tangerine/internal/features"
+	"github.com/portto/go-tangerine/log"
 	"github.com/portto/go-tangerine/params"
 	"github.com/portto/go-tangerine/rlp"
 	"github.com/portto/go-tangerine/rpc"
 	"github.com/portto/go-tangerine/trie"
+	dexCore "github.com/portto/tangerine-consensus/core"
+	coreCrypto "github.com/portto/tangerine-consensus/core/crypto"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+	coreUtils "github.com/portto/tangerine-consensus/core/utils"
 )
 
 // PublicEthereumAPI provides an API to access Ethereum full node-related
@@ -69,6 +83,565 @@ func (api *PublicEthereumAPI) ChainId() hexutil.Uint64 {
 	return (hexutil.Uint64)(chainID.Uint64())
 }
 
+// PublicTangerineAPI provides an API to access Tangerine specific consensus
+// information.
+type PublicTangerineAPI struct {
+	dex *Tangerine
+
+	sentTxs *lru.Cache // Recently used idempotency keys, in front of the database
+	sentMu  sync.Mutex // Makes checking idempotency keys and recording them atomic
+	gpks    *lru.Cache // DKG group public keys by round
+}
+
+const (
+	// sentTxsCacheSize is the number of idempotency keys cached in memory.
+	sentTxsCacheSize = 65536
+
+	// gpkCacheSize is the number of DKG group public keys remembered.
+	gpkCacheSize = 16
+)
+
+// NewPublicTangerineAPI creates a new Tangerine protocol API.
+func NewPublicTangerineAPI(dex *Tangerine) *PublicTangerineAPI {
+	sentTxs, _ := lru.New(sentTxsCacheSize)
+	gpks, _ := lru.New(gpkCacheSize)
+	return &PublicTangerineAPI{dex: dex, sentTxs: sentTxs, gpks: gpks}
+}
+
+// errIdempotencyKeyUsed is returned for a transaction submitted with the key
+// of another one.
+var errIdempotencyKeyUsed = errors.New("idempotency key already used by another transaction")
+
+// RawTxRequest is a signed transaction submitted in a batch. Key optionally
+// identifies the submission, so it can be safely retried.
+type RawTxRequest struct {
+	Tx  hexutil.Bytes `json:"tx"`
+	Key string        `json:"key,omitempty"`
+}
+
+// SendTxResult is the outcome of a transaction submitted in a batch. Replayed
+// is set if the idempotency key was already used to send the transaction.
+type SendTxResult struct {
+	Hash     *common.Hash `json:"hash,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	Replayed bool         `json:"replayed,omitempty"`
+}
+
+// SendRawTransactions adds a batch of signed transactions to the transaction
+// pool. Each transaction succeeds or fails on its own. Resubmitting a key
+// already sent returns the original hash without resending, so callers can
+// retry a batch after a timeout.
+//
+// Keys are stored by hash in the chain database, so they are remembered
+// across restarts and never expire. They are not shared between nodes.
+func (api *PublicTangerineAPI) SendRawTransactions(ctx context.Context, reqs []RawTxRequest) []*SendTxResult {
+	var (
+		results = make([]*SendTxResult, len(reqs))
+		txs     = make([]*types.Transaction, 0, len(reqs))
+		index   = make([]int, 0, len(reqs))
+		first   = make(map[string]int) // Request first using each key in the batch
+		repeats []int
+	)
+	// Keys are checked, used and recorded in one locked section, so that
+	// concurrent retries of a batch cannot send a transaction twice.
+	api.sentMu.Lock()
+	defer api.sentMu.Unlock()
+
+	for i, req := range reqs {
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(req.Tx, tx); err != nil {
+			results[i] = &SendTxResult{Error: err.Error()}
+			continue
+		}
+		hash := tx.Hash()
+		if req.Key != "" {
+			if j, ok := first[req.Key]; ok {
+				if txs[j].Hash() != hash {
+					results[i] = &SendTxResult{Error: errIdempotencyKeyUsed.Error()}
+				} else {
+					repeats = append(repeats, i)
+				}
+				continue
+			}
+			if sent, ok := api.sentTx(req.Key); ok {
+				if sent != hash {
+					results[i] = &SendTxResult{Error: errIdempotencyKeyUsed.Error()}
+				} else {
+					results[i] = &SendTxResult{Hash: &hash, Replayed: true}
+				}
+				continue
+			}
+			first[req.Key] = len(txs)
+		}
+		txs = append(txs, tx)
+		index = append(index, i)
+	}
+	types.GlobalSigCache.Add(types.NewEIP155Signer(api.dex.chainConfig.ChainID), txs)
+
+	for i, err := range api.dex.APIBackend.SendTxs(ctx, txs) {
+		req, tx := reqs[index[i]], txs[i]
+		if err != nil {
+			results[index[i]] = &SendTxResult{Error: err.Error()}
+			continue
+		}
+		hash := tx.Hash()
+		if req.Key != "" {
+			api.recordSentTx(req.Key, hash)
+		}
+		log.Info("Submitted transaction", "fullhash", hash.Hex(), "recipient", tx.To())
+		results[index[i]] = &SendTxResult{Hash: &hash}
+	}
+	// Repeated keys share the outcome of their first request.
+	for _, i := range repeats {
+		result := *results[index[first[reqs[i].Key]]]
+		result.Replayed = result.Error == ""
+		results[i] = &result
+	}
+	return results
+}
+
+// sentTx returns the hash of the transaction sent with an idempotency key.
+func (api *PublicTangerineAPI) sentTx(key string) (common.Hash, bool) {
+	if sent, ok := api.sentTxs.Get(key); ok {
+		return sent.(common.Hash), true
+	}
+	sent := rawdb.ReadSentTxHash(api.dex.chainDb, crypto.Keccak256Hash([]byte(key)))
+	if sent == (common.Hash{}) {
+		return common.Hash{}, false
+	}
+	api.sentTxs.Add(key, sent)
+	return sent, true
+}
+
+// recordSentTx remembers the hash of the transaction sent with an
+// idempotency key.
+func (api *PublicTangerineAPI) recordSentTx(key string, hash common.Hash) {
+	rawdb.WriteSentTxHash(api.dex.chainDb, crypto.Keccak256Hash([]byte(key)), hash)
+	api.sentTxs.Add(key, hash)
+}
+
+// DkgResetReport returns the report generated when the DKG of the given round
+// was reset, reset being the reset count of the failed DKG attempt.
+func (api *PublicTangerineAPI) DkgResetReport(round, reset uint64) (*types.DKGResetReport, error) {
+	report := rawdb.ReadDKGResetReport(api.dex.chainDb, round, reset)
+	if report == nil {
+		return nil, fmt.Errorf("DKG reset report of round %d reset %d not found", round, reset)
+	}
+	return report, nil
+}
+
+// Features returns the state of the feature flags of the node.
+func (api *PublicTangerineAPI) Features() []features.Status {
+	return features.List()
+}
+
+// ProposerStatus returns the state of the block proposer of the node.
+func (api *PublicTangerineAPI) ProposerStatus() (*ProposerStatus, error) {
+	return api.dex.bp.Status()
+}
+
+// EstimateRewards projects the block rewards of staking stakeAmount, per
+// round and over the next year, from the configuration of the current round
+// and the halving schedule.
+func (api *PublicTangerineAPI) EstimateRewards(stakeAmount *hexutil.Big) (*RewardEstimate, error) {
+	if stakeAmount == nil {
+		return nil, errZeroStake
+	}
+	round := api.dex.blockchain.CurrentBlock().Round()
+	gs, err := api.dex.governance.GetConfigState(round)
+	if err != nil {
+		return nil, err
+	}
+	head, err := api.dex.governance.GetHeadGovState()
+	if err != nil {
+		return nil, err
+	}
+	// The supply and the halving schedule are tracked in the head state.
+	config := gs.Configuration()
+	headConfig := head.Configuration()
+	config.NextHalvingSupply = headConfig.NextHalvingSupply
+	config.LastHalvedAmount = headConfig.LastHalvedAmount
+	return estimateRewards(round, config, gs.TotalStaked(), head.TotalSupply(), stakeAmount.ToInt())
+}
+
+// maxHeadersRange is the maximum number of headers served by a single
+// GetHeadersRange call.
+const maxHeadersRange = 1024
+
+// CompactHeader is a block header stripped down to the fields needed to
+// verify the finality of the chain.
+type CompactHeader struct {
+	Number      hexutil.Uint64     `json:"number"`
+	Hash        common.Hash        `json:"hash"`
+	ParentHash  common.Hash        `json:"parentHash"`
+	Root        common.Hash        `json:"stateRoot"`
+	TxHash      common.Hash        `json:"transactionsRoot"`
+	ReceiptHash common.Hash        `json:"receiptsRoot"`
+	Coinbase    common.Address     `json:"miner"`
+	Time        hexutil.Uint64     `json:"timestamp"`
+	Round       hexutil.Uint64     `json:"round"`
+	Randomness  hexutil.Bytes      `json:"randomness"`
+	CoreHash    common.Hash        `json:"coreHash"`
+	ProposerID  coreTypes.NodeID   `json:"proposerID"`
+	Position    coreTypes.Position `json:"position"`
+	Witness     coreTypes.Witness  `json:"witness"`
+	Signature   hexutil.Bytes      `json:"signature"`
+}
+
+// GetHeadersRange returns up to count compact headers of the canonical chain
+// starting at block number from. At most maxHeadersRange headers are
+// returned, fewer if the range goes beyond the current head, and ranges
+// wrapping around the block numbers are rejected.
+func (api *PublicTangerineAPI) GetHeadersRange(from, count hexutil.Uint64) ([]*CompactHeader, error) {
+	if count > maxHeadersRange {
+		return nil, fmt.Errorf("too many headers requested: %d > %d", count, maxHeadersRange)
+	}
+	if from+count < from {
+		return nil, fmt.Errorf("invalid header range: %d headers from %d", count, from)
+	}
+	chain := api.dex.BlockChain()
+	head := chain.CurrentHeader().Number.Uint64()
+	headers := make([]*CompactHeader, 0, count)
+	for number := uint64(from); number < uint64(from+count) && number <= head; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			break
+		}
+		compact := &CompactHeader{
+			Number:      hexutil.Uint64(number),
+			Hash:        header.Hash(),
+			ParentHash:  header.ParentHash,
+			Root:        header.Root,
+			TxHash:      header.TxHash,
+			ReceiptHash: header.ReceiptHash,
+			Coinbase:    header.Coinbase,
+			Time:        hexutil.Uint64(header.Time),
+			Round:       hexutil.Uint64(header.Round),
+			Randomness:  header.Randomness,
+		}
+		// The genesis block carries no consensus metadata.
+		if len(header.DexconMeta) > 0 {
+			block, err := header.CoreBlock()
+			if err != nil {
+				return nil, fmt.Errorf("invalid dexcon meta of block %d: %v", number, err)
+			}
+			compact.CoreHash = common.Hash(block.Hash)
+			compact.ProposerID = block.ProposerID
+			compact.Position = block.Position
+			compact.Witness = block.Witness
+			compact.Signature = block.Signature.Signature
+		}
+		headers = append(headers, compact)
+	}
+	return headers, nil
+}
+
+// BlockRandomness is the randomness of a block, with the proof of it if
+// requested.
+type BlockRandomness struct {
+	Number     hexutil.Uint64   `json:"number"`
+	Round      hexutil.Uint64   `json:"round"`
+	Randomness hexutil.Bytes    `json:"randomness"`
+	Proof      *RandomnessProof `json:"proof,omitempty"`
+}
+
+// RandomnessProof proves the randomness of a block: the randomness is the
+// threshold signature of the consensus core block hash, verifiable with the
+// group public key of the DKG of the round.
+type RandomnessProof struct {
+	CoreHash       common.Hash   `json:"coreHash"`
+	GroupPublicKey hexutil.Bytes `json:"groupPublicKey"`
+	SignatureType  string        `json:"signatureType"`
+}
+
+// GetRandomness returns the randomness of the canonical block number. With
+// withProof set, the proof of the randomness is included, blocks of the rounds
+// preceding the first DKG have none.
+func (api *PublicTangerineAPI) GetRandomness(number rpc.BlockNumber, withProof *bool) (*BlockRandomness, error) {
+	var header *types.Header
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		header = api.dex.blockchain.CurrentHeader()
+	} else {
+		header = api.dex.blockchain.GetHeaderByNumber(uint64(number))
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	result := &BlockRandomness{
+		Number:     hexutil.Uint64(header.Number.Uint64()),
+		Round:      hexutil.Uint64(header.Round),
+		Randomness: header.Randomness,
+	}
+	if withProof == nil || !*withProof || header.Round < dexCore.DKGDelayRound ||
+		len(header.DexconMeta) == 0 {
+		return result, nil
+	}
+	block, err := header.CoreBlock()
+	if err != nil {
+		return nil, fmt.Errorf("invalid dexcon meta of block %d: %v", header.Number, err)
+	}
+	gpk, err := api.groupPublicKey(header.Round)
+	if err != nil {
+		return nil, err
+	}
+	proof := &RandomnessProof{
+		CoreHash:       common.Hash(block.Hash),
+		GroupPublicKey: gpk.GroupPublicKey.Bytes(),
+		SignatureType:  "bls",
+	}
+	if !gpk.VerifySignature(block.Hash, coreCrypto.Signature{
+		Type:      proof.SignatureType,
+		Signature: header.Randomness,
+	}) {
+		return nil, fmt.Errorf("randomness of block %d does not verify", header.Number)
+	}
+	result.Proof = proof
+	return result, nil
+}
+
+// groupPublicKey returns the group public key of the DKG of round.
+func (api *PublicTangerineAPI) groupPublicKey(round uint64) (*dkgTypes.GroupPublicKey, error) {
+	if gpk, ok := api.gpks.Get(round); ok {
+		return gpk.(*dkgTypes.GroupPublicKey), nil
+	}
+	gov := api.dex.governance
+	if !gov.IsDKGFinal(round) {
+		return nil, fmt.Errorf("DKG of round %d is not final", round)
+	}
+	gpk, err := dkgTypes.NewGroupPublicKey(round,
+		gov.DKGMasterPublicKeys(round), gov.DKGComplaints(round),
+		coreUtils.GetDKGThreshold(gov.Configuration(round)))
+	if err != nil {
+		return nil, err
+	}
+	api.gpks.Add(round, gpk)
+	return gpk, nil
+}
+
+// maxProposedBlocksRange is the maximum block range scanned by a single
+// BlocksProposedBy call.
+const maxProposedBlocksRange = 1000000
+
+// BlocksProposedBy returns the numbers of the canonical blocks within
+// [fromBlock, toBlock] whose coinbase is address.
+func (api *PublicTangerineAPI) BlocksProposedBy(address common.Address,
+	fromBlock, toBlock rpc.BlockNumber) ([]hexutil.Uint64, error) {
+	chain := api.dex.BlockChain()
+	head := chain.CurrentBlock().NumberU64()
+	from, to := uint64(fromBlock), uint64(toBlock)
+	if fromBlock < 0 {
+		from = head
+	}
+	if toBlock < 0 || to > head {
+		to = head
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range: %d > %d", from, to)
+	}
+	if to-from >= maxProposedBlocksRange {
+		return nil, fmt.Errorf("block range too large: %d > %d", to-from+1, maxProposedBlocksRange)
+	}
+	numbers := []hexutil.Uint64{}
+	db := api.dex.ChainDb()
+	for _, number := range rawdb.ReadProposedBlocks(db, address, from, to) {
+		// Skip entries left by blocks no longer canonical.
+		if header := chain.GetHeaderByNumber(number); header == nil || header.Coinbase != address {
+			continue
+		}
+		numbers = append(numbers, hexutil.Uint64(number))
+	}
+	return numbers, nil
+}
+
+// ExtendedReceipt is the execution report of a transaction beyond its
+// receipt: the gas refunded and the accounts touched.
+type ExtendedReceipt struct {
+	BlockHash        common.Hash      `json:"blockHash"`
+	BlockNumber      hexutil.Uint64   `json:"blockNumber"`
+	TransactionHash  common.Hash      `json:"transactionHash"`
+	TransactionIndex hexutil.Uint64   `json:"transactionIndex"`
+	GasRefund        hexutil.Uint64   `json:"gasRefund"`
+	TouchedAccounts  []common.Address `json:"touchedAccounts"`
+}
+
+// GetExtendedReceipt returns the extended receipt of the transaction hash,
+// nil if the transaction is unknown. Extended receipts are only stored for
+// the blocks written while they are enabled.
+func (api *PublicTangerineAPI) GetExtendedReceipt(hash common.Hash) (*ExtendedReceipt, error) {
+	if !api.dex.blockchain.ExtendedReceipts() {
+		return nil, errors.New("extended receipts disabled")
+	}
+	blockHash, number, index := rawdb.ReadTxLookupEntry(api.dex.ChainDb(), hash)
+	if blockHash == (common.Hash{}) {
+		return nil, nil
+	}
+	receipts := api.dex.blockchain.GetExtendedReceiptsByHash(blockHash)
+	if receipts == nil {
+		return nil, fmt.Errorf("no extended receipts stored for block %d", number)
+	}
+	if index >= uint64(len(receipts)) || receipts[index].TxHash != hash {
+		return nil, fmt.Errorf("extended receipt of %x missing from block %d", hash, number)
+	}
+	receipt := receipts[index]
+	result := &ExtendedReceipt{
+		BlockHash:        blockHash,
+		BlockNumber:      hexutil.Uint64(number),
+		TransactionHash:  hash,
+		TransactionIndex: hexutil.Uint64(index),
+		GasRefund:        hexutil.Uint64(receipt.GasRefund),
+		TouchedAccounts:  receipt.TouchedAccounts,
+	}
+	if result.TouchedAccounts == nil {
+		result.TouchedAccounts = []common.Address{}
+	}
+	return result, nil
+}
+
+// PublicGovernanceAPI provides an API to access the governance information
+// indexed by the node.
+type PublicGovernanceAPI struct {
+	dex *Tangerine
+}
+
+// NewPublicGovernanceAPI creates a new governance API.
+func NewPublicGovernanceAPI(dex *Tangerine) *PublicGovernanceAPI {
+	return &PublicGovernanceAPI{dex: dex}
+}
+
+// StakeEvent is a stake change of a node owner as returned over RPC.
+type StakeEvent struct {
+	Type        string         `json:"type"`
+	Amount      *hexutil.Big   `json:"amount"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	LogIndex    hexutil.Uint   `json:"logIndex"`
+}
+
+// StakeHistory returns the stake deposits and withdrawals of the node owner
+// address in the canonical chain, oldest first.
+func (api *PublicGovernanceAPI) StakeHistory(address common.Address) []*StakeEvent {
+	var (
+		db     = api.dex.ChainDb()
+		events = []*StakeEvent{}
+	)
+	for _, event := range rawdb.ReadStakeEvents(db, address) {
+		// Skip entries left by blocks no longer canonical.
+		if rawdb.ReadCanonicalHash(db, event.BlockNumber) != event.BlockHash {
+			continue
+		}
+		events = append(events, &StakeEvent{
+			Type:        event.Kind.String(),
+			Amount:      (*hexutil.Big)(event.Amount),
+			BlockNumber: hexutil.Uint64(event.BlockNumber),
+			BlockHash:   event.BlockHash,
+			TxHash:      event.TxHash,
+			LogIndex:    hexutil.Uint(event.LogIndex),
+		})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].BlockNumber != events[j].BlockNumber {
+			return events[i].BlockNumber < events[j].BlockNumber
+		}
+		return events[i].LogIndex < events[j].LogIndex
+	})
+	return events
+}
+
+// GovernanceSummary is the overall staking state of the governance contract.
+type GovernanceSummary struct {
+	TotalSupply *hexutil.Big   `json:"totalSupply"`
+	TotalStaked *hexutil.Big   `json:"totalStaked"`
+	MinStake    *hexutil.Big   `json:"minStake"`
+	NodeCount   hexutil.Uint64 `json:"nodeCount"`
+	CRSRound    hexutil.Uint64 `json:"crsRound"`
+	DKGRound    hexutil.Uint64 `json:"dkgRound"`
+}
+
+// GovernanceNode is a node registered in the governance contract.
+type GovernanceNode struct {
+	Owner      common.Address `json:"owner"`
+	PublicKey  hexutil.Bytes  `json:"publicKey"`
+	Staked     *hexutil.Big   `json:"staked"`
+	Fined      *hexutil.Big   `json:"fined"`
+	Unstaked   *hexutil.Big   `json:"unstaked"`
+	UnstakedAt *hexutil.Big   `json:"unstakedAt"`
+	Name       string         `json:"name"`
+	Email      string         `json:"email"`
+	Location   string         `json:"location"`
+	Url        string         `json:"url"`
+}
+
+// governanceState returns the governance contract state at the given block.
+func (api *PublicGovernanceAPI) governanceState(ctx context.Context, blockNr rpc.BlockNumber) (*vm.GovernanceState, error) {
+	state, _, err := api.dex.APIBackend.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	return &vm.GovernanceState{StateDB: state}, nil
+}
+
+// Summary returns the overall staking state at the given block. It saves
+// callers from encoding several governance contract calls by hand.
+func (api *PublicGovernanceAPI) Summary(ctx context.Context, blockNr rpc.BlockNumber) (*GovernanceSummary, error) {
+	gs, err := api.governanceState(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	return &GovernanceSummary{
+		TotalSupply: (*hexutil.Big)(gs.TotalSupply()),
+		TotalStaked: (*hexutil.Big)(gs.TotalStaked()),
+		MinStake:    (*hexutil.Big)(gs.MinStake()),
+		NodeCount:   hexutil.Uint64(gs.LenNodes().Uint64()),
+		CRSRound:    hexutil.Uint64(gs.CRSRound().Uint64()),
+		DKGRound:    hexutil.Uint64(gs.DKGRound().Uint64()),
+	}, nil
+}
+
+// Node returns the node registered by the owner address at the given block,
+// or nil if there is none.
+func (api *PublicGovernanceAPI) Node(ctx context.Context, owner common.Address, blockNr rpc.BlockNumber) (*GovernanceNode, error) {
+	gs, err := api.governanceState(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	offset := gs.NodesOffsetByAddress(owner)
+	if offset.Sign() < 0 {
+		return nil, nil
+	}
+	return governanceNodeAt(gs, offset), nil
+}
+
+// Nodes returns all nodes registered at the given block.
+func (api *PublicGovernanceAPI) Nodes(ctx context.Context, blockNr rpc.BlockNumber) ([]*GovernanceNode, error) {
+	gs, err := api.governanceState(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	count := gs.LenNodes().Uint64()
+	nodes := make([]*GovernanceNode, 0, count)
+	for i := uint64(0); i < count; i++ {
+		nodes = append(nodes, governanceNodeAt(gs, new(big.Int).SetUint64(i)))
+	}
+	return nodes, nil
+}
+
+func governanceNodeAt(gs *vm.GovernanceState, index *big.Int) *GovernanceNode {
+	n := gs.Node(index)
+	return &GovernanceNode{
+		Owner:      n.Owner,
+		PublicKey:  n.PublicKey,
+		Staked:     (*hexutil.Big)(n.Staked),
+		Fined:      (*hexutil.Big)(n.Fined),
+		Unstaked:   (*hexutil.Big)(n.Unstaked),
+		UnstakedAt: (*hexutil.Big)(n.UnstakedAt),
+		Name:       n.Name,
+		Email:      n.Email,
+		Location:   n.Location,
+		Url:        n.Url,
+	}
+}
+
 // PrivateAdminAPI is the collection of Ethereum full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -82,7 +655,9 @@ func NewPrivateAdminAPI(dex *Tangerine) *PrivateAdminAPI {
 }
 
 // ExportChain exports the current blockchain into a local file.
-func (api *PrivateAdminAPI) ExportChain(file string) (bool, error) {
+func (api *PrivateAdminAPI) ExportChain(ctx context.Context, file string) (ok bool, err error) {
+	defer func() { api.dex.audit.record(api.dex.audit.requester(ctx), AuditExportChain, file, err) }()
+
 	// Make sure we can create the file to export into
 	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
 	if err != nil {
@@ -114,7 +689,12 @@ func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
 }
 
 // ImportChain imports a blockchain from a local file.
-func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
+func (api *PrivateAdminAPI) ImportChain(ctx context.Context, file string) (ok bool, err error) {
+	defer func() { api.dex.audit.record(api.dex.audit.requester(ctx), AuditImportChain, file, err) }()
+
+	if api.dex.config.SafeMode {
+		return false, errSafeMode
+	}
 	// Make sure the can access the file to import
 	in, err := os.Open(file)
 	if err != nil {
@@ -174,6 +754,65 @@ func (api *PrivateAdminAPI) NotaryInfo() (*NotaryInfo, error) {
 	return api.dex.protocolManager.NotaryInfo()
 }
 
+// NotaryConns returns the connections to the notary set members.
+func (api *PrivateAdminAPI) NotaryConns() []*NotaryConn {
+	return api.dex.protocolManager.peers.NotaryConns()
+}
+
+// RekeyNotaryConns drops the notary connections established at least minAge
+// seconds ago, so that they are redialed with fresh session keys. The
+// connections are dropped a few at a time in the background. It returns the
+// number of connections to drop, zero if a rekey is already in progress.
+func (api *PrivateAdminAPI) RekeyNotaryConns(ctx context.Context, minAge uint64) int {
+	scheduled := api.dex.protocolManager.peers.Rekey(time.Duration(minAge) * time.Second)
+	api.dex.audit.record(api.dex.audit.requester(ctx), AuditRekeyNotaryConns,
+		fmt.Sprintf("minAge=%d scheduled=%d", minAge, scheduled), nil)
+	return scheduled
+}
+
+// RotateNodeKey schedules a node key rotation, returning the next node key.
+// The node switches to it at the first round the governance knows it, once
+// the node owner called replaceNodePublicKey with it, and stores it as the
+// node key of the data directory. The node key address pays for the
+// governance transactions, so the next one needs funds too.
+//
+// The switch does not restart the process, but it is not seamless: the
+// consensus core is stopped and synced again with the new key, and every
+// peer is dropped when the p2p identity changes. The node does not propose
+// until the new core has synced.
+func (api *PrivateAdminAPI) RotateNodeKey(ctx context.Context) (*PendingNodeKey, error) {
+	if api.dex.config.SafeMode {
+		api.dex.audit.record(api.dex.audit.requester(ctx), AuditRotateNodeKey, "", errSafeMode)
+		return nil, errSafeMode
+	}
+	pending, err := api.dex.keyRotator.Schedule()
+	var details string
+	if pending != nil {
+		details = "next=" + pending.NodeKeyAddress.Hex()
+	}
+	api.dex.audit.record(api.dex.audit.requester(ctx), AuditRotateNodeKey, details, err)
+	return pending, err
+}
+
+// PendingNodeKey returns the next node key of the pending rotation, nil if
+// none.
+func (api *PrivateAdminAPI) PendingNodeKey() *PendingNodeKey {
+	return api.dex.keyRotator.Pending()
+}
+
+// CancelNodeKeyRotation drops the pending node key rotation.
+func (api *PrivateAdminAPI) CancelNodeKeyRotation(ctx context.Context) error {
+	err := api.dex.keyRotator.Cancel()
+	api.dex.audit.record(api.dex.audit.requester(ctx), AuditCancelNodeKeyRotation, "", err)
+	return err
+}
+
+// AuditLog returns the entries of the audit log of sensitive operations from
+// sequence number from on, at most 1000 of them.
+func (api *PrivateAdminAPI) AuditLog(from uint64) []*types.AuditEntry {
+	return api.dex.audit.entries(from)
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -217,6 +856,13 @@ func NewPrivateDebugAPI(config *params.ChainConfig, dex *Tangerine) *PrivateDebu
 	return &PrivateDebugAPI{config: config, dex: dex}
 }
 
+// ConsensusProfile captures a CPU profile for nsec seconds and returns it in
+// the pprof format. The samples are labeled with the consensus subsystem
+// running them, which "go tool pprof -tagfocus" can filter on.
+func (api *PrivateDebugAPI) ConsensusProfile(ctx context.Context, nsec uint) (hexutil.Bytes, error) {
+	return captureCPUProfile(ctx, time.Duration(nsec)*time.Second)
+}
+
 // Preimage is a debug API function that returns the preimage for a sha3 hash, if known.
 func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 	if preimage := rawdb.ReadPreimage(api.dex.ChainDb(), hash); preimage != nil {
@@ -225,6 +871,50 @@ func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hex
 	return nil, errors.New("unknown preimage")
 }
 
+// CoreCacheStats returns the occupancy, eviction counts and estimated memory
+// usage of the cache holding core votes and blocks.
+func (api *PrivateDebugAPI) CoreCacheStats() *CacheStats {
+	return api.dex.protocolManager.cache.stats()
+}
+
+// AgreementState returns the state of the agreement module of the running
+// consensus core: the locked value, the votes collected and the leader block
+// of the current position. It helps diagnose a period that does not end.
+func (api *PrivateDebugAPI) AgreementState() (*AgreementState, error) {
+	return api.dex.bp.AgreementState()
+}
+
+// TxPropagation returns how the transaction reached the node and how many
+// peers it was relayed to. Only the recent transactions are remembered.
+func (api *PrivateDebugAPI) TxPropagation(hash common.Hash) (*TxPropagation, error) {
+	record := api.dex.protocolManager.txProps.get(hash)
+	if record == nil {
+		return nil, errors.New("unknown transaction")
+	}
+	return record, nil
+}
+
+// PeerHistory is the result of a peer history query.
+type PeerHistory struct {
+	Events    []*types.PeerEvent `json:"events"`
+	Truncated bool               `json:"truncated"` // Whether more events were recorded
+}
+
+// PeerHistory returns the peer connection and disconnection events recorded
+// since the given unix time in seconds, oldest first.
+func (api *PrivateDebugAPI) PeerHistory(since int64) (*PeerHistory, error) {
+	history := api.dex.protocolManager.peerHistory
+	if history == nil {
+		return nil, errors.New("peer history disabled")
+	}
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	events, truncated := history.since(since*1000, now)
+	if events == nil {
+		events = []*types.PeerEvent{}
+	}
+	return &PeerHistory{Events: events, Truncated: truncated}, nil
+}
+
 // BadBlockArgs represents the entries in the list returned when bad blocks are queried.
 type BadBlockArgs struct {
 	Hash  common.Hash            `json:"hash"`
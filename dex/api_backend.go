@@ -18,7 +18,9 @@ package dex
 
 import (
 	"context"
+	"fmt"
 	"math/big"
+	"time"
 
 	"github.com/portto/go-tangerine/accounts"
 	"github.com/portto/go-tangerine/common"
@@ -33,6 +35,7 @@ import (
 
 	"github.com/portto/go-tangerine/ethdb"
 	"github.com/portto/go-tangerine/event"
+	"github.com/portto/go-tangerine/log"
 	"github.com/portto/go-tangerine/params"
 	"github.com/portto/go-tangerine/rpc"
 )
@@ -53,6 +56,10 @@ func (b *DexAPIBackend) CurrentBlock() *types.Block {
 }
 
 func (b *DexAPIBackend) SetHead(number uint64) {
+	if b.dex.config.SafeMode {
+		log.Warn("Not rewinding chain in safe mode", "number", number)
+		return
+	}
 	b.dex.protocolManager.downloader.Cancel()
 	b.dex.blockchain.SetHead(number)
 }
@@ -139,11 +146,40 @@ func (b *DexAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 }
 
 func (b *DexAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	if b.dex.config.SafeMode {
+		return errSafeMode
+	}
+	if err := verifyGovernanceTx(signedTx); err != nil {
+		return err
+	}
+	b.dex.protocolManager.txProps.seen(types.Transactions{signedTx}, TxOriginLocal, "")
 	return b.dex.txPool.AddLocal(signedTx)
 }
 
 func (b *DexAPIBackend) SendTxs(ctx context.Context, signedTxs []*types.Transaction) []error {
-	return b.dex.txPool.AddLocals(signedTxs)
+	if b.dex.config.SafeMode {
+		errs := make([]error, len(signedTxs))
+		for i := range errs {
+			errs[i] = errSafeMode
+		}
+		return errs
+	}
+	var (
+		errs  = make([]error, len(signedTxs))
+		txs   = make([]*types.Transaction, 0, len(signedTxs))
+		index = make([]int, 0, len(signedTxs))
+	)
+	for i, tx := range signedTxs {
+		if errs[i] = verifyGovernanceTx(tx); errs[i] == nil {
+			txs = append(txs, tx)
+			index = append(index, i)
+		}
+	}
+	b.dex.protocolManager.txProps.seen(txs, TxOriginLocal, "")
+	for i, err := range b.dex.txPool.AddLocals(txs) {
+		errs[index[i]] = err
+	}
+	return errs
 }
 
 func (b *DexAPIBackend) GetPoolTransactions() (types.Transactions, error) {
@@ -187,11 +223,15 @@ func (b *DexAPIBackend) ProtocolVersion() int {
 }
 
 func (b *DexAPIBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
-	gs, err := b.dex.governance.GetConfigState(b.dex.blockchain.CurrentBlock().Round())
+	return b.MinGasPrice(ctx, b.dex.blockchain.CurrentBlock().Round())
+}
+
+func (b *DexAPIBackend) MinGasPrice(ctx context.Context, round uint64) (*big.Int, error) {
+	s, err := b.dex.governance.GetConfigSnapshot(round)
 	if err != nil {
 		return nil, err
 	}
-	return gs.MinGasPrice(), nil
+	return new(big.Int).Set(s.Config.MinGasPrice), nil
 }
 
 func (b *DexAPIBackend) ChainDb() ethdb.Database {
@@ -210,6 +250,10 @@ func (b *DexAPIBackend) RPCGasCap() *big.Int {
 	return b.dex.config.RPCGasCap
 }
 
+func (b *DexAPIBackend) RPCGovernanceGasCap() *big.Int {
+	return b.dex.config.RPCGovernanceGasCap
+}
+
 func (b *DexAPIBackend) BloomStatus() (uint64, uint64) {
 	sections, _, _ := b.dex.bloomIndexer.Sections()
 	return params.BloomBitsBlocks, sections
@@ -220,3 +264,9 @@ func (b *DexAPIBackend) ServiceFilter(ctx context.Context, session *bloombits.Ma
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.dex.bloomRequests)
 	}
 }
+
+// AuditUnlockAccount records an account unlock attempt in the audit log.
+func (b *DexAPIBackend) AuditUnlockAccount(ctx context.Context, addr common.Address, duration time.Duration, err error) {
+	b.dex.audit.record(b.dex.audit.requester(ctx), AuditUnlockAccount,
+		fmt.Sprintf("address=%s duration=%v", addr.Hex(), duration), err)
+}
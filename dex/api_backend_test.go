@@ -0,0 +1,416 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	dexCore "github.com/portto/tangerine-consensus/core"
+	cryptoDKG "github.com/portto/tangerine-consensus/core/crypto/dkg"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/common/hexutil"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/core/vm"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/dex/downloader"
+	"github.com/portto/go-tangerine/eth/filters"
+	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/event"
+	"github.com/portto/go-tangerine/params"
+	"github.com/portto/go-tangerine/rlp"
+	"github.com/portto/go-tangerine/rpc"
+)
+
+// Tests that the calls modifying the chain are rejected in safe mode.
+func TestSafeModeRejectsWrites(t *testing.T) {
+	dex := &Tangerine{config: &Config{SafeMode: true}}
+	backend := &DexAPIBackend{dex: dex}
+
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	if err := backend.SendTx(context.Background(), tx); err != errSafeMode {
+		t.Errorf("SendTx error mismatch: have %v, want %v", err, errSafeMode)
+	}
+	for i, err := range backend.SendTxs(context.Background(), []*types.Transaction{tx, tx}) {
+		if err != errSafeMode {
+			t.Errorf("SendTxs error %d mismatch: have %v, want %v", i, err, errSafeMode)
+		}
+	}
+	if _, err := NewPrivateAdminAPI(dex).ImportChain(context.Background(), "chain.rlp"); err != errSafeMode {
+		t.Errorf("ImportChain error mismatch: have %v, want %v", err, errSafeMode)
+	}
+}
+
+// Tests that governance configuration updates breaking the consensus core are
+// refused before being sent.
+func TestVerifyGovernanceTx(t *testing.T) {
+	updateConfiguration := func(roundLength int64) *types.Transaction {
+		data, err := vm.GovernanceABI.ABI.Pack("updateConfiguration",
+			big.NewInt(1), big.NewInt(1), big.NewInt(1), big.NewInt(1),
+			big.NewInt(250), big.NewInt(20000), big.NewInt(1), big.NewInt(1),
+			big.NewInt(roundLength), big.NewInt(1000), []*big.Int{})
+		if err != nil {
+			t.Fatalf("failed to pack updateConfiguration: %v", err)
+		}
+		return types.NewTransaction(0, vm.GovernanceContractAddress, big.NewInt(0), 1000000, big.NewInt(1), data)
+	}
+	if err := verifyGovernanceTx(updateConfiguration(3600)); err != nil {
+		t.Errorf("safe configuration refused: %v", err)
+	}
+	if err := verifyGovernanceTx(updateConfiguration(100)); err == nil {
+		t.Errorf("dangerous configuration accepted")
+	}
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	if err := verifyGovernanceTx(tx); err != nil {
+		t.Errorf("plain transaction refused: %v", err)
+	}
+}
+
+// Tests that batched transactions fail independently and that idempotency
+// keys already used are answered without resending.
+func TestSendRawTransactions(t *testing.T) {
+	dex := &Tangerine{config: &Config{SafeMode: true}, chainConfig: params.TestChainConfig, chainDb: ethdb.NewMemDatabase()}
+	dex.APIBackend = &DexAPIBackend{dex: dex}
+	api := NewPublicTangerineAPI(dex)
+
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	other := types.NewTransaction(1, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	enc, _ := rlp.EncodeToBytes(tx)
+	otherEnc, _ := rlp.EncodeToBytes(other)
+	api.recordSentTx("sent", tx.Hash())
+
+	results := api.SendRawTransactions(context.Background(), []RawTxRequest{
+		{Tx: []byte{0x01}},
+		{Tx: enc},
+		{Tx: enc, Key: "sent"},
+		{Tx: otherEnc, Key: "sent"},
+	})
+	if len(results) != 4 {
+		t.Fatalf("result count mismatch: have %d, want 4", len(results))
+	}
+	if results[0].Error == "" {
+		t.Errorf("malformed transaction accepted")
+	}
+	if results[1].Error != errSafeMode.Error() {
+		t.Errorf("error mismatch: have %q, want %q", results[1].Error, errSafeMode)
+	}
+	if !results[2].Replayed || results[2].Hash == nil || *results[2].Hash != tx.Hash() {
+		t.Errorf("retried key not replayed: %+v", results[2])
+	}
+	if results[3].Error == "" || results[3].Hash != nil {
+		t.Errorf("reused key with another transaction accepted: %+v", results[3])
+	}
+}
+
+// Tests that the randomness of a block is returned with a proof verifying
+// against the group public key of its round, only if requested.
+func TestGetRandomness(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+	api := NewPublicTangerineAPI(dex)
+
+	round := dexCore.DKGDelayRound
+	prv := cryptoDKG.NewPrivateKey()
+	pub := prv.PublicKey().(cryptoDKG.PublicKey)
+	api.gpks.Add(round, &dkgTypes.GroupPublicKey{Round: round, GroupPublicKey: &pub})
+
+	// The second block carries the randomness of another core block.
+	parent := dex.blockchain.Genesis().Hash()
+	var coreBlocks []*coreTypes.Block
+	for i := uint64(1); i <= 2; i++ {
+		block := &coreTypes.Block{
+			Hash:      coreCommon.NewRandomHash(),
+			Position:  coreTypes.Position{Round: round, Height: i},
+			Timestamp: time.Now(),
+		}
+		sig, err := prv.Sign(block.Hash)
+		if i == 2 {
+			sig, err = prv.Sign(coreCommon.NewRandomHash())
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.Randomness = sig.Signature
+		meta, err := types.EncodeDexconMeta(block, true)
+		if err != nil {
+			t.Fatalf("failed to encode dexcon meta: %v", err)
+		}
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     new(big.Int).SetUint64(i),
+			Round:      round,
+			DexconMeta: meta,
+			Randomness: block.Randomness,
+		}
+		rawdb.WriteHeader(dex.chainDb, header)
+		rawdb.WriteCanonicalHash(dex.chainDb, header.Hash(), i)
+		parent = header.Hash()
+		coreBlocks = append(coreBlocks, block)
+	}
+
+	withProof := true
+	result, err := api.GetRandomness(1, nil)
+	if err != nil {
+		t.Fatalf("failed to get randomness: %v", err)
+	}
+	if !bytes.Equal(result.Randomness, coreBlocks[0].Randomness) || result.Proof != nil {
+		t.Errorf("randomness mismatch: %+v", result)
+	}
+	if result, err = api.GetRandomness(1, &withProof); err != nil {
+		t.Fatalf("failed to get randomness with proof: %v", err)
+	}
+	if proof := result.Proof; proof == nil || proof.CoreHash != common.Hash(coreBlocks[0].Hash) ||
+		!bytes.Equal(proof.GroupPublicKey, pub.Bytes()) {
+		t.Errorf("proof mismatch: %+v", result.Proof)
+	}
+	if _, err := api.GetRandomness(2, &withProof); err == nil {
+		t.Errorf("randomness of another core block proved")
+	}
+	// Blocks of the rounds before the first DKG have no proof.
+	if result, err := api.GetRandomness(0, &withProof); err != nil || result.Proof != nil {
+		t.Errorf("genesis randomness mismatch: %+v, %v", result, err)
+	}
+}
+
+// Tests the bounds of the header ranges served by tan_getHeadersRange.
+func TestGetHeadersRange(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 4, nil, nil)
+	defer pm.Stop()
+	api := NewPublicTangerineAPI(&Tangerine{blockchain: pm.blockchain})
+
+	tests := []struct {
+		from, count uint64
+		numbers     []uint64 // nil if the range is rejected
+	}{
+		{0, 2, []uint64{0, 1}},
+		{3, 10, []uint64{3, 4}}, // cut at the head
+		{5, 2, []uint64{}},      // past the head
+		{1, 0, []uint64{}},
+		{1, maxHeadersRange + 1, nil},
+		{math.MaxUint64, 2, nil}, // wrapping around
+	}
+	for i, tt := range tests {
+		headers, err := api.GetHeadersRange(hexutil.Uint64(tt.from), hexutil.Uint64(tt.count))
+		if tt.numbers == nil {
+			if err == nil {
+				t.Errorf("test %d: range %d+%d not rejected", i, tt.from, tt.count)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to get headers: %v", i, err)
+			continue
+		}
+		if len(headers) != len(tt.numbers) {
+			t.Errorf("test %d: header count mismatch: have %d, want %d", i, len(headers), len(tt.numbers))
+			continue
+		}
+		for j, header := range headers {
+			want := pm.blockchain.GetHeaderByNumber(tt.numbers[j])
+			if uint64(header.Number) != tt.numbers[j] || header.Hash != want.Hash() ||
+				header.ParentHash != want.ParentHash {
+				t.Errorf("test %d: header %d mismatch: have %d %x, want %d %x",
+					i, j, header.Number, header.Hash, tt.numbers[j], want.Hash())
+			}
+		}
+	}
+}
+
+// Tests that the configuration of a round is decoded once and that the gas
+// price suggested from it can't alter the cached snapshot.
+func TestConfigSnapshotCache(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+
+	snapshot, err := dex.governance.GetConfigSnapshot(0)
+	if err != nil {
+		t.Fatalf("failed to get config snapshot: %v", err)
+	}
+	if cached, _ := dex.governance.GetConfigSnapshot(0); cached != snapshot {
+		t.Errorf("config snapshot decoded again")
+	}
+	gs, err := dex.governance.GetConfigState(0)
+	if err != nil {
+		t.Fatalf("failed to get config state: %v", err)
+	}
+	if have, want := snapshot.Config.MinGasPrice, gs.MinGasPrice(); have.Cmp(want) != 0 {
+		t.Errorf("min gas price mismatch: have %v, want %v", have, want)
+	}
+
+	price, err := dex.APIBackend.SuggestPrice(context.Background())
+	if err != nil {
+		t.Fatalf("failed to suggest price: %v", err)
+	}
+	price.SetInt64(0)
+	if snapshot.Config.MinGasPrice.Cmp(gs.MinGasPrice()) != 0 {
+		t.Errorf("suggested price modified the snapshot")
+	}
+}
+
+// Tests that concurrent retries of a batch, and keys repeated within a batch,
+// send the transaction once.
+func TestSendRawTransactionsRetries(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	dex, accounts, err := newTangerine(key, 1)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+	defer dex.txPool.Stop()
+	dex.config = &Config{}
+	dex.protocolManager = &ProtocolManager{txProps: newTxPropagations(16)}
+	api := NewPublicTangerineAPI(dex)
+
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), 21000, dex.chainConfig.Dexcon.MinGasPrice, nil), signer, accounts[0])
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	enc, _ := rlp.EncodeToBytes(tx)
+
+	var (
+		wg      sync.WaitGroup
+		start   = make(chan struct{})
+		results = make([][]*SendTxResult, 32)
+	)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			results[i] = api.SendRawTransactions(context.Background(), []RawTxRequest{
+				{Tx: enc, Key: "retry"},
+				{Tx: enc, Key: "retry"},
+			})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	sent := 0
+	for _, batch := range results {
+		for j, result := range batch {
+			if result.Error != "" || result.Hash == nil || *result.Hash != tx.Hash() {
+				t.Fatalf("retry failed: %+v", result)
+			}
+			if !result.Replayed {
+				if j != 0 {
+					t.Errorf("repeated key in batch not replayed")
+				}
+				sent++
+			}
+		}
+	}
+	if sent != 1 {
+		t.Errorf("sent count mismatch: have %d, want 1", sent)
+	}
+	if pending, _ := dex.txPool.Stats(); pending != 1 {
+		t.Errorf("pending count mismatch: have %d, want 1", pending)
+	}
+	// Keys outlive the API instance, as they do a restart.
+	result := NewPublicTangerineAPI(dex).SendRawTransactions(context.Background(), []RawTxRequest{{Tx: enc, Key: "retry"}})
+	if !result[0].Replayed || result[0].Hash == nil || *result[0].Hash != tx.Hash() {
+		t.Errorf("key not replayed by a new API instance: %+v", result[0])
+	}
+}
+
+// Tests that the polling filters of the eth namespace report the blocks and
+// pending transactions of a dex node until uninstalled.
+func TestFilterAPI(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dex, keys, err := newTangerine(key, 1)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+	dex.eventMux = new(event.TypeMux)
+	api := filters.NewPublicFilterAPIWithTimeout(dex.APIBackend, false, time.Minute)
+
+	blockFilter := api.NewBlockFilter()
+	txFilter := api.NewPendingTransactionFilter()
+	logFilter, err := api.NewFilter(filters.FilterCriteria{})
+	if err != nil {
+		t.Fatalf("failed to create log filter: %v", err)
+	}
+
+	block := newTestConfirmedBlock(1, time.Now())
+	dex.app.BlockConfirmed(*block)
+	dex.app.BlockDelivered(block.Hash, block.Position, nil)
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), 21000,
+		big.NewInt(1e9), nil), types.NewEIP155Signer(dex.chainConfig.ChainID), keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dex.txPool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+
+	changes := func(id rpc.ID) []common.Hash {
+		for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+			result, err := api.GetFilterChanges(id)
+			if err != nil {
+				t.Fatalf("failed to get filter changes: %v", err)
+			}
+			if hashes := result.([]common.Hash); len(hashes) > 0 {
+				return hashes
+			}
+		}
+		return nil
+	}
+	if hashes := changes(blockFilter); len(hashes) != 1 || hashes[0] != dex.blockchain.CurrentBlock().Hash() {
+		t.Errorf("block filter changes mismatch: %x", hashes)
+	}
+	if hashes := changes(txFilter); len(hashes) != 1 || hashes[0] != tx.Hash() {
+		t.Errorf("pending transaction filter changes mismatch: %x", hashes)
+	}
+	if result, err := api.GetFilterChanges(logFilter); err != nil || len(result.([]*types.Log)) != 0 {
+		t.Errorf("log filter changes mismatch: %v, %v", result, err)
+	}
+
+	for _, id := range []rpc.ID{blockFilter, txFilter, logFilter} {
+		if !api.UninstallFilter(id) {
+			t.Errorf("filter %s not uninstalled", id)
+		}
+		if _, err := api.GetFilterChanges(id); err == nil {
+			t.Errorf("uninstalled filter %s still polled", id)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	dexCore "github.com/portto/tangerine-consensus/core"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/common/math"
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/rpc"
+)
+
+// newGovernanceAPI returns a governance API over the testnet genesis, with
+// the master node staked and an unstaked account.
+func newGovernanceAPI(t *testing.T) (*PublicGovernanceAPI, *ecdsa.PrivateKey, *ecdsa.PrivateKey) {
+	master, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dex, accounts, err := newTangerine(master, 1)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+	return NewPublicGovernanceAPI(dex), master, accounts[0]
+}
+
+// Tests that the summary adds up the genesis stakes and balances.
+func TestGovernanceSummary(t *testing.T) {
+	api, _, _ := newGovernanceAPI(t)
+
+	// The testnet nodes, the master node and the unstaked account.
+	genesis := core.DefaultTestnetGenesisBlock()
+	var (
+		nodes  uint64 = 1
+		staked        = big.NewInt(50000000000000000)
+		supply        = new(big.Int).Add(big.NewInt(100000000000000000), math.BigPow(10, 20))
+	)
+	for _, account := range genesis.Alloc {
+		if account.Staked.Sign() > 0 {
+			nodes++
+		}
+		staked.Add(staked, account.Staked)
+		supply.Add(supply, account.Balance)
+	}
+
+	summary, err := api.Summary(context.Background(), rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to get summary: %v", err)
+	}
+	if uint64(summary.NodeCount) != nodes {
+		t.Errorf("node count mismatch: have %d, want %d", summary.NodeCount, nodes)
+	}
+	if summary.TotalStaked.ToInt().Cmp(staked) != 0 {
+		t.Errorf("total staked mismatch: have %v, want %v", summary.TotalStaked, staked)
+	}
+	if summary.TotalSupply.ToInt().Cmp(supply) != 0 {
+		t.Errorf("total supply mismatch: have %v, want %v", summary.TotalSupply, supply)
+	}
+	if summary.MinStake.ToInt().Cmp(genesis.Config.Dexcon.MinStake) != 0 {
+		t.Errorf("min stake mismatch: have %v, want %v", summary.MinStake, genesis.Config.Dexcon.MinStake)
+	}
+	// The first DKG runs for the round after the delay rounds.
+	if summary.CRSRound != 0 || uint64(summary.DKGRound) != dexCore.DKGDelayRound {
+		t.Errorf("rounds mismatch: have CRS %d DKG %d, want 0 and %d",
+			summary.CRSRound, summary.DKGRound, dexCore.DKGDelayRound)
+	}
+}
+
+// Tests that nodes are looked up by owner, and that owners without a node
+// get none.
+func TestGovernanceNode(t *testing.T) {
+	api, master, unstaked := newGovernanceAPI(t)
+
+	owner := crypto.PubkeyToAddress(master.PublicKey)
+	node, err := api.Node(context.Background(), owner, rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to get node: %v", err)
+	}
+	if node == nil {
+		t.Fatal("master node not found")
+	}
+	if node.Owner != owner || !bytes.Equal(node.PublicKey, crypto.FromECDSAPub(&master.PublicKey)) {
+		t.Errorf("node identity mismatch: have %x %x", node.Owner, node.PublicKey)
+	}
+	if node.Staked.ToInt().Cmp(big.NewInt(50000000000000000)) != 0 || node.Fined.ToInt().Sign() != 0 {
+		t.Errorf("node stake mismatch: staked %v fined %v", node.Staked, node.Fined)
+	}
+
+	for _, owner := range []common.Address{crypto.PubkeyToAddress(unstaked.PublicKey), {1}} {
+		node, err := api.Node(context.Background(), owner, rpc.LatestBlockNumber)
+		if err != nil || node != nil {
+			t.Errorf("node of %x mismatch: have %+v, %v, want none", owner, node, err)
+		}
+	}
+}
+
+// Tests that all nodes are listed, consistently with the summary and the
+// lookups by owner.
+func TestGovernanceNodes(t *testing.T) {
+	api, master, _ := newGovernanceAPI(t)
+
+	nodes, err := api.Nodes(context.Background(), rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to get nodes: %v", err)
+	}
+	summary, err := api.Summary(context.Background(), rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to get summary: %v", err)
+	}
+	if uint64(len(nodes)) != uint64(summary.NodeCount) {
+		t.Fatalf("node count mismatch: have %d, want %d", len(nodes), summary.NodeCount)
+	}
+	staked := new(big.Int)
+	found := false
+	for _, node := range nodes {
+		staked.Add(staked, node.Staked.ToInt())
+		byOwner, err := api.Node(context.Background(), node.Owner, rpc.LatestBlockNumber)
+		if err != nil || byOwner == nil || !bytes.Equal(byOwner.PublicKey, node.PublicKey) {
+			t.Errorf("node %x mismatch with its lookup: %+v, %v", node.Owner, byOwner, err)
+		}
+		found = found || node.Owner == crypto.PubkeyToAddress(master.PublicKey)
+	}
+	if !found {
+		t.Errorf("master node not listed")
+	}
+	if staked.Cmp(summary.TotalStaked.ToInt()) != 0 {
+		t.Errorf("listed stakes mismatch: have %v, want %v", staked, summary.TotalStaked)
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/common/hexutil"
+	"github.com/portto/go-tangerine/core/state"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/rlp"
+	"github.com/portto/go-tangerine/rpc"
+	"github.com/portto/go-tangerine/trie"
+)
+
+// maxStateDiffLeaves is the maximum number of changed accounts, and of
+// changed storage slots per account, a state diff may hold.
+const maxStateDiffLeaves = 10000
+
+// AccountSnapshot is the state of an account in a state diff.
+type AccountSnapshot struct {
+	Nonce       hexutil.Uint64 `json:"nonce"`
+	Balance     *hexutil.Big   `json:"balance"`
+	CodeHash    common.Hash    `json:"codeHash"`
+	StorageRoot common.Hash    `json:"storageRoot"`
+}
+
+// StorageChange is the change of a storage slot in a state diff, absent slots
+// being zero.
+type StorageChange struct {
+	From common.Hash `json:"from"`
+	To   common.Hash `json:"to"`
+}
+
+// AccountDiff is the change of an account between two states. From is nil for
+// created accounts and To is nil for deleted ones. Storage slots are keyed by
+// slot, or by hashed slot if its preimage is unknown.
+type AccountDiff struct {
+	From    *AccountSnapshot               `json:"from"`
+	To      *AccountSnapshot               `json:"to"`
+	Storage map[common.Hash]*StorageChange `json:"storage,omitempty"`
+}
+
+// StateDiff is the set of accounts changed between two states.
+type StateDiff map[common.Address]*AccountDiff
+
+// StateDiff returns the accounts and storage slots changed between the states
+// of the finalized blocks a and b, restricted to addresses if not empty. The
+// diff is computed by walking the state tries, both states have to be
+// available in the database.
+func (api *PrivateDebugAPI) StateDiff(ctx context.Context, a, b rpc.BlockNumber, addresses []common.Address) (StateDiff, error) {
+	from, err := api.finalizedBlock(a)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.finalizedBlock(b)
+	if err != nil {
+		return nil, err
+	}
+	return diffStates(api.dex.blockchain.StateCache().TrieDB(), from.Root(), to.Root(), addresses)
+}
+
+// finalizedBlock returns the canonical block number, all of which are final.
+func (api *PrivateDebugAPI) finalizedBlock(number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return api.dex.blockchain.CurrentBlock(), nil
+	}
+	block := api.dex.blockchain.GetBlockByNumber(uint64(number))
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	return block, nil
+}
+
+// diffStates computes the diff between the states of root from and root to.
+func diffStates(db *trie.Database, from, to common.Hash, addresses []common.Address) (StateDiff, error) {
+	fromTrie, err := trie.NewSecure(from, db, 0)
+	if err != nil {
+		return nil, err
+	}
+	toTrie, err := trie.NewSecure(to, db, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[common.Address][2][]byte)
+	if len(addresses) == 0 {
+		leaves, err := diffLeaves(fromTrie, toTrie)
+		if err != nil {
+			return nil, err
+		}
+		for hash, values := range leaves {
+			key := toTrie.GetKey(hash[:])
+			if key == nil {
+				key = fromTrie.GetKey(hash[:])
+			}
+			if key == nil {
+				return nil, fmt.Errorf("no preimage found for hash %x", hash)
+			}
+			changes[common.BytesToAddress(key)] = values
+		}
+	} else {
+		for _, address := range addresses {
+			fromValue, err := fromTrie.TryGet(address[:])
+			if err != nil {
+				return nil, err
+			}
+			toValue, err := toTrie.TryGet(address[:])
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(fromValue, toValue) {
+				changes[address] = [2][]byte{fromValue, toValue}
+			}
+		}
+	}
+
+	diff := make(StateDiff, len(changes))
+	for address, values := range changes {
+		accountDiff := new(AccountDiff)
+		var fromRoot, toRoot common.Hash
+		if accountDiff.From, err = decodeAccountSnapshot(values[0]); err != nil {
+			return nil, err
+		} else if accountDiff.From != nil {
+			fromRoot = accountDiff.From.StorageRoot
+		}
+		if accountDiff.To, err = decodeAccountSnapshot(values[1]); err != nil {
+			return nil, err
+		} else if accountDiff.To != nil {
+			toRoot = accountDiff.To.StorageRoot
+		}
+		if fromRoot != toRoot {
+			if accountDiff.Storage, err = diffStorage(db, fromRoot, toRoot); err != nil {
+				return nil, fmt.Errorf("account %x: %v", address, err)
+			}
+		}
+		diff[address] = accountDiff
+	}
+	return diff, nil
+}
+
+// diffStorage computes the storage slots changed between the storage tries of
+// root from and root to.
+func diffStorage(db *trie.Database, from, to common.Hash) (map[common.Hash]*StorageChange, error) {
+	fromTrie, err := trie.NewSecure(from, db, 0)
+	if err != nil {
+		return nil, err
+	}
+	toTrie, err := trie.NewSecure(to, db, 0)
+	if err != nil {
+		return nil, err
+	}
+	leaves, err := diffLeaves(fromTrie, toTrie)
+	if err != nil {
+		return nil, err
+	}
+	storage := make(map[common.Hash]*StorageChange, len(leaves))
+	for hash, values := range leaves {
+		slot := hash
+		if key := toTrie.GetKey(hash[:]); key != nil {
+			slot = common.BytesToHash(key)
+		} else if key := fromTrie.GetKey(hash[:]); key != nil {
+			slot = common.BytesToHash(key)
+		}
+		change := new(StorageChange)
+		if change.From, err = decodeStorageValue(values[0]); err != nil {
+			return nil, err
+		}
+		if change.To, err = decodeStorageValue(values[1]); err != nil {
+			return nil, err
+		}
+		storage[slot] = change
+	}
+	return storage, nil
+}
+
+// diffLeaves returns the leaves differing between the tries a and b, keyed by
+// hashed key, with their values in a and b, nil if absent.
+func diffLeaves(a, b *trie.SecureTrie) (map[common.Hash][2][]byte, error) {
+	leaves := make(map[common.Hash][2][]byte)
+	collect := func(x, y *trie.SecureTrie, side int) error {
+		diff, _ := trie.NewDifferenceIterator(x.NodeIterator(nil), y.NodeIterator(nil))
+		it := trie.NewIterator(diff)
+		for it.Next() {
+			hash := common.BytesToHash(it.Key)
+			values := leaves[hash]
+			values[side] = it.Value
+			leaves[hash] = values
+			if len(leaves) > maxStateDiffLeaves {
+				return fmt.Errorf("more than %d changes, narrow the diff", maxStateDiffLeaves)
+			}
+		}
+		return it.Err
+	}
+	if err := collect(b, a, 0); err != nil {
+		return nil, err
+	}
+	if err := collect(a, b, 1); err != nil {
+		return nil, err
+	}
+	return leaves, nil
+}
+
+func decodeAccountSnapshot(data []byte) (*AccountSnapshot, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var account state.Account
+	if err := rlp.DecodeBytes(data, &account); err != nil {
+		return nil, err
+	}
+	return &AccountSnapshot{
+		Nonce:       hexutil.Uint64(account.Nonce),
+		Balance:     (*hexutil.Big)(account.Balance),
+		CodeHash:    common.BytesToHash(account.CodeHash),
+		StorageRoot: account.Root,
+	}, nil
+}
+
+func decodeStorageValue(data []byte) (common.Hash, error) {
+	if len(data) == 0 {
+		return common.Hash{}, nil
+	}
+	_, content, _, err := rlp.Split(data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(content), nil
+}
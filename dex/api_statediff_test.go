@@ -0,0 +1,91 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/state"
+	"github.com/portto/go-tangerine/ethdb"
+)
+
+// Tests that state diffs report created, modified and deleted accounts and
+// storage slots.
+func TestDiffStates(t *testing.T) {
+	db := state.NewDatabase(ethdb.NewMemDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+
+	var (
+		modified  = common.Address{1}
+		storage   = common.Address{2}
+		created   = common.Address{3}
+		deleted   = common.Address{4}
+		untouched = common.Address{5}
+	)
+	statedb.SetBalance(modified, big.NewInt(1))
+	statedb.SetBalance(storage, big.NewInt(2))
+	statedb.SetState(storage, common.Hash{1}, common.Hash{1})
+	statedb.SetState(storage, common.Hash{2}, common.Hash{2})
+	statedb.SetBalance(deleted, big.NewInt(4))
+	statedb.SetBalance(untouched, big.NewInt(5))
+	from, _ := statedb.Commit(false)
+
+	statedb.SetBalance(modified, big.NewInt(10))
+	statedb.SetState(storage, common.Hash{1}, common.Hash{})
+	statedb.SetState(storage, common.Hash{3}, common.Hash{3})
+	statedb.SetBalance(created, big.NewInt(3))
+	statedb.Suicide(deleted)
+	to, _ := statedb.Commit(true)
+
+	diff, err := diffStates(db.TrieDB(), from, to, nil)
+	if err != nil {
+		t.Fatalf("failed to diff states: %v", err)
+	}
+	if len(diff) != 4 {
+		t.Fatalf("changed account count mismatch: have %d, want 4", len(diff))
+	}
+	if d := diff[modified]; d.From.Balance.ToInt().Int64() != 1 || d.To.Balance.ToInt().Int64() != 10 {
+		t.Errorf("modified account mismatch: %+v -> %+v", d.From, d.To)
+	}
+	if d := diff[created]; d.From != nil || d.To == nil {
+		t.Errorf("created account mismatch: %+v -> %+v", d.From, d.To)
+	}
+	if d := diff[deleted]; d.From == nil || d.To != nil {
+		t.Errorf("deleted account mismatch: %+v -> %+v", d.From, d.To)
+	}
+	slots := diff[storage].Storage
+	if len(slots) != 2 {
+		t.Fatalf("changed slot count mismatch: have %d, want 2", len(slots))
+	}
+	if c := slots[common.Hash{1}]; c == nil || c.From != (common.Hash{1}) || c.To != (common.Hash{}) {
+		t.Errorf("cleared slot mismatch: %+v", c)
+	}
+	if c := slots[common.Hash{3}]; c == nil || c.From != (common.Hash{}) || c.To != (common.Hash{3}) {
+		t.Errorf("set slot mismatch: %+v", c)
+	}
+
+	diff, err = diffStates(db.TrieDB(), from, to, []common.Address{modified, untouched})
+	if err != nil {
+		t.Fatalf("failed to diff filtered states: %v", err)
+	}
+	if len(diff) != 1 || diff[modified] == nil {
+		t.Errorf("filtered diff mismatch: %v", diff)
+	}
+}
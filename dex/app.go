@@ -22,6 +22,7 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"time"
 
@@ -30,10 +31,12 @@ import (
 
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/rawdb"
 	"github.com/portto/go-tangerine/core/types"
 	"github.com/portto/go-tangerine/ethdb"
 	"github.com/portto/go-tangerine/event"
 	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/params"
 	"github.com/portto/go-tangerine/rlp"
 )
 
@@ -45,6 +48,14 @@ type DexconApp struct {
 	chainDB    ethdb.Database
 	config     *Config
 
+	// arrivals tracks the order transactions arrive in the pool, nil if
+	// unused by the transaction ordering policy.
+	arrivals *txArrivals
+
+	// networkTime returns the time agreed by the network, which bounds the
+	// timestamps of the blocks to verify.
+	networkTime func() time.Time
+
 	finalizedBlockFeed event.Feed
 	scope              event.SubscriptionScope
 
@@ -60,18 +71,57 @@ type DexconApp struct {
 
 func NewDexconApp(txPool *core.TxPool, blockchain *core.BlockChain, gov *DexconGovernance,
 	chainDB ethdb.Database, config *Config) *DexconApp {
-	return &DexconApp{
+	app := &DexconApp{
 		txPool:          txPool,
 		blockchain:      blockchain,
 		gov:             gov,
 		chainDB:         chainDB,
 		config:          config,
+		networkTime:     time.Now,
 		confirmedBlocks: map[coreCommon.Hash]*blockInfo{},
 		addressNonce:    map[common.Address]uint64{},
 		addressCost:     map[common.Address]*big.Int{},
 		addressCounter:  map[common.Address]uint64{},
 		deliveredHeight: blockchain.CurrentBlock().NumberU64(),
 	}
+	switch config.TxOrdering {
+	case TxOrderFIFO, TxOrderFair:
+		app.arrivals = newTxArrivals(txPool)
+	}
+	// Restoring drops stored blocks, and safe mode must not modify the
+	// database. Nothing is delivered in safe mode anyway.
+	if !config.SafeMode {
+		app.restoreConfirmedBlocks()
+	}
+	return app
+}
+
+// restoreConfirmedBlocks reloads the blocks confirmed before a restart and
+// not delivered yet, so that they can still be delivered. Blocks delivered
+// before the restart but not removed yet are dropped.
+func (d *DexconApp) restoreConfirmedBlocks() {
+	head := d.blockchain.CurrentBlock().NumberU64()
+	var blocks []*coreTypes.Block
+	for _, block := range rawdb.ReadAppConfirmedBlocks(d.chainDB) {
+		if block.Position.Height <= head {
+			rawdb.DeleteAppConfirmedBlock(d.chainDB, common.Hash(block.Hash))
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Position.Height < blocks[j].Position.Height
+	})
+	for _, block := range blocks {
+		if err := d.addConfirmedBlock(block); err != nil {
+			log.Error("Failed to restore confirmed block", "hash", block.Hash,
+				"position", block.Position, "err", err)
+			rawdb.DeleteAppConfirmedBlock(d.chainDB, common.Hash(block.Hash))
+		}
+	}
+	if len(d.confirmedBlocks) > 0 {
+		log.Info("Restored confirmed blocks", "count", len(d.confirmedBlocks))
+	}
 }
 
 // validateNonce check if nonce is in order and return first nonce of every address.
@@ -118,6 +168,8 @@ func (d *DexconApp) validateGasPrice(txs types.Transactions, round uint64) bool
 
 // PreparePayload is called when consensus core is preparing payload for block.
 func (d *DexconApp) PreparePayload(position coreTypes.Position) (payload []byte, err error) {
+	d.waitIdle(position)
+
 	// softLimit limits the runtime of inner call to preparePayload.
 	// hardLimit limits the runtime of outer PreparePayload.
 	// If hardLimit is hit, it is possible that no payload is prepared.
@@ -156,6 +208,46 @@ func (d *DexconApp) PreparePayload(position coreTypes.Position) (payload []byte,
 	return
 }
 
+// waitIdle holds the proposal back while the tx pool is empty, until a
+// transaction arrives or the governance idle block interval has passed since
+// the last delivered block. The wait never exceeds half of lambdaBA, so the
+// proposal still reaches the agreement before it falls back to an empty block.
+// Nothing is held back before the idle block interval fork.
+func (d *DexconApp) waitIdle(position coreTypes.Position) {
+	if !d.blockchain.Config().IsIdleBlockInterval(new(big.Int).SetUint64(position.Height)) {
+		return
+	}
+	config, err := d.gov.RawConfiguration(position.Round)
+	if err != nil || config.IdleBlockInterval == 0 {
+		return
+	}
+
+	// Subscribe before checking the pool, not to miss a transaction arriving
+	// in between.
+	txCh := make(chan core.NewTxsEvent, 1)
+	sub := d.txPool.SubscribeNewTxsEvent(txCh)
+	defer sub.Unsubscribe()
+	if pending, _ := d.txPool.Stats(); pending > 0 {
+		return
+	}
+
+	tip := time.Unix(0, int64(d.blockchain.CurrentBlock().Time())*int64(time.Millisecond))
+	wait := time.Until(tip.Add(time.Duration(config.IdleBlockInterval) * time.Millisecond))
+	if limit := time.Duration(config.LambdaBA) * time.Millisecond / 2; wait > limit {
+		wait = limit
+	}
+	if wait <= 0 {
+		return
+	}
+	log.Debug("Idle proposer waiting for transactions", "position", position.String(), "wait", wait)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-txCh:
+	case <-timer.C:
+	}
+}
+
 func (d *DexconApp) preparePayload(ctx context.Context, position coreTypes.Position) (
 	payload []byte, err error) {
 	d.appMu.RLock()
@@ -180,6 +272,10 @@ func (d *DexconApp) preparePayload(ctx context.Context, position coreTypes.Posit
 
 	log.Debug("Prepare payload", "height", position.Height)
 
+	var mark uint64
+	if d.arrivals != nil {
+		mark = d.arrivals.mark()
+	}
 	txsMap, err := d.txPool.Pending()
 	if err != nil {
 		return
@@ -190,9 +286,7 @@ func (d *DexconApp) preparePayload(ctx context.Context, position coreTypes.Posit
 		return
 	}
 
-	blockGasLimit := new(big.Int).SetUint64(config.BlockGasLimit)
-	blockGasUsed := new(big.Int)
-	allTxs := make([]*types.Transaction, 0, 10000)
+	candidates := make(map[common.Address]types.Transactions, len(txsMap))
 
 addressMap:
 	for address, txs := range txsMap {
@@ -222,6 +316,7 @@ addressMap:
 
 		firstNonce := txs[0].Nonce()
 		startIndex := int(expectNonce - firstNonce)
+		endIndex := startIndex
 
 		// Warning: the pending tx will also affect by syncing, so startIndex maybe negative
 		for i := startIndex; i >= 0 && i < len(txs); i++ {
@@ -246,14 +341,36 @@ addressMap:
 				log.Warn("Insufficient funds for gas * price + value", "txHash", tx.Hash().String())
 				break
 			}
+			endIndex = i + 1
+		}
+		if endIndex > startIndex {
+			candidates[address] = txs[startIndex:endIndex]
+		}
+	}
 
-			blockGasUsed = new(big.Int).Add(blockGasUsed, big.NewInt(int64(tx.Gas())))
-			if blockGasUsed.Cmp(blockGasLimit) > 0 {
-				break addressMap
-			}
+	var ordered txOrdering
+	switch d.config.TxOrdering {
+	case TxOrderFIFO:
+		ordered = newTxsByArrival(candidates, d.arrivals.arrival(txsMap, mark))
+	case TxOrderFair:
+		ordered = newTxsRoundRobin(candidates, d.arrivals.arrival(txsMap, mark))
+	default:
+		signer := types.MakeSigner(d.blockchain.Config(), new(big.Int).SetUint64(position.Height))
+		ordered = types.NewTransactionsByPriceAndNonce(signer, candidates)
+	}
 
-			allTxs = append(allTxs, tx)
+	// Transactions exceeding the remaining gas skip their sender, leaving
+	// room for the smaller ones of the others.
+	gasLeft := config.BlockGasLimit
+	allTxs := make([]*types.Transaction, 0, 10000)
+	for tx := ordered.Peek(); tx != nil && gasLeft >= params.TxGas; tx = ordered.Peek() {
+		if tx.Gas() > gasLeft {
+			ordered.Pop()
+			continue
 		}
+		gasLeft -= tx.Gas()
+		allTxs = append(allTxs, tx)
+		ordered.Shift()
 	}
 
 	return rlp.EncodeToBytes(&allTxs)
@@ -322,6 +439,10 @@ func (d *DexconApp) VerifyBlock(block *coreTypes.Block) coreTypes.BlockVerifySta
 		return coreTypes.VerifyRetryLater
 	}
 
+	if status := d.verifyTimestamp(block); status != coreTypes.VerifyOK {
+		return status
+	}
+
 	var transactions types.Transactions
 	if len(block.Payload) == 0 {
 		return coreTypes.VerifyOK
@@ -426,6 +547,9 @@ func (d *DexconApp) VerifyBlock(block *coreTypes.Block) coreTypes.BlockVerifySta
 }
 
 // BlockDelivered is called when a block is add to the compaction chain.
+// Blocks are executed exactly once and in height order: a block delivered
+// again after a restart is ignored, while a block skipping a height is a
+// fatal error.
 func (d *DexconApp) BlockDelivered(
 	blockHash coreCommon.Hash,
 	blockPosition coreTypes.Position,
@@ -437,34 +561,76 @@ func (d *DexconApp) BlockDelivered(
 	d.appMu.Lock()
 	defer d.appMu.Unlock()
 
+	head := d.blockchain.CurrentBlock().NumberU64()
+	if blockPosition.Height > head+1 {
+		panic(fmt.Errorf("block delivered out of order: height %d, head %d",
+			blockPosition.Height, head))
+	}
 	block, txs := d.getConfirmedBlockByHash(blockHash)
 	if block == nil {
+		// Blocks delivered before a restart may be delivered again, those
+		// must match the canonical block at their height.
+		if blockPosition.Height <= head && d.isDelivered(blockHash, blockPosition.Height) {
+			log.Debug("Ignore block delivered before restart", "hash", blockHash,
+				"position", blockPosition)
+			return
+		}
 		panic("Can not get confirmed block")
 	}
 
-	block.Payload = nil
 	block.Randomness = rand
-	dexconMeta, err := rlp.EncodeToBytes(block)
-	if err != nil {
+	if err := d.executeBlock(block, txs); err != nil {
 		panic(err)
 	}
 
+	d.removeConfirmedBlock(blockHash)
+	d.deliveredHeight = block.Position.Height
+
+	// New blocks are finalized, notify other components.
+	go d.finalizedBlockFeed.Send(core.NewFinalizedBlockEvent{Block: d.blockchain.CurrentBlock()})
+}
+
+// isDelivered checks if the canonical block at height was built from the core
+// block of hash.
+func (d *DexconApp) isDelivered(hash coreCommon.Hash, height uint64) bool {
+	block := d.blockchain.GetBlockByNumber(height)
+	if block == nil {
+		return false
+	}
+	coreBlock, err := block.Header().CoreBlock()
+	if err != nil {
+		log.Error("Failed to decode dexcon meta", "number", height, "err", err)
+		return false
+	}
+	return coreBlock.Hash == hash
+}
+
+// executeBlock executes the delivered block carrying txs and inserts it into
+// the blockchain.
+func (d *DexconApp) executeBlock(block *coreTypes.Block, txs types.Transactions) error {
+	block.Payload = nil
+	dexconMeta, err := types.EncodeDexconMeta(block, d.blockchain.Config().IsCompactDexconMeta(
+		new(big.Int).SetUint64(block.Position.Height)))
+	if err != nil {
+		return err
+	}
+
 	var owner common.Address
 	if !block.IsEmpty() {
 		gs, err := d.gov.GetConfigState(block.Position.Round)
 		if err != nil {
-			panic(err)
+			return err
 		}
 		node, err := gs.GetNodeByID(block.ProposerID)
 		if err != nil {
-			panic(err)
+			return err
 		}
 		owner = node.Owner
 	}
 
 	config, err := d.gov.RawConfiguration(block.Position.Round)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	newBlock := types.NewBlock(&types.Header{
@@ -482,21 +648,16 @@ func (d *DexconApp) BlockDelivered(
 		_, err = d.blockchain.ProcessEmptyBlock(newBlock)
 		if err != nil {
 			log.Error("Failed to process empty block", "error", err)
-			panic(err)
+			return err
 		}
 	} else {
 		_, err = d.blockchain.ProcessBlock(newBlock, &block.Witness)
 		if err != nil {
 			log.Error("Failed to process pending block", "error", err)
-			panic(err)
+			return err
 		}
 	}
-
-	d.removeConfirmedBlock(blockHash)
-	d.deliveredHeight = block.Position.Height
-
-	// New blocks are finalized, notify other components.
-	go d.finalizedBlockFeed.Send(core.NewFinalizedBlockEvent{Block: d.blockchain.CurrentBlock()})
+	return nil
 }
 
 // BlockConfirmed is called when a block is confirmed.
@@ -507,9 +668,15 @@ func (d *DexconApp) BlockConfirmed(block coreTypes.Block) {
 	defer d.appMu.Unlock()
 
 	log.Debug("DexconApp block confirmed", "block", block.String())
+	// Blocks restored after a restart may be confirmed again.
+	if _, exist := d.confirmedBlocks[block.Hash]; exist {
+		log.Debug("Ignore block confirmed twice", "hash", block.Hash)
+		return
+	}
 	if err := d.addConfirmedBlock(&block); err != nil {
 		panic(err)
 	}
+	rawdb.WriteAppConfirmedBlock(d.chainDB, &block)
 }
 
 type addressInfo struct {
@@ -590,6 +757,64 @@ func (d *DexconApp) removeConfirmedBlock(hash coreCommon.Hash) {
 
 	delete(d.confirmedBlocks, hash)
 	d.undeliveredNum--
+
+	rawdb.DeleteAppConfirmedBlock(d.chainDB, common.Hash(hash))
+}
+
+// verifyTimestamp checks that the block follows its parent by at least the
+// minimum block interval once the block interval fork is active, and is not
+// ahead of the network time by more than the core message future tolerance.
+// The caller must hold appMu.
+func (d *DexconApp) verifyTimestamp(block *coreTypes.Block) coreTypes.BlockVerifyStatus {
+	if d.blockchain.Config().IsBlockInterval(new(big.Int).SetUint64(block.Position.Height)) {
+		if status := d.verifyBlockInterval(block); status != coreTypes.VerifyOK {
+			return status
+		}
+	}
+
+	if tolerance := d.config.CoreMsgFutureTolerance; tolerance > 0 &&
+		block.Timestamp.After(d.networkTime().Add(tolerance)) {
+		log.Debug("Block timestamp ahead of network time", "hash", block.Hash,
+			"timestamp", block.Timestamp)
+		return coreTypes.VerifyRetryLater
+	}
+	return coreTypes.VerifyOK
+}
+
+// verifyBlockInterval checks that the block follows its parent by at least the
+// minimum block interval. The caller must hold appMu.
+func (d *DexconApp) verifyBlockInterval(block *coreTypes.Block) coreTypes.BlockVerifyStatus {
+	var (
+		parentTime  uint64
+		parentRound uint64
+	)
+	if d.undeliveredNum == 0 {
+		parent := d.blockchain.GetBlockByNumber(d.deliveredHeight)
+		if parent == nil || parent.NumberU64() == 0 {
+			return coreTypes.VerifyOK
+		}
+		parentTime, parentRound = parent.Time(), parent.Round()
+	} else {
+		parent, _ := d.getConfirmedBlockByHash(block.ParentHash)
+		if parent == nil {
+			log.Debug("Parent of block to verify not confirmed", "hash", block.Hash)
+			return coreTypes.VerifyRetryLater
+		}
+		parentTime = uint64(parent.Timestamp.UnixNano() / 1000000)
+		parentRound = parent.Position.Round
+	}
+	config, err := d.gov.RawConfiguration(parentRound)
+	if err != nil {
+		log.Error("Failed to get configuration", "round", parentRound, "err", err)
+		return coreTypes.VerifyRetryLater
+	}
+	blockTime := uint64(block.Timestamp.UnixNano() / 1000000)
+	if err := core.VerifyBlockInterval(parentTime, blockTime, config); err != nil {
+		log.Error("Invalid block timestamp", "hash", block.Hash,
+			"time", blockTime, "parentTime", parentTime, "err", err)
+		return coreTypes.VerifyInvalidBlock
+	}
+	return coreTypes.VerifyOK
 }
 
 func (d *DexconApp) getConfirmedBlockByHash(hash coreCommon.Hash) (*coreTypes.Block, types.Transactions) {
@@ -607,5 +832,8 @@ func (d *DexconApp) SubscribeNewFinalizedBlockEvent(
 }
 
 func (d *DexconApp) Stop() {
+	if d.arrivals != nil {
+		d.arrivals.Stop()
+	}
 	d.scope.Close()
 }
@@ -0,0 +1,230 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreEcdsa "github.com/portto/tangerine-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/rlp"
+)
+
+// newTestConfirmedBlock returns an empty core block at height, confirmed
+// after parentTime.
+func newTestConfirmedBlock(height uint64, parentTime time.Time) *coreTypes.Block {
+	return &coreTypes.Block{
+		Hash:      coreCommon.Hash(crypto.Keccak256Hash(new(big.Int).SetUint64(height).Bytes())),
+		Position:  coreTypes.Position{Height: height},
+		Timestamp: parentTime.Add(time.Second),
+	}
+}
+
+// restartApp replaces the application of dex as a restarted node would.
+func restartApp(dex *Tangerine) {
+	dex.app.Stop()
+	dex.app = NewDexconApp(dex.txPool, dex.blockchain, dex.governance, dex.chainDb,
+		dex.app.config)
+}
+
+func TestAppRestartBeforeDelivery(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+
+	first := newTestConfirmedBlock(1, time.Now())
+	second := newTestConfirmedBlock(2, first.Timestamp)
+	dex.app.BlockConfirmed(*first)
+	dex.app.BlockConfirmed(*second)
+
+	// Crash before delivery, the confirmed blocks are restored.
+	restartApp(dex)
+	if n := dex.app.undeliveredNum; n != 2 {
+		t.Fatalf("restored block count mismatch: got %d, want 2", n)
+	}
+	dex.app.BlockConfirmed(*first)
+	if n := dex.app.undeliveredNum; n != 2 {
+		t.Errorf("block confirmed twice counted: got %d, want 2", n)
+	}
+
+	dex.app.BlockDelivered(first.Hash, first.Position, nil)
+	dex.app.BlockDelivered(second.Hash, second.Position, nil)
+	if head := dex.blockchain.CurrentBlock().NumberU64(); head != 2 {
+		t.Errorf("head mismatch: got %d, want 2", head)
+	}
+	if blocks := rawdb.ReadAppConfirmedBlocks(dex.chainDb); len(blocks) != 0 {
+		t.Errorf("delivered blocks left stored: %d", len(blocks))
+	}
+}
+
+func TestAppRestartAfterExecution(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+
+	block := newTestConfirmedBlock(1, time.Now())
+	dex.app.BlockConfirmed(*block)
+	dex.app.BlockDelivered(block.Hash, block.Position, nil)
+	head := dex.blockchain.CurrentBlock().Hash()
+
+	// Crash after the block was executed but before it was removed.
+	rawdb.WriteAppConfirmedBlock(dex.chainDb, block)
+	restartApp(dex)
+	if n := dex.app.undeliveredNum; n != 0 {
+		t.Errorf("delivered block restored: %d", n)
+	}
+	if rawdb.ReadAppConfirmedBlock(dex.chainDb, common.Hash(block.Hash)) != nil {
+		t.Errorf("delivered block left stored")
+	}
+
+	// The consensus core delivers the block again.
+	dex.app.BlockDelivered(block.Hash, block.Position, nil)
+	if hash := dex.blockchain.CurrentBlock().Hash(); hash != head {
+		t.Errorf("head mismatch: got %x, want %x", hash, head)
+	}
+}
+
+func TestAppRestartSafeMode(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+
+	block := newTestConfirmedBlock(1, time.Now())
+	dex.app.BlockConfirmed(*block)
+	dex.app.BlockDelivered(block.Hash, block.Position, nil)
+	rawdb.WriteAppConfirmedBlock(dex.chainDb, block)
+
+	// Safe mode leaves the stored blocks untouched.
+	config := *dex.app.config
+	config.SafeMode = true
+	dex.app.config = &config
+	restartApp(dex)
+	if rawdb.ReadAppConfirmedBlock(dex.chainDb, common.Hash(block.Hash)) == nil {
+		t.Errorf("delivered block removed in safe mode")
+	}
+}
+
+func TestAppDeliverDivergentBlock(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+
+	block := newTestConfirmedBlock(1, time.Now())
+	dex.app.BlockConfirmed(*block)
+	dex.app.BlockDelivered(block.Hash, block.Position, nil)
+	restartApp(dex)
+
+	// Another block delivered at the height of the executed one.
+	divergent := coreCommon.Hash(crypto.Keccak256Hash([]byte("divergent")))
+	defer func() {
+		if recover() == nil {
+			t.Errorf("divergent block delivered at the head ignored")
+		}
+	}()
+	dex.app.BlockDelivered(divergent, block.Position, nil)
+}
+
+func TestAppDeliverOutOfOrder(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+
+	block := newTestConfirmedBlock(2, time.Now())
+	dex.app.BlockConfirmed(*block)
+	defer func() {
+		if recover() == nil {
+			t.Errorf("block skipping a height delivered")
+		}
+		if head := dex.blockchain.CurrentBlock().NumberU64(); head != 0 {
+			t.Errorf("head mismatch: got %d, want 0", head)
+		}
+	}()
+	dex.app.BlockDelivered(block.Hash, block.Position, nil)
+}
+
+func TestAppRestoreConfirmedPayload(t *testing.T) {
+	masterKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dex, keys, err := newTangerine(masterKey, 1)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	var txs types.Transactions
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{1}, big.NewInt(1),
+			21000, big.NewInt(1e9), nil), signer, keys[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+	}
+	payload, err := rlp.EncodeToBytes(txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := newTestConfirmedBlock(1, time.Now())
+	block.ProposerID = coreTypes.NewNodeID(coreEcdsa.NewPrivateKeyFromECDSA(masterKey).PublicKey())
+	block.Payload = payload
+	dex.app.BlockConfirmed(*block)
+
+	restartApp(dex)
+	sender := crypto.PubkeyToAddress(keys[0].PublicKey)
+	if nonce, ok := dex.app.addressNonce[sender]; !ok || nonce != 2 {
+		t.Errorf("restored nonce mismatch: got %d (%t), want 2", nonce, ok)
+	}
+	if _, restored := dex.app.getConfirmedBlockByHash(block.Hash); restored == nil {
+		t.Errorf("transactions of restored block missing")
+	}
+}
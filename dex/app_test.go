@@ -2355,7 +2355,7 @@ func newTangerine(masterKey *ecdsa.PrivateKey, accountNum int) (*Tangerine, []*e
 	dex.txPool = core.NewTxPool(txPoolConfig, chainConfig, dex.blockchain)
 
 	dex.APIBackend = &DexAPIBackend{dex, nil}
-	dex.governance = NewDexconGovernance(dex.APIBackend, dex.chainConfig, config.PrivateKey)
+	dex.governance = NewDexconGovernance(dex.APIBackend, dex.chainConfig, NewLocalSigner(config.PrivateKey))
 	engine.SetGovStateFetcher(dex.governance)
 	dex.app = NewDexconApp(dex.txPool, dex.blockchain, dex.governance, db, &config)
 
@@ -0,0 +1,138 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/log"
+)
+
+// Operations recorded in the audit log.
+const (
+	AuditStartProposer         = "startProposer"
+	AuditStopProposer          = "stopProposer"
+	AuditBanPeer               = "banPeer"
+	AuditUnlockAccount         = "unlockAccount"
+	AuditImportChain           = "importChain"
+	AuditExportChain           = "exportChain"
+	AuditRotateNodeKey         = "rotateNodeKey"
+	AuditCancelNodeKeyRotation = "cancelNodeKeyRotation"
+	AuditRekeyNotaryConns      = "rekeyNotaryConns"
+)
+
+// maxAuditEntries is the maximum number of audit log entries returned by a
+// single query.
+const maxAuditEntries = 1000
+
+// auditLog is an append-only log of the sensitive operations performed on
+// the node, letting the operators sharing a validator find out who did what
+// and when. Entries are never removed.
+type auditLog struct {
+	db             ethdb.Database
+	trustProxyUser bool // Whether basic-auth user names are set by an authenticating proxy
+
+	lock   sync.Mutex
+	length uint64 // Number of entries, the sequence number of the next one
+}
+
+func newAuditLog(db ethdb.Database, trustProxyUser bool) *auditLog {
+	return &auditLog{
+		db:             db,
+		trustProxyUser: trustProxyUser,
+		length:         rawdb.ReadAuditLogLength(db),
+	}
+}
+
+// record appends an operation requested by requester to the log, err being
+// its outcome. A nil log records nothing.
+func (a *auditLog) record(requester, operation, details string, err error) {
+	if a == nil {
+		return
+	}
+	entry := &types.AuditEntry{
+		Time:      time.Now().UnixNano() / int64(time.Millisecond),
+		Operation: operation,
+		Requester: requester,
+		Details:   details,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	log.Info("Audited operation", "operation", operation, "requester", requester,
+		"details", details, "err", err)
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	entry.Seq = a.length
+	batch := a.db.NewBatch()
+	rawdb.WriteAuditEntry(batch, entry)
+	rawdb.WriteAuditLogLength(batch, a.length+1)
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to write audit entry", "operation", operation, "err", err)
+		return
+	}
+	a.length++
+}
+
+// entries returns the entries from sequence number from on, at most
+// maxAuditEntries of them.
+func (a *auditLog) entries(from uint64) []*types.AuditEntry {
+	if a == nil {
+		return nil
+	}
+	a.lock.Lock()
+	length := a.length
+	a.lock.Unlock()
+
+	var entries []*types.AuditEntry
+	for seq := from; seq < length && len(entries) < maxAuditEntries; seq++ {
+		if entry := rawdb.ReadAuditEntry(a.db, seq); entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// requester identifies the requester of the RPC call of ctx by its remote
+// address. The basic-auth user name is supplied by the client, so it prefixes
+// the address only if an authenticating proxy in front of the node sets it.
+// Calls over HTTP and websocket carry the remote address, calls over IPC or
+// in-process are identified as local.
+func (a *auditLog) requester(ctx context.Context) string {
+	requester := "local"
+	if ctx == nil {
+		return requester
+	}
+	if remote, ok := ctx.Value("remote").(string); ok && remote != "" {
+		requester = remote
+	}
+	if a == nil || !a.trustProxyUser {
+		return requester
+	}
+	if user, ok := ctx.Value("user").(string); ok && user != "" {
+		requester = user + "@" + requester
+	}
+	return requester
+}
@@ -0,0 +1,127 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/rpc"
+)
+
+// Tests that audited operations are queryable in order, and kept across
+// restarts.
+func TestAuditLog(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	audit := newAuditLog(db, false)
+
+	audit.record(types.AuditNode, AuditStartProposer, "", nil)
+	audit.record("10.0.0.1:1234", AuditImportChain, "chain.rlp", errSafeMode)
+
+	entries := audit.entries(0)
+	if len(entries) != 2 {
+		t.Fatalf("entry count mismatch: have %d, want 2", len(entries))
+	}
+	if e := entries[0]; e.Seq != 0 || e.Operation != AuditStartProposer ||
+		e.Requester != types.AuditNode || e.Error != "" || e.Time == 0 {
+		t.Errorf("first entry mismatch: %+v", e)
+	}
+	if e := entries[1]; e.Seq != 1 || e.Operation != AuditImportChain ||
+		e.Details != "chain.rlp" || e.Error != errSafeMode.Error() {
+		t.Errorf("second entry mismatch: %+v", e)
+	}
+
+	// Reopening the log appends after the stored entries.
+	audit = newAuditLog(db, false)
+	audit.record("local", AuditBanPeer, "", errors.New("oops"))
+	entries = audit.entries(1)
+	if len(entries) != 2 || entries[1].Seq != 2 || entries[1].Operation != AuditBanPeer {
+		t.Fatalf("entries mismatch after reopening: %+v", entries)
+	}
+	if entries := audit.entries(3); len(entries) != 0 {
+		t.Errorf("entries past the end: %+v", entries)
+	}
+}
+
+func TestAuditRequester(t *testing.T) {
+	audit := newAuditLog(ethdb.NewMemDatabase(), false)
+	ctx := context.Background()
+	if have := audit.requester(ctx); have != "local" {
+		t.Errorf("requester mismatch: have %s, want local", have)
+	}
+	ctx = context.WithValue(ctx, "remote", "10.0.0.1:1234")
+	if have := audit.requester(ctx); have != "10.0.0.1:1234" {
+		t.Errorf("requester mismatch: have %s, want 10.0.0.1:1234", have)
+	}
+	// Client supplied user names are only trusted behind a proxy.
+	ctx = context.WithValue(ctx, "user", "alice")
+	if have := audit.requester(ctx); have != "10.0.0.1:1234" {
+		t.Errorf("requester mismatch: have %s, want 10.0.0.1:1234", have)
+	}
+	audit.trustProxyUser = true
+	if have := audit.requester(ctx); have != "alice@10.0.0.1:1234" {
+		t.Errorf("requester mismatch: have %s, want alice@10.0.0.1:1234", have)
+	}
+}
+
+// Tests that admin calls over websocket are recorded with the remote address
+// of the connection, prefixed by the basic-auth user behind a proxy.
+func TestAuditRequesterWebsocket(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dex := &Tangerine{
+		protocolManager: &ProtocolManager{peers: newPeerSet(&testGovernance{}, newTestP2PServer(key))},
+		audit:           newAuditLog(ethdb.NewMemDatabase(), false),
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("admin", NewPrivateAdminAPI(dex)); err != nil {
+		t.Fatal(err)
+	}
+	endpoint := httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+	defer endpoint.Close()
+	url := "ws://alice:secret@" + strings.TrimPrefix(endpoint.URL, "http://")
+
+	rekey := func() string {
+		client, err := rpc.DialWebsocket(context.Background(), url, "")
+		if err != nil {
+			t.Fatalf("failed to dial websocket: %v", err)
+		}
+		defer client.Close()
+		var scheduled int
+		if err := client.Call(&scheduled, "admin_rekeyNotaryConns", 0); err != nil {
+			t.Fatalf("failed to rekey notary conns: %v", err)
+		}
+		entries := dex.audit.entries(0)
+		return entries[len(entries)-1].Requester
+	}
+	if have := rekey(); !strings.HasPrefix(have, "127.0.0.1:") {
+		t.Errorf("requester mismatch: have %s, want 127.0.0.1:<port>", have)
+	}
+	dex.audit.trustProxyUser = true
+	if have := rekey(); !strings.HasPrefix(have, "alice@127.0.0.1:") {
+		t.Errorf("requester mismatch: have %s, want alice@127.0.0.1:<port>", have)
+	}
+}
@@ -18,6 +18,8 @@
 package dex
 
 import (
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
@@ -30,6 +32,7 @@ import (
 	"github.com/portto/go-tangerine/core/rawdb"
 	"github.com/portto/go-tangerine/core/vm"
 	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/dex/consensusnet"
 	"github.com/portto/go-tangerine/dex/downloader"
 	"github.com/portto/go-tangerine/eth/filters"
 	"github.com/portto/go-tangerine/eth/gasprice"
@@ -45,6 +48,9 @@ import (
 	"github.com/portto/tangerine-consensus/core/syncer"
 )
 
+// errSafeMode is returned by the calls modifying the chain in safe mode.
+var errSafeMode = errors.New("not available in safe mode")
+
 // Tangerine implements the DEXON fullnode service.
 type Tangerine struct {
 	config      *Config
@@ -71,16 +77,24 @@ type Tangerine struct {
 	APIBackend *DexAPIBackend
 
 	// Tangerine consensus.
+	signer     *rotatingSigner
 	app        *DexconApp
 	governance *DexconGovernance
-	network    *DexconNetwork
+	network    *consensusnet.Network
 
 	bp *blockProposer
 
+	dkgResetReporter *dkgResetReporter
+	roundNotifier    *roundNotifier
+	alerter          *alerter
+	keyRotator       *keyRotator
+	peerScaler       *peerScaler  // Nil if the peer limits are static
+	lightServer      *lightServer // Nil if light clients are not served
+	audit            *auditLog
+
 	networkID     uint64
 	netRPCService *ethapi.PublicNetAPI
-
-	etherbase common.Address
+	startTime     time.Time // Time the service was started
 
 	indexer indexer.Indexer
 }
@@ -98,6 +112,10 @@ func New(ctx *node.ServiceContext, config *Config) (*Tangerine, error) {
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
+	if (chainConfig.EWASMBlock != nil || chainConfig.EWASMRound != nil) && !vm.HasEWASMEngine(config.EWASMInterpreter) {
+		return nil, fmt.Errorf("unknown ewasm engine %q", config.EWASMInterpreter)
+	}
+
 	if !config.SkipBcVersionCheck {
 		bcVersion := rawdb.ReadDatabaseVersion(chainDb)
 		if bcVersion != nil && *bcVersion != core.BlockChainVersion {
@@ -119,6 +137,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Tangerine, error) {
 		bloomRequests:  make(chan chan *bloombits.Retrieval),
 		bloomIndexer:   NewBloomIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms),
 		engine:         engine,
+		audit:          newAuditLog(chainDb, config.AuditProxyUser),
 	}
 
 	var (
@@ -134,11 +153,32 @@ func New(ctx *node.ServiceContext, config *Config) (*Tangerine, error) {
 
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
-		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
-		dex.blockchain.SetHead(compat.RewindTo)
-		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
+		if config.SafeMode {
+			log.Warn("Not rewinding chain to upgrade configuration in safe mode", "err", compat)
+		} else {
+			log.Warn("Rewinding chain to upgrade configuration", "err", compat)
+			dex.blockchain.SetHead(compat.RewindTo)
+			rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
+		}
+	}
+	// Safe mode must not modify the database, so the transaction index is
+	// left as is.
+	if !config.SafeMode {
+		dex.blockchain.SetTxLookupLimit(config.TxLookupLimit)
+	}
+	dex.blockchain.SetExtendedReceipts(config.ExtendedReceipts)
+
+	// Refuse to misread a governance contract upgraded out-of-band.
+	headState, err := dex.blockchain.State()
+	if err != nil {
+		return nil, err
+	}
+	if err := (&vm.GovernanceState{StateDB: headState}).CheckLayoutVersion(); err != nil {
+		return nil, err
+	}
+	if !config.SafeMode {
+		dex.bloomIndexer.Start(dex.blockchain)
 	}
-	dex.bloomIndexer.Start(dex.blockchain)
 
 	if config.Indexer.Enable {
 		dex.indexer = indexer.NewIndexerFromConfig(
@@ -161,7 +201,22 @@ func New(ctx *node.ServiceContext, config *Config) (*Tangerine, error) {
 	dex.APIBackend.gpo = gasprice.NewOracle(dex.APIBackend, gpoParams)
 
 	// Dexcon related objects.
-	dex.governance = NewDexconGovernance(dex.APIBackend, dex.chainConfig, config.PrivateKey)
+	signer, err := newNodeSigner(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.ExternalSigner != "" {
+		log.Info("Signing with external signer", "endpoint", config.ExternalSigner)
+		if !signerIsNodeKey(signer, config.PrivateKey) {
+			log.Warn("Node key is not the signer key, the node cannot propose blocks")
+		}
+	}
+	dex.signer = newRotatingSigner(signer)
+	dex.governance = NewDexconGovernance(dex.APIBackend, dex.chainConfig, dex.signer)
+	if err := config.AdaptiveLambda.validate(); err != nil {
+		return nil, err
+	}
+	dex.governance.adaptiveLambda = config.AdaptiveLambda
 	dex.app = NewDexconApp(dex.txPool, dex.blockchain, dex.governance, chainDb, config)
 
 	// Set config fetcher so engine can fetch current system configuration from state.
@@ -175,6 +230,9 @@ func New(ctx *node.ServiceContext, config *Config) (*Tangerine, error) {
 		config.SyncMode = downloader.FullSync
 	}
 
+	if config.SyncMode == downloader.CheckpointSync && config.Checkpoint == nil {
+		return nil, errors.New("checkpoint sync requires a trusted checkpoint")
+	}
 	pm, err := NewProtocolManager(dex.chainConfig, config.SyncMode,
 		config.NetworkId, dex.eventMux, dex.txPool, dex.engine, dex.blockchain,
 		chainDb, config.Whitelist, config.BlockProposerEnabled, dex.governance, dex.app)
@@ -182,22 +240,83 @@ func New(ctx *node.ServiceContext, config *Config) (*Tangerine, error) {
 		return nil, err
 	}
 
+	pm.downloader.SetCheckpoint(config.Checkpoint)
+	pm.msgProfilingLabels = config.MsgProfilingLabels
+	if config.DKGPassphrase != "" {
+		pm.coreDB.SetPassphrase(config.DKGPassphrase)
+	}
+	pm.futureTolerance = config.CoreMsgFutureTolerance
+	pm.sigVerifier = newSigVerifier(config.SigVerifyWorkers)
+	pm.notaryPreconnectBlocks = config.NotaryPreconnectBlocks
+	if pm.blockFanout, pm.coreBlockFanout, pm.voteFanout, err = config.Fanout.parse(); err != nil {
+		return nil, fmt.Errorf("invalid fanout: %v", err)
+	}
+	if config.CoreBlockCacheSize > 0 && config.CoreFinalizedBlockCacheSize > 0 {
+		pm.cache = newSizedCache(defaultCacheSize, config.CoreBlockCacheSize,
+			config.CoreFinalizedBlockCacheSize, pm.coreDB)
+	}
+	dex.app.networkTime = pm.networkTime
+	if config.ScrubInterval > 0 {
+		pm.scrubber = newScrubber(chainDb, dex.blockchain, pm.peers, config.ScrubInterval)
+	}
+	if config.PeerHistoryRetention > 0 {
+		pm.peerHistory = newPeerHistory(chainDb, config.PeerHistoryRetention)
+	}
+	pm.audit = dex.audit
 	dex.protocolManager = pm
-	dex.network = NewDexconNetwork(pm)
+	dex.network = consensusnet.New(pm)
+
+	if !config.SafeMode {
+		if n, err := dex.app.replayDelivered(pm.coreDB); err != nil {
+			log.Error("Failed to replay delivered core blocks", "replayed", n, "err", err)
+		} else if n > 0 {
+			log.Info("Replayed delivered core blocks", "count", n)
+		}
+	}
+	if err := CheckCoreTip(dex.blockchain, pm.coreDB); err != nil {
+		return nil, fmt.Errorf("%v, run \"gtan repair-core-tip\" to rebuild it from the chain", err)
+	}
 
 	recovery := NewRecovery(chainConfig.Recovery, config.RecoveryNetworkRPC,
-		dex.governance, config.PrivateKey)
-	watchCat := syncer.NewWatchCat(recovery, dex.governance, 10*time.Second,
+		dex.governance, dex.signer)
+	watchCat := syncer.NewWatchCat(newCachedRecovery(recovery), dex.governance, 10*time.Second,
 		time.Duration(chainConfig.Recovery.Timeout)*time.Second, log.Root())
 
 	dex.bp = NewBlockProposer(dex, watchCat, dMoment)
-
-	dex.etherbase = crypto.PubkeyToAddress(config.PrivateKey.PublicKey)
+	dex.dkgResetReporter = newDKGResetReporter(dex.blockchain, dex.governance, chainDb)
+	dex.roundNotifier = newRoundNotifier(dex.blockchain, dex.governance)
+
+	dex.alerter = newAlerter(config.Alerts, crypto.PubkeyToAddress(*signer.PublicKey()),
+		hex.EncodeToString(crypto.FromECDSAPub(signer.PublicKey())),
+		dex.governance)
+	dex.keyRotator = newKeyRotator(dex, ctx.ResolvePath(datadirNodeKey),
+		ctx.ResolvePath(datadirNextNodeKey))
+	if config.PeerScalingMargin > 0 {
+		var lightPeers int
+		if config.LightServ > 0 {
+			lightPeers = config.LightPeers
+		}
+		dex.peerScaler = newPeerScaler(dex.governance, pm, config.PeerScalingMargin, lightPeers)
+	}
+	if config.LightServ > 0 {
+		dex.lightServer = newLightServer(pm, config.LightPeers)
+	}
+	if config.StateRootGossip {
+		var signer NodeSigner
+		if config.BlockProposerEnabled {
+			signer = dex.signer
+		}
+		pm.stateRoots = newStateRootGossip(dex.blockchain, dex.governance,
+			pm.peers, dex.alerter, signer)
+	}
 	return dex, nil
 }
 
 func (s *Tangerine) Protocols() []p2p.Protocol {
-	return s.protocolManager.SubProtocols
+	if s.lightServer == nil {
+		return s.protocolManager.SubProtocols
+	}
+	return append(s.protocolManager.SubProtocols, s.lightServer.Protocols()...)
 }
 
 func (s *Tangerine) APIs() []rpc.API {
@@ -221,7 +340,17 @@ func (s *Tangerine) APIs() []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.APIBackend, false),
+			Service:   filters.NewPublicFilterAPIWithTimeout(s.APIBackend, false, s.config.FilterTimeout),
+			Public:    true,
+		}, {
+			Namespace: "tan",
+			Version:   "1.0",
+			Service:   NewPublicTangerineAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "gov",
+			Version:   "1.0",
+			Service:   NewPublicGovernanceAPI(s),
 			Public:    true,
 		}, {
 			Namespace: "admin",
@@ -246,12 +375,19 @@ func (s *Tangerine) APIs() []rpc.API {
 }
 
 func (s *Tangerine) Start(srvr *p2p.Server) error {
+	s.startTime = time.Now()
+
 	// Start the bloom bits servicing goroutines
 	s.startBloomHandlers(params.BloomBitsBlocks)
 
 	// Start the RPC service
 	s.netRPCService = ethapi.NewPublicNetAPI(srvr, s.NetVersion())
 
+	if s.config.SafeMode {
+		log.Warn("Started in safe mode, networking and consensus disabled")
+		return nil
+	}
+
 	// Figure out a max peers count based on the server limits
 	maxPeers := srvr.MaxPeers
 	if s.config.LightServ > 0 {
@@ -262,13 +398,33 @@ func (s *Tangerine) Start(srvr *p2p.Server) error {
 	}
 	// Start the networking layer and the light server if requested
 	s.protocolManager.Start(srvr, maxPeers)
+	if s.lightServer != nil {
+		s.lightServer.Start()
+	}
+
+	s.dkgResetReporter.Start()
+	s.roundNotifier.Start()
+	s.alerter.Start(s.roundNotifier)
+	s.keyRotator.Start(s.roundNotifier, srvr)
+	if s.peerScaler != nil {
+		s.peerScaler.Start(s.roundNotifier, srvr, s.blockchain.CurrentBlock().Round())
+	}
+	if s.protocolManager.stateRoots != nil {
+		s.protocolManager.stateRoots.Start(s.roundNotifier)
+	}
+	if s.protocolManager.scrubber != nil {
+		s.protocolManager.scrubber.Start()
+	}
+	s.governance.nonceManager.Start()
+	s.governance.crsProposer.Start()
 
 	if s.config.BlockProposerEnabled {
 		go func() {
 			// Since we might be in fast sync mode when started. wait for
 			// ChainHeadEvent before starting blockproposer, or else we will trigger
 			// watchcat.
-			if s.config.SyncMode == downloader.FastSync &&
+			if (s.config.SyncMode == downloader.FastSync ||
+				s.config.SyncMode == downloader.CheckpointSync) &&
 				s.blockchain.CurrentBlock().NumberU64() == 0 {
 				ch := make(chan core.ChainHeadEvent)
 				sub := s.blockchain.SubscribeChainHeadEvent(ch)
@@ -276,7 +432,7 @@ func (s *Tangerine) Start(srvr *p2p.Server) error {
 
 				<-ch
 			}
-			s.bp.Start(s)
+			s.bp.Start()
 		}()
 	}
 	return nil
@@ -286,10 +442,33 @@ func (s *Tangerine) Stop() error {
 	s.bloomIndexer.Close()
 	s.blockchain.Stop()
 	s.engine.Close()
-	s.protocolManager.Stop()
+	if !s.config.SafeMode {
+		// Stop rotating first, a key switch restarts the block proposer.
+		s.keyRotator.Stop()
+		if s.lightServer != nil {
+			s.lightServer.Stop()
+		}
+		s.protocolManager.Stop()
+	}
 	s.txPool.Stop()
 	s.eventMux.Stop()
 	s.bp.Stop()
+	if !s.config.SafeMode {
+		s.dkgResetReporter.Stop()
+		s.roundNotifier.Stop()
+		if s.peerScaler != nil {
+			s.peerScaler.Stop()
+		}
+		if s.protocolManager.stateRoots != nil {
+			s.protocolManager.stateRoots.Stop()
+		}
+		s.alerter.Stop()
+		if s.protocolManager.scrubber != nil {
+			s.protocolManager.scrubber.Stop()
+		}
+		s.governance.crsProposer.Stop()
+		s.governance.nonceManager.Stop()
+	}
 	s.app.Stop()
 	if s.indexer != nil {
 		s.indexer.Stop()
@@ -326,6 +505,13 @@ func (d *Tangerine) DexVersion() int                   { return int(d.protocolMa
 func (d *Tangerine) EventMux() *event.TypeMux          { return d.eventMux }
 func (d *Tangerine) Engine() consensus.Engine          { return d.engine }
 func (d *Tangerine) ChainDb() ethdb.Database           { return d.chainDb }
-func (d *Tangerine) Downloader() ethapi.Downloader     { return d.protocolManager.downloader }
+func (d *Tangerine) Downloader() ethapi.Downloader     { return &syncProgress{d} }
 func (d *Tangerine) NetVersion() uint64                { return d.networkID }
-func (d *Tangerine) Etherbase() common.Address         { return d.etherbase }
+func (d *Tangerine) Etherbase() common.Address         { return crypto.PubkeyToAddress(*d.signer.PublicKey()) }
+
+// SubscribeRoundChangeEvent registers a subscription of RoundChangeEvent,
+// fired once per round transition of the chain. No event is fired in safe
+// mode.
+func (d *Tangerine) SubscribeRoundChangeEvent(ch chan<- RoundChangeEvent) event.Subscription {
+	return d.roundNotifier.Subscribe(ch)
+}
@@ -1,95 +1,161 @@
 package dex
 
 import (
+	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"os"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	dexCore "github.com/portto/tangerine-consensus/core"
-	coreEcdsa "github.com/portto/tangerine-consensus/core/crypto/ecdsa"
 	"github.com/portto/tangerine-consensus/core/syncer"
 	coreTypes "github.com/portto/tangerine-consensus/core/types"
 
 	"github.com/portto/go-tangerine/core"
-	"github.com/portto/go-tangerine/dex/db"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/crypto"
 	"github.com/portto/go-tangerine/log"
-	"github.com/portto/go-tangerine/node"
-	"github.com/portto/go-tangerine/rlp"
 )
 
 var (
 	forceSyncTimeout = 20 * time.Second
+
+	// proposerRestartDelay is the time waited before syncing the consensus
+	// core again after it stalled.
+	proposerRestartDelay = 10 * time.Second
+
+	errCoreNotRunning = errors.New("consensus core not running")
 )
 
 type blockProposer struct {
-	mu        sync.Mutex
-	running   int32
-	syncing   int32
-	proposing int32
-	dex       *Tangerine
-	watchCat  *syncer.WatchCat
-	dMoment   time.Time
+	// Core heights the running consensus core sync started at and reached,
+	// accessed atomically.
+	coreSyncStart  uint64
+	coreSyncHeight uint64
+
+	mu         sync.Mutex
+	running    int32
+	syncing    int32
+	proposing  int32
+	restarting int32 // Waiting to restart a stalled consensus core
+	dex        *Tangerine
+	watchCat   *syncer.WatchCat
+	dMoment    time.Time
 
 	wg     sync.WaitGroup
 	stopCh chan struct{}
+
+	feedMu   sync.Mutex
+	lastFeed *WatchCatFeed // Last position fed to the WatchCat, nil if none
+
+	coreMu sync.Mutex
+	core   *dexCore.Consensus // Running consensus core, nil if none
+
+	runCore func() bool // Runs a consensus core, replaced in tests
 }
 
 func NewBlockProposer(dex *Tangerine, watchCat *syncer.WatchCat, dMoment time.Time) *blockProposer {
-	return &blockProposer{
+	b := &blockProposer{
 		dex:      dex,
 		watchCat: watchCat,
 		dMoment:  dMoment,
 	}
+	b.runCore = b.run
+	return b
 }
 
-func (b *blockProposer) Start(svc node.Service) error {
+// Start starts proposing blocks in the background until Stop is called. If
+// the consensus core stops delivering blocks, it is torn down and synced
+// again in-process.
+func (b *blockProposer) Start() error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	return b.start()
+}
+
+// start starts proposing blocks. The caller must hold b.mu.
+func (b *blockProposer) start() error {
 	if !atomic.CompareAndSwapInt32(&b.running, 0, 1) {
 		return fmt.Errorf("block proposer is already running")
 	}
 	log.Info("Started block proposer")
+	b.dex.audit.record(types.AuditNode, AuditStartProposer, "", nil)
 
 	b.stopCh = make(chan struct{})
 	b.wg.Add(1)
 	go func() {
 		defer b.wg.Done()
 		defer atomic.StoreInt32(&b.running, 0)
-
-		var err error
-		var c *dexCore.Consensus
-		if b.dMoment.After(time.Now()) {
-			// Start receiving core messages.
-			b.dex.protocolManager.SetReceiveCoreMessage(true)
-
-			c = b.initConsensus()
-		} else {
-			c, err = b.syncConsensus()
+		defer atomic.StoreInt32(&b.restarting, 0)
+
+		for b.runCore() {
+			log.Info("Restarting block proposer", "delay", proposerRestartDelay)
+			atomic.StoreInt32(&b.restarting, 1)
+			select {
+			case <-time.After(proposerRestartDelay):
+			case <-b.stopCh:
+				return
+			}
+			atomic.StoreInt32(&b.restarting, 0)
 		}
+	}()
+	return nil
+}
 
-		if err != nil {
+// run runs a consensus core until the block proposer is stopped or the core
+// stops delivering blocks, returning whether it should be restarted.
+func (b *blockProposer) run() bool {
+	var err error
+	var c *dexCore.Consensus
+	if b.dMoment.After(time.Now()) {
+		// Start receiving core messages.
+		b.dex.protocolManager.SetReceiveCoreMessage(true)
+
+		c = b.initConsensus()
+	} else {
+		c, err = b.syncConsensus()
+	}
+
+	if err != nil {
+		select {
+		case <-b.stopCh:
+			log.Debug("Block proposer stopped while syncing", "err", err)
+		default:
 			log.Error("Block proposer stopped, before start running", "err", err)
-			return
+			b.dex.alerter.alert(AlertProposingStopped, fmt.Sprintf(
+				"block proposer failed to start: %v", err))
 		}
+		return false
+	}
 
-		log.Info("Start running consensus core")
-		go c.Run(b.stopCh)
-		atomic.StoreInt32(&b.proposing, 1)
-
-		<-b.stopCh
+	// The core signals stalled once if it stops delivering blocks.
+	stalled := make(chan struct{}, 1)
+	log.Info("Start running consensus core")
+	go withProfileLabel(profileConsensus, func(context.Context) { c.Run(stalled) })
+	atomic.StoreInt32(&b.proposing, 1)
+	b.setCore(c)
+
+	restart := false
+	select {
+	case <-stalled:
+		log.Error("Consensus core stalled, tearing it down")
+		restart = true
+	case <-b.stopCh:
 		log.Debug("Block proposer receive stop signal")
-
-		log.Info("Block proposer successfully stopped")
-		go func() {
-			svc.Stop()
-			os.Exit(1)
-		}()
-	}()
-	return nil
+	}
+	b.dex.protocolManager.SetReceiveCoreMessage(false)
+	b.setCore(nil)
+	c.Stop()
+	log.Info("Consensus core stopped")
+
+	if restart && atomic.SwapInt32(&b.proposing, 0) == 1 {
+		b.dex.alerter.alert(AlertProposingStopped,
+			"consensus core stopped delivering blocks, restarting block proposer")
+	}
+	return restart
 }
 
 func (b *blockProposer) Stop() {
@@ -101,11 +167,40 @@ func (b *blockProposer) Stop() {
 		b.dex.protocolManager.SetReceiveCoreMessage(false)
 		close(b.stopCh)
 		b.wg.Wait()
-		atomic.StoreInt32(&b.proposing, 0)
+		if atomic.SwapInt32(&b.proposing, 0) == 1 {
+			b.dex.alerter.alert(AlertProposingStopped, "block proposer stopped")
+		}
+		b.dex.audit.record(types.AuditNode, AuditStopProposer, "", nil)
 	}
 	log.Info("Block proposer stopped")
 }
 
+// Restart calls reconfigure with the consensus core torn down, then starts
+// a new core synced in-process from the local chain, without alerting. The
+// core fixes its node ID at construction, so this is how it picks up a
+// rotated node key. A proposer not running is not started, reconfigure
+// being called alone.
+func (b *blockProposer) Restart(reconfigure func() error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	running := atomic.LoadInt32(&b.running) == 1
+	if running {
+		log.Info("Restarting block proposer")
+		b.dex.protocolManager.SetReceiveCoreMessage(false)
+		close(b.stopCh)
+		b.wg.Wait()
+		atomic.StoreInt32(&b.proposing, 0)
+	}
+	if err := reconfigure(); err != nil {
+		return err
+	}
+	if !running {
+		return nil
+	}
+	return b.start()
+}
+
 func (b *blockProposer) IsCoreSyncing() bool {
 	return atomic.LoadInt32(&b.syncing) == 1
 }
@@ -114,9 +209,105 @@ func (b *blockProposer) IsProposing() bool {
 	return atomic.LoadInt32(&b.proposing) == 1
 }
 
+// feedWatchCat feeds the WatchCat with pos, recording it for the status.
+func (b *blockProposer) feedWatchCat(pos coreTypes.Position) {
+	b.watchCat.Feed(pos)
+
+	b.feedMu.Lock()
+	b.lastFeed = &WatchCatFeed{Round: pos.Round, Height: pos.Height, Time: time.Now()}
+	b.feedMu.Unlock()
+}
+
+func (b *blockProposer) setCore(c *dexCore.Consensus) {
+	b.coreMu.Lock()
+	b.core = c
+	b.coreMu.Unlock()
+}
+
+// AgreementState returns the state of the agreement module of the running
+// consensus core.
+func (b *blockProposer) AgreementState() (*AgreementState, error) {
+	b.coreMu.Lock()
+	c := b.core
+	b.coreMu.Unlock()
+	if c == nil {
+		return nil, errCoreNotRunning
+	}
+	snapshot := c.AgreementSnapshot()
+	if snapshot == nil {
+		return nil, errCoreNotRunning
+	}
+	return newAgreementState(snapshot), nil
+}
+
+// ProposerStatus is the state of the block proposer of the node.
+type ProposerStatus struct {
+	Enabled       bool          `json:"enabled"`
+	IsProposing   bool          `json:"isProposing"`
+	IsCoreSyncing bool          `json:"isCoreSyncing"`
+	IsRestarting  bool          `json:"isRestarting"`
+	Round         uint64        `json:"round"`  // Round of the chain head
+	Height        uint64        `json:"height"` // Consensus height of the chain head
+	InNotarySet   bool          `json:"inNotarySet"`
+	InDKGSet      bool          `json:"inDKGSet"`
+	LastFeed      *WatchCatFeed `json:"lastWatchCatFeed"`
+}
+
+// WatchCatFeed is a position fed to the WatchCat while syncing the consensus
+// core.
+type WatchCatFeed struct {
+	Round  uint64    `json:"round"`
+	Height uint64    `json:"height"`
+	Time   time.Time `json:"time"`
+}
+
+// Status returns the state of the block proposer and whether the node takes
+// part in the notary and DKG sets of the current round.
+func (b *blockProposer) Status() (*ProposerStatus, error) {
+	head := b.dex.blockchain.CurrentBlock()
+	status := &ProposerStatus{
+		Enabled:       b.dex.config.BlockProposerEnabled,
+		IsProposing:   b.IsProposing(),
+		IsCoreSyncing: b.IsCoreSyncing(),
+		IsRestarting:  atomic.LoadInt32(&b.restarting) == 1,
+		Round:         head.Round(),
+		Height:        head.NumberU64(),
+	}
+	b.feedMu.Lock()
+	if b.lastFeed != nil {
+		feed := *b.lastFeed
+		status.LastFeed = &feed
+	}
+	b.feedMu.Unlock()
+
+	key := b.dex.signer.PublicKey()
+	notarySet, err := b.dex.governance.NotarySet(status.Round)
+	if err != nil {
+		return nil, err
+	}
+	_, status.InNotarySet = notarySet[hex.EncodeToString(crypto.FromECDSAPub(key))]
+
+	// The DKG set is unknown until enough master public keys are proposed.
+	if dkgSet, err := b.dex.governance.DKGSetNodeKeyAddresses(status.Round); err != nil {
+		log.Debug("Failed to get DKG set", "round", status.Round, "err", err)
+	} else {
+		_, status.InDKGSet = dkgSet[crypto.PubkeyToAddress(*key)]
+	}
+	return status, nil
+}
+
+// coreSyncProgress returns the core heights the running consensus core sync
+// started at and reached, ok being false if the core is not syncing.
+func (b *blockProposer) coreSyncProgress() (start, current uint64, ok bool) {
+	if !b.IsCoreSyncing() {
+		return 0, 0, false
+	}
+	return atomic.LoadUint64(&b.coreSyncStart), atomic.LoadUint64(&b.coreSyncHeight), true
+}
+
 func (b *blockProposer) initConsensus() *dexCore.Consensus {
-	db := db.NewDatabase(b.dex.chainDb)
-	privkey := coreEcdsa.NewPrivateKeyFromECDSA(b.dex.config.PrivateKey)
+	db := b.dex.protocolManager.coreDB
+	privkey := coreSigner{b.dex.signer}
 	return dexCore.NewConsensus(b.dMoment,
 		b.dex.app, b.dex.governance, db, b.dex.network, privkey, log.Root())
 }
@@ -127,20 +318,20 @@ func (b *blockProposer) syncConsensus() (*dexCore.Consensus, error) {
 
 	cb := b.dex.blockchain.CurrentBlock()
 
-	db := db.NewDatabase(b.dex.chainDb)
-	privkey := coreEcdsa.NewPrivateKeyFromECDSA(b.dex.config.PrivateKey)
+	db := b.dex.protocolManager.coreDB
+	privkey := coreSigner{b.dex.signer}
 	consensusSync := syncer.NewConsensus(cb.NumberU64(), b.dMoment, b.dex.app,
 		b.dex.governance, db, b.dex.network, privkey, log.Root())
 
 	blocksToSync := func(coreHeight, height uint64) []*coreTypes.Block {
 		var blocks []*coreTypes.Block
 		for len(blocks) < 2048 && coreHeight < height {
-			var block coreTypes.Block
 			b := b.dex.blockchain.GetBlockByNumber(coreHeight + 1)
-			if err := rlp.DecodeBytes(b.Header().DexconMeta, &block); err != nil {
+			block, err := b.Header().CoreBlock()
+			if err != nil {
 				panic(err)
 			}
-			blocks = append(blocks, &block)
+			blocks = append(blocks, block)
 			coreHeight = coreHeight + 1
 		}
 		return blocks
@@ -149,6 +340,19 @@ func (b *blockProposer) syncConsensus() (*dexCore.Consensus, error) {
 	// Sync all blocks in compaction chain to core.
 	_, coreHeight := db.GetCompactionChainTipInfo()
 
+	// A chain synced from a checkpoint has no blocks to fill the compaction
+	// chain with up to the checkpoint, so start it at the chain head.
+	if coreHeight < cb.NumberU64() && b.dex.blockchain.GetBlockByNumber(coreHeight+1) == nil {
+		_, height, err := RepairCoreTip(b.dex.blockchain, db)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("Compaction chain reset to chain head", "core height", coreHeight, "height", height)
+		coreHeight = height
+	}
+	atomic.StoreUint64(&b.coreSyncStart, coreHeight)
+	atomic.StoreUint64(&b.coreSyncHeight, coreHeight)
+
 Loop:
 	for {
 		currentBlock := b.dex.blockchain.CurrentBlock()
@@ -169,6 +373,7 @@ Loop:
 			return nil, err
 		}
 		coreHeight = blocks[len(blocks)-1].Position.Height
+		atomic.StoreUint64(&b.coreSyncHeight, coreHeight)
 
 		select {
 		case <-b.stopCh:
@@ -184,11 +389,11 @@ Loop:
 
 	// Feed the current block we have in local blockchain.
 	if cb.NumberU64() > 0 {
-		var block coreTypes.Block
-		if err := rlp.DecodeBytes(cb.Header().DexconMeta, &block); err != nil {
+		block, err := cb.Header().CoreBlock()
+		if err != nil {
 			panic(err)
 		}
-		b.watchCat.Feed(block.Position)
+		b.feedWatchCat(block.Position)
 	}
 
 	ch := make(chan core.ChainHeadEvent)
@@ -206,7 +411,7 @@ ListenLoop:
 				if len(blocks) == 0 {
 					break
 				}
-				b.watchCat.Feed(blocks[len(blocks)-1].Position)
+				b.feedWatchCat(blocks[len(blocks)-1].Position)
 				log.Debug("Filling compaction chain", "num", len(blocks),
 					"first", blocks[0].Position.Height,
 					"last", blocks[len(blocks)-1].Position.Height)
@@ -221,6 +426,7 @@ ListenLoop:
 					break ListenLoop
 				}
 				coreHeight = blocks[len(blocks)-1].Position.Height
+				atomic.StoreUint64(&b.coreSyncHeight, coreHeight)
 			}
 		case <-sub.Err():
 			log.Debug("System stopped when syncing consensus core")
@@ -238,6 +444,8 @@ ListenLoop:
 			}
 		case <-b.watchCat.Meow():
 			log.Info("WatchCat signaled to stop syncing")
+			b.dex.alerter.alert(AlertWatchCat, fmt.Sprintf(
+				"watchCat triggered a force sync at %s", b.watchCat.LastPosition()))
 
 			// Sleep until the next consensus start time slot.
 			// The interval T_i need to meet the following requirement:
@@ -259,6 +467,14 @@ ListenLoop:
 	}
 
 	con, err := consensusSync.GetSyncedConsensus()
-	time.Sleep(time.Duration(nextDMoment-time.Now().Unix()) * time.Second)
-	return con, err
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-time.After(time.Duration(nextDMoment-time.Now().Unix()) * time.Second):
+	case <-b.stopCh:
+		con.Stop()
+		return nil, errors.New("early stop")
+	}
+	return con, nil
 }
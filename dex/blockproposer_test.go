@@ -0,0 +1,162 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/portto/go-tangerine/crypto"
+)
+
+// Tests that a stalled consensus core is restarted in-process until the
+// block proposer is stopped, and that stopping doesn't wait for the restart
+// delay.
+func TestBlockProposerRestart(t *testing.T) {
+	defer func(delay time.Duration) { proposerRestartDelay = delay }(proposerRestartDelay)
+	proposerRestartDelay = 10 * time.Millisecond
+
+	dex := &Tangerine{protocolManager: &ProtocolManager{}}
+	bp := NewBlockProposer(dex, nil, time.Time{})
+
+	// The core stalls twice, then runs until stopped.
+	runs := make(chan struct{}, 3)
+	bp.runCore = func() bool {
+		runs <- struct{}{}
+		if len(runs) < 3 {
+			return true
+		}
+		<-bp.stopCh
+		return false
+	}
+	if err := bp.Start(); err != nil {
+		t.Fatalf("failed to start block proposer: %v", err)
+	}
+	deadline := time.After(time.Second)
+	for len(runs) < 3 {
+		select {
+		case <-deadline:
+			t.Fatalf("core run count mismatch: have %d, want 3", len(runs))
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if atomic.LoadInt32(&bp.running) != 1 {
+		t.Errorf("block proposer not running after restarts")
+	}
+	bp.Stop()
+	if atomic.LoadInt32(&bp.running) != 0 {
+		t.Errorf("block proposer running after stop")
+	}
+
+	// Stopping while waiting to restart returns right away.
+	proposerRestartDelay = time.Hour
+	stalled := make(chan struct{})
+	bp.runCore = func() bool {
+		close(stalled)
+		return true
+	}
+	if err := bp.Start(); err != nil {
+		t.Fatalf("failed to start block proposer: %v", err)
+	}
+	<-stalled
+	done := make(chan struct{})
+	go func() {
+		bp.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stop waited for the restart delay")
+	}
+}
+
+// Tests the proposer status reported while the block proposer is stopped,
+// running a consensus core and waiting to restart a stalled one.
+func TestProposerStatus(t *testing.T) {
+	defer func(delay time.Duration) { proposerRestartDelay = delay }(proposerRestartDelay)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+	dex.config = &Config{BlockProposerEnabled: true}
+	dex.signer = newRotatingSigner(NewLocalSigner(key))
+	dex.alerter = newAlerter(AlertConfig{}, crypto.PubkeyToAddress(key.PublicKey), "", nil)
+	dex.protocolManager = &ProtocolManager{peers: newPeerSet(&testGovernance{}, newTestP2PServer(key))}
+	dex.bp = NewBlockProposer(dex, nil, time.Time{})
+	api := NewPublicTangerineAPI(dex)
+
+	check := func(state string, proposing, restarting bool) {
+		t.Helper()
+		status, err := api.ProposerStatus()
+		if err != nil {
+			t.Fatalf("%s: failed to get status: %v", state, err)
+		}
+		if !status.Enabled || status.IsProposing != proposing || status.IsRestarting != restarting {
+			t.Errorf("%s: status mismatch: have proposing %t restarting %t, want %t %t",
+				state, status.IsProposing, status.IsRestarting, proposing, restarting)
+		}
+		if status.Round != 0 || status.Height != 0 {
+			t.Errorf("%s: position mismatch: %+v", state, status)
+		}
+	}
+	check("stopped", false, false)
+
+	// The core runs until stopped.
+	started := make(chan struct{})
+	dex.bp.runCore = func() bool {
+		atomic.StoreInt32(&dex.bp.proposing, 1)
+		close(started)
+		<-dex.bp.stopCh
+		return false
+	}
+	if err := dex.bp.Start(); err != nil {
+		t.Fatalf("failed to start block proposer: %v", err)
+	}
+	<-started
+	check("running", true, false)
+	dex.bp.Stop()
+	check("stopped", false, false)
+
+	// The core stalls, and waits to be restarted.
+	proposerRestartDelay = time.Hour
+	stalled := make(chan struct{})
+	dex.bp.runCore = func() bool {
+		close(stalled)
+		return true
+	}
+	if err := dex.bp.Start(); err != nil {
+		t.Fatalf("failed to start block proposer: %v", err)
+	}
+	<-stalled
+	for deadline := time.Now().Add(time.Second); atomic.LoadInt32(&dex.bp.restarting) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("block proposer not restarting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	check("restarting", false, true)
+	dex.bp.Stop()
+	check("stopped", false, false)
+}
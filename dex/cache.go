@@ -18,13 +18,41 @@
 package dex
 
 import (
+	"sort"
 	"sync"
+	"unsafe"
 
 	coreCommon "github.com/portto/tangerine-consensus/common"
 	coreDb "github.com/portto/tangerine-consensus/core/db"
 	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/log"
 )
 
+// defaultCacheSize is the default number of votes, blocks and finalized
+// blocks held by the core message cache.
+const defaultCacheSize = 5120
+
+// psigCacheTargets is the number of signed (round, hash) targets whose DKG
+// partial signatures are remembered for deduplication.
+const psigCacheTargets = 16
+
+// finalizedBlockDatabase is implemented by the core block databases storing
+// finalized blocks by position.
+type finalizedBlockDatabase interface {
+	GetFinalizedBlock(pos coreTypes.Position) (coreTypes.Block, error)
+	PutFinalizedBlock(block coreTypes.Block) error
+}
+
+// psigTarget is the target signed by DKG partial signatures.
+type psigTarget struct {
+	Round uint64
+	Hash  coreCommon.Hash
+}
+
 type voteKey struct {
 	ProposerID coreTypes.NodeID
 	Type       coreTypes.VoteType
@@ -43,33 +71,109 @@ func voteToKey(vote *coreTypes.Vote) voteKey {
 	}
 }
 
+// cache holds the recent core votes and blocks to serve pull requests. Votes
+// are evicted by position, oldest first, while blocks and finalized blocks
+// are evicted least recently used first, so that the blocks still being
+// pulled stay cached.
 type cache struct {
 	lock                sync.RWMutex
-	blockCache          map[coreCommon.Hash]*coreTypes.Block
-	finalizedBlockCache map[coreTypes.Position]*coreTypes.Block
+	blockCache          *simplelru.LRU // hash -> *coreTypes.Block
+	finalizedBlockCache *simplelru.LRU // position -> *coreTypes.Block
 	voteCache           map[coreTypes.Position]map[voteKey]*coreTypes.Vote
 	votePosition        []coreTypes.Position
 	db                  coreDb.Database
 	voteSize            int
 	size                int
+
+	voteEvictions           uint64
+	blockEvictions          uint64
+	finalizedBlockEvictions uint64
+
+	// DKG partial signatures already passed to the consensus core, by the
+	// hash of their content, and the order their targets were added in.
+	psigCache   map[psigTarget]map[common.Hash]struct{}
+	psigTargets []psigTarget
+	psigDups    uint64
+}
+
+// CacheStats is a snapshot of the occupancy of the core message cache.
+type CacheStats struct {
+	Size                       int                 `json:"size"`
+	Votes                      int                 `json:"votes"`
+	VotePositions              []VotePositionStats `json:"votePositions"`
+	Blocks                     int                 `json:"blocks"`
+	FinalizedBlocks            int                 `json:"finalizedBlocks"`
+	VoteEvictions              uint64              `json:"voteEvictions"`
+	BlockEvictions             uint64              `json:"blockEvictions"`
+	FinalizedBlockEvictions    uint64              `json:"finalizedBlockEvictions"`
+	PartialSignatures          int                 `json:"partialSignatures"`
+	RedundantPartialSignatures uint64              `json:"redundantPartialSignatures"`
+	VoteMemory                 uint64              `json:"voteMemory"`
+	BlockMemory                uint64              `json:"blockMemory"`
+}
+
+// VotePositionStats is the number of cached votes at a position.
+type VotePositionStats struct {
+	Position coreTypes.Position `json:"position"`
+	Votes    int                `json:"votes"`
+}
+
+var (
+	voteOverhead  = uint64(unsafe.Sizeof(coreTypes.Vote{}) + unsafe.Sizeof(voteKey{}))
+	blockOverhead = uint64(unsafe.Sizeof(coreTypes.Block{}))
+)
+
+// voteMemory estimates the memory held by a cached vote.
+func voteMemory(vote *coreTypes.Vote) uint64 {
+	return voteOverhead + uint64(len(vote.PartialSignature.Signature)+
+		len(vote.Signature.Signature))
+}
+
+// blockMemory estimates the memory held by a cached block.
+func blockMemory(block *coreTypes.Block) uint64 {
+	return blockOverhead + uint64(len(block.Payload)+len(block.Witness.Data)+
+		len(block.Randomness)+len(block.Signature.Signature)+
+		len(block.CRSSignature.Signature))
 }
 
 func newCache(size int, db coreDb.Database) *cache {
-	return &cache{
-		blockCache:          make(map[coreCommon.Hash]*coreTypes.Block),
-		finalizedBlockCache: make(map[coreTypes.Position]*coreTypes.Block),
-		voteCache:           make(map[coreTypes.Position]map[voteKey]*coreTypes.Vote),
-		db:                  db,
-		size:                size,
+	return newSizedCache(size, size, size, db)
+}
+
+// newSizedCache creates a cache holding at most size votes, blockSize blocks
+// and finalizedSize finalized blocks.
+func newSizedCache(size, blockSize, finalizedSize int, db coreDb.Database) *cache {
+	c := &cache{
+		voteCache: make(map[coreTypes.Position]map[voteKey]*coreTypes.Vote),
+		psigCache: make(map[psigTarget]map[common.Hash]struct{}),
+		db:        db,
+		size:      size,
 	}
+	// The eviction callbacks run with the cache lock held.
+	c.blockCache, _ = simplelru.NewLRU(blockSize, func(key, value interface{}) {
+		c.blockEvictions++
+		cacheBlockEvictMeter.Mark(1)
+	})
+	c.finalizedBlockCache, _ = simplelru.NewLRU(finalizedSize, func(key, value interface{}) {
+		c.finalizedBlockEvictions++
+		cacheFinalizedBlockEvictMeter.Mark(1)
+	})
+	return c
 }
 
-func (c *cache) addVote(vote *coreTypes.Vote) {
+// addVote caches the vote, returning false if it is already cached.
+func (c *cache) addVote(vote *coreTypes.Vote) bool {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	key := voteToKey(vote)
+	if _, exist := c.voteCache[vote.Position][key]; exist {
+		return false
+	}
 	if c.voteSize >= c.size {
 		pos := c.votePosition[0]
 		c.voteSize -= len(c.voteCache[pos])
+		c.voteEvictions += uint64(len(c.voteCache[pos]))
+		cacheVoteEvictMeter.Mark(int64(len(c.voteCache[pos])))
 		delete(c.voteCache, pos)
 		c.votePosition = c.votePosition[1:]
 	}
@@ -77,12 +181,9 @@ func (c *cache) addVote(vote *coreTypes.Vote) {
 		c.votePosition = append(c.votePosition, vote.Position)
 		c.voteCache[vote.Position] = make(map[voteKey]*coreTypes.Vote)
 	}
-	key := voteToKey(vote)
-	if _, exist := c.voteCache[vote.Position][key]; exist {
-		return
-	}
 	c.voteCache[vote.Position][key] = vote
 	c.voteSize++
+	return true
 }
 
 func (c *cache) votes(pos coreTypes.Position) []*coreTypes.Vote {
@@ -95,6 +196,34 @@ func (c *cache) votes(pos coreTypes.Position) []*coreTypes.Vote {
 	return votes
 }
 
+// addPartialSignature records a DKG partial signature, returning false if an
+// identical one was already recorded. As the validity of a partial signature
+// only depends on its content, duplicates received from several peers need
+// not be verified again by the consensus core.
+func (c *cache) addPartialSignature(psig *dkgTypes.PartialSignature) bool {
+	target := psigTarget{Round: psig.Round, Hash: psig.Hash}
+	hash := rlpHash(psig)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	psigs, exist := c.psigCache[target]
+	if !exist {
+		if len(c.psigTargets) >= psigCacheTargets {
+			delete(c.psigCache, c.psigTargets[0])
+			c.psigTargets = c.psigTargets[1:]
+		}
+		psigs = make(map[common.Hash]struct{})
+		c.psigCache[target] = psigs
+		c.psigTargets = append(c.psigTargets, target)
+	}
+	if _, dup := psigs[hash]; dup {
+		c.psigDups++
+		return false
+	}
+	psigs[hash] = struct{}{}
+	return true
+}
+
 func (c *cache) addBlocks(blocks []*coreTypes.Block) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -116,18 +245,10 @@ func (c *cache) addBlock(block *coreTypes.Block) {
 func (c *cache) addBlockNoLock(block *coreTypes.Block) {
 	// Avoid polluting cache by non-finalized blocks when we've received some
 	// finalized block from the same position.
-	if _, exist := c.finalizedBlockCache[block.Position]; exist {
+	if c.finalizedBlockCache.Contains(block.Position) {
 		return
 	}
-	block = block.Clone()
-	if len(c.blockCache) >= c.size {
-		// Randomly delete one entry.
-		for k := range c.blockCache {
-			delete(c.blockCache, k)
-			break
-		}
-	}
-	c.blockCache[block.Hash] = block
+	c.blockCache.Add(block.Hash, block.Clone())
 }
 
 func (c *cache) addFinalizedBlock(block *coreTypes.Block) {
@@ -136,50 +257,121 @@ func (c *cache) addFinalizedBlock(block *coreTypes.Block) {
 	c.addFinalizedBlockNoLock(block)
 }
 
-func (c *cache) addFinalizedBlockNoLock(block *coreTypes.Block) {
-	block = block.Clone()
-	if len(c.blockCache) >= c.size {
-		// Randomly delete one entry.
-		for k := range c.blockCache {
-			delete(c.blockCache, k)
-			break
+// putFinalizedBlock caches a finalized block and indexes it in the database,
+// if supported, to keep serving it once evicted. Only blocks finalized by the
+// local consensus core are indexed.
+func (c *cache) putFinalizedBlock(block *coreTypes.Block) {
+	c.addFinalizedBlock(block)
+	if db, ok := c.db.(finalizedBlockDatabase); ok {
+		if err := db.PutFinalizedBlock(*block); err != nil {
+			log.Warn("Failed to store finalized block", "position", block.Position, "err", err)
 		}
 	}
-	if len(c.finalizedBlockCache) >= c.size {
-		// Randomly delete one entry.
-		for k := range c.finalizedBlockCache {
-			delete(c.finalizedBlockCache, k)
-			break
-		}
-	}
-	c.blockCache[block.Hash] = block
-	c.finalizedBlockCache[block.Position] = block
 }
 
+func (c *cache) addFinalizedBlockNoLock(block *coreTypes.Block) {
+	block = block.Clone()
+	c.blockCache.Add(block.Hash, block)
+	c.finalizedBlockCache.Add(block.Position, block)
+}
+
+// blocks returns the blocks of hashes found in the cache, or in the database
+// if includeDB is set. Looking blocks up marks them as recently used. The
+// database is read without holding the cache lock.
 func (c *cache) blocks(hashes coreCommon.Hashes, includeDB bool) []*coreTypes.Block {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
+	found := make([]*coreTypes.Block, len(hashes))
+	c.lock.Lock()
+	for i, hash := range hashes {
+		if block, exist := c.blockCache.Get(hash); exist {
+			found[i] = block.(*coreTypes.Block)
+		}
+	}
+	c.lock.Unlock()
+
 	cacheBlocks := make([]*coreTypes.Block, 0, len(hashes))
-	for _, hash := range hashes {
-		if block, exist := c.blockCache[hash]; exist {
-			cacheBlocks = append(cacheBlocks, block)
-		} else if includeDB {
-			block, err := c.db.GetBlock(hash)
+	for i, block := range found {
+		if block == nil && includeDB {
+			stored, err := c.db.GetBlock(hashes[i])
 			if err != nil {
 				continue
 			}
-			cacheBlocks = append(cacheBlocks, &block)
+			block = &stored
+		}
+		if block != nil {
+			cacheBlocks = append(cacheBlocks, block)
 		}
 	}
 	return cacheBlocks
 }
 
+// finalizedBlock returns the finalized block at pos from the cache, or from
+// the database if supported. The database is read without holding the cache
+// lock.
 func (c *cache) finalizedBlock(pos coreTypes.Position) *coreTypes.Block {
+	c.lock.Lock()
+	block, exist := c.finalizedBlockCache.Get(pos)
+	c.lock.Unlock()
+	if exist {
+		return block.(*coreTypes.Block)
+	}
+	db, ok := c.db.(finalizedBlockDatabase)
+	if !ok {
+		return nil
+	}
+	stored, err := db.GetFinalizedBlock(pos)
+	if err != nil {
+		return nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// Prefer a block cached while reading the database.
+	if block, exist := c.finalizedBlockCache.Get(pos); exist {
+		return block.(*coreTypes.Block)
+	}
+	c.finalizedBlockCache.Add(pos, &stored)
+	return &stored
+}
+
+func (c *cache) stats() *CacheStats {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
-	if block, exist := c.finalizedBlockCache[pos]; exist {
-		return block
+	stats := &CacheStats{
+		Size:                       c.size,
+		Votes:                      c.voteSize,
+		VotePositions:              make([]VotePositionStats, 0, len(c.voteCache)),
+		Blocks:                     c.blockCache.Len(),
+		FinalizedBlocks:            c.finalizedBlockCache.Len(),
+		VoteEvictions:              c.voteEvictions,
+		BlockEvictions:             c.blockEvictions,
+		FinalizedBlockEvictions:    c.finalizedBlockEvictions,
+		RedundantPartialSignatures: c.psigDups,
+	}
+	for _, psigs := range c.psigCache {
+		stats.PartialSignatures += len(psigs)
+	}
+	for pos, votes := range c.voteCache {
+		stats.VotePositions = append(stats.VotePositions, VotePositionStats{
+			Position: pos,
+			Votes:    len(votes),
+		})
+		for _, vote := range votes {
+			stats.VoteMemory += voteMemory(vote)
+		}
+	}
+	sort.Slice(stats.VotePositions, func(i, j int) bool {
+		return stats.VotePositions[i].Position.Older(
+			stats.VotePositions[j].Position)
+	})
+	// Finalized blocks are shared with the block cache unless evicted from it.
+	for _, hash := range c.blockCache.Keys() {
+		block, _ := c.blockCache.Peek(hash)
+		stats.BlockMemory += blockMemory(block.(*coreTypes.Block))
+	}
+	for _, pos := range c.finalizedBlockCache.Keys() {
+		block, _ := c.finalizedBlockCache.Peek(pos)
+		if cached, _ := c.blockCache.Peek(block.(*coreTypes.Block).Hash); cached != block {
+			stats.BlockMemory += blockMemory(block.(*coreTypes.Block))
+		}
 	}
-	// TODO(jimmy): get finalized block from db
-	return nil
+	return stats
 }
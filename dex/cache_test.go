@@ -27,6 +27,12 @@ import (
 	coreCommon "github.com/portto/tangerine-consensus/common"
 	coreDb "github.com/portto/tangerine-consensus/core/db"
 	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/rawdb"
+	dexDB "github.com/portto/go-tangerine/dex/db"
+	"github.com/portto/go-tangerine/ethdb"
 )
 
 type byHash []*coreTypes.Vote
@@ -82,6 +88,9 @@ func TestCacheVote(t *testing.T) {
 	cache.addVote(vote1)
 	cache.addVote(vote2)
 	cache.addVote(vote3)
+	if cache.addVote(vote1) {
+		t.Errorf("duplicate vote added")
+	}
 
 	votes := cache.votes(pos0)
 	sort.Sort(byHash(votes))
@@ -317,6 +326,49 @@ func TestCacheFinalizedBlock(t *testing.T) {
 	}
 }
 
+func TestCacheStats(t *testing.T) {
+	db, err := coreDb.NewMemBackedDB()
+	if err != nil {
+		panic(err)
+	}
+	cache := newCache(2, db)
+	for height := uint64(0); height < 3; height++ {
+		cache.addVote(&coreTypes.Vote{
+			VoteHeader: coreTypes.VoteHeader{
+				BlockHash: coreCommon.NewRandomHash(),
+				Position:  coreTypes.Position{Height: height},
+			},
+		})
+	}
+	for i := 0; i < 3; i++ {
+		cache.addBlock(&coreTypes.Block{
+			Hash:     coreCommon.NewRandomHash(),
+			Position: coreTypes.Position{Height: uint64(i)},
+			Payload:  randomBytes(),
+		})
+	}
+	stats := cache.stats()
+	if stats.Votes != 2 || len(stats.VotePositions) != 2 {
+		t.Errorf("wrong vote count: have %d at %d positions, want 2 at 2",
+			stats.Votes, len(stats.VotePositions))
+	}
+	if !stats.VotePositions[0].Position.Older(stats.VotePositions[1].Position) {
+		t.Errorf("vote positions not sorted: %v", stats.VotePositions)
+	}
+	if stats.VoteEvictions != 1 {
+		t.Errorf("wrong vote evictions: have %d, want 1", stats.VoteEvictions)
+	}
+	if stats.Blocks != 2 || stats.BlockEvictions != 1 {
+		t.Errorf("wrong block stats: have %d blocks, %d evictions",
+			stats.Blocks, stats.BlockEvictions)
+	}
+	if stats.VoteMemory < 2*voteOverhead ||
+		stats.BlockMemory < 2*(blockOverhead+32) {
+		t.Errorf("memory underestimated: votes %d, blocks %d",
+			stats.VoteMemory, stats.BlockMemory)
+	}
+}
+
 func randomBytes() []byte {
 	bytes := make([]byte, 32)
 	for i := range bytes {
@@ -324,3 +376,145 @@ func randomBytes() []byte {
 	}
 	return bytes
 }
+
+func TestCachePartialSignature(t *testing.T) {
+	db, err := coreDb.NewMemBackedDB()
+	if err != nil {
+		panic(err)
+	}
+	cache := newCache(3, db)
+	psig := &dkgTypes.PartialSignature{
+		ProposerID: coreTypes.NodeID{Hash: coreCommon.NewRandomHash()},
+		Round:      1,
+		Hash:       coreCommon.NewRandomHash(),
+	}
+	other := *psig
+	other.ProposerID = coreTypes.NodeID{Hash: coreCommon.NewRandomHash()}
+
+	if !cache.addPartialSignature(psig) {
+		t.Errorf("first partial signature rejected")
+	}
+	if cache.addPartialSignature(psig) {
+		t.Errorf("duplicate partial signature accepted")
+	}
+	if !cache.addPartialSignature(&other) {
+		t.Errorf("partial signature of another proposer rejected")
+	}
+	stats := cache.stats()
+	if stats.PartialSignatures != 2 || stats.RedundantPartialSignatures != 1 {
+		t.Errorf("stats mismatch: %d partial signatures, %d redundant",
+			stats.PartialSignatures, stats.RedundantPartialSignatures)
+	}
+
+	// Partial signatures of the oldest targets are forgotten.
+	for i := 0; i < psigCacheTargets; i++ {
+		cache.addPartialSignature(&dkgTypes.PartialSignature{
+			Round: 1,
+			Hash:  coreCommon.NewRandomHash(),
+		})
+	}
+	if !cache.addPartialSignature(psig) {
+		t.Errorf("partial signature of evicted target rejected")
+	}
+}
+
+func TestCacheBlockLRU(t *testing.T) {
+	db, err := coreDb.NewMemBackedDB()
+	if err != nil {
+		panic(err)
+	}
+	cache := newSizedCache(3, 2, 2, db)
+	blocks := make([]*coreTypes.Block, 3)
+	for i := range blocks {
+		blocks[i] = &coreTypes.Block{
+			Hash:       coreCommon.NewRandomHash(),
+			Position:   coreTypes.Position{Height: uint64(i)},
+			Randomness: randomBytes(),
+		}
+	}
+	// Looking up the oldest block keeps it over the unused one.
+	cache.addFinalizedBlock(blocks[0])
+	cache.addFinalizedBlock(blocks[1])
+	if len(cache.blocks(coreCommon.Hashes{blocks[0].Hash}, false)) != 1 {
+		t.Fatalf("block %s not cached", blocks[0])
+	}
+	if cache.finalizedBlock(blocks[0].Position) == nil {
+		t.Fatalf("finalized block %s not cached", blocks[0])
+	}
+	cache.addFinalizedBlock(blocks[2])
+
+	if len(cache.blocks(coreCommon.Hashes{blocks[0].Hash}, false)) != 1 ||
+		cache.finalizedBlock(blocks[0].Position) == nil {
+		t.Errorf("recently used block %s evicted", blocks[0])
+	}
+	if len(cache.blocks(coreCommon.Hashes{blocks[1].Hash}, false)) != 0 ||
+		cache.finalizedBlock(blocks[1].Position) != nil {
+		t.Errorf("least recently used block %s not evicted", blocks[1])
+	}
+	stats := cache.stats()
+	if stats.BlockEvictions != 1 || stats.FinalizedBlockEvictions != 1 {
+		t.Errorf("wrong evictions: %d blocks, %d finalized blocks",
+			stats.BlockEvictions, stats.FinalizedBlockEvictions)
+	}
+}
+
+func TestCacheFinalizedBlockDB(t *testing.T) {
+	memDB := ethdb.NewMemDatabase()
+	db := dexDB.NewDatabase(memDB)
+	cache := newSizedCache(3, 1, 1, db)
+	blocks := make([]*coreTypes.Block, 3)
+	for i := range blocks {
+		blocks[i] = &coreTypes.Block{
+			Hash:       coreCommon.NewRandomHash(),
+			Position:   coreTypes.Position{Round: 1, Height: uint64(i)},
+			Randomness: randomBytes(),
+		}
+	}
+	// The consensus core stores the blocks it delivers by hash.
+	for _, block := range blocks[:2] {
+		if err := db.PutBlock(*block); err != nil {
+			t.Fatalf("failed to store block: %v", err)
+		}
+	}
+	// Finalized blocks received from peers are not indexed.
+	cache.addFinalizedBlock(blocks[0])
+	cache.addFinalizedBlock(blocks[1])
+	if block := cache.finalizedBlock(blocks[0].Position); block != nil {
+		t.Errorf("unexpected block %s from db", block)
+	}
+
+	// Evicted finalized blocks are served from the database, where only
+	// their hash is stored by position.
+	cache.putFinalizedBlock(blocks[0])
+	cache.putFinalizedBlock(blocks[1])
+	if hash := rawdb.ReadCoreFinalizedHash(memDB, 1, 0); hash == nil || *hash != common.Hash(blocks[0].Hash) {
+		t.Errorf("indexed hash mismatch: have %v, want %x", hash, blocks[0].Hash)
+	}
+	block := cache.finalizedBlock(blocks[0].Position)
+	if block == nil {
+		t.Fatalf("finalized block %s not found in db", blocks[0])
+	}
+	if !block.Hash.Equal(blocks[0].Hash) ||
+		!reflect.DeepEqual(block.Randomness, blocks[0].Randomness) {
+		t.Errorf("block mismatch: have %s, want %s", block, blocks[0])
+	}
+	if block := cache.finalizedBlock(coreTypes.Position{Round: 2}); block != nil {
+		t.Errorf("unexpected block %s", block)
+	}
+
+	// Blocks indexed before being delivered are not served until stored with
+	// their randomness.
+	cache.putFinalizedBlock(blocks[2])
+	cache.putFinalizedBlock(blocks[0])
+	if block := cache.finalizedBlock(blocks[2].Position); block != nil {
+		t.Errorf("undelivered block %s served", block)
+	}
+	undelivered := *blocks[2]
+	undelivered.Randomness = nil
+	if err := db.PutBlock(undelivered); err != nil {
+		t.Fatalf("failed to store block: %v", err)
+	}
+	if block := cache.finalizedBlock(blocks[2].Position); block != nil {
+		t.Errorf("block without randomness served: %s", block)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// clockOffsetWeight is the weight of a new sample in the moving average
+	// of the clock offset observed from a peer.
+	clockOffsetWeight = 0.1
+
+	// networkTimeMinPeers is the minimum number of peers with an observed
+	// clock offset needed to adjust the local clock to the network time.
+	networkTimeMinPeers = 3
+)
+
+// clockOffset tracks the offset between the timestamps of the core blocks
+// proposed by a peer and the local clock at their receipt.
+type clockOffset struct {
+	lock    sync.Mutex
+	offset  time.Duration
+	samples int
+}
+
+// observe adds an offset sample to the moving average.
+func (c *clockOffset) observe(sample time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.samples == 0 {
+		c.offset = sample
+	} else {
+		c.offset += time.Duration(clockOffsetWeight * float64(sample-c.offset))
+	}
+	c.samples++
+}
+
+// value returns the average offset.
+func (c *clockOffset) value() time.Duration {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.offset
+}
+
+// observed returns the average offset and whether any sample was observed.
+func (c *clockOffset) observed() (time.Duration, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.offset, c.samples > 0
+}
+
+// medianOffset returns the median of the offsets observed from peers, capped
+// to limit. As long as the majority of the peers are honest, the median stays
+// within the range of the offsets of honest peers. Zero is returned if too
+// few offsets are given.
+func medianOffset(offsets []time.Duration, limit time.Duration) time.Duration {
+	if len(offsets) < networkTimeMinPeers {
+		return 0
+	}
+	sorted := make([]time.Duration, len(offsets))
+	copy(sorted, offsets)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + median) / 2
+	}
+	if median > limit {
+		median = limit
+	} else if median < -limit {
+		median = -limit
+	}
+	return median
+}
+
+// networkTime returns the local time adjusted by the median clock offset
+// observed from the connected peers, capped to the core message future
+// tolerance.
+func (pm *ProtocolManager) networkTime() time.Time {
+	var offsets []time.Duration
+	for _, p := range pm.peers.Peers() {
+		if offset, ok := p.clockOffset.observed(); ok {
+			offsets = append(offsets, offset)
+		}
+	}
+	return time.Now().Add(medianOffset(offsets, pm.futureTolerance))
+}
+
+// checkFutureTime decides what to do with a core message timestamped at
+// timestamp received at receivedAt from a peer whose observed clock offset is
+// offset. Messages ahead of the local clock by at most tolerance, extended by
+// the positive offset of the peer capped to tolerance, are accepted. Messages
+// further ahead by up to the same amount again are deferred by the returned
+// delay, while the rest are rejected.
+func checkFutureTime(timestamp, receivedAt time.Time, offset,
+	tolerance time.Duration) (delay time.Duration, ok bool) {
+	if tolerance <= 0 {
+		return 0, true
+	}
+	if offset < 0 {
+		offset = 0
+	} else if offset > tolerance {
+		offset = tolerance
+	}
+	allowed := tolerance + offset
+	drift := timestamp.Sub(receivedAt)
+	switch {
+	case drift <= allowed:
+		return 0, true
+	case drift <= 2*allowed:
+		return drift - allowed, true
+	default:
+		return 0, false
+	}
+}
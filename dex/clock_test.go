@@ -0,0 +1,89 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckFutureTime(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		drift  time.Duration
+		offset time.Duration
+		delay  time.Duration
+		ok     bool
+	}{
+		{-time.Second, 0, 0, true},
+		{2 * time.Second, 0, 0, true},
+		{3 * time.Second, 0, time.Second, true},
+		{5 * time.Second, 0, 0, false},
+		// Positive offsets extend the tolerance, up to the tolerance.
+		{3 * time.Second, time.Second, 0, true},
+		{5 * time.Second, time.Minute, time.Second, true},
+		{9 * time.Second, time.Minute, 0, false},
+		// Negative offsets are ignored.
+		{2 * time.Second, -time.Minute, 0, true},
+	}
+	for i, tt := range tests {
+		delay, ok := checkFutureTime(now.Add(tt.drift), now, tt.offset, 2*time.Second)
+		if delay != tt.delay || ok != tt.ok {
+			t.Errorf("test %d: have (%v, %v), want (%v, %v)", i, delay, ok, tt.delay, tt.ok)
+		}
+	}
+	if _, ok := checkFutureTime(now.Add(time.Hour), now, 0, 0); !ok {
+		t.Errorf("check not disabled by zero tolerance")
+	}
+}
+
+func TestMedianOffset(t *testing.T) {
+	tests := []struct {
+		offsets []time.Duration
+		median  time.Duration
+	}{
+		{nil, 0},
+		{[]time.Duration{time.Second, time.Second}, 0},
+		{[]time.Duration{time.Second, -time.Second, 0}, 0},
+		{[]time.Duration{time.Second, 3 * time.Second, 0, 2 * time.Second}, 1500 * time.Millisecond},
+		// Minority of outliers cannot move the median far.
+		{[]time.Duration{time.Hour, time.Hour, 0, 100 * time.Millisecond, 200 * time.Millisecond}, 200 * time.Millisecond},
+		// The median is capped.
+		{[]time.Duration{time.Hour, time.Hour, time.Hour}, 2 * time.Second},
+		{[]time.Duration{-time.Hour, -time.Hour, -time.Hour}, -2 * time.Second},
+	}
+	for i, tt := range tests {
+		if median := medianOffset(tt.offsets, 2*time.Second); median != tt.median {
+			t.Errorf("test %d: have %v, want %v", i, median, tt.median)
+		}
+	}
+}
+
+func TestClockOffset(t *testing.T) {
+	var offset clockOffset
+	offset.observe(time.Second)
+	if v := offset.value(); v != time.Second {
+		t.Errorf("wrong initial offset: have %v, want %v", v, time.Second)
+	}
+	for i := 0; i < 100; i++ {
+		offset.observe(0)
+	}
+	if v := offset.value(); v > time.Millisecond {
+		t.Errorf("offset not converging: %v", v)
+	}
+}
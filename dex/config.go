@@ -28,6 +28,7 @@ import (
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/core"
 	"github.com/portto/go-tangerine/dex/downloader"
+	"github.com/portto/go-tangerine/eth/filters"
 	"github.com/portto/go-tangerine/eth/gasprice"
 	"github.com/portto/go-tangerine/indexer"
 	"github.com/portto/go-tangerine/params"
@@ -49,8 +50,33 @@ var DefaultConfig = Config{
 		Percentile: 60,
 	},
 	BlockProposerEnabled: false,
+	TxOrdering:           TxOrderPrice,
 	DefaultGasPrice:      big.NewInt(params.GWei),
+	FilterTimeout:        filters.DefaultFilterTimeout,
 	Indexer:              indexer.Config{},
+
+	RPCGovernanceGasCap: big.NewInt(50000000),
+
+	NotaryPreconnectBlocks: 60,
+	PeerHistoryRetention:   7 * 24 * time.Hour,
+
+	CoreBlockCacheSize:          defaultCacheSize,
+	CoreFinalizedBlockCacheSize: defaultCacheSize,
+
+	Alerts: AlertConfig{
+		Interval: 10 * time.Minute,
+	},
+
+	Fanout: FanoutConfig{
+		Blocks:     FanoutSqrt,
+		CoreBlocks: FanoutNotary,
+		Votes:      FanoutNotary,
+	},
+
+	AdaptiveLambda: AdaptiveLambdaConfig{
+		Min: 0.5,
+		Max: 1,
+	},
 }
 
 func init() {
@@ -74,7 +100,8 @@ type Config struct {
 	// If nil, the Ethereum main net block is used.
 	Genesis *core.Genesis `toml:",omitempty"`
 
-	// PrivateKey, also represents the node identity.
+	// PrivateKey, also represents the node identity. With an ExternalSigner
+	// it must be the signer key only if the node proposes blocks.
 	PrivateKey *ecdsa.PrivateKey `toml:",omitempty"`
 
 	// Protocol options
@@ -82,6 +109,28 @@ type Config struct {
 	SyncMode  downloader.SyncMode
 	NoPruning bool
 
+	// Checkpoint is the trusted finalized block a checkpoint sync bootstraps
+	// an empty chain from, required by the checkpoint sync mode.
+	Checkpoint *downloader.Checkpoint `toml:",omitempty"`
+
+	// TxLookupLimit is the number of recent blocks whose transactions are
+	// indexed for lookups by hash, zero indexes all blocks. Lookups of older
+	// transactions find nothing, like lookups of unknown ones, and
+	// eth_txIndexTail returns the oldest indexed block.
+	TxLookupLimit uint64
+
+	// ExtendedReceipts stores the touched accounts and gas refund of the
+	// transactions of new blocks apart from their receipts.
+	ExtendedReceipts bool
+
+	// ScrubInterval is the interval between integrity checks of randomly
+	// sampled stored blocks and receipts, zero disabling the scrubber.
+	ScrubInterval time.Duration
+
+	// PeerHistoryRetention is how long peer connection and disconnection
+	// events are kept, zero disabling the peer history.
+	PeerHistoryRetention time.Duration
+
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
@@ -110,6 +159,23 @@ type Config struct {
 	// BlockProposer options
 	BlockProposerEnabled bool
 
+	// ExternalSigner is the JSON-RPC endpoint of an external signer holding
+	// the node key, signing consensus messages and governance transactions
+	// instead of the node. Empty signs with PrivateKey.
+	ExternalSigner string `toml:",omitempty"`
+
+	// DKGPassphrase encrypts the DKG private keys and protocol info stored
+	// in the database, which are kept in plaintext if empty.
+	DKGPassphrase string `toml:"-"`
+
+	// TxOrdering is the policy ordering the transactions of proposed
+	// payloads, one of TxOrderPrice, TxOrderFIFO and TxOrderFair.
+	TxOrdering string
+
+	// SafeMode starts the node without networking and consensus, serving
+	// RPC from the local database without modifying it.
+	SafeMode bool `toml:"-"`
+
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
@@ -125,6 +191,15 @@ type Config struct {
 	// RPCGasCap is the global gas cap for eth-call variants.
 	RPCGasCap *big.Int `toml:",omitempty"`
 
+	// RPCGovernanceGasCap is the gas cap for eth-calls of read-only
+	// governance contract methods, which are exempt from RPCGasCap. Nil
+	// leaves them uncapped.
+	RPCGovernanceGasCap *big.Int `toml:",omitempty"`
+
+	// FilterTimeout is the time after which filters that have not been
+	// polled are removed.
+	FilterTimeout time.Duration
+
 	// Tangerine options
 	DMoment int64
 
@@ -133,4 +208,53 @@ type Config struct {
 
 	// Recovery network RPC
 	RecoveryNetworkRPC string
+
+	// Label protocol message handlers with the message type in profiles
+	MsgProfilingLabels bool
+
+	// CoreMsgFutureTolerance is how far ahead of the local clock core blocks
+	// may be timestamped, zero, the default, disabling the check. Notaries
+	// with skewed clocks lose liveness if it is too tight.
+	CoreMsgFutureTolerance time.Duration
+
+	// NotaryPreconnectBlocks is how many blocks before the end of a round a
+	// block proposer makes sure it is connected to the notary set of the next
+	// round, zero disabling it.
+	NotaryPreconnectBlocks uint64
+
+	// CoreBlockCacheSize and CoreFinalizedBlockCacheSize are the number of
+	// core blocks and finalized core blocks kept in memory to serve pull
+	// requests, least recently used ones being evicted first.
+	CoreBlockCacheSize          int
+	CoreFinalizedBlockCacheSize int
+
+	// SigVerifyWorkers is the number of workers verifying the signatures of
+	// the core blocks and votes received, zero meaning one per CPU.
+	SigVerifyWorkers int
+
+	// PeerScalingMargin is how many peers the node allows on top of the
+	// notary set of the current round, the peer limits following the notary
+	// set size instead of MaxPeers. Zero disables the scaling.
+	PeerScalingMargin int
+
+	// StateRootGossip enables announcing and comparing the state roots of
+	// validators at round boundaries, divergences raising an alert.
+	StateRootGossip bool
+
+	// Alerts configures the webhooks consensus critical events concerning
+	// the node are posted to.
+	Alerts AlertConfig
+
+	// Fanout configures the number of peers blocks and consensus messages
+	// are relayed to.
+	Fanout FanoutConfig
+
+	// AdaptiveLambda lets the BA interval follow the observed confirmation
+	// times within bounds, disabled by default.
+	AdaptiveLambda AdaptiveLambdaConfig
+
+	// AuditProxyUser records the basic-auth user names of RPC requests in
+	// the audit log. Only enable it behind a proxy authenticating them, as
+	// clients may send any name.
+	AuditProxyUser bool
 }
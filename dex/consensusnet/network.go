@@ -0,0 +1,145 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+// Package consensusnet adapts a peer-to-peer message transport to the Network
+// interface required by the consensus core.
+//
+// The dex package uses it to connect its ProtocolManager to the consensus
+// core. Applications that bring their own Application implementation but
+// want to reuse Tangerine's networking can do the same by passing any value
+// satisfying Transport to New.
+package consensusnet
+
+import (
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	dexCore "github.com/portto/tangerine-consensus/core"
+	"github.com/portto/tangerine-consensus/core/crypto"
+	"github.com/portto/tangerine-consensus/core/types"
+	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+)
+
+// Transport is the set of peer-to-peer primitives the Network is built on.
+// It is implemented by the dex ProtocolManager.
+type Transport interface {
+	// BroadcastPullBlocks asks peers for the blocks with the given hashes.
+	BroadcastPullBlocks(hashes coreCommon.Hashes)
+
+	// BroadcastPullVotes asks notary set peers for the votes at a position.
+	BroadcastPullVotes(pos types.Position)
+
+	// BroadcastVote sends a vote to the notary set.
+	BroadcastVote(vote *types.Vote)
+
+	// BroadcastFinalizedBlock sends a finalized block to all peers.
+	BroadcastFinalizedBlock(block *types.Block)
+
+	// BroadcastCoreBlock sends a not yet finalized block to the notary set.
+	BroadcastCoreBlock(block *types.Block)
+
+	// BroadcastAgreementResult sends an agreement result to all peers.
+	BroadcastAgreementResult(result *types.AgreementResult)
+
+	// SendDKGPrivateShare sends a private share to a single DKG participant.
+	SendDKGPrivateShare(pub crypto.PublicKey, prvShare *dkgTypes.PrivateShare)
+
+	// BroadcastDKGPrivateShare sends a private share to all DKG participants.
+	BroadcastDKGPrivateShare(prvShare *dkgTypes.PrivateShare)
+
+	// BroadcastDKGPartialSignature sends a partial signature to all DKG
+	// participants.
+	BroadcastDKGPartialSignature(psig *dkgTypes.PartialSignature)
+
+	// ReceiveChan returns the channel of consensus messages from peers.
+	ReceiveChan() <-chan types.Msg
+
+	// ReportBadPeerChan returns the channel used to report misbehaving peers.
+	ReportBadPeerChan() chan<- interface{}
+}
+
+// Network implements the consensus core Network interface on top of a
+// Transport.
+type Network struct {
+	t Transport
+}
+
+var _ dexCore.Network = (*Network)(nil)
+
+// New creates a Network sending and receiving messages through t.
+func New(t Transport) *Network {
+	return &Network{t: t}
+}
+
+// PullBlocks tries to pull blocks from the DEXON network.
+func (n *Network) PullBlocks(hashes coreCommon.Hashes) {
+	if len(hashes) == 0 {
+		return
+	}
+	n.t.BroadcastPullBlocks(hashes)
+}
+
+// PullVotes tries to pull votes from the DEXON network.
+func (n *Network) PullVotes(pos types.Position) {
+	n.t.BroadcastPullVotes(pos)
+}
+
+// BroadcastVote broadcasts vote to all nodes in DEXON network.
+func (n *Network) BroadcastVote(vote *types.Vote) {
+	n.t.BroadcastVote(vote)
+}
+
+// BroadcastBlock broadcasts block to all nodes in DEXON network.
+func (n *Network) BroadcastBlock(block *types.Block) {
+	if block.IsFinalized() {
+		n.t.BroadcastFinalizedBlock(block)
+	} else {
+		n.t.BroadcastCoreBlock(block)
+	}
+}
+
+// SendDKGPrivateShare sends PrivateShare to a DKG participant.
+func (n *Network) SendDKGPrivateShare(
+	pub crypto.PublicKey, prvShare *dkgTypes.PrivateShare) {
+	n.t.SendDKGPrivateShare(pub, prvShare)
+}
+
+// BroadcastDKGPrivateShare broadcasts PrivateShare to all DKG participants.
+func (n *Network) BroadcastDKGPrivateShare(
+	prvShare *dkgTypes.PrivateShare) {
+	n.t.BroadcastDKGPrivateShare(prvShare)
+}
+
+// BroadcastDKGPartialSignature broadcasts partialSignature to all
+// DKG participants.
+func (n *Network) BroadcastDKGPartialSignature(
+	psig *dkgTypes.PartialSignature) {
+	n.t.BroadcastDKGPartialSignature(psig)
+}
+
+// BroadcastAgreementResult broadcasts rand request to DKG set.
+func (n *Network) BroadcastAgreementResult(result *types.AgreementResult) {
+	n.t.BroadcastAgreementResult(result)
+}
+
+// ReceiveChan returns a channel to receive messages from DEXON network.
+func (n *Network) ReceiveChan() <-chan types.Msg {
+	return n.t.ReceiveChan()
+}
+
+// ReportBadPeerChan returns a channel to report bad peers.
+func (n *Network) ReportBadPeerChan() chan<- interface{} {
+	return n.t.ReportBadPeerChan()
+}
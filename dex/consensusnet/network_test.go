@@ -0,0 +1,74 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package consensusnet
+
+import (
+	"testing"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	"github.com/portto/tangerine-consensus/core/crypto"
+	"github.com/portto/tangerine-consensus/core/types"
+	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+)
+
+type recordingTransport struct {
+	pulls     int
+	finalized int
+	core      int
+}
+
+func (t *recordingTransport) BroadcastPullBlocks(coreCommon.Hashes)           { t.pulls++ }
+func (t *recordingTransport) BroadcastPullVotes(types.Position)               {}
+func (t *recordingTransport) BroadcastVote(*types.Vote)                       {}
+func (t *recordingTransport) BroadcastFinalizedBlock(*types.Block)            { t.finalized++ }
+func (t *recordingTransport) BroadcastCoreBlock(*types.Block)                 { t.core++ }
+func (t *recordingTransport) BroadcastAgreementResult(*types.AgreementResult) {}
+func (t *recordingTransport) SendDKGPrivateShare(crypto.PublicKey, *dkgTypes.PrivateShare) {
+}
+func (t *recordingTransport) BroadcastDKGPrivateShare(*dkgTypes.PrivateShare)         {}
+func (t *recordingTransport) BroadcastDKGPartialSignature(*dkgTypes.PartialSignature) {}
+func (t *recordingTransport) ReceiveChan() <-chan types.Msg                           { return nil }
+func (t *recordingTransport) ReportBadPeerChan() chan<- interface{}                   { return nil }
+
+func TestNetworkBroadcastBlock(t *testing.T) {
+	tr := &recordingTransport{}
+	n := New(tr)
+
+	n.BroadcastBlock(&types.Block{})
+	if tr.core != 1 || tr.finalized != 0 {
+		t.Errorf("unfinalized block routed wrong: core %d, finalized %d", tr.core, tr.finalized)
+	}
+	n.BroadcastBlock(&types.Block{Randomness: []byte{1}})
+	if tr.core != 1 || tr.finalized != 1 {
+		t.Errorf("finalized block routed wrong: core %d, finalized %d", tr.core, tr.finalized)
+	}
+}
+
+func TestNetworkPullBlocksSkipsEmpty(t *testing.T) {
+	tr := &recordingTransport{}
+	n := New(tr)
+
+	n.PullBlocks(nil)
+	if tr.pulls != 0 {
+		t.Errorf("empty pull was broadcast")
+	}
+	n.PullBlocks(coreCommon.Hashes{coreCommon.Hash{1}})
+	if tr.pulls != 1 {
+		t.Errorf("pull count mismatch: have %d, want 1", tr.pulls)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"fmt"
+	"time"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreDb "github.com/portto/tangerine-consensus/core/db"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/types"
+	dexDB "github.com/portto/go-tangerine/dex/db"
+	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/rlp"
+)
+
+// CheckCoreTip returns an error if the compaction chain tip stored in db is
+// not the core block of the canonical block at its height. A tip ahead of the
+// chain head is left to replayDelivered.
+func CheckCoreTip(bc *core.BlockChain, db *dexDB.DB) error {
+	hash, height := db.GetCompactionChainTipInfo()
+	if height == 0 || height > bc.CurrentBlock().NumberU64() {
+		return nil
+	}
+	header := bc.GetHeaderByNumber(height)
+	if header == nil {
+		return fmt.Errorf("canonical block %d not found", height)
+	}
+	block, err := header.CoreBlock()
+	if err != nil {
+		return fmt.Errorf("invalid DexconMeta of block %d: %v", height, err)
+	}
+	if block.Hash != hash {
+		return fmt.Errorf("compaction chain tip diverged at %d: have %s, want %s",
+			height, hash.String(), block.Hash.String())
+	}
+	return nil
+}
+
+// RepairCoreTip resets the compaction chain tip stored in db to the core
+// block of the chain head, rebuilt from its DexconMeta and stored in db if
+// missing. A higher tip is reset as well, dropping the core blocks delivered
+// but not executed, which the node syncs again.
+func RepairCoreTip(bc *core.BlockChain, db *dexDB.DB) (coreCommon.Hash, uint64, error) {
+	head := bc.CurrentBlock()
+	if head.NumberU64() == 0 {
+		return coreCommon.Hash{}, 0, db.ResetCompactionChainTipInfo(coreCommon.Hash{}, 0)
+	}
+	block, err := coreBlockOf(head)
+	if err != nil {
+		return coreCommon.Hash{}, 0, err
+	}
+	if !db.HasBlock(block.Hash) {
+		if err := db.PutBlock(*block); err != nil {
+			return coreCommon.Hash{}, 0, err
+		}
+	}
+	if err := db.ResetCompactionChainTipInfo(block.Hash, block.Position.Height); err != nil {
+		return coreCommon.Hash{}, 0, err
+	}
+	return block.Hash, block.Position.Height, nil
+}
+
+// BackfillStats reports the outcome of a core database backfill.
+type BackfillStats struct {
+	Blocks   int  // Core blocks written to the database
+	Skipped  int  // Core blocks the database already had
+	ChainTip bool // Whether the compaction chain tip was reset to the chain head
+}
+
+// BackfillCoreDB rebuilds the core blocks of the canonical chain from their
+// DexconMeta and stores the ones missing in db, along with the finalized
+// blocks, for a node whose consensus core database was lost. The compaction
+// chain tip is then reset to the chain head, unless it is on the chain and
+// not lower. Present blocks are skipped, so the backfill can be rerun after an
+// interruption.
+func BackfillCoreDB(bc *core.BlockChain, db *dexDB.DB) (BackfillStats, error) {
+	var (
+		stats  BackfillStats
+		parent coreCommon.Hash
+		head   = bc.CurrentBlock().NumberU64()
+		logged = time.Now()
+	)
+	for number := uint64(1); number <= head; number++ {
+		block := bc.GetBlockByNumber(number)
+		if block == nil {
+			return stats, fmt.Errorf("canonical block %d not found", number)
+		}
+		coreBlock, err := coreBlockOf(block)
+		if err != nil {
+			return stats, err
+		}
+		if number > 1 && coreBlock.ParentHash != parent {
+			return stats, fmt.Errorf("core block at %d not linked to its parent: have %s, want %s",
+				number, coreBlock.ParentHash.String(), parent.String())
+		}
+		parent = coreBlock.Hash
+
+		if db.HasBlock(coreBlock.Hash) {
+			stats.Skipped++
+		} else {
+			if err := db.PutBlock(*coreBlock); err != nil {
+				return stats, err
+			}
+			stats.Blocks++
+		}
+		if coreBlock.IsFinalized() {
+			if _, err := db.GetFinalizedBlock(coreBlock.Position); err == coreDb.ErrBlockDoesNotExist {
+				if err := db.PutFinalizedBlock(*coreBlock); err != nil {
+					return stats, err
+				}
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Backfilling core blocks", "number", number, "head", head,
+				"written", stats.Blocks, "skipped", stats.Skipped)
+			logged = time.Now()
+		}
+	}
+	if _, height := db.GetCompactionChainTipInfo(); height < head || CheckCoreTip(bc, db) != nil {
+		if _, _, err := RepairCoreTip(bc, db); err != nil {
+			return stats, err
+		}
+		stats.ChainTip = true
+	}
+	return stats, nil
+}
+
+// coreBlockOf rebuilds the core block of block from its DexconMeta and body.
+func coreBlockOf(block *types.Block) (*coreTypes.Block, error) {
+	coreBlock, err := block.Header().CoreBlock()
+	if err != nil {
+		return nil, fmt.Errorf("invalid DexconMeta of block %d: %v", block.NumberU64(), err)
+	}
+	if coreBlock.Position.Height != block.NumberU64() {
+		return nil, fmt.Errorf("DexconMeta of block %d at height %d",
+			block.NumberU64(), coreBlock.Position.Height)
+	}
+	// DexconMeta leaves out the payload, which is the block body.
+	if txs := block.Transactions(); len(txs) > 0 {
+		if coreBlock.Payload, err = rlp.EncodeToBytes(txs); err != nil {
+			return nil, err
+		}
+	}
+	return coreBlock, nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+	"time"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
+	"github.com/portto/go-tangerine/crypto"
+	dexDB "github.com/portto/go-tangerine/dex/db"
+	"github.com/portto/go-tangerine/ethdb"
+)
+
+// Tests that a compaction chain tip diverged from the chain is detected and
+// reset to the core block of the chain head.
+func TestRepairCoreTip(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+	db := dexDB.NewDatabase(dex.chainDb)
+
+	// Deliver and execute a few blocks.
+	var (
+		parent coreCommon.Hash
+		hashes []coreCommon.Hash
+	)
+	start := time.Unix(0, int64(dex.blockchain.CurrentBlock().Time())*int64(time.Millisecond))
+	for height := uint64(1); height <= 3; height++ {
+		block := coreTypes.Block{
+			ParentHash: parent,
+			Hash:       coreCommon.NewRandomHash(),
+			Position:   coreTypes.Position{Height: height},
+			Timestamp:  start.Add(time.Duration(height) * time.Second).UTC(),
+			Randomness: []byte{byte(height)},
+		}
+		if err := db.PutBlock(block); err != nil {
+			t.Fatalf("failed to put block: %v", err)
+		}
+		if err := db.PutCompactionChainTipInfo(block.Hash, height); err != nil {
+			t.Fatalf("failed to put tip: %v", err)
+		}
+		parent = block.Hash
+		hashes = append(hashes, block.Hash)
+	}
+	if _, err := dex.app.replayDelivered(db); err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	if err := CheckCoreTip(dex.blockchain, db); err != nil {
+		t.Fatalf("consistent tip refused: %v", err)
+	}
+
+	// A tip behind the head is fine as long as it is on the chain.
+	if err := db.ResetCompactionChainTipInfo(hashes[1], 2); err != nil {
+		t.Fatalf("failed to reset tip: %v", err)
+	}
+	if err := CheckCoreTip(dex.blockchain, db); err != nil {
+		t.Fatalf("tip behind the head refused: %v", err)
+	}
+
+	// A tip off the chain is detected and repaired.
+	if err := db.ResetCompactionChainTipInfo(coreCommon.NewRandomHash(), 2); err != nil {
+		t.Fatalf("failed to reset tip: %v", err)
+	}
+	if err := CheckCoreTip(dex.blockchain, db); err == nil {
+		t.Fatalf("diverged tip accepted")
+	}
+	hash, height, err := RepairCoreTip(dex.blockchain, db)
+	if err != nil {
+		t.Fatalf("failed to repair tip: %v", err)
+	}
+	if hash != hashes[2] || height != 3 {
+		t.Fatalf("repaired tip mismatch: have %s at %d, want %s at 3", hash.String(), height, hashes[2].String())
+	}
+	if have, haveHeight := db.GetCompactionChainTipInfo(); have != hash || haveHeight != height {
+		t.Fatalf("stored tip mismatch: have %s at %d", have.String(), haveHeight)
+	}
+	if err := CheckCoreTip(dex.blockchain, db); err != nil {
+		t.Fatalf("repaired tip refused: %v", err)
+	}
+}
+
+// Tests that a lost core database is rebuilt from the executed chain.
+func TestBackfillCoreDB(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+	db := dexDB.NewDatabase(dex.chainDb)
+
+	// Deliver and execute a few blocks.
+	var (
+		parent coreCommon.Hash
+		blocks []coreTypes.Block
+	)
+	start := time.Unix(0, int64(dex.blockchain.CurrentBlock().Time())*int64(time.Millisecond))
+	for height := uint64(1); height <= 3; height++ {
+		block := coreTypes.Block{
+			ParentHash: parent,
+			Hash:       coreCommon.NewRandomHash(),
+			Position:   coreTypes.Position{Height: height},
+			Timestamp:  start.Add(time.Duration(height) * time.Second).UTC(),
+			Randomness: []byte{byte(height)},
+		}
+		if err := db.PutBlock(block); err != nil {
+			t.Fatalf("failed to put block: %v", err)
+		}
+		if err := db.PutCompactionChainTipInfo(block.Hash, height); err != nil {
+			t.Fatalf("failed to put tip: %v", err)
+		}
+		parent = block.Hash
+		blocks = append(blocks, block)
+	}
+	if _, err := dex.app.replayDelivered(db); err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+
+	// Rebuild the core database into an empty one.
+	lost := dexDB.NewDatabase(ethdb.NewMemDatabase())
+	stats, err := BackfillCoreDB(dex.blockchain, lost)
+	if err != nil {
+		t.Fatalf("failed to backfill: %v", err)
+	}
+	if stats.Blocks != 3 || stats.Skipped != 0 || !stats.ChainTip {
+		t.Fatalf("backfill stats mismatch: %+v", stats)
+	}
+	for _, want := range blocks {
+		have, err := lost.GetBlock(want.Hash)
+		if err != nil {
+			t.Fatalf("block %d not backfilled: %v", want.Position.Height, err)
+		}
+		if have.ParentHash != want.ParentHash || have.Position != want.Position {
+			t.Fatalf("block %d mismatch: have %v, want %v", want.Position.Height, have, want)
+		}
+		if _, err := lost.GetFinalizedBlock(want.Position); err != nil {
+			t.Fatalf("finalized block %d not backfilled: %v", want.Position.Height, err)
+		}
+	}
+	if hash, height := lost.GetCompactionChainTipInfo(); hash != blocks[2].Hash || height != 3 {
+		t.Fatalf("backfilled tip mismatch: have %s at %d", hash.String(), height)
+	}
+
+	// A rerun skips the present blocks and keeps the tip.
+	if stats, err = BackfillCoreDB(dex.blockchain, lost); err != nil {
+		t.Fatalf("failed to backfill again: %v", err)
+	}
+	if stats.Blocks != 0 || stats.Skipped != 3 || stats.ChainTip {
+		t.Fatalf("rerun stats mismatch: %+v", stats)
+	}
+}
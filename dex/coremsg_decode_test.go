@@ -0,0 +1,167 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	cryptoDKG "github.com/portto/tangerine-consensus/core/crypto/dkg"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+
+	"github.com/portto/go-tangerine/rlp"
+)
+
+// coreMsgCorpus returns valid encodings of the consensus core types decoded
+// from peers or governance transactions, with constructors of empty values
+// to decode them into.
+func coreMsgCorpus(t *testing.T) map[string]struct {
+	enc []byte
+	new func() interface{}
+} {
+	prvShares, pubShares := cryptoDKG.NewPrivateKeyShares(3)
+	prvShares.SetParticipants(cryptoDKG.IDs{cryptoDKG.NewID([]byte{1})})
+	share, _ := prvShares.Share(cryptoDKG.NewID([]byte{1}))
+
+	values := map[string]struct {
+		val interface{}
+		new func() interface{}
+	}{
+		"blocks": {
+			[]*coreTypes.Block{{
+				Hash:       coreCommon.NewRandomHash(),
+				Position:   coreTypes.Position{Round: 1, Height: 2},
+				Timestamp:  time.Now().UTC(),
+				Payload:    []byte{1, 2, 3},
+				Witness:    coreTypes.Witness{Height: 1, Data: []byte{4}},
+				Randomness: []byte{5, 6, 7, 8},
+			}},
+			func() interface{} { return new([]*coreTypes.Block) },
+		},
+		"votes": {
+			[]*coreTypes.Vote{coreTypes.NewVote(coreTypes.VoteCom, coreCommon.NewRandomHash(), 1)},
+			func() interface{} { return new([]*coreTypes.Vote) },
+		},
+		"agreement": {
+			&coreTypes.AgreementResult{BlockHash: coreCommon.NewRandomHash(), Randomness: []byte{1}},
+			func() interface{} { return new(coreTypes.AgreementResult) },
+		},
+		"privateShare": {
+			&dkgTypes.PrivateShare{Round: 1, PrivateShare: *share},
+			func() interface{} { return new(dkgTypes.PrivateShare) },
+		},
+		"privateKeyShares": {
+			prvShares,
+			func() interface{} { return new(cryptoDKG.PrivateKeyShares) },
+		},
+		"masterPublicKey": {
+			&dkgTypes.MasterPublicKey{Round: 1, PublicKeyShares: *pubShares.Move()},
+			func() interface{} { return new(dkgTypes.MasterPublicKey) },
+		},
+		"complaint": {
+			&dkgTypes.Complaint{Round: 1, PrivateShare: dkgTypes.PrivateShare{Round: 1}},
+			func() interface{} { return new(dkgTypes.Complaint) },
+		},
+	}
+	corpus := make(map[string]struct {
+		enc []byte
+		new func() interface{}
+	})
+	for name, v := range values {
+		enc, err := rlp.EncodeToBytes(v.val)
+		if err != nil {
+			t.Fatalf("%s: failed to encode: %v", name, err)
+		}
+		if err := rlp.DecodeBytes(enc, v.new()); err != nil {
+			t.Fatalf("%s: failed to decode: %v", name, err)
+		}
+		corpus[name] = struct {
+			enc []byte
+			new func() interface{}
+		}{enc, v.new}
+	}
+	return corpus
+}
+
+// Tests that truncated and corrupted encodings of the consensus core types
+// fail to decode instead of crashing the node.
+func TestDecodeMalformedCoreMessages(t *testing.T) {
+	decode := func(name string, enc []byte, val interface{}) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("%s: decoding %x panicked: %v", name, enc, r)
+			}
+		}()
+		if err = rlp.DecodeBytes(enc, val); err == nil {
+			_ = fmt.Sprint(val)
+		}
+		return err
+	}
+	for name, c := range coreMsgCorpus(t) {
+		for i := 0; i < len(c.enc); i++ {
+			decode(name, c.enc[:i], c.new())
+
+			for _, b := range []byte{0x00, 0x80, 0xc0, 0xff} {
+				enc := append([]byte{}, c.enc...)
+				enc[i] = b
+				decode(name, enc, c.new())
+			}
+		}
+	}
+
+	// Crashers of the key share decoders.
+	idx, _ := rlp.EncodeToBytes(uint64(5))
+	crashers := map[string][]byte{
+		"short":       mustEncode(t, [][][]byte{{}}),
+		"long":        mustEncode(t, [][][]byte{{}, {}, {}, {}}),
+		"odd index":   mustEncode(t, [][][]byte{{}, {{1}}, {}}),
+		"index range": mustEncode(t, [][][]byte{{}, {{1}, idx}, {}}),
+	}
+	for name, enc := range crashers {
+		if err := decode(name, enc, new(cryptoDKG.PrivateKeyShares)); err == nil {
+			t.Errorf("%s: private key shares decoded", name)
+		}
+	}
+}
+
+// Tests that hardening the decoders does not change which DKG master public
+// keys the governance contract accepts: the number of master public keys is
+// not bounded by the decoder.
+func TestDecodeLargeMasterPublicKey(t *testing.T) {
+	_, pubShares := cryptoDKG.NewPrivateKeyShares(1100)
+	mpk := &dkgTypes.MasterPublicKey{Round: 1, PublicKeyShares: *pubShares.Move()}
+
+	var dec dkgTypes.MasterPublicKey
+	if err := rlp.DecodeBytes(mustEncode(t, mpk), &dec); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if !dec.PublicKeyShares.Equal(&mpk.PublicKeyShares) {
+		t.Errorf("master public keys mismatch")
+	}
+}
+
+func mustEncode(t *testing.T, val interface{}) []byte {
+	enc, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	return enc
+}
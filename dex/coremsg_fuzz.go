@@ -0,0 +1,73 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+// +build gofuzz
+
+package dex
+
+import (
+	"fmt"
+
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+
+	"github.com/portto/go-tangerine/rlp"
+)
+
+// Fuzz implements a go-fuzz fuzzer method decoding the consensus core types
+// received from peers or carried by governance transactions. The first byte
+// selects the type, the rest is the RLP encoding.
+func Fuzz(data []byte) int {
+	if len(data) == 0 {
+		return -1
+	}
+	var (
+		blocks []*coreTypes.Block
+		votes  []*coreTypes.Vote
+		val    interface{}
+	)
+	switch data[0] % 8 {
+	case 0:
+		val = &blocks
+	case 1:
+		val = &votes
+	case 2:
+		val = new(coreTypes.AgreementResult)
+	case 3:
+		val = new(dkgTypes.PrivateShare)
+	case 4:
+		val = new(dkgTypes.PartialSignature)
+	case 5:
+		val = new(dkgTypes.MasterPublicKey)
+	case 6:
+		val = new(dkgTypes.Complaint)
+	case 7:
+		val = new(dkgTypes.Finalize)
+	}
+	if err := rlp.DecodeBytes(data[1:], val); err != nil {
+		return 0
+	}
+	// Decoded values get logged, make sure they print.
+	for _, block := range blocks {
+		_ = block.String()
+	}
+	for _, vote := range votes {
+		_ = vote.String()
+	}
+	_ = fmt.Sprint(val)
+	return 1
+}
@@ -0,0 +1,166 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/event"
+	"github.com/portto/go-tangerine/log"
+)
+
+const (
+	crsChainHeadChanSize = 16
+
+	// crsResubmitBlocks is the number of blocks to wait for a CRS proposal
+	// to land before resubmitting it.
+	crsResubmitBlocks = 8
+
+	// crsMaxResubmissions is the maximum number of times a CRS proposal is
+	// resubmitted.
+	crsMaxResubmissions = 5
+
+	// crsGasPriceBump is the percentage by which the gas price is raised
+	// when replacing a pending CRS proposal, it has to exceed the price bump
+	// required by the transaction pool.
+	crsGasPriceBump = 25
+)
+
+// crsProposal is a CRS proposal submitted by this node.
+type crsProposal struct {
+	round       uint64
+	data        []byte
+	tx          *types.Transaction
+	submittedAt uint64
+	attempts    int
+}
+
+// crsProposer makes sure the CRS proposal of this node lands in the
+// governance state before its round begins, resubmitting the proposal when
+// the transaction is lost or failed.
+type crsProposer struct {
+	gov        *DexconGovernance
+	blockchain *core.BlockChain
+
+	lock    sync.Mutex
+	pending *crsProposal
+
+	headCh  chan core.ChainHeadEvent
+	headSub event.Subscription
+}
+
+func newCRSProposer(gov *DexconGovernance, blockchain *core.BlockChain) *crsProposer {
+	return &crsProposer{
+		gov:        gov,
+		blockchain: blockchain,
+	}
+}
+
+func (p *crsProposer) Start() {
+	p.headCh = make(chan core.ChainHeadEvent, crsChainHeadChanSize)
+	p.headSub = p.blockchain.SubscribeChainHeadEvent(p.headCh)
+	go p.loop()
+}
+
+func (p *crsProposer) Stop() {
+	p.headSub.Unsubscribe()
+}
+
+// track starts tracking the CRS proposal of round, tx being the transaction
+// sent for it or nil if sending failed.
+func (p *crsProposer) track(round uint64, data []byte, tx *types.Transaction) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.pending = &crsProposal{
+		round:       round,
+		data:        data,
+		tx:          tx,
+		submittedAt: p.blockchain.CurrentBlock().NumberU64(),
+	}
+}
+
+func (p *crsProposer) loop() {
+	for {
+		select {
+		case ev := <-p.headCh:
+			p.check(ev.Block)
+		case <-p.headSub.Err():
+			return
+		}
+	}
+}
+
+// check verifies the pending proposal against the state of head and
+// resubmits it if it has not landed in time.
+func (p *crsProposer) check(head *types.Block) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	proposal := p.pending
+	if proposal == nil {
+		return
+	}
+	if p.gov.CRSRound() >= proposal.round {
+		log.Debug("CRS proposal landed", "round", proposal.round,
+			"attempts", proposal.attempts+1)
+		p.pending = nil
+		return
+	}
+	if head.Round() >= proposal.round {
+		log.Error("CRS proposal missed its round", "round", proposal.round,
+			"attempts", proposal.attempts+1)
+		p.pending = nil
+		return
+	}
+	if head.NumberU64() < proposal.submittedAt+crsResubmitBlocks {
+		return
+	}
+	if proposal.attempts >= crsMaxResubmissions {
+		log.Error("Giving up CRS proposal", "round", proposal.round,
+			"attempts", proposal.attempts+1)
+		p.pending = nil
+		return
+	}
+	tx, err := p.resubmit(head, proposal)
+	if err != nil {
+		log.Error("Failed to resubmit CRS proposal", "round", proposal.round,
+			"err", err)
+	} else {
+		log.Warn("Resubmitted CRS proposal", "round", proposal.round,
+			"nonce", tx.Nonce(), "gasPrice", tx.GasPrice())
+		proposal.tx = tx
+	}
+	proposal.attempts++
+	proposal.submittedAt = head.NumberU64()
+}
+
+// resubmit sends the proposal again. A transaction still pending is replaced
+// with a higher gas price, otherwise the proposal is sent with a new nonce.
+func (p *crsProposer) resubmit(head *types.Block, proposal *crsProposal) (*types.Transaction, error) {
+	ctx := context.Background()
+	if proposal.tx == nil {
+		return p.gov.submitGovTx(ctx, proposal.data)
+	}
+	gasPrice := new(big.Int).Mul(proposal.tx.GasPrice(), big.NewInt(100+crsGasPriceBump))
+	gasPrice.Div(gasPrice, big.NewInt(100))
+	return p.gov.nonceManager.replace(ctx, proposal.tx, proposal.data, gasPrice)
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/crypto"
+)
+
+// newGovTestTangerine creates a node able to send governance transactions
+// to its transaction pool.
+func newGovTestTangerine(t *testing.T) *Tangerine {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+	dex.config = &Config{}
+	dex.protocolManager = &ProtocolManager{txProps: newTxPropagations(16)}
+	return dex
+}
+
+func headAt(number, round uint64) *types.Block {
+	return types.NewBlockWithHeader(&types.Header{
+		Number: new(big.Int).SetUint64(number),
+		Round:  round,
+	})
+}
+
+// Tests that a CRS proposal not landing in time is sent again, replacing the
+// pending transaction at a higher gas price, until its round begins.
+func TestCRSProposalResubmission(t *testing.T) {
+	dex := newGovTestTangerine(t)
+	defer dex.txPool.Stop()
+	proposer := dex.governance.crsProposer
+
+	proposer.track(1, []byte{1}, nil)
+	proposer.check(headAt(crsResubmitBlocks-1, 0))
+	if pending, _ := dex.txPool.Stats(); pending != 0 || proposer.pending.attempts != 0 {
+		t.Fatalf("proposal resubmitted early: %d pending, %d attempts", pending, proposer.pending.attempts)
+	}
+
+	// A proposal failing to send is sent again.
+	proposer.check(headAt(crsResubmitBlocks, 0))
+	first := proposer.pending.tx
+	if first == nil || proposer.pending.attempts != 1 {
+		t.Fatalf("proposal not resubmitted: %+v", proposer.pending)
+	}
+	if dex.txPool.Get(first.Hash()) == nil {
+		t.Fatalf("resubmitted proposal not in the pool")
+	}
+
+	// A pending proposal is replaced with a higher gas price.
+	proposer.check(headAt(2*crsResubmitBlocks, 0))
+	second := proposer.pending.tx
+	if second.Nonce() != first.Nonce() {
+		t.Errorf("replacement nonce mismatch: have %d, want %d", second.Nonce(), first.Nonce())
+	}
+	want := new(big.Int).Mul(first.GasPrice(), big.NewInt(100+crsGasPriceBump))
+	want.Div(want, big.NewInt(100))
+	if second.GasPrice().Cmp(want) != 0 {
+		t.Errorf("replacement gas price mismatch: have %v, want %v", second.GasPrice(), want)
+	}
+	if dex.txPool.Get(second.Hash()) == nil || dex.txPool.Get(first.Hash()) != nil {
+		t.Errorf("proposal not replaced in the pool")
+	}
+
+	// The proposal is dropped once its round began.
+	proposer.check(headAt(2*crsResubmitBlocks+1, 1))
+	if proposer.pending != nil {
+		t.Errorf("proposal kept past its round")
+	}
+}
+
+// Tests that CRS proposals are no longer tracked once they landed or were
+// resubmitted too many times.
+func TestCRSProposalDropped(t *testing.T) {
+	dex := newGovTestTangerine(t)
+	defer dex.txPool.Stop()
+	proposer := dex.governance.crsProposer
+
+	// The CRS of round 0 is in the genesis state.
+	proposer.track(0, []byte{1}, nil)
+	proposer.check(headAt(1, 0))
+	if proposer.pending != nil {
+		t.Errorf("landed proposal still tracked")
+	}
+
+	proposer.track(1, []byte{1}, nil)
+	proposer.pending.attempts = crsMaxResubmissions
+	proposer.check(headAt(crsResubmitBlocks, 0))
+	if proposer.pending != nil {
+		t.Errorf("proposal still tracked after %d resubmissions", crsMaxResubmissions)
+	}
+	if pending, _ := dex.txPool.Stats(); pending != 0 {
+		t.Errorf("proposal resubmitted past the limit: %d pending", pending)
+	}
+}
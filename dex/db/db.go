@@ -18,6 +18,10 @@
 package db
 
 import (
+	"bytes"
+	"errors"
+	"sync"
+
 	coreCommon "github.com/portto/tangerine-consensus/common"
 	coreDKG "github.com/portto/tangerine-consensus/core/crypto/dkg"
 	coreDb "github.com/portto/tangerine-consensus/core/db"
@@ -26,51 +30,211 @@ import (
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/core/rawdb"
 	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/rlp"
 )
 
+// ErrBlockNotFinalized is returned when storing a block without randomness as
+// a finalized block.
+var ErrBlockNotFinalized = errors.New("block not finalized")
+
 // DB implement dexon-consensus BlockDatabase interface.
 type DB struct {
 	db ethdb.Database
+
+	filterLock sync.RWMutex
+	filter     *blockFilter // Filter of the stored block hashes, nil if disabled
+
+	blockLock sync.Mutex // Lock serializing the checks and writes of blocks
+
+	passphrase string // Passphrase DKG secrets are encrypted with, empty if not
 }
 
 func NewDatabase(db ethdb.Database) *DB {
-	return &DB{db}
+	return &DB{db: db}
+}
+
+// NewDatabaseWithFilter creates a database keeping a bloom filter over the
+// hashes of the stored blocks, which rejects most lookups of missing blocks
+// without hitting db. All blocks must be written through the returned
+// database for the filter to stay complete.
+func NewDatabaseWithFilter(db ethdb.Database) *DB {
+	d := &DB{db: db}
+	d.filterLock.Lock()
+	d.rebuildFilter(0)
+	d.filterLock.Unlock()
+	return d
+}
+
+// rebuildFilter replaces the filter with one sized for the given number of
+// blocks, loading the stored block hashes into it in the background. The
+// caller must hold the filter lock.
+func (d *DB) rebuildFilter(blocks uint64) {
+	filter := newBlockFilter(blocks)
+	d.filter = filter
+
+	go func() {
+		ok := rawdb.IterateCoreBlockHashes(d.db, func(hash common.Hash) {
+			d.filterLock.Lock()
+			filter.add(hash)
+			d.filterLock.Unlock()
+		})
+		d.filterLock.Lock()
+		defer d.filterLock.Unlock()
+		if !ok {
+			log.Warn("Core block filter disabled, database not iterable")
+			if d.filter == filter {
+				d.filter = nil
+			}
+			return
+		}
+		filter.loaded = true
+		log.Debug("Core block filter loaded", "blocks", filter.count, "bits", filter.mask+1)
+	}()
+}
+
+// mayHaveBlock reports whether the block of hash may be stored, and whether
+// the filter was consulted.
+func (d *DB) mayHaveBlock(hash coreCommon.Hash) (maybe bool, filtered bool) {
+	d.filterLock.RLock()
+	defer d.filterLock.RUnlock()
+	if d.filter == nil || !d.filter.loaded {
+		return true, false
+	}
+	if !d.filter.contains(common.Hash(hash)) {
+		blockFilterMissMeter.Mark(1)
+		return false, true
+	}
+	return true, true
+}
+
+// addToFilter adds hash to the filter, growing it if full.
+func (d *DB) addToFilter(hash coreCommon.Hash) {
+	d.filterLock.Lock()
+	defer d.filterLock.Unlock()
+	if d.filter == nil {
+		return
+	}
+	d.filter.add(common.Hash(hash))
+	if d.filter.loaded && d.filter.full() {
+		d.rebuildFilter(2 * d.filter.count)
+	}
 }
 
 func (d *DB) HasBlock(hash coreCommon.Hash) bool {
-	return rawdb.HasCoreBlock(d.db, common.Hash(hash))
+	maybe, filtered := d.mayHaveBlock(hash)
+	if !maybe {
+		return false
+	}
+	has := rawdb.HasCoreBlock(d.db, common.Hash(hash))
+	if !has && filtered {
+		blockFilterFalsePositiveMeter.Mark(1)
+	}
+	return has
 }
 
 func (d *DB) GetBlock(hash coreCommon.Hash) (coreTypes.Block, error) {
+	maybe, filtered := d.mayHaveBlock(hash)
+	if !maybe {
+		return coreTypes.Block{}, coreDb.ErrBlockDoesNotExist
+	}
 	block := rawdb.ReadCoreBlock(d.db, common.Hash(hash))
 	if block == nil {
+		if filtered {
+			blockFilterFalsePositiveMeter.Mark(1)
+		}
 		return coreTypes.Block{}, coreDb.ErrBlockDoesNotExist
 	}
 	return *block, nil
 }
 
+// GetAllBlocks returns an iterator over the stored blocks in hash order. The
+// iterator holds a snapshot of a LevelDB database until all the blocks are
+// iterated.
 func (d *DB) GetAllBlocks() (coreDb.BlockIterator, error) {
-	return nil, coreDb.ErrNotImplemented
+	it := rawdb.NewCoreBlockIterator(d.db)
+	if it == nil {
+		return nil, coreDb.ErrNotImplemented
+	}
+	return &blockIterator{it: it}, nil
 }
 
+// UpdateBlock replaces a stored block, unless that would replace or drop the
+// randomness of a finalized block. Blocks are checked and written under a
+// lock, so concurrent writers like the syncer and the consensus core can not
+// clobber the randomness another one added to a block.
 func (d *DB) UpdateBlock(block coreTypes.Block) error {
-	if !d.HasBlock(block.Hash) {
-		return coreDb.ErrBlockDoesNotExist
+	d.blockLock.Lock()
+	defer d.blockLock.Unlock()
+
+	stored, err := d.GetBlock(block.Hash)
+	if err != nil {
+		return err
+	}
+	if stored.IsFinalized() && !bytes.Equal(stored.Randomness, block.Randomness) {
+		return coreDb.ErrRandomnessConflict
 	}
 	rawdb.WriteCoreBlock(d.db, common.Hash(block.Hash), &block)
 	return nil
 }
 
 func (d *DB) PutBlock(block coreTypes.Block) error {
+	d.blockLock.Lock()
+	defer d.blockLock.Unlock()
+
 	if d.HasBlock(block.Hash) {
 		return coreDb.ErrBlockExists
 	}
 	rawdb.WriteCoreBlock(d.db, common.Hash(block.Hash), &block)
+	d.addToFilter(block.Hash)
+	return nil
+}
+
+// GetFinalizedBlock returns the finalized block at pos, found through the
+// position index among the blocks stored by hash.
+func (d *DB) GetFinalizedBlock(pos coreTypes.Position) (coreTypes.Block, error) {
+	hash := rawdb.ReadCoreFinalizedHash(d.db, pos.Round, pos.Height)
+	if hash == nil {
+		return coreTypes.Block{}, coreDb.ErrBlockDoesNotExist
+	}
+	block, err := d.GetBlock(coreCommon.Hash(*hash))
+	if err != nil {
+		return coreTypes.Block{}, err
+	}
+	// The block is stored with its randomness only once delivered.
+	if !block.IsFinalized() {
+		return coreTypes.Block{}, coreDb.ErrBlockDoesNotExist
+	}
+	return block, nil
+}
+
+// PutFinalizedBlock indexes a finalized block by its position, replacing the
+// block indexed there if any. The block itself is stored by the consensus
+// core when delivered.
+func (d *DB) PutFinalizedBlock(block coreTypes.Block) error {
+	if !block.IsFinalized() {
+		return ErrBlockNotFinalized
+	}
+	rawdb.WriteCoreFinalizedHash(d.db, block.Position.Round, block.Position.Height,
+		common.Hash(block.Hash))
 	return nil
 }
 
 func (d *DB) GetDKGPrivateKey(round, reset uint64) (coreDKG.PrivateKey, error) {
-	key := rawdb.ReadCoreDKGPrivateKey(d.db, round, reset)
+	data := rawdb.ReadCoreDKGPrivateKeyRLP(d.db, round)
+	if len(data) == 0 {
+		return coreDKG.PrivateKey{}, coreDb.ErrDKGPrivateKeyDoesNotExist
+	}
+	data, sealed, err := d.openSecret(data)
+	if err != nil {
+		return coreDKG.PrivateKey{}, err
+	}
+	if !sealed && d.passphrase != "" {
+		if data, err := d.sealSecret(data); err == nil {
+			rawdb.WriteCoreDKGPrivateKeyRLP(d.db, round, data)
+		}
+	}
+	key := rawdb.DecodeCoreDKGPrivateKey(data, round, reset)
 	if key == nil {
 		return coreDKG.PrivateKey{}, coreDb.ErrDKGPrivateKeyDoesNotExist
 	}
@@ -86,7 +250,14 @@ func (d *DB) PutDKGPrivateKey(round, reset uint64, key coreDKG.PrivateKey) error
 		return err
 	}
 
-	return rawdb.WriteCoreDKGPrivateKey(d.db, round, reset, &key)
+	data, err := rawdb.EncodeCoreDKGPrivateKey(round, reset, &key)
+	if err != nil {
+		return err
+	}
+	if data, err = d.sealSecret(data); err != nil {
+		return err
+	}
+	return rawdb.WriteCoreDKGPrivateKeyRLP(d.db, round, data)
 }
 
 func (d *DB) PutCompactionChainTipInfo(hash coreCommon.Hash, height uint64) error {
@@ -97,18 +268,44 @@ func (d *DB) PutCompactionChainTipInfo(hash coreCommon.Hash, height uint64) erro
 	return rawdb.WriteCoreCompactionChainTip(d.db, hash, height)
 }
 
+// ResetCompactionChainTipInfo overwrites the compaction chain tip, even with a
+// lower one, to repair a tip diverged from the chain.
+func (d *DB) ResetCompactionChainTipInfo(hash coreCommon.Hash, height uint64) error {
+	return rawdb.WriteCoreCompactionChainTip(d.db, hash, height)
+}
+
 func (d *DB) GetCompactionChainTipInfo() (hash coreCommon.Hash, height uint64) {
 	return rawdb.ReadCoreCompactionChainTip(d.db)
 }
 
 func (d *DB) PutOrUpdateDKGProtocol(
 	protocol coreDb.DKGProtocolInfo) error {
-	return rawdb.WriteCoreDKGProtocol(d.db, &protocol)
+	data, err := rlp.EncodeToBytes(&protocol)
+	if err != nil {
+		return err
+	}
+	if data, err = d.sealSecret(data); err != nil {
+		return err
+	}
+	return rawdb.WriteCoreDKGProtocolRLP(d.db, data)
 }
 
 func (d *DB) GetDKGProtocol() (
 	protocol coreDb.DKGProtocolInfo, err error) {
-	dkgProtocol := rawdb.ReadCoreDKGProtocol(d.db)
+	data := rawdb.ReadCoreDKGProtocolRLP(d.db)
+	if len(data) == 0 {
+		return coreDb.DKGProtocolInfo{}, coreDb.ErrDKGProtocolDoesNotExist
+	}
+	data, sealed, err := d.openSecret(data)
+	if err != nil {
+		return coreDb.DKGProtocolInfo{}, err
+	}
+	if !sealed && d.passphrase != "" {
+		if data, err := d.sealSecret(data); err == nil {
+			rawdb.WriteCoreDKGProtocolRLP(d.db, data)
+		}
+	}
+	dkgProtocol := rawdb.DecodeCoreDKGProtocol(data)
 	if dkgProtocol == nil {
 		return coreDb.DKGProtocolInfo{}, coreDb.ErrDKGProtocolDoesNotExist
 	}
@@ -116,3 +313,28 @@ func (d *DB) GetDKGProtocol() (
 }
 
 func (d *DB) Close() error { return nil }
+
+// blockIterator implements the BlockIterator interface of the consensus core
+// over the blocks stored in a database.
+type blockIterator struct {
+	it  *rawdb.CoreBlockIterator
+	err error // Error the iteration finished with
+}
+
+func (i *blockIterator) NextBlock() (coreTypes.Block, error) {
+	if i.it == nil {
+		return coreTypes.Block{}, i.err
+	}
+	if i.it.Next() {
+		block, err := i.it.Block()
+		if err == nil {
+			return *block, nil
+		}
+		i.err = err
+	} else if i.err = i.it.Error(); i.err == nil {
+		i.err = coreDb.ErrIterationFinished
+	}
+	i.it.Release()
+	i.it = nil
+	return coreTypes.Block{}, i.err
+}
@@ -0,0 +1,280 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreDKG "github.com/portto/tangerine-consensus/core/crypto/dkg"
+	coreDb "github.com/portto/tangerine-consensus/core/db"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/ethdb"
+)
+
+func testCoreBlockHash(i int) coreCommon.Hash {
+	return coreCommon.Hash(crypto.Keccak256Hash([]byte{byte(i >> 8), byte(i)}))
+}
+
+// waitFilterLoaded waits until the block filter of d is loaded.
+func waitFilterLoaded(t *testing.T, d *DB) {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+		d.filterLock.RLock()
+		loaded := d.filter != nil && d.filter.loaded
+		d.filterLock.RUnlock()
+		if loaded {
+			return
+		}
+	}
+	t.Fatalf("block filter not loaded")
+}
+
+// Tests that the block filter answers for the blocks stored before and after
+// it was created, and grows as blocks are added.
+func TestBlockFilter(t *testing.T) {
+	chainDb := ethdb.NewMemDatabase()
+	for i := 0; i < 10; i++ {
+		hash := testCoreBlockHash(i)
+		rawdb.WriteCoreBlock(chainDb, common.Hash(hash), &coreTypes.Block{Hash: hash})
+	}
+	d := NewDatabaseWithFilter(chainDb)
+	waitFilterLoaded(t, d)
+
+	size := d.filter.mask + 1
+	blocks := int(size/blockFilterBitsPerBlock) + 10
+	for i := 10; i < blocks; i++ {
+		if err := d.PutBlock(coreTypes.Block{Hash: testCoreBlockHash(i)}); err != nil {
+			t.Fatalf("failed to put block %d: %v", i, err)
+		}
+	}
+	waitFilterLoaded(t, d)
+	if d.filter.mask+1 <= size {
+		t.Errorf("block filter not grown: %d bits", d.filter.mask+1)
+	}
+	for i := 0; i < blocks; i++ {
+		if !d.HasBlock(testCoreBlockHash(i)) {
+			t.Fatalf("block %d not found", i)
+		}
+		if _, err := d.GetBlock(testCoreBlockHash(i)); err != nil {
+			t.Fatalf("failed to get block %d: %v", i, err)
+		}
+	}
+	var passed int
+	for i := blocks; i < blocks+1000; i++ {
+		if maybe, _ := d.mayHaveBlock(testCoreBlockHash(i)); maybe {
+			passed++
+		}
+		if d.HasBlock(testCoreBlockHash(i)) {
+			t.Fatalf("missing block %d found", i)
+		}
+	}
+	if passed > 50 {
+		t.Errorf("too many false positives: %d of 1000", passed)
+	}
+}
+
+// Tests that DKG private keys are encrypted once a passphrase is set, plaintext
+// ones being encrypted as they are read.
+func TestDKGPrivateKeyEncryption(t *testing.T) {
+	chainDb := ethdb.NewMemDatabase()
+	plain := NewDatabase(chainDb)
+	key := coreDKG.NewPrivateKey()
+	if err := plain.PutDKGPrivateKey(1, 0, *key); err != nil {
+		t.Fatalf("failed to put key: %v", err)
+	}
+
+	d := NewDatabase(chainDb)
+	d.SetPassphrase("secret")
+	for i := 0; i < 2; i++ {
+		have, err := d.GetDKGPrivateKey(1, 0)
+		if err != nil {
+			t.Fatalf("read %d: failed to get key: %v", i, err)
+		}
+		if !bytes.Equal(have.Bytes(), key.Bytes()) {
+			t.Fatalf("read %d: key mismatch", i)
+		}
+		if data := rawdb.ReadCoreDKGPrivateKeyRLP(chainDb, 1); !bytes.HasPrefix(data, sealedSecretPrefix) {
+			t.Fatalf("read %d: key stored in plaintext", i)
+		}
+	}
+	if err := d.PutDKGPrivateKey(2, 0, *key); err != nil {
+		t.Fatalf("failed to put key: %v", err)
+	}
+	if _, err := d.GetDKGPrivateKey(2, 0); err != nil {
+		t.Fatalf("failed to get key: %v", err)
+	}
+
+	if _, err := plain.GetDKGPrivateKey(2, 0); err != ErrSecretSealed {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrSecretSealed)
+	}
+	wrong := NewDatabase(chainDb)
+	wrong.SetPassphrase("wrong")
+	if _, err := wrong.GetDKGPrivateKey(2, 0); err == nil {
+		t.Errorf("key decrypted with the wrong passphrase")
+	}
+}
+
+// checkAllBlocks checks that iterating d yields the blocks of hashes.
+func checkAllBlocks(t *testing.T, d *DB, hashes map[coreCommon.Hash]bool) {
+	iter, err := d.GetAllBlocks()
+	if err != nil {
+		t.Fatalf("failed to iterate blocks: %v", err)
+	}
+	seen := make(map[coreCommon.Hash]bool)
+	for {
+		block, err := iter.NextBlock()
+		if err == coreDb.ErrIterationFinished {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to get next block: %v", err)
+		}
+		if !hashes[block.Hash] || seen[block.Hash] {
+			t.Fatalf("unexpected block %x", block.Hash)
+		}
+		seen[block.Hash] = true
+	}
+	if len(seen) != len(hashes) {
+		t.Errorf("block count mismatch: have %d, want %d", len(seen), len(hashes))
+	}
+	if _, err := iter.NextBlock(); err != coreDb.ErrIterationFinished {
+		t.Errorf("error mismatch: have %v, want %v", err, coreDb.ErrIterationFinished)
+	}
+}
+
+// Tests that the stored blocks are iterated, skipping the finalized blocks
+// and the other data sharing the key space.
+func TestGetAllBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ldb, err := ethdb.NewLDBDatabase(dir, 16, 16)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	d := NewDatabaseWithFilter(ldb)
+	mem := NewDatabase(ethdb.NewMemDatabase())
+	hashes := make(map[coreCommon.Hash]bool)
+	for i := 0; i < 100; i++ {
+		block := coreTypes.Block{
+			Hash:       testCoreBlockHash(i),
+			Position:   coreTypes.Position{Height: uint64(i)},
+			Randomness: []byte{1},
+		}
+		for _, db := range []*DB{d, mem} {
+			if err := db.PutBlock(block); err != nil {
+				t.Fatalf("failed to put block %d: %v", i, err)
+			}
+			if err := db.PutFinalizedBlock(block); err != nil {
+				t.Fatalf("failed to put finalized block %d: %v", i, err)
+			}
+		}
+		hashes[block.Hash] = true
+	}
+	if err := d.PutCompactionChainTipInfo(testCoreBlockHash(99), 99); err != nil {
+		t.Fatalf("failed to put compaction chain tip: %v", err)
+	}
+	checkAllBlocks(t, mem, hashes)
+	checkAllBlocks(t, d, hashes)
+
+	// The blocks survive reopening the database.
+	ldb.Close()
+	if ldb, err = ethdb.NewLDBDatabase(dir, 16, 16); err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	defer ldb.Close()
+	d = NewDatabaseWithFilter(ldb)
+	checkAllBlocks(t, d, hashes)
+	if hash, height := d.GetCompactionChainTipInfo(); hash != testCoreBlockHash(99) || height != 99 {
+		t.Errorf("compaction chain tip mismatch: have %x %d", hash, height)
+	}
+}
+
+// Tests that updates never replace or drop the randomness of a finalized block,
+// whichever order concurrent writers store a block in.
+func TestUpdateBlockRandomness(t *testing.T) {
+	d := NewDatabase(ethdb.NewMemDatabase())
+	block := coreTypes.Block{Hash: testCoreBlockHash(1)}
+	if err := d.UpdateBlock(block); err != coreDb.ErrBlockDoesNotExist {
+		t.Fatalf("error mismatch: have %v, want %v", err, coreDb.ErrBlockDoesNotExist)
+	}
+	if err := d.PutBlock(block); err != nil {
+		t.Fatalf("failed to put block: %v", err)
+	}
+	finalized := block
+	finalized.Randomness = []byte{1}
+	if err := d.UpdateBlock(finalized); err != nil {
+		t.Fatalf("failed to finalize block: %v", err)
+	}
+	if err := d.UpdateBlock(finalized); err != nil {
+		t.Fatalf("failed to update finalized block: %v", err)
+	}
+	for _, randomness := range [][]byte{nil, {2}} {
+		update := block
+		update.Randomness = randomness
+		if err := d.UpdateBlock(update); err != coreDb.ErrRandomnessConflict {
+			t.Errorf("randomness %x: error mismatch: have %v, want %v", randomness, err, coreDb.ErrRandomnessConflict)
+		}
+	}
+	if stored, err := d.GetBlock(block.Hash); err != nil || !bytes.Equal(stored.Randomness, finalized.Randomness) {
+		t.Fatalf("randomness mismatch: have %x, want %x (err %v)", stored.Randomness, finalized.Randomness, err)
+	}
+
+	// The consensus core puts blocks confirmed by BA while the syncer puts or
+	// updates the finalized ones.
+	for i := 2; i < 100; i++ {
+		block := coreTypes.Block{Hash: testCoreBlockHash(i)}
+		finalized := block
+		finalized.Randomness = []byte{byte(i)}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := d.PutBlock(block); err != nil && err != coreDb.ErrBlockExists {
+				t.Errorf("failed to put block %d: %v", i, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			err := d.PutBlock(finalized)
+			if err == coreDb.ErrBlockExists {
+				err = d.UpdateBlock(finalized)
+			}
+			if err != nil {
+				t.Errorf("failed to put finalized block %d: %v", i, err)
+			}
+		}()
+		wg.Wait()
+		if stored, err := d.GetBlock(block.Hash); err != nil || !stored.IsFinalized() {
+			t.Fatalf("block %d: randomness dropped (err %v)", i, err)
+		}
+	}
+}
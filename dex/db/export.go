@@ -0,0 +1,236 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/rlp"
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	coreUtils "github.com/portto/tangerine-consensus/core/utils"
+)
+
+// Kinds of the entries of an export stream.
+const (
+	exportBlock uint8 = iota
+	exportCompactionChainTip
+	exportDKGProtocol
+	exportFinalizedHash
+)
+
+// exportEntry is an entry of an export stream, holding a value as stored in
+// the database. Encrypted secrets aren't RLP, so values are byte strings.
+type exportEntry struct {
+	Kind uint8
+	Data []byte
+}
+
+// finalizedHashEntry is the data of an exportFinalizedHash entry, indexing
+// a finalized block by its position.
+type finalizedHashEntry struct {
+	Round  uint64
+	Height uint64
+	Hash   coreCommon.Hash
+}
+
+// ExportStats counts the entries handled by an export or import.
+type ExportStats struct {
+	Blocks      int  // Core blocks written to the destination
+	Finalized   int  // Finalized block positions indexed in the destination
+	Skipped     int  // Entries the destination already had or superseded
+	ChainTip    bool // Whether the compaction chain tip was written
+	DKGProtocol bool // Whether the DKG protocol info was written
+}
+
+// Export writes the core blocks, the position index of the finalized ones,
+// the compaction chain tip and the DKG protocol info of d to w as an RLP
+// stream. Values are exported as stored, so DKG protocol info encrypted with
+// the node passphrase stays encrypted.
+func (d *DB) Export(w io.Writer) (ExportStats, error) {
+	var stats ExportStats
+
+	it := rawdb.NewCoreBlockIterator(d.db)
+	if it == nil {
+		return stats, fmt.Errorf("database not iterable")
+	}
+	defer it.Release()
+	for it.Next() {
+		data := rawdb.ReadCoreBlockRLP(d.db, it.Hash())
+		if len(data) == 0 {
+			continue
+		}
+		if err := rlp.Encode(w, &exportEntry{exportBlock, data}); err != nil {
+			return stats, err
+		}
+		stats.Blocks++
+
+		// The position index follows the block it points to.
+		block, err := it.Block()
+		if err != nil {
+			return stats, err
+		}
+		if !block.IsFinalized() {
+			continue
+		}
+		hash := rawdb.ReadCoreFinalizedHash(d.db, block.Position.Round, block.Position.Height)
+		if hash == nil || *hash != it.Hash() {
+			continue
+		}
+		index, err := rlp.EncodeToBytes(&finalizedHashEntry{
+			Round:  block.Position.Round,
+			Height: block.Position.Height,
+			Hash:   block.Hash,
+		})
+		if err != nil {
+			return stats, err
+		}
+		if err := rlp.Encode(w, &exportEntry{exportFinalizedHash, index}); err != nil {
+			return stats, err
+		}
+		stats.Finalized++
+	}
+	if err := it.Error(); err != nil {
+		return stats, err
+	}
+	if data, err := rawdb.ReadCoreCompactionChainTipRLP(d.db); err == nil && len(data) > 0 {
+		if err := rlp.Encode(w, &exportEntry{exportCompactionChainTip, data}); err != nil {
+			return stats, err
+		}
+		stats.ChainTip = true
+	}
+	if data := rawdb.ReadCoreDKGProtocolRLP(d.db); len(data) > 0 {
+		if err := rlp.Encode(w, &exportEntry{exportDKGProtocol, data}); err != nil {
+			return stats, err
+		}
+		stats.DKGProtocol = true
+	}
+	return stats, nil
+}
+
+// Import reads an RLP stream written by Export from r into d. The hash of
+// every block is verified before it is written and blocks already in d are
+// skipped. Finalized block positions, the compaction chain tip and the DKG
+// protocol info are only written if d has none or, for the tip, a lower one,
+// so the node's own consensus state is never rolled back.
+func (d *DB) Import(r io.Reader) (ExportStats, error) {
+	var stats ExportStats
+
+	stream := rlp.NewStream(r, 0)
+	for {
+		var entry exportEntry
+		if err := stream.Decode(&entry); err != nil {
+			if err == io.EOF {
+				return stats, nil
+			}
+			return stats, err
+		}
+		switch entry.Kind {
+		case exportBlock:
+			written, err := d.importBlock(entry.Data)
+			if err != nil {
+				return stats, err
+			}
+			if written {
+				stats.Blocks++
+			} else {
+				stats.Skipped++
+			}
+
+		case exportFinalizedHash:
+			var index finalizedHashEntry
+			if err := rlp.DecodeBytes(entry.Data, &index); err != nil {
+				return stats, fmt.Errorf("invalid finalized block index: %v", err)
+			}
+			block, err := d.GetBlock(index.Hash)
+			if err != nil || !block.IsFinalized() || block.Position.Round != index.Round ||
+				block.Position.Height != index.Height {
+				return stats, fmt.Errorf("finalized block index at %d/%d of unknown block %x",
+					index.Round, index.Height, index.Hash)
+			}
+			if rawdb.ReadCoreFinalizedHash(d.db, index.Round, index.Height) != nil {
+				stats.Skipped++
+				continue
+			}
+			if err := d.PutFinalizedBlock(block); err != nil {
+				return stats, err
+			}
+			stats.Finalized++
+
+		case exportCompactionChainTip:
+			var tip struct {
+				Height uint64
+				Hash   coreCommon.Hash
+			}
+			if err := rlp.DecodeBytes(entry.Data, &tip); err != nil {
+				return stats, fmt.Errorf("invalid compaction chain tip: %v", err)
+			}
+			if _, height := d.GetCompactionChainTipInfo(); tip.Height <= height {
+				stats.Skipped++
+				continue
+			}
+			if err := rawdb.WriteCoreCompactionChainTipRLP(d.db, entry.Data); err != nil {
+				return stats, err
+			}
+			stats.ChainTip = true
+
+		case exportDKGProtocol:
+			// Encrypted protocol info can't be checked without the
+			// passphrase it was sealed with.
+			if !bytes.HasPrefix(entry.Data, sealedSecretPrefix) &&
+				rawdb.DecodeCoreDKGProtocol(entry.Data) == nil {
+				return stats, fmt.Errorf("invalid DKG protocol info")
+			}
+			if len(rawdb.ReadCoreDKGProtocolRLP(d.db)) > 0 {
+				stats.Skipped++
+				continue
+			}
+			if err := rawdb.WriteCoreDKGProtocolRLP(d.db, entry.Data); err != nil {
+				return stats, err
+			}
+			stats.DKGProtocol = true
+
+		default:
+			return stats, fmt.Errorf("unknown export entry kind %d", entry.Kind)
+		}
+	}
+}
+
+// importBlock verifies and writes the RLP encoded core block data, reporting
+// whether it was missing from d.
+func (d *DB) importBlock(data []byte) (bool, error) {
+	var block coreTypes.Block
+	if err := rlp.DecodeBytes(data, &block); err != nil {
+		return false, fmt.Errorf("invalid core block: %v", err)
+	}
+	hash, err := coreUtils.HashBlock(&block)
+	if err != nil {
+		return false, err
+	}
+	if hash != block.Hash {
+		return false, fmt.Errorf("block hash mismatch at %s: have %x, want %x",
+			block.Position, block.Hash, hash)
+	}
+	if d.HasBlock(block.Hash) {
+		return false, nil
+	}
+	return true, d.PutBlock(block)
+}
@@ -0,0 +1,136 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bytes"
+	"testing"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreDb "github.com/portto/tangerine-consensus/core/db"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/rlp"
+)
+
+// Tests that an export is imported into an empty database, once, without
+// rolling back the consensus state of the destination.
+func TestExportImport(t *testing.T) {
+	src := NewDatabase(ethdb.NewMemDatabase())
+	hashes := make(map[coreCommon.Hash]bool)
+	for i := uint64(1); i <= 10; i++ {
+		block := testLegacyBlock(t, i)
+		if i <= 5 {
+			block.Randomness = []byte{1}
+		}
+		if err := src.PutBlock(block); err != nil {
+			t.Fatalf("failed to put block %d: %v", i, err)
+		}
+		if block.IsFinalized() {
+			if err := src.PutFinalizedBlock(block); err != nil {
+				t.Fatalf("failed to put finalized block %d: %v", i, err)
+			}
+		}
+		hashes[block.Hash] = true
+	}
+	if err := src.PutCompactionChainTipInfo(testLegacyBlock(t, 10).Hash, 10); err != nil {
+		t.Fatalf("failed to put compaction chain tip: %v", err)
+	}
+	src.SetPassphrase("secret")
+	if err := src.PutOrUpdateDKGProtocol(coreDb.DKGProtocolInfo{Round: 3}); err != nil {
+		t.Fatalf("failed to put DKG protocol: %v", err)
+	}
+
+	var dump bytes.Buffer
+	stats, err := src.Export(&dump)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if stats.Blocks != 10 || stats.Finalized != 5 || !stats.ChainTip || !stats.DKGProtocol {
+		t.Fatalf("export stats mismatch: %+v", stats)
+	}
+
+	dstDb := ethdb.NewMemDatabase()
+	dst := NewDatabase(dstDb)
+	if stats, err = dst.Import(bytes.NewReader(dump.Bytes())); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if stats.Blocks != 10 || stats.Finalized != 5 || stats.Skipped != 0 ||
+		!stats.ChainTip || !stats.DKGProtocol {
+		t.Fatalf("import stats mismatch: %+v", stats)
+	}
+	checkAllBlocks(t, dst, hashes)
+	for i := uint64(1); i <= 10; i++ {
+		_, err := dst.GetFinalizedBlock(coreTypes.Position{Height: i})
+		if finalized := err == nil; finalized != (i <= 5) {
+			t.Errorf("block %d finalized mismatch: have %t, want %t", i, finalized, i <= 5)
+		}
+	}
+	if hash, height := dst.GetCompactionChainTipInfo(); hash != testLegacyBlock(t, 10).Hash || height != 10 {
+		t.Errorf("compaction chain tip mismatch: have %x at %d", hash, height)
+	}
+	if _, err := dst.GetDKGProtocol(); err != ErrSecretSealed {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrSecretSealed)
+	}
+	dst.SetPassphrase("secret")
+	if protocol, err := dst.GetDKGProtocol(); err != nil || protocol.Round != 3 {
+		t.Errorf("DKG protocol mismatch: have round %d, err %v", protocol.Round, err)
+	}
+
+	// Reimporting skips everything, a higher tip and own protocol are kept.
+	if err := dst.PutCompactionChainTipInfo(testLegacyBlock(t, 11).Hash, 11); err != nil {
+		t.Fatalf("failed to put compaction chain tip: %v", err)
+	}
+	protocol := rawdb.ReadCoreDKGProtocolRLP(dstDb)
+	if stats, err = dst.Import(bytes.NewReader(dump.Bytes())); err != nil {
+		t.Fatalf("reimport failed: %v", err)
+	}
+	if stats.Blocks != 0 || stats.Finalized != 0 || stats.Skipped != 17 ||
+		stats.ChainTip || stats.DKGProtocol {
+		t.Fatalf("reimport stats mismatch: %+v", stats)
+	}
+	if _, height := dst.GetCompactionChainTipInfo(); height != 11 {
+		t.Errorf("compaction chain tip rolled back to %d", height)
+	}
+	if !bytes.Equal(rawdb.ReadCoreDKGProtocolRLP(dstDb), protocol) {
+		t.Errorf("DKG protocol overwritten")
+	}
+}
+
+// Tests that blocks with a bad hash abort the import.
+func TestImportBadBlock(t *testing.T) {
+	block := testLegacyBlock(t, 1)
+	block.Position.Height = 2
+	data, err := rlp.EncodeToBytes(&block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dump bytes.Buffer
+	if err := rlp.Encode(&dump, &exportEntry{exportBlock, data}); err != nil {
+		t.Fatal(err)
+	}
+	d := NewDatabase(ethdb.NewMemDatabase())
+	if _, err := d.Import(&dump); err == nil {
+		t.Fatalf("block with bad hash imported")
+	}
+	if d.HasBlock(block.Hash) {
+		t.Errorf("block with bad hash written")
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"encoding/binary"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/metrics"
+)
+
+const (
+	// blockFilterMinBits is the size of the filter of an empty database.
+	blockFilterMinBits = 1 << 16
+
+	// blockFilterBitsPerBlock is the number of bits kept for each block, for
+	// a false positive rate of about 1% with blockFilterProbes probes.
+	blockFilterBitsPerBlock = 10
+
+	// blockFilterProbes is the number of bits set for each block.
+	blockFilterProbes = 7
+)
+
+var (
+	blockFilterMissMeter          = metrics.NewRegisteredMeter("dex/db/filter/miss", nil)
+	blockFilterFalsePositiveMeter = metrics.NewRegisteredMeter("dex/db/filter/falsepositive", nil)
+)
+
+// blockFilter is a bloom filter over the hashes of the stored core blocks.
+// The hashes are uniformly distributed already, so the probed bits are
+// derived from them directly.
+type blockFilter struct {
+	bits   []uint64
+	mask   uint64 // Number of bits minus one, the number being a power of 2
+	count  uint64 // Number of hashes added
+	loaded bool   // Whether the blocks stored before the filter were added
+}
+
+// newBlockFilter creates a filter sized for the given number of blocks.
+func newBlockFilter(blocks uint64) *blockFilter {
+	size := uint64(blockFilterMinBits)
+	for size < blocks*blockFilterBitsPerBlock {
+		size <<= 1
+	}
+	return &blockFilter{
+		bits: make([]uint64, size/64),
+		mask: size - 1,
+	}
+}
+
+// add adds hash to the filter.
+func (f *blockFilter) add(hash common.Hash) {
+	for i := uint64(0); i < blockFilterProbes; i++ {
+		bit := f.probe(hash, i)
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.count++
+}
+
+// contains reports whether hash may have been added to the filter.
+func (f *blockFilter) contains(hash common.Hash) bool {
+	for i := uint64(0); i < blockFilterProbes; i++ {
+		bit := f.probe(hash, i)
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// full reports whether the filter holds more hashes than it is sized for.
+func (f *blockFilter) full() bool {
+	return f.count*blockFilterBitsPerBlock > f.mask+1
+}
+
+// probe returns the i-th bit probed for hash, by double hashing.
+func (f *blockFilter) probe(hash common.Hash, i uint64) uint64 {
+	h1 := binary.BigEndian.Uint64(hash[0:8])
+	h2 := binary.BigEndian.Uint64(hash[8:16])
+	return (h1 + i*h2) & f.mask
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"fmt"
+	"os"
+
+	coreDb "github.com/portto/tangerine-consensus/core/db"
+	coreUtils "github.com/portto/tangerine-consensus/core/utils"
+)
+
+// MigrateStats counts the blocks handled by a migration.
+type MigrateStats struct {
+	Migrated int // Blocks written to the destination
+	Skipped  int // Blocks the destination already had
+}
+
+// MigrateJSON copies the core blocks persisted as JSON by the consensus
+// core's MemBackedDB at path into d. The hash of every block is recomputed
+// and the migration aborts on the first mismatch, before the block is
+// written. Blocks already in d are skipped, so an interrupted migration can
+// be rerun.
+func MigrateJSON(path string, d *DB) (MigrateStats, error) {
+	var stats MigrateStats
+
+	// MemBackedDB treats a missing file as an empty database.
+	if _, err := os.Stat(path); err != nil {
+		return stats, err
+	}
+	legacy, err := coreDb.NewMemBackedDB(path)
+	if err != nil {
+		return stats, fmt.Errorf("failed to load legacy database: %v", err)
+	}
+	iter, err := legacy.GetAllBlocks()
+	if err != nil {
+		return stats, err
+	}
+	for {
+		block, err := iter.NextBlock()
+		if err == coreDb.ErrIterationFinished {
+			return stats, nil
+		}
+		if err != nil {
+			return stats, err
+		}
+		hash, err := coreUtils.HashBlock(&block)
+		if err != nil {
+			return stats, err
+		}
+		if hash != block.Hash {
+			return stats, fmt.Errorf("block hash mismatch at %s: have %x, want %x",
+				block.Position, block.Hash, hash)
+		}
+		if d.HasBlock(block.Hash) {
+			stats.Skipped++
+			continue
+		}
+		if err := d.PutBlock(block); err != nil {
+			return stats, err
+		}
+		stats.Migrated++
+	}
+}
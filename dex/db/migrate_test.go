@@ -0,0 +1,99 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreDb "github.com/portto/tangerine-consensus/core/db"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	coreUtils "github.com/portto/tangerine-consensus/core/utils"
+
+	"github.com/portto/go-tangerine/ethdb"
+)
+
+// writeLegacyDB persists blocks as JSON the way the consensus core did.
+func writeLegacyDB(t *testing.T, path string, blocks []coreTypes.Block) {
+	legacy, err := coreDb.NewMemBackedDB(path)
+	if err != nil {
+		t.Fatalf("failed to create legacy database: %v", err)
+	}
+	for _, block := range blocks {
+		if err := legacy.PutBlock(block); err != nil {
+			t.Fatalf("failed to put legacy block: %v", err)
+		}
+	}
+	if err := legacy.Close(); err != nil {
+		t.Fatalf("failed to persist legacy database: %v", err)
+	}
+}
+
+func testLegacyBlock(t *testing.T, height uint64) coreTypes.Block {
+	block := coreTypes.Block{
+		Position:  coreTypes.Position{Height: height},
+		Timestamp: time.Unix(1550000000, 0).UTC(),
+	}
+	hash, err := coreUtils.HashBlock(&block)
+	if err != nil {
+		t.Fatalf("failed to hash block: %v", err)
+	}
+	block.Hash = hash
+	return block
+}
+
+// Tests that legacy blocks are migrated once and bad hashes abort.
+func TestMigrateJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coredb-migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "legacy.json")
+
+	writeLegacyDB(t, path, []coreTypes.Block{testLegacyBlock(t, 1), testLegacyBlock(t, 2)})
+	d := NewDatabase(ethdb.NewMemDatabase())
+	stats, err := MigrateJSON(path, d)
+	if err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	if stats.Migrated != 2 || stats.Skipped != 0 {
+		t.Errorf("first migration stats mismatch: %+v", stats)
+	}
+	stats, err = MigrateJSON(path, d)
+	if err != nil {
+		t.Fatalf("rerun failed: %v", err)
+	}
+	if stats.Migrated != 0 || stats.Skipped != 2 {
+		t.Errorf("rerun stats mismatch: %+v", stats)
+	}
+
+	bad := testLegacyBlock(t, 3)
+	bad.Hash = coreCommon.Hash{0x01}
+	writeLegacyDB(t, path, []coreTypes.Block{bad})
+	if _, err := MigrateJSON(path, NewDatabase(ethdb.NewMemDatabase())); err == nil {
+		t.Errorf("block with bad hash migrated")
+	}
+	if _, err := MigrateJSON(filepath.Join(dir, "missing.json"), d); err == nil {
+		t.Errorf("missing legacy database accepted")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/portto/go-tangerine/accounts/keystore"
+)
+
+// sealedSecretPrefix marks the DKG secrets encrypted with the node
+// passphrase. Plaintext secrets are RLP lists, which never start with it.
+var sealedSecretPrefix = []byte("sealed:")
+
+// Scrypt parameters of the secret encryption. The DKG protocol info is
+// rewritten at every DKG step on the consensus path, which rules out the
+// standard keystore cost.
+const (
+	secretScryptN = keystore.LightScryptN
+	secretScryptP = keystore.LightScryptP
+)
+
+// ErrSecretSealed is returned when reading an encrypted DKG secret without
+// the node passphrase.
+var ErrSecretSealed = errors.New("DKG secret encrypted, node passphrase required")
+
+// SetPassphrase makes d encrypt the DKG private keys and protocol info it
+// stores with passphrase, keystore style. Secrets stored in plaintext are
+// encrypted as they are read.
+func (d *DB) SetPassphrase(passphrase string) {
+	d.passphrase = passphrase
+}
+
+// sealSecret encrypts data if a passphrase is set.
+func (d *DB) sealSecret(data []byte) ([]byte, error) {
+	if d.passphrase == "" {
+		return data, nil
+	}
+	sealed, err := keystore.EncryptDataV3(data, []byte(d.passphrase), secretScryptN, secretScryptP)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := json.Marshal(sealed)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, sealedSecretPrefix...), blob...), nil
+}
+
+// openSecret decrypts data if it is encrypted, reporting whether it was.
+func (d *DB) openSecret(data []byte) ([]byte, bool, error) {
+	if !bytes.HasPrefix(data, sealedSecretPrefix) {
+		return data, false, nil
+	}
+	if d.passphrase == "" {
+		return nil, true, ErrSecretSealed
+	}
+	var sealed keystore.CryptoJSON
+	if err := json.Unmarshal(data[len(sealedSecretPrefix):], &sealed); err != nil {
+		return nil, true, err
+	}
+	plain, err := keystore.DecryptDataV3(sealed, d.passphrase)
+	return plain, true, err
+}
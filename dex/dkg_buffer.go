@@ -0,0 +1,94 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync"
+
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+)
+
+// maxBufferedDKGShares is the number of early DKG private shares held until
+// the node enters their round.
+const maxBufferedDKGShares = 1024
+
+// dkgRoundReset identifies a run of the DKG.
+type dkgRoundReset struct {
+	round uint64
+	reset uint64
+}
+
+// dkgShareBuffer holds the DKG private shares received before the node
+// entered the (round, reset) of their DKG, which the consensus core would
+// drop, so they can be replayed once the node gets there.
+type dkgShareBuffer struct {
+	limit int
+
+	lock   sync.Mutex
+	size   int
+	shares map[dkgRoundReset][]coreTypes.Msg
+}
+
+func newDKGShareBuffer(limit int) *dkgShareBuffer {
+	return &dkgShareBuffer{
+		limit:  limit,
+		shares: make(map[dkgRoundReset][]coreTypes.Msg),
+	}
+}
+
+// add buffers the private share received from peer, returning false if the
+// buffer is full.
+func (b *dkgShareBuffer) add(peer string, ps *dkgTypes.PrivateShare) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.size >= b.limit {
+		return false
+	}
+	key := dkgRoundReset{round: ps.Round, reset: ps.Reset}
+	b.shares[key] = append(b.shares[key], coreTypes.Msg{PeerID: peer, Payload: ps})
+	b.size++
+	return true
+}
+
+// pop removes and returns the shares of the DKG runs the node has entered,
+// those of round up to reset or of earlier rounds. The shares of runs already
+// passed are returned as well, the consensus core drops them.
+func (b *dkgShareBuffer) pop(round, reset uint64) []coreTypes.Msg {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var msgs []coreTypes.Msg
+	for key, shares := range b.shares {
+		if key.round > round || (key.round == round && key.reset > reset) {
+			continue
+		}
+		msgs = append(msgs, shares...)
+		b.size -= len(shares)
+		delete(b.shares, key)
+	}
+	return msgs
+}
+
+// len returns the number of buffered shares.
+func (b *dkgShareBuffer) len() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.size
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+
+	"github.com/portto/go-tangerine/p2p"
+)
+
+func TestDKGShareBuffer(t *testing.T) {
+	b := newDKGShareBuffer(4)
+	for _, key := range []dkgRoundReset{{2, 0}, {2, 1}, {3, 0}, {1, 0}} {
+		if !b.add("peer", &dkgTypes.PrivateShare{Round: key.round, Reset: key.reset}) {
+			t.Fatalf("share of round %d reset %d not buffered", key.round, key.reset)
+		}
+	}
+	if b.add("peer", &dkgTypes.PrivateShare{Round: 2}) {
+		t.Errorf("share buffered beyond the limit")
+	}
+
+	// Shares of the runs entered and of the runs passed are popped.
+	if msgs := b.pop(2, 0); len(msgs) != 2 {
+		t.Errorf("popped share count mismatch: got %d, want 2", len(msgs))
+	}
+	if n := b.len(); n != 2 {
+		t.Errorf("buffered share count mismatch: got %d, want 2", n)
+	}
+	msgs := b.pop(2, 1)
+	if len(msgs) != 1 {
+		t.Fatalf("popped share count mismatch: got %d, want 1", len(msgs))
+	}
+	if ps := msgs[0].Payload.(*dkgTypes.PrivateShare); ps.Round != 2 || ps.Reset != 1 {
+		t.Errorf("popped share mismatch: got round %d reset %d, want round 2 reset 1",
+			ps.Round, ps.Reset)
+	}
+	if msgs[0].PeerID != "peer" {
+		t.Errorf("peer mismatch: got %s, want peer", msgs[0].PeerID)
+	}
+	if !b.add("peer", &dkgTypes.PrivateShare{Round: 3}) {
+		t.Errorf("share not buffered after shares were popped")
+	}
+}
+
+func TestBufferEarlyDKGShare(t *testing.T) {
+	crsRound := uint64(1)
+	gov := &testGovernance{}
+	gov.lenCRSFunc = func() uint64 { return crsRound }
+	pm := &ProtocolManager{
+		gov:       gov,
+		dkgShares: newDKGShareBuffer(maxBufferedDKGShares),
+		receiveCh: make(chan coreTypes.Msg, 10),
+	}
+	p := newPeer(dex64, p2p.NewPeerWithEnode(randomV4CompactNode(), "", nil), nil)
+
+	if pm.bufferEarlyDKGShare(p, &dkgTypes.PrivateShare{Round: 1}) {
+		t.Errorf("share of current DKG buffered")
+	}
+	if !pm.bufferEarlyDKGShare(p, &dkgTypes.PrivateShare{Round: 2}) {
+		t.Errorf("share of next round not buffered")
+	}
+	if !pm.bufferEarlyDKGShare(p, &dkgTypes.PrivateShare{Round: 1, Reset: 1}) {
+		t.Errorf("share of next reset not buffered")
+	}
+	if !pm.bufferEarlyDKGShare(p, &dkgTypes.PrivateShare{Round: 3}) {
+		t.Errorf("share too far ahead not held back")
+	}
+	if n := pm.dkgShares.len(); n != 2 {
+		t.Errorf("buffered share count mismatch: got %d, want 2", n)
+	}
+
+	pm.replayDKGShares()
+	if n := len(pm.receiveCh); n != 0 {
+		t.Errorf("shares replayed before entering their DKG: %d", n)
+	}
+	crsRound = 2
+	pm.replayDKGShares()
+	if n := len(pm.receiveCh); n != 2 {
+		t.Errorf("replayed share count mismatch: got %d, want 2", n)
+	}
+	if n := pm.dkgShares.len(); n != 0 {
+		t.Errorf("shares left after replay: %d", n)
+	}
+}
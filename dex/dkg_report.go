@@ -0,0 +1,245 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"time"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreEcdsa "github.com/portto/tangerine-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+	coreUtils "github.com/portto/tangerine-consensus/core/utils"
+
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/core/vm"
+	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/event"
+	"github.com/portto/go-tangerine/log"
+)
+
+const dkgReportChainEventChanSize = 64
+
+// dkgResetReporter watches the chain for DKG resets and stores a report of
+// the evidence left in the governance state by the failed DKG attempt.
+type dkgResetReporter struct {
+	blockchain *core.BlockChain
+	gov        *DexconGovernance
+	db         ethdb.Database
+
+	chainCh  chan core.ChainEvent
+	chainSub event.Subscription
+}
+
+func newDKGResetReporter(blockchain *core.BlockChain, gov *DexconGovernance,
+	db ethdb.Database) *dkgResetReporter {
+	return &dkgResetReporter{
+		blockchain: blockchain,
+		gov:        gov,
+		db:         db,
+	}
+}
+
+func (r *dkgResetReporter) Start() {
+	r.chainCh = make(chan core.ChainEvent, dkgReportChainEventChanSize)
+	r.chainSub = r.blockchain.SubscribeChainEvent(r.chainCh)
+	go r.loop()
+}
+
+func (r *dkgResetReporter) Stop() {
+	r.chainSub.Unsubscribe()
+}
+
+func (r *dkgResetReporter) loop() {
+	for {
+		select {
+		case ev := <-r.chainCh:
+			if !hasResetDKGTx(ev.Block) {
+				continue
+			}
+			report, err := r.generate(ev.Block)
+			if err != nil {
+				log.Error("Failed to generate DKG reset report",
+					"number", ev.Block.NumberU64(), "err", err)
+				continue
+			}
+			if report == nil {
+				continue
+			}
+			rawdb.WriteDKGResetReport(r.db, report)
+			log.Info("DKG reset report generated", "round", report.Round,
+				"reset", report.Reset, "missingMPKs", len(report.MissingMPKs),
+				"complaints", report.Complaints, "disqualified", len(report.Disqualified))
+		case <-r.chainSub.Err():
+			return
+		}
+	}
+}
+
+// hasResetDKGTx returns whether the block contains a resetDKG call.
+func hasResetDKGTx(block *types.Block) bool {
+	selector := vm.GovernanceABI.Name2Method["resetDKG"].Id()
+	for _, tx := range block.Transactions() {
+		if to := tx.To(); to != nil && *to == vm.GovernanceContractAddress &&
+			bytes.HasPrefix(tx.Data(), selector) {
+			return true
+		}
+	}
+	return false
+}
+
+// generate builds the report of the DKG reset included in block, if any. The
+// evidence is read from the state of the parent block since the reset clears
+// the DKG state.
+func (r *dkgResetReporter) generate(block *types.Block) (*types.DKGResetReport, error) {
+	parent := r.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, nil
+	}
+	prevState, err := r.blockchain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+	currState, err := r.blockchain.StateAt(block.Root())
+	if err != nil {
+		return nil, err
+	}
+	var (
+		prev      = &vm.GovernanceState{StateDB: prevState}
+		curr      = &vm.GovernanceState{StateDB: currState}
+		dkgRound  = new(big.Int).SetUint64(block.Round() + 1)
+		prevReset = prev.DKGResetCount(dkgRound).Uint64()
+	)
+	// The resetDKG transaction was reverted.
+	if curr.DKGResetCount(dkgRound).Uint64() == prevReset {
+		return nil, nil
+	}
+
+	configState, err := r.gov.GetConfigState(dkgRound.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	notarySetSize := configState.NotarySetSize().Uint64()
+	threshold := coreUtils.GetDKGThreshold(&coreTypes.Config{
+		NotarySetSize: uint32(notarySetSize)})
+
+	report := &types.DKGResetReport{
+		Round:          dkgRound.Uint64(),
+		Reset:          prevReset,
+		BlockNumber:    block.NumberU64(),
+		BlockHash:      block.Hash(),
+		BlockTime:      block.Time(),
+		GeneratedAt:    time.Now().Unix(),
+		NotarySetSize:  notarySetSize,
+		Threshold:      uint64(threshold),
+		NackComplaints: make(map[string]uint64),
+	}
+
+	// DKG states are only meaningful if someone started the DKG of the round.
+	var (
+		mpks       []*dkgTypes.MasterPublicKey
+		complaints []*dkgTypes.Complaint
+	)
+	if prev.DKGRound().Cmp(dkgRound) == 0 {
+		mpks = prev.DKGMasterPublicKeyItems()
+		complaints = prev.DKGComplaintItems()
+		report.MPKReadys = prev.DKGMPKReadysCount().Uint64()
+		report.Finalizeds = prev.DKGFinalizedsCount().Uint64()
+		report.Successes = prev.DKGSuccessesCount().Uint64()
+	}
+	report.MasterPublicKeys = uint64(len(mpks))
+	report.Complaints = uint64(len(complaints))
+
+	proposed := make(map[coreTypes.NodeID]struct{}, len(mpks))
+	for _, mpk := range mpks {
+		proposed[mpk.ProposerID] = struct{}{}
+	}
+	if prev.CRSRound().Cmp(dkgRound) == 0 {
+		notarySet := newNotarySet(prev.CRS(), configState, int(notarySetSize))
+		for _, id := range sortedNodeIDs(notarySet) {
+			addr := vm.IdToAddress(id)
+			if _, ok := proposed[id]; !ok {
+				report.MissingMPKs = append(report.MissingMPKs, id.Hash.String())
+			}
+			if !prev.DKGMPKReady(addr) {
+				report.MissingMPKReadys = append(report.MissingMPKReadys, id.Hash.String())
+			}
+			if !prev.DKGFinalized(addr) {
+				report.MissingFinalizeds = append(report.MissingFinalizeds, id.Hash.String())
+			}
+			if !prev.DKGSuccess(addr) {
+				report.MissingSuccesses = append(report.MissingSuccesses, id.Hash.String())
+			}
+		}
+	}
+
+	disqualified := make(map[coreTypes.NodeID]struct{})
+	nacks := make(map[coreTypes.NodeID]map[coreTypes.NodeID]struct{})
+	for _, complaint := range complaints {
+		accused := complaint.PrivateShare.ProposerID
+		if !complaint.IsNack() {
+			disqualified[accused] = struct{}{}
+			continue
+		}
+		if _, ok := nacks[accused]; !ok {
+			nacks[accused] = make(map[coreTypes.NodeID]struct{})
+		}
+		nacks[accused][complaint.ProposerID] = struct{}{}
+	}
+	for id, complainers := range nacks {
+		report.NackComplaints[id.Hash.String()] = uint64(len(complainers))
+		if len(complainers) >= threshold {
+			disqualified[id] = struct{}{}
+		}
+	}
+	for _, id := range sortedNodeIDs(disqualified) {
+		report.Disqualified = append(report.Disqualified, id.Hash.String())
+	}
+	return report, nil
+}
+
+// newNotarySet calculates the notary set selected by crs among the qualified
+// nodes of the config state.
+func newNotarySet(crs [32]byte, configState *vm.GovernanceState,
+	size int) map[coreTypes.NodeID]struct{} {
+	ns := coreTypes.NewNodeSet()
+	for _, n := range configState.QualifiedNodes() {
+		pk, err := coreEcdsa.NewPublicKeyFromByteSlice(n.PublicKey)
+		if err != nil {
+			continue
+		}
+		ns.Add(coreTypes.NewNodeID(pk))
+	}
+	return ns.GetSubSet(size, coreTypes.NewNotarySetTarget(coreCommon.Hash(crs)))
+}
+
+func sortedNodeIDs(set map[coreTypes.NodeID]struct{}) []coreTypes.NodeID {
+	ids := make([]coreTypes.NodeID, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i].Hash[:], ids[j].Hash[:]) < 0
+	})
+	return ids
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreEcdsa "github.com/portto/tangerine-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/core/vm"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/rlp"
+)
+
+// Tests that the report of a DKG reset is built from the DKG state left
+// before the reset, and that no report is built if the reset was reverted.
+func TestDKGResetReport(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+	node := coreTypes.NewNodeID(coreEcdsa.NewPublicKeyFromECDSA(&key.PublicKey))
+
+	// No node of the notary set sent its MPK, and a node got nacked by
+	// enough nodes to be disqualified.
+	genesis := dex.blockchain.Genesis()
+	statedb, err := dex.blockchain.StateAt(genesis.Root())
+	if err != nil {
+		t.Fatalf("failed to get genesis state: %v", err)
+	}
+	gs := &vm.GovernanceState{StateDB: statedb}
+	round := big.NewInt(1)
+	gs.SetCRSRound(round)
+	gs.SetCRS(common.Hash{1})
+	gs.SetDKGRound(round)
+	for i := 0; i < 4; i++ {
+		complaint := &dkgTypes.Complaint{
+			ProposerID: coreTypes.NodeID{Hash: coreCommon.Hash{byte(i + 1)}},
+			Round:      1,
+			PrivateShare: dkgTypes.PrivateShare{
+				ProposerID: node,
+				Round:      1,
+			},
+		}
+		data, err := rlp.EncodeToBytes(complaint)
+		if err != nil {
+			t.Fatalf("failed to encode complaint: %v", err)
+		}
+		gs.PushDKGComplaint(data)
+	}
+	commit := func() common.Hash {
+		root, err := statedb.Commit(true)
+		if err != nil {
+			t.Fatalf("failed to commit state: %v", err)
+		}
+		if err := statedb.Database().TrieDB().Commit(root, false); err != nil {
+			t.Fatalf("failed to commit trie: %v", err)
+		}
+		return root
+	}
+	parent := types.NewBlockWithHeader(&types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		Root:       commit(),
+	})
+	rawdb.WriteBlock(dex.chainDb, parent)
+	gs.IncDKGResetCount(round)
+	block := types.NewBlockWithHeader(&types.Header{
+		ParentHash: parent.Hash(),
+		Number:     big.NewInt(2),
+		Root:       commit(),
+	})
+
+	reporter := newDKGResetReporter(dex.blockchain, dex.governance, dex.chainDb)
+	report, err := reporter.generate(block)
+	if err != nil {
+		t.Fatalf("failed to generate report: %v", err)
+	}
+	if report == nil {
+		t.Fatal("no report generated")
+	}
+	if report.Round != 1 || report.Reset != 0 || report.BlockHash != block.Hash() {
+		t.Errorf("report position mismatch: round %d reset %d block %x",
+			report.Round, report.Reset, report.BlockHash)
+	}
+	id := node.Hash.String()
+	if report.NotarySetSize == 0 || uint64(len(report.MissingMPKs)) != report.NotarySetSize {
+		t.Errorf("missing MPK count mismatch: have %d, want %d", len(report.MissingMPKs), report.NotarySetSize)
+	}
+	if report.Complaints != 4 || report.NackComplaints[id] != 4 {
+		t.Errorf("complaints mismatch: have %d, %d against the node",
+			report.Complaints, report.NackComplaints[id])
+	}
+	if len(report.Disqualified) != 1 || report.Disqualified[0] != id {
+		t.Errorf("disqualified mismatch: have %v, want [%s]", report.Disqualified, id)
+	}
+
+	// A block leaving the reset count untouched reverted its reset.
+	reverted := types.NewBlockWithHeader(&types.Header{
+		ParentHash: parent.Hash(),
+		Number:     big.NewInt(2),
+		Root:       parent.Root(),
+	})
+	if report, err := reporter.generate(reverted); err != nil || report != nil {
+		t.Errorf("report generated for a reverted reset: %v, %v", report, err)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	dexCore "github.com/portto/tangerine-consensus/core"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/log"
+)
+
+// checkpointRounds is the number of rounds, up to and including the round of
+// the checkpoint, whose headers are retrieved with the checkpoint. Their
+// governance states are the ones a sync prepares to verify headers.
+const checkpointRounds = 4
+
+var errInvalidCheckpoint = errors.New("retrieved checkpoint is invalid")
+
+// Checkpoint is a finalized block, trusted by the user, that a checkpoint sync
+// bootstraps an empty chain from instead of the genesis.
+type Checkpoint struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// syncCheckpoint bootstraps the empty local chain from the trusted checkpoint
+// retrieved from p, returning the number the sync continues from. The headers
+// from the first block of the round checkpointRounds-1 rounds before the one
+// of the checkpoint up to the checkpoint are retrieved along with the
+// governance states of their rounds. The checkpoint is only accepted if its
+// hash is the trusted one, which the retrieved headers must link up to, and
+// the governance states, including the one of the checkpoint the round
+// heights are read from, are proven by the state roots of their headers. The
+// randomness of the checkpoint is then verified as the threshold signature of
+// the DKG group of its round. Chains too young to skip anything are synced
+// from the genesis instead.
+func (d *Downloader) syncCheckpoint(p *peerConnection, trusted *Checkpoint) (uint64, error) {
+	number := trusted.Number
+	headers, err := d.fetchHeaderBatch(p, number, 1, false)
+	if err != nil {
+		return 0, err
+	}
+	checkpoint := headers[0]
+	if checkpoint.Number.Uint64() != number || checkpoint.Hash() != trusted.Hash {
+		return 0, fmt.Errorf("%v: block %d is %x, want %x", errInvalidCheckpoint, number, checkpoint.Hash(), trusted.Hash)
+	}
+	round := checkpoint.Round
+	if round < checkpointRounds {
+		p.log.Debug("Chain too young for a checkpoint, syncing from genesis", "round", round)
+		return 0, nil
+	}
+
+	govState, err := d.fetchGovState(p, checkpoint.Hash(), checkpoint.Root)
+	if err != nil {
+		return 0, fmt.Errorf("%v: governance state: %v", errInvalidCheckpoint, err)
+	}
+	gov := newGovernance(govState)
+	from := gov.GetRoundHeight(round + 1 - checkpointRounds)
+	if from == 0 || from > number {
+		return 0, fmt.Errorf("%v: round %d starts at %d", errInvalidCheckpoint, round+1-checkpointRounds, from)
+	}
+	p.log.Debug("Retrieving checkpoint headers", "from", from, "number", number, "round", round)
+
+	chain := make([]*types.HeaderWithGovState, 0, number-from+1)
+	for next := from; next <= number; {
+		count := MaxHeaderFetch
+		if left := number - next + 1; left < uint64(count) {
+			count = int(left)
+		}
+		headers, err := d.fetchHeaderBatch(p, next, count, true)
+		if err != nil {
+			return 0, err
+		}
+		for _, header := range headers {
+			if header.Number.Uint64() != next {
+				return 0, errInvalidChain
+			}
+			if len(chain) > 0 && header.ParentHash != chain[len(chain)-1].Hash() {
+				return 0, errInvalidChain
+			}
+			chain = append(chain, header)
+			next++
+		}
+	}
+	if chain[len(chain)-1].Hash() != trusted.Hash {
+		return 0, errInvalidCheckpoint
+	}
+
+	// Every governance state retrieved is written with the headers, so all
+	// of them must be proven, and the ones of the rounds must be there.
+	for _, header := range chain {
+		if header.GovState == nil {
+			continue
+		}
+		if header.GovState.BlockHash != header.Hash() {
+			return 0, fmt.Errorf("%v: governance state of block %d mismatch", errInvalidCheckpoint, header.Number)
+		}
+		if err := verifyGovState(header.Root, header.GovState); err != nil {
+			return 0, fmt.Errorf("%v: governance state of block %d: %v", errInvalidCheckpoint, header.Number, err)
+		}
+	}
+	for r := round + 1 - checkpointRounds; r <= round; r++ {
+		height := gov.GetRoundHeight(r)
+		if height == 0 {
+			// The round of the checkpoint may not be snapshotted yet.
+			continue
+		}
+		if height < from || height > number || chain[height-from].GovState == nil {
+			return 0, fmt.Errorf("%v: no governance state of round %d", errInvalidCheckpoint, r)
+		}
+		gov.StoreState(chain[height-from].GovState)
+	}
+	if err := core.VerifyDexconMeta(checkpoint.Header, dexCore.NewTSigVerifierCache(gov, 5)); err != nil {
+		return 0, fmt.Errorf("%v: %v", errInvalidCheckpoint, err)
+	}
+
+	if err := d.lightchain.InsertCheckpoint(chain); err != nil {
+		return 0, err
+	}
+	d.syncStatsLock.Lock()
+	d.syncStatsChainOrigin = number
+	d.syncStatsLock.Unlock()
+
+	p.log.Info("Synced from checkpoint", "number", number, "hash", checkpoint.Hash(), "round", round)
+	return number, nil
+}
+
+// fetchHeaderBatch retrieves count consecutive headers starting at from from
+// p, along with governance states if withGov is set.
+func (d *Downloader) fetchHeaderBatch(p *peerConnection, from uint64, count int, withGov bool) ([]*types.HeaderWithGovState, error) {
+	go p.peer.RequestHeadersByNumber(from, count, 0, false, withGov)
+
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCancelBlockFetch
+
+		case packet := <-d.headerCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				log.Debug("Received headers from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			headers := packet.(*headerPack).headers
+			if len(headers) == 0 {
+				return nil, errEmptyHeaderSet
+			}
+			if len(headers) > count {
+				p.log.Debug("Too many headers", "headers", len(headers), "requested", count)
+				return nil, errBadPeer
+			}
+			return headers, nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for headers timed out", "elapsed", ttl)
+			return nil, errTimeout
+
+		case <-d.bodyCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
@@ -100,8 +100,10 @@ var (
 )
 
 type Downloader struct {
-	mode SyncMode       // Synchronisation mode defining the strategy used (per sync cycle)
-	mux  *event.TypeMux // Event multiplexer to announce sync operation events
+	mode           SyncMode       // Synchronisation mode defining the strategy used (per sync cycle)
+	checkpointSync bool           // Whether an empty chain is bootstrapped from a checkpoint (fast sync only)
+	checkpoint     *Checkpoint    // Trusted checkpoint an empty chain is bootstrapped from
+	mux            *event.TypeMux // Event multiplexer to announce sync operation events
 
 	genesis uint64   // Genesis block number to limit sync to (e.g. light client CHT)
 	queue   *queue   // Scheduler for selecting the hashes to download
@@ -132,6 +134,9 @@ type Downloader struct {
 	notified        int32
 	committed       int32
 
+	fastSyncProgress     rawdb.FastSyncProgress // Progress of the fast sync, persisted until the pivot is committed
+	fastSyncProgressLock sync.Mutex             // Lock protecting the fast sync progress and the pivot commit
+
 	// Channels
 	headerCh      chan dataPack // [eth/62] Channel receiving inbound block headers
 	govStateCh    chan dataPack
@@ -182,6 +187,10 @@ type LightChain interface {
 	InsertTangerineHeaderChain([]*types.HeaderWithGovState,
 		dexcon.GovernanceStateFetcher, *dexCore.TSigVerifierCache) (int, error)
 
+	// InsertCheckpoint inserts a verified segment of headers into the empty
+	// local chain as its new head.
+	InsertCheckpoint([]*types.HeaderWithGovState) error
+
 	// Rollback removes a few recently added elements from the local chain.
 	Rollback([]common.Hash)
 }
@@ -252,6 +261,12 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockC
 	return dl
 }
 
+// SetCheckpoint sets the trusted checkpoint checkpoint syncs bootstrap an empty
+// chain from. Without one, checkpoint syncs are plain fast syncs.
+func (d *Downloader) SetCheckpoint(checkpoint *Checkpoint) {
+	d.checkpoint = checkpoint
+}
+
 // Progress retrieves the synchronisation boundaries, specifically the origin
 // block where synchronisation started at (may have failed/suspended); the block
 // or header sync is currently at; and the latest known block which the sync targets.
@@ -407,7 +422,12 @@ func (d *Downloader) synchronise(id string, hash common.Hash, number uint64, mod
 
 	defer d.Cancel() // No matter what, we can't leave the cancel channel open
 
-	// Set the requested sync mode, unless it's forbidden
+	// Set the requested sync mode, unless it's forbidden. Checkpoint sync is
+	// a fast sync bootstrapped from a checkpoint.
+	d.checkpointSync = mode == CheckpointSync
+	if d.checkpointSync {
+		mode = FastSync
+	}
 	d.mode = mode
 
 	// Retrieve the origin peer and initiate the downloading process
@@ -458,12 +478,23 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, number ui
 	d.syncStatsLock.Unlock()
 
 	// Ensure our origin point is below any fast sync pivot point
-	pivot := uint64(0)
+	pivot, resume := uint64(0), uint64(0)
 	if d.mode == FastSync {
 		if height <= uint64(fsMinFullBlocks) {
 			origin = 0
 		} else {
 			pivot = height - uint64(fsMinFullBlocks)
+			// Resume an interrupted fast sync with the headers it imported
+			// and, unless it became stale, the pivot whose state it synced.
+			if progress := rawdb.ReadFastSyncProgress(d.stateDB); progress != nil {
+				if progress.Pivot > origin && progress.Pivot <= pivot && pivot <= progress.Pivot+uint64(fsMinFullBlocks) {
+					pivot = progress.Pivot
+				}
+				if resume = progress.Headers; resume > height {
+					resume = height
+				}
+				log.Debug("Resuming fast sync", "pivot", pivot, "headers", resume)
+			}
 			if pivot <= origin {
 				origin = pivot - 1
 			}
@@ -472,6 +503,9 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, number ui
 	d.committed = 1
 	if d.mode == FastSync && pivot != 0 {
 		d.committed = 0
+		d.updateFastSyncProgress(func(progress *rawdb.FastSyncProgress) {
+			progress.Pivot, progress.Headers = pivot, resume
+		})
 	}
 
 	if d.mode == FastSync || d.mode == LightSync {
@@ -481,6 +515,16 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, number ui
 			return err
 		}
 
+		if d.checkpointSync && d.checkpoint != nil && pivot != 0 &&
+			d.lightchain.CurrentHeader().Number.Uint64() == 0 {
+			if d.checkpoint.Number >= pivot {
+				p.log.Warn("Checkpoint beyond the fast sync pivot, syncing from genesis",
+					"checkpoint", d.checkpoint.Number, "pivot", pivot)
+			} else if origin, err = d.syncCheckpoint(p, d.checkpoint); err != nil {
+				return err
+			}
+		}
+
 		originHeader := d.lightchain.GetHeaderByNumber(origin)
 		if originHeader == nil {
 			return fmt.Errorf("origin header not exists, number: %d", origin)
@@ -509,13 +553,13 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, number ui
 	}
 
 	fetchers := []func() error{
-		func() error { return d.fetchHeaders(p, origin+1, pivot) }, // Headers are always retrieved
-		func() error { return d.fetchBodies(origin + 1) },          // Bodies are retrieved during normal and fast sync
-		func() error { return d.fetchReceipts(origin + 1) },        // Receipts are retrieved during fast sync
+		func() error { return d.fetchHeaders(p, origin+1, pivot, resume) }, // Headers are always retrieved
+		func() error { return d.fetchBodies(origin + 1) },                  // Bodies are retrieved during normal and fast sync
+		func() error { return d.fetchReceipts(origin + 1) },                // Receipts are retrieved during fast sync
 		func() error { return d.processHeaders(origin+1, pivot, number) },
 	}
 	if d.mode == FastSync {
-		fetchers = append(fetchers, func() error { return d.processFastSyncContent(latest) })
+		fetchers = append(fetchers, func() error { return d.processFastSyncContent(latest, pivot) })
 	} else if d.mode == FullSync {
 		fetchers = append(fetchers, d.processFullSyncContent)
 	}
@@ -648,42 +692,10 @@ func (d *Downloader) fetchGovState(p *peerConnection,
 				break
 			}
 
-			// TODO(sonic): refactor this.
 			govState := packet.(*govStatePack).govState
-
-			// reconstruct the gov state
-			db := ethdb.NewMemDatabase()
-			for _, value := range govState.Proof {
-				db.Put(crypto.Keccak256(value), value)
-			}
-
-			// proof and state should split
-			// check the state object of governance contract
-			key := crypto.Keccak256(vm.GovernanceContractAddress.Bytes())
-			_, _, err := trie.VerifyProof(root, key, db)
-			if err != nil {
+			if err := verifyGovState(root, govState); err != nil {
 				return nil, err
 			}
-
-			triedb := trie.NewDatabase(db)
-			t, err := trie.New(common.Hash{}, triedb)
-			for _, entry := range govState.Storage {
-				t.TryUpdate(entry[0], entry[1])
-			}
-			err = triedb.Commit(t.Hash(), false)
-			if err != nil {
-				return nil, err
-			}
-
-			statedb, err := state.New(root, state.NewDatabase(db))
-			if err != nil {
-				return nil, err
-			}
-
-			storageTrie := statedb.StorageTrie(vm.GovernanceContractAddress)
-			if storageTrie == nil {
-				return nil, fmt.Errorf("storage not match")
-			}
 			return govState, nil
 		case <-timeout:
 			p.log.Debug("Waiting for head header timed out", "elapsed", ttl)
@@ -695,6 +707,45 @@ func (d *Downloader) fetchGovState(p *peerConnection,
 	}
 }
 
+// verifyGovState checks the governance contract storage of govState against
+// the state root of its block.
+func verifyGovState(root common.Hash, govState *types.GovState) error {
+	// reconstruct the gov state
+	db := ethdb.NewMemDatabase()
+	for _, value := range govState.Proof {
+		db.Put(crypto.Keccak256(value), value)
+	}
+
+	// proof and state should split
+	// check the state object of governance contract
+	key := crypto.Keccak256(vm.GovernanceContractAddress.Bytes())
+	_, _, err := trie.VerifyProof(root, key, db)
+	if err != nil {
+		return err
+	}
+
+	triedb := trie.NewDatabase(db)
+	t, err := trie.New(common.Hash{}, triedb)
+	for _, entry := range govState.Storage {
+		t.TryUpdate(entry[0], entry[1])
+	}
+	err = triedb.Commit(t.Hash(), false)
+	if err != nil {
+		return err
+	}
+
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		return err
+	}
+
+	storageTrie := statedb.StorageTrie(vm.GovernanceContractAddress)
+	if storageTrie == nil {
+		return fmt.Errorf("storage not match")
+	}
+	return nil
+}
+
 // calculateRequestSpan calculates what headers to request from a peer when trying to determine the
 // common ancestor.
 // It returns parameters to be used for peer.RequestHeadersByNumber:
@@ -957,11 +1008,19 @@ func (d *Downloader) findAncestor(p *peerConnection, remoteHeader *types.Header)
 // syncing with, and fill in the missing headers using anyone else. Headers from
 // other peers are only accepted if they map cleanly to the skeleton. If no one
 // can fill in the skeleton - not even the origin peer - it's assumed invalid and
-// the origin is dropped.
-func (d *Downloader) fetchHeaders(p *peerConnection, from uint64, pivot uint64) error {
+// the origin is dropped. The headers up to resume, imported by an interrupted
+// fast sync, are replayed from the local chain instead if the peer has them.
+func (d *Downloader) fetchHeaders(p *peerConnection, from uint64, pivot uint64, resume uint64) error {
 	p.log.Debug("Directing header downloads", "origin", from)
 	defer p.log.Debug("Header download terminated")
 
+	if resume >= from {
+		var err error
+		if from, err = d.replayHeaders(p, from, resume); err != nil {
+			return err
+		}
+	}
+
 	// Create a timeout timer, and the associated header fetcher
 	skeleton := true            // Skeleton assembly phase or finishing up
 	request := time.Now()       // time of the last skeleton fetch request
@@ -1385,6 +1444,60 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 	}
 }
 
+// replayHeaders feeds the local headers from from to to to the header
+// processor, provided that the header at to is the one of p, which implies
+// all of them are. It returns the number to continue retrieving headers from.
+func (d *Downloader) replayHeaders(p *peerConnection, from, to uint64) (uint64, error) {
+	local := d.lightchain.GetHeaderByNumber(to)
+	if local == nil {
+		return from, nil
+	}
+	headers, err := d.fetchHeaderBatch(p, to, 1, false)
+	if err != nil {
+		return from, err
+	}
+	if headers[0].Hash() != local.Hash() {
+		p.log.Debug("Imported headers not on the chain of peer", "number", to)
+		return from, nil
+	}
+	p.log.Debug("Replaying imported headers", "from", from, "to", to)
+
+	parent := d.lightchain.GetHeaderByNumber(from - 1)
+	if parent == nil {
+		return from, nil
+	}
+	for from <= to {
+		batch := make([]*types.HeaderWithGovState, 0, MaxHeaderFetch)
+		for ; from <= to && len(batch) < MaxHeaderFetch; from++ {
+			header := d.lightchain.GetHeaderByNumber(from)
+			if header == nil || header.ParentHash != parent.Hash() {
+				to = from - 1
+				break
+			}
+			// The governance state of a round is carried by its first header.
+			var govState *types.GovState
+			if header.Round != parent.Round {
+				if govState, err = d.lightchain.GetGovStateByNumber(from); err != nil {
+					p.log.Debug("Governance state of imported header missing", "number", from, "err", err)
+					to = from - 1
+					break
+				}
+			}
+			batch = append(batch, &types.HeaderWithGovState{Header: header, GovState: govState})
+			parent = header
+		}
+		if len(batch) == 0 {
+			break
+		}
+		select {
+		case d.headerProcCh <- batch:
+		case <-d.cancelCh:
+			return from, errCancelHeaderFetch
+		}
+	}
+	return from, nil
+}
+
 // processHeaders takes batches of retrieved headers from an input channel and
 // keeps processing and scheduling them into the header chain and downloader's
 // queue until the stream ends or a failure occurs.
@@ -1498,6 +1611,14 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, number uint64)
 
 					for _, header := range chunk {
 						if header.GovState != nil {
+							// Light clients never download the state, prove the
+							// governance state against the header before trusting it.
+							if d.mode == LightSync {
+								if err := verifyGovState(header.Root, header.GovState); err != nil {
+									log.Debug("Invalid gov state", "number", header.Number, "hash", header.Hash(), "err", err)
+									return errInvalidChain
+								}
+							}
 							log.Debug("Got gov state, store it", "round", header.Round, "number", header.Number.Uint64())
 							d.gov.StoreState(header.GovState)
 						}
@@ -1518,6 +1639,12 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, number uint64)
 					if len(rollback) > fsHeaderSafetyNet {
 						rollback = append(rollback[:0], rollback[len(rollback)-fsHeaderSafetyNet:]...)
 					}
+					if d.mode == FastSync {
+						last := chunk[len(chunk)-1].Number.Uint64()
+						d.updateFastSyncProgress(func(progress *rawdb.FastSyncProgress) {
+							progress.Headers = last
+						})
+					}
 
 				}
 				// Unless we're doing light chains, schedule the headers for associated content retrieval
@@ -1611,7 +1738,7 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 
 // processFastSyncContent takes fetch results from the queue and writes them to the
 // database. It also controls the synchronisation of state nodes of the pivot block.
-func (d *Downloader) processFastSyncContent(latest *types.Header) error {
+func (d *Downloader) processFastSyncContent(latest *types.Header, pivot uint64) error {
 	// Start syncing state of the reported head block. This should get us most of
 	// the state of the pivot block.
 	stateSync := d.syncState(latest.Root)
@@ -1621,12 +1748,9 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 			d.queue.Close() // wake up WaitResults
 		}
 	}()
-	// Figure out the ideal pivot block. Note, that this goalpost may move if the
-	// sync takes long enough for the chain head to move significantly.
-	pivot := uint64(0)
-	if height := latest.Number.Uint64(); height > uint64(fsMinFullBlocks) {
-		pivot = height - uint64(fsMinFullBlocks)
-	}
+	// Note, that the pivot block may move if the sync takes long enough for the
+	// chain head to move significantly.
+	//
 	// To cater for moving pivot points, track the pivot block and subsequently
 	// accumulated download results separately.
 	var (
@@ -1661,6 +1785,9 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 			if height := latest.Number.Uint64(); height > pivot+2*uint64(fsMinFullBlocks) {
 				log.Warn("Pivot became stale, moving", "old", pivot, "new", height-uint64(fsMinFullBlocks))
 				pivot = height - uint64(fsMinFullBlocks)
+				d.updateFastSyncProgress(func(progress *rawdb.FastSyncProgress) {
+					progress.Pivot = pivot
+				})
 			}
 		}
 		P, beforeP, afterP := splitAroundPivot(pivot, results)
@@ -1761,10 +1888,26 @@ func (d *Downloader) commitPivotBlock(result *fetchResult) error {
 	if err := d.blockchain.FastSyncCommitHead(block.Hash()); err != nil {
 		return err
 	}
+	d.fastSyncProgressLock.Lock()
 	atomic.StoreInt32(&d.committed, 1)
+	rawdb.DeleteFastSyncProgress(d.stateDB)
+	d.fastSyncProgressLock.Unlock()
 	return nil
 }
 
+// updateFastSyncProgress applies update to the progress of the fast sync and
+// persists it, unless the pivot is already committed.
+func (d *Downloader) updateFastSyncProgress(update func(progress *rawdb.FastSyncProgress)) {
+	d.fastSyncProgressLock.Lock()
+	defer d.fastSyncProgressLock.Unlock()
+
+	if atomic.LoadInt32(&d.committed) == 1 {
+		return
+	}
+	update(&d.fastSyncProgress)
+	rawdb.WriteFastSyncProgress(d.stateDB, &d.fastSyncProgress)
+}
+
 // DeliverHeaders injects a new batch of block headers received from a remote
 // node into the download schedule.
 func (d *Downloader) DeliverHeaders(id string, headers []*types.HeaderWithGovState) (err error) {
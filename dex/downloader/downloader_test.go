@@ -28,6 +28,8 @@ import (
 	ethereum "github.com/portto/go-tangerine"
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/consensus/dexcon"
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/rawdb"
 	"github.com/portto/go-tangerine/core/state"
 	"github.com/portto/go-tangerine/core/types"
 	"github.com/portto/go-tangerine/core/vm"
@@ -59,6 +61,8 @@ type downloadTester struct {
 	ownHeaders  map[common.Hash]*types.Header  // Headers belonging to the tester
 	ownBlocks   map[common.Hash]*types.Block   // Blocks belonging to the tester
 	ownReceipts map[common.Hash]types.Receipts // Receipts belonging to the tester
+	checkpoint  common.Hash                    // Checkpoint the tester was bootstrapped from
+	pivot       common.Hash                    // Pivot block committed by a fast sync
 
 	lock sync.RWMutex
 }
@@ -138,6 +142,10 @@ func (dl *downloadTester) GetHeaderByHash(hash common.Hash) *types.Header {
 func (dl *downloadTester) GetHeaderByNumber(number uint64) *types.Header {
 	dl.lock.RLock()
 	defer dl.lock.RUnlock()
+
+	if number >= uint64(len(dl.ownHashes)) {
+		return nil
+	}
 	return dl.ownHeaders[dl.ownHashes[number]]
 }
 
@@ -207,6 +215,9 @@ func (dl *downloadTester) FastSyncCommitHead(hash common.Hash) error {
 	// For now only check that the state trie is correct
 	if block := dl.GetBlockByHash(hash); block != nil {
 		_, err := trie.NewSecure(block.Root(), trie.NewDatabase(dl.stateDb), 0)
+		if err == nil {
+			dl.pivot = hash
+		}
 		return err
 	}
 	return fmt.Errorf("non existent block: %x", hash[:4])
@@ -264,6 +275,30 @@ func (dl *downloadTester) InsertTangerineHeaderChain(headers []*types.HeaderWith
 	return len(headers), nil
 }
 
+// InsertCheckpoint injects a segment of headers into the empty simulated chain.
+func (dl *downloadTester) InsertCheckpoint(headers []*types.HeaderWithGovState) error {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if len(dl.ownHashes) != 1 {
+		return errors.New("chain not empty")
+	}
+	for i := 1; i < len(headers); i++ {
+		if headers[i].ParentHash != headers[i-1].Hash() {
+			return errors.New("unknown parent")
+		}
+	}
+	for uint64(len(dl.ownHashes)) < headers[0].Number.Uint64() {
+		dl.ownHashes = append(dl.ownHashes, common.Hash{})
+	}
+	for _, header := range headers {
+		dl.ownHashes = append(dl.ownHashes, header.Hash())
+		dl.ownHeaders[header.Hash()] = header.Header
+	}
+	dl.checkpoint = headers[len(headers)-1].Hash()
+	return nil
+}
+
 // InsertTangerineChain injects a new batch of blocks into the simulated chain.
 func (dl *downloadTester) InsertTangerineChain(blocks types.Blocks) (i int, err error) {
 	dl.lock.Lock()
@@ -295,7 +330,7 @@ func (dl *downloadTester) InsertReceiptChain(blocks types.Blocks, receipts []typ
 		if _, ok := dl.ownHeaders[blocks[i].Hash()]; !ok {
 			return i, errors.New("unknown owner")
 		}
-		if _, ok := dl.ownBlocks[blocks[i].ParentHash()]; !ok {
+		if _, ok := dl.ownBlocks[blocks[i].ParentHash()]; !ok && blocks[i].ParentHash() != dl.checkpoint {
 			return i, errors.New("unknown parent")
 		}
 		dl.ownBlocks[blocks[i].Hash()] = blocks[i]
@@ -324,7 +359,7 @@ func (dl *downloadTester) newPeer(id string, version int, chain *testChain) erro
 	dl.lock.Lock()
 	defer dl.lock.Unlock()
 
-	peer := &downloadTesterPeer{dl: dl, id: id, chain: chain}
+	peer := &downloadTesterPeer{dl: dl, id: id, chain: chain, served: make(map[uint64]int)}
 	dl.peers[id] = peer
 	return dl.downloader.RegisterPeer(id, version, peer)
 }
@@ -344,6 +379,7 @@ type downloadTesterPeer struct {
 	lock          sync.RWMutex
 	chain         *testChain
 	missingStates map[common.Hash]bool // State entries that fast sync should not return
+	served        map[uint64]int       // Number of times each header was served for syncing
 }
 
 // Head constructs a function to retrieve a peer's current head hash
@@ -363,6 +399,9 @@ func (dlp *downloadTesterPeer) RequestHeadersByHash(origin common.Hash, amount i
 	}
 
 	result := dlp.chain.headersByHash(origin, amount, skip)
+	if withGov {
+		dlp.chain.attachGovStates(result)
+	}
 	go dlp.dl.downloader.DeliverHeaders(dlp.id, result)
 	return nil
 }
@@ -376,6 +415,15 @@ func (dlp *downloadTesterPeer) RequestHeadersByNumber(origin uint64, amount int,
 	}
 
 	result := dlp.chain.headersByNumber(origin, amount, skip)
+	if withGov {
+		dlp.chain.attachGovStates(result)
+
+		dlp.lock.Lock()
+		for _, header := range result {
+			dlp.served[header.Number.Uint64()]++
+		}
+		dlp.lock.Unlock()
+	}
 	go dlp.dl.downloader.DeliverHeaders(dlp.id, result)
 	return nil
 }
@@ -1453,3 +1501,173 @@ func TestRemoteHeaderRequestSpan(t *testing.T) {
 		}
 	}
 }
+
+// Tests that checkpoint sync falls back to a fast sync from the genesis on
+// chains too young to skip any rounds.
+func TestCheckpointSyncYoungChain(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(300)
+	tester.newPeer("peer", 64, chain)
+	tester.downloader.SetCheckpoint(&Checkpoint{Number: 200, Hash: chain.chain[200]})
+
+	if err := tester.sync("peer", 0, CheckpointSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	if tester.checkpoint != (common.Hash{}) {
+		t.Fatalf("synchronised from checkpoint %x", tester.checkpoint)
+	}
+	assertOwnChain(t, tester, chain.len())
+}
+
+// Tests that checkpoint sync without a trusted checkpoint is a fast sync from
+// the genesis.
+func TestCheckpointSyncNoCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheItems - 15)
+	tester.newPeer("peer", 64, chain)
+
+	if err := tester.sync("peer", 0, CheckpointSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	if tester.checkpoint != (common.Hash{}) {
+		t.Fatalf("synchronised from checkpoint %x", tester.checkpoint)
+	}
+	assertOwnChain(t, tester, chain.len())
+}
+
+// Tests that a checkpoint whose hash isn't the trusted one is rejected before
+// anything is written to the local chain, whatever the peer proves with it.
+func TestCheckpointSyncUntrusted(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheItems - 15)
+	tester.newPeer("peer", 64, chain)
+	number := uint64(chain.len() - 1 - fsMinFullBlocks - 1)
+	tester.downloader.SetCheckpoint(&Checkpoint{Number: number, Hash: chain.chain[number-1]})
+
+	if err := tester.sync("peer", 0, CheckpointSync); err == nil || !strings.Contains(err.Error(), errInvalidCheckpoint.Error()) {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInvalidCheckpoint)
+	}
+	if tester.checkpoint != (common.Hash{}) {
+		t.Fatalf("synchronised from checkpoint %x", tester.checkpoint)
+	}
+	assertOwnChain(t, tester, 1)
+}
+
+// Tests that a trusted checkpoint whose randomness can't be verified is
+// rejected before anything is written to the local chain.
+func TestCheckpointSyncUnverified(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	// The test chain only runs the DKG of round 1, so later checkpoints
+	// can't be verified.
+	chain := testChainBase.shorten(blockCacheItems - 15)
+	tester.newPeer("peer", 64, chain)
+	number := uint64(chain.len() - 1 - fsMinFullBlocks - 1)
+	tester.downloader.SetCheckpoint(&Checkpoint{Number: number, Hash: chain.chain[number]})
+
+	if err := tester.sync("peer", 0, CheckpointSync); err == nil || !strings.Contains(err.Error(), errInvalidCheckpoint.Error()) {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInvalidCheckpoint)
+	}
+	if tester.checkpoint != (common.Hash{}) {
+		t.Fatalf("synchronised from checkpoint %x", tester.checkpoint)
+	}
+	assertOwnChain(t, tester, 1)
+}
+
+// Tests that a checkpoint is only accepted with the threshold signature of its
+// round as randomness.
+func TestVerifyCheckpoint(t *testing.T) {
+	header := testChainBase.headerm[testChainBase.chain[150]]
+	gov := newGovernance(testChainBase.govStateByHash(testChainBase.headBlock().Hash()))
+	for r := uint64(0); r <= header.Round; r++ {
+		gov.StoreState(testChainBase.govStateByHash(testChainBase.chain[gov.GetRoundHeight(r)]))
+	}
+
+	if err := core.VerifyDexconMeta(header, dexCore.NewTSigVerifierCache(gov, 5)); err != nil {
+		t.Fatalf("failed to verify checkpoint: %v", err)
+	}
+
+	forged := types.CopyHeader(header)
+	block, err := forged.CoreBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged.Randomness = testNodes.TSig(header.Round, common.Hash{})
+	block.Randomness = forged.Randomness
+	if forged.DexconMeta, err = types.EncodeDexconMeta(block, types.IsCompactDexconMeta(header.DexconMeta)); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.VerifyDexconMeta(forged, dexCore.NewTSigVerifierCache(gov, 5)); err == nil {
+		t.Fatalf("checkpoint with forged randomness verified")
+	}
+}
+
+// Tests that an interrupted fast sync resumes with the pivot whose state it
+// synced and the headers it imported, which aren't retrieved again.
+func TestFastSyncResume(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheItems - 15)
+	tester.newPeer("peer", 64, chain)
+
+	imported := uint64(chain.len() / 2)
+	if _, err := tester.InsertTangerineHeaderChain(chain.headersByNumber(1, int(imported), 0), nil, nil); err != nil {
+		t.Fatalf("failed to import headers: %v", err)
+	}
+	pivot := uint64(chain.len() - 1 - fsMinFullBlocks - 10)
+	rawdb.WriteFastSyncProgress(tester.stateDb, &rawdb.FastSyncProgress{Pivot: pivot, Headers: imported})
+
+	if err := tester.sync("peer", 0, FastSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, chain.len())
+	if tester.pivot != chain.chain[pivot] {
+		t.Errorf("pivot mismatch: have %x, want %x", tester.pivot, chain.chain[pivot])
+	}
+	for number, count := range tester.peers["peer"].served {
+		if number <= imported {
+			t.Errorf("imported header %d retrieved %d times", number, count)
+		}
+	}
+	if progress := rawdb.ReadFastSyncProgress(tester.stateDb); progress != nil {
+		t.Errorf("progress of completed sync left: %v", progress)
+	}
+}
+
+// Tests that a stale pivot of an interrupted fast sync is moved.
+func TestFastSyncResumeStalePivot(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheItems - 15)
+	tester.newPeer("peer", 64, chain)
+
+	rawdb.WriteFastSyncProgress(tester.stateDb, &rawdb.FastSyncProgress{Pivot: 1})
+	if err := tester.sync("peer", 0, FastSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, chain.len())
+	if pivot := uint64(chain.len()-1-fsMinFullBlocks); tester.pivot != chain.chain[pivot] {
+		t.Errorf("pivot mismatch: have %x, want %x", tester.pivot, chain.chain[pivot])
+	}
+}
@@ -171,7 +171,7 @@ func (tc *testChain) generate(n int, seed byte, parent *types.Block, nodes *dexc
 		block.SetCoinbase(common.Address{seed})
 		block.SetPosition(coreTypes.Position{
 			Round:  round,
-			Height: uint64(i),
+			Height: block.Number().Uint64(),
 		})
 		half := roundInterval / 2
 		switch i % roundInterval {
@@ -283,6 +283,20 @@ func (tc *testChain) govStateByHash(hash common.Hash) *types.GovState {
 	return govState
 }
 
+// attachGovStates attaches the governance states of the rounds to the first
+// headers of the rounds, like the protocol manager does.
+func (tc *testChain) attachGovStates(headers []*types.HeaderWithGovState) {
+	for _, header := range headers {
+		if header.Round == 0 {
+			continue
+		}
+		parent := tc.headerm[header.ParentHash]
+		if parent != nil && parent.Round != header.Round {
+			header.GovState = tc.govStateByHash(header.Hash())
+		}
+	}
+}
+
 // receipts returns the receipts of the given block hashes.
 func (tc *testChain) receipts(hashes []common.Hash) [][]*types.Receipt {
 	results := make([][]*types.Receipt, 0, len(hashes))
@@ -343,6 +357,14 @@ func (g *govStateFetcher) GetConfigState(round uint64) (*vm.GovernanceState, err
 	return nil, nil
 }
 
+func (g *govStateFetcher) GetConfigSnapshot(round uint64) (*vm.ConfigSnapshot, error) {
+	s, err := g.GetConfigState(round)
+	if s == nil || err != nil {
+		return nil, err
+	}
+	return vm.NewConfigSnapshot(s, round, 0), nil
+}
+
 func (g *govStateFetcher) DKGSetNodeKeyAddresses(round uint64) (map[common.Address]struct{}, error) {
 	return make(map[common.Address]struct{}), nil
 }
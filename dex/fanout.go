@@ -0,0 +1,160 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Gossip fanout strategies, picking the peers a message is relayed to among
+// the notary peers of its round and the other peers.
+const (
+	// FanoutAll relays to every peer.
+	FanoutAll = "all"
+
+	// FanoutNotary relays to the notary peers only.
+	FanoutNotary = "notary"
+
+	// FanoutSqrt relays to the square root of the peers, notary peers first.
+	FanoutSqrt = "sqrt"
+
+	// FanoutNotarySqrt relays to every notary peer and to the square root of
+	// the other peers, sampled at random.
+	FanoutNotarySqrt = "notary+sqrt"
+
+	// FanoutRandom followed by a count, as in "random:8", relays to that many
+	// peers sampled at random.
+	FanoutRandom = "random:"
+
+	// FanoutNotaryRandom followed by a count, as in "notary+random:8", relays
+	// to every notary peer and to that many other peers sampled at random.
+	FanoutNotaryRandom = "notary+random:"
+)
+
+// FanoutConfig configures the number of peers blocks and consensus messages
+// are relayed to, each being one of the fanout strategies. Large networks
+// may trade latency for bandwidth with them.
+type FanoutConfig struct {
+	Blocks     string // Blocks propagated in full, announcements reaching every peer
+	CoreBlocks string // Core blocks proposed by the node
+	Votes      string // Votes cast by the node
+}
+
+// fanout is a parsed fanout strategy.
+type fanout struct {
+	notaries bool // Relay to every notary peer, others being counted apart
+	all      bool // Relay to every peer
+	sqrt     bool // Relay to the square root of the peers
+	count    int  // Relay to count peers sampled at random, if positive
+}
+
+// parseFanout parses a fanout strategy.
+func parseFanout(strategy string) (*fanout, error) {
+	f := &fanout{}
+	spec := strategy
+	if strings.HasPrefix(spec, FanoutNotary) {
+		f.notaries = true
+		spec = strings.TrimPrefix(spec, FanoutNotary)
+		if spec == "" {
+			return f, nil
+		}
+		if !strings.HasPrefix(spec, "+") {
+			return nil, fmt.Errorf("invalid fanout %q", strategy)
+		}
+		spec = spec[1:]
+	}
+	switch {
+	case spec == FanoutAll && !f.notaries:
+		f.all = true
+	case spec == FanoutSqrt:
+		f.sqrt = true
+	case strings.HasPrefix(spec, FanoutRandom):
+		count, err := strconv.Atoi(strings.TrimPrefix(spec, FanoutRandom))
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid fanout %q: count must be positive", strategy)
+		}
+		f.count = count
+	default:
+		return nil, fmt.Errorf("invalid fanout %q", strategy)
+	}
+	return f, nil
+}
+
+// notaryOnly returns whether the strategy relays to the notary peers only.
+func (f *fanout) notaryOnly() bool {
+	return f.notaries && !f.sqrt && f.count == 0
+}
+
+// pick returns the peers to relay a message to, out of the notary peers of
+// its round and the other peers. The slices passed in may be reordered.
+func (f *fanout) pick(notaries, others []*peer) []*peer {
+	switch {
+	case f.all:
+		return append(notaries, others...)
+	case f.notaryOnly():
+		return notaries
+	case f.notaries:
+		n := f.count
+		if f.sqrt {
+			n = int(math.Sqrt(float64(len(others))))
+		}
+		return append(notaries, sample(others, n)...)
+	case f.sqrt:
+		peers := append(notaries, others...)
+		return peers[:int(math.Sqrt(float64(len(peers))))]
+	default:
+		return sample(append(notaries, others...), f.count)
+	}
+}
+
+// sample returns n peers picked at random, reordering peers.
+func sample(peers []*peer, n int) []*peer {
+	if n >= len(peers) {
+		return peers
+	}
+	for i := 0; i < n; i++ {
+		j := i + rand.Intn(len(peers)-i)
+		peers[i], peers[j] = peers[j], peers[i]
+	}
+	return peers[:n]
+}
+
+// parse parses the fanout strategies of the config, taking the default ones
+// for the message types left empty.
+func (c FanoutConfig) parse() (blocks, coreBlocks, votes *fanout, err error) {
+	parse := func(strategy, def string) (*fanout, error) {
+		if strategy == "" {
+			strategy = def
+		}
+		return parseFanout(strategy)
+	}
+	if blocks, err = parse(c.Blocks, DefaultConfig.Fanout.Blocks); err != nil {
+		return nil, nil, nil, fmt.Errorf("blocks: %v", err)
+	}
+	if coreBlocks, err = parse(c.CoreBlocks, DefaultConfig.Fanout.CoreBlocks); err != nil {
+		return nil, nil, nil, fmt.Errorf("core blocks: %v", err)
+	}
+	if votes, err = parse(c.Votes, DefaultConfig.Fanout.Votes); err != nil {
+		return nil, nil, nil, fmt.Errorf("votes: %v", err)
+	}
+	return blocks, coreBlocks, votes, nil
+}
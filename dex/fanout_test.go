@@ -0,0 +1,109 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+
+	"github.com/portto/go-tangerine/p2p"
+)
+
+func TestParseFanout(t *testing.T) {
+	valid := []string{
+		FanoutAll, FanoutNotary, FanoutSqrt, FanoutNotarySqrt,
+		FanoutRandom + "8", FanoutNotaryRandom + "1",
+	}
+	for _, strategy := range valid {
+		if _, err := parseFanout(strategy); err != nil {
+			t.Errorf("strategy %q refused: %v", strategy, err)
+		}
+	}
+	invalid := []string{
+		"", "none", "notary+", "notary+all", "notarysqrt", "random:", "random:0",
+		"random:-1", "notary+random:x",
+	}
+	for _, strategy := range invalid {
+		if _, err := parseFanout(strategy); err == nil {
+			t.Errorf("strategy %q accepted", strategy)
+		}
+	}
+
+	if _, _, _, err := (FanoutConfig{Votes: "sqrt"}).parse(); err != nil {
+		t.Errorf("config with defaults refused: %v", err)
+	}
+	if _, _, _, err := (FanoutConfig{CoreBlocks: "some"}).parse(); err == nil {
+		t.Errorf("invalid config accepted")
+	}
+}
+
+func TestFanoutPick(t *testing.T) {
+	newPeers := func(n int) []*peer {
+		peers := make([]*peer, n)
+		for i := range peers {
+			peers[i] = newPeer(dex64, p2p.NewPeerWithEnode(randomV4CompactNode(), "", nil), nil)
+		}
+		return peers
+	}
+	notaries, others := newPeers(4), newPeers(16)
+	contains := func(peers []*peer, p *peer) bool {
+		for _, q := range peers {
+			if q == p {
+				return true
+			}
+		}
+		return false
+	}
+
+	tests := []struct {
+		strategy string
+		picked   int
+		notaries bool // Whether all notaries are picked
+	}{
+		{FanoutAll, 20, true},
+		{FanoutNotary, 4, true},
+		{FanoutSqrt, 4, true},
+		{FanoutNotarySqrt, 8, true},
+		{FanoutRandom + "6", 6, false},
+		{FanoutRandom + "30", 20, true},
+		{FanoutNotaryRandom + "3", 7, true},
+	}
+	for _, tt := range tests {
+		f, err := parseFanout(tt.strategy)
+		if err != nil {
+			t.Fatalf("strategy %q refused: %v", tt.strategy, err)
+		}
+		picked := f.pick(append([]*peer{}, notaries...), append([]*peer{}, others...))
+		if len(picked) != tt.picked {
+			t.Errorf("%s: picked %d peers, want %d", tt.strategy, len(picked), tt.picked)
+		}
+		seen := make(map[*peer]bool)
+		for _, p := range picked {
+			if seen[p] {
+				t.Errorf("%s: peer %s picked twice", tt.strategy, p.id)
+			}
+			seen[p] = true
+		}
+		if tt.notaries {
+			for _, p := range notaries {
+				if !contains(picked, p) {
+					t.Errorf("%s: notary %s not picked", tt.strategy, p.id)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+)
+
+// maxFutureCoreBlocks is the maximum number of core blocks from the near
+// future waiting to be delivered, beyond which more are rejected.
+const maxFutureCoreBlocks = 1024
+
+// futureCoreBlock is a core block waiting to be delivered at due.
+type futureCoreBlock struct {
+	due   time.Time
+	peer  string // ID of the peer the block was received from
+	block *coreTypes.Block
+}
+
+// futureCoreBlockHeap orders future core blocks by due time.
+type futureCoreBlockHeap []*futureCoreBlock
+
+func (h futureCoreBlockHeap) Len() int            { return len(h) }
+func (h futureCoreBlockHeap) Less(i, j int) bool  { return h[i].due.Before(h[j].due) }
+func (h futureCoreBlockHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *futureCoreBlockHeap) Push(x interface{}) { *h = append(*h, x.(*futureCoreBlock)) }
+
+func (h *futureCoreBlockHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
+
+// futureCoreBlocks holds the core blocks received slightly ahead of the
+// local clock until they are due. A single timer moves the due blocks to a
+// delivery loop, so a slow consumer never holds up the timer.
+type futureCoreBlocks struct {
+	lock    sync.Mutex
+	queue   futureCoreBlockHeap
+	ready   []*futureCoreBlock // Due blocks waiting for the delivery loop
+	timer   *time.Timer
+	limit   int
+	deliver func(peer string, block *coreTypes.Block)
+
+	wake chan struct{}
+	quit chan struct{}
+}
+
+func newFutureCoreBlocks(limit int,
+	deliver func(peer string, block *coreTypes.Block)) *futureCoreBlocks {
+	f := &futureCoreBlocks{
+		limit:   limit,
+		deliver: deliver,
+		wake:    make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+	}
+	f.timer = time.AfterFunc(time.Hour, f.release)
+	f.timer.Stop()
+	go f.loop()
+	return f
+}
+
+// add queues block received from peer for delivery after delay, returning
+// false if the queue is full.
+func (f *futureCoreBlocks) add(peer string, block *coreTypes.Block, delay time.Duration) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if len(f.queue)+len(f.ready) >= f.limit {
+		return false
+	}
+	item := &futureCoreBlock{due: time.Now().Add(delay), peer: peer, block: block}
+	heap.Push(&f.queue, item)
+	if f.queue[0] == item {
+		f.timer.Reset(delay)
+	}
+	return true
+}
+
+// release hands the blocks due to the delivery loop and schedules the
+// release of the next.
+func (f *futureCoreBlocks) release() {
+	f.lock.Lock()
+	now := time.Now()
+	for len(f.queue) > 0 && !f.queue[0].due.After(now) {
+		f.ready = append(f.ready, heap.Pop(&f.queue).(*futureCoreBlock))
+	}
+	if len(f.queue) > 0 {
+		f.timer.Reset(f.queue[0].due.Sub(now))
+	}
+	f.lock.Unlock()
+
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+}
+
+// loop delivers the released blocks in due order until stopped.
+func (f *futureCoreBlocks) loop() {
+	for {
+		select {
+		case <-f.wake:
+			f.lock.Lock()
+			ready := f.ready
+			f.ready = nil
+			f.lock.Unlock()
+
+			for _, item := range ready {
+				f.deliver(item.peer, item.block)
+			}
+		case <-f.quit:
+			return
+		}
+	}
+}
+
+// stop drops the queued blocks and ends the delivery loop.
+func (f *futureCoreBlocks) stop() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.timer.Stop()
+	f.queue = nil
+	f.ready = nil
+	close(f.quit)
+}
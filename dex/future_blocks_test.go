@@ -0,0 +1,76 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+	"time"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+)
+
+// Tests that future core blocks are delivered in due order once due, and
+// rejected once too many are queued.
+func TestFutureCoreBlocks(t *testing.T) {
+	delivered := make(chan *coreTypes.Block, 4)
+	future := newFutureCoreBlocks(3, func(peer string, block *coreTypes.Block) {
+		if peer != "peer" {
+			t.Errorf("peer mismatch: have %s, want peer", peer)
+		}
+		delivered <- block
+	})
+	defer future.stop()
+
+	blocks := make([]*coreTypes.Block, 4)
+	for i := range blocks {
+		blocks[i] = &coreTypes.Block{Hash: coreCommon.Hash{byte(i)}}
+	}
+	delays := []time.Duration{60 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond}
+	for i, delay := range delays {
+		if !future.add("peer", blocks[i], delay) {
+			t.Fatalf("block %d rejected", i)
+		}
+	}
+	if future.add("peer", blocks[3], time.Millisecond) {
+		t.Errorf("block accepted beyond the limit")
+	}
+	start := time.Now()
+	for _, want := range []int{1, 2, 0} {
+		select {
+		case block := <-delivered:
+			if block != blocks[want] {
+				t.Errorf("delivery order mismatch: have %v, want %v", block.Hash, blocks[want].Hash)
+			}
+			if elapsed := time.Since(start); elapsed < delays[want]-5*time.Millisecond {
+				t.Errorf("block %d delivered early: %v", want, elapsed)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("block %d not delivered", want)
+		}
+	}
+	// Delivered blocks free their slots.
+	if !future.add("peer", blocks[3], time.Millisecond) {
+		t.Errorf("block rejected after the queue drained")
+	}
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatalf("block not delivered after the queue drained")
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/event"
+	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/metrics"
+)
+
+const (
+	govNonceChainHeadChanSize = 16
+
+	// govTxMaxRetries is the number of times sending a governance
+	// transaction is retried with a resynchronized nonce.
+	govTxMaxRetries = 3
+)
+
+var (
+	govTxResendMeter     = metrics.NewRegisteredMeter("dex/govtx/resend", nil)
+	govTxNonceRetryMeter = metrics.NewRegisteredMeter("dex/govtx/nonceretry", nil)
+)
+
+// govTxSignFn signs a governance transaction carrying data.
+type govTxSignFn func(data []byte, nonce uint64, gasPrice *big.Int) (*types.Transaction, error)
+
+// govTxNonceManager assigns the nonces of the governance transactions sent
+// by this node. It keeps the transactions sent until they are included in
+// the chain, so that the ones dropped from the transaction pool can be sent
+// again before they leave a gap blocking the later ones, and persists them
+// across restarts.
+type govTxNonceManager struct {
+	b          *DexAPIBackend
+	blockchain *core.BlockChain
+	db         ethdb.Database
+	address    common.Address
+	sign       govTxSignFn
+
+	lock    sync.Mutex
+	pending map[uint64]*types.Transaction
+	next    uint64
+
+	headCh  chan core.ChainHeadEvent
+	headSub event.Subscription
+}
+
+func newGovTxNonceManager(b *DexAPIBackend, blockchain *core.BlockChain,
+	db ethdb.Database, address common.Address, sign govTxSignFn) *govTxNonceManager {
+	m := &govTxNonceManager{
+		b:          b,
+		blockchain: blockchain,
+		db:         db,
+		address:    address,
+		sign:       sign,
+		pending:    make(map[uint64]*types.Transaction),
+	}
+	for _, tx := range rawdb.ReadGovPendingTxs(db, address) {
+		m.pending[tx.Nonce()] = tx
+	}
+	return m
+}
+
+// setAddress makes m send the governance transactions from address, after
+// a node key rotation. The transactions pending from the previous address
+// are left to the chain.
+func (m *govTxNonceManager) setAddress(address common.Address) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.address = address
+	m.pending = make(map[uint64]*types.Transaction)
+	m.next = 0
+	for _, tx := range rawdb.ReadGovPendingTxs(m.db, address) {
+		m.pending[tx.Nonce()] = tx
+	}
+}
+
+func (m *govTxNonceManager) Start() {
+	m.headCh = make(chan core.ChainHeadEvent, govNonceChainHeadChanSize)
+	m.headSub = m.blockchain.SubscribeChainHeadEvent(m.headCh)
+	go m.loop()
+}
+
+func (m *govTxNonceManager) Stop() {
+	m.headSub.Unsubscribe()
+}
+
+func (m *govTxNonceManager) loop() {
+	for {
+		select {
+		case <-m.headCh:
+			m.fillGaps()
+		case <-m.headSub.Err():
+			return
+		}
+	}
+}
+
+// send sends a governance transaction carrying data with the next nonce.
+func (m *govTxNonceManager) send(ctx context.Context, data []byte,
+	gasPrice *big.Int) (*types.Transaction, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.sync(ctx); err != nil {
+		return nil, err
+	}
+	return m.sendNext(ctx, data, gasPrice)
+}
+
+// sendNext sends a governance transaction carrying data with the next nonce.
+// The caller must hold the lock and have synced the nonce.
+func (m *govTxNonceManager) sendNext(ctx context.Context, data []byte,
+	gasPrice *big.Int) (*types.Transaction, error) {
+	var (
+		tx  *types.Transaction
+		err error
+	)
+	for i := 0; i < govTxMaxRetries; i++ {
+		tx, err = m.sign(data, m.next, gasPrice)
+		if err != nil {
+			return nil, err
+		}
+		err = m.b.SendTx(ctx, tx)
+		switch err {
+		case nil:
+			m.track(tx)
+			m.next++
+			return tx, nil
+		case core.ErrNonceTooLow, core.ErrReplaceUnderpriced:
+			// The nonce was taken by a transaction not sent through the
+			// manager, e.g. with the same key from another process.
+			log.Warn("Governance transaction nonce taken", "nonce", m.next, "err", err)
+			govTxNonceRetryMeter.Mark(1)
+			taken := m.next
+			if err := m.sync(ctx); err != nil {
+				return nil, err
+			}
+			if m.next == taken {
+				m.next++
+			}
+		default:
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// replace replaces a pending governance transaction with one carrying the
+// same data and nonce at a higher gas price. If the nonce of tx was already
+// consumed, the data is sent with a new nonce instead.
+func (m *govTxNonceManager) replace(ctx context.Context, tx *types.Transaction,
+	data []byte, gasPrice *big.Int) (*types.Transaction, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.sync(ctx); err != nil {
+		return nil, err
+	}
+	if m.pending[tx.Nonce()] == nil {
+		return m.sendNext(ctx, data, gasPrice)
+	}
+	replacement, err := m.sign(data, tx.Nonce(), gasPrice)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.b.SendTx(ctx, replacement); err != nil {
+		return nil, err
+	}
+	m.track(replacement)
+	return replacement, nil
+}
+
+// sync drops the pending transactions included in the chain and moves the
+// next nonce past the ones known to the transaction pool and the manager.
+// The caller must hold the lock.
+func (m *govTxNonceManager) sync(ctx context.Context) error {
+	state, err := m.blockchain.State()
+	if err != nil {
+		return err
+	}
+	stateNonce := state.GetNonce(m.address)
+	dropped := false
+	for nonce := range m.pending {
+		if nonce < stateNonce {
+			delete(m.pending, nonce)
+			dropped = true
+		}
+	}
+	if dropped {
+		m.persist()
+	}
+
+	poolNonce, err := m.b.GetPoolNonce(ctx, m.address)
+	if err != nil {
+		return err
+	}
+	if m.next < stateNonce {
+		m.next = stateNonce
+	}
+	if m.next < poolNonce {
+		m.next = poolNonce
+	}
+	for nonce := range m.pending {
+		if m.next <= nonce {
+			m.next = nonce + 1
+		}
+	}
+	return nil
+}
+
+// fillGaps sends again the pending transactions missing from the pool, which
+// would otherwise block all governance transactions with higher nonces.
+func (m *govTxNonceManager) fillGaps() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.sync(context.Background()); err != nil {
+		log.Error("Failed to sync governance transaction nonce", "err", err)
+		return
+	}
+	nonces := make([]uint64, 0, len(m.pending))
+	for nonce := range m.pending {
+		nonces = append(nonces, nonce)
+	}
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+
+	for _, nonce := range nonces {
+		tx := m.pending[nonce]
+		if m.b.GetPoolTransaction(tx.Hash()) != nil {
+			continue
+		}
+		log.Warn("Resending lost governance transaction", "nonce", nonce,
+			"fullhash", tx.Hash().Hex())
+		govTxResendMeter.Mark(1)
+		switch err := m.b.SendTx(context.Background(), tx); err {
+		case nil:
+		case core.ErrNonceTooLow:
+			delete(m.pending, nonce)
+			m.persist()
+		default:
+			log.Error("Failed to resend governance transaction", "nonce", nonce,
+				"err", err)
+			return
+		}
+	}
+}
+
+// track records tx as pending. The caller must hold the lock.
+func (m *govTxNonceManager) track(tx *types.Transaction) {
+	m.pending[tx.Nonce()] = tx
+	m.persist()
+}
+
+// persist stores the pending transactions. The caller must hold the lock.
+func (m *govTxNonceManager) persist() {
+	txs := make([]*types.Transaction, 0, len(m.pending))
+	for _, tx := range m.pending {
+		txs = append(txs, tx)
+	}
+	sort.Slice(txs, func(i, j int) bool { return txs[i].Nonce() < txs[j].Nonce() })
+	rawdb.WriteGovPendingTxs(m.db, m.address, txs)
+}
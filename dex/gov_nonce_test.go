@@ -0,0 +1,98 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+)
+
+// Tests that a pending governance transaction is replaced with the same
+// nonce, and that its data is sent with a new nonce once the nonce is gone.
+func TestGovTxNonceReplace(t *testing.T) {
+	dex := newGovTestTangerine(t)
+	defer dex.txPool.Stop()
+	m := dex.governance.nonceManager
+	ctx := context.Background()
+
+	tx, err := m.send(ctx, []byte{1}, big.NewInt(1e10))
+	if err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+	replacement, err := m.replace(ctx, tx, []byte{1}, big.NewInt(2e10))
+	if err != nil {
+		t.Fatalf("failed to replace: %v", err)
+	}
+	if replacement.Nonce() != tx.Nonce() || m.pending[tx.Nonce()] != replacement {
+		t.Errorf("replacement nonce mismatch: have %d, want %d", replacement.Nonce(), tx.Nonce())
+	}
+
+	delete(m.pending, tx.Nonce())
+	resent, err := m.replace(ctx, tx, []byte{1}, big.NewInt(4e10))
+	if err != nil {
+		t.Fatalf("failed to resend: %v", err)
+	}
+	if resent.Nonce() != tx.Nonce()+1 {
+		t.Errorf("resent nonce mismatch: have %d, want %d", resent.Nonce(), tx.Nonce()+1)
+	}
+}
+
+// Tests that concurrent sends and replacements assign every nonce once.
+func TestGovTxNonceConcurrency(t *testing.T) {
+	dex := newGovTestTangerine(t)
+	defer dex.txPool.Stop()
+	m := dex.governance.nonceManager
+	ctx := context.Background()
+
+	const senders = 8
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tx, err := m.send(ctx, []byte{byte(i)}, big.NewInt(1e10))
+			if err != nil {
+				t.Errorf("failed to send: %v", err)
+				return
+			}
+			// Replacing a transaction already replaced at the same price
+			// fails, that is fine.
+			m.replace(ctx, tx, []byte{byte(i)}, big.NewInt(2e10))
+		}(i)
+	}
+	wg.Wait()
+
+	pending, err := dex.txPool.Pending()
+	if err != nil {
+		t.Fatalf("failed to get pending transactions: %v", err)
+	}
+	txs := pending[m.address]
+	if len(txs) != senders {
+		t.Fatalf("pending transaction count mismatch: have %d, want %d", len(txs), senders)
+	}
+	for i, tx := range txs {
+		if tx.Nonce() != uint64(i) {
+			t.Errorf("transaction %d nonce mismatch: have %d", i, tx.Nonce())
+		}
+	}
+	if m.next != senders || len(m.pending) != senders {
+		t.Errorf("manager state mismatch: next %d, %d pending", m.next, len(m.pending))
+	}
+}
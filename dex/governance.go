@@ -19,13 +19,13 @@ package dex
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"fmt"
 	"math/big"
 
 	coreTypes "github.com/portto/tangerine-consensus/core/types"
 	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
 
-	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/consensus/dexcon"
 	"github.com/portto/go-tangerine/core"
 	"github.com/portto/go-tangerine/core/types"
 	"github.com/portto/go-tangerine/core/vm"
@@ -39,52 +39,74 @@ type DexconGovernance struct {
 
 	b           *DexAPIBackend
 	chainConfig *params.ChainConfig
-	privateKey  *ecdsa.PrivateKey
-	address     common.Address
+	signer      NodeSigner
+
+	nonceManager *govTxNonceManager
+	crsProposer  *crsProposer
+
+	adaptiveLambda AdaptiveLambdaConfig // Opts BA tickers into adapting their interval
 }
 
 // NewDexconGovernance returns a governance implementation of the DEXON
 // consensus governance interface.
 func NewDexconGovernance(backend *DexAPIBackend, chainConfig *params.ChainConfig,
-	privKey *ecdsa.PrivateKey) *DexconGovernance {
+	signer NodeSigner) *DexconGovernance {
 	g := &DexconGovernance{
 		Governance: core.NewGovernance(
 			core.NewGovernanceStateDB(backend.dex.BlockChain())),
 		b:           backend,
 		chainConfig: chainConfig,
-		privateKey:  privKey,
-		address:     crypto.PubkeyToAddress(privKey.PublicKey),
+		signer:      signer,
 	}
+	g.nonceManager = newGovTxNonceManager(backend, backend.dex.BlockChain(),
+		backend.dex.ChainDb(), crypto.PubkeyToAddress(*signer.PublicKey()), g.signGovTx)
+	g.crsProposer = newCRSProposer(g, backend.dex.BlockChain())
 	return g
 }
 
-// RawConfiguration return raw config in state.
+// RawConfiguration return raw config in state. The config is shared and must
+// not be modified.
 func (d *DexconGovernance) RawConfiguration(round uint64) (*params.DexconConfig, error) {
-	gs, err := d.GetConfigState(round)
+	s, err := d.GetConfigSnapshot(round)
 	if err != nil {
 		return nil, err
 	}
-	return gs.Configuration(), nil
+	return s.Config, nil
 }
 
 func (d *DexconGovernance) sendGovTx(ctx context.Context, data []byte) error {
-	gasPrice, err := d.b.SuggestPrice(ctx)
+	_, err := d.submitGovTx(ctx, data)
+	return err
+}
+
+// submitGovTx sends a governance transaction with the next nonce assigned by
+// the nonce manager and returns the transaction sent.
+func (d *DexconGovernance) submitGovTx(ctx context.Context, data []byte) (*types.Transaction, error) {
+	gasPrice, err := d.govTxGasPrice(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return d.nonceManager.send(ctx, data, gasPrice)
+}
 
-	nonce, err := d.b.GetPoolNonce(ctx, d.address)
+// govTxGasPrice returns the gas price of governance transactions.
+func (d *DexconGovernance) govTxGasPrice(ctx context.Context) (*big.Int, error) {
+	gasPrice, err := d.b.SuggestPrice(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
 	// Increase gasPrice to 10 times of suggested gas price to make sure it will
 	// be included in time.
-	gasPrice = new(big.Int).Mul(gasPrice, big.NewInt(10))
+	return new(big.Int).Mul(gasPrice, big.NewInt(10)), nil
+}
 
+// signGovTx signs a governance transaction with the given nonce and gas
+// price.
+func (d *DexconGovernance) signGovTx(data []byte, nonce uint64,
+	gasPrice *big.Int) (*types.Transaction, error) {
 	gasLimit, err := core.IntrinsicGas(data, false, false)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	tx := types.NewTransaction(
@@ -97,14 +119,14 @@ func (d *DexconGovernance) sendGovTx(ctx context.Context, data []byte) error {
 
 	signer := types.NewEIP155Signer(d.chainConfig.ChainID)
 
-	tx, err = types.SignTx(tx, signer, d.privateKey)
+	tx, err = signTx(tx, signer, d.signer)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	log.Info("Send governance transaction", "fullhash", tx.Hash().Hex(), "nonce", nonce)
 
-	return d.b.SendTx(ctx, tx)
+	return tx, nil
 }
 
 func (d *DexconGovernance) Round() uint64 {
@@ -119,10 +141,12 @@ func (d *DexconGovernance) ProposeCRS(round uint64, signedCRS []byte) {
 		return
 	}
 
-	err = d.sendGovTx(context.Background(), data)
+	tx, err := d.submitGovTx(context.Background(), data)
 	if err != nil {
 		log.Error("Failed to send proposeCRS tx", "err", err)
 	}
+	// Track the proposal even if sending failed, it will be resubmitted.
+	d.crsProposer.track(round, data, tx)
 }
 
 // AddDKGComplaint adds a DKGComplaint.
@@ -235,3 +259,49 @@ func (d *DexconGovernance) ResetDKG(newSignedCRS []byte) {
 		log.Error("Failed to send resetDKG tx", "err", err)
 	}
 }
+
+// verifyGovernanceTx refuses the governance configuration updates breaking
+// the invariants the consensus core derives from the configuration, before
+// they are sent to the network.
+func verifyGovernanceTx(tx *types.Transaction) error {
+	data := tx.Data()
+	if tx.To() == nil || *tx.To() != vm.GovernanceContractAddress || len(data) < 4 {
+		return nil
+	}
+	method, ok := vm.GovernanceABI.Sig2Method[string(data[:4])]
+	if !ok || method.Name != "updateConfiguration" {
+		return nil
+	}
+	var cfg struct {
+		MinStake         *big.Int
+		LockupPeriod     *big.Int
+		BlockGasLimit    *big.Int
+		MinGasPrice      *big.Int
+		LambdaBA         *big.Int
+		LambdaDKG        *big.Int
+		NotaryParamAlpha *big.Int
+		NotaryParamBeta  *big.Int
+		RoundLength      *big.Int
+		MinBlockInterval *big.Int
+		FineValues       []*big.Int
+	}
+	// Malformed updates are reverted by the governance contract.
+	if err := method.Inputs.Unpack(&cfg, data[4:]); err != nil {
+		return nil
+	}
+	for _, v := range []*big.Int{cfg.LambdaBA, cfg.LambdaDKG, cfg.RoundLength, cfg.MinBlockInterval} {
+		if !v.IsUint64() {
+			return fmt.Errorf("dangerous governance configuration: %v out of range", v)
+		}
+	}
+	err := dexcon.VerifyConfiguration(&params.DexconConfig{
+		LambdaBA:         cfg.LambdaBA.Uint64(),
+		LambdaDKG:        cfg.LambdaDKG.Uint64(),
+		RoundLength:      cfg.RoundLength.Uint64(),
+		MinBlockInterval: cfg.MinBlockInterval.Uint64(),
+	})
+	if err != nil {
+		return fmt.Errorf("dangerous governance configuration: %v", err)
+	}
+	return nil
+}
@@ -36,12 +36,15 @@ package dex
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"math/big"
+	"math/rand"
+	"runtime/pprof"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -81,6 +84,10 @@ const (
 
 	minTxReceiver = 3
 
+	// govTxReceiverRatio is the fraction of non-notary peers receiving
+	// governance-critical transactions; notary peers always receive them.
+	govTxReceiverRatio = 0.5
+
 	finalizedBlockChanSize = 128
 
 	maxPullPeers     = 3
@@ -100,24 +107,43 @@ const (
 // not compatible (low protocol version restrictions and high requirements).
 var errIncompatibleConfig = errors.New("incompatible configuration")
 
+// protocolError is returned for messages violating the protocol, which
+// penalize the peer sending them.
+type protocolError struct {
+	code errCode
+	msg  string
+}
+
+func (e *protocolError) Error() string {
+	return fmt.Sprintf("%v - %v", e.code, e.msg)
+}
+
 func errResp(code errCode, format string, v ...interface{}) error {
-	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+	return &protocolError{code: code, msg: fmt.Sprintf(format, v...)}
 }
 
 type ProtocolManager struct {
 	networkID uint64
 
-	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
-	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
+	fastSync       uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
+	checkpointSync uint32 // Flag whether fast sync bootstraps from a checkpoint
+	acceptTxs      uint32 // Flag whether we're considered synchronised (enables transaction processing)
 
 	txpool        txPool
 	gov           governance
 	blockchain    *core.BlockChain
 	chainconfig   *params.ChainConfig
+	coreDB        *dexDB.DB // Core block database shared with the consensus core
 	cache         *cache
+	sigVerifier   *sigVerifier
+	dkgShares     *dkgShareBuffer   // Private shares of DKG runs not entered yet
+	txProps       *txPropagations   // How recent transactions arrived and were relayed
+	txRequests    *txRequests       // Announced transactions requested from peers
+	votes         *voteBatcher      // Votes cast waiting to be broadcast
+	futureBlocks  *futureCoreBlocks // Core blocks from the near future waiting to be due
 	nextPullVote  *sync.Map
 	nextPullBlock *sync.Map
-	maxPeers      int
+	maxPeers      int32 // Accessed atomically, scaled by peerScaler
 
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
@@ -161,6 +187,38 @@ type ProtocolManager struct {
 
 	// metrics
 	blockNumberGauge metrics.Gauge
+
+	// msgProfilingLabels labels message handlers with the message type in
+	// pprof profiles.
+	msgProfilingLabels bool
+
+	// futureTolerance is how far ahead of the local clock core blocks may be
+	// timestamped, zero disabling the check.
+	futureTolerance time.Duration
+
+	// notaryPreconnectBlocks is how many blocks before the end of a round
+	// the connections to the notary set of the next round are made sure to
+	// be built, zero disabling it.
+	notaryPreconnectBlocks uint64
+
+	// scrubber checks the integrity of stored blocks, nil if disabled.
+	scrubber *scrubber
+
+	// peerHistory records peer connections and disconnections, nil if
+	// disabled.
+	peerHistory *peerHistory
+
+	// audit records the peers banned, nil if not audited.
+	audit *auditLog
+
+	// stateRoots compares the state roots announced by validators at round
+	// boundaries with the local ones, nil if disabled.
+	stateRoots *stateRootGossip
+
+	// Fanout strategies of propagated blocks, core blocks and votes.
+	blockFanout     *fanout
+	coreBlockFanout *fanout
+	voteFanout      *fanout
 }
 
 // NewProtocolManager returns a new Ethereum sub protocol manager. The Ethereum sub protocol manages peers capable
@@ -170,6 +228,8 @@ func NewProtocolManager(
 	mux *event.TypeMux, txpool txPool, engine consensus.Engine,
 	blockchain *core.BlockChain, chaindb ethdb.Database, whitelist map[uint64]common.Hash,
 	isBlockProposer bool, gov governance, app dexconApp) (*ProtocolManager, error) {
+	coreDB := dexDB.NewDatabaseWithFilter(chaindb)
+
 	// Create the protocol manager with the base fields
 	manager := &ProtocolManager{
 		networkID:          networkID,
@@ -177,7 +237,12 @@ func NewProtocolManager(
 		txpool:             txpool,
 		gov:                gov,
 		blockchain:         blockchain,
-		cache:              newCache(5120, dexDB.NewDatabase(chaindb)),
+		coreDB:             coreDB,
+		cache:              newCache(defaultCacheSize, coreDB),
+		sigVerifier:        newSigVerifier(0),
+		dkgShares:          newDKGShareBuffer(maxBufferedDKGShares),
+		txProps:            newTxPropagations(maxTxPropagations),
+		txRequests:         newTxRequests(),
 		nextPullVote:       &sync.Map{},
 		nextPullBlock:      &sync.Map{},
 		chainconfig:        config,
@@ -193,15 +258,23 @@ func NewProtocolManager(
 		app:                app,
 		blockNumberGauge:   metrics.GetOrRegisterGauge("dex/blocknumber", nil),
 	}
+	manager.votes = newVoteBatcher(voteBatchInterval, manager.broadcastVotes)
+	manager.futureBlocks = newFutureCoreBlocks(maxFutureCoreBlocks, manager.deliverFutureCoreBlock)
+	manager.blockFanout, manager.coreBlockFanout, manager.voteFanout, _ =
+		DefaultConfig.Fanout.parse()
 
 	// Figure out whether to allow fast sync or not
-	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
+	if (mode == downloader.FastSync || mode == downloader.CheckpointSync) &&
+		blockchain.CurrentBlock().NumberU64() > 0 {
 		log.Warn("Blockchain not empty, fast sync disabled")
 		mode = downloader.FullSync
 	}
-	if mode == downloader.FastSync {
+	if mode == downloader.FastSync || mode == downloader.CheckpointSync {
 		manager.fastSync = uint32(1)
 	}
+	if mode == downloader.CheckpointSync {
+		manager.checkpointSync = uint32(1)
+	}
 	// Initiate a sub-protocol for every implemented version we can handle
 	manager.SubProtocols = make([]p2p.Protocol, 0, len(ProtocolVersions))
 	for i, version := range ProtocolVersions {
@@ -216,7 +289,11 @@ func NewProtocolManager(
 				case manager.newPeerCh <- peer:
 					manager.wg.Add(1)
 					defer manager.wg.Done()
-					return manager.handle(peer)
+					var err error
+					withProfileLabel(profileNetwork, func(ctx context.Context) {
+						err = manager.handle(ctx, peer)
+					})
+					return err
 				case <-manager.quitSync:
 					return p2p.DiscQuitting
 				}
@@ -268,6 +345,7 @@ func (pm *ProtocolManager) removePeer(id string) {
 
 	pm.nextPullVote.Delete(peer.ID())
 	pm.nextPullBlock.Delete(peer.ID())
+	pm.txRequests.dropPeer(id)
 
 	// Unregister the peer from the downloader and Ethereum peer set
 	pm.downloader.UnregisterPeer(id)
@@ -285,14 +363,16 @@ func (pm *ProtocolManager) removePeer(id string) {
 }
 
 func (pm *ProtocolManager) Start(srvr p2pServer, maxPeers int) {
-	pm.maxPeers = maxPeers
+	pm.maxPeers = int32(maxPeers)
 	pm.srvr = srvr
 	pm.peers = newPeerSet(pm.gov, pm.srvr)
+	pm.peers.audit = pm.audit
 
 	// broadcast transactions
 	pm.txsCh = make(chan core.NewTxsEvent, txChanSize)
 	pm.txsSub = pm.txpool.SubscribeNewTxsEvent(pm.txsCh)
 	go pm.txBroadcastLoop()
+	go pm.txFetchLoop()
 
 	if pm.isBlockProposer {
 		// broadcast finalized blocks
@@ -314,6 +394,10 @@ func (pm *ProtocolManager) Start(srvr p2pServer, maxPeers int) {
 
 	// Listen to bad peer and disconnect it.
 	go pm.badPeerWatchLoop()
+
+	if pm.peerHistory != nil {
+		go pm.peerHistoryLoop()
+	}
 }
 
 func (pm *ProtocolManager) Stop() {
@@ -332,6 +416,7 @@ func (pm *ProtocolManager) Stop() {
 
 	// Quit fetcher, txsyncLoop.
 	close(pm.quitSync)
+	pm.futureBlocks.stop()
 
 	// Disconnect existing sessions.
 	// This also closes the gate for any new registrations on the peer set.
@@ -342,6 +427,11 @@ func (pm *ProtocolManager) Stop() {
 	// Wait for all peer handler goroutines and the loops to come down.
 	pm.wg.Wait()
 
+	// Store the peer events of the current hour, the disconnections above
+	// included.
+	if pm.peerHistory != nil {
+		pm.peerHistory.flush()
+	}
 	log.Info("Protocol manager stopped")
 }
 
@@ -353,28 +443,112 @@ func (pm *ProtocolManager) sendCoreMsg(msg *coreTypes.Msg) {
 	pm.receiveCh <- *msg
 }
 
+// deliverFutureCoreBlock delivers a core block received from the peer id
+// ahead of the local clock to the consensus core once it is due.
+func (pm *ProtocolManager) deliverFutureCoreBlock(id string, block *coreTypes.Block) {
+	if atomic.LoadInt32(&pm.receiveCoreMessage) == 0 {
+		return
+	}
+	pm.cache.addBlocks([]*coreTypes.Block{block})
+	select {
+	case pm.receiveCh <- coreTypes.Msg{PeerID: id, Payload: block}:
+	case <-pm.quitSync:
+	}
+}
+
+// validCoreBlockPayload returns whether the payload of block matches its
+// payload hash. Empty blocks carry no payload and a zero payload hash.
+func validCoreBlockPayload(block *coreTypes.Block) bool {
+	if block.IsEmpty() {
+		return len(block.Payload) == 0 && block.PayloadHash == (coreCommon.Hash{})
+	}
+	return coreCrypto.Keccak256Hash(block.Payload) == block.PayloadHash
+}
+
 func (pm *ProtocolManager) ReportBadPeerChan() chan<- interface{} {
 	return pm.reportBadPeerChan
 }
 
+// txFetchLoop requests the announced transactions whose request timed out
+// from the next peer which announced them.
+func (pm *ProtocolManager) txFetchLoop() {
+	ticker := time.NewTicker(txFetchTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			pm.retryTxRequests(now)
+		case <-pm.quitSync:
+			return
+		}
+	}
+}
+
+func (pm *ProtocolManager) retryTxRequests(now time.Time) {
+	for id, hashes := range pm.txRequests.timeouts(now) {
+		p := pm.peers.Peer(id)
+		if p == nil {
+			continue
+		}
+		if err := requestTxs(p, hashes); err != nil {
+			p.Log().Debug("Failed to request transactions", "err", err)
+		}
+	}
+}
+
+// requestTxs requests hashes from p in batches of maxTxRetrievals.
+func requestTxs(p *peer, hashes []common.Hash) error {
+	for len(hashes) > 0 {
+		n := len(hashes)
+		if n > maxTxRetrievals {
+			n = maxTxRetrievals
+		}
+		if err := p.RequestTxs(hashes[:n]); err != nil {
+			return err
+		}
+		hashes = hashes[n:]
+	}
+	return nil
+}
+
 func (pm *ProtocolManager) badPeerWatchLoop() {
-	go pm.checkPeerInWhitelist(pm.reportBadPeerChan)
+	go pm.checkPeerInWhitelist()
 	for {
 		select {
 		case id := <-pm.reportBadPeerChan:
-			log.Debug("Bad peer detected, removing", "id", id.(string))
-			pm.removePeer(id.(string))
+			log.Debug("Bad peer reported", "id", id.(string))
+			if pm.peers.Penalize(id.(string), penaltyBadPeerReport, "reported by consensus core") {
+				pm.removePeer(id.(string))
+			}
+		case <-pm.quitSync:
+			return
+		}
+	}
+}
+
+// peerHistoryLoop periodically stores the peer events of the current hour,
+// so that a crash doesn't lose them.
+func (pm *ProtocolManager) peerHistoryLoop() {
+	ticker := time.NewTicker(peerHistoryFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pm.peerHistory.flush()
 		case <-pm.quitSync:
 			return
 		}
 	}
 }
 
-func (pm *ProtocolManager) checkPeerInWhitelist(reportBadPeerChan chan<- interface{}) {
+func (pm *ProtocolManager) checkPeerInWhitelist() {
 	for {
-		for id, p := range pm.peers.peers {
+		for _, p := range pm.peers.Peers() {
 			if !pm.inWhitelist(p) {
-				reportBadPeerChan <- id
+				log.Debug("Peer not in whitelist, removing", "id", p.id)
+				pm.removePeer(p.id)
 			}
 		}
 		time.Sleep(checkPeerDuration)
@@ -400,16 +574,20 @@ func (pm *ProtocolManager) inWhitelist(p *peer) bool {
 }
 
 // handle is the callback invoked to manage the life cycle of an eth peer. When
-// this function terminates, the peer is disconnected.
-func (pm *ProtocolManager) handle(p *peer) error {
+// this function terminates, the peer is disconnected. The pprof labels of ctx
+// are the ones of the goroutine running it.
+func (pm *ProtocolManager) handle(ctx context.Context, p *peer) (err error) {
 	if !pm.inWhitelist(p) {
 		p.Log().Debug("Peer disconnect: permission denied", "name", p.Name())
 		return p2p.DiscPermissionDenied
 	}
-	// Ignore maxPeers if this is a trusted peer
-	if pm.peers.Len() >= pm.maxPeers && !p.Peer.Info().Network.Trusted {
-		return p2p.DiscTooManyPeers
+	if pm.peers.Banned(p.id) {
+		p.Log().Debug("Peer disconnect: banned", "name", p.Name())
+		return p2p.DiscUselessPeer
 	}
+	// Ignore maxPeers if this is a trusted peer. Notary set members may
+	// also exceed it, but only after proving their membership.
+	overflow := pm.peers.Len() >= int(atomic.LoadInt32(&pm.maxPeers)) && !p.Peer.Info().Network.Trusted
 	p.Log().Debug("Ethereum peer connected", "name", p.Name())
 
 	// Execute the Ethereum handshake
@@ -418,11 +596,18 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		head    = pm.blockchain.CurrentBlock().Header()
 		hash    = head.Hash()
 		number  = head.Number.Uint64()
+		key     *ecdsa.PrivateKey
 	)
-	if err := p.Handshake(pm.networkID, number, hash, genesis.Hash()); err != nil {
+	if pm.peers.IsNotary(pm.srvr.Self().ID()) {
+		key = pm.srvr.GetPrivateKey()
+	}
+	if err := p.Handshake(pm.networkID, number, hash, genesis.Hash(), key); err != nil {
 		p.Log().Debug("Ethereum handshake failed", "err", err)
 		return err
 	}
+	if overflow && !(p.notary && pm.peers.IsNotary(p.ID())) {
+		return p2p.DiscTooManyPeers
+	}
 	if rw, ok := p.rw.(*meteredMsgReadWriter); ok {
 		rw.Init(p.version)
 	}
@@ -433,6 +618,9 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	}
 	defer pm.removePeer(p.id)
 
+	pm.recordPeerEvent(p, types.PeerConnected, nil)
+	defer func() { pm.recordPeerEvent(p, types.PeerDisconnected, err) }()
+
 	// Register the peer in the downloader. If the downloader considers it banned, we disconnect
 	if err := pm.downloader.RegisterPeer(p.id, p.version, p); err != nil {
 		return err
@@ -450,16 +638,44 @@ func (pm *ProtocolManager) handle(p *peer) error {
 
 	// Handle incoming messages until the connection is torn down
 	for {
-		if err := pm.handleMsg(p); err != nil {
+		if err := pm.handleMsg(ctx, p); err != nil {
 			p.Log().Debug("Ethereum message handling failed", "err", err)
+			if perr, ok := err.(*protocolError); ok {
+				pm.peers.Penalize(p.id, protocolErrorPenalty(perr.code), err.Error())
+			}
 			return err
 		}
 	}
 }
 
+// recordPeerEvent adds the connection or disconnection of p to the peer
+// history, if enabled.
+func (pm *ProtocolManager) recordPeerEvent(p *peer, event string, reason error) {
+	if pm.peerHistory == nil {
+		return
+	}
+	info := p.Peer.Info()
+	ev := &types.PeerEvent{
+		Time:       time.Now().UnixNano() / int64(time.Millisecond),
+		Event:      event,
+		ID:         p.id,
+		Name:       p.Name(),
+		RemoteAddr: info.Network.RemoteAddress,
+		Inbound:    info.Network.Inbound,
+		Version:    p.version,
+		Notary:     pm.peers.IsNotary(p.ID()),
+		Round:      pm.blockchain.CurrentBlock().Round(),
+	}
+	if reason != nil {
+		ev.Reason = reason.Error()
+	}
+	pm.peerHistory.record(ev)
+}
+
 // handleMsg is invoked whenever an inbound message is received from a remote
-// peer. The remote connection is torn down upon returning any error.
-func (pm *ProtocolManager) handleMsg(p *peer) error {
+// peer. The remote connection is torn down upon returning any error. The
+// message labels are added to the pprof labels of ctx while handling it.
+func (pm *ProtocolManager) handleMsg(ctx context.Context, p *peer) error {
 	ch := make(chan struct{})
 	defer close(ch)
 
@@ -491,6 +707,13 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
 	}
 	defer msg.Discard()
+	defer timeHandleMsg(msg.Code, msg.ReceivedAt)()
+
+	if pm.msgProfilingLabels {
+		pprof.SetGoroutineLabels(pprof.WithLabels(ctx,
+			pprof.Labels("msg", msgCodeName(msg.Code))))
+		defer pprof.SetGoroutineLabels(ctx)
+	}
 
 	go func() {
 		start := time.Now()
@@ -517,131 +740,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&query); err != nil {
 			return errResp(ErrDecode, "%v: %v", msg, err)
 		}
-		hashMode := query.Origin.Hash != (common.Hash{})
-		first := true
-		maxNonCanonical := uint64(100)
-
-		round := map[uint64]uint64{}
-		// Gather headers until the fetch or network limits is reached
-		var (
-			bytes   common.StorageSize
-			headers []*types.HeaderWithGovState
-			unknown bool
-		)
-		for !unknown && len(headers) < int(query.Amount) && bytes < softResponseLimit && len(headers) < downloader.MaxHeaderFetch {
-			// Retrieve the next header satisfying the query
-			var origin *types.Header
-			if hashMode {
-				if first {
-					first = false
-					origin = pm.blockchain.GetHeaderByHash(query.Origin.Hash)
-					if origin != nil {
-						query.Origin.Number = origin.Number.Uint64()
-					}
-				} else {
-					origin = pm.blockchain.GetHeader(query.Origin.Hash, query.Origin.Number)
-				}
-			} else {
-				origin = pm.blockchain.GetHeaderByNumber(query.Origin.Number)
-			}
-			if origin == nil {
-				break
-			}
-			headers = append(headers, &types.HeaderWithGovState{Header: origin})
-			if round[origin.Round] == 0 {
-				round[origin.Round] = origin.Number.Uint64()
-			}
-			bytes += estHeaderRlpSize
-
-			// Advance to the next header of the query
-			switch {
-			case hashMode && query.Reverse:
-				// Hash based traversal towards the genesis block
-				ancestor := query.Skip + 1
-				if ancestor == 0 {
-					unknown = true
-				} else {
-					query.Origin.Hash, query.Origin.Number = pm.blockchain.GetAncestor(query.Origin.Hash, query.Origin.Number, ancestor, &maxNonCanonical)
-					unknown = (query.Origin.Hash == common.Hash{})
-				}
-			case hashMode && !query.Reverse:
-				// Hash based traversal towards the leaf block
-				var (
-					current = origin.Number.Uint64()
-					next    = current + query.Skip + 1
-				)
-				if next <= current {
-					infos, _ := json.MarshalIndent(p.Peer.Info(), "", "  ")
-					p.Log().Warn("GetBlockHeaders skip overflow attack", "current", current, "skip", query.Skip, "next", next, "attacker", infos)
-					unknown = true
-				} else {
-					if header := pm.blockchain.GetHeaderByNumber(next); header != nil {
-						nextHash := header.Hash()
-						expOldHash, _ := pm.blockchain.GetAncestor(nextHash, next, query.Skip+1, &maxNonCanonical)
-						if expOldHash == query.Origin.Hash {
-							query.Origin.Hash, query.Origin.Number = nextHash, next
-						} else {
-							unknown = true
-						}
-					} else {
-						unknown = true
-					}
-				}
-			case query.Reverse:
-				// Number based traversal towards the genesis block
-				if query.Origin.Number >= query.Skip+1 {
-					query.Origin.Number -= query.Skip + 1
-				} else {
-					unknown = true
-				}
-
-			case !query.Reverse:
-				// Number based traversal towards the leaf block
-				query.Origin.Number += query.Skip + 1
-			}
-		}
-
-		if query.WithGov && len(headers) > 0 {
-			last := headers[len(headers)-1]
-			currentBlock := pm.blockchain.CurrentBlock()
-
-			// Do not reply if we don't have current gov state
-			if currentBlock.NumberU64() < last.Number.Uint64() {
-				log.Debug("Current block < last request",
-					"current", currentBlock.NumberU64(), "last", last.Number.Uint64())
-				return p.SendBlockHeaders(query.Flag, []*types.HeaderWithGovState{})
-			}
-
-			snapshotHeight := map[uint64]struct{}{}
-			for r, height := range round {
-				log.Trace("#Include round", "round", r)
-				if r == 0 {
-					continue
-				}
-				h := pm.gov.GetRoundHeight(r)
-				log.Trace("#Snapshot height", "height", h)
-				if h == 0 {
-					h = height
-				}
-				snapshotHeight[h] = struct{}{}
-			}
-
-			for _, header := range headers {
-				if _, exist := snapshotHeight[header.Number.Uint64()]; exist {
-					tt := time.Now()
-					log.Debug("Handler get gov state by hash", "t", tt)
-					s, err := pm.blockchain.GetGovStateByHash(header.Hash())
-					log.Debug("Handler get gov state by hash", "elapsed", time.Since(tt))
-					if err != nil {
-						log.Warn("Get gov state by hash fail", "number", header.Number.Uint64(), "err", err)
-						return p.SendBlockHeaders(query.Flag, []*types.HeaderWithGovState{})
-					}
-					header.GovState = s
-				}
-				log.Trace("Send header", "round", header.Round, "number", header.Number.Uint64(), "gov state == nil", header.GovState == nil)
-			}
-		}
-		return p.SendBlockHeaders(query.Flag, headers)
+		return p.SendBlockHeaders(query.Flag, pm.queryHeaders(p.Peer, query))
 
 	case msg.Code == BlockHeadersMsg:
 		// A batch of headers arrived to one of our previous requests
@@ -729,6 +828,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			if err != nil {
 				log.Debug("Failed to deliver bodies", "err", err)
 			}
+		case scrubberReq:
+			if pm.scrubber != nil {
+				pm.scrubber.deliverBodies(p.id, transactions, uncles)
+			}
 		default:
 			log.Debug("Got bodies with unexpected flag", "flag", request.Flag)
 		}
@@ -813,6 +916,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&receipts); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		if pm.scrubber != nil && pm.scrubber.deliverReceipts(p.id, receipts) {
+			break
+		}
 		// Deliver all to the downloader
 		if err := pm.downloader.DeliverReceipts(p.id, receipts); err != nil {
 			log.Debug("Failed to deliver receipts", "err", err)
@@ -823,10 +929,18 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&announces); err != nil {
 			return errResp(ErrDecode, "%v: %v", msg, err)
 		}
-		// Mark the hashes as present at the remote node
+		// Mark the hashes as present at the remote node, a peer has no reason
+		// to announce a block twice.
+		useless := 0
 		for _, block := range announces {
+			if p.MarkAnnounced(block.Hash) {
+				useless++
+			}
 			p.MarkBlock(block.Hash)
 		}
+		if useless > 0 && pm.peers.Penalize(p.id, float64(useless)*penaltyUselessAnnounce, "useless announces") {
+			return p2p.DiscUselessPeer
+		}
 		// Schedule all the unknown hashes for retrieval
 		unknown := make(newBlockHashesData, 0, len(announces))
 		for _, block := range announces {
@@ -880,15 +994,73 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&txs); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		for i, tx := range txs {
-			// Validate and mark the remote transaction
-			if tx == nil {
-				return errResp(ErrDecode, "transaction %d is nil", i)
+		if err := pm.addRemoteTxs(p, txs); err != nil {
+			return err
+		}
+
+	case msg.Code == NewPooledTransactionHashesMsg && p.version >= dex65:
+		// Transactions announced, fetch the ones not known yet
+		if atomic.LoadUint32(&pm.acceptTxs) == 0 {
+			break
+		}
+		var hashes []common.Hash
+		if err := msg.Decode(&hashes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if len(hashes) > maxTxAnnounces {
+			return errResp(ErrDecode, "too many transaction hashes: %d > %d", len(hashes), maxTxAnnounces)
+		}
+		unknown := make([]common.Hash, 0, len(hashes))
+		for _, hash := range hashes {
+			p.MarkTransaction(hash)
+			if pm.txpool.Get(hash) == nil {
+				unknown = append(unknown, hash)
+			}
+		}
+		if err := requestTxs(p, pm.txRequests.announced(p.id, unknown, time.Now())); err != nil {
+			return err
+		}
+
+	case msg.Code == GetPooledTransactionsMsg && p.version >= dex65:
+		// Decode the retrieval message
+		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
+		if _, err := msgStream.List(); err != nil {
+			return err
+		}
+		// Gather transactions until the fetch or network limits is reached
+		var (
+			hash  common.Hash
+			bytes int
+			txs   types.Transactions
+		)
+		for bytes < softResponseLimit && len(txs) < maxTxRetrievals {
+			// Retrieve the hash of the next transaction
+			if err := msgStream.Decode(&hash); err == rlp.EOL {
+				break
+			} else if err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
 			}
-			p.MarkTransaction(tx.Hash())
+			// Retrieve the requested transaction, skipping unknown ones
+			if tx := pm.txpool.Get(hash); tx != nil {
+				txs = append(txs, tx)
+				bytes += int(tx.Size())
+			}
+		}
+		return p.SendPooledTransactions(txs)
+
+	case msg.Code == PooledTransactionsMsg && p.version >= dex65:
+		// Requested transactions arrived, deliver them like broadcast ones
+		if atomic.LoadUint32(&pm.acceptTxs) == 0 {
+			break
+		}
+		var txs []*types.Transaction
+		if err := msg.Decode(&txs); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		types.GlobalSigCache.Add(types.NewEIP155Signer(pm.blockchain.Config().ChainID), txs)
-		pm.txpool.AddRemotes(txs)
+		if err := pm.addRemoteTxs(p, txs); err != nil {
+			return err
+		}
+		pm.txRequests.delivered(txs)
 
 	// Block proposer-only messages.
 	case msg.Code == CoreBlockMsg:
@@ -899,8 +1071,54 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&blocks); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		pm.cache.addBlocks(blocks)
+		receivedAt := msg.ReceivedAt
+		if receivedAt.IsZero() {
+			receivedAt = time.Now()
+		}
+		// Drop blocks with corrupt payloads before they reach the consensus
+		// core, which would reject them only after queueing.
 		for _, block := range blocks {
+			if !validCoreBlockPayload(block) {
+				invalidCoreBlockMeter.Mark(1)
+				return errResp(ErrInvalidCoreBlock, "payload hash mismatch: %v", block.Hash)
+			}
+		}
+		for i, err := range pm.sigVerifier.VerifyBlocks(blocks) {
+			if err != nil {
+				invalidCoreBlockMeter.Mark(1)
+				return errResp(ErrInvalidCoreBlock, "%v: %v", err, blocks[i].Hash)
+			}
+		}
+		offset := p.clockOffset.value()
+		accepted := blocks[:0]
+		for _, block := range blocks {
+			delay, ok := checkFutureTime(block.Timestamp, receivedAt, offset,
+				pm.futureTolerance)
+			if !ok {
+				futureCoreBlockRejectMeter.Mark(1)
+				p.Log().Debug("Rejected core block from the future",
+					"hash", block.Hash, "timestamp", block.Timestamp)
+				continue
+			}
+			// Relayed blocks arrive late, only the blocks proposed by the
+			// peer itself tell its clock.
+			if block.ProposerID == p.coreID {
+				p.clockOffset.observe(block.Timestamp.Sub(receivedAt))
+			}
+			if delay > 0 {
+				if !pm.futureBlocks.add(p.ID().String(), block, delay) {
+					futureCoreBlockRejectMeter.Mark(1)
+					p.Log().Debug("Rejected core block from the future, too many queued",
+						"hash", block.Hash, "timestamp", block.Timestamp)
+					continue
+				}
+				futureCoreBlockDeferMeter.Mark(1)
+				continue
+			}
+			accepted = append(accepted, block)
+		}
+		pm.cache.addBlocks(accepted)
+		for _, block := range accepted {
 			pm.sendCoreMsg(&coreTypes.Msg{
 				PeerID:  p.ID().String(),
 				Payload: block,
@@ -914,6 +1132,13 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&votes); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		// Check the votes before caching them to serve pull requests.
+		for i, err := range pm.sigVerifier.VerifyVotes(votes) {
+			if err != nil {
+				invalidVoteMeter.Mark(1)
+				return errResp(ErrInvalidVote, "%v: %v", err, votes[i])
+			}
+		}
 		for _, vote := range votes {
 			if vote.Type >= coreTypes.VotePreCom {
 				pm.cache.addVote(vote)
@@ -953,6 +1178,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		p.MarkDKGPrivateShares(rlpHash(ps))
+		if pm.bufferEarlyDKGShare(p, &ps) {
+			break
+		}
 		pm.sendCoreMsg(&coreTypes.Msg{
 			PeerID:  p.ID().String(),
 			Payload: &ps,
@@ -966,6 +1194,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&psig); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		if !pm.cache.addPartialSignature(&psig) {
+			redundantDKGPartialSignatureMeter.Mark(1)
+			break
+		}
 		pm.sendCoreMsg(&coreTypes.Msg{
 			PeerID:  p.ID().String(),
 			Payload: &psig,
@@ -1031,17 +1263,161 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := pm.downloader.DeliverGovState(p.id, &govState); err != nil {
 			log.Debug("Failed to deliver govstates", "err", err)
 		}
+	case msg.Code == StateRootMsg && p.version >= dex65:
+		var root stateRootData
+		if err := msg.Decode(&root); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if pm.stateRoots == nil {
+			break
+		}
+		if err := pm.stateRoots.handle(p.id, &root); err != nil {
+			return errResp(ErrInvalidStateRoot, "%v", err)
+		}
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
 	}
 	return nil
 }
 
+// queryHeaders gathers the headers satisfying query for p, along with the
+// governance states of the rounds they cover if requested.
+func (pm *ProtocolManager) queryHeaders(p *p2p.Peer, query getBlockHeadersData) []*types.HeaderWithGovState {
+	hashMode := query.Origin.Hash != (common.Hash{})
+	first := true
+	maxNonCanonical := uint64(100)
+
+	round := map[uint64]uint64{}
+	// Gather headers until the fetch or network limits is reached
+	var (
+		bytes   common.StorageSize
+		headers []*types.HeaderWithGovState
+		unknown bool
+	)
+	for !unknown && len(headers) < int(query.Amount) && bytes < softResponseLimit && len(headers) < downloader.MaxHeaderFetch {
+		// Retrieve the next header satisfying the query
+		var origin *types.Header
+		if hashMode {
+			if first {
+				first = false
+				origin = pm.blockchain.GetHeaderByHash(query.Origin.Hash)
+				if origin != nil {
+					query.Origin.Number = origin.Number.Uint64()
+				}
+			} else {
+				origin = pm.blockchain.GetHeader(query.Origin.Hash, query.Origin.Number)
+			}
+		} else {
+			origin = pm.blockchain.GetHeaderByNumber(query.Origin.Number)
+		}
+		if origin == nil {
+			break
+		}
+		headers = append(headers, &types.HeaderWithGovState{Header: origin})
+		if round[origin.Round] == 0 {
+			round[origin.Round] = origin.Number.Uint64()
+		}
+		bytes += estHeaderRlpSize
+
+		// Advance to the next header of the query
+		switch {
+		case hashMode && query.Reverse:
+			// Hash based traversal towards the genesis block
+			ancestor := query.Skip + 1
+			if ancestor == 0 {
+				unknown = true
+			} else {
+				query.Origin.Hash, query.Origin.Number = pm.blockchain.GetAncestor(query.Origin.Hash, query.Origin.Number, ancestor, &maxNonCanonical)
+				unknown = (query.Origin.Hash == common.Hash{})
+			}
+		case hashMode && !query.Reverse:
+			// Hash based traversal towards the leaf block
+			var (
+				current = origin.Number.Uint64()
+				next    = current + query.Skip + 1
+			)
+			if next <= current {
+				infos, _ := json.MarshalIndent(p.Info(), "", "  ")
+				p.Log().Warn("GetBlockHeaders skip overflow attack", "current", current, "skip", query.Skip, "next", next, "attacker", infos)
+				unknown = true
+			} else {
+				if header := pm.blockchain.GetHeaderByNumber(next); header != nil {
+					nextHash := header.Hash()
+					expOldHash, _ := pm.blockchain.GetAncestor(nextHash, next, query.Skip+1, &maxNonCanonical)
+					if expOldHash == query.Origin.Hash {
+						query.Origin.Hash, query.Origin.Number = nextHash, next
+					} else {
+						unknown = true
+					}
+				} else {
+					unknown = true
+				}
+			}
+		case query.Reverse:
+			// Number based traversal towards the genesis block
+			if query.Origin.Number >= query.Skip+1 {
+				query.Origin.Number -= query.Skip + 1
+			} else {
+				unknown = true
+			}
+
+		case !query.Reverse:
+			// Number based traversal towards the leaf block
+			query.Origin.Number += query.Skip + 1
+		}
+	}
+
+	if query.WithGov && len(headers) > 0 {
+		last := headers[len(headers)-1]
+		currentBlock := pm.blockchain.CurrentBlock()
+
+		// Do not reply if we don't have current gov state
+		if currentBlock.NumberU64() < last.Number.Uint64() {
+			log.Debug("Current block < last request",
+				"current", currentBlock.NumberU64(), "last", last.Number.Uint64())
+			return []*types.HeaderWithGovState{}
+		}
+
+		snapshotHeight := map[uint64]struct{}{}
+		for r, height := range round {
+			log.Trace("#Include round", "round", r)
+			if r == 0 {
+				continue
+			}
+			h := pm.gov.GetRoundHeight(r)
+			log.Trace("#Snapshot height", "height", h)
+			if h == 0 {
+				h = height
+			}
+			snapshotHeight[h] = struct{}{}
+		}
+
+		for _, header := range headers {
+			if _, exist := snapshotHeight[header.Number.Uint64()]; exist {
+				tt := time.Now()
+				log.Debug("Handler get gov state by hash", "t", tt)
+				s, err := pm.blockchain.GetGovStateByHash(header.Hash())
+				log.Debug("Handler get gov state by hash", "elapsed", time.Since(tt))
+				if err != nil {
+					log.Warn("Get gov state by hash fail", "number", header.Number.Uint64(), "err", err)
+					return []*types.HeaderWithGovState{}
+				}
+				header.GovState = s
+			}
+			log.Trace("Send header", "round", header.Round, "number", header.Number.Uint64(), "gov state == nil", header.GovState == nil)
+		}
+	}
+	return headers
+}
+
 // BroadcastBlock will either propagate a block to a subset of it's peers, or
 // will only announce it's availability (depending what's requested).
 func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 	hash := block.Hash()
-	peers := pm.peers.PeersWithoutBlock(hash)
+	// Notary peers of the block's round and the next one come first, so the
+	// propagated subset reaches the nodes running the agreement.
+	notaries, others := pm.peers.SplitNotaries(pm.peers.PeersWithoutBlock(hash),
+		block.Round(), block.Round()+1)
 
 	// If propagation is requested, send to a subset of the peer
 	if propagate {
@@ -1050,7 +1426,7 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 			return
 		}
 		// Send the block to a subset of our peers
-		transfer := peers[:int(math.Sqrt(float64(len(peers))))]
+		transfer := pm.blockFanout.pick(notaries, others)
 		for _, peer := range transfer {
 			peer.AsyncSendNewBlock(block)
 		}
@@ -1059,6 +1435,7 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 	}
 	// Otherwise if the block is indeed in out own chain, announce it
 	if pm.blockchain.HasBlock(hash, block.NumberU64()) {
+		peers := append(notaries, others...)
 		for _, peer := range peers {
 			peer.AsyncSendNewBlockHash(block)
 		}
@@ -1066,8 +1443,30 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 	}
 }
 
+// addRemoteTxs marks the transactions received from p as known by it,
+// records their arrival and adds them to the pool.
+func (pm *ProtocolManager) addRemoteTxs(p *peer, txs []*types.Transaction) error {
+	for i, tx := range txs {
+		// Validate and mark the remote transaction
+		if tx == nil {
+			return errResp(ErrDecode, "transaction %d is nil", i)
+		}
+		p.MarkTransaction(tx.Hash())
+	}
+	origin := TxOriginPeer
+	if pm.peers.IsNotary(p.ID()) {
+		origin = TxOriginDirect
+	}
+	pm.txProps.seen(txs, origin, p.id)
+	types.GlobalSigCache.Add(types.NewEIP155Signer(pm.blockchain.Config().ChainID), txs)
+	pm.txpool.AddRemotes(txs)
+	return nil
+}
+
 // BroadcastTxs will propagate a batch of transactions to all peers which are not known to
-// already have the given transaction.
+// already have the given transaction. Peers running dex/65 outside the notary set are
+// only announced the hashes of the transactions, which they fetch if unknown, unless
+// the tx-announcements feature is disabled.
 func (pm *ProtocolManager) BroadcastTxs(txs types.Transactions) {
 	round := pm.blockchain.CurrentBlock().Round()
 	label := peerLabel{
@@ -1122,11 +1521,99 @@ func (pm *ProtocolManager) BroadcastTxs(txs types.Transactions) {
 		for peer := range receivers {
 			txset[peer] = append(txset[peer], tx)
 		}
+		pm.txProps.relayed(tx.Hash(), len(receivers))
 		log.Trace("Broadcast transaction", "hash", tx.Hash(), "recipients", len(receivers))
 	}
 
+	// Notary peers need the transactions to propose them and get them in
+	// full, while the other peers able to fetch them get the hashes only.
+	notaries := make(map[*peer]struct{}, len(notaryPeers))
+	for _, peer := range notaryPeers {
+		notaries[peer] = struct{}{}
+	}
 	for peer, txs := range txset {
-		peer.AsyncSendTransactions(txs)
+		if _, notary := notaries[peer]; notary || peer.version < dex65 || !txAnnouncements.Enabled() {
+			peer.AsyncSendTransactions(txs)
+			continue
+		}
+		hashes := make([]common.Hash, len(txs))
+		for i, tx := range txs {
+			hashes[i] = tx.Hash()
+		}
+		peer.AsyncSendPooledTransactionHashes(hashes)
+	}
+}
+
+// governanceCriticalMethods are the governance methods that have to be
+// included before a DKG phase deadline.
+var governanceCriticalMethods = map[string]struct{}{
+	"proposeCRS":            {},
+	"addDKGMasterPublicKey": {},
+	"addDKGMPKReady":        {},
+	"addDKGComplaint":       {},
+	"addDKGFinalize":        {},
+	"addDKGSuccess":         {},
+	"resetDKG":              {},
+}
+
+// isGovernanceCriticalTx returns whether tx calls one of the DKG/CRS methods
+// of the governance contract.
+func isGovernanceCriticalTx(tx *types.Transaction) bool {
+	to := tx.To()
+	if to == nil || *to != vm.GovernanceContractAddress || len(tx.Data()) < 4 {
+		return false
+	}
+	method, ok := vm.GovernanceABI.Sig2Method[string(tx.Data()[:4])]
+	if !ok {
+		return false
+	}
+	_, ok = governanceCriticalMethods[method.Name]
+	return ok
+}
+
+// BroadcastGovernanceTxs propagates governance-critical transactions without
+// batching. They are sent to every notary peer and to a larger share of the
+// other peers than regular transactions.
+func (pm *ProtocolManager) BroadcastGovernanceTxs(txs types.Transactions) {
+	label := peerLabel{
+		set:   notaryset,
+		round: pm.blockchain.CurrentBlock().Round(),
+	}
+	notaryPeers := pm.peers.PeersWithLabel(label)
+
+	// Shuffle the other peers so that the share sent to is not biased by
+	// the map iteration order.
+	peers := pm.peers.PeersWithoutLabel(label)
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	maxReceiver := int(float64(len(peers)) * govTxReceiverRatio)
+	if maxReceiver < minTxReceiver {
+		maxReceiver = minTxReceiver
+	}
+	if maxReceiver > len(peers) {
+		maxReceiver = len(peers)
+	}
+
+	var txset = make(map[*peer]types.Transactions)
+	for _, tx := range txs {
+		receivers := 0
+		for _, peer := range notaryPeers {
+			if !peer.knownTxs.Contains(tx.Hash()) {
+				txset[peer] = append(txset[peer], tx)
+				receivers++
+			}
+		}
+		for _, peer := range peers[:maxReceiver] {
+			if !peer.knownTxs.Contains(tx.Hash()) {
+				txset[peer] = append(txset[peer], tx)
+				receivers++
+			}
+		}
+		pm.txProps.relayed(tx.Hash(), receivers)
+		log.Trace("Broadcast governance transaction", "hash", tx.Hash(), "recipients", receivers)
+	}
+
+	for peer, txs := range txset {
+		peer.AsyncSendGovernanceTransactions(txs)
 	}
 }
 
@@ -1136,8 +1623,13 @@ func (pm *ProtocolManager) BroadcastFinalizedBlock(block *coreTypes.Block) {
 		log.Warn("Ignore broadcast finalized block without randomness", "block", block)
 		return
 	}
-	pm.cache.addFinalizedBlock(block)
+	pm.cache.putFinalizedBlock(block)
 
+	enc, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		log.Error("Failed to encode finalized block", "block", block, "err", err)
+		return
+	}
 	// send to notary nodes first (direct)
 	label := peerLabel{
 		set:   notaryset,
@@ -1150,36 +1642,73 @@ func (pm *ProtocolManager) BroadcastFinalizedBlock(block *coreTypes.Block) {
 			break
 		} else {
 			count--
-			peer.AsyncSendCoreBlocks([]*coreTypes.Block{block})
+			peer.AsyncSendCoreBlocks([]rlp.RawValue{enc})
 		}
 	}
 }
 
-// BroadcastCoreBlock broadcasts the core block to all its peers.
+// BroadcastCoreBlock broadcasts the core block to the peers picked by the
+// core block fanout, all peers in the notary set of its round by default.
 func (pm *ProtocolManager) BroadcastCoreBlock(block *coreTypes.Block) {
 	pm.cache.addBlock(block)
-	// send to notary nodes only.
-	label := peerLabel{
-		set:   notaryset,
-		round: block.Position.Round,
+
+	// The consensus core processes the blocks it proposes in memory before
+	// broadcasting them, so they are never decoded back; encoding is the only
+	// serialization on the local path. The block is encoded once here rather
+	// than by the broadcast loop of every peer, as it is on the critical path
+	// of the agreement.
+	enc, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		log.Error("Failed to encode core block", "block", block, "err", err)
+		return
 	}
-	for _, peer := range pm.peers.PeersWithLabel(label) {
-		peer.AsyncSendCoreBlocks([]*coreTypes.Block{block})
+	for _, peer := range pm.fanoutPeers(pm.coreBlockFanout, block.Position.Round) {
+		peer.AsyncSendCoreBlocks([]rlp.RawValue{enc})
 	}
 }
 
-// BroadcastVote broadcasts the given vote to all peers in same notary set
+// BroadcastVote broadcasts the given vote to the peers picked by the vote
+// fanout, all peers in same notary set by default. The votes cast at the same position within voteBatchInterval are sent in
+// one message, and the votes already broadcast are dropped.
 func (pm *ProtocolManager) BroadcastVote(vote *coreTypes.Vote) {
-	if vote.Type >= coreTypes.VotePreCom {
-		pm.cache.addVote(vote)
+	if vote.Type >= coreTypes.VotePreCom && !pm.cache.addVote(vote) {
+		duplicateVoteMeter.Mark(1)
+		return
 	}
+	// Like blocks, votes reach the consensus core of this node in memory and
+	// are only encoded for the peers. The vote is encoded once here rather
+	// than by the broadcast loop of every peer, as it is on the critical path
+	// of the agreement.
+	enc, err := rlp.EncodeToBytes(vote)
+	if err != nil {
+		log.Error("Failed to encode vote", "vote", vote, "err", err)
+		return
+	}
+	if !pm.votes.add(vote, enc) {
+		duplicateVoteMeter.Mark(1)
+	}
+}
+
+// broadcastVotes sends the votes cast at pos to the peers picked by the vote
+// fanout, all peers in the notary set of its round by default.
+func (pm *ProtocolManager) broadcastVotes(pos coreTypes.Position, votes []rlp.RawValue) {
+	for _, peer := range pm.fanoutPeers(pm.voteFanout, pos.Round) {
+		peer.AsyncSendVotes(votes)
+	}
+}
+
+// fanoutPeers returns the peers picked by f to relay a consensus message of
+// round to.
+func (pm *ProtocolManager) fanoutPeers(f *fanout, round uint64) []*peer {
 	label := peerLabel{
 		set:   notaryset,
-		round: vote.Position.Round,
+		round: round,
 	}
-	for _, peer := range pm.peers.PeersWithLabel(label) {
-		peer.AsyncSendVotes([]*coreTypes.Vote{vote})
+	notaries := pm.peers.PeersWithLabel(label)
+	if f.notaryOnly() {
+		return notaries
 	}
+	return f.pick(notaries, pm.peers.PeersWithoutLabel(label))
 }
 
 func (pm *ProtocolManager) BroadcastAgreementResult(
@@ -1187,7 +1716,7 @@ func (pm *ProtocolManager) BroadcastAgreementResult(
 	block := pm.cache.blocks(coreCommon.Hashes{agreement.BlockHash}, false)
 	if len(block) != 0 {
 		block[0].Randomness = agreement.Randomness
-		pm.cache.addFinalizedBlock(block[0])
+		pm.cache.putFinalizedBlock(block[0])
 	}
 
 	// send to notary nodes first (direct)
@@ -1242,6 +1771,9 @@ func (pm *ProtocolManager) BroadcastDKGPrivateShare(
 
 func (pm *ProtocolManager) BroadcastDKGPartialSignature(
 	psig *dkgTypes.PartialSignature) {
+	// The consensus core already has its own partial signature, drop it if
+	// it comes back from peers.
+	pm.cache.addPartialSignature(psig)
 	label := peerLabel{set: notaryset, round: psig.Round}
 	for _, peer := range pm.peers.PeersWithLabel(label) {
 		peer.AsyncSendDKGPartialSignature(psig)
@@ -1284,10 +1816,18 @@ func (pm *ProtocolManager) txBroadcastLoop() {
 			txs = txs[:0]
 			currentSize = 0
 		case event := <-pm.txsCh:
-			txs = append(txs, event.Txs...)
+			var govTxs types.Transactions
 			for _, tx := range event.Txs {
+				if isGovernanceCriticalTx(tx) {
+					govTxs = append(govTxs, tx)
+					continue
+				}
+				txs = append(txs, tx)
 				currentSize += tx.Size()
 			}
+			if len(govTxs) > 0 {
+				pm.BroadcastGovernanceTxs(govTxs)
+			}
 			if currentSize >= queueSizeMax {
 				pm.BroadcastTxs(txs)
 				txs = txs[:0]
@@ -1349,10 +1889,18 @@ func (pm *ProtocolManager) peerSetLoop() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go func() {
+		notaryMesh := time.NewTicker(notaryMeshInterval)
+		defer notaryMesh.Stop()
+
 		for ctx.Err() == nil {
 			select {
 			case <-time.After(time.Minute):
 				pm.peers.Status()
+				pm.peers.updateNotaryConnMetrics()
+			case <-notaryMesh.C:
+				if pm.isBlockProposer {
+					pm.maintainNotaryMesh()
+				}
 			case <-ctx.Done():
 				return
 			}
@@ -1367,6 +1915,8 @@ func (pm *ProtocolManager) peerSetLoop() {
 			if !pm.isBlockProposer {
 				break
 			}
+			pm.preconnectNextRound(event.Block)
+			pm.replayDKGShares()
 
 			newRound := pm.gov.CRSRound()
 			if newRound == 0 {
@@ -1408,6 +1958,91 @@ func (pm *ProtocolManager) peerSetLoop() {
 	}
 }
 
+// bufferEarlyDKGShare buffers the private share if it belongs to a DKG the
+// node has not entered yet, the one of the round following the latest CRS or
+// a reset of the current one, and returns whether it was held back. Shares
+// further ahead are dropped.
+func (pm *ProtocolManager) bufferEarlyDKGShare(p *peer, ps *dkgTypes.PrivateShare) bool {
+	round := pm.gov.CRSRound()
+	if ps.Round < round || (ps.Round == round && ps.Reset <= pm.gov.DKGResetCount(round)) {
+		return false
+	}
+	if ps.Round > round+1 {
+		earlyDKGShareDropMeter.Mark(1)
+		p.Log().Debug("Dropped DKG private share too far ahead",
+			"round", ps.Round, "reset", ps.Reset, "crsRound", round)
+		return true
+	}
+	if !pm.dkgShares.add(p.ID().String(), ps) {
+		earlyDKGShareDropMeter.Mark(1)
+		p.Log().Debug("DKG private share buffer full",
+			"round", ps.Round, "reset", ps.Reset)
+		return true
+	}
+	earlyDKGShareBufferMeter.Mark(1)
+	return true
+}
+
+// replayDKGShares passes the buffered private shares of the DKG runs the node
+// has entered to the consensus core.
+func (pm *ProtocolManager) replayDKGShares() {
+	if pm.dkgShares.len() == 0 {
+		return
+	}
+	round := pm.gov.CRSRound()
+	msgs := pm.dkgShares.pop(round, pm.gov.DKGResetCount(round))
+	if len(msgs) == 0 {
+		return
+	}
+	log.Debug("Replaying early DKG private shares", "count", len(msgs),
+		"round", round)
+	earlyDKGShareReplayMeter.Mark(int64(len(msgs)))
+	for i := range msgs {
+		pm.sendCoreMsg(&msgs[i])
+	}
+}
+
+// preconnectNextRound builds the connections to the notary set of the round
+// following head's once head is close enough to the end of its round, so the
+// first agreement period of the new round does not wait for connections to be
+// set up. The connections are normally built when the CRS of the next round
+// is proposed, this retries them if that failed.
+func (pm *ProtocolManager) preconnectNextRound(head *types.Block) {
+	if pm.notaryPreconnectBlocks == 0 {
+		return
+	}
+	round := head.Round()
+	next := round + 1
+	if pm.gov.CRSRound() < next || pm.peers.HasConnection(next) {
+		return
+	}
+	end := pm.gov.GetRoundHeight(round) + pm.gov.Configuration(round).RoundLength
+	if head.NumberU64()+pm.notaryPreconnectBlocks < end {
+		return
+	}
+	log.Debug("Preconnecting to next round notary set", "round", next,
+		"number", head.NumberU64(), "end", end)
+	pm.peers.BuildConnection(next)
+}
+
+// maintainNotaryMesh rebuilds the connections to the notary sets of the
+// current round and, once its CRS is known, the next one if they are missing,
+// and redials the group connections of the sets whose members are not
+// connected.
+func (pm *ProtocolManager) maintainNotaryMesh() {
+	rounds := []uint64{pm.gov.Round()}
+	if next := rounds[0] + 1; pm.gov.CRSRound() >= next {
+		rounds = append(rounds, next)
+	}
+	for _, round := range rounds {
+		if !pm.peers.HasConnection(round) {
+			log.Debug("Rebuilding notary set connection", "round", round)
+			pm.peers.BuildConnection(round)
+		}
+	}
+	pm.peers.EnsureGroupConn()
+}
+
 // NodeInfo represents a short summary of the Ethereum sub-protocol metadata
 // known about the host peer.
 type NodeInfo struct {
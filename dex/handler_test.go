@@ -17,6 +17,7 @@
 package dex
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"math"
@@ -25,10 +26,15 @@ import (
 	"net"
 	"testing"
 
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreCrypto "github.com/portto/tangerine-consensus/core/crypto"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/core"
 	"github.com/portto/go-tangerine/core/state"
 	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/core/vm"
 	"github.com/portto/go-tangerine/crypto"
 	"github.com/portto/go-tangerine/dex/downloader"
 	"github.com/portto/go-tangerine/ethdb"
@@ -479,7 +485,7 @@ func testHandlerWithConsoritum(t *testing.T, version int) {
 	peer := pm.newPeer(version, p2p.NewPeerWithEnode(node, "handlerTest", nil), pipenet1)
 
 	// try to call handle, and should get permission denied error
-	if err := pm.handle(peer); err != p2p.DiscPermissionDenied {
+	if err := pm.handle(context.Background(), peer); err != p2p.DiscPermissionDenied {
 		t.Errorf("Expect get DiscPermissionDenied, but get %v", err)
 	}
 
@@ -494,7 +500,7 @@ func testHandlerWithConsoritum(t *testing.T, version int) {
 	}
 	handleErr := make(chan error)
 	go func() {
-		handleErr <- pm2.handle(peer)
+		handleErr <- pm2.handle(context.Background(), peer)
 	}()
 	// do the handshake
 	msg, err := pipenet2.ReadMsg()
@@ -519,3 +525,54 @@ func testHandlerWithConsoritum(t *testing.T, version int) {
 		t.Errorf("err not match, expect: %s, but got: %s", expectError, err)
 	}
 }
+
+func TestIsGovernanceCriticalTx(t *testing.T) {
+	newTx := func(to common.Address, method string) *types.Transaction {
+		data := vm.GovernanceABI.Name2Method[method].Id()
+		return types.NewTransaction(0, to, big.NewInt(0), 100000, big.NewInt(1), data)
+	}
+	for _, method := range []string{"proposeCRS", "addDKGMasterPublicKey", "addDKGComplaint", "resetDKG"} {
+		if !isGovernanceCriticalTx(newTx(vm.GovernanceContractAddress, method)) {
+			t.Errorf("%s transaction not considered governance critical", method)
+		}
+	}
+	if isGovernanceCriticalTx(newTx(vm.GovernanceContractAddress, "stake")) {
+		t.Errorf("stake transaction considered governance critical")
+	}
+	if isGovernanceCriticalTx(newTx(common.Address{1}, "proposeCRS")) {
+		t.Errorf("transaction to other contract considered governance critical")
+	}
+}
+
+// Tests that core block payloads are checked against their payload hash,
+// and that empty blocks, which carry neither, are valid.
+func TestValidCoreBlockPayload(t *testing.T) {
+	payload := []byte{1, 2, 3}
+	tests := []struct {
+		block *coreTypes.Block
+		valid bool
+	}{
+		{&coreTypes.Block{
+			ProposerID:  coreTypes.NodeID{Hash: coreCommon.Hash{1}},
+			Payload:     payload,
+			PayloadHash: coreCrypto.Keccak256Hash(payload),
+		}, true},
+		{&coreTypes.Block{
+			ProposerID:  coreTypes.NodeID{Hash: coreCommon.Hash{1}},
+			Payload:     payload,
+			PayloadHash: coreCrypto.Keccak256Hash([]byte{4}),
+		}, false},
+		{&coreTypes.Block{
+			ProposerID: coreTypes.NodeID{Hash: coreCommon.Hash{1}},
+		}, false},
+		// Empty blocks have no proposer, payload and payload hash.
+		{&coreTypes.Block{}, true},
+		{&coreTypes.Block{Payload: payload}, false},
+		{&coreTypes.Block{PayloadHash: coreCrypto.Keccak256Hash(payload)}, false},
+	}
+	for i, tt := range tests {
+		if valid := validCoreBlockPayload(tt.block); valid != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want %v", i, valid, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+const (
+	// HealthLivePath is the path of the liveness probe.
+	HealthLivePath = "/health/live"
+
+	// HealthReadyPath is the path of the readiness probe.
+	HealthReadyPath = "/health/ready"
+)
+
+// HealthStatus is the response body of the health probes.
+type HealthStatus struct {
+	Ready       bool   `json:"ready"`
+	Synced      bool   `json:"synced"`
+	Proposer    bool   `json:"proposer"`
+	Proposing   bool   `json:"proposing"`
+	CoreSyncing bool   `json:"coreSyncing"`
+	BlockNumber uint64 `json:"blockNumber"`
+}
+
+// Health reports whether the node is ready to serve traffic: the chain is
+// synchronised and, if the node is a block proposer, the consensus core is
+// running.
+func (s *Tangerine) Health() *HealthStatus {
+	status := &HealthStatus{
+		Synced:      atomic.LoadUint32(&s.protocolManager.acceptTxs) == 1 && !s.protocolManager.downloader.Synchronising(),
+		Proposer:    s.config.BlockProposerEnabled,
+		Proposing:   s.bp.IsProposing(),
+		CoreSyncing: s.bp.IsCoreSyncing(),
+		BlockNumber: s.blockchain.CurrentBlock().NumberU64(),
+	}
+	status.Ready = status.Synced
+	if status.Proposer {
+		status.Ready = status.Ready && status.Proposing && !status.CoreSyncing
+	}
+	return status
+}
+
+// NewHealthHandler returns a http handler serving the liveness and readiness
+// probes of the node, which fail with 503 Service Unavailable until the node
+// is ready.
+func NewHealthHandler(dex *Tangerine) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(HealthLivePath, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc(HealthReadyPath, func(w http.ResponseWriter, r *http.Request) {
+		status := dex.Health()
+		w.Header().Set("Content-Type", "application/json")
+		if !status.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+	return mux
+}
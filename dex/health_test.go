@@ -0,0 +1,84 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/portto/go-tangerine/dex/downloader"
+)
+
+// Tests that the readiness probe fails until the node is synced and, for
+// block proposers, proposing, while the liveness probe always succeeds.
+func TestHealthProbes(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 4, nil, nil)
+	defer pm.Stop()
+
+	dex := &Tangerine{
+		config:          &Config{},
+		blockchain:      pm.blockchain,
+		protocolManager: pm,
+	}
+	dex.bp = NewBlockProposer(dex, nil, time.Time{})
+	server := httptest.NewServer(NewHealthHandler(dex))
+	defer server.Close()
+
+	probe := func(path string) (int, *HealthStatus) {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("failed to query %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		var status HealthStatus
+		if path == HealthReadyPath {
+			if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+				t.Fatalf("failed to decode status: %v", err)
+			}
+		}
+		return resp.StatusCode, &status
+	}
+
+	atomic.StoreUint32(&pm.acceptTxs, 0)
+	if code, status := probe(HealthReadyPath); code != http.StatusServiceUnavailable || status.Ready || status.Synced {
+		t.Errorf("unsynced node ready: %d %+v", code, status)
+	}
+	if code, _ := probe(HealthLivePath); code != http.StatusOK {
+		t.Errorf("liveness probe failed: %d", code)
+	}
+
+	atomic.StoreUint32(&pm.acceptTxs, 1)
+	code, status := probe(HealthReadyPath)
+	if code != http.StatusOK || !status.Ready || status.BlockNumber != 4 {
+		t.Errorf("synced node not ready: %d %+v", code, status)
+	}
+
+	// A block proposer is only ready once proposing.
+	dex.config.BlockProposerEnabled = true
+	if code, status := probe(HealthReadyPath); code != http.StatusServiceUnavailable || status.Ready {
+		t.Errorf("idle proposer ready: %d %+v", code, status)
+	}
+	atomic.StoreInt32(&dex.bp.proposing, 1)
+	if code, status := probe(HealthReadyPath); code != http.StatusOK || !status.Proposing {
+		t.Errorf("proposing node not ready: %d %+v", code, status)
+	}
+}
@@ -20,13 +20,17 @@
 package dex
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"math/big"
 	"net"
+	"reflect"
 	"sort"
 	"sync"
 	"testing"
 
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/consensus/ethash"
 	"github.com/portto/go-tangerine/core"
@@ -184,6 +188,19 @@ func (p *testTxPool) AddRemotes(txs []*types.Transaction) []error {
 	return make([]error, len(txs))
 }
 
+// Get returns the transaction of the given hash, nil if not in the pool.
+func (p *testTxPool) Get(hash common.Hash) *types.Transaction {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	for _, tx := range p.pool {
+		if tx.Hash() == hash {
+			return tx
+		}
+	}
+	return nil
+}
+
 // Pending returns all the transactions known to the pool
 func (p *testTxPool) Pending() (map[common.Address]types.Transactions, error) {
 	p.lock.RLock()
@@ -216,6 +233,7 @@ type testGovernance struct {
 	lenCRSFunc    func() uint64
 	notarySetFunc func(uint64) (map[string]struct{}, error)
 	dkgSetFunc    func(uint64) (map[string]struct{}, error)
+	roundLength   uint64
 }
 
 func (g *testGovernance) Round() uint64 {
@@ -242,7 +260,11 @@ func (g *testGovernance) DKGSet(round uint64) (map[string]struct{}, error) {
 }
 
 func (g *testGovernance) GetRoundHeight(round uint64) uint64 {
-	return 0
+	return round * g.roundLength
+}
+
+func (g *testGovernance) Configuration(round uint64) *coreTypes.Config {
+	return &coreTypes.Config{RoundLength: g.roundLength}
 }
 
 // testPeer is a simulated peer to allow testing direct network calls.
@@ -271,7 +293,7 @@ func newTestPeer(name string, version int, pm *ProtocolManager, shake bool) (*te
 	go func() {
 		select {
 		case pm.newPeerCh <- peer:
-			errc <- pm.handle(peer)
+			errc <- pm.handle(context.Background(), peer)
 		case <-pm.quitSync:
 			errc <- p2p.DiscQuitting
 		}
@@ -292,6 +314,10 @@ func newTestPeer(name string, version int, pm *ProtocolManager, shake bool) (*te
 // handshake simulates a trivial handshake that expects the same state from the
 // remote side as we are simulating locally.
 func (p *testPeer) handshake(t *testing.T, number uint64, head common.Hash, genesis common.Hash) {
+	if p.version >= dex65 {
+		p.handshake65(t, number, head, genesis)
+		return
+	}
 	msg := &statusData{
 		ProtocolVersion: uint32(p.version),
 		NetworkId:       DefaultConfig.NetworkId,
@@ -307,6 +333,36 @@ func (p *testPeer) handshake(t *testing.T, number uint64, head common.Hash, gene
 	}
 }
 
+// handshake65 is handshake for dex/65, whose status carries a challenge picked
+// randomly by the remote side, so we can only compare the rest of it.
+func (p *testPeer) handshake65(t *testing.T, number uint64, head common.Hash, genesis common.Hash) {
+	msg := &statusData65{
+		ProtocolVersion: uint32(p.version),
+		NetworkId:       DefaultConfig.NetworkId,
+		Number:          number,
+		CurrentBlock:    head,
+		GenesisBlock:    genesis,
+	}
+	recv, err := p.app.ReadMsg()
+	if err != nil {
+		t.Fatalf("status recv: %v", err)
+	}
+	var status statusData65
+	if recv.Code != StatusMsg {
+		t.Fatalf("status recv: code %x (!= %x)", recv.Code, StatusMsg)
+	}
+	if err := recv.Decode(&status); err != nil {
+		t.Fatalf("status recv: %v", err)
+	}
+	status.Challenge = common.Hash{}
+	if !reflect.DeepEqual(&status, msg) {
+		t.Fatalf("status recv: have %+v, want %+v", status, msg)
+	}
+	if err := p2p.Send(p.app, StatusMsg, msg); err != nil {
+		t.Fatalf("status send: %v", err)
+	}
+}
+
 // close terminates the local side of the peer, notifying the remote protocol
 // manager of termination.
 func (p *testPeer) close() {
@@ -0,0 +1,279 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/common/hexutil"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/event"
+	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/p2p"
+)
+
+const (
+	// Files within the instance directory holding the node key and the key
+	// a pending rotation switches to.
+	datadirNodeKey     = "nodekey"
+	datadirNextNodeKey = "nodekey.next"
+
+	keyRotationRoundChanSize = 16
+)
+
+var (
+	errRotationExternalSigner = errors.New("node key held by an external signer")
+	errRotationNoDataDir      = errors.New("node key rotation needs a data directory")
+	errNoPendingRotation      = errors.New("no pending node key rotation")
+)
+
+// PendingNodeKey is the node key a scheduled rotation switches to.
+type PendingNodeKey struct {
+	PublicKey      hexutil.Bytes  `json:"publicKey"`
+	NodeKeyAddress common.Address `json:"nodeKeyAddress"`
+}
+
+// keyRotator replaces the node key while the node process runs. A rotation is
+// scheduled by generating the next key, which the node owner registers by
+// calling replaceNodePublicKey of the governance contract. At the first round
+// whose governance state knows the next key, the node switches to it: the
+// consensus core is stopped, the signer, the p2p identity and the gas paying
+// address of the governance transactions change, and the core is synced
+// again in-process with the new identity.
+//
+// The rotation is not seamless: the consensus core fixes its node ID at
+// construction, so it cannot change keys while running, and restarting the
+// p2p server drops every peer. The node stops proposing until the new core
+// has synced and the peers have reconnected.
+//
+// The node sits out the DKG run while the switch was pending, as the old
+// key ran the consensus core then.
+type keyRotator struct {
+	dex      *Tangerine
+	keyFile  string // Node key file, empty without a data directory
+	nextFile string // Next node key file, empty without a data directory
+	srvr     *p2p.Server
+
+	lock sync.Mutex
+	next *ecdsa.PrivateKey // Key of the pending rotation, nil if none
+
+	roundCh  chan RoundChangeEvent
+	roundSub event.Subscription
+	quit     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func newKeyRotator(dex *Tangerine, keyFile, nextFile string) *keyRotator {
+	r := &keyRotator{
+		dex:      dex,
+		keyFile:  keyFile,
+		nextFile: nextFile,
+		quit:     make(chan struct{}),
+	}
+	if nextFile != "" && common.FileExist(nextFile) && !dex.signer.external() {
+		key, err := crypto.LoadECDSA(nextFile)
+		if err != nil {
+			log.Error("Failed to load pending node key", "file", nextFile, "err", err)
+		} else {
+			r.next = key
+			log.Info("Node key rotation pending", "next", crypto.PubkeyToAddress(key.PublicKey))
+		}
+	}
+	return r
+}
+
+func (r *keyRotator) Start(rounds *roundNotifier, srvr *p2p.Server) {
+	r.srvr = srvr
+	r.roundCh = make(chan RoundChangeEvent, keyRotationRoundChanSize)
+	r.roundSub = rounds.Subscribe(r.roundCh)
+	r.wg.Add(1)
+	go r.loop()
+}
+
+// Stop stops watching round changes and waits for a key switch in progress,
+// which restarts the p2p server and the block proposer, to complete.
+func (r *keyRotator) Stop() {
+	if r.roundSub != nil {
+		r.roundSub.Unsubscribe()
+	}
+	close(r.quit)
+	r.wg.Wait()
+}
+
+func (r *keyRotator) loop() {
+	defer r.wg.Done()
+
+	// The governance may have registered the next key while the node was
+	// down.
+	r.check(r.dex.blockchain.CurrentBlock().Round())
+	for {
+		select {
+		case ev := <-r.roundCh:
+			r.check(ev.NewRound)
+		case <-r.roundSub.Err():
+			return
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// Schedule schedules a rotation, generating the next node key. The key is
+// kept until the rotation so that it survives restarts. Scheduling again
+// returns the pending key.
+func (r *keyRotator) Schedule() (*PendingNodeKey, error) {
+	if r.dex.signer.external() {
+		return nil, errRotationExternalSigner
+	}
+	if r.nextFile == "" {
+		return nil, errRotationNoDataDir
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.next == nil {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		if err := crypto.SaveECDSA(r.nextFile, key); err != nil {
+			return nil, err
+		}
+		r.next = key
+		log.Info("Scheduled node key rotation", "next", crypto.PubkeyToAddress(key.PublicKey))
+	}
+	return pendingNodeKey(r.next), nil
+}
+
+// Pending returns the key of the pending rotation, nil if none.
+func (r *keyRotator) Pending() *PendingNodeKey {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.next == nil {
+		return nil
+	}
+	return pendingNodeKey(r.next)
+}
+
+// Cancel drops the pending rotation. Once the governance knows the next key
+// the rotation can no longer be cancelled.
+func (r *keyRotator) Cancel() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.next == nil {
+		return errNoPendingRotation
+	}
+	if err := os.Remove(r.nextFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	r.next = nil
+	log.Info("Cancelled node key rotation")
+	return nil
+}
+
+func pendingNodeKey(key *ecdsa.PrivateKey) *PendingNodeKey {
+	return &PendingNodeKey{
+		PublicKey:      crypto.FromECDSAPub(&key.PublicKey),
+		NodeKeyAddress: crypto.PubkeyToAddress(key.PublicKey),
+	}
+}
+
+// registered reports whether the governance state of round knows key.
+func (r *keyRotator) registered(round uint64, key *ecdsa.PublicKey) (bool, error) {
+	gs, err := r.dex.governance.GetConfigState(round)
+	if err != nil {
+		return false, err
+	}
+	return gs.NodesOffsetByNodeKeyAddress(crypto.PubkeyToAddress(*key)).Sign() >= 0, nil
+}
+
+// check switches to the next key if the governance state of round knows it.
+func (r *keyRotator) check(round uint64) {
+	r.lock.Lock()
+	if r.next == nil {
+		r.lock.Unlock()
+		return
+	}
+	ok, err := r.registered(round, &r.next.PublicKey)
+	if err != nil {
+		r.lock.Unlock()
+		log.Warn("Failed to check node key registration", "round", round, "err", err)
+		return
+	}
+	if !ok {
+		r.lock.Unlock()
+		return
+	}
+	key, err := r.commit()
+	r.lock.Unlock()
+	if err != nil {
+		log.Error("Failed to store rotated node key", "round", round, "err", err)
+		return
+	}
+	// The switch restarts the p2p server and the consensus core, which takes
+	// a while, so it runs without the lock.
+	if err := r.switchKey(key); err != nil {
+		log.Error("Failed to rotate node key", "round", round, "err", err)
+		return
+	}
+	log.Warn("Rotated node key", "round", round, "address", crypto.PubkeyToAddress(key.PublicKey),
+		"file", r.keyFile)
+}
+
+// commit stores the next key as the node key, so that a restart picks it up
+// whatever happens, and clears the pending rotation. The caller must hold
+// the lock.
+func (r *keyRotator) commit() (*ecdsa.PrivateKey, error) {
+	key := r.next
+	if err := crypto.SaveECDSA(r.keyFile, key); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(r.nextFile); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	r.next = nil
+	return key, nil
+}
+
+// switchKey makes the node use key. The consensus core signs with the node
+// signer under the node ID it was created with, so it is stopped before the
+// signer changes and a new one is synced with the new identity afterwards.
+func (r *keyRotator) switchKey(key *ecdsa.PrivateKey) error {
+	return r.dex.bp.Restart(func() error {
+		address := crypto.PubkeyToAddress(key.PublicKey)
+		r.dex.signer.swap(NewLocalSigner(key))
+		r.dex.alerter.setIdentity(address, hex.EncodeToString(crypto.FromECDSAPub(&key.PublicKey)))
+		r.dex.governance.nonceManager.setAddress(address)
+
+		// Peers know the node by its node key, so the p2p identity follows it.
+		r.srvr.Stop()
+		r.srvr.PrivateKey = key
+		if err := r.srvr.Start(); err != nil {
+			return err
+		}
+		r.dex.protocolManager.peers.RebuildConnections()
+		return nil
+	})
+}
@@ -0,0 +1,176 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/p2p"
+	"github.com/portto/go-tangerine/p2p/enode"
+)
+
+// Tests that a node key rotation is kept across restarts until cancelled,
+// and is not switched to before the governance knows the next key.
+func TestKeyRotationSchedule(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+	dex.signer = newRotatingSigner(NewLocalSigner(key))
+
+	dir, err := ioutil.TempDir("", "keyrotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, nextFile := filepath.Join(dir, datadirNodeKey), filepath.Join(dir, datadirNextNodeKey)
+
+	r := newKeyRotator(dex, keyFile, nextFile)
+	if pending := r.Pending(); pending != nil {
+		t.Fatalf("unexpected pending rotation: %+v", pending)
+	}
+	next, err := r.Schedule()
+	if err != nil {
+		t.Fatalf("failed to schedule rotation: %v", err)
+	}
+	if again, err := r.Schedule(); err != nil || again.NodeKeyAddress != next.NodeKeyAddress {
+		t.Fatalf("rescheduling mismatch: have %v, %v, want %x", again, err, next.NodeKeyAddress)
+	}
+	if !common.FileExist(nextFile) {
+		t.Fatalf("next node key not stored")
+	}
+
+	// Only the current node key is known to the governance.
+	if ok, err := r.registered(0, &key.PublicKey); err != nil || !ok {
+		t.Fatalf("node key not registered: %v", err)
+	}
+	r.check(0)
+	if pending := r.Pending(); pending == nil || pending.NodeKeyAddress != next.NodeKeyAddress {
+		t.Fatalf("rotation switched before registration")
+	}
+	if common.FileExist(keyFile) {
+		t.Fatalf("node key overwritten before registration")
+	}
+
+	// The pending rotation survives restarts.
+	r = newKeyRotator(dex, keyFile, nextFile)
+	if pending := r.Pending(); pending == nil || !bytes.Equal(pending.PublicKey, next.PublicKey) {
+		t.Fatalf("pending rotation mismatch: have %+v, want %+v", pending, next)
+	}
+	if err := r.Cancel(); err != nil {
+		t.Fatalf("failed to cancel rotation: %v", err)
+	}
+	if common.FileExist(nextFile) {
+		t.Fatalf("next node key kept after cancel")
+	}
+	if err := r.Cancel(); err != errNoPendingRotation {
+		t.Fatalf("error mismatch: have %v, want %v", err, errNoPendingRotation)
+	}
+
+	// Keys held by an external signer are rotated there.
+	dex.signer.swap(&externalSigner{pub: &key.PublicKey})
+	if _, err := r.Schedule(); err != errRotationExternalSigner {
+		t.Fatalf("error mismatch: have %v, want %v", err, errRotationExternalSigner)
+	}
+}
+
+// Tests that switching to the next node key changes the identity of the
+// node everywhere it is used, and that stopping the rotator waits for its
+// loop.
+func TestKeyRotationSwitch(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+	dex.config = &Config{}
+	dex.signer = newRotatingSigner(NewLocalSigner(key))
+	dex.alerter = newAlerter(AlertConfig{}, crypto.PubkeyToAddress(key.PublicKey), "", nil)
+	dex.protocolManager = &ProtocolManager{peers: newPeerSet(&testGovernance{}, newTestP2PServer(key))}
+	dex.bp = NewBlockProposer(dex, nil, time.Time{})
+
+	dir, err := ioutil.TempDir("", "keyrotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, nextFile := filepath.Join(dir, datadirNodeKey), filepath.Join(dir, datadirNextNodeKey)
+
+	srvr := &p2p.Server{Config: p2p.Config{PrivateKey: key, NoDiscovery: true, NoDial: true}}
+	if err := srvr.Start(); err != nil {
+		t.Fatalf("failed to start p2p server: %v", err)
+	}
+	defer srvr.Stop()
+
+	r := newKeyRotator(dex, keyFile, nextFile)
+	r.Start(newRoundNotifier(dex.blockchain, dex.governance), srvr)
+	next, err := r.Schedule()
+	if err != nil {
+		t.Fatalf("failed to schedule rotation: %v", err)
+	}
+	nextKey, err := crypto.LoadECDSA(nextFile)
+	if err != nil {
+		t.Fatalf("failed to load next node key: %v", err)
+	}
+
+	r.lock.Lock()
+	nextKey, err = r.commit()
+	r.lock.Unlock()
+	if err != nil {
+		t.Fatalf("failed to store next node key: %v", err)
+	}
+	if err := r.switchKey(nextKey); err != nil {
+		t.Fatalf("failed to switch node key: %v", err)
+	}
+	r.Stop()
+
+	if pending := r.Pending(); pending != nil {
+		t.Errorf("rotation still pending: %+v", pending)
+	}
+	if common.FileExist(nextFile) {
+		t.Errorf("next node key kept after switch")
+	}
+	if stored, err := crypto.LoadECDSA(keyFile); err != nil || stored.D.Cmp(nextKey.D) != 0 {
+		t.Errorf("stored node key mismatch: %v", err)
+	}
+	if have := crypto.PubkeyToAddress(*dex.signer.PublicKey()); have != next.NodeKeyAddress {
+		t.Errorf("signer address mismatch: have %x, want %x", have, next.NodeKeyAddress)
+	}
+	if have, _ := dex.alerter.identity(); have != next.NodeKeyAddress {
+		t.Errorf("alert address mismatch: have %x, want %x", have, next.NodeKeyAddress)
+	}
+	if have := dex.governance.nonceManager.address; have != next.NodeKeyAddress {
+		t.Errorf("governance transaction sender mismatch: have %x, want %x", have, next.NodeKeyAddress)
+	}
+	if have, want := srvr.Self().ID(), enode.PubkeyToIDV4(&nextKey.PublicKey); have != want {
+		t.Errorf("p2p identity mismatch: have %v, want %v", have, want)
+	}
+	// The switch does not start a block proposer that was not running.
+	if atomic.LoadInt32(&dex.bp.running) != 0 {
+		t.Errorf("block proposer started by the switch")
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	dexCore "github.com/portto/tangerine-consensus/core"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/consensus"
+	"github.com/portto/go-tangerine/consensus/dexcon"
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/state"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/core/vm"
+	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/params"
+)
+
+var errLightFork = errors.New("light chain fork found")
+
+// lightChain is the header only chain of a light client. Instead of proof of
+// work, a header is only accepted if its randomness is the threshold signature
+// of the DKG group of its round, whose public key is derived from governance
+// states proven by the state roots of headers. It implements
+// downloader.LightChain.
+type lightChain struct {
+	db     ethdb.Database
+	config *params.ChainConfig
+	hc     *core.HeaderChain
+
+	chainmu sync.RWMutex
+
+	// govHead is the latest header with a stored governance state, the head
+	// of the governance of the light client.
+	govHead   *types.Header
+	govHeadMu sync.RWMutex
+
+	procInterrupt int32 // interrupt signaler for header processing
+}
+
+func newLightChain(db ethdb.Database, config *params.ChainConfig) (*lightChain, error) {
+	lc := &lightChain{
+		db:     db,
+		config: config,
+	}
+	hc, err := core.NewHeaderChain(db, config, dexcon.New(), lc.interrupted)
+	if err != nil {
+		return nil, err
+	}
+	lc.hc = hc
+
+	// The header chain restores the head block, a light chain only has headers.
+	if head := rawdb.ReadHeadHeaderHash(db); head != (common.Hash{}) {
+		if header := hc.GetHeaderByHash(head); header != nil {
+			hc.SetCurrentHeader(header)
+		}
+	}
+	lc.govHead = lc.lastGovHeader(hc.CurrentHeader())
+	log.Info("Loaded most recent local header", "number", hc.CurrentHeader().Number, "hash", hc.CurrentHeader().Hash())
+	return lc, nil
+}
+
+// lastGovHeader returns the latest header up to header with a stored
+// governance state, the genesis has the full genesis state.
+func (lc *lightChain) lastGovHeader(header *types.Header) *types.Header {
+	for header.Number.Uint64() > 0 && rawdb.ReadGovState(lc.db, header.Hash()) == nil {
+		parent := lc.hc.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+		if parent == nil {
+			// Headers before a checkpoint are missing.
+			return lc.Genesis()
+		}
+		header = parent
+	}
+	return header
+}
+
+func (lc *lightChain) interrupted() bool {
+	return atomic.LoadInt32(&lc.procInterrupt) == 1
+}
+
+// Stop aborts any header processing in progress.
+func (lc *lightChain) Stop() {
+	atomic.StoreInt32(&lc.procInterrupt, 1)
+
+	lc.chainmu.Lock()
+	defer lc.chainmu.Unlock()
+}
+
+// Config returns the chain configuration.
+func (lc *lightChain) Config() *params.ChainConfig { return lc.config }
+
+// Genesis returns the genesis header.
+func (lc *lightChain) Genesis() *types.Header { return lc.hc.GetHeaderByNumber(0) }
+
+// HasHeader checks if a header is present in the database or not.
+func (lc *lightChain) HasHeader(hash common.Hash, number uint64) bool {
+	return lc.hc.HasHeader(hash, number)
+}
+
+// GetHeaderByHash retrieves a header from the database by hash.
+func (lc *lightChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return lc.hc.GetHeaderByHash(hash)
+}
+
+// GetHeaderByNumber retrieves the canonical header by number.
+func (lc *lightChain) GetHeaderByNumber(number uint64) *types.Header {
+	return lc.hc.GetHeaderByNumber(number)
+}
+
+// CurrentHeader retrieves the head header of the light chain.
+func (lc *lightChain) CurrentHeader() *types.Header {
+	return lc.hc.CurrentHeader()
+}
+
+// GovHead returns the latest header with a stored governance state.
+func (lc *lightChain) GovHead() *types.Header {
+	lc.govHeadMu.RLock()
+	defer lc.govHeadMu.RUnlock()
+	return lc.govHead
+}
+
+// GetGovStateByNumber returns the governance state at the canonical header
+// number, which is only known for headers synced with their governance state
+// and for the genesis.
+func (lc *lightChain) GetGovStateByNumber(number uint64) (*types.GovState, error) {
+	header := lc.GetHeaderByNumber(number)
+	if header == nil {
+		return nil, fmt.Errorf("header not found")
+	}
+	if govState := rawdb.ReadGovState(lc.db, header.Hash()); govState != nil {
+		return govState, nil
+	}
+	statedb, err := lc.StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	return state.GetGovState(statedb, header, vm.GovernanceContractAddress)
+}
+
+// StateAt returns the state at root. Only the governance contract of headers
+// with a stored governance state and the genesis state are available.
+func (lc *lightChain) StateAt(root common.Hash) (*state.StateDB, error) {
+	return state.New(root, state.NewDatabase(lc.db))
+}
+
+// InsertTangerineHeaderChain verifies the headers of chain, including the
+// randomness of every one of them, and writes them into the light chain.
+func (lc *lightChain) InsertTangerineHeaderChain(chain []*types.HeaderWithGovState,
+	gov dexcon.GovernanceStateFetcher, verifierCache *dexCore.TSigVerifierCache) (int, error) {
+	if len(chain) == 0 {
+		return 0, nil
+	}
+	start := time.Now()
+	if i, err := lc.hc.ValidateTangerineHeaderChain(chain, gov, verifierCache, &lightValidator{lc}); err != nil {
+		return i, err
+	}
+	// The header chain only checks the randomness of a batch if its last
+	// header fails, without a state to execute every header must prove it
+	// was finalized by the consensus core.
+	for i, header := range chain {
+		if err := core.VerifyDexconMeta(header.Header, verifierCache); err != nil {
+			return i, err
+		}
+	}
+
+	lc.chainmu.Lock()
+	defer lc.chainmu.Unlock()
+
+	whFunc := func(header *types.HeaderWithGovState) error {
+		status, err := lc.hc.WriteTangerineHeader(header)
+		if err != nil {
+			return err
+		}
+		if status == core.SideStatTy {
+			log.Error("Inserted forked header", "number", header.Number, "hash", header.Hash())
+			return errLightFork
+		}
+		if header.GovState != nil {
+			lc.govHeadMu.Lock()
+			lc.govHead = header.Header
+			lc.govHeadMu.Unlock()
+		}
+		return nil
+	}
+	return lc.hc.InsertTangerineHeaderChain(chain, whFunc, start)
+}
+
+// InsertCheckpoint writes a verified, contiguous segment of headers ending at
+// a checkpoint into the empty light chain and makes the checkpoint its head.
+func (lc *lightChain) InsertCheckpoint(chain []*types.HeaderWithGovState) error {
+	lc.chainmu.Lock()
+	defer lc.chainmu.Unlock()
+
+	if err := lc.hc.InsertCheckpoint(chain); err != nil {
+		return err
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].GovState != nil {
+			lc.govHeadMu.Lock()
+			lc.govHead = chain[i].Header
+			lc.govHeadMu.Unlock()
+			break
+		}
+	}
+	head := chain[len(chain)-1]
+	log.Info("Inserted checkpoint headers", "count", len(chain),
+		"number", head.Number, "hash", head.Hash())
+	return nil
+}
+
+// Rollback removes a few recently added headers from the light chain.
+func (lc *lightChain) Rollback(chain []common.Hash) {
+	lc.chainmu.Lock()
+	defer lc.chainmu.Unlock()
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		hash := chain[i]
+
+		head := lc.hc.CurrentHeader()
+		if head.Hash() == hash {
+			lc.hc.SetCurrentHeader(lc.hc.GetHeader(head.ParentHash, head.Number.Uint64()-1))
+		}
+	}
+	lc.govHeadMu.Lock()
+	lc.govHead = lc.lastGovHeader(lc.hc.CurrentHeader())
+	lc.govHeadMu.Unlock()
+}
+
+// lightValidator validates the witness data of headers against the light
+// chain. A light client has no blocks or states, so only the witness checks
+// are available.
+type lightValidator struct {
+	lc *lightChain
+}
+
+func (v *lightValidator) ValidateBody(block *types.Block) error {
+	return errors.New("light chain has no block bodies")
+}
+
+func (v *lightValidator) ValidateState(block, parent *types.Block, state *state.StateDB, receipts types.Receipts, usedGas uint64) error {
+	return errors.New("light chain has no states")
+}
+
+func (v *lightValidator) ValidateWitnessData(height uint64, blockHash common.Hash) error {
+	header := v.lc.GetHeaderByNumber(height)
+	if header == nil {
+		log.Error("Witnessed header not found", "number", height)
+		return consensus.ErrWitnessMismatch
+	}
+	if header.Hash() != blockHash {
+		log.Error("Invalid witness block", "first", header.Hash().String(), "second", blockHash.String())
+		return consensus.ErrWitnessMismatch
+	}
+	return nil
+}
+
+// lightGovernanceStateDB reads the governance contract from the governance
+// states stored in the light chain. It implements core.GovernanceStateDB.
+type lightGovernanceStateDB struct {
+	lc *lightChain
+}
+
+func (g *lightGovernanceStateDB) State() (*state.StateDB, error) {
+	return g.lc.StateAt(g.lc.GovHead().Root)
+}
+
+func (g *lightGovernanceStateDB) StateAt(height uint64) (*state.StateDB, error) {
+	header := g.lc.GetHeaderByNumber(height)
+	if header == nil {
+		return nil, fmt.Errorf("header at %d not exists", height)
+	}
+	return g.lc.StateAt(header.Root)
+}
@@ -0,0 +1,482 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/common/hexutil"
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/dex/downloader"
+	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/event"
+	"github.com/portto/go-tangerine/internal/ethapi"
+	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/node"
+	"github.com/portto/go-tangerine/p2p"
+	"github.com/portto/go-tangerine/p2p/enode"
+	"github.com/portto/go-tangerine/params"
+	"github.com/portto/go-tangerine/rpc"
+)
+
+// lightSyncVersion is the protocol version light peers are registered with
+// in the downloader, their header retrieval follows the one of dex64.
+const lightSyncVersion = dex64
+
+// LightTangerine implements the Tangerine light client service. It follows
+// the chain by headers only, served by full nodes running the light server,
+// and trusts a header once its randomness verifies with the group public key
+// of the DKG of its round.
+type LightTangerine struct {
+	config      *Config
+	chainConfig *params.ChainConfig
+	networkID   uint64
+
+	chainDb    ethdb.Database
+	eventMux   *event.TypeMux
+	lightchain *lightChain
+	governance *core.Governance
+	downloader *downloader.Downloader
+
+	peers     map[string]*lightPeer
+	peersLock sync.RWMutex
+	maxPeers  int
+
+	syncCh        chan *lightPeer
+	quitSync      chan struct{}
+	wg            sync.WaitGroup
+	netRPCService *ethapi.PublicNetAPI
+}
+
+// NewLight creates a new Tangerine light client.
+func NewLight(ctx *node.ServiceContext, config *Config) (*LightTangerine, error) {
+	chainDb, err := CreateDB(ctx, config, "lightchaindata")
+	if err != nil {
+		return nil, err
+	}
+	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlock(chainDb,
+		config.Genesis)
+	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
+		return nil, genesisErr
+	}
+	log.Info("Initialised chain configuration", "config", chainConfig, "genesis", genesisHash)
+
+	lc, err := newLightChain(chainDb, chainConfig)
+	if err != nil {
+		return nil, err
+	}
+	l := &LightTangerine{
+		config:      config,
+		chainConfig: chainConfig,
+		networkID:   config.NetworkId,
+		chainDb:     chainDb,
+		eventMux:    ctx.EventMux,
+		lightchain:  lc,
+		governance:  core.NewGovernance(&lightGovernanceStateDB{lc}),
+		peers:       make(map[string]*lightPeer),
+		syncCh:      make(chan *lightPeer, 1),
+		quitSync:    make(chan struct{}),
+	}
+	l.downloader = downloader.New(downloader.LightSync, chainDb, l.eventMux, nil, lc, l.removePeer)
+	return l, nil
+}
+
+// Protocols implements node.Service, returning the light protocols.
+func (l *LightTangerine) Protocols() []p2p.Protocol {
+	protocols := make([]p2p.Protocol, 0, len(LightProtocolVersions))
+	for i, version := range LightProtocolVersions {
+		version := version // Closure for the run
+		protocols = append(protocols, p2p.Protocol{
+			Name:    LightProtocolName,
+			Version: version,
+			Length:  LightProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return l.handle(newLightPeer(int(version), p, rw))
+			},
+			NodeInfo: func() interface{} {
+				head := l.lightchain.CurrentHeader()
+				return &NodeInfo{
+					Network: l.networkID,
+					Number:  head.Number.Uint64(),
+					Genesis: l.lightchain.Genesis().Hash(),
+					Config:  l.chainConfig,
+					Head:    head.Hash(),
+				}
+			},
+			PeerInfo: func(id enode.ID) interface{} {
+				if p := l.peer(fmt.Sprintf("%x", id[:8])); p != nil {
+					return p.Info()
+				}
+				return nil
+			},
+		})
+	}
+	return protocols
+}
+
+// APIs implements node.Service, returning the RPC APIs of the light client.
+func (l *LightTangerine) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "tan",
+			Version:   "1.0",
+			Service:   NewPublicLightTangerineAPI(l),
+			Public:    true,
+		}, {
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   downloader.NewPublicDownloaderAPI(l.downloader, l.eventMux),
+			Public:    true,
+		}, {
+			Namespace: "net",
+			Version:   "1.0",
+			Service:   l.netRPCService,
+			Public:    true,
+		},
+	}
+}
+
+// Start implements node.Service, starting to sync headers from the light
+// servers connected.
+func (l *LightTangerine) Start(srvr *p2p.Server) error {
+	l.netRPCService = ethapi.NewPublicNetAPI(srvr, l.networkID)
+	l.maxPeers = srvr.MaxPeers
+
+	l.wg.Add(1)
+	go l.syncer()
+	return nil
+}
+
+// Stop implements node.Service, terminating the syncing and the light chain.
+func (l *LightTangerine) Stop() error {
+	close(l.quitSync)
+	l.downloader.Terminate()
+	l.wg.Wait()
+
+	l.lightchain.Stop()
+	l.chainDb.Close()
+	log.Info("Tangerine light client stopped")
+	return nil
+}
+
+// Downloader returns the downloader syncing the light chain.
+func (l *LightTangerine) Downloader() *downloader.Downloader { return l.downloader }
+
+func (l *LightTangerine) peer(id string) *lightPeer {
+	l.peersLock.RLock()
+	defer l.peersLock.RUnlock()
+	return l.peers[id]
+}
+
+// bestPeer returns the light server with the highest head.
+func (l *LightTangerine) bestPeer() *lightPeer {
+	l.peersLock.RLock()
+	defer l.peersLock.RUnlock()
+
+	var (
+		best       *lightPeer
+		bestNumber uint64
+	)
+	for _, p := range l.peers {
+		if _, number := p.Head(); best == nil || number > bestNumber {
+			best, bestNumber = p, number
+		}
+	}
+	return best
+}
+
+func (l *LightTangerine) removePeer(id string) {
+	l.peersLock.Lock()
+	p, ok := l.peers[id]
+	delete(l.peers, id)
+	l.peersLock.Unlock()
+	if !ok {
+		return
+	}
+	log.Debug("Removing light server", "peer", id)
+	l.downloader.UnregisterPeer(id)
+	p.Disconnect(p2p.DiscUselessPeer)
+}
+
+func (l *LightTangerine) handle(p *lightPeer) error {
+	l.peersLock.RLock()
+	full := len(l.peers) >= l.maxPeers
+	l.peersLock.RUnlock()
+	if full {
+		return p2p.DiscTooManyPeers
+	}
+	head := l.lightchain.CurrentHeader()
+	status, err := LightHandshake(p.rw, &LightStatusData{
+		ProtocolVersion: uint32(p.version),
+		NetworkId:       l.networkID,
+		Number:          head.Number.Uint64(),
+		CurrentBlock:    head.Hash(),
+		GenesisBlock:    l.lightchain.Genesis().Hash(),
+	})
+	if err != nil {
+		p.Log().Debug("Light handshake failed", "err", err)
+		return err
+	}
+	p.SetHead(status.CurrentBlock, status.Number)
+
+	l.peersLock.Lock()
+	if _, ok := l.peers[p.id]; ok {
+		l.peersLock.Unlock()
+		return p2p.DiscAlreadyConnected
+	}
+	l.peers[p.id] = p
+	l.peersLock.Unlock()
+	defer l.removePeer(p.id)
+
+	if err := l.downloader.RegisterLightPeer(p.id, lightSyncVersion, p); err != nil {
+		return err
+	}
+	p.Log().Debug("Light server connected", "number", status.Number)
+	l.requestSync(p)
+
+	for {
+		if err := l.handleMsg(p); err != nil {
+			p.Log().Debug("Light message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMsg delivers the responses and announcements of a light server.
+func (l *LightTangerine) handleMsg(p *lightPeer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case LightStatusMsg:
+		// Status messages should never arrive after the handshake
+		return errResp(ErrExtraStatusMsg, "uncontrolled status message")
+
+	case LightAnnounceMsg:
+		var announce LightAnnounceData
+		if err := msg.Decode(&announce); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		p.SetHead(announce.Hash, announce.Number)
+		l.requestSync(p)
+
+	case LightBlockHeadersMsg:
+		var headers []*types.HeaderWithGovState
+		if err := msg.Decode(&headers); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := l.downloader.DeliverHeaders(p.id, headers); err != nil {
+			log.Debug("Failed to deliver headers", "err", err)
+		}
+
+	case LightGovStateMsg:
+		var govState types.GovState
+		if err := msg.Decode(&govState); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := l.downloader.DeliverGovState(p.id, &govState); err != nil {
+			log.Debug("Failed to deliver govstates", "err", err)
+		}
+
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+	return nil
+}
+
+// requestSync asks the syncer to sync with p, unless a request is pending.
+func (l *LightTangerine) requestSync(p *lightPeer) {
+	select {
+	case l.syncCh <- p:
+	default:
+	}
+}
+
+// syncer syncs the light chain with the light servers announcing new heads,
+// and periodically with the best one.
+func (l *LightTangerine) syncer() {
+	defer l.wg.Done()
+
+	forceSync := time.NewTicker(forceSyncCycle)
+	defer forceSync.Stop()
+
+	for {
+		select {
+		case p := <-l.syncCh:
+			l.synchronise(p)
+		case <-forceSync.C:
+			l.synchronise(l.bestPeer())
+		case <-l.quitSync:
+			return
+		}
+	}
+}
+
+// synchronise syncs the light chain with p if p is ahead.
+func (l *LightTangerine) synchronise(p *lightPeer) {
+	if p == nil {
+		return
+	}
+	head, number := p.Head()
+	if number <= l.lightchain.CurrentHeader().Number.Uint64() {
+		return
+	}
+	l.downloader.Synchronise(p.id, head, number, downloader.LightSync)
+}
+
+// lightPeer is a light server connected to the light client. It implements
+// downloader.LightPeer.
+type lightPeer struct {
+	*p2p.Peer
+	rw      p2p.MsgReadWriter
+	id      string
+	version int
+
+	head   common.Hash
+	number uint64
+	lock   sync.RWMutex
+}
+
+func newLightPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *lightPeer {
+	id := p.ID()
+	return &lightPeer{
+		Peer:    p,
+		rw:      rw,
+		id:      fmt.Sprintf("%x", id[:8]),
+		version: version,
+	}
+}
+
+// Info gathers and returns a collection of metadata known about a peer.
+func (p *lightPeer) Info() *PeerInfo {
+	hash, number := p.Head()
+	return &PeerInfo{
+		Version: p.version,
+		Number:  number,
+		Head:    hash.Hex(),
+	}
+}
+
+// Head retrieves the latest head announced by the peer.
+func (p *lightPeer) Head() (common.Hash, uint64) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.head, p.number
+}
+
+// SetHead updates the head of the peer if it is higher than the known one.
+func (p *lightPeer) SetHead(hash common.Hash, number uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if number >= p.number {
+		p.head, p.number = hash, number
+	}
+}
+
+// RequestHeadersByHash fetches a batch of headers from the hash of an origin
+// block.
+func (p *lightPeer) RequestHeadersByHash(origin common.Hash, amount int, skip int, reverse, withGov bool) error {
+	p.Log().Debug("Fetching batch of light headers", "count", amount, "fromhash", origin, "skip", skip, "reverse", reverse, "withgov", withGov)
+	return p2p.Send(p.rw, LightGetBlockHeadersMsg, &LightHeadersQuery{Hash: origin, Amount: uint64(amount), Skip: uint64(skip), Reverse: reverse, WithGov: withGov})
+}
+
+// RequestHeadersByNumber fetches a batch of headers from the number of an
+// origin block.
+func (p *lightPeer) RequestHeadersByNumber(origin uint64, amount int, skip int, reverse, withGov bool) error {
+	p.Log().Debug("Fetching batch of light headers", "count", amount, "fromnum", origin, "skip", skip, "reverse", reverse, "withgov", withGov)
+	return p2p.Send(p.rw, LightGetBlockHeadersMsg, &LightHeadersQuery{Number: origin, Amount: uint64(amount), Skip: uint64(skip), Reverse: reverse, WithGov: withGov})
+}
+
+// RequestGovStateByHash fetches the governance state of a block along with
+// the proof of the governance contract account.
+func (p *lightPeer) RequestGovStateByHash(hash common.Hash) error {
+	p.Log().Debug("Fetching one light gov state", "hash", hash)
+	return p2p.Send(p.rw, LightGetGovStateMsg, hash)
+}
+
+// PublicLightTangerineAPI provides an API to access the chain followed by the
+// light client. Everything it returns has been verified by the light client.
+type PublicLightTangerineAPI struct {
+	l *LightTangerine
+}
+
+// NewPublicLightTangerineAPI creates a new light client API.
+func NewPublicLightTangerineAPI(l *LightTangerine) *PublicLightTangerineAPI {
+	return &PublicLightTangerineAPI{l: l}
+}
+
+// BlockNumber returns the number of the head header.
+func (api *PublicLightTangerineAPI) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(api.l.lightchain.CurrentHeader().Number.Uint64())
+}
+
+// GetHeaderByNumber returns the canonical header number.
+func (api *PublicLightTangerineAPI) GetHeaderByNumber(number rpc.BlockNumber) (*types.Header, error) {
+	var header *types.Header
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		header = api.l.lightchain.CurrentHeader()
+	} else {
+		header = api.l.lightchain.GetHeaderByNumber(uint64(number))
+	}
+	if header == nil {
+		return nil, fmt.Errorf("header #%d not found", number)
+	}
+	return header, nil
+}
+
+// GetCRS returns the CRS of round, known up to the CRS round of the latest
+// verified governance state.
+func (api *PublicLightTangerineAPI) GetCRS(round hexutil.Uint64) (common.Hash, error) {
+	crs := common.Hash(api.l.governance.CRS(uint64(round)))
+	if crs == (common.Hash{}) {
+		return common.Hash{}, fmt.Errorf("CRS of round %d not known", round)
+	}
+	return crs, nil
+}
+
+// GetNotarySet returns the public keys of the notary set of round.
+func (api *PublicLightTangerineAPI) GetNotarySet(round hexutil.Uint64) ([]string, error) {
+	// The governance panics on missing states, make sure the round is known.
+	if _, err := api.l.governance.GetConfigState(uint64(round)); err != nil {
+		return nil, fmt.Errorf("configuration of round %d not known: %v", round, err)
+	}
+	if common.Hash(api.l.governance.CRS(uint64(round))) == (common.Hash{}) {
+		return nil, fmt.Errorf("CRS of round %d not known", round)
+	}
+	set, err := api.l.governance.NotarySet(uint64(round))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
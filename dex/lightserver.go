@@ -0,0 +1,315 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync"
+	"time"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/p2p"
+	"github.com/portto/go-tangerine/p2p/enode"
+)
+
+// Constants to match up light protocol versions and messages
+const (
+	tanl1 = 1
+)
+
+// LightProtocolName is the short name of the protocol serving light clients.
+var LightProtocolName = "tanl"
+
+// LightProtocolVersions are the supported versions of the light protocol.
+var LightProtocolVersions = []uint{tanl1}
+
+// LightProtocolLengths are the number of implemented messages of the light
+// protocol versions.
+var LightProtocolLengths = []uint64{6}
+
+// light protocol message codes
+const (
+	LightStatusMsg          = 0x00
+	LightAnnounceMsg        = 0x01
+	LightGetBlockHeadersMsg = 0x02
+	LightBlockHeadersMsg    = 0x03
+	LightGetGovStateMsg     = 0x04
+	LightGovStateMsg        = 0x05
+)
+
+// LightStatusData is the network packet for the light protocol handshake.
+type LightStatusData struct {
+	ProtocolVersion uint32
+	NetworkId       uint64
+	Number          uint64
+	CurrentBlock    common.Hash
+	GenesisBlock    common.Hash
+}
+
+// LightAnnounceData is the network packet announcing a new chain head to
+// light clients.
+type LightAnnounceData struct {
+	Hash   common.Hash
+	Number uint64
+}
+
+// LightHeadersQuery is the network packet for a header query of a light
+// client. Headers are answered with the governance states of the rounds they
+// cover if WithGov is set, so the client can verify their randomness with the
+// group public key of the DKG of their rounds.
+type LightHeadersQuery struct {
+	Hash    common.Hash // Block hash from which to retrieve headers, Number is used if empty
+	Number  uint64      // Block number from which to retrieve headers
+	Amount  uint64      // Maximum number of headers to retrieve
+	Skip    uint64      // Blocks to skip between consecutive headers
+	Reverse bool        // Query direction (false = rising towards latest, true = falling towards genesis)
+	WithGov bool        // Whether to attach the governance states of the rounds
+}
+
+// LightHandshake exchanges status with the remote side of a light protocol
+// connection and checks that both follow the same chain.
+func LightHandshake(rw p2p.MsgReadWriter, status *LightStatusData) (*LightStatusData, error) {
+	errc := make(chan error, 2)
+	var remote LightStatusData // safe to read after two values have been received from errc
+
+	go func() {
+		errc <- p2p.Send(rw, LightStatusMsg, status)
+	}()
+	go func() {
+		errc <- readLightStatus(rw, status, &remote)
+	}()
+	timeout := time.NewTimer(handshakeTimeout)
+	defer timeout.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errc:
+			if err != nil {
+				return nil, err
+			}
+		case <-timeout.C:
+			return nil, p2p.DiscReadTimeout
+		}
+	}
+	return &remote, nil
+}
+
+func readLightStatus(rw p2p.MsgReadWriter, local, remote *LightStatusData) error {
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	defer msg.Discard()
+
+	if msg.Code != LightStatusMsg {
+		return errResp(ErrNoStatusMsg, "first msg has code %x (!= %x)", msg.Code, LightStatusMsg)
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	if err := msg.Decode(remote); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	if remote.GenesisBlock != local.GenesisBlock {
+		return errResp(ErrGenesisBlockMismatch, "%x (!= %x)", remote.GenesisBlock[:8], local.GenesisBlock[:8])
+	}
+	if remote.NetworkId != local.NetworkId {
+		return errResp(ErrNetworkIdMismatch, "%d (!= %d)", remote.NetworkId, local.NetworkId)
+	}
+	if remote.ProtocolVersion != local.ProtocolVersion {
+		return errResp(ErrProtocolVersionMismatch, "%d (!= %d)", remote.ProtocolVersion, local.ProtocolVersion)
+	}
+	return nil
+}
+
+// lightServer serves headers and governance state proofs to light clients
+// and announces new chain heads to them.
+type lightServer struct {
+	pm       *ProtocolManager
+	maxPeers int
+
+	peers map[string]p2p.MsgReadWriter
+	lock  sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newLightServer(pm *ProtocolManager, maxPeers int) *lightServer {
+	return &lightServer{
+		pm:       pm,
+		maxPeers: maxPeers,
+		peers:    make(map[string]p2p.MsgReadWriter),
+		quit:     make(chan struct{}),
+	}
+}
+
+// Protocols returns the light protocol versions served.
+func (s *lightServer) Protocols() []p2p.Protocol {
+	protocols := make([]p2p.Protocol, 0, len(LightProtocolVersions))
+	for i, version := range LightProtocolVersions {
+		version := version // Closure for the run
+		protocols = append(protocols, p2p.Protocol{
+			Name:    LightProtocolName,
+			Version: version,
+			Length:  LightProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return s.handle(int(version), p, rw)
+			},
+			NodeInfo: func() interface{} {
+				return s.pm.NodeInfo()
+			},
+			PeerInfo: func(id enode.ID) interface{} {
+				return nil
+			},
+		})
+	}
+	return protocols
+}
+
+// Start starts announcing new chain heads to light clients.
+func (s *lightServer) Start() {
+	s.wg.Add(1)
+	go s.announceLoop()
+}
+
+// Stop stops announcing new chain heads.
+func (s *lightServer) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+func (s *lightServer) handle(version int, p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	id := p.ID().String()
+	s.lock.Lock()
+	if len(s.peers) >= s.maxPeers {
+		s.lock.Unlock()
+		return p2p.DiscTooManyPeers
+	}
+	s.lock.Unlock()
+
+	var (
+		genesis = s.pm.blockchain.Genesis()
+		head    = s.pm.blockchain.CurrentHeader()
+	)
+	if _, err := LightHandshake(rw, &LightStatusData{
+		ProtocolVersion: uint32(version),
+		NetworkId:       s.pm.networkID,
+		Number:          head.Number.Uint64(),
+		CurrentBlock:    head.Hash(),
+		GenesisBlock:    genesis.Hash(),
+	}); err != nil {
+		p.Log().Debug("Light handshake failed", "err", err)
+		return err
+	}
+
+	s.lock.Lock()
+	if _, exist := s.peers[id]; exist {
+		s.lock.Unlock()
+		return p2p.DiscAlreadyConnected
+	}
+	s.peers[id] = rw
+	s.lock.Unlock()
+	p.Log().Debug("Light client connected")
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.peers, id)
+		s.lock.Unlock()
+		p.Log().Debug("Light client disconnected")
+	}()
+	for {
+		if err := s.handleMsg(p, rw); err != nil {
+			p.Log().Debug("Light message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMsg answers a request of a light client.
+func (s *lightServer) handleMsg(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case LightStatusMsg:
+		// Status messages should never arrive after the handshake
+		return errResp(ErrExtraStatusMsg, "uncontrolled status message")
+
+	case LightGetBlockHeadersMsg:
+		var query LightHeadersQuery
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		headers := s.pm.queryHeaders(p, getBlockHeadersData{
+			Origin:  hashOrNumber{Hash: query.Hash, Number: query.Number},
+			Amount:  query.Amount,
+			Skip:    query.Skip,
+			Reverse: query.Reverse,
+			WithGov: query.WithGov,
+		})
+		return p2p.Send(rw, LightBlockHeadersMsg, headers)
+
+	case LightGetGovStateMsg:
+		var hash common.Hash
+		if err := msg.Decode(&hash); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		govState, err := s.pm.blockchain.GetGovStateByHash(hash)
+		if err != nil {
+			return errResp(ErrInvalidGovStateMsg, "hash=%v", hash.String())
+		}
+		return p2p.Send(rw, LightGovStateMsg, govState)
+
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+}
+
+// announceLoop announces every new chain head to the light clients.
+func (s *lightServer) announceLoop() {
+	defer s.wg.Done()
+
+	ch := make(chan core.ChainHeadEvent, 10)
+	sub := s.pm.blockchain.SubscribeChainHeadEvent(ch)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-ch:
+			announce := &LightAnnounceData{Hash: ev.Block.Hash(), Number: ev.Block.NumberU64()}
+			s.lock.Lock()
+			for id, rw := range s.peers {
+				if err := p2p.Send(rw, LightAnnounceMsg, announce); err != nil {
+					log.Debug("Failed to announce head to light client", "peer", id, "err", err)
+				}
+			}
+			s.lock.Unlock()
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/dex/downloader"
+	"github.com/portto/go-tangerine/p2p"
+	"github.com/portto/go-tangerine/p2p/enode"
+)
+
+// newTestLightClient connects a light client pipe to s, returning the client
+// side of the pipe and the channel the server side reports its exit on.
+func newTestLightClient(s *lightServer, name string) (*p2p.MsgPipeRW, <-chan error) {
+	app, pipenet := p2p.MsgPipe()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+	node := enode.NewV4(&key.PublicKey, net.IP{}, 0, 0)
+	peer := p2p.NewPeerWithEnode(node, name, nil)
+
+	errc := make(chan error, 1)
+	go func() { errc <- s.handle(tanl1, peer, pipenet) }()
+	return app, errc
+}
+
+// Tests that the light server serves headers and governance states to light
+// clients after the handshake.
+func TestLightServer(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 16, nil, nil)
+	defer pm.Stop()
+	s := newLightServer(pm, 1)
+
+	app, errc := newTestLightClient(s, "light")
+	defer app.Close()
+
+	var (
+		genesis = pm.blockchain.Genesis()
+		head    = pm.blockchain.CurrentHeader()
+	)
+	status, err := LightHandshake(app, &LightStatusData{
+		ProtocolVersion: tanl1,
+		NetworkId:       DefaultConfig.NetworkId,
+		GenesisBlock:    genesis.Hash(),
+	})
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if status.Number != head.Number.Uint64() || status.CurrentBlock != head.Hash() {
+		t.Errorf("status head mismatch: have #%d %x, want #%d %x",
+			status.Number, status.CurrentBlock, head.Number.Uint64(), head.Hash())
+	}
+
+	// Headers are served for queries by number and by hash.
+	want := []*types.HeaderWithGovState{}
+	for i := uint64(2); i < 5; i++ {
+		want = append(want, &types.HeaderWithGovState{Header: pm.blockchain.GetHeaderByNumber(i)})
+	}
+	queries := []*LightHeadersQuery{
+		{Number: 2, Amount: 3},
+		{Hash: want[0].Hash(), Amount: 3},
+	}
+	for i, query := range queries {
+		if err := p2p.Send(app, LightGetBlockHeadersMsg, query); err != nil {
+			t.Fatalf("query %d: failed to send: %v", i, err)
+		}
+		if err := p2p.ExpectMsg(app, LightBlockHeadersMsg, want); err != nil {
+			t.Errorf("query %d: headers mismatch: %v", i, err)
+		}
+	}
+
+	// Governance states are served with the proof of their block.
+	if err := p2p.Send(app, LightGetGovStateMsg, head.Hash()); err != nil {
+		t.Fatalf("failed to send gov state request: %v", err)
+	}
+	msg, err := app.ReadMsg()
+	if err != nil {
+		t.Fatalf("failed to read gov state: %v", err)
+	}
+	if msg.Code != LightGovStateMsg {
+		t.Fatalf("message code mismatch: have %x, want %x", msg.Code, LightGovStateMsg)
+	}
+	var govState types.GovState
+	if err := msg.Decode(&govState); err != nil {
+		t.Fatalf("failed to decode gov state: %v", err)
+	}
+	if govState.Root != head.Root || govState.Number.Uint64() != head.Number.Uint64() {
+		t.Errorf("gov state mismatch: have #%d root %x, want #%d root %x",
+			govState.Number, govState.Root, head.Number, head.Root)
+	}
+	s.lock.Lock()
+	clients := len(s.peers)
+	s.lock.Unlock()
+	if clients != 1 {
+		t.Errorf("light client count mismatch: have %d, want 1", clients)
+	}
+
+	// Clients beyond the limit are rejected.
+	extra, extraErrc := newTestLightClient(s, "extra")
+	defer extra.Close()
+	select {
+	case err := <-extraErrc:
+		if err != p2p.DiscTooManyPeers {
+			t.Errorf("extra client error mismatch: have %v, want %v", err, p2p.DiscTooManyPeers)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("extra client not rejected")
+	}
+
+	// Requesting an unknown governance state drops the client.
+	if err := p2p.Send(app, LightGetGovStateMsg, common.Hash{1}); err != nil {
+		t.Fatalf("failed to send gov state request: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Errorf("client not dropped on unknown gov state")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("client not dropped on unknown gov state")
+	}
+}
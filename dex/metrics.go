@@ -17,6 +17,8 @@
 package dex
 
 import (
+	"time"
+
 	"github.com/portto/go-tangerine/metrics"
 	"github.com/portto/go-tangerine/p2p"
 )
@@ -79,8 +81,116 @@ var (
 	miscInTrafficMeter                     = metrics.NewRegisteredMeter("dex/misc/in/traffic", nil)
 	miscOutPacketsMeter                    = metrics.NewRegisteredMeter("dex/misc/out/packets", nil)
 	miscOutTrafficMeter                    = metrics.NewRegisteredMeter("dex/misc/out/traffic", nil)
+	futureCoreBlockRejectMeter             = metrics.NewRegisteredMeter("dex/coreblocks/future/reject", nil)
+	futureCoreBlockDeferMeter              = metrics.NewRegisteredMeter("dex/coreblocks/future/defer", nil)
+	invalidCoreBlockMeter                  = metrics.NewRegisteredMeter("dex/coreblocks/invalid", nil)
+	invalidVoteMeter                       = metrics.NewRegisteredMeter("dex/votes/invalid", nil)
+	duplicateVoteMeter                     = metrics.NewRegisteredMeter("dex/votes/duplicate", nil)
+	voteBatchSizeHistogram                 = metrics.NewRegisteredHistogram("dex/votes/batch", nil, metrics.NewExpDecaySample(1028, 0.015))
+	bannedPeerMeter                        = metrics.NewRegisteredMeter("dex/peers/banned", nil)
+	redundantDKGPartialSignatureMeter      = metrics.NewRegisteredMeter("dex/dkgpartialsignatures/redundant", nil)
+	earlyDKGShareBufferMeter               = metrics.NewRegisteredMeter("dex/dkgprivateshares/early/buffer", nil)
+	earlyDKGShareDropMeter                 = metrics.NewRegisteredMeter("dex/dkgprivateshares/early/drop", nil)
+	earlyDKGShareReplayMeter               = metrics.NewRegisteredMeter("dex/dkgprivateshares/early/replay", nil)
+	scrubCheckedMeter                      = metrics.NewRegisteredMeter("dex/scrub/checked", nil)
+	scrubCorruptedMeter                    = metrics.NewRegisteredMeter("dex/scrub/corrupted", nil)
+	scrubRepairedMeter                     = metrics.NewRegisteredMeter("dex/scrub/repaired", nil)
+	cacheVoteEvictMeter                    = metrics.NewRegisteredMeter("dex/cache/votes/evict", nil)
+	cacheBlockEvictMeter                   = metrics.NewRegisteredMeter("dex/cache/blocks/evict", nil)
+	cacheFinalizedBlockEvictMeter          = metrics.NewRegisteredMeter("dex/cache/finalizedblocks/evict", nil)
+	recoveryVotesHitMeter                  = metrics.NewRegisteredMeter("dex/recovery/votes/hit", nil)
+	recoveryVotesStaleMeter                = metrics.NewRegisteredMeter("dex/recovery/votes/stale", nil)
+	recoveryVotesMissMeter                 = metrics.NewRegisteredMeter("dex/recovery/votes/miss", nil)
+	recoveryVotesFailureMeter              = metrics.NewRegisteredMeter("dex/recovery/votes/failure", nil)
+	recoveryVotesFetchTimer                = metrics.NewRegisteredTimer("dex/recovery/votes/fetch", nil)
+	stateRootCheckedMeter                  = metrics.NewRegisteredMeter("dex/stateroot/checked", nil)
+	stateRootDivergenceMeter               = metrics.NewRegisteredMeter("dex/stateroot/divergence", nil)
+	notaryConnGauge                        = metrics.NewRegisteredGauge("dex/notary/conns", nil)
+	notaryConnMaxAgeGauge                  = metrics.NewRegisteredGauge("dex/notary/conns/maxage", nil)
+	notaryConnAgeHistogram                 = metrics.NewRegisteredHistogram("dex/notary/conns/age", nil, metrics.NewExpDecaySample(1028, 0.015))
+	notaryRekeyMeter                       = metrics.NewRegisteredMeter("dex/notary/rekey", nil)
+	roundGauge                             = metrics.NewRegisteredGauge("dex/round", nil)
+)
+
+// msgCodeNames are the names of the message codes used in handler metrics.
+var msgCodeNames = map[uint64]string{
+	StatusMsg:              "status",
+	NewBlockHashesMsg:      "newblockhashes",
+	TxMsg:                  "txs",
+	GetBlockHeadersMsg:     "getblockheaders",
+	BlockHeadersMsg:        "blockheaders",
+	GetBlockBodiesMsg:      "getblockbodies",
+	BlockBodiesMsg:         "blockbodies",
+	NewBlockMsg:            "newblock",
+	GetNodeDataMsg:         "getnodedata",
+	NodeDataMsg:            "nodedata",
+	GetReceiptsMsg:         "getreceipts",
+	ReceiptsMsg:            "receipts",
+	CoreBlockMsg:           "coreblock",
+	VoteMsg:                "vote",
+	AgreementMsg:           "agreement",
+	DKGPrivateShareMsg:     "dkgprivateshare",
+	DKGPartialSignatureMsg: "dkgpartialsignature",
+	PullBlocksMsg:          "pullblocks",
+	PullVotesMsg:           "pullvotes",
+	GetGovStateMsg:         "getgovstate",
+	GovStateMsg:            "govstate",
+	NotaryClaimMsg:         "notaryclaim",
+	StateRootMsg:           "stateroot",
+
+	NewPooledTransactionHashesMsg: "newpooledtxhashes",
+	GetPooledTransactionsMsg:      "getpooledtxs",
+	PooledTransactionsMsg:         "pooledtxs",
+}
+
+// msgCodeName returns the name of a message code, "misc" if unknown.
+func msgCodeName(code uint64) string {
+	if name, ok := msgCodeNames[code]; ok {
+		return name
+	}
+	return "misc"
+}
+
+// handlerTimers measure the time spent by messages of a code waiting to be
+// handled after being read from the network and being handled.
+type handlerTimers struct {
+	wait   metrics.Timer
+	handle metrics.Timer
+}
+
+var (
+	handlerTimersByCode = newHandlerTimers()
+	miscHandlerTimers   = handlerTimers{
+		wait:   metrics.NewRegisteredTimer("dex/handle/misc/wait", nil),
+		handle: metrics.NewRegisteredTimer("dex/handle/misc/latency", nil),
+	}
 )
 
+func newHandlerTimers() map[uint64]handlerTimers {
+	timers := make(map[uint64]handlerTimers, len(msgCodeNames))
+	for code, name := range msgCodeNames {
+		timers[code] = handlerTimers{
+			wait:   metrics.NewRegisteredTimer("dex/handle/"+name+"/wait", nil),
+			handle: metrics.NewRegisteredTimer("dex/handle/"+name+"/latency", nil),
+		}
+	}
+	return timers
+}
+
+// timeHandleMsg records the queue wait of a message received at receivedAt
+// and returns a function recording its handling latency when called.
+func timeHandleMsg(code uint64, receivedAt time.Time) func() {
+	timers, ok := handlerTimersByCode[code]
+	if !ok {
+		timers = miscHandlerTimers
+	}
+	start := time.Now()
+	if !receivedAt.IsZero() {
+		timers.wait.Update(start.Sub(receivedAt))
+	}
+	return func() { timers.handle.UpdateSince(start) }
+}
+
 // meteredMsgReadWriter is a wrapper around a p2p.MsgReadWriter, capable of
 // accumulating the above defined metrics based on the data stream contents.
 type meteredMsgReadWriter struct {
@@ -126,7 +236,7 @@ func (rw *meteredMsgReadWriter) ReadMsg() (p2p.Msg, error) {
 		packets, traffic = propHashInPacketsMeter, propHashInTrafficMeter
 	case msg.Code == NewBlockMsg:
 		packets, traffic = propBlockInPacketsMeter, propBlockInTrafficMeter
-	case msg.Code == TxMsg:
+	case msg.Code == TxMsg || msg.Code == PooledTransactionsMsg:
 		packets, traffic = propTxnInPacketsMeter, propTxnInTrafficMeter
 
 	case msg.Code == CoreBlockMsg:
@@ -168,7 +278,7 @@ func (rw *meteredMsgReadWriter) WriteMsg(msg p2p.Msg) error {
 		packets, traffic = propHashOutPacketsMeter, propHashOutTrafficMeter
 	case msg.Code == NewBlockMsg:
 		packets, traffic = propBlockOutPacketsMeter, propBlockOutTrafficMeter
-	case msg.Code == TxMsg:
+	case msg.Code == TxMsg || msg.Code == PooledTransactionsMsg:
 		packets, traffic = propTxnOutPacketsMeter, propTxnOutTrafficMeter
 
 	case msg.Code == CoreBlockMsg:
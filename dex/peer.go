@@ -34,14 +34,19 @@
 package dex
 
 import (
+	"crypto/ecdsa"
+	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"sync"
 	"time"
 
 	mapset "github.com/deckarep/golang-set"
 	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreEcdsa "github.com/portto/tangerine-consensus/core/crypto/ecdsa"
 	coreTypes "github.com/portto/tangerine-consensus/core/types"
 	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
 
@@ -71,6 +76,14 @@ const (
 	// contain a single transaction, or thousands.
 	maxQueuedTxs = 1024
 
+	// maxQueuedTxAnns is the maximum number of transaction hash announcements
+	// to queue up before dropping them.
+	maxQueuedTxAnns = 1024
+
+	// maxQueuedGovTxs is the maximum number of governance-critical transaction
+	// lists to queue up before dropping broadcasts.
+	maxQueuedGovTxs = 128
+
 	// maxQueuedProps is the maximum number of block propagations to queue up before
 	// dropping broadcasts. There's not much point in queueing stale blocks, so a few
 	// that might cover uncles should be enough.
@@ -89,11 +102,36 @@ const (
 	maxQueuedPullBlocks           = 128
 	maxQueuedPullVotes            = 128
 	maxQueuedPullRandomness       = 128
+	maxQueuedStateRoots           = 16
 
 	handshakeTimeout = 5 * time.Second
 
 	groupConnNum     = 3
 	groupConnTimeout = 3 * time.Minute
+
+	// notaryMeshInterval is the interval the connections to the notary sets
+	// of the current and the next round are checked for completeness.
+	notaryMeshInterval = 30 * time.Second
+
+	// Notary connections are rekeyed rekeyStageSize at a time, waiting for
+	// the notary mesh to be redialed between the stages.
+	rekeyStageSize     = 4
+	rekeyStageInterval = notaryMeshInterval
+
+	// Penalties added to the score of misbehaving peers.
+	penaltyMalformedMsg    = 50 // Oversized, undecodable and unexpected messages
+	penaltyInvalidCoreMsg  = 20 // Invalid votes, core blocks and other consensus data
+	penaltyBadPeerReport   = 50 // Misbehaviour reported by the consensus core
+	penaltyUselessAnnounce = 1  // Announcing a block the peer already announced
+
+	// Peers are banned for peerBanDuration once their penalty, halving every
+	// peerPenaltyHalfLife, reaches peerBanThreshold.
+	peerBanThreshold    = 100
+	peerPenaltyHalfLife = 10 * time.Minute
+	peerBanDuration     = time.Hour
+
+	// maxPeerScoreEntries is the number of peers whose penalties are tracked.
+	maxPeerScoreEntries = 4096
 )
 
 // PeerInfo represents a short summary of the Ethereum sub-protocol metadata known
@@ -140,43 +178,61 @@ type peer struct {
 	lastKnownAgreementPosition     coreTypes.Position // The position of latest agreement to be known by this peer
 	knownTxs                       mapset.Set         // Set of transaction hashes known to be known by this peer
 	knownBlocks                    mapset.Set         // Set of block hashes known to be known by this peer
+	announcedBlocks                mapset.Set         // Set of block hashes announced by this peer
 	knownAgreements                mapset.Set
 	knownDKGPrivateShares          mapset.Set
 	queuedTxs                      chan []*types.Transaction // Queue of transactions to broadcast to the peer
+	queuedTxAnns                   chan []common.Hash        // Queue of transaction hashes to announce to the peer
+	queuedGovTxs                   chan []*types.Transaction // Queue of governance-critical transactions to broadcast to the peer
 	queuedProps                    chan *types.Block         // Queue of blocks to broadcast to the peer
 	queuedAnns                     chan *types.Block         // Queue of blocks to announce to the peer
-	queuedCoreBlocks               chan []*coreTypes.Block
-	queuedVotes                    chan []*coreTypes.Vote
+	queuedCoreBlocks               chan []rlp.RawValue       // Queue of encoded core blocks to broadcast to the peer
+	queuedVotes                    chan []rlp.RawValue       // Queue of encoded votes to broadcast to the peer
 	queuedAgreements               chan *coreTypes.AgreementResult
 	queuedDKGPrivateShares         chan *dkgTypes.PrivateShare
 	queuedDKGPartialSignatures     chan *dkgTypes.PartialSignature
 	queuedPullBlocks               chan coreCommon.Hashes
 	queuedPullVotes                chan coreTypes.Position
 	queuedPullRandomness           chan coreCommon.Hashes
+	queuedStateRoots               chan *stateRootData
 	term                           chan struct{} // Termination channel to stop the broadcaster
+
+	coreID      coreTypes.NodeID // Consensus core ID of the peer, zero if its key is unknown
+	clockOffset clockOffset      // Offset of the timestamps of core blocks proposed by the peer
+
+	notary bool // Whether the peer proved control of its node key for a notary claim
 }
 
 func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
+	var coreID coreTypes.NodeID
+	if pub := p.Node().Pubkey(); pub != nil {
+		coreID = coreTypes.NewNodeID(coreEcdsa.NewPublicKeyFromECDSA(pub))
+	}
 	return &peer{
 		Peer:                       p,
 		rw:                         rw,
 		version:                    version,
 		id:                         p.ID().String(),
+		coreID:                     coreID,
 		knownTxs:                   mapset.NewSet(),
 		knownBlocks:                mapset.NewSet(),
+		announcedBlocks:            mapset.NewSet(),
 		knownAgreements:            mapset.NewSet(),
 		knownDKGPrivateShares:      mapset.NewSet(),
 		queuedTxs:                  make(chan []*types.Transaction, maxQueuedTxs),
+		queuedTxAnns:               make(chan []common.Hash, maxQueuedTxAnns),
+		queuedGovTxs:               make(chan []*types.Transaction, maxQueuedGovTxs),
 		queuedProps:                make(chan *types.Block, maxQueuedProps),
 		queuedAnns:                 make(chan *types.Block, maxQueuedAnns),
-		queuedCoreBlocks:           make(chan []*coreTypes.Block, maxQueuedCoreBlocks),
-		queuedVotes:                make(chan []*coreTypes.Vote, maxQueuedVotes),
+		queuedCoreBlocks:           make(chan []rlp.RawValue, maxQueuedCoreBlocks),
+		queuedVotes:                make(chan []rlp.RawValue, maxQueuedVotes),
 		queuedAgreements:           make(chan *coreTypes.AgreementResult, maxQueuedAgreements),
 		queuedDKGPrivateShares:     make(chan *dkgTypes.PrivateShare, maxQueuedDKGPrivateShare),
 		queuedDKGPartialSignatures: make(chan *dkgTypes.PartialSignature, maxQueuedDKGParitialSignature),
 		queuedPullBlocks:           make(chan coreCommon.Hashes, maxQueuedPullBlocks),
 		queuedPullVotes:            make(chan coreTypes.Position, maxQueuedPullVotes),
 		queuedPullRandomness:       make(chan coreCommon.Hashes, maxQueuedPullRandomness),
+		queuedStateRoots:           make(chan *stateRootData, maxQueuedStateRoots),
 		term:                       make(chan struct{}),
 	}
 }
@@ -185,8 +241,25 @@ func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 // transaction broadcasts into the remote peer.
 // The goal is to have an async writer that does not lock up node internals.
 func (p *peer) broadcast() {
-	queuedVotes := make([]*coreTypes.Vote, 0, maxQueuedVotes)
+	queuedVotes := make([]rlp.RawValue, 0, maxQueuedVotes)
+	queuedGovTxs := make([]*types.Transaction, 0)
 	for {
+	PriorityBroadcastGovTx:
+		for {
+			select {
+			case txs := <-p.queuedGovTxs:
+				queuedGovTxs = append(queuedGovTxs, txs...)
+			default:
+				break PriorityBroadcastGovTx
+			}
+		}
+		if len(queuedGovTxs) != 0 {
+			if err := p.SendTransactions(queuedGovTxs); err != nil {
+				return
+			}
+			p.Log().Trace("Broadcast governance transactions", "count", len(queuedGovTxs))
+			queuedGovTxs = queuedGovTxs[:0]
+		}
 	PriorityBroadcastVote:
 		for {
 			select {
@@ -197,7 +270,7 @@ func (p *peer) broadcast() {
 			}
 		}
 		if len(queuedVotes) != 0 {
-			if err := p.SendVotes(queuedVotes); err != nil {
+			if err := p.SendVotesRLP(queuedVotes); err != nil {
 				return
 			}
 			p.Log().Trace("Broadcast votes", "count", len(queuedVotes))
@@ -216,15 +289,20 @@ func (p *peer) broadcast() {
 			}
 			p.Log().Trace("Announced block", "number", block.Number(), "hash", block.Hash())
 		case blocks := <-p.queuedCoreBlocks:
-			if err := p.SendCoreBlocks(blocks); err != nil {
+			if err := p.SendCoreBlocksRLP(blocks); err != nil {
 				return
 			}
 			p.Log().Trace("Broadcast core blocks", "count", len(blocks))
 		case votes := <-p.queuedVotes:
-			if err := p.SendVotes(votes); err != nil {
+			if err := p.SendVotesRLP(votes); err != nil {
 				return
 			}
 			p.Log().Trace("Broadcast votes", "count", len(votes))
+		case txs := <-p.queuedGovTxs:
+			if err := p.SendTransactions(txs); err != nil {
+				return
+			}
+			p.Log().Trace("Broadcast governance transactions", "count", len(txs))
 		case agreement := <-p.queuedAgreements:
 			if err := p.SendAgreement(agreement); err != nil {
 				return
@@ -250,6 +328,11 @@ func (p *peer) broadcast() {
 				return
 			}
 			p.Log().Trace("Pulling Votes", "position", pos)
+		case root := <-p.queuedStateRoots:
+			if err := p.SendStateRoot(root); err != nil {
+				return
+			}
+			p.Log().Trace("Broadcast state root", "number", root.Number)
 		case <-p.term:
 			return
 		case <-time.After(100 * time.Millisecond):
@@ -260,6 +343,11 @@ func (p *peer) broadcast() {
 				return
 			}
 			p.Log().Trace("Broadcast transactions", "count", len(txs))
+		case hashes := <-p.queuedTxAnns:
+			if err := p.SendPooledTransactionHashes(hashes); err != nil {
+				return
+			}
+			p.Log().Trace("Announced transactions", "count", len(hashes))
 		default:
 		}
 	}
@@ -310,6 +398,19 @@ func (p *peer) MarkBlock(hash common.Hash) {
 	p.knownBlocks.Add(hash)
 }
 
+// MarkAnnounced marks a block as announced by the peer, returning whether the
+// peer announced it before.
+func (p *peer) MarkAnnounced(hash common.Hash) bool {
+	if p.announcedBlocks.Contains(hash) {
+		return true
+	}
+	for p.announcedBlocks.Cardinality() >= maxKnownBlocks {
+		p.announcedBlocks.Pop()
+	}
+	p.announcedBlocks.Add(hash)
+	return false
+}
+
 // MarkTransaction marks a transaction as known for the peer, ensuring that it
 // will never be propagated to this particular peer.
 func (p *peer) MarkTransaction(hash common.Hash) {
@@ -372,6 +473,57 @@ func (p *peer) AsyncSendTransactions(txs []*types.Transaction) {
 	}
 }
 
+// SendPooledTransactionHashes announces transactions to the peer by their
+// hashes and includes them in its transaction hash set for future reference.
+func (p *peer) SendPooledTransactionHashes(hashes []common.Hash) error {
+	for _, hash := range hashes {
+		p.knownTxs.Add(hash)
+	}
+	return p.logSend(p2p.Send(p.rw, NewPooledTransactionHashesMsg, hashes), NewPooledTransactionHashesMsg)
+}
+
+// AsyncSendPooledTransactionHashes queues transaction hashes to announce to
+// the remote peer. If the peer's announcement queue is full, the event is
+// silently dropped.
+func (p *peer) AsyncSendPooledTransactionHashes(hashes []common.Hash) {
+	select {
+	case p.queuedTxAnns <- hashes:
+		for _, hash := range hashes {
+			p.knownTxs.Add(hash)
+		}
+	default:
+		p.Log().Debug("Dropping transaction announcement", "count", len(hashes))
+	}
+}
+
+// RequestTxs fetches a batch of announced transactions from the peer.
+func (p *peer) RequestTxs(hashes []common.Hash) error {
+	p.Log().Debug("Fetching batch of transactions", "count", len(hashes))
+	return p.logSend(p2p.Send(p.rw, GetPooledTransactionsMsg, hashes), GetPooledTransactionsMsg)
+}
+
+// SendPooledTransactions sends the transactions requested by the peer.
+func (p *peer) SendPooledTransactions(txs types.Transactions) error {
+	for _, tx := range txs {
+		p.knownTxs.Add(tx.Hash())
+	}
+	return p.logSend(p2p.Send(p.rw, PooledTransactionsMsg, txs), PooledTransactionsMsg)
+}
+
+// AsyncSendGovernanceTransactions queues governance-critical transactions for
+// propagation ahead of regular transactions. If the peer's queue is full, the
+// event is silently dropped.
+func (p *peer) AsyncSendGovernanceTransactions(txs []*types.Transaction) {
+	select {
+	case p.queuedGovTxs <- txs:
+		for _, tx := range txs {
+			p.knownTxs.Add(tx.Hash())
+		}
+	default:
+		p.Log().Debug("Dropping governance transaction propagation", "count", len(txs))
+	}
+}
+
 // SendNewBlockHashes announces the availability of a number of blocks through
 // a hash notification.
 func (p *peer) SendNewBlockHashes(hashes []common.Hash, numbers []uint64) error {
@@ -419,7 +571,15 @@ func (p *peer) SendCoreBlocks(blocks []*coreTypes.Block) error {
 	return p.logSend(p2p.Send(p.rw, CoreBlockMsg, blocks), CoreBlockMsg)
 }
 
-func (p *peer) AsyncSendCoreBlocks(blocks []*coreTypes.Block) {
+// SendCoreBlocksRLP sends a batch of core blocks, already RLP encoded, to the
+// peer.
+func (p *peer) SendCoreBlocksRLP(blocks []rlp.RawValue) error {
+	return p.logSend(p2p.Send(p.rw, CoreBlockMsg, blocks), CoreBlockMsg)
+}
+
+// AsyncSendCoreBlocks queues RLP encoded core blocks for broadcast to the
+// peer, letting a block broadcast to many peers be encoded only once.
+func (p *peer) AsyncSendCoreBlocks(blocks []rlp.RawValue) {
 	select {
 	case p.queuedCoreBlocks <- blocks:
 	default:
@@ -431,7 +591,14 @@ func (p *peer) SendVotes(votes []*coreTypes.Vote) error {
 	return p.logSend(p2p.Send(p.rw, VoteMsg, votes), VoteMsg)
 }
 
-func (p *peer) AsyncSendVotes(votes []*coreTypes.Vote) {
+// SendVotesRLP sends a batch of votes, already RLP encoded, to the peer.
+func (p *peer) SendVotesRLP(votes []rlp.RawValue) error {
+	return p.logSend(p2p.Send(p.rw, VoteMsg, votes), VoteMsg)
+}
+
+// AsyncSendVotes queues RLP encoded votes for broadcast to the peer, letting
+// a vote broadcast to many peers be encoded only once.
+func (p *peer) AsyncSendVotes(votes []rlp.RawValue) {
 	select {
 	case p.queuedVotes <- votes:
 	default:
@@ -504,6 +671,18 @@ func (p *peer) AsyncSendPullVotes(pos coreTypes.Position) {
 }
 
 // SendBlockHeaders sends a batch of block headers to the remote peer.
+func (p *peer) SendStateRoot(root *stateRootData) error {
+	return p.logSend(p2p.Send(p.rw, StateRootMsg, root), StateRootMsg)
+}
+
+func (p *peer) AsyncSendStateRoot(root *stateRootData) {
+	select {
+	case p.queuedStateRoots <- root:
+	default:
+		p.Log().Debug("Dropping state root propagation")
+	}
+}
+
 func (p *peer) SendBlockHeaders(flag uint8, headers []*types.HeaderWithGovState) error {
 	return p.logSend(p2p.Send(p.rw, BlockHeadersMsg, headersData{Flag: flag, Headers: headers}), BlockHeadersMsg)
 }
@@ -591,18 +770,42 @@ func (p *peer) RequestReceipts(hashes []common.Hash) error {
 
 // Handshake executes the eth protocol handshake, negotiating version number,
 // network IDs, difficulties, head and genesis blocks.
-func (p *peer) Handshake(network uint64, number uint64, head common.Hash, genesis common.Hash) error {
+//
+// From dex/65 on, if key is not nil, the local node claims notary set
+// membership and signs the challenge picked by the remote side with it. A
+// matching claim sent by the remote side marks the peer as notary, leaving the
+// membership check against the node set to the caller.
+func (p *peer) Handshake(network uint64, number uint64, head common.Hash, genesis common.Hash, key *ecdsa.PrivateKey) error {
+	var challenge common.Hash
+	if _, err := rand.Read(challenge[:]); err != nil {
+		return err
+	}
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
-	var status statusData // safe to read after two values have been received from errc
+	var status statusData65 // safe to read after two values have been received from errc
 
+	if p.version < dex65 {
+		key = nil
+	}
 	go func() {
-		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
+		if p.version < dex65 {
+			errc <- p2p.Send(p.rw, StatusMsg, &statusData{
+				ProtocolVersion: uint32(p.version),
+				NetworkId:       network,
+				Number:          number,
+				CurrentBlock:    head,
+				GenesisBlock:    genesis,
+			})
+			return
+		}
+		errc <- p2p.Send(p.rw, StatusMsg, &statusData65{
 			ProtocolVersion: uint32(p.version),
 			NetworkId:       network,
 			Number:          number,
 			CurrentBlock:    head,
 			GenesisBlock:    genesis,
+			Challenge:       challenge,
+			Notary:          key != nil,
 		})
 	}()
 	go func() {
@@ -621,10 +824,41 @@ func (p *peer) Handshake(network uint64, number uint64, head common.Hash, genesi
 		}
 	}
 	p.number, p.head = status.Number, status.CurrentBlock
+
+	// Exchange notary claims, if any side announced one
+	pending := 0
+	if key != nil {
+		pending++
+		go func() {
+			id := enode.PubkeyToIDV4(&key.PublicKey)
+			sig, err := crypto.Sign(notaryClaimHash(status.Challenge, id).Bytes(), key)
+			if err != nil {
+				errc <- err
+				return
+			}
+			errc <- p2p.Send(p.rw, NotaryClaimMsg, &notaryClaimData{ID: id, Signature: sig})
+		}()
+	}
+	if status.Notary {
+		pending++
+		go func() {
+			errc <- p.readNotaryClaim(challenge)
+		}()
+	}
+	for i := 0; i < pending; i++ {
+		select {
+		case err := <-errc:
+			if err != nil {
+				return err
+			}
+		case <-timeout.C:
+			return p2p.DiscReadTimeout
+		}
+	}
 	return nil
 }
 
-func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash) (err error) {
+func (p *peer) readStatus(network uint64, status *statusData65, genesis common.Hash) (err error) {
 	msg, err := p.rw.ReadMsg()
 	if err != nil {
 		return err
@@ -636,7 +870,19 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
 	}
 	// Decode the handshake and make sure everything matches
-	if err := msg.Decode(&status); err != nil {
+	if p.version < dex65 {
+		var status64 statusData
+		if err := msg.Decode(&status64); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		*status = statusData65{
+			ProtocolVersion: status64.ProtocolVersion,
+			NetworkId:       status64.NetworkId,
+			Number:          status64.Number,
+			CurrentBlock:    status64.CurrentBlock,
+			GenesisBlock:    status64.GenesisBlock,
+		}
+	} else if err := msg.Decode(status); err != nil {
 		return errResp(ErrDecode, "msg %v: %v", msg, err)
 	}
 	if status.GenesisBlock != genesis {
@@ -651,6 +897,39 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 	return nil
 }
 
+// readNotaryClaim reads the notary claim of the remote side and checks that it
+// was signed over the given challenge by the node key of the connected peer.
+func (p *peer) readNotaryClaim(challenge common.Hash) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	defer msg.Discard()
+
+	if msg.Code != NotaryClaimMsg {
+		return errResp(ErrInvalidNotaryClaim, "msg has code %x (!= %x)", msg.Code, NotaryClaimMsg)
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	var claim notaryClaimData
+	if err := msg.Decode(&claim); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	if claim.ID != p.ID() {
+		return errResp(ErrInvalidNotaryClaim, "claimed id %x (!= %x)", claim.ID[:8], p.ID().Bytes()[:8])
+	}
+	pub, err := crypto.SigToPub(notaryClaimHash(challenge, claim.ID).Bytes(), claim.Signature)
+	if err != nil {
+		return errResp(ErrInvalidNotaryClaim, "%v", err)
+	}
+	if enode.PubkeyToIDV4(pub) != claim.ID {
+		return errResp(ErrInvalidNotaryClaim, "signature mismatch")
+	}
+	p.notary = true
+	return nil
+}
+
 // String implements fmt.Stringer.
 func (p *peer) String() string {
 	return fmt.Sprintf("Peer %s [%s]", p.id,
@@ -669,10 +948,30 @@ type peerSet struct {
 	srvr p2pServer
 	gov  governance
 
+	rekeying bool // Whether notary connections are being rekeyed
+
 	label2Nodes    map[peerLabel]map[string]*enode.Node
 	directConn     map[peerLabel]struct{}
 	groupConnPeers map[peerLabel]map[string]time.Time
 	allDirectPeers map[string]map[peerLabel]struct{}
+
+	scores    map[string]*peerScore // Penalties of misbehaving peers
+	banned    map[string]time.Time  // Banned peers and the end of their bans
+	scoreLock sync.Mutex
+
+	audit *auditLog // Records the bans, nil if not audited
+}
+
+// peerScore is the penalty accumulated by a misbehaving peer, which halves
+// every peerPenaltyHalfLife.
+type peerScore struct {
+	penalty float64
+	updated time.Time
+}
+
+// decayed returns the penalty decayed until now.
+func (s *peerScore) decayed(now time.Time) float64 {
+	return s.penalty * math.Exp2(-float64(now.Sub(s.updated))/float64(peerPenaltyHalfLife))
 }
 
 // newPeerSet creates a new peer set to track the active participants.
@@ -686,9 +985,131 @@ func newPeerSet(gov governance, srvr p2pServer) *peerSet {
 		directConn:     make(map[peerLabel]struct{}),
 		groupConnPeers: make(map[peerLabel]map[string]time.Time),
 		allDirectPeers: make(map[string]map[peerLabel]struct{}),
+		scores:         make(map[string]*peerScore),
+		banned:         make(map[string]time.Time),
 	}
 }
 
+// protocolErrorPenalty returns the penalty of a protocol error by its class.
+// Invalid consensus data may be relayed by honest peers that are behind or
+// ahead of the local node, so it costs less than malformed messages, and
+// incompatible peers are only disconnected.
+func protocolErrorPenalty(code errCode) float64 {
+	switch code {
+	case ErrMsgTooLarge, ErrDecode, ErrInvalidMsgCode, ErrNoStatusMsg, ErrExtraStatusMsg:
+		return penaltyMalformedMsg
+	case ErrInvalidGovStateMsg, ErrInvalidCoreBlock, ErrInvalidNotaryClaim,
+		ErrInvalidStateRoot, ErrInvalidVote:
+		return penaltyInvalidCoreMsg
+	default:
+		return 0
+	}
+}
+
+// exempt returns whether the peer id is exempt from penalties. Notary set
+// members are needed by BA and trusted peers are chosen by the operator, so
+// a false positive must not keep them out for a ban; they are only
+// disconnected.
+func (ps *peerSet) exempt(id string) bool {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	if ps.isNotary(id) {
+		return true
+	}
+	p := ps.peers[id]
+	return p != nil && p.Peer.Trusted()
+}
+
+// Penalize adds penalty to the score of the peer id, banning it once the
+// decayed penalty reaches peerBanThreshold. It returns whether the peer is
+// banned. Exempt peers are never penalized.
+func (ps *peerSet) Penalize(id string, penalty float64, reason string) bool {
+	if penalty <= 0 {
+		return ps.Banned(id)
+	}
+	if ps.exempt(id) {
+		log.Debug("Not penalizing exempt peer", "id", id, "reason", reason)
+		return false
+	}
+	ps.scoreLock.Lock()
+	defer ps.scoreLock.Unlock()
+
+	now := time.Now()
+	if until, ok := ps.banned[id]; ok && now.Before(until) {
+		return true
+	}
+	score := ps.scores[id]
+	if score == nil {
+		if len(ps.scores) >= maxPeerScoreEntries {
+			ps.pruneScores(now)
+		}
+		score = &peerScore{}
+		ps.scores[id] = score
+	}
+	score.penalty = score.decayed(now) + penalty
+	score.updated = now
+	log.Debug("Penalized peer", "id", id, "reason", reason, "penalty", penalty,
+		"score", score.penalty)
+
+	if score.penalty < peerBanThreshold {
+		return false
+	}
+	delete(ps.scores, id)
+	ps.banned[id] = now.Add(peerBanDuration)
+	bannedPeerMeter.Mark(1)
+	log.Info("Banned misbehaving peer", "id", id, "reason", reason,
+		"duration", peerBanDuration)
+	ps.audit.record(types.AuditNode, AuditBanPeer,
+		fmt.Sprintf("id=%s reason=%s duration=%v", id, reason, peerBanDuration), nil)
+	return true
+}
+
+// pruneScores drops the bans which ended and the scores decayed to less than
+// a penalty point, or all scores if none did.
+func (ps *peerSet) pruneScores(now time.Time) {
+	for id, until := range ps.banned {
+		if !now.Before(until) {
+			delete(ps.banned, id)
+		}
+	}
+	for id, score := range ps.scores {
+		if score.decayed(now) < 1 {
+			delete(ps.scores, id)
+		}
+	}
+	if len(ps.scores) >= maxPeerScoreEntries {
+		ps.scores = make(map[string]*peerScore)
+	}
+}
+
+// Banned returns whether the peer id is banned.
+func (ps *peerSet) Banned(id string) bool {
+	ps.scoreLock.Lock()
+	defer ps.scoreLock.Unlock()
+
+	until, ok := ps.banned[id]
+	if !ok {
+		return false
+	}
+	if !time.Now().Before(until) {
+		delete(ps.banned, id)
+		return false
+	}
+	return true
+}
+
+// Penalty returns the decayed penalty of the peer id.
+func (ps *peerSet) Penalty(id string) float64 {
+	ps.scoreLock.Lock()
+	defer ps.scoreLock.Unlock()
+
+	if score := ps.scores[id]; score != nil {
+		return score.decayed(time.Now())
+	}
+	return 0
+}
+
 // Register injects a new peer into the working set, or returns an error if the
 // peer is already known. If a new peer it registered, its broadcast loop is also
 // started.
@@ -796,6 +1217,29 @@ func (ps *peerSet) PeersWithoutLabel(label peerLabel) []*peer {
 	return list
 }
 
+// SplitNotaries splits peers into the members of the notary sets of rounds
+// and the others, keeping their relative order.
+func (ps *peerSet) SplitNotaries(peers []*peer, rounds ...uint64) (notaries, others []*peer) {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	for _, p := range peers {
+		notary := false
+		for _, round := range rounds {
+			if _, ok := ps.label2Nodes[peerLabel{set: notaryset, round: round}][p.id]; ok {
+				notary = true
+				break
+			}
+		}
+		if notary {
+			notaries = append(notaries, p)
+		} else {
+			others = append(others, p)
+		}
+	}
+	return notaries, others
+}
+
 func (ps *peerSet) PeersWithoutAgreement(position coreTypes.Position) []*peer {
 	ps.lock.RLock()
 	defer ps.lock.RUnlock()
@@ -849,6 +1293,153 @@ func (ps *peerSet) Close() {
 	ps.closed = true
 }
 
+// IsNotary checks if the given node is in the notary set of any round the
+// peer set is currently connected for.
+func (ps *peerSet) IsNotary(id enode.ID) bool {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	return ps.isNotary(id.String())
+}
+
+// NotaryConn is a connection to a notary set member.
+type NotaryConn struct {
+	ID      string        `json:"id"`
+	Inbound bool          `json:"inbound"`
+	Age     time.Duration `json:"age"`
+}
+
+// NotaryConns returns the connections to the notary set members of the
+// rounds the peer set is connected for.
+func (ps *peerSet) NotaryConns() []*NotaryConn {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	var conns []*NotaryConn
+	for id, p := range ps.peers {
+		if ps.isNotary(id) {
+			conns = append(conns, &NotaryConn{ID: id, Inbound: p.Inbound(), Age: p.Age()})
+		}
+	}
+	return conns
+}
+
+// Rekey drops the notary connections older than minAge, so that they are
+// redialed with fresh session keys. The connections are dropped oldest first
+// in stages, so that most of the notary set stays connected meanwhile. It
+// returns the number of connections to drop, zero if a rekey is already in
+// progress.
+func (ps *peerSet) Rekey(minAge time.Duration) int {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if ps.closed || ps.rekeying {
+		return 0
+	}
+	stages := ps.rekeyStages(minAge, rekeyStageSize)
+	if len(stages) == 0 {
+		return 0
+	}
+	ps.rekeying = true
+	go func() {
+		ps.rekey(stages, rekeyStageInterval)
+
+		ps.lock.Lock()
+		ps.rekeying = false
+		ps.lock.Unlock()
+	}()
+
+	scheduled := 0
+	for _, stage := range stages {
+		scheduled += len(stage)
+	}
+	return scheduled
+}
+
+// rekeyStages returns the notary connections older than minAge, oldest
+// first, in stages of size connections. The caller must hold the lock.
+func (ps *peerSet) rekeyStages(minAge time.Duration, size int) [][]*peer {
+	var conns []*peer
+	for id, p := range ps.peers {
+		if ps.isNotary(id) && p.Age() >= minAge {
+			conns = append(conns, p)
+		}
+	}
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].Age() > conns[j].Age()
+	})
+
+	var stages [][]*peer
+	for len(conns) > size {
+		stages = append(stages, conns[:size])
+		conns = conns[size:]
+	}
+	if len(conns) > 0 {
+		stages = append(stages, conns)
+	}
+	return stages
+}
+
+// rekey drops the connections of stages, waiting interval between the
+// stages. Connections gone meanwhile are skipped, and it stops once the peer
+// set is closed. It returns the number of connections dropped.
+func (ps *peerSet) rekey(stages [][]*peer, interval time.Duration) int {
+	dropped := 0
+	for i, stage := range stages {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		ps.lock.RLock()
+		if ps.closed {
+			ps.lock.RUnlock()
+			break
+		}
+		n := 0
+		for _, p := range stage {
+			if ps.peers[p.id] != p {
+				continue
+			}
+			log.Debug("Rekeying notary connection", "id", p.id, "age", p.Age())
+			p.Disconnect(p2p.DiscRequested)
+			n++
+		}
+		ps.lock.RUnlock()
+
+		notaryRekeyMeter.Mark(int64(n))
+		dropped += n
+	}
+	return dropped
+}
+
+// isNotary reports whether id is a notary set member of any round the peer
+// set is connected for. The caller must hold the lock.
+func (ps *peerSet) isNotary(id string) bool {
+	for label, nodes := range ps.label2Nodes {
+		if label.set != notaryset {
+			continue
+		}
+		if _, ok := nodes[id]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// updateNotaryConnMetrics reports the number and age of the notary
+// connections.
+func (ps *peerSet) updateNotaryConnMetrics() {
+	var maxAge time.Duration
+	conns := ps.NotaryConns()
+	for _, conn := range conns {
+		notaryConnAgeHistogram.Update(int64(conn.Age / time.Second))
+		if conn.Age > maxAge {
+			maxAge = conn.Age
+		}
+	}
+	notaryConnGauge.Update(int64(len(conns)))
+	notaryConnMaxAgeGauge.Update(int64(maxAge / time.Second))
+}
+
 func (ps *peerSet) BuildConnection(round uint64) {
 	ps.lock.Lock()
 	defer ps.lock.Unlock()
@@ -874,6 +1465,36 @@ func (ps *peerSet) BuildConnection(round uint64) {
 	}
 }
 
+// RebuildConnections forgets the connections built for the notary sets and
+// builds them again, as needed once the p2p identity of the node changed.
+func (ps *peerSet) RebuildConnections() {
+	ps.lock.Lock()
+	var rounds []uint64
+	for label := range ps.label2Nodes {
+		if label.set == notaryset {
+			rounds = append(rounds, label.round)
+		}
+		ps.forgetDirectConn(label)
+		ps.forgetGroupConn(label)
+		delete(ps.label2Nodes, label)
+	}
+	ps.lock.Unlock()
+
+	for _, round := range rounds {
+		ps.BuildConnection(round)
+	}
+}
+
+// HasConnection returns whether the connections to the notary set of round
+// are built.
+func (ps *peerSet) HasConnection(round uint64) bool {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	_, ok := ps.label2Nodes[peerLabel{set: notaryset, round: round}]
+	return ok
+}
+
 func (ps *peerSet) ForgetLabelConnection(label peerLabel) {
 	ps.lock.Lock()
 	defer ps.lock.Unlock()
@@ -916,15 +1537,30 @@ func (ps *peerSet) EnsureGroupConn() {
 	now := time.Now()
 	for label, peers := range ps.groupConnPeers {
 		// Remove timeout group conn peer.
+		timeout := make(map[string]struct{})
 		for id, addtime := range peers {
 			if ps.peers[id] == nil && time.Since(addtime) > groupConnTimeout {
 				ps.removeDirectPeer(id, label)
 				delete(ps.groupConnPeers[label], id)
+				timeout[id] = struct{}{}
 			}
 		}
 
-		// Add new group conn peer.
+		// Add new group conn peer, trying the members not dialed yet first.
 		for id := range ps.label2Nodes[label] {
+			if len(ps.groupConnPeers[label]) >= groupConnNum {
+				break
+			}
+			if _, ok := peers[id]; ok {
+				continue
+			}
+			if _, ok := timeout[id]; ok {
+				continue
+			}
+			ps.groupConnPeers[label][id] = now
+			ps.addDirectPeer(id, label)
+		}
+		for id := range timeout {
 			if len(ps.groupConnPeers[label]) >= groupConnNum {
 				break
 			}
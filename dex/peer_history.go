@@ -0,0 +1,160 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync"
+	"time"
+
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/log"
+)
+
+const (
+	// peerHistoryBucket is the time span of the peer events stored together.
+	peerHistoryBucket = time.Hour
+
+	// peerHistoryFlushInterval is the interval the events of the current
+	// bucket are stored at, bounding what a crash loses.
+	peerHistoryFlushInterval = 5 * time.Minute
+
+	// maxPeerHistoryEvents is the maximum number of peer events returned by
+	// a single query.
+	maxPeerHistoryEvents = 10000
+)
+
+// peerHistory is a rolling log of peer connections and disconnections,
+// persisted to reconstruct the network conditions around past incidents.
+// The events of the current bucket are kept in memory and stored when the
+// bucket rolls over, periodically, and when the history is closed.
+type peerHistory struct {
+	db        ethdb.Database
+	retention uint64 // Number of buckets kept
+
+	lock    sync.Mutex
+	tail    *uint64            // Oldest stored bucket
+	current *uint64            // Bucket of the events held in memory
+	events  []*types.PeerEvent // Events of the current bucket
+}
+
+func newPeerHistory(db ethdb.Database, retention time.Duration) *peerHistory {
+	buckets := uint64(retention / peerHistoryBucket)
+	if buckets == 0 {
+		buckets = 1
+	}
+	return &peerHistory{
+		db:        db,
+		retention: buckets,
+		tail:      rawdb.ReadPeerHistoryTail(db),
+	}
+}
+
+func peerHistoryBucketOf(ms int64) uint64 {
+	if ms < 0 {
+		return 0
+	}
+	return uint64(ms / int64(peerHistoryBucket/time.Millisecond))
+}
+
+// record appends ev to the history. Moving to another bucket stores the
+// events of the current one and drops the buckets that fell out of the
+// retention window.
+func (h *peerHistory) record(ev *types.PeerEvent) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if bucket := peerHistoryBucketOf(ev.Time); h.current == nil || *h.current != bucket {
+		h.roll(bucket)
+	}
+	h.events = append(h.events, ev)
+}
+
+// roll stores the events held in memory and makes bucket the current one,
+// picking up the events stored for it before a restart.
+func (h *peerHistory) roll(bucket uint64) {
+	batch := h.db.NewBatch()
+	if h.current != nil {
+		rawdb.WritePeerEvents(batch, *h.current, h.events)
+	}
+	var oldest uint64
+	if bucket >= h.retention {
+		oldest = bucket - h.retention + 1
+	}
+	switch {
+	case h.tail == nil:
+		oldest = bucket
+		rawdb.WritePeerHistoryTail(batch, oldest)
+	case *h.tail < oldest:
+		for b := *h.tail; b < oldest; b++ {
+			rawdb.DeletePeerEvents(batch, b)
+		}
+		rawdb.WritePeerHistoryTail(batch, oldest)
+	default:
+		oldest = *h.tail
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to store peer history", "err", err)
+	}
+	h.tail = &oldest
+	h.current = &bucket
+	h.events = rawdb.ReadPeerEvents(h.db, bucket)
+}
+
+// flush stores the events held in memory.
+func (h *peerHistory) flush() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.current != nil {
+		rawdb.WritePeerEvents(h.db, *h.current, h.events)
+	}
+}
+
+// since returns the recorded events from time since on, in milliseconds,
+// along with whether the result was truncated.
+func (h *peerHistory) since(since, now int64) ([]*types.PeerEvent, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.tail == nil {
+		return nil, false
+	}
+	first := peerHistoryBucketOf(since)
+	if first < *h.tail {
+		first = *h.tail
+	}
+	var result []*types.PeerEvent
+	for b := first; b <= peerHistoryBucketOf(now); b++ {
+		events := h.events
+		if h.current == nil || *h.current != b {
+			events = rawdb.ReadPeerEvents(h.db, b)
+		}
+		for _, ev := range events {
+			if ev.Time < since {
+				continue
+			}
+			if len(result) == maxPeerHistoryEvents {
+				return result, true
+			}
+			result = append(result, ev)
+		}
+	}
+	return result, false
+}
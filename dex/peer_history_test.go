@@ -0,0 +1,89 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/ethdb"
+)
+
+// Tests that peer events are queryable by time, and dropped from the
+// database once out of the retention window.
+func TestPeerHistory(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	history := newPeerHistory(db, 2*peerHistoryBucket)
+
+	hour := int64(peerHistoryBucket / time.Millisecond)
+	record := func(ms int64) {
+		history.record(&types.PeerEvent{Time: ms, Event: types.PeerConnected, ID: fmt.Sprint(ms)})
+	}
+	record(10)
+	record(hour + 10)
+	record(hour + 20)
+
+	events, truncated := history.since(hour+15, 2*hour)
+	if truncated || len(events) != 1 || events[0].Time != hour+20 {
+		t.Fatalf("events mismatch: %v, truncated %v", events, truncated)
+	}
+	if events, _ := history.since(0, 2*hour); len(events) != 3 {
+		t.Fatalf("event count mismatch: have %d, want 3", len(events))
+	}
+	// The current bucket is only stored once it rolls over or is flushed.
+	if events := rawdb.ReadPeerEvents(db, 1); events != nil {
+		t.Errorf("current bucket stored before rolling over: %v", events)
+	}
+	if events := rawdb.ReadPeerEvents(db, 0); len(events) != 1 {
+		t.Errorf("rolled over bucket event count mismatch: have %d, want 1", len(events))
+	}
+
+	// Recording into the third bucket drops the first one.
+	record(2*hour + 10)
+	if events := rawdb.ReadPeerEvents(db, 0); events != nil {
+		t.Errorf("expired events not deleted: %v", events)
+	}
+	if tail := rawdb.ReadPeerHistoryTail(db); tail == nil || *tail != 1 {
+		t.Errorf("tail mismatch: %v", tail)
+	}
+	if events, _ := history.since(0, 3*hour); len(events) != 3 {
+		t.Errorf("event count mismatch: have %d, want 3", len(events))
+	}
+
+	if events := rawdb.ReadPeerEvents(db, 1); len(events) != 2 {
+		t.Errorf("rolled over bucket event count mismatch: have %d, want 2", len(events))
+	}
+
+	// The history is picked up again after a restart, events of the current
+	// bucket included.
+	history.flush()
+	if events := rawdb.ReadPeerEvents(db, 2); len(events) != 1 {
+		t.Errorf("flushed bucket event count mismatch: have %d, want 1", len(events))
+	}
+	history = newPeerHistory(db, 2*peerHistoryBucket)
+	if events, _ := history.since(hour+15, 3*hour); len(events) != 2 {
+		t.Errorf("reloaded event count mismatch: have %d, want 2", len(events))
+	}
+	record(2*hour + 20)
+	if events, _ := history.since(2*hour, 3*hour); len(events) != 2 {
+		t.Errorf("resumed bucket event count mismatch: have %d, want 2", len(events))
+	}
+}
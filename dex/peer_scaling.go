@@ -0,0 +1,123 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync/atomic"
+
+	"github.com/portto/go-tangerine/event"
+	"github.com/portto/go-tangerine/log"
+)
+
+const (
+	peerScalingRoundChanSize = 16
+
+	// minScaledPeers is the lowest peer count the scaling goes down to,
+	// keeping non-notary peers reachable on tiny networks.
+	minScaledPeers = 8
+
+	// scaledDialRatio is the dial ratio of the p2p server by default, in
+	// which a third of the peers are dialed.
+	scaledDialRatio = 3
+)
+
+// peerScalingGovernance is the governance data needed to size the peer
+// limits at startup.
+type peerScalingGovernance interface {
+	NotarySet(round uint64) (map[string]struct{}, error)
+}
+
+// peerLimiter is the p2p server whose peer limits are scaled.
+type peerLimiter interface {
+	SetMaxPeers(maxPeers, dialRatio int)
+}
+
+// peerScaler scales the peer limits with the notary set size of the current
+// round, so that small networks don't keep sockets they never fill and
+// large ones don't run out of room for the notary set connections.
+type peerScaler struct {
+	gov        peerScalingGovernance
+	pm         *ProtocolManager
+	margin     int // Peers allowed on top of the notary set
+	lightPeers int // Peers reserved to the light server
+
+	roundCh  chan RoundChangeEvent
+	roundSub event.Subscription
+}
+
+func newPeerScaler(gov peerScalingGovernance, pm *ProtocolManager, margin, lightPeers int) *peerScaler {
+	return &peerScaler{
+		gov:        gov,
+		pm:         pm,
+		margin:     margin,
+		lightPeers: lightPeers,
+	}
+}
+
+func (s *peerScaler) Start(rounds *roundNotifier, srvr peerLimiter, round uint64) {
+	s.roundCh = make(chan RoundChangeEvent, peerScalingRoundChanSize)
+	s.roundSub = rounds.Subscribe(s.roundCh)
+	go s.loop(srvr, round)
+}
+
+func (s *peerScaler) Stop() {
+	s.roundSub.Unsubscribe()
+}
+
+func (s *peerScaler) loop(srvr peerLimiter, round uint64) {
+	notarySet, err := s.gov.NotarySet(round)
+	if err != nil {
+		log.Warn("Failed to get notary set for peer scaling", "round", round, "err", err)
+	} else {
+		s.scale(srvr, round, len(notarySet))
+	}
+	for {
+		select {
+		case ev := <-s.roundCh:
+			s.scale(srvr, ev.NewRound, len(ev.NewNotarySet))
+		case <-s.roundSub.Err():
+			return
+		}
+	}
+}
+
+// scale applies the peer limits of a notary set of notarySetSize nodes.
+func (s *peerScaler) scale(srvr peerLimiter, round uint64, notarySetSize int) {
+	maxPeers, dialRatio := scaledPeerLimits(notarySetSize, s.margin)
+	atomic.StoreInt32(&s.pm.maxPeers, int32(maxPeers))
+	srvr.SetMaxPeers(maxPeers+s.lightPeers, dialRatio)
+	log.Info("Scaled peer limits", "round", round, "notaryset", notarySetSize,
+		"maxpeers", maxPeers, "dialratio", dialRatio)
+}
+
+// scaledPeerLimits returns the peer limits for a notary set of
+// notarySetSize nodes: room for the whole set plus margin, dialing a third of
+// the peers as usual, but half of them if that falls short of half the
+// notary set, so that the node reaches the set without waiting for its
+// members to dial in.
+func scaledPeerLimits(notarySetSize, margin int) (maxPeers, dialRatio int) {
+	maxPeers = notarySetSize + margin
+	if maxPeers < minScaledPeers {
+		maxPeers = minScaledPeers
+	}
+	dialRatio = scaledDialRatio
+	if maxPeers/dialRatio < notarySetSize/2 {
+		dialRatio = 2
+	}
+	return maxPeers, dialRatio
+}
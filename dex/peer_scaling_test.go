@@ -0,0 +1,69 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+)
+
+type testPeerLimiter struct {
+	maxPeers, dialRatio int
+}
+
+func (l *testPeerLimiter) SetMaxPeers(maxPeers, dialRatio int) {
+	l.maxPeers, l.dialRatio = maxPeers, dialRatio
+}
+
+func TestScaledPeerLimits(t *testing.T) {
+	tests := []struct {
+		notarySetSize, margin int
+		maxPeers, dialRatio   int
+	}{
+		{0, 0, minScaledPeers, 3},
+		{4, 2, minScaledPeers, 3},
+		{4, 20, 24, 3},
+		{20, 20, 40, 3},
+		{30, 10, 40, 2}, // 40/3 dialed fall short of 15
+		{100, 20, 120, 2},
+		{100, 100, 200, 3},
+	}
+	for i, tt := range tests {
+		maxPeers, dialRatio := scaledPeerLimits(tt.notarySetSize, tt.margin)
+		if maxPeers != tt.maxPeers || dialRatio != tt.dialRatio {
+			t.Errorf("test %d: limits mismatch: have %d/%d, want %d/%d",
+				i, maxPeers, dialRatio, tt.maxPeers, tt.dialRatio)
+		}
+	}
+}
+
+// Tests that the light server peers are added on top of the scaled limits of
+// the p2p server but not of the protocol manager.
+func TestPeerScalerLightPeers(t *testing.T) {
+	var (
+		pm     = &ProtocolManager{maxPeers: 50}
+		srvr   = new(testPeerLimiter)
+		scaler = newPeerScaler(nil, pm, 10, 5)
+	)
+	scaler.scale(srvr, 1, 30)
+	if pm.maxPeers != 40 {
+		t.Errorf("protocol max peers mismatch: have %d, want %d", pm.maxPeers, 40)
+	}
+	if srvr.maxPeers != 45 || srvr.dialRatio != 2 {
+		t.Errorf("server limits mismatch: have %d/%d, want %d/%d", srvr.maxPeers, srvr.dialRatio, 45, 2)
+	}
+}
@@ -3,10 +3,16 @@ package dex
 import (
 	"crypto/ecdsa"
 	"encoding/hex"
+	"math/big"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
 	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/dex/downloader"
+	"github.com/portto/go-tangerine/p2p"
 	"github.com/portto/go-tangerine/p2p/enode"
 )
 
@@ -241,3 +247,422 @@ func randomV4CompactNode() *enode.Node {
 	}
 	return enode.NewV4(&privkey.PublicKey, nil, 0, 0)
 }
+
+func TestPeerHandshakeNotaryClaim(t *testing.T) {
+	notaryKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := dex65
+	handshake := func(id enode.ID, key *ecdsa.PrivateKey) (*peer, *peer, error) {
+		app1, app2 := p2p.MsgPipe()
+		defer app1.Close()
+
+		// Each side sees the other one as its peer.
+		local := newPeer(version, p2p.NewPeer(enode.ID{1}, "remote", nil), app1)
+		remote := newPeer(version, p2p.NewPeer(id, "local", nil), app2)
+
+		errc := make(chan error, 1)
+		go func() {
+			errc <- local.Handshake(DefaultConfig.NetworkId, 0, common.Hash{}, common.Hash{}, key)
+		}()
+		err := remote.Handshake(DefaultConfig.NetworkId, 0, common.Hash{}, common.Hash{}, nil)
+		<-errc
+		return local, remote, err
+	}
+
+	// A peer not claiming membership is not marked as notary.
+	_, remote, err := handshake(enode.PubkeyToIDV4(&notaryKey.PublicKey), nil)
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if remote.notary {
+		t.Errorf("peer without claim marked as notary")
+	}
+
+	// The claim is signed with the node key of the connected peer.
+	_, remote, err = handshake(enode.PubkeyToIDV4(&notaryKey.PublicKey), notaryKey)
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if !remote.notary {
+		t.Errorf("peer with valid claim not marked as notary")
+	}
+
+	// The claim is signed with a key not matching the connected peer.
+	_, remote, err = handshake(enode.PubkeyToIDV4(&notaryKey.PublicKey), otherKey)
+	if err == nil {
+		t.Fatalf("handshake with forged claim succeeded")
+	}
+	if remote.notary {
+		t.Errorf("peer with forged claim marked as notary")
+	}
+
+	// Notary claims are not part of dex/64.
+	version = dex64
+	_, remote, err = handshake(enode.PubkeyToIDV4(&notaryKey.PublicKey), notaryKey)
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if remote.notary {
+		t.Errorf("dex/64 peer marked as notary")
+	}
+}
+
+func TestPeerSetIsNotary(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestP2PServer(key)
+	self := server.Self()
+	other := randomV4CompactNode()
+
+	gov := &testGovernance{}
+	gov.notarySetFunc = func(round uint64) (map[string]struct{}, error) {
+		return newTestNodeSet([]*enode.Node{self}), nil
+	}
+
+	ps := newPeerSet(gov, server)
+	if ps.IsNotary(self.ID()) {
+		t.Errorf("node is notary before connection is built")
+	}
+	ps.BuildConnection(10)
+	if !ps.IsNotary(self.ID()) {
+		t.Errorf("notary set member not recognized")
+	}
+	if ps.IsNotary(other.ID()) {
+		t.Errorf("node outside notary set recognized as notary")
+	}
+	ps.ForgetConnection(10)
+	if ps.IsNotary(self.ID()) {
+		t.Errorf("node is notary after connection is forgotten")
+	}
+}
+
+func TestPreconnectNextRound(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestP2PServer(key)
+	self := server.Self()
+
+	crsRound := uint64(0)
+	gov := &testGovernance{roundLength: 100}
+	gov.lenCRSFunc = func() uint64 { return crsRound }
+	gov.notarySetFunc = func(round uint64) (map[string]struct{}, error) {
+		return newTestNodeSet([]*enode.Node{self}), nil
+	}
+	pm := &ProtocolManager{
+		gov:                    gov,
+		peers:                  newPeerSet(gov, server),
+		notaryPreconnectBlocks: 10,
+	}
+	head := func(number uint64) *types.Block {
+		return types.NewBlockWithHeader(&types.Header{
+			Number: new(big.Int).SetUint64(number),
+			Round:  number / gov.roundLength,
+		})
+	}
+
+	pm.preconnectNextRound(head(95))
+	if pm.peers.HasConnection(1) {
+		t.Errorf("connection built without CRS of next round")
+	}
+	crsRound = 1
+	pm.preconnectNextRound(head(85))
+	if pm.peers.HasConnection(1) {
+		t.Errorf("connection built too early")
+	}
+	pm.preconnectNextRound(head(90))
+	if !pm.peers.HasConnection(1) {
+		t.Errorf("connection not built before round end")
+	}
+}
+
+func TestPeerSetSplitNotaries(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestP2PServer(key)
+
+	var nodes []*enode.Node
+	var peers []*peer
+	for i := 0; i < 5; i++ {
+		node := randomV4CompactNode()
+		nodes = append(nodes, node)
+		peers = append(peers, newPeer(dex64, p2p.NewPeerWithEnode(node, "", nil), nil))
+	}
+
+	gov := &testGovernance{}
+	gov.notarySetFunc = func(round uint64) (map[string]struct{}, error) {
+		if round == 10 {
+			return newTestNodeSet([]*enode.Node{nodes[3]}), nil
+		}
+		return newTestNodeSet([]*enode.Node{nodes[1], nodes[4]}), nil
+	}
+
+	ps := newPeerSet(gov, server)
+	ps.BuildConnection(10)
+	ps.BuildConnection(11)
+
+	notaries, others := ps.SplitNotaries(peers, 10, 11)
+	if want := []*peer{peers[1], peers[3], peers[4]}; !reflect.DeepEqual(notaries, want) {
+		t.Errorf("notaries mismatch: got %v, want %v", peerIDs(notaries), peerIDs(want))
+	}
+	if want := []*peer{peers[0], peers[2]}; !reflect.DeepEqual(others, want) {
+		t.Errorf("others mismatch: got %v, want %v", peerIDs(others), peerIDs(want))
+	}
+
+	notaries, others = ps.SplitNotaries(peers, 10)
+	if want := []*peer{peers[3]}; !reflect.DeepEqual(notaries, want) {
+		t.Errorf("notaries mismatch: got %v, want %v", peerIDs(notaries), peerIDs(want))
+	}
+	if want := []*peer{peers[0], peers[1], peers[2], peers[4]}; !reflect.DeepEqual(others, want) {
+		t.Errorf("others mismatch: got %v, want %v", peerIDs(others), peerIDs(want))
+	}
+}
+
+func peerIDs(peers []*peer) []string {
+	ids := make([]string, len(peers))
+	for i, p := range peers {
+		ids[i] = p.id
+	}
+	return ids
+}
+
+func TestMaintainNotaryMesh(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestP2PServer(key)
+
+	var nodes []*enode.Node
+	for i := 0; i < groupConnNum+2; i++ {
+		nodes = append(nodes, randomV4CompactNode())
+	}
+
+	round := uint64(1)
+	gov := &testGovernance{}
+	gov.lenCRSFunc = func() uint64 { return round }
+	gov.notarySetFunc = func(round uint64) (map[string]struct{}, error) {
+		return newTestNodeSet(nodes), nil
+	}
+	pm := &ProtocolManager{
+		gov:   gov,
+		peers: newPeerSet(gov, server),
+	}
+
+	pm.maintainNotaryMesh()
+	if !pm.peers.HasConnection(round) {
+		t.Errorf("connection of current round not built")
+	}
+	if pm.peers.HasConnection(round + 1) {
+		t.Errorf("connection built without CRS of next round")
+	}
+
+	// The connection is rebuilt once lost.
+	pm.peers.ForgetConnection(round)
+	pm.maintainNotaryMesh()
+	if !pm.peers.HasConnection(round) {
+		t.Errorf("connection of current round not rebuilt")
+	}
+
+	// Group connection peers which never connected are replaced by the
+	// members not dialed yet.
+	label := peerLabel{set: notaryset, round: round}
+	pm.peers.lock.Lock()
+	var stale string
+	for id := range pm.peers.groupConnPeers[label] {
+		stale = id
+		pm.peers.groupConnPeers[label][id] = time.Now().Add(-groupConnTimeout - time.Second)
+		break
+	}
+	pm.peers.lock.Unlock()
+
+	pm.maintainNotaryMesh()
+
+	pm.peers.lock.RLock()
+	defer pm.peers.lock.RUnlock()
+	if n := len(pm.peers.groupConnPeers[label]); n != groupConnNum {
+		t.Errorf("group connection peer count mismatch: got %d, want %d", n, groupConnNum)
+	}
+	if _, ok := pm.peers.groupConnPeers[label][stale]; ok {
+		t.Errorf("timed out group connection peer %s not replaced", stale)
+	}
+}
+
+func TestPeerSetPenalize(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := newPeerSet(&testGovernance{}, newTestP2PServer(key))
+
+	if ps.Penalize("a", peerBanThreshold/2, "test") {
+		t.Errorf("peer banned below the threshold")
+	}
+	// Half of the penalty is forgiven after a half life.
+	ps.scores["a"].updated = time.Now().Add(-peerPenaltyHalfLife)
+	if penalty := ps.Penalty("a"); penalty < peerBanThreshold/4-1 || penalty > peerBanThreshold/4+1 {
+		t.Errorf("decayed penalty mismatch: got %v, want %v", penalty, peerBanThreshold/4)
+	}
+	if ps.Penalize("a", peerBanThreshold/2, "test") {
+		t.Errorf("peer banned below the threshold after decay")
+	}
+	if !ps.Penalize("a", peerBanThreshold/2, "test") {
+		t.Errorf("peer not banned past the threshold")
+	}
+	if !ps.Banned("a") {
+		t.Errorf("banned peer not reported")
+	}
+	if ps.Banned("b") {
+		t.Errorf("unknown peer reported banned")
+	}
+
+	// Bans end after peerBanDuration.
+	ps.banned["a"] = time.Now().Add(-time.Second)
+	if ps.Banned("a") {
+		t.Errorf("peer banned after the ban ended")
+	}
+	if penalty := ps.Penalty("a"); penalty != 0 {
+		t.Errorf("penalty kept after the ban: %v", penalty)
+	}
+}
+
+// Tests that notary set members are never banned and that protocol errors
+// are penalized by their class.
+func TestPeerSetPenalizeExempt(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := newPeerSet(&testGovernance{}, newTestP2PServer(key))
+	ps.label2Nodes[peerLabel{set: notaryset, round: 1}] = map[string]*enode.Node{"notary": nil}
+
+	for i := 0; i < 3; i++ {
+		if ps.Penalize("notary", peerBanThreshold, "test") {
+			t.Fatalf("notary banned")
+		}
+	}
+	if ps.Banned("notary") || ps.Penalty("notary") != 0 {
+		t.Errorf("notary penalized")
+	}
+
+	// A single error of a class doesn't ban a peer, the invalid consensus
+	// data that honest peers may relay takes more of them than malformed
+	// messages, and incompatible peers aren't penalized.
+	if penalty := protocolErrorPenalty(ErrDecode); penalty <= 0 || penalty >= peerBanThreshold {
+		t.Errorf("malformed message penalty out of range: %v", penalty)
+	}
+	if protocolErrorPenalty(ErrInvalidVote) >= protocolErrorPenalty(ErrDecode) {
+		t.Errorf("invalid vote penalized as much as a malformed message")
+	}
+	if penalty := protocolErrorPenalty(ErrNetworkIdMismatch); penalty != 0 {
+		t.Errorf("incompatible peer penalized: %v", penalty)
+	}
+	if ps.Penalize("peer", protocolErrorPenalty(ErrNetworkIdMismatch), "test") || ps.Penalty("peer") != 0 {
+		t.Errorf("incompatible peer penalized")
+	}
+	for i := 0; i < peerBanThreshold/penaltyInvalidCoreMsg-1; i++ {
+		if ps.Penalize("peer", protocolErrorPenalty(ErrInvalidCoreBlock), "test") {
+			t.Fatalf("peer banned after %d invalid core blocks", i+1)
+		}
+	}
+}
+
+// Tests that notary connections are rekeyed oldest first in stages, skipping
+// the connections gone meanwhile.
+func TestPeerSetRekey(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := newPeerSet(&testGovernance{}, newTestP2PServer(key))
+	notaries := make(map[string]*enode.Node)
+	ps.label2Nodes[peerLabel{set: notaryset, round: 1}] = notaries
+
+	var conns []*peer
+	for i := 0; i < 7; i++ {
+		p := newPeer(dex64, p2p.NewPeer(enode.ID{byte(i + 1)}, "peer", nil), nil)
+		ps.peers[p.id] = p
+		if i != 3 {
+			notaries[p.id] = nil
+			conns = append(conns, p)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if stages := ps.rekeyStages(time.Hour, 4); len(stages) != 0 {
+		t.Fatalf("rekeying recent connections: %v", stages)
+	}
+	stages := ps.rekeyStages(0, 4)
+	if len(stages) != 2 || len(stages[0]) != 4 || len(stages[1]) != 2 {
+		t.Fatalf("stages mismatch: have %v", stages)
+	}
+	for i, p := range append(stages[0], stages[1]...) {
+		if p != conns[i] {
+			t.Errorf("connection %d mismatch: have %v, want %v", i, p, conns[i])
+		}
+	}
+
+	delete(ps.peers, conns[5].id)
+	if dropped := ps.rekey(stages, 0); dropped != 5 {
+		t.Errorf("dropped connections mismatch: have %d, want 5", dropped)
+	}
+
+	// Rekeys don't overlap, and stop once the peer set is closed.
+	ps.rekeying = true
+	if scheduled := ps.Rekey(0); scheduled != 0 {
+		t.Errorf("rekey scheduled while in progress: %d", scheduled)
+	}
+	ps.rekeying = false
+	ps.Close()
+	if scheduled := ps.Rekey(0); scheduled != 0 {
+		t.Errorf("rekey scheduled after close: %d", scheduled)
+	}
+	if dropped := ps.rekey(stages, 0); dropped != 0 {
+		t.Errorf("connections dropped after close: %d", dropped)
+	}
+}
+
+func TestUselessAnnouncesBan(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	peer, errc := newTestPeer("peer", dex64, pm, true)
+	defer pm.Stop()
+	defer peer.close()
+
+	// Announce a known block, which is not fetched.
+	genesis := pm.blockchain.Genesis()
+	announce := newBlockHashesData{{Hash: genesis.Hash(), Number: genesis.NumberU64()}}
+	if err := p2p.Send(peer.app, NewBlockHashesMsg, announce); err != nil {
+		t.Fatalf("failed to send announce: %v", err)
+	}
+	// Repeating the announce enough times bans the peer. The sender is
+	// unblocked once the pipe is closed.
+	go func() {
+		for {
+			if err := p2p.Send(peer.app, NewBlockHashesMsg, announce); err != nil {
+				return
+			}
+		}
+	}()
+	select {
+	case err := <-errc:
+		if err != p2p.DiscUselessPeer {
+			t.Errorf("disconnect reason mismatch: got %v, want %v", err, p2p.DiscUselessPeer)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("peer not disconnected")
+	}
+	if !pm.peers.Banned(peer.id) {
+		t.Errorf("peer not banned")
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"time"
+)
+
+// profileLabel is the pprof label key attributing goroutines to the consensus
+// subsystems. The agreement and DKG goroutines of the consensus core are
+// labeled "agreement" and "dkg", and override the "consensus" label they
+// inherit.
+const profileLabel = "subsystem"
+
+const (
+	profileConsensus = "consensus" // Consensus core not covered by other labels
+	profileNetwork   = "network"   // Peer message handlers
+)
+
+// maxConsensusProfileDuration is the longest CPU profile captured by
+// ConsensusProfile.
+const maxConsensusProfileDuration = 5 * time.Minute
+
+// withProfileLabel runs f with the pprof label of subsystem, which is
+// inherited by all goroutines started by f. The labeled context is passed to
+// f, to derive more labels from.
+func withProfileLabel(subsystem string, f func(ctx context.Context)) {
+	pprof.Do(context.Background(), pprof.Labels(profileLabel, subsystem), f)
+}
+
+// captureCPUProfile captures a CPU profile for the given duration.
+func captureCPUProfile(ctx context.Context, duration time.Duration) ([]byte, error) {
+	if duration <= 0 || duration > maxConsensusProfileDuration {
+		return nil, fmt.Errorf("invalid profile duration %v, max %v", duration,
+			maxConsensusProfileDuration)
+	}
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return nil, err
+	}
+	select {
+	case <-time.After(duration):
+	case <-ctx.Done():
+	}
+	pprof.StopCPUProfile()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
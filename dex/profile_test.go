@@ -0,0 +1,100 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/dex/downloader"
+	"github.com/portto/go-tangerine/p2p"
+	"github.com/portto/go-tangerine/p2p/enode"
+)
+
+func TestCaptureCPUProfile(t *testing.T) {
+	for _, d := range []time.Duration{0, maxConsensusProfileDuration + time.Second} {
+		if _, err := captureCPUProfile(context.Background(), d); err == nil {
+			t.Errorf("profile of duration %v captured", d)
+		}
+	}
+
+	profile, err := captureCPUProfile(context.Background(), 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to capture profile: %v", err)
+	}
+	if len(profile) == 0 {
+		t.Errorf("empty profile captured")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := captureCPUProfile(ctx, time.Minute); err != context.Canceled {
+		t.Errorf("canceled profile error mismatch: have %v, want %v", err, context.Canceled)
+	}
+}
+
+// Tests that the message label is dropped once a message is handled, leaving
+// the subsystem label of the peer handler in place.
+func TestHandleMsgProfileLabels(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+	pm.msgProfilingLabels = true
+
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	p := pm.newPeer(dex64, p2p.NewPeer(enode.ID{1}, "peer", nil), net)
+	go p2p.Send(app, TxMsg, []*types.Transaction{})
+
+	var labels string
+	withProfileLabel(profileNetwork, func(ctx context.Context) {
+		if err := pm.handleMsg(ctx, p); err != nil {
+			t.Fatalf("failed to handle message: %v", err)
+		}
+		labels = goroutineLabels(t)
+	})
+	if want := `{"subsystem":"network"}`; labels != want {
+		t.Errorf("labels mismatch: have %s, want %s", labels, want)
+	}
+}
+
+// goroutineLabels returns the pprof labels of the calling goroutine, as
+// printed in goroutine profiles.
+func goroutineLabels(t *testing.T) string {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		t.Fatalf("failed to write goroutine profile: %v", err)
+	}
+	for _, record := range strings.Split(buf.String(), "\n\n") {
+		if !strings.Contains(record, "dex.goroutineLabels") {
+			continue
+		}
+		for _, line := range strings.Split(record, "\n") {
+			if strings.HasPrefix(line, "# labels: ") {
+				return strings.TrimPrefix(line, "# labels: ")
+			}
+		}
+		return ""
+	}
+	t.Fatal("calling goroutine not in profile")
+	return ""
+}
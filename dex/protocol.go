@@ -38,6 +38,8 @@ import (
 	"fmt"
 	"io"
 
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/core"
 	"github.com/portto/go-tangerine/core/types"
@@ -50,19 +52,26 @@ import (
 // Constants to match up protocol versions and messages
 const (
 	dex64 = 64
+	dex65 = 65
 )
 
 // ProtocolName is the official short name of the protocol used during capability negotiation.
 var ProtocolName = "dex"
 
 // ProtocolVersions are the supported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{dex64}
+var ProtocolVersions = []uint{dex65, dex64}
 
 // ProtocolLengths are the number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{43}
+var ProtocolLengths = []uint64{48, 43}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
+// Messages are not compressed by the protocol. The RLPx transport snappy
+// compresses the payload of every message once both sides speak devp2p v5,
+// which every node does, so core blocks, votes, agreement results and DKG
+// messages are already compressed on the wire and compressing them here
+// would only cost CPU.
+
 // eth protocol message codes
 const (
 	// Protocol messages belonging to eth/62
@@ -92,6 +101,13 @@ const (
 
 	GetGovStateMsg = 0x29
 	GovStateMsg    = 0x2a
+
+	// Protocol messages belonging to dex/65
+	NotaryClaimMsg                = 0x2b
+	StateRootMsg                  = 0x2c
+	NewPooledTransactionHashesMsg = 0x2d
+	GetPooledTransactionsMsg      = 0x2e
+	PooledTransactionsMsg         = 0x2f
 )
 
 type errCode int
@@ -107,12 +123,17 @@ const (
 	ErrExtraStatusMsg
 	ErrSuspendedPeer
 	ErrInvalidGovStateMsg
+	ErrInvalidCoreBlock
+	ErrInvalidNotaryClaim
+	ErrInvalidStateRoot
+	ErrInvalidVote
 )
 
 const (
 	fetcherReq = uint8(iota)
 	downloaderReq
 	whitelistReq
+	scrubberReq
 )
 
 func (e errCode) String() string {
@@ -130,12 +151,20 @@ var errorToString = map[int]string{
 	ErrNoStatusMsg:             "No status message",
 	ErrExtraStatusMsg:          "Extra status message",
 	ErrSuspendedPeer:           "Suspended peer",
+	ErrInvalidCoreBlock:        "Invalid core block",
+	ErrInvalidNotaryClaim:      "Invalid notary claim",
+	ErrInvalidStateRoot:        "Invalid state root",
+	ErrInvalidVote:             "Invalid vote",
 }
 
 type txPool interface {
 	// AddRemotes should add the given transactions to the pool.
 	AddRemotes([]*types.Transaction) []error
 
+	// Get should return the transaction of the given hash if it is in the
+	// pool, nil otherwise.
+	Get(hash common.Hash) *types.Transaction
+
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending() (map[common.Address]types.Transactions, error)
@@ -148,6 +177,8 @@ type txPool interface {
 type governance interface {
 	GetRoundHeight(uint64) uint64
 
+	Configuration(uint64) *coreTypes.Config
+
 	Round() uint64
 
 	CRSRound() uint64
@@ -183,6 +214,59 @@ type statusData struct {
 	GenesisBlock    common.Hash
 }
 
+// statusData65 is the network packet for the status message of dex/65.
+type statusData65 struct {
+	ProtocolVersion uint32
+	NetworkId       uint64
+	Number          uint64
+	CurrentBlock    common.Hash
+	GenesisBlock    common.Hash
+
+	// Challenge is a fresh random value the remote side has to sign if it
+	// claims notary set membership. Notary is set if the sender is going to
+	// follow up with such a claim.
+	Challenge common.Hash
+	Notary    bool
+}
+
+// notaryClaimData is the network packet proving that the sender controls the
+// node key of a notary set member.
+type notaryClaimData struct {
+	ID        enode.ID
+	Signature []byte // Signature over the challenge picked by the receiver
+}
+
+// notaryClaimHash returns the digest signed in a notary claim.
+func notaryClaimHash(challenge common.Hash, id enode.ID) common.Hash {
+	hw := sha3.NewLegacyKeccak256()
+	hw.Write([]byte("dex notary claim"))
+	hw.Write(challenge[:])
+	hw.Write(id[:])
+	var h common.Hash
+	hw.Sum(h[:0])
+	return h
+}
+
+// stateRootData is the network packet announcing the state root a notary set
+// member reached at the first block of a round.
+type stateRootData struct {
+	Round     uint64
+	Number    uint64
+	Hash      common.Hash // Hash of the block
+	Root      common.Hash // State root after executing the block
+	Signature []byte      // Signature of the announcer's node key
+}
+
+// stateRootHash returns the digest signed in a state root announcement.
+func stateRootHash(d *stateRootData) common.Hash {
+	hw := sha3.NewLegacyKeccak256()
+	hw.Write([]byte("dex state root"))
+	rlp.Encode(hw, []interface{}{d.Round, d.Number, d.Hash, d.Root})
+	var h common.Hash
+	hw.Sum(h[:0])
+	return h
+}
+
 // newBlockHashesData is the network packet for the block announcements.
 type newBlockHashesData []struct {
 	Hash   common.Hash // Hash of one particular block being announced
@@ -20,6 +20,7 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -27,13 +28,16 @@ import (
 	coreCommon "github.com/portto/tangerine-consensus/common"
 	coreCrypto "github.com/portto/tangerine-consensus/core/crypto"
 	"github.com/portto/tangerine-consensus/core/crypto/dkg"
+	coreEcdsa "github.com/portto/tangerine-consensus/core/crypto/ecdsa"
 	coreTypes "github.com/portto/tangerine-consensus/core/types"
 	dkgTypes "github.com/portto/tangerine-consensus/core/types/dkg"
+	coreUtils "github.com/portto/tangerine-consensus/core/utils"
 
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/core/types"
 	"github.com/portto/go-tangerine/crypto"
 	"github.com/portto/go-tangerine/dex/downloader"
+	"github.com/portto/go-tangerine/internal/features"
 	"github.com/portto/go-tangerine/p2p"
 	"github.com/portto/go-tangerine/p2p/enode"
 	"github.com/portto/go-tangerine/rlp"
@@ -127,6 +131,12 @@ func testRecvTransactions(t *testing.T, protocol int) {
 	case <-time.After(2 * time.Second):
 		t.Errorf("no NewTxsEvent received within 2 seconds")
 	}
+	if record := pm.txProps.get(tx.Hash()); record == nil {
+		t.Errorf("transaction propagation not recorded")
+	} else if record.Origin != TxOriginPeer || record.Peer != p.id {
+		t.Errorf("propagation origin mismatch: got %s from %q, want %s from %q",
+			record.Origin, record.Peer, TxOriginPeer, p.id)
+	}
 }
 
 // This test checks that pending transactions are sent.
@@ -187,6 +197,119 @@ func testSendTransactions(t *testing.T, protocol int) {
 	wg.Wait()
 }
 
+// Tests that announced transactions are fetched and delivered to the pool.
+func TestFetchAnnouncedTransactions(t *testing.T) {
+	txAdded := make(chan []*types.Transaction)
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, txAdded)
+	pm.acceptTxs = 1 // mark synced to accept transactions
+	p, _ := newTestPeer("peer", dex65, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	if err := p2p.Send(p.app, NewPooledTransactionHashesMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if err := p2p.ExpectMsg(p.app, GetPooledTransactionsMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("transaction request mismatch: %v", err)
+	}
+	if err := p2p.Send(p.app, PooledTransactionsMsg, []*types.Transaction{tx}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case added := <-txAdded:
+		if len(added) != 1 || added[0].Hash() != tx.Hash() {
+			t.Errorf("added transactions mismatch: got %v, want %x", added, tx.Hash())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("fetched transaction not added within 2 seconds")
+	}
+
+	// A transaction known to the pool is not fetched again.
+	other := newTestTransaction(testAccount, 1, 0)
+	hashes := []common.Hash{tx.Hash(), other.Hash()}
+	if err := p2p.Send(p.app, NewPooledTransactionHashesMsg, hashes); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if err := p2p.ExpectMsg(p.app, GetPooledTransactionsMsg, []common.Hash{other.Hash()}); err != nil {
+		t.Fatalf("transaction request mismatch: %v", err)
+	}
+}
+
+// Tests that an announced transaction withheld by the peer it is requested
+// from is requested from another peer announcing it once the request times out.
+func TestFetchWithheldTransactions(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.acceptTxs = 1 // mark synced to accept transactions
+	p1, _ := newTestPeer("peer1", dex65, pm, true)
+	p2, _ := newTestPeer("peer2", dex65, pm, true)
+	defer pm.Stop()
+	defer p1.close()
+	defer p2.close()
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	if err := p2p.Send(p1.app, NewPooledTransactionHashesMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if err := p2p.ExpectMsg(p1.app, GetPooledTransactionsMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("transaction request mismatch: %v", err)
+	}
+	if err := p2p.Send(p2.app, NewPooledTransactionHashesMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	// Messages of a peer are handled in order, so once the retrieval below is
+	// served the announcement has been handled without requesting the
+	// transaction again.
+	if err := p2p.Send(p2.app, GetPooledTransactionsMsg, []common.Hash{}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if err := p2p.ExpectMsg(p2.app, PooledTransactionsMsg, []*types.Transaction{}); err != nil {
+		t.Fatalf("pooled transactions mismatch: %v", err)
+	}
+
+	go pm.retryTxRequests(time.Now().Add(txFetchTimeout))
+	if err := p2p.ExpectMsg(p2.app, GetPooledTransactionsMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("transaction request mismatch: %v", err)
+	}
+}
+
+// Tests that pooled transactions are served and announced to dex/65 peers.
+func TestServePooledTransactions(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	p, _ := newTestPeer("peer", dex65, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	pm.txpool.AddRemotes([]*types.Transaction{tx})
+
+	request := []common.Hash{tx.Hash(), {1}}
+	if err := p2p.Send(p.app, GetPooledTransactionsMsg, request); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if err := p2p.ExpectMsg(p.app, PooledTransactionsMsg, []*types.Transaction{tx}); err != nil {
+		t.Fatalf("pooled transactions mismatch: %v", err)
+	}
+
+	other := newTestTransaction(testAccount, 1, 0)
+	pm.BroadcastTxs(types.Transactions{other})
+	if err := p2p.ExpectMsg(p.app, NewPooledTransactionHashesMsg, []common.Hash{other.Hash()}); err != nil {
+		t.Fatalf("transaction announcement mismatch: %v", err)
+	}
+
+	// Without the tx-announcements feature, full transactions are relayed.
+	if err := features.Apply("-tx-announcements"); err != nil {
+		t.Fatalf("failed to disable transaction announcements: %v", err)
+	}
+	defer features.Apply("tx-announcements")
+
+	last := newTestTransaction(testAccount, 2, 0)
+	pm.BroadcastTxs(types.Transactions{last})
+	if err := p2p.ExpectMsg(p.app, TxMsg, []*types.Transaction{last}); err != nil {
+		t.Fatalf("transaction relay mismatch: %v", err)
+	}
+}
+
 // Tests that the custom union field encoder and decoder works correctly.
 func TestGetBlockHeadersDataEncodeDecode(t *testing.T) {
 	// Create a "random" hash for testing
@@ -247,8 +370,9 @@ func TestRecvCoreBlocks(t *testing.T) {
 			Round:  12,
 			Height: 13,
 		},
-		Timestamp: time.Now().UTC(),
-		Payload:   []byte{3, 3, 3, 3, 3},
+		Timestamp:   time.Now().UTC(),
+		Payload:     []byte{3, 3, 3, 3, 3},
+		PayloadHash: coreCrypto.Keccak256Hash([]byte{3, 3, 3, 3, 3}),
 		Witness: coreTypes.Witness{
 			Height: 13,
 			Data:   []byte{4, 4, 4, 4, 4},
@@ -263,6 +387,10 @@ func TestRecvCoreBlocks(t *testing.T) {
 			Signature: []byte("crs-signature"),
 		},
 	}
+	prv, _ := coreEcdsa.NewPrivateKey()
+	if err := coreUtils.NewSigner(prv).SignBlock(&block); err != nil {
+		t.Fatalf("failed to sign block: %v", err)
+	}
 
 	if err := p2p.Send(p.app, CoreBlockMsg, []*coreTypes.Block{&block}); err != nil {
 		t.Fatalf("send error: %v", err)
@@ -280,6 +408,107 @@ func TestRecvCoreBlocks(t *testing.T) {
 	}
 }
 
+// Tests that core blocks with a corrupt payload are dropped and the peer
+// sending them is disconnected.
+func TestRecvCoreBlocksCorruptPayload(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.SetReceiveCoreMessage(true)
+
+	p, errc := newTestPeer("peer", dex64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	block := coreTypes.Block{
+		ProposerID: coreTypes.NodeID{Hash: coreCommon.Hash{1, 2, 3}},
+		Hash:       coreCommon.Hash{2, 2, 2, 2, 2},
+		Position: coreTypes.Position{
+			Round:  12,
+			Height: 13,
+		},
+		Timestamp:   time.Now().UTC(),
+		Payload:     []byte{3, 3, 3, 3, 3},
+		PayloadHash: coreCrypto.Keccak256Hash([]byte{6, 6, 6, 6, 6}),
+	}
+
+	if err := p2p.Send(p.app, CoreBlockMsg, []*coreTypes.Block{&block}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+
+	select {
+	case err := <-errc:
+		if err == nil || !strings.Contains(err.Error(), errorToString[ErrInvalidCoreBlock]) {
+			t.Errorf("wrong disconnect error: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Errorf("peer not disconnected within 3 seconds")
+	}
+	select {
+	case msg := <-pm.ReceiveChan():
+		t.Errorf("corrupt core block forwarded: %v", msg.Payload)
+	default:
+	}
+}
+
+// Tests that empty finalized blocks, which are hashed by every node without
+// being signed, are forwarded to the consensus core, and that the peer stays
+// connected.
+func TestRecvEmptyCoreBlocks(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.SetReceiveCoreMessage(true)
+
+	p, errc := newTestPeer("peer", dex64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	block := coreTypes.Block{
+		ParentHash: coreCommon.Hash{1, 1, 1, 1, 1},
+		Position: coreTypes.Position{
+			Round:  12,
+			Height: 13,
+		},
+		Timestamp: time.Now().UTC(),
+		Witness: coreTypes.Witness{
+			Height: 12,
+			Data:   []byte{4, 4, 4, 4, 4},
+		},
+		Randomness: []byte{5, 5, 5, 5, 5},
+	}
+	hash, err := coreUtils.HashBlock(&block)
+	if err != nil {
+		t.Fatalf("failed to hash block: %v", err)
+	}
+	block.Hash = hash
+
+	if err := p2p.Send(p.app, CoreBlockMsg, []*coreTypes.Block{&block}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+
+	select {
+	case msg := <-pm.ReceiveChan():
+		if rb := msg.Payload.(*coreTypes.Block); rb.Hash != block.Hash {
+			t.Errorf("block mismatch: have %v, want %v", rb.Hash, block.Hash)
+		}
+	case err := <-errc:
+		t.Fatalf("peer disconnected: %v", err)
+	case <-time.After(3 * time.Second):
+		t.Errorf("no core block received within 3 seconds")
+	}
+
+	// An empty block whose hash doesn't match is still rejected.
+	block.Hash = coreCommon.Hash{2, 2, 2, 2, 2}
+	if err := p2p.Send(p.app, CoreBlockMsg, []*coreTypes.Block{&block}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if err == nil || !strings.Contains(err.Error(), errorToString[ErrInvalidCoreBlock]) {
+			t.Errorf("wrong disconnect error: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Errorf("peer not disconnected within 3 seconds")
+	}
+}
+
 func TestSendCoreBlocks(t *testing.T) {
 	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
 	pm.SetReceiveCoreMessage(true)
@@ -379,6 +608,18 @@ func TestSendCoreBlocks(t *testing.T) {
 	waitForRegister(pm, len(testPeers))
 	pm.BroadcastCoreBlock(&block)
 	wg.Wait()
+	checkNoLoopback(t, pm)
+}
+
+// checkNoLoopback checks that the consensus messages the node broadcast are
+// not passed back to its consensus core, which processed them in memory
+// before broadcasting.
+func checkNoLoopback(t *testing.T, pm *ProtocolManager) {
+	select {
+	case msg := <-pm.ReceiveChan():
+		t.Errorf("broadcast message looped back: %v", msg.Payload)
+	default:
+	}
 }
 
 func TestRecvVotes(t *testing.T) {
@@ -402,10 +643,10 @@ func TestRecvVotes(t *testing.T) {
 			Type:      "456",
 			Signature: []byte("psig"),
 		},
-		Signature: coreCrypto.Signature{
-			Type:      "123",
-			Signature: []byte("sig"),
-		},
+	}
+	prv, _ := coreEcdsa.NewPrivateKey()
+	if err := coreUtils.NewSigner(prv).SignVote(&vote); err != nil {
+		t.Fatalf("failed to sign vote: %v", err)
 	}
 
 	if err := p2p.Send(p.app, VoteMsg, []*coreTypes.Vote{&vote}); err != nil {
@@ -425,8 +666,49 @@ func TestRecvVotes(t *testing.T) {
 	}
 }
 
+// Tests that votes with an invalid signature are dropped and the peer
+// sending them is disconnected.
+func TestRecvVotesInvalidSignature(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.SetReceiveCoreMessage(true)
+
+	p, errc := newTestPeer("peer", dex64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	vote := coreTypes.Vote{
+		VoteHeader: coreTypes.VoteHeader{
+			Period:   10,
+			Position: coreTypes.Position{Round: 12, Height: 13},
+		},
+	}
+	prv, _ := coreEcdsa.NewPrivateKey()
+	if err := coreUtils.NewSigner(prv).SignVote(&vote); err != nil {
+		t.Fatalf("failed to sign vote: %v", err)
+	}
+	vote.Period++
+
+	if err := p2p.Send(p.app, VoteMsg, []*coreTypes.Vote{&vote}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if err == nil || !strings.Contains(err.Error(), errorToString[ErrInvalidVote]) {
+			t.Errorf("wrong disconnect error: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Errorf("peer not disconnected within 3 seconds")
+	}
+	select {
+	case msg := <-pm.ReceiveChan():
+		t.Errorf("invalid vote forwarded: %v", msg.Payload)
+	default:
+	}
+}
+
 func TestSendVotes(t *testing.T) {
 	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.SetReceiveCoreMessage(true)
 	defer pm.Stop()
 
 	vote := coreTypes.Vote{
@@ -517,6 +799,7 @@ func TestSendVotes(t *testing.T) {
 	waitForRegister(pm, len(testPeers))
 	pm.BroadcastVote(&vote)
 	wg.Wait()
+	checkNoLoopback(t, pm)
 }
 
 type mockPublicKey ecdsa.PublicKey
@@ -541,7 +824,7 @@ func TestRecvDKGPrivateShare(t *testing.T) {
 	privateShare := dkgTypes.PrivateShare{
 		ProposerID:   coreTypes.NodeID{coreCommon.Hash{1, 2, 3}},
 		ReceiverID:   coreTypes.NodeID{coreCommon.Hash{3, 4, 5}},
-		Round:        10,
+		Round:        1,
 		PrivateShare: *privkey,
 		Signature: coreCrypto.Signature{
 			Type:      "DKGPrivateShare",
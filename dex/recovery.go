@@ -18,7 +18,6 @@
 package dex
 
 import (
-	"crypto/ecdsa"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -260,29 +259,36 @@ type Recovery struct {
 	gov          *DexconGovernance
 	contract     common.Address
 	confirmation int
-	publicKey    string
-	privateKey   *ecdsa.PrivateKey
-	nodeAddress  common.Address
+	signer       NodeSigner
 	client       *ethrpc.EthRPC
 }
 
 func NewRecovery(config *params.RecoveryConfig, networkRPC string,
-	gov *DexconGovernance, privKey *ecdsa.PrivateKey) *Recovery {
+	gov *DexconGovernance, signer NodeSigner) *Recovery {
 	client := ethrpc.New(networkRPC)
 	return &Recovery{
 		gov:          gov,
 		contract:     config.Contract,
 		confirmation: config.Confirmation,
-		publicKey:    hex.EncodeToString(crypto.FromECDSAPub(&privKey.PublicKey)),
-		privateKey:   privKey,
-		nodeAddress:  crypto.PubkeyToAddress(privKey.PublicKey),
+		signer:       signer,
 		client:       client,
 	}
 }
 
+// publicKey returns the hex encoded node key, as found in notary sets. It is
+// read from the signer every time as the node key may be rotated.
+func (r *Recovery) publicKey() string {
+	return hex.EncodeToString(crypto.FromECDSAPub(r.signer.PublicKey()))
+}
+
+// nodeAddress returns the address of the node key.
+func (r *Recovery) nodeAddress() common.Address {
+	return crypto.PubkeyToAddress(*r.signer.PublicKey())
+}
+
 func (r *Recovery) callRPC(data []byte, tag string) ([]byte, error) {
 	res, err := r.client.EthCall(ethrpc.T{
-		From: r.nodeAddress.String(),
+		From: r.nodeAddress().String(),
 		To:   r.contract.String(),
 		Data: "0x" + hex.EncodeToString(data),
 	}, tag)
@@ -308,7 +314,7 @@ func (r *Recovery) genVoteForSkipBlockTx(height uint64) (*types.Transaction, err
 		return nil, err
 	}
 
-	data, err := abiObject.Pack("voted", big.NewInt(int64(height)), r.nodeAddress)
+	data, err := abiObject.Pack("voted", big.NewInt(int64(height)), r.nodeAddress())
 	if err != nil {
 		return nil, err
 	}
@@ -355,7 +361,7 @@ func (r *Recovery) genVoteForSkipBlockTx(height uint64) (*types.Transaction, err
 		return nil, err
 	}
 
-	nonce, err := r.client.EthGetTransactionCount(r.nodeAddress.String(), "pending")
+	nonce, err := r.client.EthGetTransactionCount(r.nodeAddress().String(), "pending")
 	if err != nil {
 		return nil, err
 	}
@@ -373,7 +379,7 @@ func (r *Recovery) genVoteForSkipBlockTx(height uint64) (*types.Transaction, err
 		data)
 
 	signer := types.NewEIP155Signer(big.NewInt(int64(networkID)))
-	return types.SignTx(tx, signer, r.privateKey)
+	return signTx(tx, signer, r.signer)
 }
 
 func (r *Recovery) ProposeSkipBlock(height uint64) error {
@@ -381,7 +387,7 @@ func (r *Recovery) ProposeSkipBlock(height uint64) error {
 	if err != nil {
 		return err
 	}
-	if _, ok := notarySet[r.publicKey]; !ok {
+	if _, ok := notarySet[r.publicKey()]; !ok {
 		return errors.New("not in notary set")
 	}
 
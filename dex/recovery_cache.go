@@ -0,0 +1,198 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/portto/go-tangerine/log"
+)
+
+const (
+	// recoveryVotesTTL is how long fetched votes are served without being
+	// revalidated.
+	recoveryVotesTTL = 5 * time.Second
+
+	// recoveryVotesWait is how long a query without cached votes waits for
+	// the first fetch.
+	recoveryVotesWait = 3 * time.Second
+
+	// Bounds of the exponential backoff between failed fetches.
+	recoveryMinBackoff = time.Second
+	recoveryMaxBackoff = time.Minute
+
+	// maxRecoveryVotesEntries is the number of heights votes are cached for.
+	maxRecoveryVotesEntries = 16
+)
+
+var errRecoveryVotesPending = errors.New("recovery votes not fetched yet")
+
+// recoveryBackend is the recovery contract access cached by cachedRecovery.
+type recoveryBackend interface {
+	ProposeSkipBlock(height uint64) error
+	Votes(height uint64) (uint64, error)
+}
+
+// recoveryVotes is the cached vote count of a height.
+type recoveryVotes struct {
+	votes   uint64
+	valid   bool      // Whether votes was ever fetched
+	fetched time.Time // Time of the last successful fetch, zero if outdated
+	err     error     // Error of the last failed fetch
+
+	failures    int
+	nextAttempt time.Time     // Earliest next fetch after failures
+	refreshing  chan struct{} // Closed once the running fetch is done
+}
+
+// cachedRecovery serves the recovery votes queried by the WatchCat from a
+// cache refreshed in the background, so that a slow or failing recovery
+// endpoint does not stall the force sync decision. Outdated counts are
+// served while being revalidated, and failing fetches are retried with
+// exponential backoff.
+type cachedRecovery struct {
+	backend recoveryBackend
+
+	lock    sync.Mutex
+	entries map[uint64]*recoveryVotes
+}
+
+func newCachedRecovery(backend recoveryBackend) *cachedRecovery {
+	return &cachedRecovery{
+		backend: backend,
+		entries: make(map[uint64]*recoveryVotes),
+	}
+}
+
+// ProposeSkipBlock implements core.Recovery, outdating the cached votes of
+// height once proposed.
+func (r *cachedRecovery) ProposeSkipBlock(height uint64) error {
+	if err := r.backend.ProposeSkipBlock(height); err != nil {
+		return err
+	}
+	r.lock.Lock()
+	if entry, ok := r.entries[height]; ok {
+		entry.fetched = time.Time{}
+	}
+	r.lock.Unlock()
+	return nil
+}
+
+// Votes implements core.Recovery.
+func (r *cachedRecovery) Votes(height uint64) (uint64, error) {
+	r.lock.Lock()
+	now := time.Now()
+	entry := r.entry(height)
+	if entry.valid {
+		if now.Sub(entry.fetched) < recoveryVotesTTL {
+			recoveryVotesHitMeter.Mark(1)
+		} else {
+			recoveryVotesStaleMeter.Mark(1)
+			r.refresh(height, entry, now)
+		}
+		votes := entry.votes
+		r.lock.Unlock()
+		return votes, nil
+	}
+	recoveryVotesMissMeter.Mark(1)
+	done := r.refresh(height, entry, now)
+	r.lock.Unlock()
+
+	if done != nil {
+		select {
+		case <-done:
+		case <-time.After(recoveryVotesWait):
+		}
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if entry.valid {
+		return entry.votes, nil
+	}
+	if entry.err != nil {
+		return 0, entry.err
+	}
+	return 0, errRecoveryVotesPending
+}
+
+// entry returns the cache entry of height, evicting the lowest height if the
+// cache is full. The caller must hold the lock.
+func (r *cachedRecovery) entry(height uint64) *recoveryVotes {
+	if entry, ok := r.entries[height]; ok {
+		return entry
+	}
+	if len(r.entries) >= maxRecoveryVotesEntries {
+		lowest := height
+		for h := range r.entries {
+			if h < lowest {
+				lowest = h
+			}
+		}
+		delete(r.entries, lowest)
+	}
+	entry := new(recoveryVotes)
+	r.entries[height] = entry
+	return entry
+}
+
+// refresh starts fetching the votes of height unless a fetch is running or
+// backing off, returning the channel closed once the fetch is done, if any.
+// The caller must hold the lock.
+func (r *cachedRecovery) refresh(height uint64, entry *recoveryVotes, now time.Time) chan struct{} {
+	if entry.refreshing != nil {
+		return entry.refreshing
+	}
+	if now.Before(entry.nextAttempt) {
+		return nil
+	}
+	done := make(chan struct{})
+	entry.refreshing = done
+
+	go func() {
+		defer close(done)
+
+		start := time.Now()
+		votes, err := r.backend.Votes(height)
+		recoveryVotesFetchTimer.UpdateSince(start)
+
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		entry.refreshing = nil
+		if err != nil {
+			recoveryVotesFailureMeter.Mark(1)
+			backoff := recoveryMaxBackoff
+			if entry.failures < 16 {
+				if backoff = recoveryMinBackoff << uint(entry.failures); backoff > recoveryMaxBackoff {
+					backoff = recoveryMaxBackoff
+				}
+			}
+			entry.failures++
+			entry.err = err
+			entry.nextAttempt = time.Now().Add(backoff)
+			log.Warn("Failed to fetch recovery votes", "height", height,
+				"failures", entry.failures, "retry", backoff, "err", err)
+			return
+		}
+		entry.votes, entry.valid = votes, true
+		entry.fetched = time.Now()
+		entry.err, entry.failures, entry.nextAttempt = nil, 0, time.Time{}
+	}()
+	return done
+}
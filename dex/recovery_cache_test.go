@@ -0,0 +1,117 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testRecoveryBackend struct {
+	lock  sync.Mutex
+	votes uint64
+	err   error
+	calls int
+}
+
+func (b *testRecoveryBackend) ProposeSkipBlock(height uint64) error {
+	return nil
+}
+
+func (b *testRecoveryBackend) Votes(height uint64) (uint64, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.calls++
+	return b.votes, b.err
+}
+
+func (b *testRecoveryBackend) set(votes uint64, err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.votes, b.err = votes, err
+}
+
+func (b *testRecoveryBackend) callCount() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.calls
+}
+
+// waitRefresh waits for the running fetch of height, if any.
+func waitRefresh(r *cachedRecovery, height uint64) {
+	r.lock.Lock()
+	done := r.entries[height].refreshing
+	r.lock.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// Tests that recovery votes are cached, served stale while revalidated, and
+// that failed fetches back off.
+func TestCachedRecovery(t *testing.T) {
+	backend := &testRecoveryBackend{votes: 3}
+	r := newCachedRecovery(backend)
+
+	if votes, err := r.Votes(1); err != nil || votes != 3 {
+		t.Fatalf("first votes mismatch: have %d, %v, want 3", votes, err)
+	}
+	backend.set(4, nil)
+	if votes, _ := r.Votes(1); votes != 3 || backend.callCount() != 1 {
+		t.Fatalf("fresh votes refetched: have %d after %d calls", votes, backend.callCount())
+	}
+
+	// Outdated votes are served while being revalidated.
+	r.lock.Lock()
+	r.entries[1].fetched = time.Now().Add(-recoveryVotesTTL)
+	r.lock.Unlock()
+	if votes, _ := r.Votes(1); votes != 3 {
+		t.Fatalf("stale votes mismatch: have %d, want 3", votes)
+	}
+	waitRefresh(r, 1)
+	if votes, _ := r.Votes(1); votes != 4 {
+		t.Fatalf("revalidated votes mismatch: have %d, want 4", votes)
+	}
+
+	// Failures are reported until a fetch succeeds, without refetching
+	// while backing off.
+	failure := errors.New("unavailable")
+	backend.set(5, failure)
+	if _, err := r.Votes(2); err != failure {
+		t.Fatalf("error mismatch: have %v, want %v", err, failure)
+	}
+	calls := backend.callCount()
+	if _, err := r.Votes(2); err != failure || backend.callCount() != calls {
+		t.Fatalf("fetched while backing off: %v after %d calls", err, backend.callCount())
+	}
+	r.lock.Lock()
+	if entry := r.entries[2]; entry.failures != 1 ||
+		time.Until(entry.nextAttempt) > recoveryMinBackoff {
+		t.Errorf("backoff mismatch: %d failures, retry in %v", entry.failures,
+			time.Until(entry.nextAttempt))
+	}
+	r.entries[2].nextAttempt = time.Time{}
+	r.lock.Unlock()
+
+	backend.set(5, nil)
+	if votes, err := r.Votes(2); err != nil || votes != 5 {
+		t.Fatalf("recovered votes mismatch: have %d, %v, want 5", votes, err)
+	}
+}
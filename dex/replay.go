@@ -0,0 +1,88 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"fmt"
+
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
+	"github.com/portto/go-tangerine/core/types"
+	dexDB "github.com/portto/go-tangerine/dex/db"
+	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/rlp"
+)
+
+// replayDelivered executes the core blocks the consensus core delivered
+// without the node executing them, as happens if it stopped in between. The
+// compaction chain is walked back from its tip down to the chain head, and
+// the missing blocks are executed in order. It returns the number of blocks
+// executed.
+func (d *DexconApp) replayDelivered(db *dexDB.DB) (int, error) {
+	d.appMu.Lock()
+	defer d.appMu.Unlock()
+
+	head := d.blockchain.CurrentBlock()
+	hash, height := db.GetCompactionChainTipInfo()
+	if height <= head.NumberU64() {
+		return 0, nil
+	}
+	log.Warn("Consensus core ahead of the chain, replaying delivered blocks",
+		"core", height, "chain", head.NumberU64())
+
+	blocks := make([]*coreTypes.Block, 0, height-head.NumberU64())
+	for ; height > head.NumberU64(); height-- {
+		block, err := db.GetBlock(hash)
+		if err != nil {
+			return 0, fmt.Errorf("delivered core block %d (%s) not found: %v",
+				height, hash.String(), err)
+		}
+		if block.Position.Height != height || !block.IsFinalized() {
+			return 0, fmt.Errorf("core block %s is not the finalized block %d",
+				hash.String(), height)
+		}
+		blocks = append(blocks, &block)
+		hash = block.ParentHash
+	}
+	if head.NumberU64() > 0 {
+		headBlock, err := head.Header().CoreBlock()
+		if err != nil {
+			return 0, err
+		}
+		if headBlock.Hash != hash {
+			return 0, fmt.Errorf("compaction chain does not extend chain head %d: have parent %s, want %s",
+				head.NumberU64(), hash.String(), headBlock.Hash.String())
+		}
+	}
+
+	for i := len(blocks) - 1; i >= 0; i-- {
+		block := blocks[i]
+		var txs types.Transactions
+		if len(block.Payload) != 0 {
+			if err := rlp.DecodeBytes(block.Payload, &txs); err != nil {
+				return len(blocks) - 1 - i, err
+			}
+		}
+		if err := d.executeBlock(block, txs); err != nil {
+			return len(blocks) - 1 - i, err
+		}
+		d.deliveredHeight = block.Position.Height
+		log.Info("Replayed delivered core block", "position", block.Position.String())
+	}
+	return len(blocks), nil
+}
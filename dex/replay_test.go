@@ -0,0 +1,77 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+	"time"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
+	"github.com/portto/go-tangerine/crypto"
+	dexDB "github.com/portto/go-tangerine/dex/db"
+)
+
+// Tests that core blocks delivered but not executed are executed on startup,
+// and that a compaction chain missing blocks is refused.
+func TestReplayDelivered(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	dex, _, err := newTangerine(key, 0)
+	if err != nil {
+		t.Fatalf("failed to create tangerine: %v", err)
+	}
+	db := dexDB.NewDatabase(dex.chainDb)
+
+	var parent coreCommon.Hash
+	start := time.Unix(0, int64(dex.blockchain.CurrentBlock().Time())*int64(time.Millisecond))
+	for height := uint64(1); height <= 3; height++ {
+		block := coreTypes.Block{
+			ParentHash: parent,
+			Hash:       coreCommon.NewRandomHash(),
+			Position:   coreTypes.Position{Height: height},
+			Timestamp:  start.Add(time.Duration(height) * time.Second).UTC(),
+			Randomness: []byte{byte(height)},
+		}
+		if err := db.PutBlock(block); err != nil {
+			t.Fatalf("failed to put block: %v", err)
+		}
+		if err := db.PutCompactionChainTipInfo(block.Hash, height); err != nil {
+			t.Fatalf("failed to put tip: %v", err)
+		}
+		parent = block.Hash
+	}
+
+	if n, err := dex.app.replayDelivered(db); err != nil || n != 3 {
+		t.Fatalf("replay mismatch: have %d, %v, want 3", n, err)
+	}
+	if number := dex.blockchain.CurrentBlock().NumberU64(); number != 3 {
+		t.Fatalf("chain height mismatch: have %d, want 3", number)
+	}
+	if n, err := dex.app.replayDelivered(db); err != nil || n != 0 {
+		t.Fatalf("second replay mismatch: have %d, %v, want 0", n, err)
+	}
+
+	// A tip not linked to the chain head is refused.
+	if err := db.PutCompactionChainTipInfo(coreCommon.NewRandomHash(), 5); err != nil {
+		t.Fatalf("failed to put tip: %v", err)
+	}
+	if _, err := dex.app.replayDelivered(db); err == nil {
+		t.Fatalf("replayed a broken compaction chain")
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/portto/go-tangerine/common/hexutil"
+	"github.com/portto/go-tangerine/consensus/dexcon"
+	"github.com/portto/go-tangerine/params"
+)
+
+// rewardYear is the period annualized rewards are projected over.
+const rewardYear = 365 * 24 * time.Hour
+
+var errZeroStake = errors.New("zero stake")
+
+// RewardEstimate is the projected block reward of a stake, assuming the node
+// proposes its share of the blocks and the configuration and the other
+// stakes stay unchanged.
+type RewardEstimate struct {
+	Stake          *hexutil.Big     `json:"stake"`
+	TotalStaked    *hexutil.Big     `json:"totalStaked"` // Including the stake
+	Round          hexutil.Uint64   `json:"round"`
+	RoundLength    hexutil.Uint64   `json:"roundLength"`
+	RoundInterval  hexutil.Uint64   `json:"roundInterval"` // Milliseconds
+	MiningVelocity float32          `json:"miningVelocity"`
+	PerRound       *hexutil.Big     `json:"perRound"`
+	Annual         *hexutil.Big     `json:"annual"`
+	Halvings       []*RewardHalving `json:"halvings"` // Halvings within a year
+}
+
+// RewardHalving is a projected mining velocity halving.
+type RewardHalving struct {
+	Round          hexutil.Uint64 `json:"round"`
+	MiningVelocity float32        `json:"miningVelocity"`
+	PerRound       *hexutil.Big   `json:"perRound"`
+}
+
+// estimateRewards projects the rewards of stake from round on, config being
+// the configuration of round, totalStaked the stakes besides stake and
+// totalSupply the current supply. The blocks are rewarded in proportion to
+// the stakes, and the mining velocity halves each time the supply reaches
+// the next halving supply.
+func estimateRewards(round uint64, config *params.DexconConfig,
+	totalStaked, totalSupply, stake *big.Int) (*RewardEstimate, error) {
+	if stake.Sign() <= 0 {
+		return nil, errZeroStake
+	}
+	cfg := *config
+	total := new(big.Int).Add(totalStaked, stake)
+	roundInterval := cfg.RoundLength * cfg.MinBlockInterval
+	if roundInterval == 0 {
+		return nil, errors.New("zero round interval")
+	}
+
+	// perRound returns the rewards of all stakes and of stake in a round.
+	perRound := func() (*big.Int, *big.Int) {
+		all := new(big.Int).Mul(dexcon.BlockReward(&cfg, total),
+			new(big.Int).SetUint64(cfg.RoundLength))
+		own := new(big.Int).Mul(all, stake)
+		return all, own.Div(own, total)
+	}
+	_, own := perRound()
+	estimate := &RewardEstimate{
+		Stake:          (*hexutil.Big)(new(big.Int).Set(stake)),
+		TotalStaked:    (*hexutil.Big)(total),
+		Round:          hexutil.Uint64(round),
+		RoundLength:    hexutil.Uint64(cfg.RoundLength),
+		RoundInterval:  hexutil.Uint64(roundInterval),
+		MiningVelocity: cfg.MiningVelocity,
+		PerRound:       (*hexutil.Big)(own),
+		Halvings:       []*RewardHalving{},
+	}
+
+	// The rewards are constant between halvings, project them span by span.
+	var (
+		annual     = new(big.Int)
+		supply     = new(big.Int).Set(totalSupply)
+		nextSupply = new(big.Int).Set(cfg.NextHalvingSupply)
+		lastHalved = new(big.Int).Set(cfg.LastHalvedAmount)
+		left       = uint64(rewardYear/time.Millisecond) / roundInterval
+	)
+	for left > 0 {
+		all, own := perRound()
+		if all.Sign() == 0 {
+			break
+		}
+		// Rounds until the supply reaches the next halving supply.
+		rounds := left
+		if remaining := new(big.Int).Sub(nextSupply, supply); remaining.Sign() <= 0 {
+			rounds = 0
+		} else {
+			remaining.Add(remaining, all).Sub(remaining, big.NewInt(1)).Div(remaining, all)
+			if remaining.IsUint64() && remaining.Uint64() < left {
+				rounds = remaining.Uint64()
+			}
+		}
+		n := new(big.Int).SetUint64(rounds)
+		annual.Add(annual, new(big.Int).Mul(own, n))
+		supply.Add(supply, new(big.Int).Mul(all, n))
+		round += rounds
+		left -= rounds
+		if left == 0 {
+			break
+		}
+
+		cfg.MiningVelocity /= 2
+		lastHalved.Div(lastHalved, big.NewInt(2))
+		nextSupply.Add(nextSupply, lastHalved)
+		_, own = perRound()
+		estimate.Halvings = append(estimate.Halvings, &RewardHalving{
+			Round:          hexutil.Uint64(round),
+			MiningVelocity: cfg.MiningVelocity,
+			PerRound:       (*hexutil.Big)(own),
+		})
+	}
+	estimate.Annual = (*hexutil.Big)(annual)
+	return estimate, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/portto/go-tangerine/params"
+)
+
+// Tests that rewards are projected in proportion to the stake and halve once
+// the supply reaches the next halving supply.
+func TestEstimateRewards(t *testing.T) {
+	var (
+		ether       = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+		stake       = new(big.Int).Mul(big.NewInt(1000000), ether)
+		totalStaked = new(big.Int).Mul(big.NewInt(3000000), ether)
+		roundsYear  = uint64(52560) // 10 minutes rounds
+	)
+	config := &params.DexconConfig{
+		RoundLength:       600,
+		MinBlockInterval:  1000,
+		MiningVelocity:    0.5,
+		NextHalvingSupply: new(big.Int).Mul(big.NewInt(1000000000), ether),
+		LastHalvedAmount:  new(big.Int).Mul(big.NewInt(1000000000), ether),
+	}
+
+	// Without halving, the stake earns the mining velocity in a year.
+	estimate, err := estimateRewards(10, config, totalStaked, big.NewInt(0), stake)
+	if err != nil {
+		t.Fatalf("failed to estimate rewards: %v", err)
+	}
+	if len(estimate.Halvings) != 0 {
+		t.Fatalf("unexpected halvings: %v", estimate.Halvings)
+	}
+	annual := new(big.Int).Mul(estimate.PerRound.ToInt(), new(big.Int).SetUint64(roundsYear))
+	if estimate.Annual.ToInt().Cmp(annual) != 0 {
+		t.Fatalf("annual rewards mismatch: have %v, want %v", estimate.Annual, annual)
+	}
+	want := new(big.Int).Div(stake, big.NewInt(2))
+	if diff := new(big.Int).Sub(want, annual); diff.Sign() < 0 || diff.Cmp(new(big.Int).Div(want, big.NewInt(1000))) > 0 {
+		t.Fatalf("annual rewards mismatch: have %v, want about %v", annual, want)
+	}
+
+	// The supply reaches the halving supply half way through the year.
+	all := new(big.Int).Mul(estimate.PerRound.ToInt(), big.NewInt(4))
+	config.NextHalvingSupply = new(big.Int).Mul(all, new(big.Int).SetUint64(roundsYear/2))
+	estimate, err = estimateRewards(10, config, totalStaked, big.NewInt(0), stake)
+	if err != nil {
+		t.Fatalf("failed to estimate rewards: %v", err)
+	}
+	if len(estimate.Halvings) != 1 {
+		t.Fatalf("halvings mismatch: have %d, want 1", len(estimate.Halvings))
+	}
+	halving := estimate.Halvings[0]
+	if uint64(halving.Round) != 10+roundsYear/2 || halving.MiningVelocity != 0.25 {
+		t.Fatalf("halving mismatch: have round %d velocity %v, want round %d velocity 0.25",
+			halving.Round, halving.MiningVelocity, 10+roundsYear/2)
+	}
+	annual = new(big.Int).Add(estimate.PerRound.ToInt(), halving.PerRound.ToInt())
+	annual.Mul(annual, new(big.Int).SetUint64(roundsYear/2))
+	if estimate.Annual.ToInt().Cmp(annual) != 0 {
+		t.Fatalf("annual rewards mismatch: have %v, want %v", estimate.Annual, annual)
+	}
+
+	if _, err := estimateRewards(10, config, totalStaked, big.NewInt(0), big.NewInt(0)); err != errZeroStake {
+		t.Fatalf("error mismatch: have %v, want %v", err, errZeroStake)
+	}
+}
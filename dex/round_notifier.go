@@ -0,0 +1,148 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/event"
+	"github.com/portto/go-tangerine/log"
+	"github.com/portto/go-tangerine/params"
+)
+
+const roundChainEventChanSize = 64
+
+// RoundChangeEvent is posted once per round transition of the chain, when the
+// first block of the new round is inserted.
+type RoundChangeEvent struct {
+	Block *types.Block // First block of the new round
+
+	OldRound     uint64
+	OldConfig    *params.DexconConfig
+	OldNotarySet map[string]struct{}
+
+	NewRound     uint64
+	NewConfig    *params.DexconConfig
+	NewNotarySet map[string]struct{}
+}
+
+// roundGovernance is the governance data needed to describe a round.
+type roundGovernance interface {
+	RawConfiguration(round uint64) (*params.DexconConfig, error)
+	NotarySet(round uint64) (map[string]struct{}, error)
+}
+
+// roundNotifier watches the chain for round transitions and posts them to its
+// subscribers, letting applications embedding the node react to them without
+// polling the governance state.
+type roundNotifier struct {
+	blockchain *core.BlockChain
+	gov        roundGovernance
+	feed       event.Feed
+
+	// Round of the last inserted block and its governance data, nil until
+	// needed by the first transition.
+	round     uint64
+	config    *params.DexconConfig
+	notarySet map[string]struct{}
+
+	chainCh  chan core.ChainEvent
+	chainSub event.Subscription
+}
+
+func newRoundNotifier(blockchain *core.BlockChain, gov roundGovernance) *roundNotifier {
+	return &roundNotifier{
+		blockchain: blockchain,
+		gov:        gov,
+	}
+}
+
+func (n *roundNotifier) Start() {
+	n.round = n.blockchain.CurrentBlock().Round()
+	roundGauge.Update(int64(n.round))
+	n.chainCh = make(chan core.ChainEvent, roundChainEventChanSize)
+	n.chainSub = n.blockchain.SubscribeChainEvent(n.chainCh)
+	go n.loop()
+}
+
+func (n *roundNotifier) Stop() {
+	n.chainSub.Unsubscribe()
+}
+
+// Subscribe registers a subscription of RoundChangeEvent.
+func (n *roundNotifier) Subscribe(ch chan<- RoundChangeEvent) event.Subscription {
+	return n.feed.Subscribe(ch)
+}
+
+func (n *roundNotifier) loop() {
+	for {
+		select {
+		case ev := <-n.chainCh:
+			if e := n.check(ev.Block); e != nil {
+				n.feed.Send(*e)
+			}
+		case <-n.chainSub.Err():
+			return
+		}
+	}
+}
+
+// check returns the round change event of block, nil if block does not begin
+// a new round or the governance data of the rounds is not available.
+func (n *roundNotifier) check(block *types.Block) *RoundChangeEvent {
+	if block.Round() <= n.round {
+		return nil
+	}
+	if n.config == nil {
+		config, notarySet, err := n.describe(n.round)
+		if err != nil {
+			log.Error("Failed to get round governance data", "round", n.round, "err", err)
+			return nil
+		}
+		n.config, n.notarySet = config, notarySet
+	}
+	config, notarySet, err := n.describe(block.Round())
+	if err != nil {
+		log.Error("Failed to get round governance data", "round", block.Round(), "err", err)
+		return nil
+	}
+	e := &RoundChangeEvent{
+		Block:        block,
+		OldRound:     n.round,
+		OldConfig:    n.config,
+		OldNotarySet: n.notarySet,
+		NewRound:     block.Round(),
+		NewConfig:    config,
+		NewNotarySet: notarySet,
+	}
+	n.round, n.config, n.notarySet = block.Round(), config, notarySet
+	roundGauge.Update(int64(n.round))
+	return e
+}
+
+func (n *roundNotifier) describe(round uint64) (*params.DexconConfig, map[string]struct{}, error) {
+	config, err := n.gov.RawConfiguration(round)
+	if err != nil {
+		return nil, nil, err
+	}
+	notarySet, err := n.gov.NotarySet(round)
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, notarySet, nil
+}
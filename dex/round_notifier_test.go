@@ -0,0 +1,84 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/params"
+)
+
+type testRoundGovernance struct {
+	failing map[uint64]bool
+}
+
+func (g *testRoundGovernance) RawConfiguration(round uint64) (*params.DexconConfig, error) {
+	if g.failing[round] {
+		return nil, errors.New("unavailable")
+	}
+	return &params.DexconConfig{RoundLength: round}, nil
+}
+
+func (g *testRoundGovernance) NotarySet(round uint64) (map[string]struct{}, error) {
+	return map[string]struct{}{fmt.Sprint(round): {}}, nil
+}
+
+// Tests that a round change event is returned exactly once per transition.
+func TestRoundNotifier(t *testing.T) {
+	gov := &testRoundGovernance{failing: map[uint64]bool{2: true}}
+	n := newRoundNotifier(nil, gov)
+
+	block := func(number, round uint64) *types.Block {
+		return types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(number), Round: round})
+	}
+	if e := n.check(block(1, 0)); e != nil {
+		t.Fatalf("event fired without round change: %+v", e)
+	}
+	e := n.check(block(2, 1))
+	if e == nil {
+		t.Fatalf("round change not fired")
+	}
+	if e.OldRound != 0 || e.NewRound != 1 || e.OldConfig.RoundLength != 0 ||
+		e.NewConfig.RoundLength != 1 {
+		t.Errorf("event mismatch: %+v", e)
+	}
+	if _, ok := e.NewNotarySet["1"]; !ok {
+		t.Errorf("new notary set mismatch: %v", e.NewNotarySet)
+	}
+	if e := n.check(block(3, 1)); e != nil {
+		t.Errorf("round change fired twice: %+v", e)
+	}
+
+	// Transitions whose governance data is unavailable are retried on the
+	// next block.
+	if e := n.check(block(4, 2)); e != nil {
+		t.Errorf("event fired without governance data: %+v", e)
+	}
+	delete(gov.failing, 2)
+	e = n.check(block(5, 2))
+	if e == nil || e.OldRound != 1 || e.NewRound != 2 {
+		t.Fatalf("retried round change mismatch: %+v", e)
+	}
+	if _, ok := e.OldNotarySet["1"]; !ok {
+		t.Errorf("old notary set mismatch: %v", e.OldNotarySet)
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/log"
+)
+
+// scrubRepairTimeout is how long a peer has to answer a repair request before
+// the repair is given up until the block is sampled again.
+const scrubRepairTimeout = 30 * time.Second
+
+// scrubResult is the outcome of the integrity check of a stored block.
+type scrubResult struct {
+	hash        common.Hash
+	header      *types.Header
+	badHeader   bool
+	badBody     bool
+	badReceipts bool
+}
+
+func (r *scrubResult) corrupted() bool {
+	return r.badHeader || r.badBody || r.badReceipts
+}
+
+// scrubBlock re-reads the canonical block number from the database and checks
+// its header against the canonical chain, and its body and receipts against
+// the header. The body and receipts are not checked if the header is corrupted.
+func scrubBlock(db rawdb.DatabaseReader, number uint64) *scrubResult {
+	result := &scrubResult{hash: rawdb.ReadCanonicalHash(db, number)}
+	if result.hash == (common.Hash{}) {
+		result.badHeader = true
+		return result
+	}
+	header := rawdb.ReadHeader(db, result.hash, number)
+	if header == nil || header.Hash() != result.hash || header.Number.Uint64() != number {
+		result.badHeader = true
+		return result
+	}
+	if number > 0 && rawdb.ReadCanonicalHash(db, number-1) != header.ParentHash {
+		result.badHeader = true
+		return result
+	}
+	result.header = header
+
+	var txs []*types.Transaction
+	var uncles []*types.Header
+	if body := rawdb.ReadBody(db, result.hash, number); body != nil {
+		txs, uncles = body.Transactions, body.Uncles
+	}
+	result.badBody = !bodyMatches(header, txs, uncles)
+	result.badReceipts = types.DeriveSha(rawdb.ReadReceipts(db, result.hash, number)) != header.ReceiptHash
+	return result
+}
+
+// bodyMatches returns whether the transactions and uncles are the body of the
+// block with the given header.
+func bodyMatches(header *types.Header, txs []*types.Transaction, uncles []*types.Header) bool {
+	return types.DeriveSha(types.Transactions(txs)) == header.TxHash &&
+		types.CalcUncleHash(uncles) == header.UncleHash
+}
+
+// scrubRepair is a pending request to a peer for data found corrupted.
+type scrubRepair struct {
+	header   *types.Header
+	peer     string
+	deadline time.Time
+}
+
+// scrubber is a low priority background job re-reading randomly sampled
+// stored blocks and receipts to detect corruption of the database, so that
+// operators on unreliable disks learn about it before serving or building on
+// bad data. Corrupted bodies and receipts are fetched again from peers and
+// rewritten; corrupted headers can only be reported.
+type scrubber struct {
+	db       ethdb.Database
+	chain    *core.BlockChain
+	peers    *peerSet
+	interval time.Duration
+
+	lock     sync.Mutex
+	bodies   map[common.Hash]*scrubRepair
+	receipts map[common.Hash]*scrubRepair
+
+	quit chan struct{}
+}
+
+func newScrubber(db ethdb.Database, chain *core.BlockChain, peers *peerSet,
+	interval time.Duration) *scrubber {
+	return &scrubber{
+		db:       db,
+		chain:    chain,
+		peers:    peers,
+		interval: interval,
+		bodies:   make(map[common.Hash]*scrubRepair),
+		receipts: make(map[common.Hash]*scrubRepair),
+		quit:     make(chan struct{}),
+	}
+}
+
+func (s *scrubber) Start() {
+	go s.loop()
+}
+
+func (s *scrubber) Stop() {
+	close(s.quit)
+}
+
+func (s *scrubber) loop() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.expire()
+			head := s.chain.CurrentBlock().NumberU64()
+			s.check(uint64(rand.Int63n(int64(head) + 1)))
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// check scrubs the canonical block number, reporting and requesting repairs
+// of the corrupted data.
+func (s *scrubber) check(number uint64) {
+	scrubCheckedMeter.Mark(1)
+
+	result := scrubBlock(s.db, number)
+	if !result.corrupted() {
+		return
+	}
+	scrubCorruptedMeter.Mark(1)
+	log.Error("Corrupted block data found in database", "number", number,
+		"hash", result.hash, "header", result.badHeader, "body", result.badBody,
+		"receipts", result.badReceipts)
+
+	if result.badHeader {
+		return
+	}
+	hashes := []common.Hash{result.hash}
+	if result.badBody {
+		if p := s.request(s.bodies, result.header); p != nil {
+			if err := p.RequestBodies(scrubberReq, hashes); err != nil {
+				log.Debug("Failed to request block body repair", "peer", p.id, "err", err)
+			}
+		}
+	}
+	if result.badReceipts {
+		if p := s.request(s.receipts, result.header); p != nil {
+			if err := p.RequestReceipts(hashes); err != nil {
+				log.Debug("Failed to request receipts repair", "peer", p.id, "err", err)
+			}
+		}
+	}
+}
+
+// request picks a random peer to repair the block data and records the repair
+// in pending, returning nil if no peer is available or a repair is already
+// pending.
+func (s *scrubber) request(pending map[common.Hash]*scrubRepair, header *types.Header) *peer {
+	peers := s.peers.Peers()
+	if len(peers) == 0 {
+		log.Warn("No peer to repair corrupted block data from", "number", header.Number)
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := pending[header.Hash()]; ok {
+		return nil
+	}
+	p := peers[rand.Intn(len(peers))]
+	pending[header.Hash()] = &scrubRepair{
+		header:   header,
+		peer:     p.id,
+		deadline: time.Now().Add(scrubRepairTimeout),
+	}
+	return p
+}
+
+// expire drops the repairs not answered in time.
+func (s *scrubber) expire() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	for _, pending := range []map[common.Hash]*scrubRepair{s.bodies, s.receipts} {
+		for hash, repair := range pending {
+			if now.After(repair.deadline) {
+				delete(pending, hash)
+			}
+		}
+	}
+}
+
+// deliverBodies rewrites the pending body repairs matched by the block bodies
+// received from peer.
+func (s *scrubber) deliverBodies(peer string, txs [][]*types.Transaction, uncles [][]*types.Header) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i := range txs {
+		for hash, repair := range s.bodies {
+			if repair.peer != peer || !bodyMatches(repair.header, txs[i], uncles[i]) {
+				continue
+			}
+			number := repair.header.Number.Uint64()
+			rawdb.WriteBody(s.db, hash, number, &types.Body{Transactions: txs[i], Uncles: uncles[i]})
+			delete(s.bodies, hash)
+
+			scrubRepairedMeter.Mark(1)
+			log.Info("Repaired corrupted block body", "number", number, "hash", hash, "peer", peer)
+			break
+		}
+	}
+}
+
+// deliverReceipts rewrites the pending receipts repairs matched by the
+// receipts received from peer, returning whether any was matched. Receipts
+// requests are not flagged, so unmatched ones belong to the downloader.
+func (s *scrubber) deliverReceipts(peer string, receipts [][]*types.Receipt) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	matched := false
+	for i := range receipts {
+		sha := types.DeriveSha(types.Receipts(receipts[i]))
+		for hash, repair := range s.receipts {
+			if repair.peer != peer || repair.header.ReceiptHash != sha {
+				continue
+			}
+			number := repair.header.Number.Uint64()
+			rawdb.WriteReceipts(s.db, hash, number, receipts[i])
+			delete(s.receipts, hash)
+			matched = true
+
+			scrubRepairedMeter.Mark(1)
+			log.Info("Repaired corrupted receipts", "number", number, "hash", hash, "peer", peer)
+			break
+		}
+	}
+	return matched
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/ethdb"
+)
+
+// Tests that corrupted block bodies and receipts are detected and repaired
+// from the peer asked for them.
+func TestScrubber(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+
+	genesis := &types.Header{Number: big.NewInt(0), TxHash: types.EmptyRootHash,
+		UncleHash: types.EmptyUncleHash, ReceiptHash: types.EmptyRootHash}
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	txs := types.Transactions{types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)}
+	receipts := types.Receipts{types.NewReceipt(nil, false, 21000)}
+	receipts[0].Bloom = types.CreateBloom(receipts)
+	header := &types.Header{
+		ParentHash:  genesis.Hash(),
+		Number:      big.NewInt(1),
+		TxHash:      types.DeriveSha(txs),
+		UncleHash:   types.EmptyUncleHash,
+		ReceiptHash: types.DeriveSha(receipts),
+	}
+	hash := header.Hash()
+	rawdb.WriteHeader(db, header)
+	rawdb.WriteCanonicalHash(db, hash, 1)
+	rawdb.WriteBody(db, hash, 1, &types.Body{Transactions: txs})
+	rawdb.WriteReceipts(db, hash, 1, receipts)
+
+	if result := scrubBlock(db, 1); result.corrupted() {
+		t.Fatalf("intact block reported corrupted: %+v", result)
+	}
+	rawdb.WriteBody(db, hash, 1, &types.Body{})
+	rawdb.WriteReceipts(db, hash, 1, nil)
+	result := scrubBlock(db, 1)
+	if result.badHeader || !result.badBody || !result.badReceipts {
+		t.Fatalf("corruption mismatch: %+v", result)
+	}
+	rawdb.WriteCanonicalHash(db, common.Hash{1}, 0)
+	if result := scrubBlock(db, 1); !result.badHeader {
+		t.Fatalf("block off the canonical chain not reported")
+	}
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	s := newScrubber(db, nil, nil, time.Second)
+	deadline := time.Now().Add(time.Minute)
+	s.bodies[hash] = &scrubRepair{header: header, peer: "a", deadline: deadline}
+	s.receipts[hash] = &scrubRepair{header: header, peer: "a", deadline: deadline}
+
+	s.deliverBodies("b", [][]*types.Transaction{txs}, [][]*types.Header{nil})
+	if s.deliverReceipts("b", [][]*types.Receipt{receipts}) {
+		t.Errorf("receipts from unrequested peer accepted")
+	}
+	if s.deliverReceipts("a", [][]*types.Receipt{nil}) {
+		t.Errorf("mismatching receipts accepted")
+	}
+	s.deliverBodies("a", [][]*types.Transaction{txs}, [][]*types.Header{nil})
+	if !s.deliverReceipts("a", [][]*types.Receipt{receipts}) {
+		t.Errorf("repaired receipts not accepted")
+	}
+	if result := scrubBlock(db, 1); result.corrupted() {
+		t.Errorf("repaired block reported corrupted: %+v", result)
+	}
+	if len(s.bodies) != 0 || len(s.receipts) != 0 {
+		t.Errorf("repairs still pending: bodies %d, receipts %d", len(s.bodies), len(s.receipts))
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreCrypto "github.com/portto/tangerine-consensus/core/crypto"
+	coreEcdsa "github.com/portto/tangerine-consensus/core/crypto/ecdsa"
+
+	"github.com/portto/go-tangerine/common/hexutil"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/rpc"
+)
+
+// externalSignerTimeout bounds every request to an external signer.
+const externalSignerTimeout = 5 * time.Second
+
+var errSignerMismatch = errors.New("signature not made by the node key")
+
+// NodeSigner signs on behalf of the node key. It signs the consensus core
+// blocks, votes and DKG messages, the governance and recovery transactions
+// and the state root announcements, so that the key can be kept in an
+// external signer or HSM.
+//
+// The BLS shares of the DKG are generated by the consensus core every round
+// and never leave it.
+type NodeSigner interface {
+	// PublicKey returns the public key of the node.
+	PublicKey() *ecdsa.PublicKey
+
+	// SignHash signs hash, returning a signature in the [R || S || V]
+	// format of crypto.Sign.
+	SignHash(hash []byte) ([]byte, error)
+}
+
+// localSigner is a NodeSigner holding the node key in memory.
+type localSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+// NewLocalSigner returns a NodeSigner signing with key.
+func NewLocalSigner(key *ecdsa.PrivateKey) NodeSigner {
+	return &localSigner{key: key}
+}
+
+func (s *localSigner) PublicKey() *ecdsa.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s *localSigner) SignHash(hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, s.key)
+}
+
+// externalSigner is a NodeSigner delegating to an external signer serving
+// the signer_publicKey and signer_signHash JSON-RPC methods.
+type externalSigner struct {
+	client *rpc.Client
+	pub    *ecdsa.PublicKey
+}
+
+// NewExternalSigner connects to the external signer at endpoint and fetches
+// the public key it signs for.
+func NewExternalSigner(endpoint string) (NodeSigner, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), externalSignerTimeout)
+	defer cancel()
+
+	client, err := rpc.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	var raw hexutil.Bytes
+	if err := client.CallContext(ctx, &raw, "signer_publicKey"); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to fetch external signer public key: %v", err)
+	}
+	pub, err := crypto.UnmarshalPubkey(raw)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("invalid external signer public key: %v", err)
+	}
+	return &externalSigner{client: client, pub: pub}, nil
+}
+
+func (s *externalSigner) PublicKey() *ecdsa.PublicKey {
+	return s.pub
+}
+
+// SignHash implements NodeSigner, checking the returned signature was made
+// by the node key so that a misconfigured signer does not get the node to
+// broadcast invalid consensus messages.
+func (s *externalSigner) SignHash(hash []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), externalSignerTimeout)
+	defer cancel()
+
+	var sig hexutil.Bytes
+	if err := s.client.CallContext(ctx, &sig, "signer_signHash", hexutil.Bytes(hash)); err != nil {
+		return nil, err
+	}
+	pub, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(pub, crypto.FromECDSAPub(s.pub)) {
+		return nil, errSignerMismatch
+	}
+	return sig, nil
+}
+
+// newNodeSigner returns the signer of the node: the external signer when one
+// is configured, the local node key otherwise. The node key, which is also the
+// p2p identity, is optional with an external signer. A block proposer however
+// must be reachable by its notary key, so it refuses to run unless the node
+// key is the key the signer holds.
+func newNodeSigner(config *Config) (NodeSigner, error) {
+	if config.ExternalSigner == "" {
+		if config.PrivateKey == nil {
+			return nil, errors.New("node key required without an external signer")
+		}
+		return NewLocalSigner(config.PrivateKey), nil
+	}
+	signer, err := NewExternalSigner(config.ExternalSigner)
+	if err != nil {
+		return nil, err
+	}
+	if config.BlockProposerEnabled && !signerIsNodeKey(signer, config.PrivateKey) {
+		if config.PrivateKey == nil {
+			return nil, fmt.Errorf("block proposer requires the node key of external signer key %s",
+				crypto.PubkeyToAddress(*signer.PublicKey()).Hex())
+		}
+		return nil, fmt.Errorf("external signer key %s is not the node key %s",
+			crypto.PubkeyToAddress(*signer.PublicKey()).Hex(),
+			crypto.PubkeyToAddress(config.PrivateKey.PublicKey).Hex())
+	}
+	return signer, nil
+}
+
+// signerIsNodeKey reports whether signer signs for the node key key.
+func signerIsNodeKey(signer NodeSigner, key *ecdsa.PrivateKey) bool {
+	return key != nil && bytes.Equal(crypto.FromECDSAPub(signer.PublicKey()),
+		crypto.FromECDSAPub(&key.PublicKey))
+}
+
+// rotatingSigner is a NodeSigner whose key can be replaced while the node
+// runs, see keyRotator.
+type rotatingSigner struct {
+	lock   sync.RWMutex
+	signer NodeSigner
+}
+
+func newRotatingSigner(signer NodeSigner) *rotatingSigner {
+	return &rotatingSigner{signer: signer}
+}
+
+func (s *rotatingSigner) PublicKey() *ecdsa.PublicKey {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.signer.PublicKey()
+}
+
+func (s *rotatingSigner) SignHash(hash []byte) ([]byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.signer.SignHash(hash)
+}
+
+// external reports whether the key is held by an external signer.
+func (s *rotatingSigner) external() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	_, ok := s.signer.(*externalSigner)
+	return ok
+}
+
+// swap makes s sign with signer from now on.
+func (s *rotatingSigner) swap(signer NodeSigner) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.signer = signer
+}
+
+// coreSigner adapts a NodeSigner to the private key of the consensus core.
+type coreSigner struct {
+	signer NodeSigner
+}
+
+func (s coreSigner) PublicKey() coreCrypto.PublicKey {
+	return coreEcdsa.NewPublicKeyFromECDSA(s.signer.PublicKey())
+}
+
+func (s coreSigner) Sign(hash coreCommon.Hash) (coreCrypto.Signature, error) {
+	sig, err := s.signer.SignHash(hash[:])
+	return coreCrypto.Signature{Type: "ecdsa", Signature: sig}, err
+}
+
+// signTx signs tx with the node key.
+func signTx(tx *types.Transaction, txSigner types.Signer, signer NodeSigner) (*types.Transaction, error) {
+	sig, err := signer.SignHash(txSigner.Hash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(txSigner, sig)
+}
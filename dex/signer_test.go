@@ -0,0 +1,147 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"crypto/ecdsa"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	coreCrypto "github.com/portto/tangerine-consensus/core/crypto"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/common/hexutil"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/rpc"
+)
+
+// TestSignerService is an external signer holding key.
+type TestSignerService struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s *TestSignerService) PublicKey() hexutil.Bytes {
+	return crypto.FromECDSAPub(&s.key.PublicKey)
+}
+
+func (s *TestSignerService) SignHash(hash hexutil.Bytes) (hexutil.Bytes, error) {
+	return crypto.Sign(hash, s.key)
+}
+
+func newTestExternalSigner(t *testing.T, key, signing *ecdsa.PrivateKey) NodeSigner {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("signer", &TestSignerService{key: signing}); err != nil {
+		t.Fatalf("failed to register signer: %v", err)
+	}
+	return &externalSigner{client: rpc.DialInProc(srv), pub: &key.PublicKey}
+}
+
+// Tests that consensus messages and transactions signed through an external
+// signer verify against the node key, and that signatures of another key are
+// refused.
+func TestExternalSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := newTestExternalSigner(t, key, key)
+
+	hash := coreCommon.NewRandomHash()
+	sig, err := coreSigner{signer}.Sign(hash)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	pub, err := coreCrypto.SigToPub(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover public key: %v", err)
+	}
+	if !pub.VerifySignature(hash, sig) || string(pub.Bytes()) != string(crypto.FromECDSAPub(&key.PublicKey)) {
+		t.Fatalf("signature not made by the node key")
+	}
+
+	txSigner := types.NewEIP155Signer(big.NewInt(1))
+	tx := types.NewTransaction(0, common.Address{1}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	if tx, err = signTx(tx, txSigner, signer); err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if from, _ := types.Sender(txSigner, tx); from != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("sender mismatch: have %x, want %x", from, crypto.PubkeyToAddress(key.PublicKey))
+	}
+
+	other, _ := crypto.GenerateKey()
+	signer = newTestExternalSigner(t, key, other)
+	if _, err := signer.SignHash(hash[:]); err != errSignerMismatch {
+		t.Fatalf("error mismatch: have %v, want %v", err, errSignerMismatch)
+	}
+}
+
+// Tests that the node key is optional with an external signer, and must be
+// the signer key for a block proposer.
+func TestNewNodeSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	dir, err := ioutil.TempDir("", "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	endpoint := filepath.Join(dir, "signer.ipc")
+	listener, _, err := rpc.StartIPCEndpoint(endpoint, []rpc.API{
+		{Namespace: "signer", Service: &TestSignerService{key: key}, Public: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to start signer: %v", err)
+	}
+	defer listener.Close()
+
+	if _, err := newNodeSigner(&Config{}); err == nil {
+		t.Errorf("signer created without a node key")
+	}
+	signer, err := newNodeSigner(&Config{PrivateKey: key})
+	if err != nil {
+		t.Fatalf("failed to create local signer: %v", err)
+	}
+	if _, ok := signer.(*localSigner); !ok {
+		t.Errorf("signer type mismatch: have %T, want local signer", signer)
+	}
+	for _, k := range []*ecdsa.PrivateKey{nil, key} {
+		signer, err := newNodeSigner(&Config{PrivateKey: k, ExternalSigner: endpoint})
+		if err != nil {
+			t.Fatalf("failed to create external signer: %v", err)
+		}
+		if have := crypto.PubkeyToAddress(*signer.PublicKey()); have != crypto.PubkeyToAddress(key.PublicKey) {
+			t.Errorf("signer key mismatch: have %x, want %x", have, crypto.PubkeyToAddress(key.PublicKey))
+		}
+	}
+	for _, k := range []*ecdsa.PrivateKey{nil, other} {
+		if _, err := newNodeSigner(&Config{PrivateKey: k, ExternalSigner: endpoint}); err != nil {
+			t.Errorf("external signer rejected for a non-proposing node: %v", err)
+		}
+		config := &Config{PrivateKey: k, ExternalSigner: endpoint, BlockProposerEnabled: true}
+		if _, err := newNodeSigner(config); err == nil {
+			t.Errorf("block proposer accepted without the signer key as node key")
+		}
+	}
+	config := &Config{PrivateKey: key, ExternalSigner: endpoint, BlockProposerEnabled: true}
+	if _, err := newNodeSigner(config); err != nil {
+		t.Errorf("block proposer rejected with the signer key as node key: %v", err)
+	}
+}
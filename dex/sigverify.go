@@ -0,0 +1,115 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	coreUtils "github.com/portto/tangerine-consensus/core/utils"
+)
+
+// sigVerifier verifies the signatures of bursts of consensus core messages
+// in parallel. Every batch is verified by the calling goroutine plus the
+// helpers of a pool shared by all peers that are idle, bounding the number of
+// goroutines verifying at once.
+type sigVerifier struct {
+	workers chan struct{} // Tokens of the busy helpers
+}
+
+// newSigVerifier returns a sigVerifier verifying a batch with up to workers
+// goroutines, the caller included, zero or less meaning one per CPU.
+func newSigVerifier(workers int) *sigVerifier {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &sigVerifier{workers: make(chan struct{}, workers-1)}
+}
+
+// VerifyVotes verifies the signatures of votes, returning the errors by
+// index, nil for the valid ones.
+func (v *sigVerifier) VerifyVotes(votes []*coreTypes.Vote) []error {
+	return v.verify(len(votes), func(i int) error {
+		ok, err := coreUtils.VerifyVoteSignature(votes[i])
+		if err == nil && !ok {
+			err = coreUtils.ErrIncorrectSignature
+		}
+		return err
+	})
+}
+
+// VerifyBlocks verifies the hashes and signatures of blocks, returning the
+// errors by index, nil for the valid ones. The payload hashes are not
+// checked. Empty blocks are hashed by every node without being signed, so
+// only their hashes are verified.
+func (v *sigVerifier) VerifyBlocks(blocks []*coreTypes.Block) []error {
+	return v.verify(len(blocks), func(i int) error {
+		block := blocks[i]
+		if !block.IsEmpty() {
+			return coreUtils.VerifyBlockSignatureWithoutPayload(block)
+		}
+		hash, err := coreUtils.HashBlock(block)
+		if err != nil {
+			return err
+		}
+		if hash != block.Hash {
+			return coreUtils.ErrIncorrectHash
+		}
+		return nil
+	})
+}
+
+// verify runs fn for the indexes below n on the calling goroutine and the
+// idle helpers, returning the errors by index.
+func (v *sigVerifier) verify(n int, fn func(i int) error) []error {
+	var (
+		errs = make([]error, n)
+		next = int32(-1)
+		wg   sync.WaitGroup
+	)
+	work := func() {
+		for {
+			i := int(atomic.AddInt32(&next, 1))
+			if i >= n {
+				return
+			}
+			errs[i] = fn(i)
+		}
+	}
+Borrow:
+	for extra := 1; extra < n; extra++ {
+		select {
+		case v.workers <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-v.workers
+					wg.Done()
+				}()
+				work()
+			}()
+		default:
+			break Borrow
+		}
+	}
+	work()
+	wg.Wait()
+	return errs
+}
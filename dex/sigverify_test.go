@@ -0,0 +1,108 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+	"time"
+
+	coreEcdsa "github.com/portto/tangerine-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+	coreUtils "github.com/portto/tangerine-consensus/core/utils"
+)
+
+func newTestSignedVotes(t testing.TB, n int) []*coreTypes.Vote {
+	prv, err := coreEcdsa.NewPrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer := coreUtils.NewSigner(prv)
+	votes := make([]*coreTypes.Vote, n)
+	for i := range votes {
+		votes[i] = &coreTypes.Vote{
+			VoteHeader: coreTypes.VoteHeader{
+				Period:   uint64(i),
+				Position: coreTypes.Position{Round: 1, Height: uint64(i)},
+			},
+		}
+		if err := signer.SignVote(votes[i]); err != nil {
+			t.Fatalf("failed to sign vote: %v", err)
+		}
+	}
+	return votes
+}
+
+// Tests that the signatures of a burst are all checked, whatever the number
+// of workers and the public keys recovered before.
+func TestSigVerifier(t *testing.T) {
+	prv, _ := coreEcdsa.NewPrivateKey()
+	blocks := make([]*coreTypes.Block, 16)
+	for i := range blocks {
+		blocks[i] = &coreTypes.Block{
+			Position:  coreTypes.Position{Round: 1, Height: uint64(i)},
+			Timestamp: time.Now().UTC(),
+			Payload:   []byte{byte(i)},
+		}
+		if err := coreUtils.NewSigner(prv).SignBlock(blocks[i]); err != nil {
+			t.Fatalf("failed to sign block: %v", err)
+		}
+	}
+	votes := newTestSignedVotes(t, 16)
+
+	// Verify them intact first, so that the recovered keys get cached.
+	v := newSigVerifier(0)
+	for i, err := range v.VerifyVotes(votes) {
+		if err != nil {
+			t.Fatalf("vote %d: failed to verify: %v", i, err)
+		}
+	}
+
+	// Break a few of them.
+	blocks[3].Position.Height++
+	votes[7].Period++
+	votes[15].Signature.Signature = nil
+
+	for _, workers := range []int{1, 4, 32} {
+		v := newSigVerifier(workers)
+		for i, err := range v.VerifyBlocks(blocks) {
+			if (err != nil) != (i == 3) {
+				t.Errorf("workers %d: block %d error mismatch: %v", workers, i, err)
+			}
+		}
+		for i, err := range v.VerifyVotes(votes) {
+			if (err != nil) != (i == 7 || i == 15) {
+				t.Errorf("workers %d: vote %d error mismatch: %v", workers, i, err)
+			}
+		}
+		if len(v.workers) != 0 {
+			t.Errorf("workers %d: %d workers not released", workers, len(v.workers))
+		}
+	}
+}
+
+func benchmarkVerifyVotes(b *testing.B, workers int) {
+	votes := newTestSignedVotes(b, 64)
+	v := newSigVerifier(workers)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v.VerifyVotes(votes)
+	}
+}
+
+func BenchmarkVerifyVotesSerial(b *testing.B)   { benchmarkVerifyVotes(b, 1) }
+func BenchmarkVerifyVotesParallel(b *testing.B) { benchmarkVerifyVotes(b, 0) }
@@ -0,0 +1,322 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/event"
+	"github.com/portto/go-tangerine/log"
+)
+
+const (
+	stateRootRoundChanSize = 16
+
+	// stateRootWindow is the number of round boundaries announcements are
+	// tracked for, older ones being ignored.
+	stateRootWindow = 4
+
+	// maxPendingStateRoots is the number of announcements kept for blocks
+	// not inserted yet.
+	maxPendingStateRoots = 256
+)
+
+var errStateRootSignature = errors.New("invalid state root signature")
+
+// stateRootChain is the chain access needed to compare announced state
+// roots with the local ones.
+type stateRootChain interface {
+	CurrentBlock() *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+}
+
+// stateRootGovernance is the governance data needed to check announcers.
+type stateRootGovernance interface {
+	NotarySet(round uint64) (map[string]struct{}, error)
+}
+
+// stateRootPeers is the set of peers announcements are relayed to.
+type stateRootPeers interface {
+	Peers() []*peer
+}
+
+// pendingStateRoot is an announcement for a block not inserted yet.
+type pendingStateRoot struct {
+	from string
+	root *stateRootData
+}
+
+// stateRootGossip has validators announce the state root of the first block
+// of every round, and compares the roots announced by the others with the
+// local ones. A mismatch means some node executed the same blocks to a
+// different state, which is raised loudly before it turns into a consensus
+// failure.
+type stateRootGossip struct {
+	chain   stateRootChain
+	gov     stateRootGovernance
+	peers   stateRootPeers
+	alerter *alerter
+	signer  NodeSigner // Node key, nil if not announcing
+
+	lock    sync.Mutex
+	seen    map[uint64]map[common.Address]struct{} // Announcers of the tracked boundaries
+	pending []pendingStateRoot
+
+	roundCh  chan RoundChangeEvent
+	roundSub event.Subscription
+}
+
+func newStateRootGossip(chain stateRootChain, gov stateRootGovernance,
+	peers stateRootPeers, alerter *alerter, signer NodeSigner) *stateRootGossip {
+	return &stateRootGossip{
+		chain:   chain,
+		gov:     gov,
+		peers:   peers,
+		alerter: alerter,
+		signer:  signer,
+		seen:    make(map[uint64]map[common.Address]struct{}),
+	}
+}
+
+func (g *stateRootGossip) Start(rounds *roundNotifier) {
+	g.roundCh = make(chan RoundChangeEvent, stateRootRoundChanSize)
+	g.roundSub = rounds.Subscribe(g.roundCh)
+	go g.loop()
+}
+
+func (g *stateRootGossip) Stop() {
+	g.roundSub.Unsubscribe()
+}
+
+func (g *stateRootGossip) loop() {
+	for {
+		select {
+		case ev := <-g.roundCh:
+			g.announce(ev)
+			g.retryPending()
+		case <-g.roundSub.Err():
+			return
+		}
+	}
+}
+
+// announce signs and broadcasts the state root of the first block of the
+// new round if the node is one of its validators.
+func (g *stateRootGossip) announce(ev RoundChangeEvent) {
+	if g.signer == nil {
+		return
+	}
+	pub := g.signer.PublicKey()
+	if _, ok := ev.NewNotarySet[hex.EncodeToString(crypto.FromECDSAPub(pub))]; !ok {
+		return
+	}
+	root := &stateRootData{
+		Round:  ev.NewRound,
+		Number: ev.Block.NumberU64(),
+		Hash:   ev.Block.Hash(),
+		Root:   ev.Block.Root(),
+	}
+	sig, err := g.signer.SignHash(stateRootHash(root).Bytes())
+	if err != nil {
+		log.Error("Failed to sign state root", "err", err)
+		return
+	}
+	root.Signature = sig
+
+	g.lock.Lock()
+	g.markSeen(root.Number, crypto.PubkeyToAddress(*pub))
+	g.lock.Unlock()
+	g.relay("", root)
+	log.Debug("Announced state root", "round", root.Round, "number", root.Number,
+		"root", root.Root)
+}
+
+// handle checks an announcement received from a peer, relaying it to the
+// other peers once verified. Announcements of blocks not inserted yet are
+// kept until the chain reaches them.
+func (g *stateRootGossip) handle(from string, root *stateRootData) error {
+	pub, err := crypto.SigToPub(stateRootHash(root).Bytes(), root.Signature)
+	if err != nil {
+		return errStateRootSignature
+	}
+	signer := crypto.PubkeyToAddress(*pub)
+
+	g.lock.Lock()
+	if g.isSeen(root.Number, signer) || g.isExpired(root.Number) {
+		g.lock.Unlock()
+		return nil
+	}
+	g.lock.Unlock()
+
+	// The round is chosen by the sender, so it must be the round the local
+	// block starts. Blocks not inserted yet can only start the next round.
+	block := g.chain.GetBlockByNumber(root.Number)
+	if block == nil {
+		if head := g.chain.CurrentBlock(); root.Round > head.Round()+1 {
+			log.Debug("State root announced too far ahead", "round", root.Round, "number", root.Number)
+			return nil
+		}
+	} else if !g.isRoundStart(block, root.Round) {
+		log.Debug("State root announced for a block not starting its round", "round", root.Round,
+			"number", root.Number)
+		return nil
+	}
+	notarySet, err := g.gov.NotarySet(root.Round)
+	if err != nil {
+		log.Debug("Failed to get notary set of state root", "round", root.Round, "err", err)
+		return nil
+	}
+	if _, ok := notarySet[hex.EncodeToString(crypto.FromECDSAPub(pub))]; !ok {
+		log.Debug("State root announced by non-notary", "round", root.Round, "signer", signer)
+		return nil
+	}
+	g.lock.Lock()
+	if block == nil {
+		g.addPending(from, root)
+		g.lock.Unlock()
+		return nil
+	}
+	if g.isSeen(root.Number, signer) {
+		g.lock.Unlock()
+		return nil
+	}
+	g.markSeen(root.Number, signer)
+	g.lock.Unlock()
+
+	g.relay(from, root)
+	g.compare(block, root, signer)
+	return nil
+}
+
+// isRoundStart reports whether block is the first block of round.
+func (g *stateRootGossip) isRoundStart(block *types.Block, round uint64) bool {
+	if block.Round() != round {
+		return false
+	}
+	if block.NumberU64() == 0 {
+		return true
+	}
+	parent := g.chain.GetBlockByNumber(block.NumberU64() - 1)
+	return parent != nil && parent.Round() < round
+}
+
+// compare raises a divergence if the announced block or state root differs
+// from the local one.
+func (g *stateRootGossip) compare(block *types.Block, root *stateRootData, signer common.Address) {
+	stateRootCheckedMeter.Mark(1)
+	switch {
+	case block.Hash() != root.Hash:
+		g.diverge(fmt.Sprintf("validator %s has block %d as %s, local block is %s",
+			signer.Hex(), root.Number, root.Hash.Hex(), block.Hash().Hex()))
+	case block.Root() != root.Root:
+		g.diverge(fmt.Sprintf("validator %s has state root %s at block %d, local state root is %s",
+			signer.Hex(), root.Root.Hex(), root.Number, block.Root().Hex()))
+	}
+}
+
+func (g *stateRootGossip) diverge(text string) {
+	stateRootDivergenceMeter.Mark(1)
+	log.Error("State divergence detected", "detail", text)
+	g.alerter.alert(AlertStateDivergence, text)
+}
+
+// relay sends root to all dex/65 peers but the one it came from.
+func (g *stateRootGossip) relay(from string, root *stateRootData) {
+	for _, p := range g.peers.Peers() {
+		if p.id != from && p.version >= dex65 {
+			p.AsyncSendStateRoot(root)
+		}
+	}
+}
+
+// retryPending handles again the announcements of blocks not inserted at the
+// time they were received.
+func (g *stateRootGossip) retryPending() {
+	g.lock.Lock()
+	pending := g.pending
+	g.pending = nil
+	g.lock.Unlock()
+
+	for _, p := range pending {
+		if err := g.handle(p.from, p.root); err != nil {
+			log.Debug("Invalid pending state root", "number", p.root.Number, "err", err)
+		}
+	}
+}
+
+// addPending keeps root until its block is inserted, dropping the oldest
+// announcement if too many are pending. The caller must hold the lock.
+func (g *stateRootGossip) addPending(from string, root *stateRootData) {
+	if len(g.pending) >= maxPendingStateRoots {
+		g.pending = g.pending[1:]
+	}
+	g.pending = append(g.pending, pendingStateRoot{from: from, root: root})
+}
+
+// isSeen reports whether signer's announcement of number was handled. The
+// caller must hold the lock.
+func (g *stateRootGossip) isSeen(number uint64, signer common.Address) bool {
+	_, ok := g.seen[number][signer]
+	return ok
+}
+
+// isExpired reports whether number is older than the tracked boundaries,
+// so that stale announcements are not relayed around again. The caller must
+// hold the lock.
+func (g *stateRootGossip) isExpired(number uint64) bool {
+	if len(g.seen) < stateRootWindow {
+		return false
+	}
+	if _, ok := g.seen[number]; ok {
+		return false
+	}
+	for n := range g.seen {
+		if n < number {
+			return false
+		}
+	}
+	return true
+}
+
+// markSeen records signer's announcement of number, forgetting the oldest
+// boundary once more than stateRootWindow are tracked. The caller must hold
+// the lock.
+func (g *stateRootGossip) markSeen(number uint64, signer common.Address) {
+	signers, ok := g.seen[number]
+	if !ok {
+		signers = make(map[common.Address]struct{})
+		g.seen[number] = signers
+	}
+	signers[signer] = struct{}{}
+
+	if len(g.seen) > stateRootWindow {
+		lowest := number
+		for n := range g.seen {
+			if n < lowest {
+				lowest = n
+			}
+		}
+		delete(g.seen, lowest)
+	}
+}
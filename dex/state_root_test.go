@@ -0,0 +1,183 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/p2p"
+)
+
+type testStateRootChain map[uint64]*types.Block
+
+func (c testStateRootChain) CurrentBlock() *types.Block {
+	head := types.NewBlockWithHeader(&types.Header{Number: new(big.Int)})
+	for _, block := range c {
+		if block.NumberU64() > head.NumberU64() {
+			head = block
+		}
+	}
+	return head
+}
+
+func (c testStateRootChain) GetBlockByNumber(number uint64) *types.Block {
+	return c[number]
+}
+
+type testStateRootGovernance map[string]struct{}
+
+func (g testStateRootGovernance) NotarySet(round uint64) (map[string]struct{}, error) {
+	return g, nil
+}
+
+type testStateRootPeers []*peer
+
+func (ps testStateRootPeers) Peers() []*peer { return ps }
+
+func signedStateRoot(key *ecdsa.PrivateKey, block *types.Block, root common.Hash) *stateRootData {
+	d := &stateRootData{
+		Round:  block.Round(),
+		Number: block.NumberU64(),
+		Hash:   block.Hash(),
+		Root:   root,
+	}
+	d.Signature, _ = crypto.Sign(stateRootHash(d).Bytes(), key)
+	return d
+}
+
+// Tests that announced state roots are checked against the local chain,
+// alerting on divergences only for verified notary set members.
+func TestStateRootGossip(t *testing.T) {
+	notary, _ := crypto.GenerateKey()
+	outsider, _ := crypto.GenerateKey()
+	gov := testStateRootGovernance{
+		hex.EncodeToString(crypto.FromECDSAPub(&notary.PublicKey)): {},
+	}
+	block := types.NewBlockWithHeader(&types.Header{
+		Number: big.NewInt(10),
+		Root:   common.Hash{1},
+		Round:  1,
+	})
+	chain := testStateRootChain{
+		9: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(9)}),
+	}
+
+	sink := newTestAlertSink()
+	defer sink.Close()
+	a := newAlerter(AlertConfig{Webhooks: map[string]string{"*": sink.URL}},
+		common.Address{}, "", &testAlertGovernance{})
+	g := newStateRootGossip(chain, gov, testStateRootPeers{}, a, nil)
+
+	// Invalid signatures are rejected.
+	bad := signedStateRoot(notary, block, common.Hash{2})
+	bad.Signature = bad.Signature[1:]
+	if err := g.handle("peer", bad); err != errStateRootSignature {
+		t.Fatalf("error mismatch: have %v, want %v", err, errStateRootSignature)
+	}
+
+	// Roots of blocks not inserted yet are checked once they are.
+	if err := g.handle("peer", signedStateRoot(notary, block, common.Hash{2})); err != nil {
+		t.Fatalf("failed to handle state root: %v", err)
+	}
+	if len(g.pending) != 1 {
+		t.Fatalf("pending count mismatch: have %d, want 1", len(g.pending))
+	}
+	chain[10] = block
+
+	// Divergences announced by non-notaries are ignored.
+	if err := g.handle("peer", signedStateRoot(outsider, block, common.Hash{2})); err != nil {
+		t.Fatalf("failed to handle state root: %v", err)
+	}
+	a.Stop()
+	if posted := sink.posted(); len(posted) != 0 {
+		t.Fatalf("non-notary raised alerts: %v", posted)
+	}
+
+	g.retryPending()
+	// Repeated announcements are only checked once.
+	g.handle("peer", signedStateRoot(notary, block, common.Hash{2}))
+	a.Stop()
+	if posted := sink.posted(); len(posted) != 1 || posted[0] != AlertStateDivergence {
+		t.Fatalf("alerts mismatch: %v", posted)
+	}
+	if len(g.pending) != 0 {
+		t.Errorf("pending count mismatch: have %d, want 0", len(g.pending))
+	}
+}
+
+// Tests that announcements are dropped unless their round is the one the
+// local block starts.
+func TestStateRootGossipRound(t *testing.T) {
+	notary, _ := crypto.GenerateKey()
+	gov := testStateRootGovernance{
+		hex.EncodeToString(crypto.FromECDSAPub(&notary.PublicKey)): {},
+	}
+	header := func(number int64, round uint64) *types.Block {
+		return types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number), Round: round})
+	}
+	chain := testStateRootChain{9: header(9, 0), 10: header(10, 1), 11: header(11, 1)}
+	g := newStateRootGossip(chain, gov, testStateRootPeers{}, nil, nil)
+
+	// Old rounds claimed for a block of a later round.
+	old := signedStateRoot(notary, chain[10], common.Hash{})
+	old.Round = 0
+	old.Signature, _ = crypto.Sign(stateRootHash(old).Bytes(), notary)
+	// Blocks in the middle of their round.
+	middle := signedStateRoot(notary, chain[11], common.Hash{})
+	// Blocks not inserted yet, beyond the next round.
+	ahead := signedStateRoot(notary, header(20, 3), common.Hash{})
+
+	for name, root := range map[string]*stateRootData{"old round": old, "middle": middle, "ahead": ahead} {
+		if err := g.handle("peer", root); err != nil {
+			t.Fatalf("%s: failed to handle state root: %v", name, err)
+		}
+	}
+	if len(g.seen) != 0 || len(g.pending) != 0 {
+		t.Errorf("dropped announcements tracked: seen %v, pending %d", g.seen, len(g.pending))
+	}
+
+	// The first block of a round and the next round are accepted.
+	g.handle("peer", signedStateRoot(notary, chain[10], chain[10].Root()))
+	g.handle("peer", signedStateRoot(notary, header(12, 2), common.Hash{}))
+	if len(g.seen) != 1 || len(g.pending) != 1 {
+		t.Errorf("announcements mismatch: seen %v, pending %d", g.seen, len(g.pending))
+	}
+}
+
+// Tests that state roots are only relayed to dex/65 peers, other than the one
+// the announcement came from.
+func TestStateRootRelay(t *testing.T) {
+	var peers testStateRootPeers
+	for _, version := range []int{dex64, dex65, dex65} {
+		peers = append(peers, newPeer(version, p2p.NewPeerWithEnode(randomV4CompactNode(), "", nil), nil))
+	}
+	g := newStateRootGossip(testStateRootChain{}, testStateRootGovernance{}, peers, nil, nil)
+	g.relay(peers[2].id, &stateRootData{Number: 1})
+
+	for i, want := range []int{0, 1, 0} {
+		if have := len(peers[i].queuedStateRoots); have != want {
+			t.Errorf("peer %d: queued state roots mismatch: have %d, want %d", i, have, want)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/crypto"
+	"github.com/portto/go-tangerine/log"
+)
+
+const (
+	// StatusAPIPath is the path of the JSON status of the node.
+	StatusAPIPath = "/api/status"
+
+	// StatusPeersPath is the path of the JSON list of the connected peers.
+	StatusPeersPath = "/api/peers"
+
+	// StatusProposalsPath is the path of the JSON list of the blocks recently
+	// proposed by the node.
+	StatusProposalsPath = "/api/proposals"
+
+	// statusRecentProposals is the number of recent proposals listed.
+	statusRecentProposals = 20
+
+	// statusProposalWindow is the number of latest blocks scanned for recent
+	// proposals.
+	statusProposalWindow = 4096
+)
+
+// NodeStatus is the state of the node shown on the status page.
+type NodeStatus struct {
+	Sync     *SyncStatus     `json:"sync"`
+	Round    *RoundStatus    `json:"round"`
+	Peers    *PeerCounts     `json:"peers"`
+	Proposer *ProposerStatus `json:"proposer"` // Nil if the notary set is unknown
+	DKG      *DKGStatus      `json:"dkg"`
+	Uptime   string          `json:"uptime"`
+}
+
+// SyncStatus is the synchronisation state of the chain.
+type SyncStatus struct {
+	Synced        bool        `json:"synced"`
+	Synchronising bool        `json:"synchronising"`
+	StartingBlock uint64      `json:"startingBlock"`
+	CurrentBlock  uint64      `json:"currentBlock"`
+	HighestBlock  uint64      `json:"highestBlock"`
+	Head          common.Hash `json:"head"`
+	HeadTime      uint64      `json:"headTime"` // Milliseconds since the epoch
+}
+
+// RoundStatus is the round of the chain head and its configuration.
+type RoundStatus struct {
+	Round       uint64 `json:"round"`
+	CRSRound    uint64 `json:"crsRound"`
+	RoundHeight uint64 `json:"roundHeight"` // Height the round started at
+	RoundLength uint64 `json:"roundLength"`
+}
+
+// PeerCounts are the numbers of connected peers.
+type PeerCounts struct {
+	Connected int `json:"connected"`
+	Max       int `json:"max"`
+	Notary    int `json:"notary"` // Members of the notary sets connected for
+}
+
+// DKGStatus is the progress of the DKG of the latest CRS round.
+type DKGStatus struct {
+	Round    uint64 `json:"round"`
+	Reset    uint64 `json:"reset"`
+	MPKReady bool   `json:"mpkReady"`
+	Final    bool   `json:"final"`
+	Success  bool   `json:"success"`
+}
+
+// StatusPeer is a connected peer listed on the status page.
+type StatusPeer struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	RemoteAddr string `json:"remoteAddr"`
+	Inbound    bool   `json:"inbound"`
+	Notary     bool   `json:"notary"`
+	Number     uint64 `json:"number"`
+	Age        string `json:"age"`
+}
+
+// StatusProposal is a block recently proposed by the node.
+type StatusProposal struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+	Round  uint64      `json:"round"`
+	Time   uint64      `json:"time"` // Milliseconds since the epoch
+	Txs    int         `json:"txs"`
+}
+
+// Status returns the state of the node shown on the status page.
+func (s *Tangerine) Status() *NodeStatus {
+	pm := s.protocolManager
+	head := s.blockchain.CurrentBlock()
+	progress := pm.downloader.Progress()
+
+	status := &NodeStatus{
+		Sync: &SyncStatus{
+			Synced:        atomic.LoadUint32(&pm.acceptTxs) == 1,
+			Synchronising: pm.downloader.Synchronising(),
+			StartingBlock: progress.StartingBlock,
+			CurrentBlock:  head.NumberU64(),
+			HighestBlock:  progress.HighestBlock,
+			Head:          head.Hash(),
+			HeadTime:      head.Time(),
+		},
+		Round: &RoundStatus{
+			Round:       head.Round(),
+			CRSRound:    s.governance.CRSRound(),
+			RoundHeight: s.governance.GetRoundHeight(head.Round()),
+			RoundLength: s.governance.Configuration(head.Round()).RoundLength,
+		},
+		Peers: &PeerCounts{
+			Connected: pm.peers.Len(),
+			Max:       int(atomic.LoadInt32(&pm.maxPeers)),
+			Notary:    len(pm.peers.NotaryConns()),
+		},
+		Uptime: common.PrettyAge(s.startTime).String(),
+	}
+	if status.Sync.HighestBlock < status.Sync.CurrentBlock {
+		status.Sync.HighestBlock = status.Sync.CurrentBlock
+	}
+	if proposer, err := s.bp.Status(); err != nil {
+		log.Debug("Failed to get proposer status", "err", err)
+	} else {
+		status.Proposer = proposer
+	}
+
+	round := status.Round.CRSRound
+	status.DKG = &DKGStatus{
+		Round:    round,
+		Reset:    s.governance.DKGResetCount(round),
+		MPKReady: s.governance.IsDKGMPKReady(round),
+		Final:    s.governance.IsDKGFinal(round),
+		Success:  s.governance.IsDKGSuccess(round),
+	}
+	return status
+}
+
+// StatusPeers returns the connected peers, the notary set members first.
+func (s *Tangerine) StatusPeers() []*StatusPeer {
+	ps := s.protocolManager.peers
+	peers := ps.Peers()
+	list := make([]*StatusPeer, 0, len(peers))
+	for _, p := range peers {
+		_, number := p.Head()
+		list = append(list, &StatusPeer{
+			ID:         p.id,
+			Name:       p.Name(),
+			RemoteAddr: p.RemoteAddr().String(),
+			Inbound:    p.Inbound(),
+			Notary:     ps.IsNotary(p.ID()),
+			Number:     number,
+			Age:        common.PrettyAge(time.Now().Add(-p.Age())).String(),
+		})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Notary != list[j].Notary {
+			return list[i].Notary
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// StatusProposals returns the blocks recently proposed by the node, the
+// latest first. Blocks are indexed under the owner of the node, which is
+// looked up by the node key in the governance state of the chain head.
+func (s *Tangerine) StatusProposals() ([]*StatusProposal, error) {
+	gs, err := s.governance.GetHeadGovState()
+	if err != nil {
+		return nil, err
+	}
+	offset := gs.NodesOffsetByNodeKeyAddress(crypto.PubkeyToAddress(*s.signer.PublicKey()))
+	if offset.Sign() < 0 {
+		return []*StatusProposal{}, nil
+	}
+	owner := gs.Node(offset).Owner
+	return recentProposals(s.blockchain, s.chainDb, owner, statusRecentProposals), nil
+}
+
+// recentProposals returns up to limit canonical blocks of the latest
+// statusProposalWindow blocks whose coinbase is owner, the latest first.
+func recentProposals(chain *core.BlockChain, db rawdb.DatabaseReader, owner common.Address,
+	limit int) []*StatusProposal {
+	var (
+		proposals = []*StatusProposal{}
+		head      = chain.CurrentBlock().NumberU64()
+		from      uint64
+	)
+	if head >= statusProposalWindow {
+		from = head - statusProposalWindow + 1
+	}
+	numbers := rawdb.ReadProposedBlocks(db, owner, from, head)
+	for i := len(numbers) - 1; i >= 0; i-- {
+		block := chain.GetBlockByNumber(numbers[i])
+		// Skip entries left by blocks no longer canonical.
+		if block == nil || block.Coinbase() != owner {
+			continue
+		}
+		proposals = append(proposals, &StatusProposal{
+			Number: block.NumberU64(),
+			Hash:   block.Hash(),
+			Round:  block.Round(),
+			Time:   block.Time(),
+			Txs:    len(block.Transactions()),
+		})
+		if len(proposals) >= limit {
+			break
+		}
+	}
+	return proposals
+}
+
+// NewStatusHandler returns a http handler serving the status page of the
+// node and the JSON endpoints it is rendered from.
+func NewStatusHandler(dex *Tangerine) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(statusPage))
+	})
+	mux.HandleFunc(StatusAPIPath, func(w http.ResponseWriter, r *http.Request) {
+		writeStatusJSON(w, dex.Status())
+	})
+	mux.HandleFunc(StatusPeersPath, func(w http.ResponseWriter, r *http.Request) {
+		writeStatusJSON(w, dex.StatusPeers())
+	})
+	mux.HandleFunc(StatusProposalsPath, func(w http.ResponseWriter, r *http.Request) {
+		proposals, err := dex.StatusProposals()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeStatusJSON(w, proposals)
+	})
+	return mux
+}
+
+func writeStatusJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debug("Failed to write status", "err", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+// statusPage is the status page of the node. It has no dependencies and
+// renders the JSON endpoints of the status server, refreshing them every few
+// seconds.
+const statusPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Tangerine node status</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+h2 { font-size: 1.1em; margin-top: 1.5em; }
+table { border-collapse: collapse; }
+td, th { border-bottom: 1px solid #ddd; padding: 0.3em 0.8em; text-align: left; }
+th { background: #f4f4f4; }
+.mono { font-family: monospace; }
+.ok { color: #1a7f37; }
+.bad { color: #cf222e; }
+#error { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>Tangerine node status</h1>
+<div id="error"></div>
+<div id="status"></div>
+<h2>Recent proposals</h2>
+<div id="proposals"></div>
+<h2>Peers</h2>
+<div id="peers"></div>
+<script>
+function esc(v) {
+  return String(v).replace(/[&<>"']/g, function(c) {
+    return {"&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;", "'": "&#39;"}[c];
+  });
+}
+function flag(v) {
+  return v ? '<span class="ok">yes</span>' : '<span class="bad">no</span>';
+}
+function time(ms) {
+  return new Date(ms).toLocaleString();
+}
+function rows(pairs) {
+  return '<table>' + pairs.map(function(p) {
+    return '<tr><th>' + esc(p[0]) + '</th><td>' + p[1] + '</td></tr>';
+  }).join('') + '</table>';
+}
+function table(head, body) {
+  return '<table><tr>' + head.map(function(h) { return '<th>' + esc(h) + '</th>'; }).join('') +
+    '</tr>' + body.map(function(r) {
+      return '<tr>' + r.map(function(c) { return '<td>' + c + '</td>'; }).join('') + '</tr>';
+    }).join('') + '</table>';
+}
+function renderStatus(s) {
+  var html = '<h2>Sync</h2>' + rows([
+    ['Synced', flag(s.sync.synced)],
+    ['Synchronising', flag(s.sync.synchronising)],
+    ['Block', esc(s.sync.currentBlock) + ' / ' + esc(s.sync.highestBlock)],
+    ['Head', '<span class="mono">' + esc(s.sync.head) + '</span>'],
+    ['Head time', esc(time(s.sync.headTime))],
+    ['Uptime', esc(s.uptime)]
+  ]);
+  html += '<h2>Round</h2>' + rows([
+    ['Round', esc(s.round.round)],
+    ['CRS round', esc(s.round.crsRound)],
+    ['Round start', esc(s.round.roundHeight)],
+    ['Round length', esc(s.round.roundLength)]
+  ]);
+  if (s.proposer) {
+    html += '<h2>Proposer</h2>' + rows([
+      ['Enabled', flag(s.proposer.enabled)],
+      ['Proposing', flag(s.proposer.isProposing)],
+      ['Core syncing', flag(s.proposer.isCoreSyncing)],
+      ['In notary set', flag(s.proposer.inNotarySet)],
+      ['In DKG set', flag(s.proposer.inDKGSet)]
+    ]);
+  }
+  html += '<h2>DKG</h2>' + rows([
+    ['Round', esc(s.dkg.round)],
+    ['Reset', esc(s.dkg.reset)],
+    ['MPK ready', flag(s.dkg.mpkReady)],
+    ['Final', flag(s.dkg.final)],
+    ['Success', flag(s.dkg.success)]
+  ]);
+  html += '<h2>Peer counts</h2>' + rows([
+    ['Connected', esc(s.peers.connected) + ' / ' + esc(s.peers.max)],
+    ['Notary', esc(s.peers.notary)]
+  ]);
+  document.getElementById('status').innerHTML = html;
+}
+function renderProposals(list) {
+  document.getElementById('proposals').innerHTML = list.length == 0 ? 'None' :
+    table(['Number', 'Round', 'Time', 'Txs', 'Hash'], list.map(function(p) {
+      return [esc(p.number), esc(p.round), esc(time(p.time)), esc(p.txs),
+        '<span class="mono">' + esc(p.hash) + '</span>'];
+    }));
+}
+function renderPeers(list) {
+  document.getElementById('peers').innerHTML = list.length == 0 ? 'None' :
+    table(['ID', 'Name', 'Address', 'Inbound', 'Notary', 'Block', 'Age'], list.map(function(p) {
+      return ['<span class="mono">' + esc(p.id.substring(0, 16)) + '</span>', esc(p.name),
+        esc(p.remoteAddr), flag(p.inbound), flag(p.notary), esc(p.number), esc(p.age)];
+    }));
+}
+function load(path, render) {
+  return fetch(path).then(function(r) {
+    if (!r.ok) { throw new Error(path + ': ' + r.status); }
+    return r.json();
+  }).then(render);
+}
+function refresh() {
+  Promise.all([
+    load('api/status', renderStatus),
+    load('api/proposals', renderProposals),
+    load('api/peers', renderPeers)
+  ]).then(function() {
+    document.getElementById('error').textContent = '';
+  }, function(err) {
+    document.getElementById('error').textContent = 'Failed to refresh: ' + err.message;
+  });
+}
+refresh();
+setInterval(refresh, 5000);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,58 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/dex/downloader"
+)
+
+func TestRecentProposals(t *testing.T) {
+	owner, other := common.Address{1}, common.Address{2}
+	// Every third block is proposed by owner.
+	generator := func(i int, block *core.BlockGen) {
+		if i%3 == 0 {
+			block.SetCoinbase(owner)
+		} else {
+			block.SetCoinbase(other)
+		}
+	}
+	pm, db := newTestProtocolManagerMust(t, downloader.FullSync, 20, generator, nil)
+	defer pm.Stop()
+
+	proposals := recentProposals(pm.blockchain, db, owner, 4)
+	want := []uint64{19, 16, 13, 10}
+	if len(proposals) != len(want) {
+		t.Fatalf("proposal count mismatch: got %d, want %d", len(proposals), len(want))
+	}
+	for i, p := range proposals {
+		if p.Number != want[i] {
+			t.Errorf("proposal %d number mismatch: got %d, want %d", i, p.Number, want[i])
+		}
+		if block := pm.blockchain.GetBlockByNumber(p.Number); p.Hash != block.Hash() {
+			t.Errorf("proposal %d hash mismatch: got %x, want %x", i, p.Hash, block.Hash())
+		}
+	}
+
+	if proposals := recentProposals(pm.blockchain, db, common.Address{3}, 4); len(proposals) != 0 {
+		t.Errorf("proposals of unknown owner: %d", len(proposals))
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	ethereum "github.com/portto/go-tangerine"
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/core/types"
 	"github.com/portto/go-tangerine/dex/downloader"
@@ -192,6 +193,9 @@ func (pm *ProtocolManager) synchronise(peer *peer, force bool) {
 	if atomic.LoadUint32(&pm.fastSync) == 1 {
 		// Fast sync was explicitly requested, and explicitly granted
 		mode = downloader.FastSync
+		if atomic.LoadUint32(&pm.checkpointSync) == 1 {
+			mode = downloader.CheckpointSync
+		}
 	} else if currentBlock.NumberU64() == 0 && pm.blockchain.CurrentFastBlock().NumberU64() > 0 {
 		// The database seems empty as the current block is the genesis. Yet the fast
 		// block is ahead, so fast sync was enabled for this node at a certain point.
@@ -202,7 +206,7 @@ func (pm *ProtocolManager) synchronise(peer *peer, force bool) {
 		mode = downloader.FastSync
 	}
 
-	if mode == downloader.FastSync {
+	if mode != downloader.FullSync {
 		// Make sure the peer's total difficulty we are synchronizing is higher.
 		if pm.blockchain.CurrentFastBlock().NumberU64() >= pNumber {
 			return
@@ -216,6 +220,7 @@ func (pm *ProtocolManager) synchronise(peer *peer, force bool) {
 	if atomic.LoadUint32(&pm.fastSync) == 1 {
 		log.Info("Fast sync complete, auto disabling")
 		atomic.StoreUint32(&pm.fastSync, 0)
+		atomic.StoreUint32(&pm.checkpointSync, 0)
 	}
 	atomic.StoreUint32(&pm.acceptTxs, 1) // Mark initial sync done
 	if head := pm.blockchain.CurrentBlock(); head.NumberU64() > 0 {
@@ -228,3 +233,52 @@ func (pm *ProtocolManager) synchronise(peer *peer, force bool) {
 		go pm.BroadcastBlock(head, false)
 	}
 }
+
+// syncProgress reports the sync progress of the node in the format of
+// eth_syncing. While the consensus core catches up with the chain, the core
+// height is reported against the highest block known locally or announced by
+// peers, so that tooling sees a syncing node rather than a synced one that
+// does not propose. The compaction chain tip and whether the core is syncing
+// are always reported, to tell the chain sync from the consensus core sync.
+type syncProgress struct {
+	dex *Tangerine
+}
+
+func (s *syncProgress) Progress() ethereum.SyncProgress {
+	progress := s.dex.protocolManager.downloader.Progress()
+	progress.CoreHeight, progress.CoreRound = s.coreTip()
+
+	start, current, ok := s.dex.bp.coreSyncProgress()
+	if !ok {
+		return progress
+	}
+	highest := s.dex.blockchain.CurrentBlock().NumberU64()
+	if progress.HighestBlock > highest {
+		highest = progress.HighestBlock
+	}
+	if p := s.dex.protocolManager.peers.BestPeer(); p != nil {
+		if _, number := p.Head(); number > highest {
+			highest = number
+		}
+	}
+	return ethereum.SyncProgress{
+		StartingBlock: start,
+		CurrentBlock:  current,
+		HighestBlock:  highest,
+		CoreHeight:    progress.CoreHeight,
+		CoreRound:     progress.CoreRound,
+		CoreSyncing:   true,
+	}
+}
+
+// coreTip returns the height and the round of the compaction chain tip. The
+// round of a tip missing from the core database, e.g. one just repaired, is
+// taken from the chain head.
+func (s *syncProgress) coreTip() (height, round uint64) {
+	db := s.dex.protocolManager.coreDB
+	hash, height := db.GetCompactionChainTipInfo()
+	if block, err := db.GetBlock(hash); err == nil {
+		return height, block.Position.Round
+	}
+	return height, s.dex.blockchain.CurrentBlock().Round()
+}
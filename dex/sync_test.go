@@ -0,0 +1,65 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ethereum "github.com/portto/go-tangerine"
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/dex/downloader"
+)
+
+// Tests that eth_syncing reports the consensus core sync against the highest
+// block known, and the downloader progress once the core synced.
+func TestSyncProgressCoreSync(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 4, nil, nil)
+	defer pm.Stop()
+	dex := &Tangerine{protocolManager: pm, blockchain: pm.blockchain}
+	dex.bp = NewBlockProposer(dex, nil, time.Time{})
+	progress := &syncProgress{dex}
+
+	if have, want := progress.Progress(), pm.downloader.Progress(); have != want {
+		t.Errorf("progress mismatch without core sync: have %+v, want %+v", have, want)
+	}
+
+	atomic.StoreInt32(&dex.bp.syncing, 1)
+	atomic.StoreUint64(&dex.bp.coreSyncStart, 1)
+	atomic.StoreUint64(&dex.bp.coreSyncHeight, 2)
+	want := ethereum.SyncProgress{StartingBlock: 1, CurrentBlock: 2, HighestBlock: 4, CoreSyncing: true}
+	if have := progress.Progress(); have != want {
+		t.Errorf("core sync progress mismatch: have %+v, want %+v", have, want)
+	}
+
+	// A peer announcing a higher head raises the highest block.
+	peer, _ := newTestPeer("peer", dex64, pm, true)
+	defer peer.close()
+	for deadline := time.Now().Add(time.Second); pm.peers.Peer(peer.id) == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("peer not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	peer.peer.SetHead(common.Hash{1}, 10)
+	want.HighestBlock = 10
+	if have := progress.Progress(); have != want {
+		t.Errorf("core sync progress mismatch: have %+v, want %+v", have, want)
+	}
+}
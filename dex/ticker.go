@@ -0,0 +1,165 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	dexCore "github.com/portto/tangerine-consensus/core"
+)
+
+const (
+	// minAdaptiveLambda is the lowest fraction of the governance lambda an
+	// adaptive BA ticker may be configured to tick at.
+	minAdaptiveLambda = 0.25
+
+	// adaptiveLambdaDecay is the weight, in 1/n, of a faster confirmation
+	// in the moving average of an adaptive ticker.
+	adaptiveLambdaDecay = 8
+)
+
+// AdaptiveLambdaConfig lets the BA tickers follow the observed confirmation
+// times instead of ticking at the lambda of the governance. The bounds are
+// fractions of the governance lambda of the round.
+type AdaptiveLambdaConfig struct {
+	Enabled bool
+	Min     float64 // Lowest interval, at least minAdaptiveLambda
+	Max     float64 // Highest interval, at most 1
+}
+
+// validate checks that the bounds of an enabled config are within
+// [minAdaptiveLambda, 1].
+func (c *AdaptiveLambdaConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Min < minAdaptiveLambda || c.Min > c.Max || c.Max > 1 {
+		return fmt.Errorf("invalid adaptive lambda bounds [%v, %v], want %v <= min <= max <= 1",
+			c.Min, c.Max, minAdaptiveLambda)
+	}
+	return nil
+}
+
+// NewRoundTicker returns an adaptive BA ticker for round if enabled, nil
+// making the consensus core use the default tickers.
+func (d *DexconGovernance) NewRoundTicker(round uint64, tickerType dexCore.TickerType) dexCore.Ticker {
+	if tickerType != dexCore.TickerBA || !d.adaptiveLambda.Enabled {
+		return nil
+	}
+	lambda := d.Configuration(round).LambdaBA
+	return newAdaptiveTicker(
+		time.Duration(float64(lambda)*d.adaptiveLambda.Min),
+		time.Duration(float64(lambda)*d.adaptiveLambda.Max))
+}
+
+var _ dexCore.TickerObserver = (*adaptiveTicker)(nil)
+
+// adaptiveTicker is a BA ticker whose interval follows the observed
+// confirmation times within [min, max]. Confirmations on the fast path take
+// about one network delay, which the fast vote state waits three clocks
+// for, so the interval tracks the confirmation time. The average rises at
+// once on slower confirmations and decays slowly on faster ones, the new
+// interval being applied on Restart.
+type adaptiveTicker struct {
+	lock     sync.Mutex
+	min, max time.Duration
+	avg      time.Duration
+
+	ticker *time.Ticker
+	tickCh chan time.Time
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// newAdaptiveTicker creates an adaptiveTicker starting at max.
+func newAdaptiveTicker(min, max time.Duration) *adaptiveTicker {
+	t := &adaptiveTicker{min: min, max: max, avg: max}
+	t.start(max)
+	return t
+}
+
+// ObserveConfirmation implements dexCore.TickerObserver.
+func (t *adaptiveTicker) ObserveConfirmation(elapsed time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if elapsed >= t.avg {
+		t.avg = elapsed
+	} else {
+		t.avg -= (t.avg - elapsed) / adaptiveLambdaDecay
+	}
+}
+
+// interval returns the average confirmation time within the bounds.
+func (t *adaptiveTicker) interval() time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	switch {
+	case t.avg < t.min:
+		return t.min
+	case t.avg > t.max:
+		return t.max
+	default:
+		return t.avg
+	}
+}
+
+// Tick implements dexCore.Ticker.
+func (t *adaptiveTicker) Tick() <-chan time.Time {
+	return t.tickCh
+}
+
+// Stop implements dexCore.Ticker.
+func (t *adaptiveTicker) Stop() {
+	t.ticker.Stop()
+	close(t.quit)
+	t.wg.Wait()
+	close(t.tickCh)
+}
+
+// Restart implements dexCore.Ticker, applying the current interval.
+func (t *adaptiveTicker) Restart() {
+	t.Stop()
+	t.start(t.interval())
+}
+
+func (t *adaptiveTicker) start(interval time.Duration) {
+	t.ticker = time.NewTicker(interval)
+	t.tickCh = make(chan time.Time)
+	t.quit = make(chan struct{})
+	t.wg.Add(1)
+	go t.loop(t.ticker, t.tickCh, t.quit)
+}
+
+func (t *adaptiveTicker) loop(ticker *time.Ticker, tickCh chan<- time.Time, quit <-chan struct{}) {
+	defer t.wg.Done()
+	for {
+		select {
+		case <-quit:
+			return
+		case v := <-ticker.C:
+			select {
+			case tickCh <- v:
+			default:
+			}
+		}
+	}
+}
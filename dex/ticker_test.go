@@ -0,0 +1,109 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+	"time"
+
+	dexCore "github.com/portto/tangerine-consensus/core"
+)
+
+func TestAdaptiveLambdaConfigValidate(t *testing.T) {
+	tests := []struct {
+		config AdaptiveLambdaConfig
+		valid  bool
+	}{
+		{AdaptiveLambdaConfig{}, true},
+		{AdaptiveLambdaConfig{Min: 0.1, Max: 2}, true}, // Disabled
+		{AdaptiveLambdaConfig{Enabled: true, Min: 0.5, Max: 1}, true},
+		{AdaptiveLambdaConfig{Enabled: true, Min: minAdaptiveLambda, Max: minAdaptiveLambda}, true},
+		{AdaptiveLambdaConfig{Enabled: true, Min: 0.1, Max: 1}, false},
+		{AdaptiveLambdaConfig{Enabled: true, Min: 0.5, Max: 1.5}, false},
+		{AdaptiveLambdaConfig{Enabled: true, Min: 0.8, Max: 0.5}, false},
+	}
+	for i, tt := range tests {
+		if err := tt.config.validate(); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want %v", i, err, tt.valid)
+		}
+	}
+}
+
+func TestAdaptiveTickerDisabled(t *testing.T) {
+	gov := &DexconGovernance{}
+	if ticker := gov.NewRoundTicker(1, dexCore.TickerBA); ticker != nil {
+		t.Errorf("adaptive ticker created while disabled")
+	}
+	gov.adaptiveLambda = AdaptiveLambdaConfig{Enabled: true, Min: 0.5, Max: 1}
+	if ticker := gov.NewRoundTicker(1, dexCore.TickerDKG); ticker != nil {
+		t.Errorf("adaptive DKG ticker created")
+	}
+}
+
+// Tests that the interval of an adaptive ticker follows the confirmation
+// times within its bounds, rising at once and decaying slowly.
+func TestAdaptiveTickerInterval(t *testing.T) {
+	ticker := newAdaptiveTicker(250*time.Millisecond, time.Second)
+	defer ticker.Stop()
+
+	if interval := ticker.interval(); interval != time.Second {
+		t.Fatalf("initial interval mismatch: have %v, want %v", interval, time.Second)
+	}
+	// Fast confirmations lower the interval by 1/adaptiveLambdaDecay of the
+	// difference each.
+	ticker.ObserveConfirmation(200 * time.Millisecond)
+	if interval, want := ticker.interval(), time.Second-800*time.Millisecond/adaptiveLambdaDecay; interval != want {
+		t.Errorf("decayed interval mismatch: have %v, want %v", interval, want)
+	}
+	for i := 0; i < 100; i++ {
+		ticker.ObserveConfirmation(10 * time.Millisecond)
+	}
+	if interval := ticker.interval(); interval != 250*time.Millisecond {
+		t.Errorf("interval below the lower bound: have %v, want %v", interval, 250*time.Millisecond)
+	}
+	// A slow confirmation raises it at once, up to the upper bound.
+	ticker.ObserveConfirmation(600 * time.Millisecond)
+	if interval := ticker.interval(); interval != 600*time.Millisecond {
+		t.Errorf("raised interval mismatch: have %v, want %v", interval, 600*time.Millisecond)
+	}
+	ticker.ObserveConfirmation(5 * time.Second)
+	if interval := ticker.interval(); interval != time.Second {
+		t.Errorf("interval above the upper bound: have %v, want %v", interval, time.Second)
+	}
+}
+
+// Tests that an adaptive ticker ticks at its interval, applied on restart.
+func TestAdaptiveTickerRestart(t *testing.T) {
+	ticker := newAdaptiveTicker(20*time.Millisecond, time.Hour)
+	defer ticker.Stop()
+
+	select {
+	case <-ticker.Tick():
+		t.Fatalf("ticked before the interval")
+	case <-time.After(100 * time.Millisecond):
+	}
+	for i := 0; i < 100; i++ {
+		ticker.ObserveConfirmation(time.Millisecond)
+	}
+	ticker.Restart()
+	select {
+	case <-ticker.Tick():
+	case <-time.After(time.Second):
+		t.Fatalf("no tick at the adapted interval")
+	}
+}
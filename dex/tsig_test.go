@@ -0,0 +1,81 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"reflect"
+	"testing"
+
+	coreCommon "github.com/portto/tangerine-consensus/common"
+	cryptoDKG "github.com/portto/tangerine-consensus/core/crypto/dkg"
+)
+
+// Tests that partial signatures verified in a batch are found invalid
+// exactly when they are invalid on their own.
+func TestVerifyPartialSignatures(t *testing.T) {
+	hash := coreCommon.NewRandomHash()
+	other := coreCommon.NewRandomHash()
+
+	const n = 7
+	pubKeys := make([]cryptoDKG.PublicKey, n)
+	valid := make([]cryptoDKG.PartialSignature, n)
+	wrong := make([]cryptoDKG.PartialSignature, n)
+	for i := 0; i < n; i++ {
+		prv := cryptoDKG.NewPrivateKey()
+		pubKeys[i] = prv.PublicKey().(cryptoDKG.PublicKey)
+		sig, err := prv.Sign(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		valid[i] = cryptoDKG.PartialSignature(sig)
+		if sig, err = prv.Sign(other); err != nil {
+			t.Fatal(err)
+		}
+		wrong[i] = cryptoDKG.PartialSignature(sig)
+	}
+	// A valid signature under another public key.
+	swapped := append([]cryptoDKG.PartialSignature{}, valid...)
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+
+	mixed := append([]cryptoDKG.PartialSignature{}, valid...)
+	mixed[2] = wrong[2]
+	mixed[4].Signature = nil
+	mixed[5].Signature = []byte{1, 2, 3}
+	mixed[6] = wrong[6]
+
+	tests := []struct {
+		name    string
+		pubKeys []cryptoDKG.PublicKey
+		sigs    []cryptoDKG.PartialSignature
+		invalid []int
+	}{
+		{"valid", pubKeys, valid, nil},
+		{"invalid", pubKeys, wrong, []int{0, 1, 2, 3, 4, 5, 6}},
+		{"swapped", pubKeys, swapped, []int{0, 1}},
+		{"mixed", pubKeys, mixed, []int{2, 4, 5, 6}},
+		{"single", pubKeys[3:4], valid[3:4], nil},
+		{"single invalid", pubKeys[3:4], wrong[3:4], []int{0}},
+		{"empty", nil, nil, nil},
+	}
+	for _, tt := range tests {
+		invalid := cryptoDKG.VerifyPartialSignatures(hash, tt.pubKeys, tt.sigs)
+		if !reflect.DeepEqual(invalid, tt.invalid) {
+			t.Errorf("%s: invalid signatures mismatch: have %v, want %v", tt.name, invalid, tt.invalid)
+		}
+	}
+}
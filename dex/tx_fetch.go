@@ -0,0 +1,169 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync"
+	"time"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/internal/features"
+)
+
+// txAnnouncements guards relaying transactions to dex/65 peers outside the
+// notary set as hash announcements. Disabled, they get full transactions.
+var txAnnouncements = features.Register("tx-announcements",
+	"Relay transactions to dex/65 peers as hash announcements", true)
+
+const (
+	// maxTxAnnounces is the maximum number of transaction hashes a peer may
+	// announce in a single message.
+	maxTxAnnounces = 4096
+
+	// maxTxRetrievals is the maximum number of transactions requested from
+	// or returned to a peer in a single message.
+	maxTxRetrievals = 256
+
+	// txFetchTimeout is how long a requested transaction is waited for before
+	// it is requested again from the next peer announcing it.
+	txFetchTimeout = 5 * time.Second
+
+	// maxTxRequests is the number of pending transaction requests tracked,
+	// beyond which the timed out ones are dropped.
+	maxTxRequests = 16384
+)
+
+// txAnnounce tracks the peers that announced a transaction not known yet.
+type txAnnounce struct {
+	peers     []string  // Announcers not asked for the transaction yet
+	from      string    // Peer the transaction was last requested from
+	requested time.Time // Time the transaction was last requested
+}
+
+// txRequests tracks the announced transactions requested from peers, so
+// that a transaction announced by several peers is fetched from one only,
+// falling back to the next announcer if the request times out.
+type txRequests struct {
+	lock    sync.Mutex
+	pending map[common.Hash]*txAnnounce // Hash -> announcers and request time
+}
+
+func newTxRequests() *txRequests {
+	return &txRequests{pending: make(map[common.Hash]*txAnnounce)}
+}
+
+// announced records that peer announced hashes, and returns the ones to
+// request from it at now: those not requested within txFetchTimeout. The
+// others are kept to be requested from peer if the pending request times out.
+func (r *txRequests) announced(peer string, hashes []common.Hash, now time.Time) []common.Hash {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.pending) >= maxTxRequests {
+		r.expire(now)
+	}
+	var missing []common.Hash
+	for _, hash := range hashes {
+		ann, ok := r.pending[hash]
+		if !ok {
+			if len(r.pending) >= maxTxRequests {
+				break
+			}
+			ann = new(txAnnounce)
+			r.pending[hash] = ann
+		}
+		if now.Sub(ann.requested) >= txFetchTimeout {
+			ann.from, ann.requested = peer, now
+			missing = append(missing, hash)
+			continue
+		}
+		if peer != ann.from && !containsString(ann.peers, peer) {
+			ann.peers = append(ann.peers, peer)
+		}
+	}
+	return missing
+}
+
+// timeouts returns by peer the transactions whose request timed out at now,
+// marking them requested from the next peer which announced them.
+// Transactions no other peer announced stop being tracked.
+func (r *txRequests) timeouts(now time.Time) map[string][]common.Hash {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	retries := make(map[string][]common.Hash)
+	for hash, ann := range r.pending {
+		if now.Sub(ann.requested) < txFetchTimeout {
+			continue
+		}
+		if len(ann.peers) == 0 {
+			delete(r.pending, hash)
+			continue
+		}
+		peer := ann.peers[0]
+		ann.peers = ann.peers[1:]
+		ann.from, ann.requested = peer, now
+		retries[peer] = append(retries[peer], hash)
+	}
+	return retries
+}
+
+// expire stops tracking the timed out requests no other peer can serve. The
+// caller must hold the lock.
+func (r *txRequests) expire(now time.Time) {
+	for hash, ann := range r.pending {
+		if len(ann.peers) == 0 && now.Sub(ann.requested) >= txFetchTimeout {
+			delete(r.pending, hash)
+		}
+	}
+}
+
+// delivered stops tracking the requests of txs.
+func (r *txRequests) delivered(txs []*types.Transaction) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, tx := range txs {
+		delete(r.pending, tx.Hash())
+	}
+}
+
+// dropPeer forgets the announcements of a disconnected peer.
+func (r *txRequests) dropPeer(peer string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, ann := range r.pending {
+		for i, id := range ann.peers {
+			if id == peer {
+				ann.peers = append(ann.peers[:i], ann.peers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
+)
+
+// Tests that a transaction announced by several peers is requested from one
+// of them, and from the next one when the request times out.
+func TestTxRequestsTimeout(t *testing.T) {
+	r := newTxRequests()
+	hash := common.Hash{1}
+	now := time.Now()
+
+	if have := r.announced("a", []common.Hash{hash}, now); !reflect.DeepEqual(have, []common.Hash{hash}) {
+		t.Fatalf("requests from a mismatch: have %v, want %v", have, []common.Hash{hash})
+	}
+	for _, peer := range []string{"a", "b", "c", "b", "d"} {
+		if have := r.announced(peer, []common.Hash{hash}, now); len(have) != 0 {
+			t.Fatalf("requests from %s mismatch: have %v, want none", peer, have)
+		}
+	}
+	if have := r.timeouts(now.Add(txFetchTimeout - 1)); len(have) != 0 {
+		t.Fatalf("retries before timeout: %v", have)
+	}
+
+	// The withholding peers are skipped in announcement order.
+	now = now.Add(txFetchTimeout)
+	if have, want := r.timeouts(now), map[string][]common.Hash{"b": {hash}}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("retries mismatch: have %v, want %v", have, want)
+	}
+	r.dropPeer("c")
+	now = now.Add(txFetchTimeout)
+	if have, want := r.timeouts(now), map[string][]common.Hash{"d": {hash}}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("retries mismatch: have %v, want %v", have, want)
+	}
+
+	// Once no announcer is left the transaction is no longer tracked.
+	now = now.Add(txFetchTimeout)
+	if have := r.timeouts(now); len(have) != 0 {
+		t.Fatalf("retries without announcers: %v", have)
+	}
+	if len(r.pending) != 0 {
+		t.Fatalf("pending requests mismatch: have %d, want 0", len(r.pending))
+	}
+}
+
+// Tests that delivered transactions are no longer requested.
+func TestTxRequestsDelivered(t *testing.T) {
+	r := newTxRequests()
+	tx := newTestTransaction(testAccount, 0, 0)
+	now := time.Now()
+
+	r.announced("a", []common.Hash{tx.Hash()}, now)
+	r.announced("b", []common.Hash{tx.Hash()}, now)
+	r.delivered([]*types.Transaction{tx})
+
+	if have := r.timeouts(now.Add(txFetchTimeout)); len(have) != 0 {
+		t.Fatalf("retries of delivered transaction: %v", have)
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"container/heap"
+	"sort"
+	"sync"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/event"
+)
+
+// Transaction ordering policies of proposed payloads.
+const (
+	// TxOrderPrice orders transactions by gas price, honouring the nonce
+	// order of every sender.
+	TxOrderPrice = "price"
+
+	// TxOrderFIFO orders transactions by the time they became executable in
+	// the local pool.
+	TxOrderFIFO = "fifo"
+
+	// TxOrderFair takes one transaction of every sender in turn, senders
+	// being visited in arrival order.
+	TxOrderFair = "fair"
+)
+
+const txArrivalChanSize = 4096
+
+// txOrdering yields the transactions of a payload in policy order, keeping
+// the nonce order of every sender.
+type txOrdering interface {
+	// Peek returns the next transaction, nil once done.
+	Peek() *types.Transaction
+
+	// Shift replaces the next transaction by the following one of the same
+	// sender.
+	Shift()
+
+	// Pop drops the next transaction along with the remaining ones of the
+	// same sender.
+	Pop()
+}
+
+// txArrivals numbers transactions in the order they are promoted to the
+// executable set of the pool. Transactions promoted before tracking started
+// are numbered zero, ordering first.
+type txArrivals struct {
+	lock sync.Mutex
+	seq  map[common.Hash]uint64
+	next uint64
+
+	txCh  chan core.NewTxsEvent
+	txSub event.Subscription
+}
+
+func newTxArrivals(pool *core.TxPool) *txArrivals {
+	a := &txArrivals{
+		seq:  make(map[common.Hash]uint64),
+		next: 1,
+		txCh: make(chan core.NewTxsEvent, txArrivalChanSize),
+	}
+	a.txSub = pool.SubscribeNewTxsEvent(a.txCh)
+	go a.loop()
+	return a
+}
+
+func (a *txArrivals) Stop() {
+	a.txSub.Unsubscribe()
+}
+
+func (a *txArrivals) loop() {
+	for {
+		select {
+		case ev := <-a.txCh:
+			a.add(ev.Txs)
+		case <-a.txSub.Err():
+			return
+		}
+	}
+}
+
+func (a *txArrivals) add(txs []*types.Transaction) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for _, tx := range txs {
+		if _, ok := a.seq[tx.Hash()]; !ok {
+			a.seq[tx.Hash()] = a.next
+			a.next++
+		}
+	}
+}
+
+// mark returns the number the next arriving transaction gets, to be passed
+// to arrival.
+func (a *txArrivals) mark() uint64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.next
+}
+
+// arrival returns the arrival numbers of the transactions of pending, a
+// snapshot of the pool taken after mark. Transactions which left the pool,
+// that is neither in pending nor arrived after mark, are forgotten.
+func (a *txArrivals) arrival(pending map[common.Address]types.Transactions, mark uint64) map[common.Hash]uint64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	seq := make(map[common.Hash]uint64)
+	for hash, n := range a.seq {
+		if n >= mark {
+			seq[hash] = n
+		}
+	}
+	for _, txs := range pending {
+		for _, tx := range txs {
+			if n, ok := a.seq[tx.Hash()]; ok {
+				seq[tx.Hash()] = n
+			}
+		}
+	}
+	a.seq = seq
+	return seq
+}
+
+// txQueue is the remaining transactions of a sender.
+type txQueue struct {
+	from common.Address
+	txs  types.Transactions
+}
+
+// newTxQueues returns the queues of txs ordered by the arrival of their first
+// transaction, ties being broken by sender.
+func newTxQueues(txs map[common.Address]types.Transactions, seq map[common.Hash]uint64) []*txQueue {
+	queues := make([]*txQueue, 0, len(txs))
+	for from, list := range txs {
+		if len(list) > 0 {
+			queues = append(queues, &txQueue{from: from, txs: list})
+		}
+	}
+	sort.Slice(queues, func(i, j int) bool {
+		return arrivedBefore(queues[i], queues[j], seq)
+	})
+	return queues
+}
+
+func arrivedBefore(a, b *txQueue, seq map[common.Hash]uint64) bool {
+	sa, sb := seq[a.txs[0].Hash()], seq[b.txs[0].Hash()]
+	if sa != sb {
+		return sa < sb
+	}
+	return bytes.Compare(a.from[:], b.from[:]) < 0
+}
+
+// txQueueHeap is a heap of sender queues ordered by the arrival of their
+// first transaction.
+type txQueueHeap struct {
+	queues []*txQueue
+	seq    map[common.Hash]uint64
+}
+
+func (h *txQueueHeap) Len() int           { return len(h.queues) }
+func (h *txQueueHeap) Less(i, j int) bool { return arrivedBefore(h.queues[i], h.queues[j], h.seq) }
+func (h *txQueueHeap) Swap(i, j int)      { h.queues[i], h.queues[j] = h.queues[j], h.queues[i] }
+
+func (h *txQueueHeap) Push(x interface{}) {
+	h.queues = append(h.queues, x.(*txQueue))
+}
+
+func (h *txQueueHeap) Pop() interface{} {
+	old := h.queues
+	n := len(old)
+	x := old[n-1]
+	h.queues = old[0 : n-1]
+	return x
+}
+
+// txsByArrival implements the TxOrderFIFO policy.
+type txsByArrival struct {
+	heads *txQueueHeap
+}
+
+func newTxsByArrival(txs map[common.Address]types.Transactions, seq map[common.Hash]uint64) *txsByArrival {
+	// Sorted queues already satisfy the heap invariant.
+	return &txsByArrival{heads: &txQueueHeap{queues: newTxQueues(txs, seq), seq: seq}}
+}
+
+func (t *txsByArrival) Peek() *types.Transaction {
+	if t.heads.Len() == 0 {
+		return nil
+	}
+	return t.heads.queues[0].txs[0]
+}
+
+func (t *txsByArrival) Shift() {
+	if q := t.heads.queues[0]; len(q.txs) > 1 {
+		q.txs = q.txs[1:]
+		heap.Fix(t.heads, 0)
+	} else {
+		heap.Pop(t.heads)
+	}
+}
+
+func (t *txsByArrival) Pop() {
+	heap.Pop(t.heads)
+}
+
+// txsRoundRobin implements the TxOrderFair policy.
+type txsRoundRobin struct {
+	queues []*txQueue
+	next   int
+}
+
+func newTxsRoundRobin(txs map[common.Address]types.Transactions, seq map[common.Hash]uint64) *txsRoundRobin {
+	return &txsRoundRobin{queues: newTxQueues(txs, seq)}
+}
+
+func (t *txsRoundRobin) Peek() *types.Transaction {
+	if len(t.queues) == 0 {
+		return nil
+	}
+	return t.queues[t.next].txs[0]
+}
+
+func (t *txsRoundRobin) Shift() {
+	if q := t.queues[t.next]; len(q.txs) > 1 {
+		q.txs = q.txs[1:]
+		t.next++
+	} else {
+		t.queues = append(t.queues[:t.next], t.queues[t.next+1:]...)
+	}
+	if t.next >= len(t.queues) {
+		t.next = 0
+	}
+}
+
+func (t *txsRoundRobin) Pop() {
+	t.queues = append(t.queues[:t.next], t.queues[t.next+1:]...)
+	if t.next >= len(t.queues) {
+		t.next = 0
+	}
+}
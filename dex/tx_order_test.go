@@ -0,0 +1,88 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
+)
+
+// Tests that the FIFO and fair orderings follow arrival order, keep the
+// nonce order of every sender and drop popped senders.
+func TestTxOrdering(t *testing.T) {
+	var (
+		a = common.Address{1}
+		b = common.Address{2}
+		c = common.Address{3}
+	)
+	newTxs := func(to common.Address, n int) types.Transactions {
+		txs := make(types.Transactions, n)
+		for i := range txs {
+			txs[i] = types.NewTransaction(uint64(i), to, big.NewInt(0), 21000, big.NewInt(1), nil)
+		}
+		return txs
+	}
+	pending := map[common.Address]types.Transactions{
+		a: newTxs(a, 3),
+		b: newTxs(b, 2),
+		c: newTxs(c, 1),
+	}
+	// Arrivals: b0, a0, c0, a1, b1, a2.
+	seq := map[common.Hash]uint64{
+		pending[b][0].Hash(): 1,
+		pending[a][0].Hash(): 2,
+		pending[c][0].Hash(): 3,
+		pending[a][1].Hash(): 4,
+		pending[b][1].Hash(): 5,
+		pending[a][2].Hash(): 6,
+	}
+	name := func(tx *types.Transaction) string {
+		return fmt.Sprintf("%x%d", tx.To()[0], tx.Nonce())
+	}
+	drain := func(ordered txOrdering, pop common.Address) []string {
+		var names []string
+		for tx := ordered.Peek(); tx != nil; tx = ordered.Peek() {
+			if *tx.To() == pop && tx.Nonce() == 1 {
+				ordered.Pop()
+				continue
+			}
+			names = append(names, name(tx))
+			ordered.Shift()
+		}
+		return names
+	}
+	tests := []struct {
+		ordered txOrdering
+		pop     common.Address
+		want    string
+	}{
+		{newTxsByArrival(pending, seq), common.Address{}, "[20 10 30 11 21 12]"},
+		{newTxsByArrival(pending, seq), a, "[20 10 30 21]"},
+		{newTxsRoundRobin(pending, seq), common.Address{}, "[20 10 30 21 11 12]"},
+		{newTxsRoundRobin(pending, seq), b, "[20 10 30 11 12]"},
+	}
+	for i, tt := range tests {
+		if have := fmt.Sprint(drain(tt.ordered, tt.pop)); have != tt.want {
+			t.Errorf("test %d: order mismatch: have %s, want %s", i, have, tt.want)
+		}
+	}
+}
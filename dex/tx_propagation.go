@@ -0,0 +1,123 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/core/types"
+)
+
+// maxTxPropagations is the number of transactions whose propagation is
+// remembered, least recently seen ones being evicted first.
+const maxTxPropagations = 16384
+
+// TxOrigin tells how a transaction reached the node first.
+type TxOrigin string
+
+const (
+	// TxOriginLocal is a transaction submitted through the RPC of the node,
+	// including the governance transactions of the node itself.
+	TxOriginLocal TxOrigin = "local"
+
+	// TxOriginPeer is a transaction gossiped by a peer.
+	TxOriginPeer TxOrigin = "peer"
+
+	// TxOriginDirect is a transaction forwarded by a peer connected as a
+	// member of the notary set, which transactions are sent to first.
+	TxOriginDirect TxOrigin = "direct"
+)
+
+// TxPropagation is the propagation record of a transaction.
+type TxPropagation struct {
+	Hash      common.Hash `json:"hash"`
+	Origin    TxOrigin    `json:"origin"`
+	Peer      string      `json:"peer,omitempty"` // Peer delivering it first
+	FirstSeen int64       `json:"firstSeen"`      // Milliseconds since the epoch
+	Received  int         `json:"received"`       // Number of deliveries by peers
+	Relayed   int         `json:"relayed"`        // Number of peers it was relayed to
+}
+
+// txPropagations records how the recent transactions reached the node and
+// how far they were relayed, to investigate censored or delayed ones.
+type txPropagations struct {
+	lock    sync.Mutex
+	records *simplelru.LRU // hash -> *TxPropagation
+}
+
+func newTxPropagations(size int) *txPropagations {
+	records, _ := simplelru.NewLRU(size, nil)
+	return &txPropagations{records: records}
+}
+
+// seen records the arrival of txs from origin. Peer is the id of the peer
+// delivering them, empty for local transactions.
+func (t *txPropagations) seen(txs types.Transactions, origin TxOrigin, peer string) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, tx := range txs {
+		hash := tx.Hash()
+		if v, ok := t.records.Get(hash); ok {
+			if peer != "" {
+				v.(*TxPropagation).Received++
+			}
+			continue
+		}
+		record := &TxPropagation{
+			Hash:      hash,
+			Origin:    origin,
+			Peer:      peer,
+			FirstSeen: now,
+		}
+		if peer != "" {
+			record.Received = 1
+		}
+		t.records.Add(hash, record)
+	}
+}
+
+// relayed records that the transaction was sent to the given number of
+// peers. Transactions not seen arriving are ignored.
+func (t *txPropagations) relayed(hash common.Hash, peers int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if v, ok := t.records.Peek(hash); ok {
+		v.(*TxPropagation).Relayed += peers
+	}
+}
+
+// get returns a copy of the propagation record of the transaction, nil if
+// unknown.
+func (t *txPropagations) get(hash common.Hash) *TxPropagation {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	v, ok := t.records.Peek(hash)
+	if !ok {
+		return nil
+	}
+	record := *v.(*TxPropagation)
+	return &record
+}
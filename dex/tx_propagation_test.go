@@ -0,0 +1,66 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+
+	"github.com/portto/go-tangerine/core/types"
+)
+
+func TestTxPropagations(t *testing.T) {
+	props := newTxPropagations(2)
+	local := newTestTransaction(testAccount, 0, 0)
+	remote := newTestTransaction(testAccount, 1, 0)
+
+	props.seen(types.Transactions{local}, TxOriginLocal, "")
+	props.seen(types.Transactions{remote}, TxOriginDirect, "notary")
+	props.seen(types.Transactions{local, remote}, TxOriginPeer, "peer")
+	props.relayed(local.Hash(), 3)
+	props.relayed(local.Hash(), 2)
+
+	record := props.get(local.Hash())
+	if record == nil {
+		t.Fatalf("local transaction not recorded")
+	}
+	if record.Origin != TxOriginLocal || record.Peer != "" {
+		t.Errorf("origin mismatch: got %s from %q, want %s", record.Origin, record.Peer, TxOriginLocal)
+	}
+	if record.Received != 1 || record.Relayed != 5 {
+		t.Errorf("count mismatch: got %d received %d relayed, want 1 received 5 relayed",
+			record.Received, record.Relayed)
+	}
+	record = props.get(remote.Hash())
+	if record == nil {
+		t.Fatalf("remote transaction not recorded")
+	}
+	if record.Origin != TxOriginDirect || record.Peer != "notary" || record.Received != 2 {
+		t.Errorf("record mismatch: got %s from %q received %d, want %s from %q received 2",
+			record.Origin, record.Peer, record.Received, TxOriginDirect, "notary")
+	}
+
+	// The least recently seen transaction is evicted.
+	props.seen(types.Transactions{newTestTransaction(testAccount, 2, 0)}, TxOriginLocal, "")
+	if props.get(local.Hash()) != nil {
+		t.Errorf("least recently seen transaction not evicted")
+	}
+	props.relayed(local.Hash(), 1)
+	if props.get(local.Hash()) != nil {
+		t.Errorf("evicted transaction recorded by a relay")
+	}
+}
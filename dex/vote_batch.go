@@ -0,0 +1,91 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync"
+	"time"
+
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
+	"github.com/portto/go-tangerine/rlp"
+)
+
+// voteBatchInterval is how long the first vote cast at a position is held,
+// so that the votes cast for the same position right after it are sent
+// along in the same message.
+const voteBatchInterval = 10 * time.Millisecond
+
+// voteBatch is the votes cast at a position waiting to be broadcast.
+type voteBatch struct {
+	keys  map[voteKey]struct{}
+	votes []rlp.RawValue // Encoded votes, in the order they were cast
+}
+
+// voteBatcher coalesces the votes cast at the same position within a batching
+// window, handing each batch over to be broadcast as one message.
+type voteBatcher struct {
+	lock     sync.Mutex
+	pending  map[coreTypes.Position]*voteBatch
+	interval time.Duration
+	flush    func(pos coreTypes.Position, votes []rlp.RawValue)
+}
+
+func newVoteBatcher(interval time.Duration,
+	flush func(pos coreTypes.Position, votes []rlp.RawValue)) *voteBatcher {
+	return &voteBatcher{
+		pending:  make(map[coreTypes.Position]*voteBatch),
+		interval: interval,
+		flush:    flush,
+	}
+}
+
+// add queues the encoded vote for broadcast, returning false if it is
+// already queued.
+func (b *voteBatcher) add(vote *coreTypes.Vote, enc rlp.RawValue) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	batch, exist := b.pending[vote.Position]
+	if !exist {
+		batch = &voteBatch{keys: make(map[voteKey]struct{})}
+		b.pending[vote.Position] = batch
+		pos := vote.Position
+		time.AfterFunc(b.interval, func() { b.release(pos) })
+	}
+	key := voteToKey(vote)
+	if _, exist := batch.keys[key]; exist {
+		return false
+	}
+	batch.keys[key] = struct{}{}
+	batch.votes = append(batch.votes, enc)
+	return true
+}
+
+// release hands the votes queued at pos over to be broadcast.
+func (b *voteBatcher) release(pos coreTypes.Position) {
+	b.lock.Lock()
+	batch := b.pending[pos]
+	delete(b.pending, pos)
+	b.lock.Unlock()
+
+	if batch != nil {
+		voteBatchSizeHistogram.Update(int64(len(batch.votes)))
+		b.flush(pos, batch.votes)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+	"time"
+
+	coreTypes "github.com/portto/tangerine-consensus/core/types"
+
+	"github.com/portto/go-tangerine/rlp"
+)
+
+func TestVoteBatcher(t *testing.T) {
+	type batch struct {
+		pos   coreTypes.Position
+		votes []rlp.RawValue
+	}
+	flushed := make(chan batch, 2)
+	batcher := newVoteBatcher(20*time.Millisecond, func(pos coreTypes.Position, votes []rlp.RawValue) {
+		flushed <- batch{pos, votes}
+	})
+
+	pos := coreTypes.Position{Round: 1, Height: 2}
+	vote := func(pos coreTypes.Position, typ coreTypes.VoteType) *coreTypes.Vote {
+		return &coreTypes.Vote{VoteHeader: coreTypes.VoteHeader{Type: typ, Position: pos}}
+	}
+	if !batcher.add(vote(pos, coreTypes.VoteInit), rlp.RawValue{1}) {
+		t.Fatalf("first vote rejected")
+	}
+	if !batcher.add(vote(pos, coreTypes.VotePreCom), rlp.RawValue{2}) {
+		t.Fatalf("second vote rejected")
+	}
+	if batcher.add(vote(pos, coreTypes.VoteInit), rlp.RawValue{1}) {
+		t.Errorf("duplicate vote queued")
+	}
+	other := coreTypes.Position{Round: 1, Height: 3}
+	batcher.add(vote(other, coreTypes.VoteInit), rlp.RawValue{3})
+
+	for i := 0; i < 2; i++ {
+		select {
+		case b := <-flushed:
+			switch b.pos {
+			case pos:
+				if len(b.votes) != 2 || b.votes[0][0] != 1 || b.votes[1][0] != 2 {
+					t.Errorf("batch at %v mismatch: %v", b.pos, b.votes)
+				}
+			case other:
+				if len(b.votes) != 1 || b.votes[0][0] != 3 {
+					t.Errorf("batch at %v mismatch: %v", b.pos, b.votes)
+				}
+			default:
+				t.Errorf("unexpected batch at %v", b.pos)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("votes not flushed")
+		}
+	}
+
+	// Votes cast after a batch is flushed start a new one.
+	if !batcher.add(vote(pos, coreTypes.VoteInit), rlp.RawValue{1}) {
+		t.Errorf("vote of flushed batch rejected")
+	}
+}
@@ -124,6 +124,10 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
+	if (chainConfig.EWASMBlock != nil || chainConfig.EWASMRound != nil) && !vm.HasEWASMEngine(config.EWASMInterpreter) {
+		return nil, fmt.Errorf("unknown ewasm engine %q", config.EWASMInterpreter)
+	}
+
 	eth := &Ethereum{
 		config:         config,
 		chainDb:        chainDb,
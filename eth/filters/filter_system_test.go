@@ -212,6 +212,35 @@ func TestBlockSubscription(t *testing.T) {
 	<-sub1.Err()
 }
 
+// TestFilterTimeout tests whether filters that are not polled are removed after
+// the configured timeout while polled filters are kept.
+func TestFilterTimeout(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux        = new(event.TypeMux)
+		db         = ethdb.NewMemDatabase()
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		api        = NewPublicFilterAPIWithTimeout(backend, false, 100*time.Millisecond)
+	)
+
+	polled := api.NewBlockFilter()
+	idle := api.NewBlockFilter()
+	for i := 0; i < 10; i++ {
+		time.Sleep(50 * time.Millisecond)
+		if _, err := api.GetFilterChanges(polled); err != nil {
+			t.Fatalf("polled filter removed: %v", err)
+		}
+	}
+	if _, err := api.GetFilterChanges(idle); err == nil {
+		t.Errorf("idle filter not removed")
+	}
+}
+
 // TestPendingTxFilter tests whether pending tx filters retrieve all pending transactions that are posted to the event mux.
 func TestPendingTxFilter(t *testing.T) {
 	t.Parallel()
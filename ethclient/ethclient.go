@@ -280,6 +280,9 @@ type rpcProgress struct {
 	HighestBlock  hexutil.Uint64
 	PulledStates  hexutil.Uint64
 	KnownStates   hexutil.Uint64
+	CoreHeight    hexutil.Uint64
+	CoreRound     hexutil.Uint64
+	CoreSyncing   bool
 }
 
 // SyncProgress retrieves the current progress of the sync algorithm. If there's
@@ -304,6 +307,9 @@ func (ec *Client) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, err
 		HighestBlock:  uint64(progress.HighestBlock),
 		PulledStates:  uint64(progress.PulledStates),
 		KnownStates:   uint64(progress.KnownStates),
+		CoreHeight:    uint64(progress.CoreHeight),
+		CoreRound:     uint64(progress.CoreRound),
+		CoreSyncing:   progress.CoreSyncing,
 	}, nil
 }
 
@@ -33,6 +33,7 @@ type ReadOnlyBlockChain interface {
 	GetBlocksFromHash(common.Hash, int) (blocks []*types.Block)
 	GetBody(common.Hash) *types.Body
 	GetBodyRLP(common.Hash) rlp.RawValue
+	GetExtendedReceiptsByHash(common.Hash) []*types.ExtendedReceipt
 	GetGovStateByHash(common.Hash) (*types.GovState, error)
 	GetGovStateByNumber(uint64) (*types.GovState, error)
 	GetHeader(common.Hash, uint64) *types.Header
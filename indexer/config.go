@@ -19,11 +19,14 @@ type Config struct {
 	PluginFlags string
 
 	// The genesis block from dex.Config
-	Genesis *core.Genesis
+	Genesis *core.Genesis `toml:"-"`
 
 	// Protocol options from dex.Config (partial)
-	NetworkID uint64
-	SyncMode  downloader.SyncMode
+	NetworkID uint64              `toml:"-"`
+	SyncMode  downloader.SyncMode `toml:"-"`
+
+	// Whether extended receipts are stored, from dex.Config
+	ExtendedReceipts bool `toml:"-"`
 }
 
 // NewIndexerFromConfig initialize exporter according to given config.
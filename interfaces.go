@@ -103,6 +103,10 @@ type SyncProgress struct {
 	HighestBlock  uint64 // Highest alleged block number in the chain
 	PulledStates  uint64 // Number of state trie entries already downloaded
 	KnownStates   uint64 // Total number of state trie entries known about
+
+	CoreHeight  uint64 // Height of the compaction chain tip of the consensus core
+	CoreRound   uint64 // Round of the compaction chain tip of the consensus core
+	CoreSyncing bool   // Whether the consensus core is catching up with the compaction chain
 }
 
 // ChainSyncReader wraps access to the node's current sync status. If there's no
@@ -27,6 +27,7 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/portto/go-tangerine/accounts"
+	"github.com/portto/go-tangerine/accounts/abi"
 	"github.com/portto/go-tangerine/accounts/keystore"
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/common/hexutil"
@@ -67,6 +68,60 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*hexutil.Big, error)
 	return (*hexutil.Big)(price), err
 }
 
+// MaxPriorityFeePerGas returns a suggestion for the gas tip cap of EIP-1559
+// clients. There is no fee market, blocks report the governance minimum gas
+// price as their base fee, so the tip is what the suggested price exceeds it by.
+func (s *PublicEthereumAPI) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, error) {
+	tip, err := suggestTip(ctx, s.b, s.b.CurrentBlock().Round())
+	return (*hexutil.Big)(tip), err
+}
+
+// suggestTip returns the gas tip suggested on top of the base fee of round.
+func suggestTip(ctx context.Context, b Backend, round uint64) (*big.Int, error) {
+	price, err := b.SuggestPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	baseFee, err := b.MinGasPrice(ctx, round)
+	if err != nil {
+		return nil, err
+	}
+	tip := new(big.Int).Sub(price, baseFee)
+	if tip.Sign() < 0 {
+		tip.SetUint64(0)
+	}
+	return tip, nil
+}
+
+// feeCapsGasPrice translates the fee caps EIP-1559 clients set instead of a
+// gas price into the gas price of a legacy transaction: the base fee of round
+// plus the tip, capped by maxFee. The tip defaults to the suggested one.
+func feeCapsGasPrice(ctx context.Context, b Backend, round uint64, maxFee, maxTip *hexutil.Big) (*big.Int, error) {
+	if maxFee != nil && maxTip != nil && maxFee.ToInt().Cmp(maxTip.ToInt()) < 0 {
+		return nil, fmt.Errorf("maxFeePerGas (%v) < maxPriorityFeePerGas (%v)", maxFee, maxTip)
+	}
+	baseFee, err := b.MinGasPrice(ctx, round)
+	if err != nil {
+		return nil, err
+	}
+	tip := maxTip.ToInt()
+	if maxTip == nil {
+		if tip, err = suggestTip(ctx, b, round); err != nil {
+			return nil, err
+		}
+	}
+	price := new(big.Int).Add(baseFee, tip)
+	if maxFee != nil {
+		if maxFee.ToInt().Cmp(baseFee) < 0 {
+			return nil, fmt.Errorf("maxFeePerGas (%v) < base fee (%v)", maxFee, (*hexutil.Big)(baseFee))
+		}
+		if price.Cmp(maxFee.ToInt()) > 0 {
+			price.Set(maxFee.ToInt())
+		}
+	}
+	return price, nil
+}
+
 // ProtocolVersion returns the current Ethereum protocol version this node supports
 func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -93,6 +148,9 @@ func (s *PublicEthereumAPI) Syncing() (interface{}, error) {
 		"highestBlock":  hexutil.Uint64(progress.HighestBlock),
 		"pulledStates":  hexutil.Uint64(progress.PulledStates),
 		"knownStates":   hexutil.Uint64(progress.KnownStates),
+		"coreHeight":    hexutil.Uint64(progress.CoreHeight),
+		"coreRound":     hexutil.Uint64(progress.CoreRound),
+		"coreSyncing":   progress.CoreSyncing,
 	}, nil
 }
 
@@ -317,7 +375,8 @@ func (s *PrivateAccountAPI) ImportRawKey(privkey string, password string) (commo
 // UnlockAccount will unlock the account associated with the given address with
 // the given password for duration seconds. If duration is nil it will use a
 // default of 300 seconds. It returns an indication if the account was unlocked.
-func (s *PrivateAccountAPI) UnlockAccount(addr common.Address, password string, duration *uint64) (bool, error) {
+// The attempt is recorded by the backends keeping an audit log.
+func (s *PrivateAccountAPI) UnlockAccount(ctx context.Context, addr common.Address, password string, duration *uint64) (bool, error) {
 	const max = uint64(time.Duration(math.MaxInt64) / time.Second)
 	var d time.Duration
 	if duration == nil {
@@ -331,6 +390,11 @@ func (s *PrivateAccountAPI) UnlockAccount(addr common.Address, password string,
 	if err != nil {
 		log.Warn("Failed account unlock attempt", "address", addr, "err", err)
 	}
+	if auditor, ok := s.b.(interface {
+		AuditUnlockAccount(ctx context.Context, addr common.Address, duration time.Duration, err error)
+	}); ok {
+		auditor.AuditUnlockAccount(ctx, addr, d, err)
+	}
 	return err == nil, err
 }
 
@@ -391,7 +455,7 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 	if args.Gas == nil {
 		return nil, fmt.Errorf("gas not specified")
 	}
-	if args.GasPrice == nil {
+	if args.GasPrice == nil && args.MaxFeePerGas == nil {
 		return nil, fmt.Errorf("gasPrice not specified")
 	}
 	if args.Nonce == nil {
@@ -717,14 +781,21 @@ type CallArgs struct {
 	GasPrice hexutil.Big     `json:"gasPrice"`
 	Value    hexutil.Big     `json:"value"`
 	Data     hexutil.Bytes   `json:"data"`
+
+	// EIP-1559 clients set the fee caps instead of the gas price, see
+	// feeCapsGasPrice.
+	MaxFeePerGas         *hexutil.Big `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big `json:"maxPriorityFeePerGas"`
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, timeout time.Duration, globalGasCap *big.Int) ([]byte, uint64, bool, error) {
+// doCall executes the call and returns its output, the gas used, the error
+// the EVM aborted with and the error that kept the call from executing.
+func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, timeout time.Duration, globalGasCap *big.Int) ([]byte, uint64, error, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
 	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
-		return nil, 0, false, err
+		return nil, 0, nil, err
 	}
 	// Set sender address or use a default if none specified
 	addr := args.From
@@ -745,6 +816,11 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 		gas = globalGasCap.Uint64()
 	}
 	gasPrice := args.GasPrice.ToInt()
+	if gasPrice.Sign() == 0 && (args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil) {
+		if gasPrice, err = feeCapsGasPrice(ctx, s.b, header.Round, args.MaxFeePerGas, args.MaxPriorityFeePerGas); err != nil {
+			return nil, 0, nil, err
+		}
+	}
 	if gasPrice.Sign() == 0 {
 		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
 	}
@@ -766,7 +842,7 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// Get a new instance of the EVM.
 	evm, vmError, err := s.b.GetEVM(ctx, msg, state, header)
 	if err != nil {
-		return nil, 0, false, err
+		return nil, 0, nil, err
 	}
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
@@ -778,18 +854,74 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// Setup the gas pool (also for unmetered requests)
 	// and apply the message.
 	gp := new(core.GasPool).AddGas(math.MaxUint64)
-	res, gas, failed, err := core.ApplyMessage(evm, msg, gp)
+	res, gas, failure, err := core.ApplyCall(evm, msg, gp)
 	if err := vmError(); err != nil {
-		return nil, 0, false, err
+		return nil, 0, nil, err
 	}
-	return res, gas, failed, err
+	return res, gas, failure, err
+}
+
+// revertError is the error of a reverted call. Following geth, it has the
+// code 3 and the revert data as its data, and its message includes the
+// reason if the data is an Error(string).
+type revertError struct {
+	error
+	reason string // revert data, hex encoded
+}
+
+func newRevertError(data []byte) *revertError {
+	err := vm.ErrExecutionReverted
+	if reason, errUnpack := abi.UnpackRevert(data); errUnpack == nil {
+		err = fmt.Errorf("%v: %v", vm.ErrExecutionReverted, reason)
+	}
+	return &revertError{
+		error:  err,
+		reason: hexutil.Encode(data),
+	}
+}
+
+// ErrorCode returns the JSON error code for a revertal.
+// See: https://github.com/ethereum/wiki/wiki/JSON-RPC-Error-Codes-Improvement-Proposal
+func (e *revertError) ErrorCode() int {
+	return 3
+}
+
+// ErrorData returns the hex encoded revert data.
+func (e *revertError) ErrorData() interface{} {
+	return e.reason
 }
 
 // Call executes the given transaction on the state for the given block number.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
 func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	result, _, _, err := s.doCall(ctx, args, blockNr, 5*time.Second, s.b.RPCGasCap())
-	return (hexutil.Bytes)(result), err
+	gasCap := s.b.RPCGasCap()
+	if isGovernanceView(args) {
+		gasCap = s.b.RPCGovernanceGasCap()
+	}
+	result, _, failure, err := s.doCall(ctx, args, blockNr, 5*time.Second, gasCap)
+	if err != nil {
+		return nil, err
+	}
+	if failure == vm.ErrExecutionReverted && len(result) > 0 {
+		return nil, newRevertError(result)
+	}
+	if failure != nil {
+		return nil, failure
+	}
+	return (hexutil.Bytes)(result), nil
+}
+
+// isGovernanceView checks if the call invokes a read-only method of the
+// governance contract. Those are cheap, so they get their own gas cap.
+func isGovernanceView(args CallArgs) bool {
+	if args.To == nil || *args.To != vm.GovernanceContractAddress {
+		return false
+	}
+	if args.Value.ToInt().Sign() != 0 || len(args.Data) < 4 {
+		return false
+	}
+	method, ok := vm.GovernanceABI.Sig2Method[string(args.Data[:4])]
+	return ok && method.Const
 }
 
 // EstimateGas returns an estimate of the amount of gas needed to execute the
@@ -818,20 +950,26 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	}
 	cap = hi
 
-	// Create a helper to check if a gas allowance results in an executable transaction
-	executable := func(gas uint64) bool {
+	// Create a helper to check if a gas allowance results in an executable
+	// transaction. Errors other than a too low gas allowance abort the search.
+	executable := func(gas uint64) ([]byte, error, error) {
 		args.Gas = hexutil.Uint64(gas)
 
-		_, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, 0, gasCap)
-		if err != nil || failed {
-			return false
+		result, _, failure, err := s.doCall(ctx, args, rpc.PendingBlockNumber, 0, gasCap)
+		if err == vm.ErrOutOfGas {
+			// The allowance doesn't even cover the intrinsic gas.
+			return nil, err, nil
 		}
-		return true
+		return result, failure, err
 	}
 	// Execute the binary search and hone in on an executable gas limit
 	for lo+1 < hi {
 		mid := (hi + lo) / 2
-		if !executable(mid) {
+		_, failure, err := executable(mid)
+		if err != nil {
+			return 0, err
+		}
+		if failure != nil {
 			lo = mid
 		} else {
 			hi = mid
@@ -839,8 +977,18 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	}
 	// Reject the transaction as invalid if it still fails at the highest allowance
 	if hi == cap {
-		if !executable(hi) {
-			return 0, fmt.Errorf("gas required exceeds allowance (%d) or always failing transaction", cap)
+		result, failure, err := executable(hi)
+		if err != nil {
+			return 0, err
+		}
+		if failure != nil {
+			if failure == vm.ErrExecutionReverted && len(result) > 0 {
+				return 0, newRevertError(result)
+			}
+			if failure == vm.ErrOutOfGas {
+				return 0, fmt.Errorf("gas required exceeds allowance (%d)", cap)
+			}
+			return 0, failure
 		}
 	}
 	return hexutil.Uint64(hi), nil
@@ -967,13 +1115,17 @@ func RPCMarshalBlock(b *types.Block, inclTx bool, fullTx bool) (map[string]inter
 }
 
 // rpcOutputBlock uses the generalized output filler, then adds the total difficulty field, which requires
-// a `PublicBlockchainAPI`.
+// a `PublicBlockchainAPI`. The minimum gas price of the block's round is added as its base fee for
+// EIP-1559 clients, if the governance state of the round is available.
 func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
 	fields, err := RPCMarshalBlock(b, inclTx, fullTx)
 	if err != nil {
 		return nil, err
 	}
 	fields["totalDifficulty"] = (*hexutil.Big)(s.b.GetTd(b.Hash()))
+	if baseFee, err := s.b.MinGasPrice(context.Background(), b.Round()); err == nil {
+		fields["baseFeePerGas"] = (*hexutil.Big)(baseFee)
+	}
 	return fields, err
 }
 
@@ -1154,6 +1306,17 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 	return nil
 }
 
+// TxIndexTail returns the number of the oldest block whose transactions can
+// be looked up by hash, zero if all are. Lookups of transactions in older
+// blocks return null like lookups of unknown ones, so a client knowing the
+// block of a transaction tells them apart with it.
+func (s *PublicTransactionPoolAPI) TxIndexTail() hexutil.Uint64 {
+	if tail := rawdb.ReadTxIndexTail(s.b.ChainDb()); tail != nil {
+		return hexutil.Uint64(*tail)
+	}
+	return 0
+}
+
 // GetRawTransactionByHash returns the bytes of the transaction for the given hash.
 func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 	var tx *types.Transaction
@@ -1249,6 +1412,11 @@ type SendTxArgs struct {
 	// newer name and should be preferred by clients.
 	Data  *hexutil.Bytes `json:"data"`
 	Input *hexutil.Bytes `json:"input"`
+
+	// EIP-1559 clients set the fee caps instead of the gas price, see
+	// feeCapsGasPrice.
+	MaxFeePerGas         *hexutil.Big `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big `json:"maxPriorityFeePerGas"`
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
@@ -1257,6 +1425,16 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 		args.Gas = new(hexutil.Uint64)
 		*(*uint64)(args.Gas) = 90000
 	}
+	if args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil {
+		if args.GasPrice != nil {
+			return errors.New("both gasPrice and (maxFeePerGas or maxPriorityFeePerGas) specified")
+		}
+		price, err := feeCapsGasPrice(ctx, b, b.CurrentBlock().Round(), args.MaxFeePerGas, args.MaxPriorityFeePerGas)
+		if err != nil {
+			return err
+		}
+		args.GasPrice = (*hexutil.Big)(price)
+	}
 	if args.GasPrice == nil {
 		price, err := b.SuggestPrice(ctx)
 		if err != nil {
@@ -1449,7 +1627,7 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if args.Gas == nil {
 		return nil, fmt.Errorf("gas not specified")
 	}
-	if args.GasPrice == nil {
+	if args.GasPrice == nil && args.MaxFeePerGas == nil {
 		return nil, fmt.Errorf("gasPrice not specified")
 	}
 	if args.Nonce == nil {
@@ -0,0 +1,350 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/portto/go-tangerine/common"
+	"github.com/portto/go-tangerine/common/hexutil"
+	"github.com/portto/go-tangerine/core"
+	"github.com/portto/go-tangerine/core/rawdb"
+	"github.com/portto/go-tangerine/core/state"
+	"github.com/portto/go-tangerine/core/types"
+	"github.com/portto/go-tangerine/core/vm"
+	"github.com/portto/go-tangerine/ethdb"
+	"github.com/portto/go-tangerine/params"
+	"github.com/portto/go-tangerine/rpc"
+)
+
+// feeBackend is a backend suggesting a gas price above the minimum gas price,
+// the only methods the fee translation uses.
+type feeBackend struct {
+	Backend
+	price    *big.Int
+	minPrice *big.Int
+}
+
+func (b *feeBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	return b.price, nil
+}
+
+func (b *feeBackend) MinGasPrice(ctx context.Context, round uint64) (*big.Int, error) {
+	return b.minPrice, nil
+}
+
+func (b *feeBackend) CurrentBlock() *types.Block {
+	return types.NewBlockWithHeader(&types.Header{Number: new(big.Int)})
+}
+
+func hexBig(n int64) *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(n))
+}
+
+// Tests that the fee caps of EIP-1559 clients translate into the base fee plus
+// the tip, capped by the max fee.
+func TestFeeCapsGasPrice(t *testing.T) {
+	b := &feeBackend{price: big.NewInt(150), minPrice: big.NewInt(100)}
+
+	tests := []struct {
+		maxFee, maxTip *hexutil.Big
+		price          int64
+		fail           bool
+	}{
+		{nil, nil, 150, false},
+		{nil, hexBig(10), 110, false},
+		{hexBig(200), nil, 150, false},
+		{hexBig(120), nil, 120, false},
+		{hexBig(200), hexBig(30), 130, false},
+		{hexBig(120), hexBig(30), 120, false},
+		{hexBig(100), hexBig(0), 100, false},
+		{hexBig(20), hexBig(30), 0, true},
+		{hexBig(90), nil, 0, true},
+	}
+	for i, tt := range tests {
+		price, err := feeCapsGasPrice(context.Background(), b, 0, tt.maxFee, tt.maxTip)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("test %d: no error, price %v", i, price)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: error: %v", i, err)
+			continue
+		}
+		if price.Int64() != tt.price {
+			t.Errorf("test %d: price mismatch: have %v, want %d", i, price, tt.price)
+		}
+	}
+
+	// The suggested tip never goes below zero.
+	b.price = big.NewInt(50)
+	if tip, err := suggestTip(context.Background(), b, 0); err != nil || tip.Sign() != 0 {
+		t.Errorf("tip mismatch: have %v, %v, want 0", tip, err)
+	}
+}
+
+// Tests that transactions sent with fee caps get the translated gas price, and
+// that fee caps don't go together with a gas price.
+func TestSendTxArgsFeeCaps(t *testing.T) {
+	b := &feeBackend{price: big.NewInt(150), minPrice: big.NewInt(100)}
+	to := common.Address{1}
+	nonce := hexutil.Uint64(0)
+
+	args := SendTxArgs{To: &to, Nonce: &nonce, MaxFeePerGas: hexBig(200), MaxPriorityFeePerGas: hexBig(5)}
+	if err := args.setDefaults(context.Background(), b); err != nil {
+		t.Fatalf("failed to set defaults: %v", err)
+	}
+	if price := args.toTransaction().GasPrice(); price.Int64() != 105 {
+		t.Errorf("gas price mismatch: have %v, want 105", price)
+	}
+
+	args = SendTxArgs{To: &to, Nonce: &nonce, GasPrice: hexBig(150), MaxFeePerGas: hexBig(200)}
+	if err := args.setDefaults(context.Background(), b); err == nil {
+		t.Errorf("no error with both gas price and fee caps")
+	}
+}
+
+// callBackend is a backend executing calls on a fixed state, the only methods
+// calls and gas estimations use.
+type callBackend struct {
+	Backend
+	state  *state.StateDB
+	header *types.Header
+}
+
+func newCallBackend(t *testing.T, accounts map[common.Address]core.GenesisAccount) *callBackend {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	for addr, account := range accounts {
+		statedb.SetBalance(addr, account.Balance)
+		statedb.SetCode(addr, account.Code)
+	}
+	return &callBackend{
+		state: statedb,
+		header: &types.Header{
+			Number:     big.NewInt(1),
+			Difficulty: new(big.Int),
+			GasLimit:   params.GenesisGasLimit,
+		},
+	}
+}
+
+func (b *callBackend) RPCGasCap() *big.Int { return big.NewInt(25000000) }
+
+func (b *callBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	return types.NewBlockWithHeader(b.header), nil
+}
+
+func (b *callBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	return b.state.Copy(), b.header, nil
+}
+
+func (b *callBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header) (*vm.EVM, func() error, error) {
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      msg.From(),
+		BlockNumber: new(big.Int).Set(header.Number),
+		Time:        new(big.Int),
+		Difficulty:  new(big.Int),
+		Round:       new(big.Int),
+		GasLimit:    header.GasLimit,
+		GasPrice:    new(big.Int).Set(msg.GasPrice()),
+	}
+	return vm.NewEVM(context, state, params.TestChainConfig, vm.Config{}), func() error { return nil }, nil
+}
+
+// revertingCode returns the code of a contract reverting every call with
+// data.
+func revertingCode(data []byte) []byte {
+	size := byte(len(data))
+	code := []byte{
+		byte(vm.PUSH1), size, byte(vm.PUSH1), 12, byte(vm.PUSH1), 0, byte(vm.CODECOPY),
+		byte(vm.PUSH1), size, byte(vm.PUSH1), 0, byte(vm.REVERT),
+	}
+	return append(code, data...)
+}
+
+// revertReason returns the revert data of an Error(string) with reason.
+func revertReason(reason string) []byte {
+	data := common.FromHex("0x08c379a0")
+	data = append(data, common.LeftPadBytes([]byte{0x20}, 32)...)
+	data = append(data, common.LeftPadBytes([]byte{byte(len(reason))}, 32)...)
+	return append(data, common.RightPadBytes([]byte(reason), 32)...)
+}
+
+// Tests that reverted calls and gas estimations fail with the revert error of
+// geth, and that senders unable to pay for the gas get the insufficient funds
+// error.
+func TestCallRevertErrors(t *testing.T) {
+	core.TestingMode = true
+
+	var (
+		sender   = common.Address{1}
+		pauper   = common.Address{2}
+		reverter = common.Address{3}
+		data     = revertReason("boom")
+	)
+	b := newCallBackend(t, map[common.Address]core.GenesisAccount{
+		sender:   {Balance: new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(1000000))},
+		pauper:   {Balance: new(big.Int)},
+		reverter: {Balance: new(big.Int), Code: revertingCode(data)},
+	})
+	api := NewPublicBlockChainAPI(b)
+
+	type dataError interface {
+		ErrorCode() int
+		ErrorData() interface{}
+	}
+	checkRevert := func(method string, err error) {
+		rerr, ok := err.(dataError)
+		if !ok {
+			t.Fatalf("%s: error mismatch: have %v, want revert error", method, err)
+		}
+		if want := "execution reverted: boom"; err.Error() != want {
+			t.Errorf("%s: message mismatch: have %q, want %q", method, err.Error(), want)
+		}
+		if rerr.ErrorCode() != 3 {
+			t.Errorf("%s: code mismatch: have %d, want 3", method, rerr.ErrorCode())
+		}
+		if have, want := rerr.ErrorData(), hexutil.Encode(data); have != want {
+			t.Errorf("%s: data mismatch: have %v, want %s", method, have, want)
+		}
+	}
+
+	args := CallArgs{From: sender, To: &reverter}
+	_, err := api.Call(context.Background(), args, rpc.LatestBlockNumber)
+	checkRevert("eth_call", err)
+	_, err = api.EstimateGas(context.Background(), args)
+	checkRevert("eth_estimateGas", err)
+
+	to := common.Address{4}
+	args = CallArgs{From: pauper, To: &to}
+	if _, err := api.EstimateGas(context.Background(), args); err != core.ErrInsufficientFunds {
+		t.Errorf("error mismatch: have %v, want %v", err, core.ErrInsufficientFunds)
+	}
+}
+
+// capBackend is a call backend recording the gas of calls without executing
+// them, with its own gas cap for governance views.
+type capBackend struct {
+	*callBackend
+	govCap *big.Int
+	gas    uint64
+}
+
+func (b *capBackend) RPCGovernanceGasCap() *big.Int { return b.govCap }
+
+func (b *capBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header) (*vm.EVM, func() error, error) {
+	b.gas = msg.Gas()
+	return nil, nil, errCallRecorded
+}
+
+var errCallRecorded = errors.New("call recorded")
+
+// Tests that calls of read-only governance methods are capped by the
+// governance gas cap, and other calls by the regular one.
+func TestCallGovernanceGasCap(t *testing.T) {
+	sender := common.Address{1}
+	b := &capBackend{
+		callBackend: newCallBackend(t, map[common.Address]core.GenesisAccount{
+			sender: {Balance: new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(1000000))},
+		}),
+		govCap: big.NewInt(30000000),
+	}
+	api := NewPublicBlockChainAPI(b)
+
+	view := hexutil.Bytes(vm.GovernanceABI.Name2Method["crsRound"].Id())
+	calls := []struct {
+		args CallArgs
+		cap  uint64
+	}{
+		{CallArgs{From: sender, To: &vm.GovernanceContractAddress, Data: view}, 30000000},
+		// Governance methods changing the state, and other contracts.
+		{CallArgs{From: sender, To: &vm.GovernanceContractAddress,
+			Data: hexutil.Bytes(vm.GovernanceABI.Name2Method["stake"].Id())}, 25000000},
+		{CallArgs{From: sender, To: &common.Address{2}, Data: view}, 25000000},
+	}
+	for i, call := range calls {
+		if _, err := api.Call(context.Background(), call.args, rpc.LatestBlockNumber); err != errCallRecorded {
+			t.Fatalf("call %d: error mismatch: have %v, want %v", i, err, errCallRecorded)
+		}
+		if b.gas != call.cap {
+			t.Errorf("call %d: gas mismatch: have %d, want %d", i, b.gas, call.cap)
+		}
+	}
+}
+
+// lookupBackend is a backend with an empty transaction pool, the only methods
+// the lookups by hash use.
+type lookupBackend struct {
+	Backend
+	db ethdb.Database
+}
+
+func (b *lookupBackend) ChainDb() ethdb.Database { return b.db }
+
+func (b *lookupBackend) GetPoolTransaction(hash common.Hash) *types.Transaction { return nil }
+
+// Tests that the index tail tells the transactions of pruned blocks apart from
+// unknown ones, both looked up as null.
+func TestTxIndexTail(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	var blocks []*types.Block
+	for number := uint64(0); number < 3; number++ {
+		tx := types.NewTransaction(number, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil)
+		block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(number)},
+			[]*types.Transaction{tx}, nil, nil)
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), number)
+		rawdb.WriteTxLookupEntries(db, block)
+		blocks = append(blocks, block)
+	}
+	api := NewPublicTransactionPoolAPI(&lookupBackend{db: db}, nil)
+	if tail := api.TxIndexTail(); tail != 0 {
+		t.Errorf("tail mismatch: have %d, want 0", tail)
+	}
+
+	if err := core.UnindexTransactions(db, 0, 2, nil); err != nil {
+		t.Fatalf("failed to unindex transactions: %v", err)
+	}
+	if tail := api.TxIndexTail(); tail != 2 {
+		t.Errorf("tail mismatch: have %d, want 2", tail)
+	}
+	pruned := blocks[1].Transactions()[0].Hash()
+	if tx := api.GetTransactionByHash(context.Background(), pruned); tx != nil {
+		t.Errorf("pruned transaction found: %v", tx)
+	}
+	if tx := api.GetTransactionByHash(context.Background(), common.Hash{1}); tx != nil {
+		t.Errorf("unknown transaction found: %v", tx)
+	}
+	// The block of the pruned transaction is older than the tail.
+	if number := blocks[1].NumberU64(); number >= uint64(api.TxIndexTail()) {
+		t.Errorf("pruned transaction block %d not older than the tail", number)
+	}
+	indexed := blocks[2].Transactions()[0].Hash()
+	if tx := api.GetTransactionByHash(context.Background(), indexed); tx == nil {
+		t.Errorf("indexed transaction not found")
+	}
+}
@@ -45,10 +45,12 @@ type Backend interface {
 	Downloader() Downloader
 	ProtocolVersion() int
 	SuggestPrice(ctx context.Context) (*big.Int, error)
+	MinGasPrice(ctx context.Context, round uint64) (*big.Int, error) // governance minimum gas price of a round
 	ChainDb() ethdb.Database
 	EventMux() *event.TypeMux
 	AccountManager() *accounts.Manager
-	RPCGasCap() *big.Int // global gas cap for eth_call over rpc: DoS protection
+	RPCGasCap() *big.Int           // global gas cap for eth_call over rpc: DoS protection
+	RPCGovernanceGasCap() *big.Int // gas cap for eth_call on read-only governance methods
 
 	// BlockChain API
 	SetHead(number uint64)
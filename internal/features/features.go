@@ -0,0 +1,168 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package features is a registry of feature flags guarding experimental
+// subsystems.
+//
+// Flags are registered by the subsystems they guard with a default state. The
+// default can be overridden at build time by linking a flag specification
+// into buildFeatures, e.g.
+//
+//	go build -ldflags "-X github.com/portto/go-tangerine/internal/features.buildFeatures=foo,-bar"
+//
+// and at runtime with Apply, which is what the --features command line flag
+// does. The state of every flag is reported through metrics and RPC so the
+// adoption of experimental subsystems can be observed across the network.
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/portto/go-tangerine/metrics"
+)
+
+// buildFeatures is the flag specification set at build time.
+var buildFeatures = ""
+
+// Sources of the state of a flag.
+const (
+	SourceDefault = "default"
+	SourceBuild   = "build"
+	SourceRuntime = "runtime"
+)
+
+// Flag is a feature flag.
+type Flag struct {
+	name        string
+	description string
+	defaultOn   bool
+	enabled     int32
+	source      string
+	gauge       metrics.Gauge
+}
+
+// Name returns the name of the flag.
+func (f *Flag) Name() string {
+	return f.name
+}
+
+// Enabled returns whether the guarded feature is enabled.
+func (f *Flag) Enabled() bool {
+	return atomic.LoadInt32(&f.enabled) == 1
+}
+
+func (f *Flag) set(enabled bool, source string) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&f.enabled, v)
+	f.source = source
+	f.gauge.Update(int64(v))
+}
+
+// Status is the state of a flag.
+type Status struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	Default     bool   `json:"default"`
+	Source      string `json:"source"`
+}
+
+var (
+	lock  sync.RWMutex
+	flags = make(map[string]*Flag)
+)
+
+// Register adds a flag to the registry. The flag is enabled if defaultOn is
+// set, unless overridden by the build time specification. Registering the
+// same name twice panics.
+func Register(name, description string, defaultOn bool) *Flag {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, exist := flags[name]; exist {
+		panic(fmt.Sprintf("feature flag %q registered twice", name))
+	}
+	f := &Flag{
+		name:        name,
+		description: description,
+		defaultOn:   defaultOn,
+		gauge:       metrics.NewRegisteredGauge("features/"+name, nil),
+	}
+	f.set(defaultOn, SourceDefault)
+	if enabled, ok := parse(buildFeatures)[name]; ok {
+		f.set(enabled, SourceBuild)
+	}
+	flags[name] = f
+	return f
+}
+
+// Apply sets the flags named in spec, a comma separated list of flag names
+// each prefixed with "-" to disable the flag.
+func Apply(spec string) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	states := parse(spec)
+	for name := range states {
+		if _, exist := flags[name]; !exist {
+			return fmt.Errorf("unknown feature flag %q", name)
+		}
+	}
+	for name, enabled := range states {
+		flags[name].set(enabled, SourceRuntime)
+	}
+	return nil
+}
+
+// List returns the state of all registered flags sorted by name.
+func List() []Status {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	list := make([]Status, 0, len(flags))
+	for _, f := range flags {
+		list = append(list, Status{
+			Name:        f.name,
+			Description: f.description,
+			Enabled:     f.Enabled(),
+			Default:     f.defaultOn,
+			Source:      f.source,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// parse decodes a flag specification into the requested flag states.
+func parse(spec string) map[string]bool {
+	states := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		enabled := !strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		if name != "" {
+			states[name] = enabled
+		}
+	}
+	return states
+}
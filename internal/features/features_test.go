@@ -0,0 +1,63 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package features
+
+import "testing"
+
+func TestFeatureFlags(t *testing.T) {
+	buildFeatures = "built,-unbuilt"
+	defer func() { buildFeatures = "" }()
+
+	var (
+		off     = Register("off", "disabled by default", false)
+		on      = Register("on", "enabled by default", true)
+		built   = Register("built", "enabled at build time", false)
+		unbuilt = Register("unbuilt", "disabled at build time", true)
+	)
+	if off.Enabled() || !on.Enabled() || !built.Enabled() || unbuilt.Enabled() {
+		t.Fatalf("wrong initial states: off %v, on %v, built %v, unbuilt %v",
+			off.Enabled(), on.Enabled(), built.Enabled(), unbuilt.Enabled())
+	}
+	if err := Apply("off, -on"); err != nil {
+		t.Fatalf("failed to apply flags: %v", err)
+	}
+	if !off.Enabled() || on.Enabled() {
+		t.Errorf("runtime flags not applied: off %v, on %v", off.Enabled(), on.Enabled())
+	}
+	if err := Apply("missing"); err == nil {
+		t.Errorf("unknown flag accepted")
+	}
+
+	want := map[string]string{
+		"built":   SourceBuild,
+		"off":     SourceRuntime,
+		"on":      SourceRuntime,
+		"unbuilt": SourceBuild,
+	}
+	list := List()
+	if len(list) != len(want) {
+		t.Fatalf("wrong number of flags: have %d, want %d", len(list), len(want))
+	}
+	for i, status := range list {
+		if i > 0 && list[i-1].Name >= status.Name {
+			t.Errorf("flags not sorted: %v", list)
+		}
+		if status.Source != want[status.Name] {
+			t.Errorf("flag %s: have source %s, want %s", status.Name, status.Source, want[status.Name])
+		}
+	}
+}
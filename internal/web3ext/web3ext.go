@@ -31,6 +31,8 @@ var Modules = map[string]string{
 	"rpc":        RPC_JS,
 	"shh":        Shh_JS,
 	"swarmfs":    SWARMFS_JS,
+	"gov":        Gov_JS,
+	"tan":        Tan_JS,
 	"txpool":     TxPool_JS,
 }
 
@@ -207,6 +209,24 @@ web3._extend({
 			name: 'stopProposing',
 			call: 'admin_stopProposing'
 		}),
+		new web3._extend.Method({
+			name: 'rekeyNotaryConns',
+			call: 'admin_rekeyNotaryConns',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'rotateNodeKey',
+			call: 'admin_rotateNodeKey'
+		}),
+		new web3._extend.Method({
+			name: 'cancelNodeKeyRotation',
+			call: 'admin_cancelNodeKeyRotation'
+		}),
+		new web3._extend.Method({
+			name: 'auditLog',
+			call: 'admin_auditLog',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -233,6 +253,14 @@ web3._extend({
 			name: 'notaryInfo',
 			getter: 'admin_notaryInfo'
 		}),
+		new web3._extend.Property({
+			name: 'notaryConns',
+			getter: 'admin_notaryConns'
+		}),
+		new web3._extend.Property({
+			name: 'pendingNodeKey',
+			getter: 'admin_pendingNodeKey'
+		}),
 	]
 });
 `
@@ -327,6 +355,11 @@ web3._extend({
 			call: 'debug_cpuProfile',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'consensusProfile',
+			call: 'debug_consensusProfile',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'startCPUProfile',
 			call: 'debug_startCPUProfile',
@@ -463,6 +496,32 @@ web3._extend({
 			params: 2,
 			inputFormatter:[null, null],
 		}),
+		new web3._extend.Method({
+			name: 'stateDiff',
+			call: 'debug_stateDiff',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null],
+		}),
+		new web3._extend.Method({
+			name: 'peerHistory',
+			call: 'debug_peerHistory',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'coreCacheStats',
+			call: 'debug_coreCacheStats',
+			params: 0,
+		}),
+		new web3._extend.Method({
+			name: 'agreementState',
+			call: 'debug_agreementState',
+			params: 0,
+		}),
+		new web3._extend.Method({
+			name: 'txPropagation',
+			call: 'debug_txPropagation',
+			params: 1,
+		}),
 	],
 	properties: []
 });
@@ -534,6 +593,16 @@ web3._extend({
 				return formatted;
 			}
 		}),
+		new web3._extend.Property({
+			name: 'txIndexTail',
+			getter: 'eth_txIndexTail',
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Property({
+			name: 'maxPriorityFeePerGas',
+			getter: 'eth_maxPriorityFeePerGas',
+			outputFormatter: web3._extend.utils.toBigNumber
+		}),
 	]
 });
 `
@@ -699,6 +768,96 @@ web3._extend({
 });
 `
 
+const Tan_JS = `
+web3._extend({
+	property: 'tan',
+	methods: [
+		new web3._extend.Method({
+			name: 'dkgResetReport',
+			call: 'tan_dkgResetReport',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getHeadersRange',
+			call: 'tan_getHeadersRange',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getRandomness',
+			call: 'tan_getRandomness',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'features',
+			call: 'tan_features',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'blocksProposedBy',
+			call: 'tan_blocksProposedBy',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'sendRawTransactions',
+			call: 'tan_sendRawTransactions',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'estimateRewards',
+			call: 'tan_estimateRewards',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getExtendedReceipt',
+			call: 'tan_getExtendedReceipt',
+			params: 1
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'proposerStatus',
+			getter: 'tan_proposerStatus'
+		}),
+	]
+});
+`
+
+const Gov_JS = `
+web3._extend({
+	property: 'gov',
+	methods: [
+		new web3._extend.Method({
+			name: 'stakeHistory',
+			call: 'gov_stakeHistory',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'summary',
+			call: 'gov_summary',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'node',
+			call: 'gov_node',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'nodes',
+			call: 'gov_nodes',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+	]
+});
+`
+
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
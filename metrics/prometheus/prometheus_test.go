@@ -0,0 +1,62 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/portto/go-tangerine/metrics"
+)
+
+func TestHandler(t *testing.T) {
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = enabled }()
+
+	reg := metrics.NewRegistry()
+	metrics.NewRegisteredCounter("txpool/invalid", reg).Inc(3)
+	metrics.NewRegisteredGauge("chain/head/block", reg).Update(42)
+	metrics.NewRegisteredGaugeFloat64("dex/ratio", reg).Update(0.5)
+	metrics.NewRegisteredHistogram("dex/notary/conns/age", reg, metrics.NewUniformSample(10)).Update(7)
+	timer := metrics.NewRegisteredTimer("chain/inserts", reg)
+	timer.Update(time.Second)
+
+	rec := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE txpool_invalid counter\ntxpool_invalid 3\n",
+		"# TYPE chain_head_block gauge\nchain_head_block 42\n",
+		"# TYPE dex_ratio gauge\ndex_ratio 0.5\n",
+		"# TYPE dex_notary_conns_age_count counter\ndex_notary_conns_age_count 1\n",
+		"dex_notary_conns_age {quantile=\"0.5\"} 7\n",
+		"# TYPE chain_inserts summary\n",
+		"chain_inserts {quantile=\"0.99\"} 1e+09\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in output:\n%s", want, body)
+		}
+	}
+	if strings.Index(body, "chain_head_block") > strings.Index(body, "chain_inserts") ||
+		strings.Index(body, "chain_inserts") > strings.Index(body, "txpool_invalid") {
+		t.Errorf("metrics not sorted by name:\n%s", body)
+	}
+}
@@ -151,6 +151,20 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// BatchRequestLimit is the maximum number of requests in a batch accepted
+	// by the HTTP and websocket RPC listeners, zero meaning unlimited.
+	BatchRequestLimit int `toml:",omitempty"`
+
+	// BatchResponseMaxSize is the maximum cumulative size in bytes of the
+	// responses to a batch served by the HTTP and websocket RPC listeners,
+	// zero meaning unlimited.
+	BatchResponseMaxSize int `toml:",omitempty"`
+
+	// RPCEndpoints is a list of additional HTTP and websocket RPC listeners,
+	// each exposing its own set of API modules. This allows a node to serve
+	// public and operational traffic on separate interfaces.
+	RPCEndpoints []RPCEndpointConfig `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -159,6 +173,55 @@ type Config struct {
 	oldGethResourceWarning bool
 }
 
+// RPCEndpointConfig describes an additional HTTP or websocket RPC listener.
+type RPCEndpointConfig struct {
+	// Name identifies the listener in logs.
+	Name string `toml:",omitempty"`
+
+	// Protocol is the transport of the listener, either "http" or "ws".
+	Protocol string
+
+	// Host and Port are the interface and TCP port to listen at.
+	Host string
+	Port int
+
+	// Modules is the list of API modules to expose via the listener. If empty,
+	// all public modules are exposed.
+	Modules []string `toml:",omitempty"`
+
+	// Origins is the CORS domain list of an HTTP listener, or the list of
+	// origins accepted by a websocket listener.
+	Origins []string `toml:",omitempty"`
+
+	// VirtualHosts is the list of virtual hostnames accepted by an HTTP
+	// listener.
+	VirtualHosts []string `toml:",omitempty"`
+
+	// RateLimit is the number of requests per second accepted by the listener,
+	// with bursts up to RateBurst requests. Zero disables rate limiting.
+	RateLimit float64 `toml:",omitempty"`
+	RateBurst int     `toml:",omitempty"`
+
+	// BatchRequestLimit and BatchResponseMaxSize bound the batches served by
+	// the listener, zero using the limits of the main listeners.
+	BatchRequestLimit    int `toml:",omitempty"`
+	BatchResponseMaxSize int `toml:",omitempty"`
+}
+
+// Endpoint resolves the listening address of the RPC endpoint.
+func (c *RPCEndpointConfig) Endpoint() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// batchLimits returns the batch limits of the main HTTP and websocket
+// listeners.
+func (c *Config) batchLimits() rpc.BatchLimits {
+	return rpc.BatchLimits{
+		MaxRequests:     c.BatchRequestLimit,
+		MaxResponseSize: c.BatchResponseMaxSize,
+	}
+}
+
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
 // account the set data folders as well as the designated platform we're currently
 // running on.
@@ -67,6 +67,8 @@ type Node struct {
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
 
+	rpcEndpoints []*rpcEndpoint // Additional HTTP and websocket RPC listeners
+
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
 
@@ -275,6 +277,13 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 		n.stopInProc()
 		return err
 	}
+	if err := n.startRPCEndpoints(apis, n.config.RPCEndpoints); err != nil {
+		n.stopWS()
+		n.stopHTTP()
+		n.stopIPC()
+		n.stopInProc()
+		return err
+	}
 	// All API endpoints started successfully
 	n.rpcAPIs = apis
 	return nil
@@ -337,7 +346,7 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts)
+	listener, handler, err := rpc.StartLimitedHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts, rpc.RateLimit{}, n.config.batchLimits())
 	if err != nil {
 		return err
 	}
@@ -370,7 +379,7 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll)
+	listener, handler, err := rpc.StartLimitedWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll, rpc.RateLimit{}, n.config.batchLimits())
 	if err != nil {
 		return err
 	}
@@ -397,6 +406,65 @@ func (n *Node) stopWS() {
 	}
 }
 
+// rpcEndpoint is an additional HTTP or websocket RPC listener.
+type rpcEndpoint struct {
+	url      string
+	listener net.Listener
+	handler  *rpc.Server
+}
+
+// startRPCEndpoints initializes and starts the additional RPC endpoints. If
+// any of them fails, the ones already started are terminated.
+func (n *Node) startRPCEndpoints(apis []rpc.API, configs []RPCEndpointConfig) error {
+	for _, config := range configs {
+		var (
+			endpoint = config.Endpoint()
+			limit    = rpc.RateLimit{Rate: config.RateLimit, Burst: config.RateBurst}
+			batch    = n.config.batchLimits()
+			listener net.Listener
+			handler  *rpc.Server
+			err      error
+		)
+		if config.BatchRequestLimit > 0 {
+			batch.MaxRequests = config.BatchRequestLimit
+		}
+		if config.BatchResponseMaxSize > 0 {
+			batch.MaxResponseSize = config.BatchResponseMaxSize
+		}
+		switch config.Protocol {
+		case "http":
+			listener, handler, err = rpc.StartLimitedHTTPEndpoint(endpoint, apis, config.Modules, config.Origins, config.VirtualHosts, n.config.HTTPTimeouts, limit, batch)
+		case "ws":
+			listener, handler, err = rpc.StartLimitedWSEndpoint(endpoint, apis, config.Modules, config.Origins, false, limit, batch)
+		default:
+			err = fmt.Errorf("unknown RPC endpoint protocol %q", config.Protocol)
+		}
+		if err != nil {
+			n.stopRPCEndpoints()
+			return err
+		}
+		url := fmt.Sprintf("%s://%s", config.Protocol, listener.Addr())
+		n.log.Info("RPC endpoint opened", "name", config.Name, "url", url,
+			"modules", strings.Join(config.Modules, ","), "ratelimit", config.RateLimit)
+		n.rpcEndpoints = append(n.rpcEndpoints, &rpcEndpoint{
+			url:      url,
+			listener: listener,
+			handler:  handler,
+		})
+	}
+	return nil
+}
+
+// stopRPCEndpoints terminates the additional RPC endpoints.
+func (n *Node) stopRPCEndpoints() {
+	for _, endpoint := range n.rpcEndpoints {
+		endpoint.listener.Close()
+		endpoint.handler.Stop()
+		n.log.Info("RPC endpoint closed", "url", endpoint.url)
+	}
+	n.rpcEndpoints = nil
+}
+
 // Stop terminates a running node along with all it's services. In the node was
 // not started, an error is returned.
 func (n *Node) Stop() error {
@@ -409,6 +477,7 @@ func (n *Node) Stop() error {
 	}
 
 	// Terminate the API, services and the p2p server.
+	n.stopRPCEndpoints()
 	n.stopWS()
 	n.stopHTTP()
 	n.stopIPC()
@@ -173,6 +173,10 @@ func (s *dialstate) removeDirect(n *enode.Node) {
 	s.hist.remove(n.ID())
 }
 
+func (s *dialstate) setMaxDynDials(n int) {
+	s.maxDynDials = n
+}
+
 func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Time) []task {
 	if s.start.IsZero() {
 		s.start = now
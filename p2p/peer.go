@@ -185,6 +185,17 @@ func (p *Peer) Inbound() bool {
 	return p.rw.is(inboundConn)
 }
 
+// Trusted returns true if the peer is a trusted node
+func (p *Peer) Trusted() bool {
+	return p.rw.is(trustedConn)
+}
+
+// Age returns how long the peer has been connected, that is since its
+// session keys were negotiated.
+func (p *Peer) Age() time.Duration {
+	return time.Duration(mclock.Now() - p.created)
+}
+
 func newPeer(conn *conn, protocols []Protocol) *Peer {
 	protomap := matchProtocols(protocols, conn.caps, conn)
 	p := &Peer{
@@ -152,7 +152,8 @@ type Config struct {
 
 // Server manages all peer connections.
 type Server struct {
-	// Config fields may not be modified while the server is running.
+	// Config fields may not be modified while the server is running,
+	// except MaxPeers and DialRatio through SetMaxPeers.
 	Config
 
 	// Hooks for testing. These are useful because we can inhibit
@@ -175,6 +176,8 @@ type Server struct {
 	peerOp     chan peerOpFunc
 	peerOpDone chan struct{}
 
+	setlimits chan peerLimits
+
 	quit          chan struct{}
 	addstatic     chan *enode.Node
 	removestatic  chan *enode.Node
@@ -315,6 +318,30 @@ func (srv *Server) PeerCount() int {
 	return count
 }
 
+// peerLimits are the maximum number of peers and the dial ratio of a server.
+type peerLimits struct {
+	maxPeers  int
+	dialRatio int
+}
+
+// SetMaxPeers changes MaxPeers and DialRatio, also while the server is
+// running. Connected peers above the new limits are kept, but no more are
+// accepted or dialed until the peer count drops below them.
+func (srv *Server) SetMaxPeers(maxPeers, dialRatio int) {
+	srv.lock.Lock()
+	if !srv.running {
+		srv.MaxPeers, srv.DialRatio = maxPeers, dialRatio
+		srv.lock.Unlock()
+		return
+	}
+	srv.lock.Unlock()
+
+	select {
+	case srv.setlimits <- peerLimits{maxPeers, dialRatio}:
+	case <-srv.quit:
+	}
+}
+
 // AddPeer connects to the given node and maintains the connection until the
 // server is shut down. If the connection fails for any reason, the server will
 // attempt to reconnect the peer.
@@ -502,6 +529,7 @@ func (srv *Server) Start() (err error) {
 	srv.removetrusted = make(chan *enode.Node)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
+	srv.setlimits = make(chan peerLimits)
 
 	if err := srv.setupLocalNode(); err != nil {
 		return err
@@ -661,6 +689,7 @@ type dialer interface {
 	removeStatic(*enode.Node)
 	addDirect(*enode.Node)
 	removeDirect(*enode.Node)
+	setMaxDynDials(int)
 }
 
 func (srv *Server) run(dialstate dialer) {
@@ -774,6 +803,12 @@ running:
 			// This channel is used by Peers and PeerCount.
 			op(peers)
 			srv.peerOpDone <- struct{}{}
+		case l := <-srv.setlimits:
+			// This channel is used by SetMaxPeers. The limits are only
+			// read by this loop while the server is running.
+			srv.log.Debug("Changing peer limits", "maxpeers", l.maxPeers, "dialratio", l.dialRatio)
+			srv.MaxPeers, srv.DialRatio = l.maxPeers, l.dialRatio
+			dialstate.setMaxDynDials(srv.maxDialedConns())
 		case t := <-taskdone:
 			// A task got done. Tell dialstate about it so it
 			// can update its state and remove it from the active
@@ -343,6 +343,8 @@ func (tg taskgen) addDirect(*enode.Node) {
 }
 func (tg taskgen) removeDirect(*enode.Node) {
 }
+func (tg taskgen) setMaxDynDials(int) {
+}
 
 type testTask struct {
 	index  int
@@ -481,6 +483,24 @@ func TestServerPeerLimits(t *testing.T) {
 		t.Errorf("unexpected close error: %q", tp.closeErr)
 	}
 	conn.Close()
+
+	// Check that raising the limits while running makes room.
+	srv.SetMaxPeers(10, 2)
+	conn, _ = net.Pipe()
+	srv.SetupConn(conn, flags, dialDest)
+	if tp.closeErr != DiscUselessPeer {
+		t.Errorf("unexpected close error: %q", tp.closeErr)
+	}
+	conn.Close()
+
+	// Check that lowering them again makes the server full.
+	srv.SetMaxPeers(0, 0)
+	conn, _ = net.Pipe()
+	srv.SetupConn(conn, flags, dialDest)
+	if tp.closeErr != DiscTooManyPeers {
+		t.Errorf("unexpected close error: %q", tp.closeErr)
+	}
+	conn.Close()
 }
 
 func TestServerSetupConn(t *testing.T) {
@@ -149,18 +149,18 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), 0, big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil, nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), 0, big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, new(EthashConfig), nil, nil, nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), 0, big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil, nil}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), 0, big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil, nil}
 
-	AllDexconProtocolChanges = &ChainConfig{big.NewInt(1337), 0, big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, new(DexconConfig), new(RecoveryConfig)}
+	AllDexconProtocolChanges = &ChainConfig{big.NewInt(1337), 0, big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil, nil, new(DexconConfig), new(RecoveryConfig)}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), 0, big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil, nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), 0, big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, new(EthashConfig), nil, nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 
 	// Ethereum MainnetChainConfig is the chain parameters to run a node on the main network.
@@ -235,6 +235,13 @@ type ChainConfig struct {
 	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` // Constantinople switch block (nil = no fork, 0 = already activated)
 	PetersburgBlock     *big.Int `json:"petersburgBlock,omitempty"`     // Petersburg switch block (nil = same as Constantinople)
 	EWASMBlock          *big.Int `json:"ewasmBlock,omitempty"`          // EWASM switch block (nil = no fork, 0 = already activated)
+	EWASMRound          *big.Int `json:"ewasmRound,omitempty"`          // EWASM switch round (nil = no fork, 0 = already activated)
+
+	CompactDexconMetaBlock *big.Int `json:"compactDexconMetaBlock,omitempty"` // Compact DexconMeta encoding switch block (nil = no fork, 0 = already activated)
+	RewardAddressBlock     *big.Int `json:"rewardAddressBlock,omitempty"`     // Node reward address switch block (nil = no fork, 0 = already activated)
+	BlockIntervalBlock     *big.Int `json:"blockIntervalBlock,omitempty"`     // Minimum block interval enforcement switch block (nil = no fork, 0 = already activated)
+	IdleBlockIntervalBlock *big.Int `json:"idleBlockIntervalBlock,omitempty"` // Governance idle block interval switch block (nil = no fork, 0 = already activated)
+	GovernanceLayoutBlock  *big.Int `json:"governanceLayoutBlock,omitempty"`  // Governance storage layout marker switch block (nil = no fork, 0 = already activated)
 
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
@@ -286,6 +293,7 @@ type DexconConfig struct {
 	FineValues        []*big.Int       `json:"fineValues"`
 	IsConsortium      bool             `json:"isConsortium"`
 	AddressWhitelist  []common.Address `json:"addressWhitelist"`
+	IdleBlockInterval uint64           `json:"idleBlockInterval,omitempty"`
 }
 
 type dexconConfigSpecMarshaling struct {
@@ -298,7 +306,7 @@ type dexconConfigSpecMarshaling struct {
 
 // String implements the stringer interface, returning the consensus engine details.
 func (d *DexconConfig) String() string {
-	return fmt.Sprintf("{GenesisCRSText: %v Owner: %v MinStake: %v LockupPeriod: %v MiningVelocity: %v NextHalvingSupply: %v LastHalvedAmount: %v MinGasPrice: %v BlockGasLimit: %v LambdaBA: %v LambdaDKG: %v NotaryParamAlpha: %v NotaryParamBeta: %v RoundLength: %v MinBlockInterval: %v FineValues: %v IsConsortium: %v AddressWhitelist: %v}",
+	return fmt.Sprintf("{GenesisCRSText: %v Owner: %v MinStake: %v LockupPeriod: %v MiningVelocity: %v NextHalvingSupply: %v LastHalvedAmount: %v MinGasPrice: %v BlockGasLimit: %v LambdaBA: %v LambdaDKG: %v NotaryParamAlpha: %v NotaryParamBeta: %v RoundLength: %v MinBlockInterval: %v FineValues: %v IsConsortium: %v AddressWhitelist: %v IdleBlockInterval: %v}",
 		d.GenesisCRSText,
 		d.Owner,
 		d.MinStake,
@@ -317,6 +325,7 @@ func (d *DexconConfig) String() string {
 		d.FineValues,
 		d.IsConsortium,
 		d.AddressWhitelist,
+		d.IdleBlockInterval,
 	)
 }
 
@@ -399,6 +408,42 @@ func (c *ChainConfig) IsEWASM(num *big.Int) bool {
 	return isForked(c.EWASMBlock, num)
 }
 
+// IsEWASMRound returns whether round is either equal to the EWASM fork round
+// or greater.
+func (c *ChainConfig) IsEWASMRound(round *big.Int) bool {
+	return isForked(c.EWASMRound, round)
+}
+
+// IsCompactDexconMeta returns whether num is either equal to the compact
+// DexconMeta fork block or greater.
+func (c *ChainConfig) IsCompactDexconMeta(num *big.Int) bool {
+	return isForked(c.CompactDexconMetaBlock, num)
+}
+
+// IsRewardAddress returns whether num is either equal to the reward address
+// fork block or greater.
+func (c *ChainConfig) IsRewardAddress(num *big.Int) bool {
+	return isForked(c.RewardAddressBlock, num)
+}
+
+// IsBlockInterval returns whether num is either equal to the minimum block
+// interval enforcement fork block or greater.
+func (c *ChainConfig) IsBlockInterval(num *big.Int) bool {
+	return isForked(c.BlockIntervalBlock, num)
+}
+
+// IsIdleBlockInterval returns whether num is either equal to the idle block
+// interval fork block or greater.
+func (c *ChainConfig) IsIdleBlockInterval(num *big.Int) bool {
+	return isForked(c.IdleBlockIntervalBlock, num)
+}
+
+// IsGovernanceLayout returns whether num is either equal to the governance
+// storage layout marker fork block or greater.
+func (c *ChainConfig) IsGovernanceLayout(num *big.Int) bool {
+	return isForked(c.GovernanceLayoutBlock, num)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -436,6 +481,18 @@ func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *Confi
 	return lasterr
 }
 
+// CheckRoundCompatible checks whether fork transitions scheduled by round have
+// been imported with a mismatching chain configuration. round is the round of
+// the head block and roundHeight returns the height of the first block of a
+// round that is not after it.
+func (c *ChainConfig) CheckRoundCompatible(newcfg *ChainConfig, round uint64, roundHeight func(uint64) uint64) *ConfigCompatError {
+	bround := new(big.Int).SetUint64(round)
+	if isForkIncompatible(c.EWASMRound, newcfg.EWASMRound, bround) {
+		return newRoundCompatError("ewasm fork round", c.EWASMRound, newcfg.EWASMRound, roundHeight)
+	}
+	return nil
+}
+
 func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
 	if isForkIncompatible(c.HomesteadBlock, newcfg.HomesteadBlock, head) {
 		return newCompatError("Homestead fork block", c.HomesteadBlock, newcfg.HomesteadBlock)
@@ -470,6 +527,21 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.EWASMBlock, newcfg.EWASMBlock, head) {
 		return newCompatError("ewasm fork block", c.EWASMBlock, newcfg.EWASMBlock)
 	}
+	if isForkIncompatible(c.CompactDexconMetaBlock, newcfg.CompactDexconMetaBlock, head) {
+		return newCompatError("compact dexcon meta fork block", c.CompactDexconMetaBlock, newcfg.CompactDexconMetaBlock)
+	}
+	if isForkIncompatible(c.RewardAddressBlock, newcfg.RewardAddressBlock, head) {
+		return newCompatError("reward address fork block", c.RewardAddressBlock, newcfg.RewardAddressBlock)
+	}
+	if isForkIncompatible(c.BlockIntervalBlock, newcfg.BlockIntervalBlock, head) {
+		return newCompatError("block interval fork block", c.BlockIntervalBlock, newcfg.BlockIntervalBlock)
+	}
+	if isForkIncompatible(c.IdleBlockIntervalBlock, newcfg.IdleBlockIntervalBlock, head) {
+		return newCompatError("idle block interval fork block", c.IdleBlockIntervalBlock, newcfg.IdleBlockIntervalBlock)
+	}
+	if isForkIncompatible(c.GovernanceLayoutBlock, newcfg.GovernanceLayoutBlock, head) {
+		return newCompatError("governance layout fork block", c.GovernanceLayoutBlock, newcfg.GovernanceLayoutBlock)
+	}
 	return nil
 }
 
@@ -502,7 +574,7 @@ func configNumEqual(x, y *big.Int) bool {
 // ChainConfig that would alter the past.
 type ConfigCompatError struct {
 	What string
-	// block numbers of the stored and new configurations
+	// block (or round) numbers of the stored and new configurations
 	StoredConfig, NewConfig *big.Int
 	// the block number to which the local chain must be rewound to correct the error
 	RewindTo uint64
@@ -525,6 +597,28 @@ func newCompatError(what string, storedblock, newblock *big.Int) *ConfigCompatEr
 	return err
 }
 
+// newRoundCompatError is like newCompatError for forks scheduled by round. The
+// chain is rewound to the parent of the first block of the earlier fork round,
+// which is the first block executed under the changed rules.
+func newRoundCompatError(what string, storedround, newround *big.Int, roundHeight func(uint64) uint64) *ConfigCompatError {
+	var rew *big.Int
+	switch {
+	case storedround == nil:
+		rew = newround
+	case newround == nil || storedround.Cmp(newround) < 0:
+		rew = storedround
+	default:
+		rew = newround
+	}
+	err := &ConfigCompatError{what, storedround, newround, 0}
+	if rew != nil && rew.Sign() > 0 {
+		if height := roundHeight(rew.Uint64()); height > 0 {
+			err.RewindTo = height - 1
+		}
+	}
+	return err
+}
+
 func (err *ConfigCompatError) Error() string {
 	return fmt.Sprintf("mismatching %s in database (have %d, want %d, rewindto %d)", err.What, err.StoredConfig, err.NewConfig, err.RewindTo)
 }
@@ -560,7 +654,7 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 
 // NewTestChainConfig is the ChainConfig constructor for test
 func NewTestChainConig() *ChainConfig {
-	return &ChainConfig{big.NewInt(1), 0, big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil, nil}
+	return &ChainConfig{big.NewInt(1), 0, big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, new(EthashConfig), nil, nil, nil}
 }
 
 func NewTestDexonConfig() *DexconConfig {
@@ -79,3 +79,56 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckRoundCompatible(t *testing.T) {
+	// Rounds of 100 blocks each, round r starts at height 100*r.
+	roundHeight := func(round uint64) uint64 { return round * 100 }
+	type test struct {
+		stored, new *ChainConfig
+		round       uint64
+		wantErr     *ConfigCompatError
+	}
+	tests := []test{
+		{
+			stored:  &ChainConfig{EWASMRound: big.NewInt(200)},
+			new:     &ChainConfig{EWASMRound: big.NewInt(300)},
+			round:   50,
+			wantErr: nil,
+		},
+		{
+			stored:  &ChainConfig{EWASMRound: big.NewInt(10)},
+			new:     &ChainConfig{EWASMRound: big.NewInt(10)},
+			round:   50,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{EWASMRound: big.NewInt(10)},
+			new:    &ChainConfig{EWASMRound: nil},
+			round:  30,
+			wantErr: &ConfigCompatError{
+				What:         "ewasm fork round",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    nil,
+				RewindTo:     999,
+			},
+		},
+		{
+			stored: &ChainConfig{EWASMRound: big.NewInt(40)},
+			new:    &ChainConfig{EWASMRound: big.NewInt(20)},
+			round:  30,
+			wantErr: &ConfigCompatError{
+				What:         "ewasm fork round",
+				StoredConfig: big.NewInt(40),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     1999,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		err := test.stored.CheckRoundCompatible(test.new, test.round, roundHeight)
+		if !reflect.DeepEqual(err, test.wantErr) {
+			t.Errorf("error mismatch:\nstored: %v\nnew: %v\nround: %v\nerr: %v\nwant: %v", test.stored, test.new, test.round, err, test.wantErr)
+		}
+	}
+}
@@ -33,6 +33,7 @@ func (d DexconConfig) MarshalJSON() ([]byte, error) {
 		FineValues        []*math.HexOrDecimal256 `json:"fineValues"`
 		IsConsortium      bool                    `json:"isConsortium"`
 		AddressWhitelist  []common.Address        `json:"addressWhitelist"`
+		IdleBlockInterval uint64                  `json:"idleBlockInterval,omitempty"`
 	}
 	var enc DexconConfig
 	enc.GenesisCRSText = d.GenesisCRSText
@@ -58,6 +59,7 @@ func (d DexconConfig) MarshalJSON() ([]byte, error) {
 	}
 	enc.IsConsortium = d.IsConsortium
 	enc.AddressWhitelist = d.AddressWhitelist
+	enc.IdleBlockInterval = d.IdleBlockInterval
 	return json.Marshal(&enc)
 }
 
@@ -82,6 +84,7 @@ func (d *DexconConfig) UnmarshalJSON(input []byte) error {
 		FineValues        []*math.HexOrDecimal256 `json:"fineValues"`
 		IsConsortium      *bool                   `json:"isConsortium"`
 		AddressWhitelist  []common.Address        `json:"addressWhitelist"`
+		IdleBlockInterval *uint64                 `json:"idleBlockInterval,omitempty"`
 	}
 	var dec DexconConfig
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -144,5 +147,8 @@ func (d *DexconConfig) UnmarshalJSON(input []byte) error {
 	if dec.AddressWhitelist != nil {
 		d.AddressWhitelist = dec.AddressWhitelist
 	}
+	if dec.IdleBlockInterval != nil {
+		d.IdleBlockInterval = *dec.IdleBlockInterval
+	}
 	return nil
 }
@@ -24,6 +24,13 @@ import (
 
 // StartHTTPEndpoint starts the HTTP RPC endpoint, configured with cors/vhosts/modules
 func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []string, vhosts []string, timeouts HTTPTimeouts) (net.Listener, *Server, error) {
+	return StartLimitedHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts, RateLimit{}, BatchLimits{})
+}
+
+// StartLimitedHTTPEndpoint starts the HTTP RPC endpoint, configured with
+// cors/vhosts/modules and accepting requests at most at the given rate and
+// within the given batch limits.
+func StartLimitedHTTPEndpoint(endpoint string, apis []API, modules []string, cors []string, vhosts []string, timeouts HTTPTimeouts, limit RateLimit, batch BatchLimits) (net.Listener, *Server, error) {
 	// Generate the whitelist based on the allowed modules
 	whitelist := make(map[string]bool)
 	for _, module := range modules {
@@ -31,6 +38,8 @@ func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []str
 	}
 	// Register all the APIs exposed by the services
 	handler := NewServer()
+	handler.SetRateLimit(limit)
+	handler.SetBatchLimits(batch)
 	for _, api := range apis {
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -53,7 +62,12 @@ func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []str
 
 // StartWSEndpoint starts a websocket endpoint
 func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []string, exposeAll bool) (net.Listener, *Server, error) {
+	return StartLimitedWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll, RateLimit{}, BatchLimits{})
+}
 
+// StartLimitedWSEndpoint starts a websocket endpoint accepting requests at
+// most at the given rate and within the given batch limits.
+func StartLimitedWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []string, exposeAll bool, limit RateLimit, batch BatchLimits) (net.Listener, *Server, error) {
 	// Generate the whitelist based on the allowed modules
 	whitelist := make(map[string]bool)
 	for _, module := range modules {
@@ -61,6 +75,8 @@ func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []
 	}
 	// Register all the APIs exposed by the services
 	handler := NewServer()
+	handler.SetRateLimit(limit)
+	handler.SetBatchLimits(batch)
 	for _, api := range apis {
 		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -64,3 +64,29 @@ type shutdownError struct{}
 func (e *shutdownError) ErrorCode() int { return -32000 }
 
 func (e *shutdownError) Error() string { return "server is shutting down" }
+
+// issued when the request rate of the server has been exceeded.
+type rateLimitError struct{}
+
+func (e *rateLimitError) ErrorCode() int { return -32005 }
+
+func (e *rateLimitError) Error() string { return "request rate limit exceeded" }
+
+// issued when a batch holds more requests than the server accepts.
+type batchTooLargeError struct{ limit int }
+
+func (e *batchTooLargeError) ErrorCode() int { return -32600 }
+
+func (e *batchTooLargeError) Error() string {
+	return fmt.Sprintf("batch too large, at most %d requests allowed", e.limit)
+}
+
+// issued for the requests of a batch whose responses exceed the response size
+// limit of the server.
+type responseTooLargeError struct{ limit int }
+
+func (e *responseTooLargeError) ErrorCode() int { return -32003 }
+
+func (e *responseTooLargeError) Error() string {
+	return fmt.Sprintf("batch response too large, at most %d bytes allowed", e.limit)
+}
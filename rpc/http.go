@@ -238,7 +238,19 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// All checks passed, create a codec that reads direct from the request body
 	// untilEOF and writes the response to w and order the server to process a
 	// single request.
-	ctx := r.Context()
+	ctx := withRequestValues(r.Context(), r)
+
+	body := io.LimitReader(r.Body, maxRequestContentLength)
+	codec := NewJSONCodec(&httpReadWriteNopCloser{body, w})
+	defer codec.Close()
+
+	w.Header().Set("content-type", contentType)
+	srv.ServeSingleRequest(ctx, codec, OptionMethodInvocation)
+}
+
+// withRequestValues returns a copy of ctx carrying the details of the HTTP
+// request r, or of the upgrade request of a websocket connection.
+func withRequestValues(ctx context.Context, r *http.Request) context.Context {
 	ctx = context.WithValue(ctx, "remote", r.RemoteAddr)
 	ctx = context.WithValue(ctx, "scheme", r.Proto)
 	ctx = context.WithValue(ctx, "local", r.Host)
@@ -248,13 +260,12 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if origin := r.Header.Get("Origin"); origin != "" {
 		ctx = context.WithValue(ctx, "Origin", origin)
 	}
-
-	body := io.LimitReader(r.Body, maxRequestContentLength)
-	codec := NewJSONCodec(&httpReadWriteNopCloser{body, w})
-	defer codec.Close()
-
-	w.Header().Set("content-type", contentType)
-	srv.ServeSingleRequest(ctx, codec, OptionMethodInvocation)
+	// The basic-auth user, only authenticated if a proxy in front of the
+	// node checks it.
+	if user, _, ok := r.BasicAuth(); ok {
+		ctx = context.WithValue(ctx, "user", user)
+	}
+	return ctx
 }
 
 // validateRequest returns a non-zero response code and error message if the
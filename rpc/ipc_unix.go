@@ -41,7 +41,7 @@ import "C"
 // ipcListen will create a Unix socket on the given endpoint.
 func ipcListen(endpoint string) (net.Listener, error) {
 	if len(endpoint) > int(C.tan_max_socket_path_size()) {
-		log.Warn(fmt.Sprintf("The ipc endpoint is longer than %d characters. ", C.max_socket_path_size()),
+		log.Warn(fmt.Sprintf("The ipc endpoint is longer than %d characters. ", C.tan_max_socket_path_size()),
 			"endpoint", endpoint)
 	}
 
@@ -0,0 +1,51 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"testing"
+)
+
+func TestRateLimiter(t *testing.T) {
+	if newRateLimiter(RateLimit{}) != nil {
+		t.Fatalf("limiter created without a rate")
+	}
+	limiter := newRateLimiter(RateLimit{Rate: 0.001, Burst: 2})
+	if !limiter.allow(2) {
+		t.Errorf("burst not allowed")
+	}
+	if limiter.allow(1) {
+		t.Errorf("request allowed over the limit")
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	server.SetRateLimit(RateLimit{Rate: 0.001, Burst: 1})
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var resp Result
+	if err := client.Call(&resp, "service_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	err := client.Call(&resp, "service_echo", "hello", 10, &Args{"world"})
+	if err == nil || err.Error() != (&rateLimitError{}).Error() {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+}
@@ -18,6 +18,7 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"runtime"
@@ -172,18 +173,27 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 		// check if server is ordered to shutdown and return an error
 		// telling the client that his request failed.
 		if atomic.LoadInt32(&s.run) != 1 {
-			err = &shutdownError{}
-			if batch {
-				resps := make([]interface{}, len(reqs))
-				for i, r := range reqs {
-					resps[i] = codec.CreateErrorResponse(&r.id, err)
-				}
-				codec.Write(resps)
-			} else {
-				codec.Write(codec.CreateErrorResponse(&reqs[0].id, err))
-			}
+			s.rejectRequests(codec, reqs, batch, &shutdownError{})
 			return nil
 		}
+		// reject the requests exceeding the rate limit of the server, the
+		// connection stays open for later requests.
+		if s.limiter != nil && !s.limiter.allow(len(reqs)) {
+			s.rejectRequests(codec, reqs, batch, &rateLimitError{})
+			if singleShot {
+				return nil
+			}
+			continue
+		}
+		// reject batches holding more requests than allowed as a whole, each
+		// request getting the error so that clients can match it.
+		if limit := s.batchLimits.MaxRequests; batch && limit > 0 && len(reqs) > limit {
+			s.rejectRequests(codec, reqs, batch, &batchTooLargeError{limit})
+			if singleShot {
+				return nil
+			}
+			continue
+		}
 		// If a single shot request is executing, run and return immediately
 		if singleShot {
 			if batch {
@@ -208,6 +218,37 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 	return nil
 }
 
+// rejectRequests responds to all given requests with err.
+func (s *Server) rejectRequests(codec ServerCodec, reqs []*serverRequest, batch bool, err Error) {
+	if batch {
+		resps := make([]interface{}, len(reqs))
+		for i, r := range reqs {
+			resps[i] = codec.CreateErrorResponse(&r.id, err)
+		}
+		codec.Write(resps)
+	} else {
+		codec.Write(codec.CreateErrorResponse(&reqs[0].id, err))
+	}
+}
+
+// BatchLimits bounds the memory used to serve a batch request.
+type BatchLimits struct {
+	MaxRequests     int // Maximum number of requests in a batch (0 = unlimited)
+	MaxResponseSize int // Maximum cumulative size of the responses in bytes (0 = unlimited)
+}
+
+// SetBatchLimits bounds the batches the server accepts. It must be called
+// before the server starts serving.
+func (s *Server) SetBatchLimits(limits BatchLimits) {
+	s.batchLimits = limits
+}
+
+// SetRateLimit limits the number of requests the server accepts across all
+// of its connections. It must be called before the server starts serving.
+func (s *Server) SetRateLimit(limit RateLimit) {
+	s.limiter = newRateLimiter(limit)
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes the
 // response back using the given codec. It will block until the codec is closed or the server is
 // stopped. In either case the codec is closed.
@@ -279,6 +320,7 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		if err != nil {
 			return codec.CreateErrorResponse(&req.id, &callbackError{err.Error()}), nil
 		}
+		req.subid = subid
 
 		// active the subscription after the sub id was successfully sent to the client
 		activateSub := func() {
@@ -313,8 +355,14 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
-			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
-			return res, nil
+			rpcErr, ok := e.(Error)
+			if !ok {
+				rpcErr = &callbackError{e.Error()}
+			}
+			if de, ok := e.(DataError); ok {
+				return codec.CreateErrorResponseWithInfo(&req.id, rpcErr, de.ErrorData()), nil
+			}
+			return codec.CreateErrorResponse(&req.id, rpcErr), nil
 		}
 	}
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
@@ -343,17 +391,51 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 
 // execBatch executes the given requests and writes the result back using the codec.
 // It will only write the response back when the last request is processed.
+//
+// If the responses grow beyond the response size limit of the server, the
+// remaining requests are not executed and are answered with an error instead.
 func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*serverRequest) {
-	responses := make([]interface{}, len(requests))
-	var callbacks []func()
+	var (
+		responses = make([]interface{}, len(requests))
+		callbacks []func()
+		limit     = s.batchLimits.MaxResponseSize
+		size      int
+	)
 	for i, req := range requests {
+		if limit > 0 && size > limit {
+			responses[i] = codec.CreateErrorResponse(&req.id, &responseTooLargeError{limit})
+			continue
+		}
+		var callback func()
 		if req.err != nil {
 			responses[i] = codec.CreateErrorResponse(&req.id, req.err)
 		} else {
-			var callback func()
-			if responses[i], callback = s.handle(ctx, codec, req); callback != nil {
-				callbacks = append(callbacks, callback)
+			responses[i], callback = s.handle(ctx, codec, req)
+		}
+		if limit > 0 {
+			// Encode the response once to account for its size, the
+			// encoding being written as is.
+			enc, err := json.Marshal(responses[i])
+			if err != nil {
+				log.Error("Failed to encode batch response", "err", err)
+			} else {
+				responses[i] = json.RawMessage(enc)
+				size += len(enc)
 			}
+			if size > limit {
+				responses[i] = codec.CreateErrorResponse(&req.id, &responseTooLargeError{limit})
+				// the client never learns the id of a subscription whose
+				// response is dropped, release it instead of activating it.
+				if callback != nil && req.subid != "" {
+					if notifier, supported := NotifierFromContext(ctx); supported {
+						notifier.unsubscribe(req.subid)
+					}
+				}
+				continue
+			}
+		}
+		if callback != nil {
+			callbacks = append(callbacks, callback)
 		}
 	}
 
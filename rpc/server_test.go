@@ -19,6 +19,7 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net"
 	"reflect"
 	"testing"
@@ -160,3 +161,74 @@ func TestServerMethodExecution(t *testing.T) {
 func TestServerMethodWithCtx(t *testing.T) {
 	testServerMethodExecution(t, "echoWithCtx")
 }
+
+func TestServerBatchLimits(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	server.SetBatchLimits(BatchLimits{MaxRequests: 3, MaxResponseSize: 100})
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	newBatch := func(n int) []BatchElem {
+		batch := make([]BatchElem, n)
+		for i := range batch {
+			batch[i] = BatchElem{
+				Method: "service_echo",
+				Args:   []interface{}{"hello", i, &Args{"world"}},
+				Result: new(Result),
+			}
+		}
+		return batch
+	}
+	// Batches over the request limit are rejected as a whole.
+	batch := newBatch(4)
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	for i, elem := range batch {
+		if elem.Error == nil || elem.Error.Error() != (&batchTooLargeError{3}).Error() {
+			t.Errorf("request %d: expected batch too large error, got %v", i, elem.Error)
+		}
+	}
+	// Requests past the response size limit are answered with an error.
+	batch = newBatch(3)
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch[0].Error != nil {
+		t.Errorf("request 0: unexpected error %v", batch[0].Error)
+	}
+	for i, elem := range batch[1:] {
+		if elem.Error == nil || elem.Error.Error() != (&responseTooLargeError{100}).Error() {
+			t.Errorf("request %d: expected response too large error, got %v", i+1, elem.Error)
+		}
+	}
+}
+
+type dataError struct{}
+
+func (e *dataError) Error() string          { return "data error" }
+func (e *dataError) ErrorCode() int         { return 3 }
+func (e *dataError) ErrorData() interface{} { return "0x01" }
+
+type ErrorService struct{}
+
+func (s *ErrorService) Plain() error { return errors.New("plain error") }
+func (s *ErrorService) Data() error  { return &dataError{} }
+
+// Tests that the code and data of errors returned by callbacks are sent along.
+func TestServerErrorCodeAndData(t *testing.T) {
+	server := newTestServer("test", new(ErrorService))
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	err := client.Call(nil, "test_plain")
+	if e, ok := err.(*jsonError); !ok || e.Code != -32000 || e.Message != "plain error" || e.Data != nil {
+		t.Errorf("plain error mismatch: %#v", err)
+	}
+	err = client.Call(nil, "test_data")
+	if e, ok := err.(*jsonError); !ok || e.Code != 3 || e.Message != "data error" || e.ErrorData() != "0x01" {
+		t.Errorf("data error mismatch: %#v", err)
+	}
+}
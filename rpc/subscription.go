@@ -115,7 +115,8 @@ func (n *Notifier) Closed() <-chan interface{} {
 	return n.codec.Closed()
 }
 
-// unsubscribe a subscription.
+// unsubscribe a subscription. Subscriptions that were never activated are
+// released as well, dropping their buffered notifications.
 // If the subscription could not be found ErrSubscriptionNotFound is returned.
 func (n *Notifier) unsubscribe(id ID) error {
 	n.subMu.Lock()
@@ -125,6 +126,12 @@ func (n *Notifier) unsubscribe(id ID) error {
 		delete(n.active, id)
 		return nil
 	}
+	if s, found := n.inactive[id]; found {
+		close(s.err)
+		delete(n.inactive, id)
+		delete(n.buffer, id)
+		return nil
+	}
 	return ErrSubscriptionNotFound
 }
 
@@ -153,6 +153,49 @@ func TestNotifications(t *testing.T) {
 	}
 }
 
+// Tests that a subscription whose batch response is dropped for exceeding the
+// response size limit is released instead of lingering until disconnect.
+func TestDroppedBatchSubscriptionReleased(t *testing.T) {
+	server := NewServer()
+	server.SetBatchLimits(BatchLimits{MaxResponseSize: 1})
+	service := &NotificationTestService{unsubscribed: make(chan string)}
+
+	if err := server.RegisterName("eth", service); err != nil {
+		t.Fatalf("unable to register test service %v", err)
+	}
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	go server.ServeCodec(NewJSONCodec(serverConn), OptionMethodInvocation|OptionSubscriptions)
+
+	out := json.NewEncoder(clientConn)
+	in := json.NewDecoder(clientConn)
+
+	request := []map[string]interface{}{{
+		"id":      1,
+		"method":  "eth_subscribe",
+		"version": "2.0",
+		"params":  []interface{}{"someSubscription", 0, 0},
+	}}
+	if err := out.Encode(request); err != nil {
+		t.Fatal(err)
+	}
+	var responses []jsonErrResponse
+	if err := in.Decode(&responses); err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 1 || responses[0].Error.Code != (&responseTooLargeError{}).ErrorCode() {
+		t.Fatalf("expected response too large error, got %+v", responses)
+	}
+	// The connection is still open, the subscription must be released.
+	select {
+	case <-service.unsubscribed:
+	case <-time.After(1 * time.Second):
+		t.Fatal("dropped subscription not released after one second")
+	}
+}
+
 func waitForMessages(t *testing.T, in *json.Decoder, successes chan<- jsonSuccessResponse,
 	failures chan<- jsonErrResponse, notifications chan<- jsonNotification, errors chan<- error) {
 
@@ -60,6 +60,7 @@ type serverRequest struct {
 	callb         *callback
 	args          []reflect.Value
 	isUnsubscribe bool
+	subid         ID // subscription created by a subscribe request
 	err           Error
 }
 
@@ -74,6 +75,9 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   mapset.Set
+
+	limiter     *rateLimiter
+	batchLimits BatchLimits
 }
 
 // rpcRequest represents a raw incoming RPC request
@@ -92,6 +96,13 @@ type Error interface {
 	ErrorCode() int // returns the code
 }
 
+// DataError is an error returned by a callback that carries extra data,
+// which is sent along in the data field of the error response.
+type DataError interface {
+	Error() string          // returns the message
+	ErrorData() interface{} // returns the error data
+}
+
 // ServerCodec implements reading, parsing and writing RPC messages for the server side of
 // a RPC session. Implementations must be go-routine safe since the codec can be called in
 // multiple go-routines concurrently.
@@ -1,13 +1,15 @@
 package main
 
 import (
+	"crypto/ecdsa"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"math/big"
-	"os"
 	"strconv"
 
+	"github.com/portto/go-tangerine/accounts/hdkey"
 	"github.com/portto/go-tangerine/common"
 	"github.com/portto/go-tangerine/core"
 	"github.com/portto/go-tangerine/crypto"
@@ -22,16 +24,35 @@ var preFundAddresss = []string{
 	"0xAA2fe8D6024682aF0540F8BA776b549bB50251ab", // Monkey
 }
 
+var mnemonic = flag.String("mnemonic", "", "derive the owner, monkey and node keys from this mnemonic")
+
 func main() {
+	flag.Parse()
+
 	genesis := core.DefaultGenesisBlock()
 
 	// Clear previous allocation.
 	genesis.Alloc = make(map[common.Address]core.GenesisAccount)
 
-	count, err := strconv.Atoi(os.Args[1])
+	count, err := strconv.Atoi(flag.Arg(0))
 	if err != nil {
 		panic(err)
 	}
+
+	// Keys derived from a mnemonic regenerate the same network every time.
+	var wallet *hdkey.Wallet
+	if *mnemonic != "" {
+		if wallet, err = hdkey.NewWallet(*mnemonic, ""); err != nil {
+			panic(err)
+		}
+		owner := saveKey(wallet.OwnerKey, "keystore/owner.key")
+		monkey := saveKey(wallet.MonkeyKey, "keystore/monkey.key")
+		genesis.Config.Dexcon.Owner = crypto.PubkeyToAddress(owner.PublicKey)
+		preFundAddresss = []string{
+			genesis.Config.Dexcon.Owner.String(),
+			crypto.PubkeyToAddress(monkey.PublicKey).String(),
+		}
+	}
 	for _, addr := range preFundAddresss {
 		address := common.HexToAddress(addr)
 		genesis.Alloc[address] = core.GenesisAccount{
@@ -41,7 +62,12 @@ func main() {
 		fmt.Printf("Created account %s\n", address.String())
 	}
 	for i := 0; i < count; i++ {
-		privKey, err := crypto.GenerateKey()
+		var privKey *ecdsa.PrivateKey
+		if wallet != nil {
+			privKey, err = wallet.NodeKey(i)
+		} else {
+			privKey, err = crypto.GenerateKey()
+		}
 		if err != nil {
 			panic(err)
 		}
@@ -72,3 +98,15 @@ func main() {
 	}
 	fmt.Println("Done.")
 }
+
+// saveKey saves the key derived by derive to file.
+func saveKey(derive func() (*ecdsa.PrivateKey, error), file string) *ecdsa.PrivateKey {
+	key, err := derive()
+	if err != nil {
+		panic(err)
+	}
+	if err := crypto.SaveECDSA(file, key); err != nil {
+		panic(err)
+	}
+	return key
+}